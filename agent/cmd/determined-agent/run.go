@@ -140,6 +140,20 @@ func newRunCmd() *cobra.Command {
 	cmd.Flags().Lookup("artificial-slots").Hidden = true
 	cmd.Flags().StringVar(&opts.ImageRoot, "image-root", "", "Path to local container image cache")
 
+	// Data cache flags.
+	cmd.Flags().StringVar(&opts.DataCache.Dir, "data-cache-dir", "",
+		"Path to the local cache that staged datasets are stored in")
+	cmd.Flags().IntVar(&opts.DataCache.MaxSizeGB, "data-cache-max-size-gb", 0,
+		"Maximum size of the unused datasets kept in the data cache, or 0 for no limit")
+	cmd.Flags().StringSliceVar(&opts.DataCache.FileRoots, "data-cache-file-roots", nil,
+		"Directories of the host that file:// datasets may be staged from")
+
+	// Egress flags.
+	cmd.Flags().StringVar(&opts.Egress.NetworkName, "egress-network-name", "determined-egress",
+		"Docker network that containers of tasks with an egress policy run on")
+	cmd.Flags().StringVar(&opts.Egress.Subnet, "egress-subnet", "172.31.255.0/24",
+		"Subnet of the egress network, which must not overlap with other networks of the host")
+
 	// Endpoint TLS flags.
 	cmd.Flags().BoolVar(&opts.TLS, "tls", false, "Use TLS for the API server")
 	cmd.Flags().StringVar(&opts.CertFile, "tls-cert", "", "Path to TLS certificate file")
@@ -119,6 +119,7 @@ func (a *Agent) run(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to detect devices: %v", devices)
 	}
+	devices = detect.TimeSlice(devices, mopts.TimeSlicesPerGPU)
 
 	a.log.Tracef("setting up %s runtime", a.opts.ContainerRuntime)
 	var cruntime container.ContainerRuntime
@@ -10,8 +10,11 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/sirupsen/logrus"
 
+	"github.com/determined-ai/determined/agent/internal/datacache"
 	"github.com/determined-ai/determined/agent/pkg/docker"
 	"github.com/determined-ai/determined/agent/pkg/events"
 	"github.com/determined-ai/determined/master/pkg/aproto"
@@ -36,8 +39,10 @@ type Container struct {
 	log      *logrus.Entry
 	cruntime ContainerRuntime
 	pub      events.Publisher[Event]
+	cache    *datacache.Cache
 
 	// Internal state. Access should be protected.
+	datasets []string // Cache keys of the staged datasets the container holds.
 	mu       sync.RWMutex
 	state    cproto.State // Updated throughout run, access protected.
 	signals  chan syscall.Signal
@@ -53,6 +58,7 @@ func Start(
 	req aproto.StartContainer,
 	cl ContainerRuntime,
 	pub events.Publisher[Event],
+	cache *datacache.Cache,
 ) *Container {
 	c := &Container{
 		containerID:  req.Container.ID,
@@ -65,6 +71,7 @@ func Start(
 		}),
 		cruntime: cl,
 		pub:      pub,
+		cache:    cache,
 		state:    req.Container.State,
 		signals:  make(chan syscall.Signal),
 		done:     make(chan struct{}),
@@ -90,6 +97,7 @@ func Reattach(
 	container cproto.Container,
 	cl ContainerRuntime,
 	pub events.Publisher[Event],
+	cache *datacache.Cache,
 ) *Container {
 	c := &Container{
 		// TODO(Brad): We should be recovering the allocation ID for logging.
@@ -103,6 +111,7 @@ func Reattach(
 		}),
 		cruntime: cl,
 		pub:      pub,
+		cache:    cache,
 		state:    container.State,
 		signals:  make(chan syscall.Signal, 16), // Not infinite, but large enough to not drop often.
 		done:     make(chan struct{}),
@@ -204,6 +213,16 @@ func (c *Container) run(parent context.Context) (err error) {
 			return fmt.Errorf("pulling container image: %w", err)
 		}
 
+		if len(c.spec.StagedData) > 0 {
+			c.log.Trace("staging data")
+			if err = c.transition(ctx, cproto.Staging, nil, nil); err != nil {
+				return err
+			}
+			if err = c.stageData(ctx); err != nil {
+				return fmt.Errorf("staging data: %w", err)
+			}
+		}
+
 		c.log.Trace("creating container, copying files, etc")
 		if err = c.transition(ctx, cproto.Starting, nil, nil); err != nil {
 			return err
@@ -284,10 +303,52 @@ func (c *Container) reattach(ctx context.Context) error {
 	case dc == nil:
 		return ErrMissing
 	default:
+		c.reacquireDatasets(dc.ContainerInfo.Mounts)
 		return c.wait(ctx, dc)
 	}
 }
 
+// stageData stages the datasets of the container into the data cache and mounts them read-only.
+func (c *Container) stageData(ctx context.Context) error {
+	if c.cache == nil {
+		return errors.New("the agent has no data cache, set data_cache.dir in its configuration")
+	}
+
+	logf := func(format string, args ...any) {
+		msg := fmt.Sprintf(format, args...)
+		if err := c.shimDockerEvents().Publish(ctx, docker.NewLogEvent(model.LogLevelInfo, msg)); err != nil {
+			c.log.WithError(err).Warn("publishing data staging log")
+		}
+	}
+	for _, d := range c.spec.StagedData {
+		path, err := c.cache.Acquire(ctx, d, logf)
+		if err != nil {
+			return err
+		}
+		c.datasets = append(c.datasets, d.CacheKey)
+		c.spec.RunSpec.HostConfig.Mounts = append(c.spec.RunSpec.HostConfig.Mounts, mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   path,
+			Target:   d.MountPath,
+			ReadOnly: true,
+		})
+	}
+	return nil
+}
+
+// reacquireDatasets takes references on the cached datasets a reattached container mounts, so
+// that they aren't evicted while it runs.
+func (c *Container) reacquireDatasets(mounts []types.MountPoint) {
+	if c.cache == nil {
+		return
+	}
+	for _, m := range mounts {
+		if key, ok := c.cache.AcquirePath(m.Source); ok {
+			c.datasets = append(c.datasets, key)
+		}
+	}
+}
+
 func (c *Container) wait(ctx context.Context, dc *docker.Container) error {
 	c.log.Trace("in monitoring loop")
 	for {
@@ -332,6 +393,10 @@ func (c *Container) finalize(ctx context.Context, err error) {
 		return
 	}
 
+	for _, key := range c.datasets {
+		c.cache.Release(key)
+	}
+
 	var stop aproto.ContainerStopped
 	switch err := err.(type) {
 	case nil:
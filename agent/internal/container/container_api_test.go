@@ -152,7 +152,7 @@ func TestContainer(t *testing.T) {
 						HostConfig: dcontainer.HostConfig{AutoRemove: true},
 					},
 				},
-			}, cl, events.NilPublisher[container.Event]{})
+			}, cl, events.NilPublisher[container.Event]{}, nil)
 			defer c.Stop()
 
 			t.Log("setup canceler")
@@ -195,7 +195,7 @@ func TestContainer(t *testing.T) {
 				subg.Wait()
 
 				t.Log("reattaching container")
-				c = container.Reattach(c.Summary(), cl, events.NilPublisher[container.Event]{})
+				c = container.Reattach(c.Summary(), cl, events.NilPublisher[container.Event]{}, nil)
 				exit = c.Wait()
 			}
 
@@ -290,7 +290,7 @@ func TestContainerStatus(t *testing.T) {
 						HostConfig: dcontainer.HostConfig{AutoRemove: true},
 					},
 				},
-			}, cl, events.NilPublisher[container.Event]{})
+			}, cl, events.NilPublisher[container.Event]{}, nil)
 			defer c.Stop()
 			containerCh <- c
 		}()
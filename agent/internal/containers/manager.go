@@ -14,6 +14,8 @@ import (
 	"golang.org/x/sys/unix"
 
 	"github.com/determined-ai/determined/agent/internal/container"
+	"github.com/determined-ai/determined/agent/internal/datacache"
+	"github.com/determined-ai/determined/agent/internal/egress"
 	"github.com/determined-ai/determined/agent/internal/options"
 	"github.com/determined-ai/determined/agent/pkg/docker"
 	"github.com/determined-ai/determined/agent/pkg/events"
@@ -42,6 +44,10 @@ type Manager struct {
 	log      *log.Entry
 	cruntime container.ContainerRuntime
 	pub      events.Publisher[container.Event]
+	cache    *datacache.Cache // Nil unless a data cache directory is configured.
+	// firewall enforces the egress specs of containers. Nil unless the runtime is Docker and an
+	// egress network is configured.
+	firewall *egress.Firewall
 
 	// Internal state. Access should be protected.
 	containers  map[cproto.ID]*container.Container
@@ -58,6 +64,26 @@ func New(
 	cl container.ContainerRuntime,
 	pub events.Publisher[container.Event],
 ) (*Manager, error) {
+	var cache *datacache.Cache
+	if opts.DataCache.Dir != "" {
+		var err error
+		cache, err = datacache.New(
+			opts.DataCache.Dir, int64(opts.DataCache.MaxSizeGB)<<30, opts.DataCache.FileRoots)
+		if err != nil {
+			return nil, fmt.Errorf("creating data cache: %w", err)
+		}
+	}
+
+	var firewall *egress.Firewall
+	if dcl, ok := cl.(*docker.Client); ok && opts.Egress != (options.EgressOptions{}) {
+		masterHost, masterPort := containerMasterAddress(opts)
+		var err error
+		firewall, err = egress.New(dcl.Inner(), opts.Egress, masterHost, masterPort)
+		if err != nil {
+			return nil, fmt.Errorf("creating egress firewall: %w", err)
+		}
+	}
+
 	return &Manager{
 		opts:        opts,
 		mopts:       mopts,
@@ -65,6 +91,8 @@ func New(
 		log:         log.WithField("component", "container-manager"),
 		cruntime:    cl,
 		pub:         pub,
+		cache:       cache,
+		firewall:    firewall,
 		containers:  make(map[cproto.ID]*container.Container),
 		recentExits: ring.New(RecentExitsCacheSize),
 		wg:          waitgroupx.WithContext(context.Background()), // Manager-scoped group.
@@ -205,17 +233,30 @@ func (m *Manager) StartContainer(ctx context.Context, req aproto.StartContainer)
 	}
 	req.Spec = spec
 
+	if req.Spec.Egress != nil {
+		if m.firewall == nil {
+			return fmt.Errorf("container has an egress policy, which this agent can't enforce " +
+				"without the Docker container runtime and an egress network")
+		}
+		if err := m.firewall.Apply(ctx, req.Container.ID, &req.Spec); err != nil {
+			return fmt.Errorf("applying egress policy: %w", err)
+		}
+	}
+
 	m.mu.Lock()
 	if m.containers[req.Container.ID] != nil {
 		m.mu.Unlock()
 		return fmt.Errorf("container already created: %s", req.Container.ID)
 	}
-	c := container.Start(req, m.cruntime, m.pub)
+	c := container.Start(req, m.cruntime, m.pub, m.cache)
 	m.containers[req.Container.ID] = c
 	m.mu.Unlock()
 
 	m.wg.Go(func(_ context.Context) {
 		exit := c.Wait()
+		if exit != nil {
+			m.releaseEgress(req.Container.ID)
+		}
 		m.mu.Lock()
 		if exit != nil {
 			m.recentExits = m.recentExits.Prev()
@@ -277,6 +318,16 @@ func (m *Manager) NumContainers() int {
 	return len(m.containers)
 }
 
+// releaseEgress removes the egress rules of a container that exited, if it had any.
+func (m *Manager) releaseEgress(id cproto.ID) {
+	if m.firewall == nil {
+		return
+	}
+	if err := m.firewall.Release(context.TODO(), id); err != nil {
+		m.log.WithError(err).Errorf("removing egress rules of container %s", id)
+	}
+}
+
 func (m *Manager) reattachContainer(
 	ctx context.Context, containerPrevState cproto.Container, containerInfo types.Container,
 ) (*cproto.Container, error) {
@@ -305,12 +356,15 @@ func (m *Manager) reattachContainer(
 		c.Signal(ctx, syscall.SIGKILL)
 		return nil, errors.New(errorMsg)
 	}
-	c := container.Reattach(*containerCurrState, m.cruntime, m.pub)
+	c := container.Reattach(*containerCurrState, m.cruntime, m.pub, m.cache)
 	m.containers[cID] = c
 	m.mu.Unlock()
 
 	m.wg.Go(func(_ context.Context) {
 		exit := c.Wait()
+		if exit != nil {
+			m.releaseEgress(cID)
+		}
 		m.mu.Lock()
 		if exit != nil {
 			m.recentExits = m.recentExits.Prev()
@@ -5,7 +5,10 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/determined-ai/determined/agent/internal/detect"
 	"github.com/determined-ai/determined/agent/internal/options"
+	"github.com/determined-ai/determined/master/pkg/cproto"
+	"github.com/determined-ai/determined/master/pkg/device"
 )
 
 func TestAddProxyInfo(t *testing.T) {
@@ -78,3 +81,27 @@ func TestAddProxyInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestGPUSharingEnv(t *testing.T) {
+	slices := detect.TimeSlice([]device.Device{
+		{ID: 0, UUID: "GPU-0", Type: device.CUDA},
+		{ID: 1, UUID: "GPU-1", Type: device.CUDA},
+	}, 4)
+	require.Len(t, slices, 8)
+
+	// A quarter of the second GPU.
+	cont := cproto.Container{ID: "c", Devices: slices[4:5]}
+	require.Equal(t, []string{"DET_CONTAINER_ID=c", "DET_SLOT_IDS=[1]"}, containerEnv(cont, 4))
+	require.Equal(t, []string{"GPU-1"}, cont.DeviceUUIDsByType(device.CUDA))
+	require.Equal(t, []string{
+		"TF_FORCE_GPU_ALLOW_GROWTH=false",
+		"DET_GPU_MEMORY_FRACTION=0.25",
+		"XLA_PYTHON_CLIENT_MEM_FRACTION=0.25",
+	}, addGPUSharingEnv([]string{"TF_FORCE_GPU_ALLOW_GROWTH=false"}, cont, 4))
+
+	// Whole GPUs aren't shared.
+	cont = cproto.Container{ID: "c", Devices: slices}
+	require.Equal(t, []string{"DET_CONTAINER_ID=c", "DET_SLOT_IDS=[0,1]"}, containerEnv(cont, 4))
+	require.Equal(t, []string{"GPU-0", "GPU-1"}, cont.DeviceUUIDsByType(device.CUDA))
+	require.Empty(t, addGPUSharingEnv(nil, cont, 4))
+}
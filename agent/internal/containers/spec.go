@@ -30,7 +30,10 @@ func overwriteSpec(
 	spec.RunSpec.ContainerConfig.Env = addProxyInfo(spec.RunSpec.ContainerConfig.Env, opts)
 	spec.RunSpec.ContainerConfig.Env = append(
 		spec.RunSpec.ContainerConfig.Env, makeGlobalEnvVars(opts, mopts)...)
-	spec.RunSpec.ContainerConfig.Env = append(spec.RunSpec.ContainerConfig.Env, containerEnv(cont)...)
+	spec.RunSpec.ContainerConfig.Env = append(
+		spec.RunSpec.ContainerConfig.Env, containerEnv(cont, mopts.TimeSlicesPerGPU)...)
+	spec.RunSpec.ContainerConfig.Env = addGPUSharingEnv(
+		spec.RunSpec.ContainerConfig.Env, cont, mopts.TimeSlicesPerGPU)
 
 	spec.RunSpec.HostConfig.AutoRemove = !opts.ContainerAutoRemoveDisabled
 
@@ -141,10 +144,19 @@ func injectRocmDeviceRequests(cont cproto.Container, hostConfig *dcontainer.Host
 	return nil
 }
 
-func containerEnv(cont cproto.Container) []string {
+func containerEnv(cont cproto.Container, slicesPerGPU int) []string {
 	var slotIds []string
+	seen := make(map[device.ID]bool)
 	for _, d := range cont.Devices {
-		slotIds = append(slotIds, strconv.Itoa(int(d.ID)))
+		id := d.ID
+		if slicesPerGPU > 1 && detect.IsTimeSliceable(d) {
+			// The slices of a GPU are a single slot to the task.
+			id /= device.ID(slicesPerGPU)
+		}
+		if !seen[id] {
+			seen[id] = true
+			slotIds = append(slotIds, strconv.Itoa(int(id)))
+		}
 	}
 	return []string{
 		fmt.Sprintf("DET_CONTAINER_ID=%s", cont.ID),
@@ -152,6 +164,33 @@ func containerEnv(cont cproto.Container) []string {
 	}
 }
 
+// addGPUSharingEnv limits the GPU memory that frameworks take when the container has some of the
+// time slices of a GPU, to its share of them, unless the task sets the variables itself.
+func addGPUSharingEnv(env []string, cont cproto.Container, slicesPerGPU int) []string {
+	held := 0
+	for _, d := range cont.Devices {
+		if detect.IsTimeSliceable(d) {
+			held++
+		}
+	}
+	if slicesPerGPU <= 1 || held == 0 || held >= slicesPerGPU {
+		return env
+	}
+
+	fraction := strconv.FormatFloat(float64(held)/float64(slicesPerGPU), 'f', -1, 64)
+	for _, v := range []string{
+		"DET_GPU_MEMORY_FRACTION=" + fraction,
+		"XLA_PYTHON_CLIENT_MEM_FRACTION=" + fraction,
+		"TF_FORCE_GPU_ALLOW_GROWTH=true",
+	} {
+		key := strings.SplitN(v, "=", 2)[0]
+		if !slices.ContainsFunc(env, func(e string) bool { return strings.HasPrefix(e, key+"=") }) {
+			env = append(env, v)
+		}
+	}
+	return env
+}
+
 func makeContainerDockerLabels(cont cproto.Container) map[string]string {
 	labels := map[string]string{}
 	labels[docker.ContainerVersionLabel] = docker.ContainerVersionValue
@@ -207,14 +246,7 @@ func makeGlobalEnvVars(opts options.Options, mopts aproto.MasterSetAgentOptions)
 	if opts.Security.TLS.Enabled {
 		masterScheme = httpSecureScheme
 	}
-	masterHost := opts.ContainerMasterHost
-	if masterHost == "" {
-		masterHost = opts.MasterHost
-	}
-	masterPort := opts.ContainerMasterPort
-	if masterPort == 0 {
-		masterPort = opts.MasterPort
-	}
+	masterHost, masterPort := containerMasterAddress(opts)
 	globalEnvVars := []string{
 		fmt.Sprintf("DET_CLUSTER_ID=%s", mopts.MasterInfo.ClusterID),
 		fmt.Sprintf("DET_MASTER_ID=%s", mopts.MasterInfo.MasterID),
@@ -231,6 +263,19 @@ func makeGlobalEnvVars(opts options.Options, mopts aproto.MasterSetAgentOptions)
 	return globalEnvVars
 }
 
+// containerMasterAddress returns the host and port containers reach the master at.
+func containerMasterAddress(opts options.Options) (string, int) {
+	masterHost := opts.ContainerMasterHost
+	if masterHost == "" {
+		masterHost = opts.MasterHost
+	}
+	masterPort := opts.ContainerMasterPort
+	if masterPort == 0 {
+		masterPort = opts.MasterPort
+	}
+	return masterHost, masterPort
+}
+
 func makeLabels(opts options.Options, mopts aproto.MasterSetAgentOptions) map[string]string {
 	return map[string]string{
 		docker.ContainerTypeLabel: docker.ContainerTypeValue,
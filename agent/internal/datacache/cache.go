@@ -0,0 +1,372 @@
+package datacache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/determined-ai/determined/master/pkg/cproto"
+)
+
+// The cache directory holds the staged datasets, the metadata that marks them complete, datasets
+// that are being staged, and evicted datasets that are being deleted, each in a subdirectory.
+const (
+	datasetsDir = "datasets"
+	metadataDir = "metadata"
+	stagingDir  = "staging"
+	trashDir    = "trash"
+)
+
+// Cache is a node-local cache of datasets that are staged for task containers. Each dataset is
+// staged once per cache key into a directory of the cache, which is shared by every container of
+// the agent that stages the same key. Datasets are reference counted by the containers using them
+// and, once unused, evicted least recently used first when the cache grows over its size limit.
+type Cache struct {
+	dir       string
+	maxBytes  int64
+	fileRoots []string
+	log       *logrus.Entry
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	// deletions tracks the background deletion of evicted datasets.
+	deletions sync.WaitGroup
+}
+
+type entry struct {
+	source   string
+	refs     int
+	size     int64
+	lastUsed time.Time
+	// staged is closed once the dataset is staged, or staging failed with err.
+	staged chan struct{}
+	err    error
+	// cancel cancels the staging, which happens once no container waits for it.
+	cancel   context.CancelFunc
+	canceled bool
+}
+
+// metadata is persisted next to each staged dataset, so the cache survives agent restarts.
+type metadata struct {
+	Source string `json:"source"`
+	Size   int64  `json:"size"`
+}
+
+// New returns a cache in dir, which may not grow over maxBytes once its datasets are unused, or
+// without limit if maxBytes is zero. file:// sources must be under one of fileRoots. Datasets
+// staged by previous runs of the agent are kept, and partially staged ones are removed.
+func New(dir string, maxBytes int64, fileRoots []string) (*Cache, error) {
+	for _, root := range fileRoots {
+		if !filepath.IsAbs(root) {
+			return nil, fmt.Errorf("data cache file root %q is not an absolute path", root)
+		}
+	}
+	c := &Cache{
+		dir:       dir,
+		maxBytes:  maxBytes,
+		fileRoots: fileRoots,
+		log:       logrus.WithField("component", "data-cache"),
+		entries:   map[string]*entry{},
+	}
+
+	for _, sub := range []string{stagingDir, trashDir} {
+		if err := os.RemoveAll(filepath.Join(dir, sub)); err != nil {
+			return nil, fmt.Errorf("cleaning up data cache directory: %w", err)
+		}
+	}
+	for _, sub := range []string{datasetsDir, metadataDir, stagingDir, trashDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("creating data cache directory: %w", err)
+		}
+	}
+
+	datasets, err := os.ReadDir(filepath.Join(dir, datasetsDir))
+	if err != nil {
+		return nil, fmt.Errorf("reading data cache directory: %w", err)
+	}
+	for _, d := range datasets {
+		key := d.Name()
+		m, modTime, err := readMetadata(c.metadataPath(key))
+		if err != nil {
+			c.log.WithError(err).Infof("removing incomplete cached dataset %s", key)
+			c.remove(key)
+			continue
+		}
+		staged := make(chan struct{})
+		close(staged)
+		c.entries[key] = &entry{source: m.Source, size: m.Size, lastUsed: modTime, staged: staged}
+	}
+	metadatas, err := os.ReadDir(filepath.Join(dir, metadataDir))
+	if err != nil {
+		return nil, fmt.Errorf("reading data cache directory: %w", err)
+	}
+	for _, m := range metadatas {
+		if _, ok := c.entries[strings.TrimSuffix(m.Name(), ".json")]; !ok {
+			if err := os.Remove(filepath.Join(dir, metadataDir, m.Name())); err != nil {
+				c.log.WithError(err).Warnf("removing stale metadata %s", m.Name())
+			}
+		}
+	}
+
+	c.mu.Lock()
+	c.evictLocked()
+	c.mu.Unlock()
+	return c, nil
+}
+
+// Acquire returns the host path of a dataset, staging it first unless it is already cached or
+// being staged for another container. Progress of the staging is reported through logf. Every
+// successful call must be paired with a call to Release once the dataset is no longer mounted.
+//
+// The dataset is staged on a context of the cache rather than ctx, so that a container that stops
+// waiting for it doesn't fail the staging for the other containers waiting for it. The staging is
+// only canceled once no container waits for it.
+func (c *Cache) Acquire(
+	ctx context.Context, d cproto.StagedDataset, logf func(format string, args ...any),
+) (string, error) {
+	c.mu.Lock()
+	e, ok := c.entries[d.CacheKey]
+	for ok && e.canceled {
+		// Wait for the canceled staging to clean up before staging the dataset again.
+		c.mu.Unlock()
+		select {
+		case <-e.staged:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		c.mu.Lock()
+		e, ok = c.entries[d.CacheKey]
+	}
+	switch {
+	case ok && e.source != d.Source:
+		c.mu.Unlock()
+		return "", fmt.Errorf("cache key %s already holds %s, not %s", d.CacheKey, e.source, d.Source)
+	case ok:
+		e.refs++
+	default:
+		stageCtx, cancel := context.WithCancel(context.Background())
+		e = &entry{source: d.Source, refs: 1, staged: make(chan struct{}), cancel: cancel}
+		c.entries[d.CacheKey] = e
+		go c.stageEntry(stageCtx, e, d, logf)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-e.staged:
+	case <-ctx.Done():
+		c.release(d.CacheKey, e)
+		return "", ctx.Err()
+	}
+	if e.err != nil {
+		return "", e.err
+	}
+	if ok {
+		logf("using dataset %s from the data cache", d.CacheKey)
+	}
+	return c.path(d.CacheKey), nil
+}
+
+// stageEntry stages a dataset for the containers that acquire it while it is staged.
+func (c *Cache) stageEntry(
+	ctx context.Context, e *entry, d cproto.StagedDataset, logf func(format string, args ...any),
+) {
+	defer e.cancel()
+	size, err := c.stage(ctx, d, logf)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer close(e.staged)
+	if err != nil {
+		// Waiting containers fail with this error and the next one to acquire the key retries.
+		e.err = fmt.Errorf("staging dataset %s from %s: %w", d.CacheKey, d.Source, err)
+		delete(c.entries, d.CacheKey)
+		return
+	}
+	// The staging may have finished just as it was canceled.
+	e.canceled = false
+	e.size, e.lastUsed = size, time.Now()
+	c.evictLocked()
+}
+
+// AcquirePath takes a reference on the dataset cached at a host path, if there is one. It is used
+// for containers that are reattached after an agent restart, which mount datasets staged before.
+func (c *Cache) AcquirePath(hostPath string) (key string, ok bool) {
+	if filepath.Dir(filepath.Clean(hostPath)) != filepath.Join(c.dir, datasetsDir) {
+		return "", false
+	}
+	key = filepath.Base(hostPath)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	e.refs++
+	return key, true
+}
+
+// Release drops a reference on a dataset, which is evicted once unused if the cache is full.
+func (c *Cache) Release(key string) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok {
+		c.release(key, e)
+	}
+}
+
+// release drops a reference on the entry of a key, unless the entry was replaced since, and
+// cancels its staging if nothing waits for it anymore.
+func (c *Cache) release(key string, e *entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries[key] != e {
+		return
+	}
+	e.refs--
+	if e.refs == 0 && !isClosed(e.staged) {
+		e.canceled = true
+		e.cancel()
+		return
+	}
+	e.lastUsed = time.Now()
+	// The modification time of the metadata keeps the order of use across agent restarts.
+	if err := os.Chtimes(c.metadataPath(key), e.lastUsed, e.lastUsed); err != nil {
+		c.log.WithError(err).Debugf("touching metadata of dataset %s", key)
+	}
+	c.evictLocked()
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, datasetsDir, key)
+}
+
+func (c *Cache) metadataPath(key string) string {
+	return filepath.Join(c.dir, metadataDir, key+".json")
+}
+
+func (c *Cache) stage(
+	ctx context.Context, d cproto.StagedDataset, logf func(format string, args ...any),
+) (int64, error) {
+	partial := filepath.Join(c.dir, stagingDir, d.CacheKey)
+	if err := os.RemoveAll(partial); err != nil {
+		return 0, err
+	}
+	if err := os.Mkdir(partial, 0o755); err != nil {
+		return 0, err
+	}
+
+	p := newProgress(d, logf)
+	defer p.stop()
+	if err := fetch(ctx, d.Source, c.fileRoots, partial, p); err != nil {
+		if rErr := os.RemoveAll(partial); rErr != nil {
+			c.log.WithError(rErr).Warnf("removing partially staged dataset %s", d.CacheKey)
+		}
+		return 0, err
+	}
+	p.done()
+
+	// Rename the dataset into place before writing its metadata, which marks it complete.
+	if err := os.RemoveAll(c.path(d.CacheKey)); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(partial, c.path(d.CacheKey)); err != nil {
+		return 0, err
+	}
+	size := p.bytes.Load()
+	bs, err := json.Marshal(metadata{Source: d.Source, Size: size})
+	if err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(c.metadataPath(d.CacheKey), bs, 0o600); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// evictLocked removes unused datasets, least recently used first, until the cache fits its limit.
+func (c *Cache) evictLocked() {
+	if c.maxBytes == 0 {
+		return
+	}
+
+	var total int64
+	var unused []string
+	for key, e := range c.entries {
+		total += e.size
+		if e.refs == 0 && isClosed(e.staged) {
+			unused = append(unused, key)
+		}
+	}
+	sort.Slice(unused, func(i, j int) bool {
+		return c.entries[unused[i]].lastUsed.Before(c.entries[unused[j]].lastUsed)
+	})
+
+	for _, key := range unused {
+		if total <= c.maxBytes {
+			return
+		}
+		c.log.Infof("evicting dataset %s from the data cache", key)
+		total -= c.entries[key].size
+		delete(c.entries, key)
+		c.remove(key)
+	}
+	if total > c.maxBytes {
+		c.log.Warnf("data cache holds %d bytes of datasets in use, over its limit of %d bytes",
+			total, c.maxBytes)
+	}
+}
+
+// remove deletes the metadata of a dataset so it is no longer considered cached, then moves the
+// dataset aside and deletes it in the background, since large datasets take a while to delete.
+func (c *Cache) remove(key string) {
+	if err := os.Remove(c.metadataPath(key)); err != nil && !os.IsNotExist(err) {
+		c.log.WithError(err).Warnf("removing metadata of dataset %s", key)
+	}
+	trash := filepath.Join(c.dir, trashDir, fmt.Sprintf("%s.%d", key, time.Now().UnixNano()))
+	if err := os.Rename(c.path(key), trash); err != nil {
+		c.log.WithError(err).Warnf("removing dataset %s", key)
+		return
+	}
+	c.deletions.Add(1)
+	go func() {
+		defer c.deletions.Done()
+		if err := os.RemoveAll(trash); err != nil {
+			c.log.WithError(err).Warnf("removing dataset %s", key)
+		}
+	}()
+}
+
+func readMetadata(path string) (*metadata, time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	bs, err := os.ReadFile(path) // #nosec G304 // The path is in the data cache directory.
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var m metadata
+	if err := json.Unmarshal(bs, &m); err != nil {
+		return nil, time.Time{}, err
+	}
+	return &m, info.ModTime(), nil
+}
+
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
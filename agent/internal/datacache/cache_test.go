@@ -0,0 +1,203 @@
+package datacache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/pkg/cproto"
+)
+
+func nopLogf(string, ...any) {}
+
+// writeSource writes a dataset of the given files into a directory and returns its file:// URI.
+func writeSource(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	return "file://" + dir
+}
+
+func newTestCache(t *testing.T, dir string, maxBytes int64) *Cache {
+	c, err := New(dir, maxBytes, []string{os.TempDir()})
+	require.NoError(t, err)
+	t.Cleanup(c.deletions.Wait)
+	return c
+}
+
+func TestCacheStagesOnce(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCache(t, t.TempDir(), 0)
+	d := cproto.StagedDataset{
+		Source:    writeSource(t, map[string]string{"a.txt": "a", "nested/b.txt": "bb"}),
+		CacheKey:  "mnist",
+		MountPath: "/data/mnist",
+	}
+
+	path, err := c.Acquire(ctx, d, nopLogf)
+	require.NoError(t, err)
+	bs, err := os.ReadFile(filepath.Join(path, "nested", "b.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "bb", string(bs))
+
+	// The second container shares the staged dataset, even once its source is gone.
+	require.NoError(t, os.RemoveAll(d.Source[len("file://"):]))
+	samePath, err := c.Acquire(ctx, d, nopLogf)
+	require.NoError(t, err)
+	require.Equal(t, path, samePath)
+	require.Equal(t, 2, c.entries[d.CacheKey].refs)
+
+	c.Release(d.CacheKey)
+	c.Release(d.CacheKey)
+	require.Equal(t, 0, c.entries[d.CacheKey].refs)
+}
+
+func TestCacheKeySourceMismatch(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCache(t, t.TempDir(), 0)
+	d := cproto.StagedDataset{Source: writeSource(t, map[string]string{"a": "a"}), CacheKey: "key"}
+	_, err := c.Acquire(ctx, d, nopLogf)
+	require.NoError(t, err)
+
+	d.Source = writeSource(t, map[string]string{"b": "b"})
+	_, err = c.Acquire(ctx, d, nopLogf)
+	require.ErrorContains(t, err, "already holds")
+}
+
+func TestCacheFailedStagingRetries(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCache(t, t.TempDir(), 0)
+	d := cproto.StagedDataset{Source: "file:///does/not/exist", CacheKey: "key"}
+	_, err := c.Acquire(ctx, d, nopLogf)
+	require.Error(t, err)
+	require.NotContains(t, c.entries, d.CacheKey)
+
+	d.Source = writeSource(t, map[string]string{"a": "a"})
+	_, err = c.Acquire(ctx, d, nopLogf)
+	require.NoError(t, err)
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCache(t, t.TempDir(), 10)
+	acquire := func(key string) cproto.StagedDataset {
+		d := cproto.StagedDataset{Source: writeSource(t, map[string]string{"f": "12345"}), CacheKey: key}
+		_, err := c.Acquire(ctx, d, nopLogf)
+		require.NoError(t, err)
+		return d
+	}
+
+	a := acquire("a")
+	b := acquire("b")
+	c.Release(a.CacheKey)
+	c.Release(b.CacheKey)
+	require.Contains(t, c.entries, "a")
+	require.Contains(t, c.entries, "b")
+
+	// Datasets in use are never evicted, even if the cache is over its limit.
+	d := acquire("d")
+	require.NotContains(t, c.entries, "a")
+	require.Contains(t, c.entries, "b")
+	require.Contains(t, c.entries, "d")
+	c.Release(d.CacheKey)
+
+	c.deletions.Wait()
+	_, err := os.Stat(c.path("a"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestCacheSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	c := newTestCache(t, dir, 0)
+	d := cproto.StagedDataset{Source: writeSource(t, map[string]string{"f": "12345"}), CacheKey: "key"}
+	path, err := c.Acquire(ctx, d, nopLogf)
+	require.NoError(t, err)
+
+	// A dataset left partially staged by a crash is removed.
+	require.NoError(t, os.Mkdir(c.path("partial"), 0o755))
+
+	c = newTestCache(t, dir, 0)
+	require.Contains(t, c.entries, "key")
+	require.Equal(t, int64(5), c.entries["key"].size)
+	require.NotContains(t, c.entries, "partial")
+
+	key, ok := c.AcquirePath(path)
+	require.True(t, ok)
+	require.Equal(t, "key", key)
+	require.Equal(t, 1, c.entries["key"].refs)
+	_, ok = c.AcquirePath("/somewhere/else")
+	require.False(t, ok)
+}
+
+func TestCacheCanceledWhileWaiting(t *testing.T) {
+	c := newTestCache(t, t.TempDir(), 0)
+	stagingCanceled := false
+	// Another container waits for the dataset being staged.
+	e := &entry{
+		source:   "file:///src",
+		refs:     1,
+		staged:   make(chan struct{}),
+		lastUsed: time.Now(),
+		cancel:   func() { stagingCanceled = true },
+	}
+	c.entries["key"] = e
+
+	// A container that stops waiting doesn't cancel the staging for the other one.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := c.Acquire(ctx, cproto.StagedDataset{Source: "file:///src", CacheKey: "key"}, nopLogf)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 1, e.refs)
+	require.False(t, stagingCanceled)
+
+	// Once no container waits for it, the staging is canceled.
+	c.Release("key")
+	require.Equal(t, 0, e.refs)
+	require.True(t, stagingCanceled)
+	require.True(t, e.canceled)
+}
+
+func TestWriteFileRejectsEscapes(t *testing.T) {
+	dst := t.TempDir()
+	p := &progress{}
+	err := writeFile(context.Background(), dst, "../escape", nil, p)
+	require.ErrorContains(t, err, "outside of the dataset")
+}
+
+func TestResolveFileSource(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "dataset"), 0o755))
+	require.NoError(t, os.Mkdir(root+"-sibling", 0o755))
+	t.Cleanup(func() { _ = os.RemoveAll(root + "-sibling") })
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "link")))
+
+	for _, path := range []string{root, filepath.Join(root, "dataset")} {
+		resolved, err := resolveFileSource([]string{root}, path)
+		require.NoError(t, err)
+		require.Equal(t, path, resolved)
+	}
+	for _, path := range []string{
+		outside,
+		root + "-sibling",
+		filepath.Join(root, "..", filepath.Base(outside)),
+		// Links are followed before checking where a source is.
+		filepath.Join(root, "link"),
+	} {
+		_, err := resolveFileSource([]string{root}, path)
+		require.Error(t, err, path)
+	}
+	_, err := resolveFileSource(nil, filepath.Join(root, "dataset"))
+	require.ErrorContains(t, err, "not under a file root")
+
+	_, err = New(t.TempDir(), 0, []string{"relative"})
+	require.ErrorContains(t, err, "not an absolute path")
+}
@@ -0,0 +1,270 @@
+package datacache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/docker/go-units"
+	"google.golang.org/api/iterator"
+
+	s3checkpoints "github.com/determined-ai/determined/master/pkg/checkpoints/s3"
+	"github.com/determined-ai/determined/master/pkg/cproto"
+)
+
+// progressInterval is how often the progress of staging a dataset is logged.
+var progressInterval = 30 * time.Second
+
+// fetch downloads or copies every file under a source URI into dst. Sources are s3://bucket/prefix
+// and gs://bucket/prefix, with the credentials of the agent, or file:///path on the agent's host
+// under one of fileRoots.
+func fetch(ctx context.Context, source string, fileRoots []string, dst string, p *progress) error {
+	u, err := url.Parse(source)
+	if err != nil {
+		return fmt.Errorf("parsing source: %w", err)
+	}
+	prefix := strings.TrimPrefix(u.Path, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	switch u.Scheme {
+	case "s3":
+		return fetchS3(ctx, u.Host, prefix, dst, p)
+	case "gs":
+		return fetchGCS(ctx, u.Host, prefix, dst, p)
+	case "file":
+		src, err := resolveFileSource(fileRoots, u.Path)
+		if err != nil {
+			return err
+		}
+		return copyTree(ctx, src, dst, p)
+	default:
+		return fmt.Errorf("unsupported source scheme %q", u.Scheme)
+	}
+}
+
+func fetchS3(ctx context.Context, bucket, prefix, dst string, p *progress) error {
+	cfg := &aws.Config{}
+	// Buckets of S3-compatible stores have no region, which the session then finds in the
+	// environment of the agent instead.
+	if region, err := s3checkpoints.GetS3BucketRegion(ctx, bucket); err == nil && region != "" {
+		cfg.Region = &region
+	}
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return err
+	}
+	client := s3.New(sess)
+
+	var fetchErr error
+	err = client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: &bucket,
+		Prefix: &prefix,
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, o := range page.Contents {
+			key := aws.StringValue(o.Key)
+			if strings.HasSuffix(key, "/") {
+				continue
+			}
+			out, err := client.GetObjectWithContext(ctx, &s3.GetObjectInput{Bucket: &bucket, Key: o.Key})
+			if err != nil {
+				fetchErr = fmt.Errorf("getting s3://%s/%s: %w", bucket, key, err)
+				return false
+			}
+			err = writeFile(ctx, dst, strings.TrimPrefix(key, prefix), out.Body, p)
+			if cErr := out.Body.Close(); err == nil {
+				err = cErr
+			}
+			if err != nil {
+				fetchErr = fmt.Errorf("staging s3://%s/%s: %w", bucket, key, err)
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("listing s3://%s/%s: %w", bucket, prefix, err)
+	}
+	return fetchErr
+}
+
+func fetchGCS(ctx context.Context, bucket, prefix, dst string, p *progress) error {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	b := client.Bucket(bucket)
+	objects := b.Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := objects.Next()
+		if err == iterator.Done {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("listing gs://%s/%s: %w", bucket, prefix, err)
+		}
+		if strings.HasSuffix(attrs.Name, "/") {
+			continue
+		}
+
+		r, err := b.Object(attrs.Name).NewReader(ctx)
+		if err != nil {
+			return fmt.Errorf("getting gs://%s/%s: %w", bucket, attrs.Name, err)
+		}
+		err = writeFile(ctx, dst, strings.TrimPrefix(attrs.Name, prefix), r, p)
+		if cErr := r.Close(); err == nil {
+			err = cErr
+		}
+		if err != nil {
+			return fmt.Errorf("staging gs://%s/%s: %w", bucket, attrs.Name, err)
+		}
+	}
+}
+
+// resolveFileSource returns the directory a file:// source refers to once links are followed, if
+// it is under one of the roots. The agent reads sources as root, so without this a task could
+// stage any file of the host, whatever its user.
+func resolveFileSource(roots []string, path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	for _, root := range roots {
+		root, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not under a file root of the data cache of the agent", path)
+}
+
+func copyTree(ctx context.Context, src, dst string, p *progress) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			// Directories are created along with the files in them, and links are skipped so
+			// that a dataset can't reach outside of its source.
+			return nil
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(path) // #nosec G304 // Sources are under the roots the agent allows.
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		return writeFile(ctx, dst, rel, f, p)
+	})
+}
+
+// writeFile writes a file of the dataset, refusing names that would escape its directory.
+func writeFile(ctx context.Context, dst, name string, r io.Reader, p *progress) error {
+	path := filepath.Join(dst, filepath.FromSlash(name))
+	if !strings.HasPrefix(path, filepath.Clean(dst)+string(filepath.Separator)) {
+		return fmt.Errorf("file name %q is outside of the dataset", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644) // #nosec G304
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, &contextReader{ctx: ctx, r: r, p: p})
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		return err
+	}
+	p.files.Add(1)
+	return nil
+}
+
+// contextReader stops a copy once its context is canceled and counts the bytes it reads.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+	p   *progress
+}
+
+func (c *contextReader) Read(b []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := c.r.Read(b)
+	c.p.bytes.Add(int64(n))
+	return n, err
+}
+
+// progress counts what has been staged of a dataset and logs it periodically.
+type progress struct {
+	files atomic.Int64
+	bytes atomic.Int64
+
+	dataset cproto.StagedDataset
+	logf    func(format string, args ...any)
+	start   time.Time
+	stopped chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newProgress(d cproto.StagedDataset, logf func(format string, args ...any)) *progress {
+	p := &progress{dataset: d, logf: logf, start: time.Now(), stopped: make(chan struct{})}
+	logf("staging dataset %s from %s", d.CacheKey, d.Source)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		t := time.NewTicker(progressInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				logf("staging dataset %s: %d files, %s so far",
+					d.CacheKey, p.files.Load(), units.HumanSize(float64(p.bytes.Load())))
+			case <-p.stopped:
+				return
+			}
+		}
+	}()
+	return p
+}
+
+func (p *progress) done() {
+	p.logf("staged dataset %s: %d files, %s in %s", p.dataset.CacheKey, p.files.Load(),
+		units.HumanSize(float64(p.bytes.Load())), time.Since(p.start).Round(time.Second))
+}
+
+func (p *progress) stop() {
+	close(p.stopped)
+	p.wg.Wait()
+}
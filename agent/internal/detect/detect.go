@@ -14,6 +14,9 @@ import (
 	"github.com/determined-ai/determined/master/pkg/device"
 )
 
+// artificialBrand is the brand of artificial devices.
+const artificialBrand = "Artificial"
+
 // Detect the devices available. If artificial devices are configured, prefers those, otherwise,
 // we detect cuda, rocm, cpu (or no) devices based on the configured slot type.
 func Detect(slotType, agentID, visibleGPUs string, artificialSlots int) ([]device.Device, error) {
@@ -51,7 +54,7 @@ func Detect(slotType, agentID, visibleGPUs string, artificialSlots int) ([]devic
 			}
 			id := u.String()
 			detected = append(detected, device.Device{
-				ID: device.ID(i), Brand: "Artificial", UUID: id, Type: device.CPU,
+				ID: device.ID(i), Brand: artificialBrand, UUID: id, Type: device.CPU,
 			})
 		}
 	case slotType == "none":
@@ -110,6 +113,34 @@ func Detect(slotType, agentID, visibleGPUs string, artificialSlots int) ([]devic
 	return detected, nil
 }
 
+// TimeSlice returns the devices to advertise when each GPU is time-sliced into slices, which
+// replaces each GPU with a device per slice that has the UUID of the GPU and the ID
+// slices*ID+slice. Artificial devices are sliced like GPUs, to test time slicing without them.
+func TimeSlice(devices []device.Device, slices int) []device.Device {
+	if slices <= 1 {
+		return devices
+	}
+
+	var sliced []device.Device
+	for _, d := range devices {
+		if !IsTimeSliceable(d) {
+			sliced = append(sliced, d)
+			continue
+		}
+		for i := 0; i < slices; i++ {
+			slice := d
+			slice.ID = device.ID(slices*int(d.ID) + i)
+			sliced = append(sliced, slice)
+		}
+	}
+	return sliced
+}
+
+// IsTimeSliceable returns whether the device is time-sliced when the agent time-slices its GPUs.
+func IsTimeSliceable(d device.Device) bool {
+	return d.Type == device.CUDA || d.Type == device.ROCM || d.Brand == artificialBrand
+}
+
 // randFromString returns a random-number generated seeded from an input string.
 func randFromString(seed string) (*rand.Rand, error) {
 	h := sha256.New()
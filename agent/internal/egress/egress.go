@@ -0,0 +1,352 @@
+// Package egress restricts the network traffic that the containers of tasks send. Containers with
+// an egress spec run on a dedicated Docker network, where each one gets an address whose traffic
+// is filtered by an iptables chain that the agent creates with the container and removes after it
+// exits.
+package egress
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/sirupsen/logrus"
+
+	"github.com/determined-ai/determined/agent/internal/options"
+	"github.com/determined-ai/determined/master/pkg/cproto"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+const (
+	// dockerUserChain is where Docker lets users filter the traffic of its containers.
+	dockerUserChain = "DOCKER-USER"
+	// inputChain is where traffic to the addresses of the host itself is filtered, which Docker
+	// doesn't forward through DOCKER-USER.
+	inputChain = "INPUT"
+	// filterChain filters all traffic from the egress network. It lets replies through, jumps to
+	// the chain of the container that sent the traffic and drops everything else.
+	filterChain = "DET-EGRESS"
+	// containerChainPrefix prefixes the chains of containers. Chain names have at most 28 bytes.
+	containerChainPrefix = "DET-EGRESS-"
+	containerChainIDLen  = 16
+)
+
+// Networks is the part of the Docker client that manages networks.
+type Networks interface {
+	NetworkInspect(
+		ctx context.Context, networkID string, options types.NetworkInspectOptions,
+	) (types.NetworkResource, error)
+	NetworkCreate(
+		ctx context.Context, name string, options types.NetworkCreate,
+	) (types.NetworkCreateResponse, error)
+}
+
+// Firewall runs containers on the egress network and manages their iptables rules.
+type Firewall struct {
+	networks   Networks
+	name       string
+	subnet     netip.Prefix
+	masterHost string
+	masterPort int
+	log        *logrus.Entry
+
+	// iptables and lookup are replaced in tests.
+	iptables func(ctx context.Context, args ...string) (string, error)
+	lookup   func(ctx context.Context, host string) ([]netip.Addr, error)
+
+	mu sync.Mutex
+	// ready is set once the network and the filter chain exist. They are created when the first
+	// container with an egress spec starts, so that agents which never run one don't need
+	// iptables.
+	ready bool
+	// assigned are the addresses given to containers, which may not be connected to the network
+	// yet.
+	assigned map[cproto.ID]netip.Addr
+}
+
+// New returns a firewall for the egress network configured in opts. Containers on it can reach the
+// master at masterHost:masterPort.
+func New(
+	networks Networks, opts options.EgressOptions, masterHost string, masterPort int,
+) (*Firewall, error) {
+	subnet, err := netip.ParsePrefix(opts.Subnet)
+	if err != nil {
+		return nil, fmt.Errorf("parsing egress subnet: %w", err)
+	}
+	return &Firewall{
+		networks:   networks,
+		name:       opts.NetworkName,
+		subnet:     subnet.Masked(),
+		masterHost: masterHost,
+		masterPort: masterPort,
+		log:        logrus.WithField("component", "egress"),
+		iptables:   runIPTables,
+		lookup: func(ctx context.Context, host string) ([]netip.Addr, error) {
+			return net.DefaultResolver.LookupNetIP(ctx, "ip4", host)
+		},
+		assigned: make(map[cproto.ID]netip.Addr),
+	}, nil
+}
+
+// Apply restricts the traffic of a container to what its egress spec allows, by installing the
+// iptables rules of its address and moving it onto the egress network with that address. Hostnames
+// are resolved once, here, and added to the container's /etc/hosts, since the container can't
+// reach DNS servers.
+func (f *Firewall) Apply(ctx context.Context, id cproto.ID, spec *cproto.Spec) error {
+	rules, hosts, err := f.rules(ctx, spec.Egress.Allow)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.assigned[id]; ok {
+		return fmt.Errorf("container %s already has egress rules", id)
+	}
+	if err := f.setUp(ctx); err != nil {
+		return fmt.Errorf("setting up egress network %s: %w", f.name, err)
+	}
+	addr, err := f.assign(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := f.install(ctx, id, addr, rules); err != nil {
+		delete(f.assigned, id)
+		if rErr := f.remove(ctx, id); rErr != nil {
+			f.log.WithError(rErr).Warnf("cleaning up egress rules of container %s", id)
+		}
+		return err
+	}
+
+	spec.RunSpec.HostConfig.NetworkMode = container.NetworkMode(f.name)
+	spec.RunSpec.HostConfig.ExtraHosts = append(spec.RunSpec.HostConfig.ExtraHosts, hosts...)
+	spec.RunSpec.NetworkingConfig.EndpointsConfig = map[string]*network.EndpointSettings{
+		f.name: {IPAMConfig: &network.EndpointIPAMConfig{IPv4Address: addr.String()}},
+	}
+	f.log.Infof("container %s restricted to egress rules of %s", id, addr)
+	return nil
+}
+
+// Release removes the rules of a container after it exits. It does nothing for containers that
+// had no rules, so it is safe to call for every container, including reattached ones.
+func (f *Firewall) Release(ctx context.Context, id cproto.ID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.assigned, id)
+	return f.remove(ctx, id)
+}
+
+// rules returns the iptables rules of a container's chain, which allow the master and the
+// destinations of the allow list, and the /etc/hosts entries of the hostnames among them.
+func (f *Firewall) rules(ctx context.Context, allow []string) ([][]string, []string, error) {
+	var rules [][]string
+	var hosts []string
+
+	masterAddrs, err := f.resolve(ctx, f.masterHost, &hosts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("resolving master: %w", err)
+	}
+	for _, a := range masterAddrs {
+		rules = append(rules, []string{
+			"-d", a.String() + "/32", "-p", "tcp", "--dport", fmt.Sprint(f.masterPort), "-j", "RETURN",
+		})
+	}
+
+	for _, dest := range allow {
+		prefix, host, err := model.ParseEgressDestination(dest)
+		if err != nil {
+			return nil, nil, err
+		}
+		var prefixes []netip.Prefix
+		if host != "" {
+			addrs, err := f.resolve(ctx, host, &hosts)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, a := range addrs {
+				prefixes = append(prefixes, netip.PrefixFrom(a, 32))
+			}
+		} else if prefix.Addr().Is4() {
+			// The egress network has no IPv6, so IPv6 destinations can't be reached anyway.
+			prefixes = append(prefixes, prefix)
+		}
+		for _, p := range prefixes {
+			rules = append(rules, []string{"-d", p.String(), "-j", "RETURN"})
+		}
+	}
+	return rules, hosts, nil
+}
+
+// resolve returns the IPv4 addresses of a host, and adds a hosts entry for it if it is a hostname.
+func (f *Firewall) resolve(ctx context.Context, host string, hosts *[]string) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr.Unmap()}, nil
+	}
+	addrs, err := f.lookup(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s: %w", host, err)
+	}
+	var v4 []netip.Addr
+	for _, a := range addrs {
+		if a = a.Unmap(); a.Is4() {
+			v4 = append(v4, a)
+		}
+	}
+	if len(v4) == 0 {
+		return nil, fmt.Errorf("resolving %s: no IPv4 addresses", host)
+	}
+	*hosts = append(*hosts, host+":"+v4[0].String())
+	return v4, nil
+}
+
+// setUp creates the egress network and the filter chain if they don't exist yet, and sends the
+// traffic of the network to the filter chain.
+func (f *Firewall) setUp(ctx context.Context) error {
+	if f.ready {
+		return nil
+	}
+
+	res, err := f.networks.NetworkInspect(ctx, f.name, types.NetworkInspectOptions{})
+	switch {
+	case client.IsErrNotFound(err):
+		if _, err := f.networks.NetworkCreate(ctx, f.name, types.NetworkCreate{
+			CheckDuplicate: true,
+			Driver:         "bridge",
+			IPAM:           &network.IPAM{Config: []network.IPAMConfig{{Subnet: f.subnet.String()}}},
+		}); err != nil {
+			return fmt.Errorf("creating network: %w", err)
+		}
+		f.log.Infof("created egress network %s with subnet %s", f.name, f.subnet)
+	case err != nil:
+		return fmt.Errorf("inspecting network: %w", err)
+	case len(res.IPAM.Config) == 0 || res.IPAM.Config[0].Subnet != f.subnet.String():
+		return fmt.Errorf("network exists with IPAM config %v instead of subnet %s",
+			res.IPAM.Config, f.subnet)
+	}
+
+	if _, err := f.iptables(ctx, "-S", filterChain); err != nil {
+		for _, rule := range [][]string{
+			{"-N", filterChain},
+			{"-A", filterChain, "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "RETURN"},
+			{"-A", filterChain, "-j", "DROP"},
+		} {
+			if _, err := f.iptables(ctx, rule...); err != nil {
+				return err
+			}
+		}
+	}
+	// Traffic to other hosts passes DOCKER-USER and traffic to the host passes INPUT. Both are
+	// filtered, so that containers can't reach services of the host that their policy doesn't allow.
+	for _, chain := range []string{dockerUserChain, inputChain} {
+		jump := []string{chain, "-s", f.subnet.String(), "-j", filterChain}
+		if _, err := f.iptables(ctx, append([]string{"-C"}, jump...)...); err != nil {
+			if _, err := f.iptables(ctx, append([]string{"-I"}, jump...)...); err != nil {
+				return err
+			}
+		}
+	}
+
+	f.ready = true
+	return nil
+}
+
+// assign picks a free address on the egress network for a container. Addresses of containers
+// that are already connected to the network are looked up, so that containers which were started
+// before the agent restarted keep theirs.
+func (f *Firewall) assign(ctx context.Context, id cproto.ID) (netip.Addr, error) {
+	res, err := f.networks.NetworkInspect(ctx, f.name, types.NetworkInspectOptions{})
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("inspecting egress network %s: %w", f.name, err)
+	}
+	used := make(map[netip.Addr]bool)
+	for _, e := range res.Containers {
+		if p, err := netip.ParsePrefix(e.IPv4Address); err == nil {
+			used[p.Addr()] = true
+		}
+	}
+	for _, a := range f.assigned {
+		used[a] = true
+	}
+
+	// The first address is the network's and the second the gateway's; the last is broadcast.
+	for a := f.subnet.Addr().Next().Next(); f.subnet.Contains(a.Next()); a = a.Next() {
+		if !used[a] {
+			f.assigned[id] = a
+			return a, nil
+		}
+	}
+	return netip.Addr{}, fmt.Errorf("no free addresses in egress subnet %s", f.subnet)
+}
+
+// install creates the chain of a container and sends the traffic of its address to it.
+func (f *Firewall) install(ctx context.Context, id cproto.ID, addr netip.Addr, rules [][]string) error {
+	chain := containerChain(id)
+	if _, err := f.iptables(ctx, "-N", chain); err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if _, err := f.iptables(ctx, append([]string{"-A", chain}, rule...)...); err != nil {
+			return err
+		}
+	}
+	if _, err := f.iptables(ctx, "-A", chain, "-j", "DROP"); err != nil {
+		return err
+	}
+	// Jump after the rule that lets replies through, and before the rule that drops the rest.
+	_, err := f.iptables(ctx, "-I", filterChain, "2", "-s", addr.String()+"/32", "-j", chain)
+	return err
+}
+
+// remove deletes the chain of a container and the jumps to it, if there are any.
+func (f *Firewall) remove(ctx context.Context, id cproto.ID) error {
+	chain := containerChain(id)
+	if _, err := f.iptables(ctx, "-S", chain); err != nil {
+		return nil //nolint:nilerr // The container has no chain.
+	}
+
+	out, err := f.iptables(ctx, "-S", filterChain)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if !strings.HasPrefix(line, "-A ") || !strings.HasSuffix(line, " -j "+chain) {
+			continue
+		}
+		args := strings.Fields(line)
+		args[0] = "-D"
+		if _, err := f.iptables(ctx, args...); err != nil {
+			return err
+		}
+	}
+	if _, err := f.iptables(ctx, "-F", chain); err != nil {
+		return err
+	}
+	_, err = f.iptables(ctx, "-X", chain)
+	return err
+}
+
+func containerChain(id cproto.ID) string {
+	s := strings.ReplaceAll(string(id), "-", "")
+	if len(s) > containerChainIDLen {
+		s = s[:containerChainIDLen]
+	}
+	return containerChainPrefix + s
+}
+
+func runIPTables(ctx context.Context, args ...string) (string, error) {
+	// -w waits for the xtables lock instead of failing while Docker holds it.
+	out, err := exec.CommandContext(ctx, "iptables", append([]string{"-w"}, args...)...).
+		CombinedOutput() //nolint:gosec
+	if err != nil {
+		return "", fmt.Errorf("iptables %s: %w: %s",
+			strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
@@ -0,0 +1,196 @@
+package egress
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/agent/internal/options"
+	"github.com/determined-ai/determined/master/pkg/cproto"
+)
+
+type fakeNetworks struct {
+	created    *types.NetworkCreate
+	containers map[string]types.EndpointResource
+}
+
+func (n *fakeNetworks) NetworkInspect(
+	_ context.Context, _ string, _ types.NetworkInspectOptions,
+) (types.NetworkResource, error) {
+	if n.created == nil {
+		return types.NetworkResource{}, errdefs.NotFound(fmt.Errorf("no such network"))
+	}
+	return types.NetworkResource{IPAM: *n.created.IPAM, Containers: n.containers}, nil
+}
+
+func (n *fakeNetworks) NetworkCreate(
+	_ context.Context, _ string, options types.NetworkCreate,
+) (types.NetworkCreateResponse, error) {
+	n.created = &options
+	return types.NetworkCreateResponse{}, nil
+}
+
+// fakeIPTables keeps the rules of chains like iptables -S prints them.
+type fakeIPTables map[string][]string
+
+func (t fakeIPTables) run(_ context.Context, args ...string) (string, error) {
+	chain := args[1]
+	rules, exists := t[chain]
+	switch args[0] {
+	case "-N":
+		if exists {
+			return "", fmt.Errorf("chain %s already exists", chain)
+		}
+		t[chain] = nil
+		return "", nil
+	case "-X":
+		delete(t, chain)
+		return "", nil
+	}
+	if !exists && chain != dockerUserChain && chain != inputChain {
+		return "", fmt.Errorf("no chain %s", chain)
+	}
+
+	rule := "-A " + strings.Join(args[1:], " ")
+	switch args[0] {
+	case "-S":
+		return strings.Join(append([]string{"-N " + chain}, rules...), "\n"), nil
+	case "-A":
+		t[chain] = append(rules, rule)
+	case "-I":
+		if len(args) > 2 && args[2] == "2" {
+			rule = "-A " + chain + " " + strings.Join(args[3:], " ")
+			t[chain] = append(rules[:1:1], append([]string{rule}, rules[1:]...)...)
+		} else {
+			t[chain] = append([]string{rule}, rules...)
+		}
+	case "-C", "-D":
+		for i, r := range rules {
+			if r == rule {
+				if args[0] == "-D" {
+					t[chain] = append(rules[:i:i], rules[i+1:]...)
+				}
+				return "", nil
+			}
+		}
+		return "", fmt.Errorf("no rule %q", rule)
+	case "-F":
+		t[chain] = nil
+	}
+	return "", nil
+}
+
+func newTestFirewall(t *testing.T) (*Firewall, *fakeNetworks, fakeIPTables) {
+	networks := &fakeNetworks{containers: map[string]types.EndpointResource{
+		// A container that was started before the agent restarted.
+		"running": {IPv4Address: "10.9.0.2/29"},
+	}}
+	f, err := New(networks, options.EgressOptions{NetworkName: "egress", Subnet: "10.9.0.0/29"},
+		"master.example.com", 8080)
+	require.NoError(t, err)
+	rules := fakeIPTables{}
+	f.iptables = rules.run
+	f.lookup = func(_ context.Context, host string) ([]netip.Addr, error) {
+		switch host {
+		case "master.example.com":
+			return []netip.Addr{netip.MustParseAddr("192.0.2.1")}, nil
+		case "pypi.org":
+			return []netip.Addr{netip.MustParseAddr("198.51.100.7"), netip.MustParseAddr("2001:db8::1")}, nil
+		}
+		return nil, fmt.Errorf("no such host %s", host)
+	}
+	return f, networks, rules
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	f, networks, rules := newTestFirewall(t)
+
+	spec := cproto.Spec{Egress: &cproto.EgressSpec{Allow: []string{"10.1.0.0/16", "pypi.org", "fd00::/8"}}}
+	require.NoError(t, f.Apply(ctx, "0123456789abcdef-0123", &spec))
+
+	require.Equal(t, "10.9.0.0/29", networks.created.IPAM.Config[0].Subnet)
+	require.Equal(t, "egress", string(spec.RunSpec.HostConfig.NetworkMode))
+	require.Equal(t, []string{"master.example.com:192.0.2.1", "pypi.org:198.51.100.7"},
+		spec.RunSpec.HostConfig.ExtraHosts)
+	// The first free address is taken, after the gateway and the container that already runs.
+	require.Equal(t, "10.9.0.3",
+		spec.RunSpec.NetworkingConfig.EndpointsConfig["egress"].IPAMConfig.IPv4Address)
+
+	const chain = "DET-EGRESS-0123456789abcdef"
+	require.Equal(t, []string{"-A DOCKER-USER -s 10.9.0.0/29 -j DET-EGRESS"}, rules[dockerUserChain])
+	require.Equal(t, []string{"-A INPUT -s 10.9.0.0/29 -j DET-EGRESS"}, rules[inputChain])
+	require.Equal(t, []string{
+		"-A DET-EGRESS -m conntrack --ctstate RELATED,ESTABLISHED -j RETURN",
+		"-A DET-EGRESS -s 10.9.0.3/32 -j " + chain,
+		"-A DET-EGRESS -j DROP",
+	}, rules[filterChain])
+	require.Equal(t, []string{
+		"-A " + chain + " -d 192.0.2.1/32 -p tcp --dport 8080 -j RETURN",
+		"-A " + chain + " -d 10.1.0.0/16 -j RETURN",
+		"-A " + chain + " -d 198.51.100.7/32 -j RETURN",
+		"-A " + chain + " -j DROP",
+	}, rules[chain])
+
+	// Master only.
+	other := cproto.Spec{Egress: &cproto.EgressSpec{}}
+	require.NoError(t, f.Apply(ctx, "other", &other))
+	require.Equal(t, "10.9.0.4",
+		other.RunSpec.NetworkingConfig.EndpointsConfig["egress"].IPAMConfig.IPv4Address)
+	require.Equal(t, []string{
+		"-A DET-EGRESS-other -d 192.0.2.1/32 -p tcp --dport 8080 -j RETURN",
+		"-A DET-EGRESS-other -j DROP",
+	}, rules["DET-EGRESS-other"])
+
+	// The subnet is full: .0 is the network's, .1 the gateway's and .7 broadcast.
+	for _, id := range []cproto.ID{"a", "b"} {
+		require.NoError(t, f.Apply(ctx, id, &cproto.Spec{Egress: &cproto.EgressSpec{}}))
+	}
+	require.ErrorContains(t, f.Apply(ctx, "c", &cproto.Spec{Egress: &cproto.EgressSpec{}}),
+		"no free addresses")
+
+	require.NoError(t, f.Release(ctx, "0123456789abcdef-0123"))
+	require.NotContains(t, rules, chain)
+	require.Equal(t, []string{
+		"-A DET-EGRESS -m conntrack --ctstate RELATED,ESTABLISHED -j RETURN",
+		"-A DET-EGRESS -s 10.9.0.6/32 -j DET-EGRESS-b",
+		"-A DET-EGRESS -s 10.9.0.5/32 -j DET-EGRESS-a",
+		"-A DET-EGRESS -s 10.9.0.4/32 -j DET-EGRESS-other",
+		"-A DET-EGRESS -j DROP",
+	}, rules[filterChain])
+	// Its address is free again.
+	require.NoError(t, f.Apply(ctx, "c", &cproto.Spec{Egress: &cproto.EgressSpec{}}))
+
+	// Containers without rules are released without errors.
+	require.NoError(t, f.Release(ctx, "unrestricted"))
+}
+
+func TestApplyFailures(t *testing.T) {
+	ctx := context.Background()
+	f, _, rules := newTestFirewall(t)
+
+	err := f.Apply(ctx, "a", &cproto.Spec{Egress: &cproto.EgressSpec{Allow: []string{"unknown.example.com"}}})
+	require.ErrorContains(t, err, "no such host")
+	require.Empty(t, f.assigned)
+
+	require.NoError(t, f.Apply(ctx, "a", &cproto.Spec{Egress: &cproto.EgressSpec{}}))
+	require.ErrorContains(t, f.Apply(ctx, "a", &cproto.Spec{Egress: &cproto.EgressSpec{}}),
+		"already has egress rules")
+	require.Len(t, rules["DET-EGRESS-a"], 2)
+
+	// The network of another subnet must not be reused.
+	other, err := New(&fakeNetworks{created: &types.NetworkCreate{
+		IPAM: &network.IPAM{Config: []network.IPAMConfig{{Subnet: "172.20.0.0/16"}}},
+	}}, options.EgressOptions{NetworkName: "egress", Subnet: "10.9.0.0/29"}, "192.0.2.1", 8080)
+	require.NoError(t, err)
+	other.iptables = rules.run
+	require.ErrorContains(t, other.Apply(ctx, "b", &cproto.Spec{Egress: &cproto.EgressSpec{}}),
+		"instead of subnet 10.9.0.0/29")
+}
@@ -3,6 +3,7 @@ package options
 import (
 	"crypto/tls"
 	"encoding/json"
+	"net/netip"
 	"reflect"
 
 	"github.com/determined-ai/determined/master/pkg/check"
@@ -60,6 +61,9 @@ type Options struct {
 	SingularityOptions SingularityOptions `json:"singularity_options"`
 	PodmanOptions      PodmanOptions      `json:"podman_options"`
 
+	DataCache DataCacheOptions `json:"data_cache"`
+	Egress    EgressOptions    `json:"egress"`
+
 	Debug bool `json:"debug"`
 }
 
@@ -86,6 +90,8 @@ func (o Options) Validate() []error {
 		o.validateTLS(),
 		check.In(o.SlotType, []string{"gpu", "cuda", "rocm", "cpu", "auto", "none"}),
 		check.NotEmpty(o.MasterHost, "master host must be provided"),
+		check.GreaterThanOrEqualTo(o.DataCache.MaxSizeGB, 0, "data cache max size must be >= 0"),
+		o.Egress.validate(),
 	}
 }
 
@@ -191,3 +197,40 @@ type SingularityOptions struct {
 type PodmanOptions struct {
 	AllowNetworkCreation bool `json:"allow_network_creation"` // review
 }
+
+// DataCacheOptions configures the node-local cache that datasets are staged into for tasks which
+// set resources.staged_data. Data staging is disabled unless a directory is set.
+type DataCacheOptions struct {
+	Dir string `json:"dir"`
+	// MaxSizeGB limits the size of the unused datasets kept in the cache, or is unlimited if zero.
+	MaxSizeGB int `json:"max_size_gb"`
+	// FileRoots are the directories of the agent's host that file:// sources may be under. The
+	// agent reads them with its own permissions rather than those of the task's user, so file://
+	// sources are rejected unless roots are set.
+	FileRoots []string `json:"file_roots"`
+}
+
+// EgressOptions configures the Docker network that containers of tasks with an egress policy run
+// on. Their traffic is filtered by iptables rules the agent manages, so the agent must be able to
+// run iptables in the network namespace of the host. Egress policies can't be enforced if unset.
+type EgressOptions struct {
+	NetworkName string `json:"network_name"`
+	// Subnet is the subnet of the network, which the agent assigns the addresses of containers
+	// from. It must not overlap with other networks of the host.
+	Subnet string `json:"subnet"`
+}
+
+func (e EgressOptions) validate() error {
+	if e == (EgressOptions{}) {
+		return nil
+	}
+	subnet, err := netip.ParsePrefix(e.Subnet)
+	if err != nil || !subnet.Addr().Is4() || subnet.Bits() > 29 {
+		return errors.Errorf("egress subnet must be an IPv4 CIDR of at least 8 addresses, got %q",
+			e.Subnet)
+	}
+	if e.NetworkName == "" {
+		return errors.New("egress network name must be provided")
+	}
+	return nil
+}
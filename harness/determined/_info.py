@@ -5,6 +5,7 @@ from typing import Any, Dict, Iterable, List, Optional, Union
 from determined import gpu
 
 DEFAULT_RENDEZVOUS_INFO_PATH = "/run/determined/info/rendezvous.json"
+DEFAULT_RENDEZVOUS_ENV_PATH = "/run/determined/info/rendezvous.env"
 DEFAULT_TRIAL_INFO_PATH = "/run/determined/info/trial.json"
 DEFAULT_RESOURCES_INFO_PATH = "/run/determined/info/resources.json"
 DEFAULT_CLUSTER_INFO_PATH = "/run/determined/info/cluster.json"
@@ -47,6 +48,21 @@ class RendezvousInfo:
         with open(path, "w") as f:
             json.dump(vars(self), f)
 
+    def _to_env_file(self, path: str = DEFAULT_RENDEZVOUS_ENV_PATH) -> None:
+        """
+        _to_env_file writes the RendezvousInfo as a shell script of exported variables, so that
+        the entrypoints of commands and shells, and the users of shells, can source it.
+        """
+        env = {
+            "DET_CONTAINER_RANK": str(self.container_rank),
+            "DET_CHIEF_IP": self.container_addrs[0],
+            "DET_CONTAINER_ADDRS": " ".join(self.container_addrs),
+            "DET_CONTAINER_SLOT_COUNTS": " ".join(str(c) for c in self.container_slot_counts),
+        }
+        with open(path, "w") as f:
+            for k, v in env.items():
+                f.write(f'export {k}="{v}"\n')
+
     @classmethod
     def _from_file(
         cls,
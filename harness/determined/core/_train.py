@@ -1,3 +1,4 @@
+import datetime
 import enum
 import logging
 import pathlib
@@ -17,6 +18,16 @@ class EarlyExitReason(enum.Enum):
     USER_REQUESTED_STOP = "EXITED_REASON_USER_REQUESTED_STOP"
 
 
+def _metadata_value(value: Any) -> Any:
+    if isinstance(value, datetime.datetime):
+        if value.tzinfo is None:
+            value = value.replace(tzinfo=datetime.timezone.utc)
+        return {"type": "timestamp", "value": value.isoformat()}
+    if value is not None and not isinstance(value, (str, bool, int, float)):
+        raise TypeError(f"unsupported metadata value {value!r} of type {type(value).__name__}")
+    return value
+
+
 class TrainContext:
     """
     ``TrainContext`` gives access to report training and validation metrics to the Determined master
@@ -55,6 +66,18 @@ class TrainContext:
         logger.debug(f"set_status({status})")
         self._session.post(f"/api/v1/trials/{self._trial_id}/runner/metadata", json=body)
 
+    def set_metadata(self, metadata: Dict[str, Any]) -> None:
+        """
+        Set typed metadata keys of the trial, which experiment searches can filter and sort on.
+
+        Values may be strings, numbers, bools or ``datetime.datetime`` timestamps, where naive
+        timestamps are taken to be in UTC. A ``None`` value removes the key.
+        """
+
+        body = {k: _metadata_value(v) for k, v in metadata.items()}
+        logger.debug(f"set_metadata({metadata})")
+        self._session.patch(f"/trials/{self._trial_id}/metadata", json=body)
+
     def _get_last_validation(self) -> Optional[int]:
         # This is needed by the workload sequencer, but it is not generally stable, because it is
         # easy to call this before reporting any metrics.  If your last checkpoint was older than
@@ -265,6 +288,9 @@ class DummyTrainContext(TrainContext):
     def set_status(self, status: str) -> None:
         logger.info(f"status: {status}")
 
+    def set_metadata(self, metadata: Dict[str, Any]) -> None:
+        logger.info(f"set_metadata({metadata})")
+
     def _get_last_validation(self) -> Optional[int]:
         return None
 
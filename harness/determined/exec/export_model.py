@@ -0,0 +1,134 @@
+"""
+The entrypoint for the model version export container.
+"""
+import argparse
+import json
+import logging
+import os
+import pathlib
+import subprocess
+import sys
+import tempfile
+from typing import Any, List
+
+import determined as det
+from determined import errors
+from determined.common import constants, storage
+
+logger = logging.getLogger("determined")
+
+
+def export(
+    manager: storage.StorageManager,
+    export_uuid: str,
+    checkpoints: List[Any],
+    command: List[str],
+    workdir: pathlib.Path,
+) -> None:
+    """
+    Download the checkpoints of a model version, run the export command on them and upload what
+    it writes to the output directory under the export UUID.
+    """
+    input_dir = workdir.joinpath("input")
+    output_dir = workdir.joinpath("output")
+    input_dir.mkdir(parents=True, exist_ok=True)
+    output_dir.mkdir(parents=True, exist_ok=True)
+
+    for ckpt in checkpoints:
+        logger.info(f"Downloading checkpoint {ckpt['uuid']}")
+        manager.download(ckpt["uuid"], input_dir.joinpath(ckpt["dir"]))
+
+    env = dict(os.environ)
+    env["DET_EXPORT_INPUT_DIR"] = str(input_dir)
+    env["DET_EXPORT_OUTPUT_DIR"] = str(output_dir)
+    logger.info(f"Running export command: {command}")
+    subprocess.run(command, env=env, check=True)
+
+    if not any(output_dir.iterdir()):
+        raise RuntimeError("export command did not write anything to $DET_EXPORT_OUTPUT_DIR")
+    logger.info(f"Uploading export {export_uuid}")
+    manager.upload(output_dir, export_uuid)
+
+
+def delete(manager: storage.StorageManager, export_uuids: List[str]) -> None:
+    """
+    Delete the files of exports of model versions that were deleted.
+    """
+    for export_uuid in export_uuids:
+        logger.info(f"Deleting export {export_uuid}")
+        try:
+            manager.delete(export_uuid, ["**/*"])
+        except errors.CheckpointNotFound as e:
+            logger.warning(e)
+
+
+def json_file_arg(val: str) -> Any:
+    with open(val) as f:
+        return json.load(f)
+
+
+def main(argv: List[str]) -> None:
+    parser = argparse.ArgumentParser(description="Determined model version export")
+
+    parser.add_argument(
+        "--version",
+        action="version",
+        version=f"Determined model version export, version {det.__version__}",
+    )
+    parser.add_argument("--export-uuid", help="The UUID to store the export under")
+    parser.add_argument(
+        "--log-level",
+        default=os.getenv("DET_LOG_LEVEL", "INFO"),
+        choices=["DEBUG", "INFO", "WARNING", "ERROR"],
+        help="Set the logging level",
+    )
+    parser.add_argument(
+        "--storage-config",
+        type=json_file_arg,
+        required=True,
+        help="Storage config (JSON-formatted file)",
+    )
+    parser.add_argument(
+        "--checkpoints",
+        type=json_file_arg,
+        help="Checkpoints to export and their directories (JSON-formatted file)",
+    )
+    parser.add_argument(
+        "--command",
+        type=json_file_arg,
+        help="Export command (JSON-formatted file)",
+    )
+    parser.add_argument(
+        "--delete",
+        type=json_file_arg,
+        help="UUIDs of exports to delete instead of exporting (JSON-formatted file)",
+    )
+
+    args = parser.parse_args(argv)
+    if args.delete is None and None in (args.export_uuid, args.checkpoints, args.command):
+        parser.error(
+            "--export-uuid, --checkpoints and --command are required unless --delete is given"
+        )
+
+    logging.basicConfig(
+        level=args.log_level, format="%(asctime)s:%(module)s:%(levelname)s: %(message)s"
+    )
+
+    logger.info(f"Determined model version export, version {det.__version__}")
+
+    storage_config = args.storage_config
+    masked_config = json.dumps(det.util.mask_checkpoint_storage(storage_config))
+    logger.info(f"Using checkpoint storage: {masked_config}")
+
+    manager = storage.build(storage_config, container_path=constants.SHARED_FS_CONTAINER_PATH)
+
+    if args.delete is not None:
+        delete(manager, args.delete)
+        return
+
+    with tempfile.TemporaryDirectory() as workdir:
+        export(manager, args.export_uuid, args.checkpoints, args.command, pathlib.Path(workdir))
+
+
+if __name__ == "__main__":
+    main(sys.argv[1:])
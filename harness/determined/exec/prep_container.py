@@ -216,6 +216,24 @@ def do_rendezvous(sess: api.Session, allocation_id: str) -> None:
         raise ValueError(f"unsupported resources type: {r_type}")
 
     rendezvous_info._to_file()
+    rendezvous_info._to_env_file()
+
+
+def mark_daemon(sess: api.Session, allocation_id: str) -> None:
+    # Daemon resources are killed by the master once every non-daemon resource has exited, which
+    # is how the workers of multi-container commands and shells exit with their chief.
+    r_id = os.environ.get("DET_RESOURCES_ID")
+    assert r_id, "Unable to mark resources as daemon without DET_RESOURCES_ID"
+
+    bindings.post_MarkAllocationResourcesDaemon(
+        sess,
+        allocationId=allocation_id,
+        resourcesId=r_id,
+        body=bindings.v1MarkAllocationResourcesDaemonRequest(
+            allocationId=allocation_id,
+            resourcesId=r_id,
+        ),
+    )
 
 
 def proxy_ifaces() -> List[str]:
@@ -270,6 +288,7 @@ if __name__ == "__main__":
     parser.add_argument("--resources", action="store_true")
     parser.add_argument("--rendezvous", action="store_true")
     parser.add_argument("--proxy", action="store_true")
+    parser.add_argument("--daemon", action="store_true")
     parser.add_argument("--notify_container_running", action="store_true")
     parser.add_argument(
         "--download_context_directory",
@@ -373,3 +392,6 @@ if __name__ == "__main__":
 
     if args.proxy:
         do_proxy(sess, info.allocation_id)
+
+    if args.daemon:
+        mark_daemon(sess, info.allocation_id)
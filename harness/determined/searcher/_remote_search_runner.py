@@ -1,11 +1,14 @@
+import json
 import logging
 import os
 import pickle
+import time
 from pathlib import Path
 from typing import Any, Dict, Iterable, List, Optional, Tuple, Union
 
 import determined as det
 from determined import searcher
+from determined.common.api import authentication, bindings, request
 from determined.experimental import client
 
 logger = logging.getLogger("determined.searcher")
@@ -74,6 +77,123 @@ class RemoteSearchRunner(searcher.SearchRunner):
 
         return experiment_id
 
+    def run_experiment(
+        self,
+        experiment_id: int,
+        session: client.Session,
+        prior_operations: Optional[List[searcher.Operation]],
+        sleep_time: float = 1.0,
+    ) -> None:
+        """
+        Receive searcher events over the searcher stream of the experiment instead of polling for
+        them, reconnecting whenever the stream drops. The master resends the events whose
+        operations it didn't get, so they are handled the same way as after a restart.
+        """
+        experiment_is_active = True
+        try:
+            while experiment_is_active:
+                try:
+                    experiment_is_active, prior_operations = self._run_stream(
+                        experiment_id, session, prior_operations
+                    )
+                except _SearcherStreamError as e:
+                    logger.warning(f"searcher stream of experiment {experiment_id} failed: {e}")
+                if experiment_is_active:
+                    time.sleep(sleep_time)
+        except KeyboardInterrupt:
+            print("Runner interrupted")
+
+    def _run_stream(
+        self,
+        experiment_id: int,
+        session: client.Session,
+        prior_operations: Optional[List[searcher.Operation]],
+    ) -> Tuple[bool, Optional[List[searcher.Operation]]]:
+        """
+        Handle events from one connection of the searcher stream until it closes. Returns whether
+        the experiment may still be active and the operations posted for the last event handled.
+        """
+        import lomond
+
+        # The WebSocket carries the messages of the StreamSearcherEvents RPC as JSON.
+        url = request.make_url(session._master, "experiments/searcher-stream")
+        ws = lomond.WebSocket(request.maybe_upgrade_ws_scheme(url))
+        auth = session._auth or authentication.cli_auth
+        if auth is not None:
+            ws.add_header(b"Authorization", f"Bearer {auth.get_session_token()}".encode())
+        session_class = lomond.session.WebsocketSession
+        if session._cert is not None:
+            from determined.cli import proxy
+
+            cert = session._cert
+            session_class = lambda socket: proxy.CustomSSLWebsocketSession(  # noqa: E731
+                socket, cert.bundle, cert.name
+            )
+
+        # The first event of a connection may be one that was already handled, if the runner
+        # stopped after saving its state but before the master got the operations.
+        first_event = True
+        try:
+            for ws_event in ws.connect(ping_rate=0, session_class=session_class):
+                if isinstance(
+                    ws_event,
+                    (
+                        lomond.events.ConnectFail,
+                        lomond.events.Rejected,
+                        lomond.events.ProtocolError,
+                    ),
+                ):
+                    raise _SearcherStreamError(str(ws_event))
+                if isinstance(ws_event, (lomond.events.Closing, lomond.events.Disconnected)):
+                    return True, prior_operations
+                if isinstance(ws_event, lomond.events.Ready):
+                    ws.send_json({"subscribe": {"experimentId": experiment_id}})
+                    continue
+                if not isinstance(ws_event, lomond.events.Text):
+                    continue
+
+                msg = json.loads(ws_event.text)
+                if msg.get("error"):
+                    # See post_operations for why closing a trial that is already gone is fine.
+                    if "could not be found" not in msg["error"]:
+                        raise RuntimeError(f"searcher stream error: {msg['error']}")
+                    logger.warning(f"searcher stream error: {msg['error']}")
+                events = [
+                    bindings.v1SearcherEvent.from_json(e) for e in msg.get("searcherEvents") or []
+                ]
+                if not events:
+                    continue
+                logger.info(json.dumps([self._searcher_event_as_dict(e) for e in events]))
+                for event in events:
+                    last_event_id = self.state.last_event_id
+                    if (
+                        first_event
+                        and last_event_id != 0
+                        and last_event_id >= event.id >= 0
+                        and prior_operations is not None
+                    ):
+                        logger.info(f"Resubmitting operations for event.id={event.id}")
+                        operations = prior_operations
+                    else:
+                        if event.experimentInactive:
+                            return self._on_experiment_inactive(event), prior_operations
+                        operations = self._get_operations(event)
+                        self.state.last_event_id = event.id
+                        self.save_state(experiment_id, operations)
+                        prior_operations = operations
+
+                    first_event = False
+                    body = bindings.v1PostSearcherOperationsRequest(
+                        experimentId=experiment_id,
+                        searcherOperations=[op._to_searcher_operation() for op in operations],
+                        triggeredByEvent=event,
+                    )
+                    ws.send_json({"operations": body.to_json(omit_unset=True)})
+        finally:
+            if not ws.is_closed:
+                ws.close()
+        return True, prior_operations
+
     def load_state(self, storage_id: str) -> Tuple[int, List[searcher.Operation]]:
         with self.context.checkpoint.restore_path(storage_id) as path:
             self.state, experiment_id = self.search_method.load(path)
@@ -96,3 +216,7 @@ class RemoteSearchRunner(searcher.SearchRunner):
             "your search method will automatically resume when the experiment "
             "becomes active again."
         )
+
+
+class _SearcherStreamError(Exception):
+    pass
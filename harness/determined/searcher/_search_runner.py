@@ -148,28 +148,7 @@ class SearchRunner:
                         operations = prior_operations
                     else:
                         if event.experimentInactive:
-                            logger.info(
-                                f"experiment {self.state.experiment_id} is "
-                                f"inactive; state={event.experimentInactive.experimentState}"
-                            )
-                            if (
-                                event.experimentInactive.experimentState
-                                == bindings.experimentv1State.COMPLETED
-                            ):
-                                self.state.experiment_completed = True
-                            elif (
-                                event.experimentInactive.experimentState
-                                == bindings.experimentv1State.ERROR
-                            ):
-                                self.state.experiment_failed = True
-
-                            if (
-                                event.experimentInactive.experimentState
-                                == bindings.experimentv1State.PAUSED
-                            ):
-                                self._show_experiment_paused_msg()
-                            else:
-                                experiment_is_active = False
+                            experiment_is_active = self._on_experiment_inactive(event)
                             break
 
                         operations = self._get_operations(event)
@@ -184,6 +163,26 @@ class SearchRunner:
         except KeyboardInterrupt:
             print("Runner interrupted")
 
+    def _on_experiment_inactive(self, event: bindings.v1SearcherEvent) -> bool:
+        """
+        Record that the experiment is no longer active and return whether it may become active
+        again, which it only does if it was paused.
+        """
+        assert event.experimentInactive is not None
+        logger.info(
+            f"experiment {self.state.experiment_id} is "
+            f"inactive; state={event.experimentInactive.experimentState}"
+        )
+        if event.experimentInactive.experimentState == bindings.experimentv1State.COMPLETED:
+            self.state.experiment_completed = True
+        elif event.experimentInactive.experimentState == bindings.experimentv1State.ERROR:
+            self.state.experiment_failed = True
+
+        if event.experimentInactive.experimentState == bindings.experimentv1State.PAUSED:
+            self._show_experiment_paused_msg()
+            return True
+        return False
+
     def post_operations(
         self,
         session: client.Session,
@@ -0,0 +1,45 @@
+import datetime
+from unittest import mock
+
+import pytest
+
+from determined import core
+
+
+def make_test_train_context() -> core.TrainContext:
+    return core.TrainContext(
+        session=mock.MagicMock(),
+        trial_id=1,
+        run_id=2,
+        exp_id=3,
+        distributed=core.DummyDistributedContext(),
+        tensorboard_mode=core.TensorboardMode.MANUAL,
+        tensorboard_manager=None,
+        tbd_writer=None,
+    )
+
+
+def test_set_metadata() -> None:
+    train = make_test_train_context()
+    train.set_metadata(
+        {
+            "dataset": "v3",
+            "rows": 1000,
+            "cleaned": True,
+            "snapshot": datetime.datetime(2023, 12, 1),
+            "stale": None,
+        }
+    )
+    train._session.patch.assert_called_once_with(  # type: ignore
+        "/trials/1/metadata",
+        json={
+            "dataset": "v3",
+            "rows": 1000,
+            "cleaned": True,
+            "snapshot": {"type": "timestamp", "value": "2023-12-01T00:00:00+00:00"},
+            "stale": None,
+        },
+    )
+
+    with pytest.raises(TypeError, match="unsupported metadata value"):
+        train.set_metadata({"tags": ["a"]})
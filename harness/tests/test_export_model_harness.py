@@ -0,0 +1,55 @@
+import pathlib
+import sys
+import uuid
+
+import pytest
+
+from determined.common import storage
+from determined.exec.export_model import delete, export
+from tests.storage import util as storage_util
+
+COPY_INPUT = [
+    sys.executable,
+    "-c",
+    "import os, shutil; "
+    "shutil.copytree(os.environ['DET_EXPORT_INPUT_DIR'], "
+    "os.path.join(os.environ['DET_EXPORT_OUTPUT_DIR'], 'model'))",
+]
+
+
+@pytest.fixture()
+def manager(tmp_path: pathlib.Path) -> storage.StorageManager:
+    base_path = tmp_path.joinpath("storage")
+    base_path.mkdir()
+    return storage.SharedFSStorageManager(str(base_path))
+
+
+def test_export(manager: storage.StorageManager, tmp_path: pathlib.Path) -> None:
+    checkpoints = []
+    for i in range(2):
+        storage_id = str(uuid.uuid4())
+        with manager.store_path(storage_id) as path:
+            storage_util.create_checkpoint(path)
+        checkpoints.append({"uuid": storage_id, "dir": f"{i}-{storage_id}"})
+
+    export_uuid = str(uuid.uuid4())
+    export(manager, export_uuid, checkpoints, COPY_INPUT, tmp_path.joinpath("work"))
+
+    exported = pathlib.Path(manager._base_path).joinpath(export_uuid, "model")
+    assert sorted(p.name for p in exported.iterdir()) == sorted(c["dir"] for c in checkpoints)
+
+
+def test_export_without_output(manager: storage.StorageManager, tmp_path: pathlib.Path) -> None:
+    with pytest.raises(RuntimeError):
+        export(
+            manager, str(uuid.uuid4()), [], [sys.executable, "-c", ""], tmp_path.joinpath("work")
+        )
+
+
+def test_delete(manager: storage.StorageManager, tmp_path: pathlib.Path) -> None:
+    export_uuid = str(uuid.uuid4())
+    export(manager, export_uuid, [], COPY_INPUT, tmp_path.joinpath("work"))
+
+    # Exports that are already gone are skipped.
+    delete(manager, [export_uuid, str(uuid.uuid4())])
+    assert not pathlib.Path(manager._base_path).joinpath(export_uuid).exists()
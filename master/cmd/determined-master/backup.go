@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/determined-ai/determined/master/internal/backup"
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/internal/db"
+)
+
+func newBackupCmd() *cobra.Command {
+	var opts backup.Options
+	cmd := &cobra.Command{
+		Use: "backup PATH",
+		Short: `export users, groups, workspaces, projects, experiments, trials, checkpoints, models,
+		templates and webhooks to an archive`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runBackup(context.TODO(), args[0], opts); err != nil {
+				log.Error(fmt.Sprintf("%+v", err))
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringSliceVar(&opts.Workspaces, "workspace", nil,
+		"only back up the named workspaces and what belongs to them; may be repeated")
+	return cmd
+}
+
+func newRestoreCmd() *cobra.Command {
+	var opts backup.RestoreOptions
+	cmd := &cobra.Command{
+		Use:   "restore PATH",
+		Short: "restore an archive written by backup",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runRestore(context.TODO(), args[0], opts); err != nil {
+				log.Error(fmt.Sprintf("%+v", err))
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&opts.SkipExisting, "skip-existing", false,
+		"skip rows that already exist with the same content instead of failing")
+	cmd.Flags().BoolVar(&opts.RemapIDs, "remap-ids", false,
+		"give restored rows new IDs, to restore into a cluster that already has other rows")
+	return cmd
+}
+
+// connectForBackup connects to the database without running migrations, since archives are tied
+// to the schema version they were taken at.
+func connectForBackup() (*db.PgDB, error) {
+	if err := initializeConfig(); err != nil {
+		return nil, err
+	}
+	return db.Connect(&config.GetMasterConfig().DB)
+}
+
+func runBackup(ctx context.Context, path string, opts backup.Options) (err error) {
+	database, err := connectForBackup()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errd := database.Close(); errd != nil {
+			log.Errorf("error closing pg connection: %s", errd)
+		}
+	}()
+
+	f, err := os.Create(path) // #nosec G304
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errc := f.Close(); err == nil {
+			err = errc
+		}
+		if err != nil {
+			_ = os.Remove(path)
+		}
+	}()
+	m, err := backup.Backup(ctx, f, opts)
+	if err != nil {
+		return err
+	}
+	for _, t := range m.Tables {
+		log.Infof("backed up %d rows of %s", t.Rows, t.Name)
+	}
+	log.Infof("wrote backup at schema version %d to %s", m.SchemaVersion, path)
+	return nil
+}
+
+func runRestore(ctx context.Context, path string, opts backup.RestoreOptions) error {
+	database, err := connectForBackup()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errd := database.Close(); errd != nil {
+			log.Errorf("error closing pg connection: %s", errd)
+		}
+	}()
+
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	restored, err := backup.Restore(ctx, f, opts)
+	if err != nil {
+		return err
+	}
+	for _, t := range restored {
+		if skipped := t.Rows - t.Inserted; skipped > 0 {
+			log.Infof("restored %d rows of %s, skipped %d existing", t.Inserted, t.Name, skipped)
+		} else {
+			log.Infof("restored %d rows of %s", t.Inserted, t.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/determined-ai/determined/master/internal/doctor"
+)
+
+func newDoctorCmd() *cobra.Command {
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use: "doctor",
+		Short: `check the master configuration, database, checkpoint storage and cluster state
+		for problems`,
+		Run: func(cmd *cobra.Command, args []string) {
+			failed, err := runDoctor(context.TODO(), asJSON)
+			if err != nil {
+				log.Error(fmt.Sprintf("%+v", err))
+				os.Exit(1)
+			}
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the report as JSON")
+	return cmd
+}
+
+func runDoctor(ctx context.Context, asJSON bool) (failed bool, err error) {
+	// The config isn't validated up front so that validation errors end up in the report along
+	// with everything else.
+	conf, err := loadConfig()
+	if err != nil {
+		return false, err
+	}
+
+	report := doctor.Run(ctx, conf)
+	if asJSON {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	return report.Failed(), err
+}
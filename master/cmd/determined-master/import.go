@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/importer"
+	"github.com/determined-ai/determined/master/internal/user"
+	"github.com/determined-ai/determined/master/pkg/etc"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+type importFlags struct {
+	format         string
+	projectID      int
+	username       string
+	name           string
+	searcherMetric string
+	largerIsBetter bool
+}
+
+func newImportCmd() *cobra.Command {
+	var flags importFlags
+	cmd := &cobra.Command{
+		Use: "import PATH",
+		Short: `import runs from an MLflow file store or TensorBoard log directory into
+		unmanaged experiments`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runImport(context.TODO(), args[0], flags); err != nil {
+				log.Error(fmt.Sprintf("%+v", err))
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&flags.format, "format", importer.SourceMLflow,
+		"format of PATH: mlflow or tensorboard")
+	cmd.Flags().IntVar(&flags.projectID, "project-id", model.DefaultProjectID,
+		"project to import into")
+	cmd.Flags().StringVar(&flags.username, "user", "admin", "owner of the imported experiments")
+	cmd.Flags().StringVar(&flags.name, "name", "",
+		"experiment name for TensorBoard logs, defaults to the directory name")
+	cmd.Flags().StringVar(&flags.searcherMetric, "searcher-metric", "",
+		"validation metric to rank trials by, defaults to the first one with loss in its name")
+	cmd.Flags().BoolVar(&flags.largerIsBetter, "larger-is-better", false,
+		"whether larger values of --searcher-metric are better")
+	return cmd
+}
+
+func runImport(ctx context.Context, path string, flags importFlags) error {
+	if err := initializeConfig(); err != nil {
+		return err
+	}
+	masterConfig := config.GetMasterConfig()
+	database, err := db.Setup(&masterConfig.DB)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errd := database.Close(); errd != nil {
+			log.Errorf("error closing pg connection: %s", errd)
+		}
+	}()
+	if err = etc.SetRootPath(filepath.Join(masterConfig.Root, "static/srv")); err != nil {
+		return err
+	}
+
+	owner, err := user.ByUsername(ctx, flags.username)
+	if err != nil {
+		return fmt.Errorf("looking up user %s: %w", flags.username, err)
+	}
+	name := flags.name
+	if name == "" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		name = filepath.Base(abs)
+	}
+	exps, err := importer.Read(os.DirFS(path), flags.format, name)
+	if err != nil {
+		return err
+	}
+
+	opts := importer.Options{
+		ProjectID:         flags.projectID,
+		Owner:             *owner,
+		CheckpointStorage: masterConfig.CheckpointStorage,
+		SearcherMetric:    flags.searcherMetric,
+		SmallerIsBetter:   !flags.largerIsBetter,
+	}
+	for i := range exps {
+		res, err := importer.Import(ctx, database, &exps[i], opts)
+		if err != nil {
+			return fmt.Errorf("importing experiment %q: %w", exps[i].Name, err)
+		}
+		//nolint:forbidigo
+		fmt.Printf("imported %q as experiment %d: %d trials, %d runs skipped\n",
+			res.Name, res.ExperimentID, len(res.TrialIDs), res.SkippedRuns)
+	}
+	return nil
+}
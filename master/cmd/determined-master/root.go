@@ -34,6 +34,10 @@ func newRootCmd() *cobra.Command {
 	}
 	cmd.AddCommand(newMigrateCmd())
 	cmd.AddCommand(newPopulateCmd())
+	cmd.AddCommand(newImportCmd())
+	cmd.AddCommand(newDoctorCmd())
+	cmd.AddCommand(newBackupCmd())
+	cmd.AddCommand(newRestoreCmd())
 	return cmd
 }
 
@@ -69,18 +73,7 @@ func runRoot() error {
 // file, environment variables, and command line flags) and also initializes
 // global logging state based on those options.
 func initializeConfig() error {
-	// Fetch an initial config to get the config file path and read its settings into Viper.
-	initialConfig, err := getConfig(v.AllSettings())
-	if err != nil {
-		return err
-	}
-
-	bs, err := readConfigFile(initialConfig.ConfigFile)
-	if err != nil {
-		return err
-	}
-
-	conf, err := mergeConfigIntoViper(bs)
+	conf, err := loadConfig()
 	if err != nil {
 		return err
 	}
@@ -98,6 +91,23 @@ func initializeConfig() error {
 	return nil
 }
 
+// loadConfig reads the master config from the config file, environment variables, and command
+// line flags, without validating it.
+func loadConfig() (*config.Config, error) {
+	// Fetch an initial config to get the config file path and read its settings into Viper.
+	initialConfig, err := getConfig(v.AllSettings())
+	if err != nil {
+		return nil, err
+	}
+
+	bs, err := readConfigFile(initialConfig.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeConfigIntoViper(bs)
+}
+
 func mergeConfigIntoViper(bs []byte) (*config.Config, error) {
 	// Write a configMap from the config file, and create a copy (cpMap) to
 	// deepcopy values needed to override viper's merge auto-lowercasing.
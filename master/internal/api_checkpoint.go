@@ -91,11 +91,14 @@ func (m *Master) canDoActionOnCheckpointThroughModel(
 		return api.NotFoundErrs("checkpoint", ckptID, true)
 	}
 
-	var errCanGetModel error
+	errCanGetModel := error(authz.PermissionDeniedError{})
 	for _, modelID := range modelIDs {
 		model := &modelv1.Model{}
 		err = m.db.QueryProto("get_model_by_id", model, modelID)
-		if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			// Trashed models do not grant access to their checkpoints.
+			continue
+		} else if err != nil {
 			return err
 		}
 		if errCanGetModel = modelauth.AuthZProvider.Get().CanGetModel(
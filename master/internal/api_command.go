@@ -33,11 +33,13 @@ import (
 	"github.com/determined-ai/determined/master/pkg/archive"
 	"github.com/determined-ai/determined/master/pkg/check"
 	pkgCommand "github.com/determined-ai/determined/master/pkg/command"
+	"github.com/determined-ai/determined/master/pkg/device"
 	"github.com/determined-ai/determined/master/pkg/etc"
 	"github.com/determined-ai/determined/master/pkg/model"
 	"github.com/determined-ai/determined/master/pkg/protoutils"
 	"github.com/determined-ai/determined/master/pkg/schemas"
 	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
+	"github.com/determined-ai/determined/master/pkg/ssh"
 	"github.com/determined-ai/determined/master/pkg/tasks"
 	"github.com/determined-ai/determined/proto/pkg/apiv1"
 	"github.com/determined-ai/determined/proto/pkg/utilv1"
@@ -58,6 +60,7 @@ type protoCommandParams struct {
 	Config       *pstruct.Struct
 	Files        []*utilv1.File
 	MustZeroSlot bool
+	TaskType     model.TaskType
 }
 
 func (a *apiServer) getCommandLaunchParams(ctx context.Context, req *protoCommandParams,
@@ -86,6 +89,12 @@ func (a *apiServer) getCommandLaunchParams(ctx context.Context, req *protoComman
 		return nil, nil, err
 	}
 
+	var templateValues map[string]any
+	if v, ok := req.Config.GetFields()[templates.ParameterValuesKey]; ok {
+		templateValues = v.GetStructValue().AsMap()
+		delete(req.Config.Fields, templates.ParameterValuesKey)
+	}
+
 	var configBytes []byte
 	if req.Config != nil {
 		configBytes, err = protojson.Marshal(req.Config)
@@ -101,18 +110,21 @@ func (a *apiServer) getCommandLaunchParams(ctx context.Context, req *protoComman
 		resources.Slots = 0
 	}
 	poolName, err := a.m.rm.ResolveResourcePool(
-		resources.ResourcePool, int(cmdSpec.Metadata.WorkspaceID), resources.Slots)
+		resources.ResourcePool, int(cmdSpec.Metadata.WorkspaceID), resources.WholeSlots())
 	if err != nil {
 		return nil, nil, status.Errorf(codes.InvalidArgument, err.Error())
 	}
-	if err = a.m.rm.ValidateResources(poolName, resources.Slots, true); err != nil {
+	if err = a.m.rm.ValidateResources(poolName, resources.WholeSlots(), true); err != nil {
 		return nil, nil, fmt.Errorf("validating resources: %v", err)
 	}
+	if err = a.m.checkStagedDataSupported(resources.StagedData); err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 
 	launchWarnings, err := a.m.rm.ValidateResourcePoolAvailability(
 		&sproto.ValidateResourcePoolAvailabilityRequest{
 			Name:  poolName,
-			Slots: resources.Slots,
+			Slots: resources.WholeSlots(),
 		},
 	)
 	if err != nil {
@@ -136,13 +148,20 @@ func (a *apiServer) getCommandLaunchParams(ctx context.Context, req *protoComman
 	taskSpec.AgentUserGroup = agentUserGroup
 	taskSpec.Owner = userModel
 
-	// Get the full configuration.
+	// Get the full configuration. Clear the default weight, to tell whether the template or the
+	// user set one.
 	config := model.DefaultConfig(&taskSpec.TaskContainerDefaults)
+	defaultWeight := config.Resources.Weight
+	config.Resources.Weight = 0
 	if req.TemplateName != "" {
-		err := templates.UnmarshalTemplateConfig(ctx, req.TemplateName, aUser, &config, false)
+		_, err := templates.UnmarshalTemplateConfig(
+			ctx, req.TemplateName, aUser, &config, false, templateValues)
 		if err != nil {
 			return nil, launchWarnings, err
 		}
+	} else if templateValues != nil {
+		return nil, launchWarnings, status.Errorf(codes.InvalidArgument,
+			"%s can only be set when using a template", templates.ParameterValuesKey)
 	}
 	workDirInDefaults := config.WorkDir
 	if len(configBytes) != 0 {
@@ -163,6 +182,21 @@ func (a *apiServer) getCommandLaunchParams(ctx context.Context, req *protoComman
 		config.Resources.Slots = 0
 	}
 
+	// Fill in the scheduling defaults of the workspace's binding to the pool, if any.
+	binding, err := db.GetRPWorkspaceBinding(ctx, int(cmdSpec.Metadata.WorkspaceID), poolName)
+	if err != nil {
+		return nil, launchWarnings, err
+	}
+	if binding != nil && config.Resources.Priority == nil {
+		config.Resources.Priority = binding.Priority
+	}
+	if config.Resources.Weight == 0 {
+		config.Resources.Weight = defaultWeight
+		if binding != nil && binding.Weight != nil {
+			config.Resources.Weight = *binding.Weight
+		}
+	}
+
 	taskContainerPodSpec := taskSpec.TaskContainerDefaults.GPUPodSpec
 	if config.Resources.Slots == 0 {
 		taskContainerPodSpec = taskSpec.TaskContainerDefaults.CPUPodSpec
@@ -172,6 +206,20 @@ func (a *apiServer) getCommandLaunchParams(ctx context.Context, req *protoComman
 		(*expconf.PodSpec)(taskContainerPodSpec),
 	))
 
+	deviceTypes, err := a.m.imageDeviceTypes(poolName, resources.WholeSlots())
+	if err != nil {
+		return nil, launchWarnings, err
+	}
+	if err = enforceImagePolicy(ctx, int(cmdSpec.Metadata.WorkspaceID), userModel, req.TaskType,
+		config.Environment.RegistryAuth, deviceTypes, map[device.Type]*string{
+			device.CPU:  &config.Environment.Image.CPU,
+			device.CUDA: &config.Environment.Image.CUDA,
+			device.ROCM: &config.Environment.Image.ROCM,
+		}, config.Environment.PodSpec,
+	); err != nil {
+		return nil, launchWarnings, err
+	}
+
 	var contextDirectory []byte
 	if len(req.Files) > 0 {
 		userFiles := filesToArchive(req.Files)
@@ -385,6 +433,7 @@ func (a *apiServer) LaunchCommand(
 		WorkspaceID:  req.WorkspaceId,
 		Config:       req.Config,
 		Files:        req.Files,
+		TaskType:     model.TaskTypeCommand,
 	}, user)
 	if err != nil {
 		return nil, api.WrapWithFallbackCode(err, codes.InvalidArgument,
@@ -433,6 +482,16 @@ func (a *apiServer) LaunchCommand(
 	}
 	maps.Copy(launchReq.Spec.Base.ExtraEnvVars, OIDCPachydermEnvVars)
 
+	// Commands that span several containers ssh between them with these keys.
+	launchReq.Spec.TaskType = model.TaskTypeCommand
+	if launchReq.Spec.CanSpanAgents() {
+		keys, err := ssh.GenerateKey(launchReq.Spec.Base.SSHRsaSize, nil)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		launchReq.Spec.Keys = &keys
+	}
+
 	// Launch a command.
 	cmd, err := command.DefaultCmdService.LaunchGenericCommand(
 		model.TaskTypeCommand,
@@ -273,7 +273,7 @@ func (a *apiServer) GetSearcherEvents(
 	if !ok {
 		return nil, api.NotFoundErrs("experiment", fmt.Sprint(req.ExperimentId), true)
 	}
-	w, err := e.GetSearcherEventsWatcher()
+	w, err := e.GetSearcherEventsWatcher(0)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal,
 			"failed to get searcher events: long polling %v", err)
@@ -324,6 +324,16 @@ func (a *apiServer) PostSearcherOperations(
 	return &apiv1.PostSearcherOperationsResponse{}, nil
 }
 
+func (a *apiServer) StreamSearcherEvents(srv apiv1.Determined_StreamSearcherEventsServer) error {
+	return a.m.serveSearcherStream(srv.Context(), srv,
+		func(ctx context.Context, expID int) (*model.Experiment, error) {
+			exp, _, err := a.getExperimentAndCheckCanDoActions(
+				ctx, expID, experiment.AuthZProvider.Get().CanRunCustomSearch,
+			)
+			return exp, err
+		})
+}
+
 func (a *apiServer) GetExperiment(
 	ctx context.Context, req *apiv1.GetExperimentRequest,
 ) (*apiv1.GetExperimentResponse, error) {
@@ -377,6 +387,17 @@ func (a *apiServer) DeleteExperiment(
 		return nil, err
 	}
 
+	if a.m.config.Trash.Enabled {
+		results, err := experiment.TrashExperiments(ctx, []int32{req.ExperimentId}, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(results) == 0 {
+			return nil, errors.Errorf("TrashExperiments returned neither pass nor fail on trash query")
+		}
+		return &apiv1.DeleteExperimentResponse{}, results[0].Error
+	}
+
 	results, _, err := experiment.DeleteExperiments(ctx,
 		[]int32{req.ExperimentId}, nil)
 	// report error from the multi-experiment selection code
@@ -415,6 +436,14 @@ func (a *apiServer) DeleteExperiments(
 		return nil, status.Errorf(codes.Internal, "failed to get the user: %s", err)
 	}
 
+	if a.m.config.Trash.Enabled {
+		results, err := experiment.TrashExperiments(ctx, req.ExperimentIds, req.Filters)
+		if err != nil {
+			return nil, err
+		}
+		return &apiv1.DeleteExperimentsResponse{Results: experiment.ToAPIResults(results)}, nil
+	}
+
 	results, experiments, err := experiment.DeleteExperiments(ctx, req.ExperimentIds, req.Filters)
 	if err != nil {
 		return nil, err
@@ -590,7 +619,9 @@ func (a *apiServer) GetExperiments(
 	query := db.Bun().NewSelect().
 		Model(&resp.Experiments).
 		ModelTableExpr("experiments as e").
-		Apply(getExperimentColumns)
+		Apply(getExperimentColumns).
+		Where("e.trashed_at IS NULL").
+		Where("p.trashed_at IS NULL")
 
 	if req.ShowTrialData {
 		query.ColumnExpr(`
@@ -2498,6 +2529,19 @@ func sortExperiments(sortString *string, experimentQuery *bun.SelectQuery) error
 			hps := strings.ReplaceAll(strings.TrimPrefix(paramDetail[0], "hp."), ".", "'->'")
 			experimentQuery.OrderExpr(
 				fmt.Sprintf("e.config->'hyperparameters'->'%s' %s", hps, sortDirection))
+		case strings.HasPrefix(paramDetail[0], "metadata."),
+			strings.HasPrefix(paramDetail[0], "trialMetadata."):
+			location, key := locationExperimentMetadata, strings.TrimPrefix(paramDetail[0], "metadata.")
+			if strings.HasPrefix(paramDetail[0], "trialMetadata.") {
+				location, key = locationTrialMetadata, strings.TrimPrefix(paramDetail[0], "trialMetadata.")
+			}
+			table, join := metadataTableToSQL(location)
+			// A key holds a single type per entity, so only one of the row fields is set. Of the
+			// trials of an experiment, the one that sorts first decides.
+			experimentQuery.OrderExpr(fmt.Sprintf(`(
+				SELECT ROW(m.number_value, m.timestamp_value, m.string_value, m.bool_value) AS v
+				FROM %s m WHERE %s AND m.key = ? ORDER BY v %s LIMIT 1
+			) ?`, table, join, sortDirection), key, bun.Safe(sortDirection))
 		case strings.Contains(paramDetail[0], "."):
 			metricGroup, metricName, metricQualifier, err := parseMetricsName(paramDetail[0])
 			if err != nil {
@@ -2523,6 +2567,20 @@ func sortExperiments(sortString *string, experimentQuery *bun.SelectQuery) error
 func (a *apiServer) SearchExperiments(
 	ctx context.Context,
 	req *apiv1.SearchExperimentsRequest,
+) (*apiv1.SearchExperimentsResponse, error) {
+	curUser, _, err := grpcutil.GetUser(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get the user: %s", err)
+	}
+	return a.searchExperiments(ctx, curUser, req)
+}
+
+// searchExperiments runs an experiment search on behalf of curUser. It is shared by the
+// SearchExperiments API and anything else that needs to execute a stored search (saved views).
+func (a *apiServer) searchExperiments(
+	ctx context.Context,
+	curUser *model.User,
+	req *apiv1.SearchExperimentsRequest,
 ) (*apiv1.SearchExperimentsResponse, error) {
 	resp := &apiv1.SearchExperimentsResponse{}
 	var experiments []*experimentv1.Experiment
@@ -2532,12 +2590,11 @@ func (a *apiServer) SearchExperiments(
 		ModelTableExpr("experiments as e").
 		Column("e.best_trial_id").
 		Join("LEFT JOIN trials ON trials.id = e.best_trial_id").
-		Apply(getExperimentColumns)
+		Apply(getExperimentColumns).
+		Where("e.trashed_at IS NULL").
+		Where("p.trashed_at IS NULL")
 
-	curUser, _, err := grpcutil.GetUser(ctx)
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to get the user: %s", err)
-	}
+	var err error
 	var proj *projectv1.Project
 	if req.ProjectId != nil {
 		proj, err = a.GetProjectByID(ctx, *req.ProjectId, *curUser)
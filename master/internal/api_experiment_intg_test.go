@@ -1512,6 +1512,10 @@ func TestExperimentSearchApiFilterParsing(t *testing.T) {
 
 		//  Invalid experiment field
 		`{"filterGroup":{"children":[{"location":"LOCATION_TYPE_EXPERIMENT","columnName":"notValid","kind":"field","value":"default"}],"conjunction":"and","kind":"group"},"showArchived":false}`,
+
+		// Substring match on numeric metadata
+		`{"filterGroup":{"children":[{"location":"LOCATION_TYPE_TRIAL_METADATA","columnName":"rows","kind":"field","operator":"contains","value":10}],"conjunction":"and","kind":"group"},"showArchived":false}`,
+		`{"filterGroup":{"children":[{"type":"COLUMN_TYPE_NUMBER","location":"LOCATION_TYPE_EXPERIMENT_METADATA","columnName":"rows","kind":"field","operator":"notContains","value":"10"}],"conjunction":"and","kind":"group"},"showArchived":false}`,
 	}
 	for _, c := range invalidTestCases {
 		q := db.Bun().NewSelect()
@@ -1648,6 +1652,12 @@ func TestExperimentSearchApiFilterParsing(t *testing.T) {
 					ELSE false
 				 END))))`,
 		},
+		{`{"filterGroup":{"children":[{"location":"LOCATION_TYPE_EXPERIMENT_METADATA","columnName":"dataset_version","kind":"field","operator":"=","value":"v3"}],"conjunction":"and","kind":"group"},"showArchived":true}`, `(((EXISTS (SELECT 1 FROM experiment_metadata m WHERE m.experiment_id = e.id AND m.key = 'dataset_version' AND m.string_value = 'v3'))))`},
+		{`{"filterGroup":{"children":[{"location":"LOCATION_TYPE_EXPERIMENT_METADATA","columnName":"dataset_version","kind":"field","operator":"contains","value":"v3"}],"conjunction":"and","kind":"group"},"showArchived":true}`, `(((EXISTS (SELECT 1 FROM experiment_metadata m WHERE m.experiment_id = e.id AND m.key = 'dataset_version' AND m.string_value ILIKE '%v3%'))))`},
+		{`{"filterGroup":{"children":[{"location":"LOCATION_TYPE_EXPERIMENT_METADATA","columnName":"rows","kind":"field","operator":">","value":1000}],"conjunction":"and","kind":"group"},"showArchived":true}`, `(((EXISTS (SELECT 1 FROM experiment_metadata m WHERE m.experiment_id = e.id AND m.key = 'rows' AND m.number_value > 1000))))`},
+		{`{"filterGroup":{"children":[{"type":"COLUMN_TYPE_BOOLEAN","location":"LOCATION_TYPE_TRIAL_METADATA","columnName":"cleaned","kind":"field","operator":"=","value":true}],"conjunction":"and","kind":"group"},"showArchived":true}`, `(((EXISTS (SELECT 1 FROM trial_metadata m WHERE m.trial_id IN (SELECT mt.id FROM trials mt WHERE mt.experiment_id = e.id) AND m.key = 'cleaned' AND m.bool_value = TRUE))))`},
+		{`{"filterGroup":{"children":[{"type":"COLUMN_TYPE_DATE","location":"LOCATION_TYPE_EXPERIMENT_METADATA","columnName":"snapshot","kind":"field","operator":"<","value":"2023-12-01T00:00:00Z"}],"conjunction":"and","kind":"group"},"showArchived":true}`, `(((EXISTS (SELECT 1 FROM experiment_metadata m WHERE m.experiment_id = e.id AND m.key = 'snapshot' AND m.timestamp_value < '2023-12-01T00:00:00Z'))))`},
+		{`{"filterGroup":{"children":[{"location":"LOCATION_TYPE_EXPERIMENT_METADATA","columnName":"dataset_version","kind":"field","operator":"isEmpty","value":null}],"conjunction":"and","kind":"group"},"showArchived":true}`, `(((NOT EXISTS (SELECT 1 FROM experiment_metadata m WHERE m.experiment_id = e.id AND m.key = 'dataset_version'))))`},
 	}
 	for _, c := range validTestCases {
 		q := db.Bun().NewSelect()
@@ -1668,6 +1678,27 @@ func TestExperimentSearchApiFilterParsing(t *testing.T) {
 	}
 }
 
+func TestExperimentSearchApiProfilerFilterParsing(t *testing.T) {
+	setupAPITest(t, nil)
+	validTestCases := [][2]string{
+		{`{"filterGroup":{"children":[{"location":"LOCATION_TYPE_PROFILER","columnName":"gpuUtilP95","kind":"field","operator":"<","value":60}],"conjunction":"and","kind":"group"},"showArchived":true}`, `((((SELECT ps.gpu_util_p95 FROM experiment_profiler_summaries ps WHERE ps.experiment_id = e.id) < 60)))`},
+		{`{"filterGroup":{"children":[{"location":"LOCATION_TYPE_PROFILER","columnName":"bottlenecks","kind":"field","operator":"contains","value":"input_bound"}],"conjunction":"and","kind":"group"},"showArchived":true}`, `(((EXISTS (SELECT 1 FROM experiment_profiler_summaries ps WHERE ps.experiment_id = e.id AND 'input_bound' = ANY(ps.bottlenecks)))))`},
+		{`{"filterGroup":{"children":[{"location":"LOCATION_TYPE_PROFILER","columnName":"bottlenecks","kind":"field","operator":"isEmpty","value":null}],"conjunction":"and","kind":"group"},"showArchived":true}`, `(((NOT EXISTS (SELECT 1 FROM experiment_profiler_summaries ps WHERE ps.experiment_id = e.id AND cardinality(ps.bottlenecks) > 0))))`},
+	}
+	for _, c := range validTestCases {
+		q := db.Bun().NewSelect()
+		var efr experimentFilterRoot
+		err := json.Unmarshal([]byte(c[0]), &efr)
+		require.NoError(t, err)
+		q = q.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			_, err = efr.toSQL(q)
+			return q
+		})
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf(`SELECT * WHERE %v`, c[1]), q.String())
+	}
+}
+
 func TestDeleteExperiments(t *testing.T) {
 	var mockRM mocks.ResourceManager
 	// Need _anything_ to error to check the error flow leaves things in DELETE_FAILED and that they are delete-able
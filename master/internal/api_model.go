@@ -539,6 +539,17 @@ func (a *apiServer) DeleteModel(
 		currModel.WorkspaceId); err != nil {
 		return nil, err
 	}
+	if a.m.config.Trash.Enabled {
+		if _, err := db.Bun().NewUpdate().Table("models").
+			Set("trashed_at = now()").
+			Where("id = ?", currModel.Id).
+			Where("trashed_at IS NULL").
+			Exec(ctx); err != nil {
+			return nil, errors.Wrapf(err, "error moving model %q to the trash", req.ModelName)
+		}
+		return &apiv1.DeleteModelResponse{}, nil
+	}
+
 	holder := &modelv1.Model{}
 	err = a.m.db.QueryProto("delete_model", holder, currModel.Name)
 
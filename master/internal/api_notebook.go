@@ -236,6 +236,7 @@ func (a *apiServer) LaunchNotebook(
 		WorkspaceID:  req.WorkspaceId,
 		Config:       req.Config,
 		Files:        req.Files,
+		TaskType:     model.TaskTypeNotebook,
 	}, user)
 	if err != nil {
 		return nil, api.WrapWithFallbackCode(err, codes.InvalidArgument,
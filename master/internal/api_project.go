@@ -620,16 +620,9 @@ func (a *apiServer) PatchProject(
 }
 
 func (a *apiServer) deleteProject(ctx context.Context, projectID int32,
-	expList []*model.Experiment,
+	expList []*model.Experiment, user *model.User,
 ) (err error) {
 	holder := &projectv1.Project{}
-	user, _, err := grpcutil.GetUser(ctx)
-	if err != nil {
-		log.WithError(err).Errorf("failed to access user and delete project %d", projectID)
-		_ = a.m.db.QueryProto("delete_fail_project", holder, projectID, err.Error())
-		return err
-	}
-
 	log.Debugf("deleting project %d experiments", projectID)
 	if err = a.deleteExperiments(expList, user); err != nil {
 		log.WithError(err).Errorf("failed to delete experiments")
@@ -651,12 +644,44 @@ func (a *apiServer) DeleteProject(
 	ctx context.Context, req *apiv1.DeleteProjectRequest) (*apiv1.DeleteProjectResponse,
 	error,
 ) {
-	_, _, err := a.getProjectAndCheckCanDoActions(ctx, req.Id,
+	_, curUser, err := a.getProjectAndCheckCanDoActions(ctx, req.Id,
 		project.AuthZProvider.Get().CanDeleteProject)
 	if err != nil {
 		return nil, err
 	}
 
+	if a.m.config.Trash.Enabled {
+		// Only trash projects the trash can purge later, so apply the same checks as deleting.
+		active, err := db.Bun().NewSelect().Table("experiments").
+			Where("project_id = ?", req.Id).
+			Where("state NOT IN (?)", bun.In(model.StatesToStrings(model.TerminalStates))).
+			Exists(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error checking project (%d) for active experiments", req.Id)
+		}
+		if active {
+			return nil, status.Errorf(codes.FailedPrecondition,
+				"project (%d) has active experiments and cannot be deleted", req.Id)
+		}
+		res, err := db.Bun().NewUpdate().Table("projects").
+			Set("trashed_at = now()").
+			Where("id = ?", req.Id).
+			Where("NOT immutable").
+			Where("trashed_at IS NULL").
+			Exec(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error moving project (%d) to the trash", req.Id)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if n == 0 {
+			return nil, errors.Errorf(
+				"project (%d) does not exist, is already in the trash or not deletable by this user",
+				req.Id)
+		}
+		return &apiv1.DeleteProjectResponse{Completed: true}, nil
+	}
+
 	holder := &projectv1.Project{}
 	err = a.m.db.QueryProto("deletable_project", holder, req.Id)
 	if holder.Id == 0 {
@@ -675,7 +700,7 @@ func (a *apiServer) DeleteProject(
 			errors.Wrapf(err, "error deleting project (%d)", req.Id)
 	}
 	go func() {
-		_ = a.deleteProject(ctx, req.Id, expList)
+		_ = a.deleteProject(ctx, req.Id, expList, &curUser)
 	}()
 	return &apiv1.DeleteProjectResponse{Completed: false},
 		errors.Wrapf(err, "error deleting project (%d)", req.Id)
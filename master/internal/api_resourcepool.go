@@ -145,6 +145,9 @@ func (a *apiServer) OverwriteRPWorkspaceBindings(
 	if err != nil {
 		return nil, err
 	}
+	if err := a.m.pushWorkspaceMaxSlots(ctx, req.ResourcePoolName); err != nil {
+		return nil, err
+	}
 
 	return &apiv1.OverwriteRPWorkspaceBindingsResponse{}, nil
 }
@@ -166,6 +169,9 @@ func (a *apiServer) UnbindRPFromWorkspace(
 	if err != nil {
 		return nil, err
 	}
+	if err := a.m.pushWorkspaceMaxSlots(ctx, req.ResourcePoolName); err != nil {
+		return nil, err
+	}
 	return &apiv1.UnbindRPFromWorkspaceResponse{}, nil
 }
 
@@ -264,6 +264,7 @@ func TestPatchBindingsSucceeds(t *testing.T) {
 		Return(&apiv1.GetResourcePoolsResponse{
 			ResourcePools: []*resourcepoolv1.ResourcePool{{Name: testPoolName}},
 		}, nil).Times(7)
+	mockRM.On("SetWorkspaceMaxSlots", mock.Anything).Return().Times(3)
 
 	_, err := api.BindRPToWorkspace(ctx, &apiv1.BindRPToWorkspaceRequest{
 		ResourcePoolName: testPoolName,
@@ -336,6 +337,7 @@ func TestDeleteBindingsSucceeds(t *testing.T) {
 		Return(&apiv1.GetResourcePoolsResponse{
 			ResourcePools: []*resourcepoolv1.ResourcePool{{Name: testPoolName}},
 		}, nil).Times(3)
+	mockRM.On("SetWorkspaceMaxSlots", mock.Anything).Return().Times(2)
 
 	_, err := api.BindRPToWorkspace(ctx, &apiv1.BindRPToWorkspaceRequest{
 		ResourcePoolName: testPoolName,
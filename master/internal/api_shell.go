@@ -193,6 +193,7 @@ func (a *apiServer) LaunchShell(
 		WorkspaceID:  req.WorkspaceId,
 		Config:       req.Config,
 		Files:        req.Files,
+		TaskType:     model.TaskTypeShell,
 	}, user)
 	if err != nil {
 		return nil, api.WrapWithFallbackCode(err, codes.InvalidArgument,
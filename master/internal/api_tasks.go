@@ -23,6 +23,7 @@ import (
 	expauth "github.com/determined-ai/determined/master/internal/experiment"
 	"github.com/determined-ai/determined/master/internal/grpcutil"
 	"github.com/determined-ai/determined/master/internal/logpattern"
+	"github.com/determined-ai/determined/master/internal/secrets"
 	"github.com/determined-ai/determined/master/internal/sproto"
 	"github.com/determined-ai/determined/master/internal/task"
 	"github.com/determined-ai/determined/master/internal/webhooks"
@@ -470,6 +471,7 @@ func (a *apiServer) PostTaskLogs(
 
 		logs[i] = model.TaskLogFromProto(req.Logs[i])
 	}
+	secrets.Redact(logs)
 
 	if err := a.m.taskLogBackend.AddTaskLogs(logs); err != nil {
 		return nil, fmt.Errorf("adding task logs to task log backend: %w", err)
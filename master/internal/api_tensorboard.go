@@ -225,6 +225,7 @@ func (a *apiServer) LaunchTensorboard(
 		WorkspaceID:  req.WorkspaceId,
 		Config:       req.Config,
 		Files:        req.Files,
+		TaskType:     model.TaskTypeTensorboard,
 		MustZeroSlot: true,
 	}, user)
 	if err != nil {
@@ -66,6 +66,7 @@ func MockRM() *mocks.ResourceManager {
 	)
 	mockRM.On("ValidateResourcePoolAvailability", mock.Anything).Return(nil, nil)
 	mockRM.On("SetGroupMaxSlots", mock.Anything).Return()
+	mockRM.On("SetWorkspaceMaxSlots", mock.Anything).Return()
 	mockRM.On("SetGroupWeight", mock.Anything).Return(nil)
 	mockRM.On("Allocate", mock.Anything).Return(func(msg sproto.AllocateRequest) *sproto.ResourcesSubscription {
 		return rmevents.Subscribe(msg.AllocationID)
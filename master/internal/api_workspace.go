@@ -512,7 +512,7 @@ func (a *apiServer) PatchWorkspace(
 }
 
 func (a *apiServer) deleteWorkspace(
-	ctx context.Context, workspaceID int32, projects []*projectv1.Project,
+	ctx context.Context, workspaceID int32, projects []*projectv1.Project, user *model.User,
 ) {
 	log.Debugf("deleting workspace %d projects", workspaceID)
 	holder := &workspacev1.Workspace{}
@@ -524,7 +524,7 @@ func (a *apiServer) deleteWorkspace(
 			_ = a.m.db.QueryProto("delete_fail_workspace", holder, workspaceID, err.Error())
 			return
 		}
-		err = a.deleteProject(ctx, pj.Id, expList)
+		err = a.deleteProject(ctx, pj.Id, expList, user)
 		if err != nil {
 			log.WithError(err).Errorf("error deleting project %d while deleting workspace %d", pj.Id,
 				workspaceID)
@@ -545,7 +545,7 @@ func (a *apiServer) DeleteWorkspace(
 	ctx context.Context,
 	req *apiv1.DeleteWorkspaceRequest,
 ) (*apiv1.DeleteWorkspaceResponse, error) {
-	_, _, err := a.getWorkspaceAndCheckCanDoActions(ctx, req.Id, false,
+	_, curUser, err := a.getWorkspaceAndCheckCanDoActions(ctx, req.Id, false,
 		workspace.AuthZProvider.Get().CanDeleteWorkspace)
 	if err != nil {
 		return nil, err
@@ -572,6 +572,17 @@ func (a *apiServer) DeleteWorkspace(
 	if err != nil {
 		return nil, err
 	}
+	// Projects in the trash are not listed above but go down with the workspace all the same.
+	var trashedProjectIDs []int32
+	if err = db.Bun().NewSelect().Table("projects").Column("id").
+		Where("workspace_id = ?", req.Id).
+		Where("trashed_at IS NOT NULL").
+		Scan(ctx, &trashedProjectIDs); err != nil {
+		return nil, err
+	}
+	for _, id := range trashedProjectIDs {
+		projects = append(projects, &projectv1.Project{Id: id})
+	}
 
 	log.Debugf("deleting workspace %d NTSC", req.Id)
 	command.DefaultCmdService.DeleteWorkspaceNTSC(req)
@@ -588,7 +599,7 @@ func (a *apiServer) DeleteWorkspace(
 			errors.Wrapf(err, "error deleting workspace (%d)", req.Id)
 	}
 	go func() {
-		a.deleteWorkspace(ctx, req.Id, projects)
+		a.deleteWorkspace(ctx, req.Id, projects, &curUser)
 	}()
 	return &apiv1.DeleteWorkspaceResponse{Completed: false},
 		errors.Wrapf(err, "error deleting workspace (%d)", req.Id)
@@ -0,0 +1,406 @@
+// Package backup exports cluster metadata to a versioned archive and restores it, independently of
+// pg_dump and the Postgres version.
+//
+// An archive is a gzipped tarball holding manifest.json followed by one JSON lines file per table,
+// in the order they must be restored in. Rows keep their IDs unless they are remapped, so
+// restoring into a cluster that already has some of them otherwise only works when skipping
+// existing rows, and only if those rows are unchanged.
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/determined-ai/determined/master/internal/db"
+)
+
+// FormatVersion is the version of the archive layout written by Backup.
+const FormatVersion = 1
+
+const manifestName = "manifest.json"
+
+// workspacesPlaceholder stands for a subquery of the IDs of the backed-up workspaces in filters.
+const workspacesPlaceholder = "{workspaces}"
+
+const (
+	projectsInScope    = "(SELECT id FROM projects WHERE workspace_id IN ({workspaces}))"
+	experimentsInScope = "(SELECT id FROM experiments WHERE project_id IN " + projectsInScope + ")"
+	runsInScope        = "(SELECT id FROM runs WHERE experiment_id IN " + experimentsInScope + ")"
+	tasksInScope       = "(SELECT task_id FROM trial_id_task_id WHERE trial_id IN " + runsInScope + ")"
+	modelsInScope      = "(SELECT id FROM models WHERE workspace_id IN ({workspaces}))"
+	// usersInScope are the users the backed-up rows reference.
+	usersInScope = "(" +
+		"SELECT user_id FROM workspaces WHERE id IN ({workspaces})" +
+		" UNION SELECT user_id FROM projects WHERE workspace_id IN ({workspaces})" +
+		" UNION SELECT owner_id FROM saved_views WHERE project_id IN " + projectsInScope +
+		" UNION SELECT owner_id FROM experiments WHERE project_id IN " + projectsInScope +
+		" UNION SELECT j.owner_id FROM jobs j JOIN experiments e ON e.job_id = j.job_id" +
+		" WHERE e.project_id IN " + projectsInScope +
+		" UNION SELECT user_id FROM models WHERE workspace_id IN ({workspaces})" +
+		" UNION SELECT user_id FROM model_versions WHERE model_id IN " + modelsInScope +
+		" UNION SELECT v.created_by FROM template_versions v" +
+		" JOIN templates t ON t.name = v.template_name WHERE t.workspace_id IN ({workspaces})" +
+		")"
+)
+
+// table describes how one table is backed up.
+type table struct {
+	name    string
+	orderBy string
+	// where restricts the rows to those of the backed-up workspaces. Tables without one are
+	// cluster-wide and backed up in full.
+	where string
+	// referencedWhere restricts backups of some workspaces to the rows that the rows of those
+	// workspaces reference. Full backups include every row.
+	referencedWhere string
+	// fullOnly tables are left out of backups restricted to some workspaces.
+	fullOnly bool
+	// omit lists columns that aren't backed up, such as references to metrics, which aren't either.
+	omit []string
+	// naturalKey is a unique column that rows are matched to existing rows on when remapping IDs;
+	// matching rows are used instead of being restored.
+	naturalKey string
+	// ownedBy are columns referencing the rows that own a row. When remapping IDs, a row whose
+	// owners all matched existing rows is skipped, so that those keep their own.
+	ownedBy []string
+}
+
+// tables lists the backed-up tables such that every table comes after those it references.
+var tables = []table{
+	{name: "users", orderBy: "id", referencedWhere: "id IN " + usersInScope, naturalKey: "username"},
+	{
+		name:            "agent_user_groups",
+		orderBy:         "id",
+		referencedWhere: "user_id IN " + usersInScope,
+		ownedBy:         []string{"user_id"},
+	},
+	{
+		name:            "groups",
+		orderBy:         "id",
+		referencedWhere: "id IN (SELECT group_id FROM user_group_membership WHERE user_id IN " + usersInScope + ")",
+		naturalKey:      "group_name",
+	},
+	{
+		name:            "user_group_membership",
+		orderBy:         "user_id, group_id",
+		referencedWhere: "user_id IN " + usersInScope,
+		ownedBy:         []string{"user_id", "group_id"},
+	},
+	{
+		name:            "user_ssh_keys",
+		orderBy:         "id",
+		referencedWhere: "user_id IN " + usersInScope,
+		ownedBy:         []string{"user_id"},
+	},
+	{name: "workspaces", orderBy: "id", where: "id IN ({workspaces})"},
+	{name: "projects", orderBy: "id", where: "workspace_id IN ({workspaces})"},
+	{name: "saved_views", orderBy: "id", where: "project_id IN " + projectsInScope},
+	{
+		name:    "jobs",
+		orderBy: "job_id",
+		where:   "job_id IN (SELECT job_id FROM experiments WHERE project_id IN " + projectsInScope + ")",
+	},
+	{name: "experiments", orderBy: "id", where: "project_id IN " + projectsInScope},
+	{
+		name:    "experiment_metadata",
+		orderBy: "experiment_id, key",
+		where:   "experiment_id IN " + experimentsInScope,
+	},
+	{
+		name:    "runs",
+		orderBy: "id",
+		where:   "experiment_id IN " + experimentsInScope,
+		omit:    []string{"latest_validation_id", "best_validation_id"},
+	},
+	{name: "trial_metadata", orderBy: "trial_id, key", where: "trial_id IN " + runsInScope},
+	{name: "tasks", orderBy: "task_id", where: "task_id IN " + tasksInScope},
+	{name: "trial_id_task_id", orderBy: "trial_id, task_id", where: "trial_id IN " + runsInScope},
+	{name: "allocations", orderBy: "allocation_id", where: "task_id IN " + tasksInScope},
+	{name: "checkpoints_v2", orderBy: "id", where: "task_id IN " + tasksInScope},
+	{name: "models", orderBy: "id", where: "workspace_id IN ({workspaces})"},
+	{
+		name:    "model_versions",
+		orderBy: "id",
+		where:   "model_id IN " + modelsInScope,
+	},
+	{
+		name:    "model_version_checkpoints",
+		orderBy: "model_version_id, ordinal",
+		where:   "model_version_id IN (SELECT id FROM model_versions WHERE model_id IN " + modelsInScope + ")",
+	},
+	{name: "templates", orderBy: "name", where: "workspace_id IN ({workspaces})"},
+	{
+		name:    "template_versions",
+		orderBy: "template_name, version",
+		where:   "template_name IN (SELECT name FROM templates WHERE workspace_id IN ({workspaces}))",
+	},
+	{name: "workspace_volumes", orderBy: "workspace_id, name", where: "workspace_id IN ({workspaces})"},
+	{name: "webhooks", orderBy: "id", fullOnly: true},
+	{name: "webhook_triggers", orderBy: "id", fullOnly: true},
+	// Secrets stay encrypted, so a restored cluster needs the same secrets master key to use them.
+	{name: "secrets", orderBy: "id", fullOnly: true},
+}
+
+func tableByName(name string) (table, bool) {
+	for _, t := range tables {
+		if t.name == name {
+			return t, true
+		}
+	}
+	return table{}, false
+}
+
+// Manifest describes the contents of an archive.
+type Manifest struct {
+	FormatVersion int `json:"format_version"`
+	// SchemaVersion is the database migration version the archive was taken at.
+	SchemaVersion int64     `json:"schema_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	ClusterID     string    `json:"cluster_id"`
+	// Workspaces are the names of the workspaces the archive is restricted to, if any.
+	Workspaces []string        `json:"workspaces,omitempty"`
+	Tables     []TableManifest `json:"tables"`
+}
+
+// TableManifest describes the rows of one table in an archive.
+type TableManifest struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Rows    int      `json:"rows"`
+}
+
+func tableFileName(name string) string {
+	return "tables/" + name + ".jsonl"
+}
+
+// Options configures a backup.
+type Options struct {
+	// Workspaces restricts the backup to the named workspaces and what belongs to them, along with
+	// the users those reference and their groups and keys.
+	Workspaces []string
+}
+
+// Backup writes an archive of the cluster metadata to w. Every table is read from the same
+// snapshot of the database.
+func Backup(ctx context.Context, w io.Writer, opts Options) (*Manifest, error) {
+	tx, err := db.Bun().BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("starting backup transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	m := &Manifest{
+		FormatVersion: FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		Workspaces:    opts.Workspaces,
+	}
+	if m.SchemaVersion, err = schemaVersion(ctx, tx); err != nil {
+		return nil, err
+	}
+	err = tx.NewSelect().Table("cluster_id").Column("cluster_id").Scan(ctx, &m.ClusterID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("reading cluster id: %w", err)
+	}
+	workspaces, err := workspaceScope(ctx, tx, opts.Workspaces)
+	if err != nil {
+		return nil, err
+	}
+
+	// A tar header needs the size of its file, so every table is spooled to disk first.
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+	for _, t := range tables {
+		if t.fullOnly && len(opts.Workspaces) > 0 {
+			continue
+		}
+		f, err := os.CreateTemp("", "determined-backup-*.jsonl")
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+		tm, err := dumpTable(ctx, tx, t, workspaces, len(opts.Workspaces) > 0, f)
+		if err != nil {
+			return nil, fmt.Errorf("backing up %s: %w", t.name, err)
+		}
+		m.Tables = append(m.Tables, *tm)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	err = writeTarFile(tw, manifestName, int64(len(manifest)), bytes.NewReader(manifest))
+	if err != nil {
+		return nil, err
+	}
+	for i, f := range files {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if err := writeTarFile(tw, tableFileName(m.Tables[i].Name), info.Size(), f); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func writeTarFile(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    size,
+		ModTime: time.Now(),
+	}); err != nil {
+		return fmt.Errorf("writing %s to archive: %w", name, err)
+	}
+	if _, err := io.Copy(tw, r); err != nil {
+		return fmt.Errorf("writing %s to archive: %w", name, err)
+	}
+	return nil
+}
+
+// schemaVersion returns the migration version of the database, as recorded by go-pg.
+func schemaVersion(ctx context.Context, idb bun.IDB) (int64, error) {
+	var version int64
+	if err := idb.NewRaw(
+		"SELECT version FROM gopg_migrations ORDER BY id DESC LIMIT 1",
+	).Scan(ctx, &version); err != nil {
+		return 0, fmt.Errorf("reading schema version: %w", err)
+	}
+	return version, nil
+}
+
+// workspaceScope returns a subquery of the IDs of the named workspaces, or of every workspace if
+// no names are given.
+func workspaceScope(ctx context.Context, idb bun.IDB, names []string) (string, error) {
+	if len(names) == 0 {
+		return "SELECT id FROM workspaces", nil
+	}
+	var rows []struct {
+		ID   int    `bun:"id"`
+		Name string `bun:"name"`
+	}
+	if err := idb.NewSelect().Table("workspaces").Column("id", "name").
+		Where("name IN (?)", bun.In(names)).
+		Scan(ctx, &rows); err != nil {
+		return "", fmt.Errorf("looking up workspaces: %w", err)
+	}
+	ids := make([]string, 0, len(rows))
+	found := make(map[string]bool, len(rows))
+	for _, r := range rows {
+		ids = append(ids, strconv.Itoa(r.ID))
+		found[r.Name] = true
+	}
+	for _, name := range names {
+		if !found[name] {
+			return "", fmt.Errorf("workspace %q not found", name)
+		}
+	}
+	return strings.Join(ids, ","), nil
+}
+
+// tableColumns returns the columns of a table that can be inserted into, in order.
+func tableColumns(ctx context.Context, idb bun.IDB, name string) ([]string, error) {
+	var columns []string
+	if err := idb.NewRaw(`
+SELECT column_name
+FROM information_schema.columns
+WHERE table_schema = current_schema() AND table_name = ? AND is_generated = 'NEVER'
+ORDER BY ordinal_position`, name).Scan(ctx, &columns); err != nil {
+		return nil, fmt.Errorf("reading columns of %s: %w", name, err)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s does not exist", name)
+	}
+	return columns, nil
+}
+
+func dumpTable(
+	ctx context.Context, tx bun.Tx, t table, workspaces string, restricted bool, w io.Writer,
+) (*TableManifest, error) {
+	all, err := tableColumns(ctx, tx, t.name)
+	if err != nil {
+		return nil, err
+	}
+	omit := make(map[string]bool, len(t.omit))
+	for _, c := range t.omit {
+		omit[c] = true
+	}
+	tm := &TableManifest{Name: t.name}
+	for _, c := range all {
+		if !omit[c] {
+			tm.Columns = append(tm.Columns, c)
+		}
+	}
+
+	idents := make([]string, 0, len(tm.Columns))
+	for _, c := range tm.Columns {
+		idents = append(idents, strconv.Quote(c))
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(idents, ", "), strconv.Quote(t.name))
+	where := t.where
+	if restricted && t.referencedWhere != "" {
+		where = t.referencedWhere
+	}
+	if where != "" {
+		query += " WHERE " + strings.ReplaceAll(where, workspacesPlaceholder, workspaces)
+	}
+	query += " ORDER BY " + t.orderBy
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT to_jsonb(r)::text FROM (%s) r", query))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bw := bufio.NewWriter(w)
+	for rows.Next() {
+		var row string
+		if err := rows.Scan(&row); err != nil {
+			return nil, err
+		}
+		if _, err := bw.WriteString(row + "\n"); err != nil {
+			return nil, err
+		}
+		tm.Rows++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tm, bw.Flush()
+}
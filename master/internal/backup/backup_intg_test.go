@@ -0,0 +1,174 @@
+//go:build integration
+// +build integration
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/pkg/etc"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func TestBackupRestore(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, etc.SetRootPath(db.RootFromDB))
+	pgDB := db.MustResolveTestPostgres(t)
+	db.MustMigrateTestPostgres(t, pgDB, db.MigrationsFromDB)
+
+	user := db.RequireMockUser(t, pgDB)
+	exp := db.RequireMockExperiment(t, pgDB, user)
+	trialID := db.RequireMockTrialID(t, pgDB, exp)
+
+	var workspace string
+	require.NoError(t, db.Bun().NewRaw(`
+SELECT w.name FROM workspaces w JOIN projects p ON p.workspace_id = w.id WHERE p.id = ?`,
+		exp.ProjectID).Scan(ctx, &workspace))
+
+	var buf bytes.Buffer
+	m, err := Backup(ctx, &buf, Options{Workspaces: []string{workspace}})
+	require.NoError(t, err)
+	require.Equal(t, []string{workspace}, m.Workspaces)
+	rows := map[string]int{}
+	for _, tm := range m.Tables {
+		rows[tm.Name] = tm.Rows
+		require.NotContains(t, []string{"webhooks", "webhook_triggers"}, tm.Name)
+	}
+	require.Equal(t, 1, rows["workspaces"])
+	// Only the users the workspace references are backed up.
+	var users int
+	require.NoError(t, db.Bun().NewRaw(fmt.Sprintf("SELECT count(*) FROM users WHERE id IN %s",
+		strings.ReplaceAll(usersInScope, workspacesPlaceholder,
+			fmt.Sprintf("SELECT id FROM workspaces WHERE name = '%s'", workspace)))).
+		Scan(ctx, &users))
+	require.Equal(t, users, rows["users"])
+	require.GreaterOrEqual(t, rows["users"], 1)
+	require.GreaterOrEqual(t, rows["experiments"], 1)
+	require.GreaterOrEqual(t, rows["runs"], 1)
+
+	// Everything in the archive already exists, so it can only be restored by skipping it.
+	archive := buf.Bytes()
+	_, err = Restore(ctx, bytes.NewReader(archive), RestoreOptions{})
+	require.Error(t, err)
+	restored, err := Restore(ctx, bytes.NewReader(archive), RestoreOptions{SkipExisting: true})
+	require.NoError(t, err)
+	for _, rt := range restored {
+		require.Equal(t, rows[rt.Name], rt.Rows, rt.Name)
+		require.Zero(t, rt.Inserted, rt.Name)
+	}
+
+	// Rows that changed since the backup are not skipped.
+	var description string
+	require.NoError(t, db.Bun().NewRaw("SELECT description FROM projects WHERE id = ?",
+		exp.ProjectID).Scan(ctx, &description))
+	_, err = db.Bun().NewRaw("UPDATE projects SET description = 'changed' WHERE id = ?",
+		exp.ProjectID).Exec(ctx)
+	require.NoError(t, err)
+	_, err = Restore(ctx, bytes.NewReader(archive), RestoreOptions{SkipExisting: true})
+	require.ErrorContains(t, err, "already exists with different content")
+	_, err = db.Bun().NewRaw("UPDATE projects SET description = ? WHERE id = ?",
+		description, exp.ProjectID).Exec(ctx)
+	require.NoError(t, err)
+
+	// A deleted trial is brought back with its ID.
+	_, err = db.Bun().NewDelete().Table("trial_id_task_id").Where("trial_id = ?", trialID).Exec(ctx)
+	require.NoError(t, err)
+	_, err = db.Bun().NewDelete().Table("runs").Where("id = ?", trialID).Exec(ctx)
+	require.NoError(t, err)
+	restored, err = Restore(ctx, bytes.NewReader(archive), RestoreOptions{SkipExisting: true})
+	require.NoError(t, err)
+	inserted := map[string]int{}
+	for _, rt := range restored {
+		inserted[rt.Name] = rt.Inserted
+	}
+	require.Equal(t, 1, inserted["runs"])
+	require.Equal(t, 1, inserted["trial_id_task_id"])
+	trial, err := db.TrialByID(ctx, trialID)
+	require.NoError(t, err)
+	require.Equal(t, exp.ID, trial.ExperimentID)
+	require.Equal(t, model.ActiveState, trial.State)
+
+	_, err = Backup(ctx, &bytes.Buffer{}, Options{Workspaces: []string{"no-such-workspace"}})
+	require.ErrorContains(t, err, "not found")
+}
+
+func TestRestoreRemapsIDs(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, etc.SetRootPath(db.RootFromDB))
+	pgDB := db.MustResolveTestPostgres(t)
+	db.MustMigrateTestPostgres(t, pgDB, db.MigrationsFromDB)
+
+	owner := db.RequireMockUser(t, pgDB)
+	member := db.RequireMockUser(t, pgDB)
+	workspace := uuid.NewString()
+	workspaceIDs, err := db.MockWorkspaces([]string{workspace}, owner.ID)
+	require.NoError(t, err)
+	var projectID int
+	require.NoError(t, db.Bun().NewRaw(`
+INSERT INTO projects (name, workspace_id, user_id) VALUES ('restored', ?, ?) RETURNING id`,
+		workspaceIDs[0], member.ID).Scan(ctx, &projectID))
+
+	var buf bytes.Buffer
+	_, err = Backup(ctx, &buf, Options{Workspaces: []string{workspace}})
+	require.NoError(t, err)
+
+	// The database now has different rows with the IDs in the archive: the workspace is renamed
+	// and the member is gone under their name, as if restoring into another cluster.
+	_, err = db.Bun().NewRaw("UPDATE workspaces SET name = ? WHERE id = ?",
+		workspace+"-old", workspaceIDs[0]).Exec(ctx)
+	require.NoError(t, err)
+	_, err = db.Bun().NewRaw("UPDATE users SET username = username || '-old' WHERE id = ?",
+		member.ID).Exec(ctx)
+	require.NoError(t, err)
+	_, err = db.Bun().NewRaw(
+		"UPDATE groups SET group_name = group_name || '-old' WHERE user_id = ?", member.ID,
+	).Exec(ctx)
+	require.NoError(t, err)
+	var users int
+	require.NoError(t, db.Bun().NewRaw("SELECT count(*) FROM users").Scan(ctx, &users))
+
+	archive := buf.Bytes()
+	_, err = Restore(ctx, bytes.NewReader(archive), RestoreOptions{SkipExisting: true})
+	require.ErrorContains(t, err, "already exists with different content")
+	_, err = Restore(ctx, bytes.NewReader(archive), RestoreOptions{SkipExisting: true, RemapIDs: true})
+	require.Error(t, err)
+
+	restored, err := Restore(ctx, bytes.NewReader(archive), RestoreOptions{RemapIDs: true})
+	require.NoError(t, err)
+	inserted := map[string]int{}
+	for _, rt := range restored {
+		inserted[rt.Name] = rt.Inserted
+	}
+	require.Equal(t, 1, inserted["workspaces"])
+	require.Equal(t, 1, inserted["projects"])
+	// The owner exists under the same name and is reused; the member is restored as a new user.
+	require.Equal(t, 1, inserted["users"])
+	var usersAfter int
+	require.NoError(t, db.Bun().NewRaw("SELECT count(*) FROM users").Scan(ctx, &usersAfter))
+	require.Equal(t, users+1, usersAfter)
+
+	var got struct {
+		WorkspaceID int    `bun:"workspace_id"`
+		OwnerID     int    `bun:"owner_id"`
+		ProjectID   int    `bun:"project_id"`
+		ProjectUser string `bun:"project_user"`
+	}
+	require.NoError(t, db.Bun().NewRaw(`
+SELECT w.id AS workspace_id, w.user_id AS owner_id, p.id AS project_id, u.username AS project_user
+FROM workspaces w
+JOIN projects p ON p.workspace_id = w.id
+JOIN users u ON u.id = p.user_id
+WHERE w.name = ?`, workspace).Scan(ctx, &got))
+	require.NotEqual(t, int(workspaceIDs[0]), got.WorkspaceID)
+	require.NotEqual(t, projectID, got.ProjectID)
+	require.Equal(t, int(owner.ID), got.OwnerID)
+	require.Equal(t, member.Username, got.ProjectUser)
+}
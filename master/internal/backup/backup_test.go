@@ -0,0 +1,155 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeArchive(t *testing.T, files ...string) *bytes.Buffer {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for i := 0; i < len(files); i += 2 {
+		require.NoError(t, writeTarFile(tw, files[i], int64(len(files[i+1])),
+			strings.NewReader(files[i+1])))
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return &buf
+}
+
+func manifestJSON(t *testing.T, m Manifest) string {
+	bs, err := json.Marshal(m)
+	require.NoError(t, err)
+	return string(bs)
+}
+
+func TestReadManifest(t *testing.T) {
+	m := Manifest{
+		FormatVersion: FormatVersion,
+		SchemaVersion: 20231219093000,
+		Tables:        []TableManifest{{Name: "users", Columns: []string{"id"}, Rows: 1}},
+	}
+	got, tr, err := ReadManifest(writeArchive(t,
+		manifestName, manifestJSON(t, m),
+		tableFileName("users"), `{"id": 1}`+"\n",
+	))
+	require.NoError(t, err)
+	require.Equal(t, m, *got)
+	hdr, err := tr.Next()
+	require.NoError(t, err)
+	require.Equal(t, "tables/users.jsonl", hdr.Name)
+
+	_, _, err = ReadManifest(writeArchive(t, tableFileName("users"), `{"id": 1}`))
+	require.ErrorContains(t, err, "instead of manifest.json")
+
+	m.FormatVersion = FormatVersion + 1
+	_, _, err = ReadManifest(writeArchive(t, manifestName, manifestJSON(t, m)))
+	require.ErrorContains(t, err, "not supported")
+
+	_, _, err = ReadManifest(strings.NewReader("not an archive"))
+	require.Error(t, err)
+}
+
+func TestManifestValidate(t *testing.T) {
+	const version = 20231219093000
+	withTables := func(names ...string) *Manifest {
+		m := &Manifest{FormatVersion: FormatVersion, SchemaVersion: version}
+		for _, name := range names {
+			m.Tables = append(m.Tables, TableManifest{Name: name})
+		}
+		return m
+	}
+
+	require.NoError(t, withTables("users", "workspaces", "experiments", "runs").validate(version))
+	require.NoError(t, withTables().validate(version))
+	require.ErrorContains(t, withTables("users").validate(version+1), "schema version")
+	require.ErrorContains(t, withTables("runs", "experiments").validate(version), "out of order")
+	require.ErrorContains(t, withTables("users", "users").validate(version), "out of order")
+	require.ErrorContains(t, withTables("raw_steps").validate(version), "unknown table")
+}
+
+func TestTableFilters(t *testing.T) {
+	names := map[string]bool{}
+	for _, tbl := range tables {
+		require.False(t, names[tbl.name], "%s is listed twice", tbl.name)
+		names[tbl.name] = true
+		require.NotEmpty(t, tbl.orderBy, tbl.name)
+		if tbl.where != "" {
+			require.Contains(t, tbl.where, workspacesPlaceholder, tbl.name)
+			require.False(t, tbl.fullOnly, tbl.name)
+			require.Empty(t, tbl.referencedWhere, tbl.name)
+		}
+		if tbl.referencedWhere != "" {
+			require.Contains(t, tbl.referencedWhere, workspacesPlaceholder, tbl.name)
+			require.False(t, tbl.fullOnly, tbl.name)
+		}
+	}
+}
+
+func TestSkipExistingQuery(t *testing.T) {
+	q := skipExistingQuery("runs", []string{"id", "state"}, []string{"id"})
+	require.Equal(t, 1, strings.Count(q, "?"))
+	require.Contains(t, q, `ON CONFLICT DO NOTHING`)
+	require.Contains(t, q, `RETURNING "id"`)
+	require.Contains(t, q, `e."id" = i."id"`)
+	require.Contains(t, q, `to_jsonb(ROW(e."id", e."state")) = to_jsonb(ROW(i."id", i."state"))`)
+	require.Contains(t, q, `jsonb_build_object('id', i."id")`)
+
+	// Without a primary key, rows are matched on their content.
+	q = skipExistingQuery("user_group_membership", []string{"user_id", "group_id"}, nil)
+	require.Contains(t, q, `RETURNING to_jsonb(ROW("user_id", "group_id")) AS r`)
+	require.Contains(t, q, `x.r = to_jsonb(ROW(i."user_id", i."group_id"))`)
+}
+
+func TestRemapRows(t *testing.T) {
+	r := newIDRemapper()
+	r.ids["users"] = map[int64]int64{1: 1, 2: 7}
+	r.matched["users"] = map[int64]bool{1: true}
+	r.ids["groups"] = map[int64]int64{3: 3, 4: 9}
+	r.matched["groups"] = map[int64]bool{3: true}
+	p := &remapPlan{
+		table:    table{name: "user_group_membership", ownedBy: []string{"user_id", "group_id"}},
+		refs:     map[string]string{"user_id": "users", "group_id": "groups"},
+		nullable: map[string]bool{},
+	}
+
+	// Only rows whose owners all matched existing rows are skipped.
+	rows := []map[string]json.RawMessage{
+		{"user_id": json.RawMessage("1"), "group_id": json.RawMessage("3")},
+		{"user_id": json.RawMessage("1"), "group_id": json.RawMessage("4")},
+		{"user_id": json.RawMessage("2"), "group_id": json.RawMessage("3")},
+	}
+	rows = r.skipOwned(p, rows)
+	require.Len(t, rows, 2)
+	for _, row := range rows {
+		require.NoError(t, r.rewriteRefs(p, row))
+	}
+	require.Equal(t, map[string]json.RawMessage{
+		"user_id": json.RawMessage("1"), "group_id": json.RawMessage("9"),
+	}, rows[0])
+	require.Equal(t, map[string]json.RawMessage{
+		"user_id": json.RawMessage("7"), "group_id": json.RawMessage("3"),
+	}, rows[1])
+
+	// References to rows that aren't in the archive are cleared if they can be.
+	p = &remapPlan{
+		table:    table{name: "projects"},
+		refs:     map[string]string{"user_id": "users", "workspace_id": "workspaces"},
+		nullable: map[string]bool{"user_id": true},
+	}
+	row := map[string]json.RawMessage{"user_id": json.RawMessage("5"), "workspace_id": json.RawMessage("2")}
+	require.NoError(t, r.rewriteRefs(p, row))
+	require.Equal(t, json.RawMessage("null"), row["user_id"])
+	// References to tables that aren't remapped are kept.
+	require.Equal(t, json.RawMessage("2"), row["workspace_id"])
+
+	r.ids["workspaces"] = map[int64]int64{}
+	require.ErrorContains(t, r.rewriteRefs(p, row), "not in the archive")
+}
@@ -0,0 +1,248 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/uptrace/bun"
+)
+
+// idRemapper gives restored rows new IDs and rewrites the references between them, so that an
+// archive can be restored into a cluster that already has other rows with the same IDs.
+type idRemapper struct {
+	// ids maps the IDs that rows had in the archive to their IDs in the database, per table. Only
+	// tables whose IDs are remapped have an entry.
+	ids map[string]map[int64]int64
+	// matched holds the IDs in the archive of the rows that matched existing rows, per table.
+	matched map[string]map[int64]bool
+}
+
+func newIDRemapper() *idRemapper {
+	return &idRemapper{
+		ids:     map[string]map[int64]int64{},
+		matched: map[string]map[int64]bool{},
+	}
+}
+
+// remapPlan describes how the rows of one table are remapped.
+type remapPlan struct {
+	table table
+	// sequence generates the new IDs of the rows, if the table has an id column backed by one.
+	sequence *string
+	// refs maps columns to the tables whose IDs they reference.
+	refs map[string]string
+	// nullable columns are cleared if they reference a row that isn't in the archive.
+	nullable map[string]bool
+}
+
+// plan reads the ID sequence and the foreign keys of a table to remap its rows.
+func (r *idRemapper) plan(ctx context.Context, tx bun.Tx, name string) (*remapPlan, error) {
+	t, ok := tableByName(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown table %s", name)
+	}
+	p := &remapPlan{table: t, refs: map[string]string{}, nullable: map[string]bool{}}
+
+	var fks []struct {
+		Column   string `bun:"column"`
+		RefTable string `bun:"ref_table"`
+	}
+	if err := tx.NewRaw(`
+SELECT a.attname AS column, rc.relname AS ref_table
+FROM pg_constraint c
+JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = c.conkey[1]
+JOIN pg_attribute ra ON ra.attrelid = c.confrelid AND ra.attnum = c.confkey[1]
+JOIN pg_class rc ON rc.oid = c.confrelid
+WHERE c.conrelid = to_regclass(quote_ident(?)) AND c.contype = 'f'
+	AND cardinality(c.conkey) = 1 AND ra.attname = 'id'`, name).Scan(ctx, &fks); err != nil {
+		return nil, fmt.Errorf("reading the foreign keys of %s: %w", name, err)
+	}
+	for _, fk := range fks {
+		p.refs[fk.Column] = fk.RefTable
+	}
+
+	var nullable []string
+	if err := tx.NewRaw(`
+SELECT column_name
+FROM information_schema.columns
+WHERE table_schema = current_schema() AND table_name = ? AND is_nullable = 'YES'`,
+		name).Scan(ctx, &nullable); err != nil {
+		return nil, fmt.Errorf("reading columns of %s: %w", name, err)
+	}
+	for _, c := range nullable {
+		p.nullable[c] = true
+	}
+
+	var err error
+	if p.sequence, err = idSequence(ctx, tx, name); err != nil {
+		return nil, err
+	}
+	if p.sequence != nil {
+		r.ids[name] = map[int64]int64{}
+		r.matched[name] = map[int64]bool{}
+	}
+	return p, nil
+}
+
+// remap returns the rows of a batch to insert, with new IDs and rewritten references. Rows that
+// match existing rows, and rows owned only by those, are left out.
+func (r *idRemapper) remap(
+	ctx context.Context, tx bun.Tx, p *remapPlan, batch []json.RawMessage,
+) ([]json.RawMessage, error) {
+	rows := make([]map[string]json.RawMessage, 0, len(batch))
+	for _, b := range batch {
+		var row map[string]json.RawMessage
+		if err := json.Unmarshal(b, &row); err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	rows, err := r.matchExisting(ctx, tx, p, rows)
+	if err != nil {
+		return nil, err
+	}
+	rows = r.skipOwned(p, rows)
+
+	// Every row of the batch gets its new ID before any reference is rewritten, so that rows can
+	// reference rows later in the same batch.
+	if p.sequence != nil && len(rows) > 0 {
+		var newIDs []int64
+		if err := tx.NewRaw("SELECT nextval(?) FROM generate_series(1, ?)",
+			*p.sequence, len(rows)).Scan(ctx, &newIDs); err != nil {
+			return nil, fmt.Errorf("allocating IDs: %w", err)
+		}
+		for i, row := range rows {
+			old, err := idValue(row["id"])
+			if err != nil || old == nil {
+				return nil, fmt.Errorf("row without an id: %s", row["id"])
+			}
+			r.ids[p.table.name][*old] = newIDs[i]
+			row["id"] = json.RawMessage(strconv.FormatInt(newIDs[i], 10))
+		}
+	}
+
+	out := make([]json.RawMessage, 0, len(rows))
+	for _, row := range rows {
+		if err := r.rewriteRefs(p, row); err != nil {
+			return nil, err
+		}
+		bs, err := json.Marshal(row)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, bs)
+	}
+	return out, nil
+}
+
+// matchExisting maps the rows that have the same natural key as existing rows to those, and
+// returns the rest.
+func (r *idRemapper) matchExisting(
+	ctx context.Context, tx bun.Tx, p *remapPlan, rows []map[string]json.RawMessage,
+) ([]map[string]json.RawMessage, error) {
+	if p.table.naturalKey == "" || len(rows) == 0 {
+		return rows, nil
+	}
+	keys := make([]string, 0, len(rows))
+	for _, row := range rows {
+		var key string
+		if err := json.Unmarshal(row[p.table.naturalKey], &key); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", p.table.naturalKey, err)
+		}
+		keys = append(keys, key)
+	}
+	var existing []struct {
+		ID  int64  `bun:"id"`
+		Key string `bun:"key"`
+	}
+	if err := tx.NewRaw(fmt.Sprintf("SELECT id, %[1]s AS key FROM %[2]s WHERE %[1]s IN (?)",
+		strconv.Quote(p.table.naturalKey), strconv.Quote(p.table.name)),
+		bun.In(keys)).Scan(ctx, &existing); err != nil {
+		return nil, fmt.Errorf("matching existing rows: %w", err)
+	}
+	byKey := make(map[string]int64, len(existing))
+	for _, e := range existing {
+		byKey[e.Key] = e.ID
+	}
+
+	var rest []map[string]json.RawMessage
+	for i, row := range rows {
+		id, ok := byKey[keys[i]]
+		if !ok {
+			rest = append(rest, row)
+			continue
+		}
+		old, err := idValue(row["id"])
+		if err != nil || old == nil {
+			return nil, fmt.Errorf("row without an id: %s", row["id"])
+		}
+		r.ids[p.table.name][*old] = id
+		r.matched[p.table.name][*old] = true
+	}
+	return rest, nil
+}
+
+// skipOwned leaves out the rows whose owners all matched existing rows, which keep their own.
+func (r *idRemapper) skipOwned(
+	p *remapPlan, rows []map[string]json.RawMessage,
+) []map[string]json.RawMessage {
+	if len(p.table.ownedBy) == 0 {
+		return rows
+	}
+	var rest []map[string]json.RawMessage
+	for _, row := range rows {
+		owned := true
+		for _, c := range p.table.ownedBy {
+			old, err := idValue(row[c])
+			if err != nil || old == nil || !r.matched[p.refs[c]][*old] {
+				owned = false
+				break
+			}
+		}
+		if !owned {
+			rest = append(rest, row)
+		}
+	}
+	return rest
+}
+
+// rewriteRefs points the references of a row at the remapped IDs of the rows they reference.
+// References to rows that aren't in the archive are cleared, or fail if they can't be.
+func (r *idRemapper) rewriteRefs(p *remapPlan, row map[string]json.RawMessage) error {
+	for c, ref := range p.refs {
+		ids, ok := r.ids[ref]
+		if !ok {
+			continue
+		}
+		old, err := idValue(row[c])
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", c, err)
+		}
+		if old == nil {
+			continue
+		}
+		if id, ok := ids[*old]; ok {
+			row[c] = json.RawMessage(strconv.FormatInt(id, 10))
+			continue
+		}
+		if !p.nullable[c] {
+			return fmt.Errorf("%s references %s %d, which is not in the archive", c, ref, *old)
+		}
+		row[c] = json.RawMessage("null")
+	}
+	return nil
+}
+
+// idValue returns the ID in a JSON value, or nil if it is null or missing.
+func idValue(raw json.RawMessage) (*int64, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var id *int64
+	if err := json.Unmarshal(raw, &id); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
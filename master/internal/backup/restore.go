@@ -0,0 +1,363 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/uptrace/bun"
+
+	"github.com/determined-ai/determined/master/internal/db"
+)
+
+// restoreBatchSize is how many rows are inserted per statement.
+const restoreBatchSize = 500
+
+// maxRowSize bounds the size of a single row in an archive; rows hold model definitions.
+const maxRowSize = 1 << 30
+
+// RestoreOptions configures a restore.
+type RestoreOptions struct {
+	// SkipExisting skips rows that already exist with the same content instead of failing the
+	// restore, to restore part of an archive into the cluster it came from. Rows whose key exists
+	// with different content still fail the restore.
+	SkipExisting bool
+	// RemapIDs gives restored rows new IDs and rewrites the references to them, to restore into a
+	// cluster that already has other rows with the same IDs. Users and groups that exist with the
+	// same name are used instead of being restored, and keep their own agent user groups, SSH keys
+	// and memberships.
+	RemapIDs bool
+}
+
+// RestoredTable is the outcome of restoring one table.
+type RestoredTable struct {
+	Name     string
+	Rows     int
+	Inserted int
+}
+
+// ReadManifest reads the manifest from the start of an archive and returns it along with a reader
+// positioned at the first table.
+func ReadManifest(r io.Reader) (*Manifest, *tar.Reader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading archive: %w", err)
+	}
+	if hdr.Name != manifestName {
+		return nil, nil, fmt.Errorf("archive starts with %s instead of %s", hdr.Name, manifestName)
+	}
+	var m Manifest
+	if err := json.NewDecoder(tr).Decode(&m); err != nil {
+		return nil, nil, fmt.Errorf("reading manifest: %w", err)
+	}
+	if m.FormatVersion != FormatVersion {
+		return nil, nil, fmt.Errorf("archive format version %d is not supported, expected %d",
+			m.FormatVersion, FormatVersion)
+	}
+	return &m, tr, nil
+}
+
+// validate checks that the archive was taken at the schema version of the database and that its
+// tables are ones Backup writes, in an order they can be restored in.
+func (m *Manifest) validate(schemaVersion int64) error {
+	if m.SchemaVersion != schemaVersion {
+		return fmt.Errorf("archive was taken at schema version %d but the database is at %d; "+
+			"restore into a cluster running the same version of Determined", m.SchemaVersion,
+			schemaVersion)
+	}
+	next := 0
+	for _, tm := range m.Tables {
+		i := next
+		for i < len(tables) && tables[i].name != tm.Name {
+			i++
+		}
+		if i == len(tables) {
+			if _, ok := tableByName(tm.Name); ok {
+				return fmt.Errorf("table %s is out of order", tm.Name)
+			}
+			return fmt.Errorf("unknown table %s", tm.Name)
+		}
+		next = i + 1
+	}
+	return nil
+}
+
+// Restore restores an archive written by Backup into the database, in a single transaction.
+func Restore(ctx context.Context, r io.Reader, opts RestoreOptions) ([]RestoredTable, error) {
+	if opts.SkipExisting && opts.RemapIDs {
+		return nil, errors.New("existing rows cannot be skipped when remapping IDs")
+	}
+	m, tr, err := ReadManifest(r)
+	if err != nil {
+		return nil, err
+	}
+	var remapper *idRemapper
+	if opts.RemapIDs {
+		remapper = newIDRemapper()
+	}
+
+	var restored []RestoredTable
+	err = db.Bun().RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		version, err := schemaVersion(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if err := m.validate(version); err != nil {
+			return err
+		}
+
+		for _, tm := range m.Tables {
+			hdr, err := tr.Next()
+			if err != nil {
+				return fmt.Errorf("reading %s: %w", tableFileName(tm.Name), err)
+			}
+			if hdr.Name != tableFileName(tm.Name) {
+				return fmt.Errorf("expected %s in archive, found %s", tableFileName(tm.Name), hdr.Name)
+			}
+			rt, err := restoreTable(ctx, tx, tm, tr, opts, remapper)
+			if err != nil {
+				return fmt.Errorf("restoring %s: %w", tm.Name, err)
+			}
+			restored = append(restored, *rt)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+func restoreTable(
+	ctx context.Context, tx bun.Tx, tm TableManifest, r io.Reader, opts RestoreOptions,
+	remapper *idRemapper,
+) (*RestoredTable, error) {
+	current, err := tableColumns(ctx, tx, tm.Name)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(current))
+	for _, c := range current {
+		known[c] = true
+	}
+	for _, c := range tm.Columns {
+		if !known[c] {
+			return nil, fmt.Errorf("column %s does not exist", c)
+		}
+	}
+	query := fmt.Sprintf(
+		"INSERT INTO %[1]s (%[2]s) OVERRIDING SYSTEM VALUE "+
+			"SELECT %[2]s FROM jsonb_populate_recordset(NULL::%[1]s, ?::jsonb)",
+		strconv.Quote(tm.Name), columnList("", tm.Columns))
+	if opts.SkipExisting {
+		key, err := primaryKey(ctx, tx, tm.Name)
+		if err != nil {
+			return nil, err
+		}
+		query = skipExistingQuery(tm.Name, tm.Columns, key)
+	}
+	var plan *remapPlan
+	if remapper != nil {
+		if plan, err = remapper.plan(ctx, tx, tm.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	rt := &RestoredTable{Name: tm.Name}
+	insert := func(batch []json.RawMessage) error {
+		if plan != nil {
+			var err error
+			if batch, err = remapper.remap(ctx, tx, plan, batch); err != nil {
+				return err
+			}
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		rows, err := json.Marshal(batch)
+		if err != nil {
+			return err
+		}
+		if !opts.SkipExisting {
+			res, err := tx.NewRaw(query, string(rows)).Exec(ctx)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			rt.Inserted += int(n)
+			return nil
+		}
+
+		var result struct {
+			Inserted int     `bun:"inserted"`
+			Conflict *string `bun:"conflict"`
+		}
+		if err := tx.NewRaw(query, string(rows)).Scan(ctx, &result); err != nil {
+			return err
+		}
+		if result.Conflict != nil {
+			return fmt.Errorf("row %s already exists with different content", *result.Conflict)
+		}
+		rt.Inserted += result.Inserted
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRowSize)
+	var batch []json.RawMessage
+	for scanner.Scan() {
+		batch = append(batch, json.RawMessage(append([]byte(nil), scanner.Bytes()...)))
+		rt.Rows++
+		if len(batch) == restoreBatchSize {
+			if err := insert(batch); err != nil {
+				return nil, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := insert(batch); err != nil {
+		return nil, err
+	}
+	if rt.Rows != tm.Rows {
+		return nil, fmt.Errorf("archive has %d rows but its manifest lists %d", rt.Rows, tm.Rows)
+	}
+
+	// Remapped IDs are taken from the sequence, so it is already past them.
+	if known["id"] && rt.Inserted > 0 && remapper == nil {
+		if err := resetSequence(ctx, tx, tm.Name); err != nil {
+			return nil, err
+		}
+	}
+	return rt, nil
+}
+
+// columnList returns the quoted columns, qualified by alias unless it is empty.
+func columnList(alias string, columns []string) string {
+	idents := make([]string, 0, len(columns))
+	for _, c := range columns {
+		if alias != "" {
+			idents = append(idents, alias+"."+strconv.Quote(c))
+		} else {
+			idents = append(idents, strconv.Quote(c))
+		}
+	}
+	return strings.Join(idents, ", ")
+}
+
+// skipExistingQuery returns a query that inserts the rows of a batch that don't exist yet, and
+// reports how many it inserted and the first row that exists with different content. Rows are
+// matched on key, or on their content for tables without a primary key; their content is compared
+// as JSON, since not every column type has an equality operator.
+func skipExistingQuery(name string, columns, key []string) string {
+	match := func(a, b string, cols []string) string {
+		conds := make([]string, 0, len(cols))
+		for _, c := range cols {
+			conds = append(conds, fmt.Sprintf("%[1]s.%[3]s = %[2]s.%[3]s", a, b, strconv.Quote(c)))
+		}
+		return strings.Join(conds, " AND ")
+	}
+	content := func(alias string) string {
+		return fmt.Sprintf("to_jsonb(ROW(%s))", columnList(alias, columns))
+	}
+
+	returning := fmt.Sprintf("to_jsonb(ROW(%s)) AS r", columnList("", columns))
+	inserted := "SELECT 1 FROM inserted x WHERE x.r = " + content("i")
+	existing, conflict := "TRUE", content("i")
+	if len(key) > 0 {
+		returning = columnList("", key)
+		inserted = "SELECT 1 FROM inserted x WHERE " + match("x", "i", key)
+		existing = match("e", "i", key)
+		// Report the key of the conflicting row rather than its content, which may be secret.
+		pairs := make([]string, 0, len(key))
+		for _, c := range key {
+			pairs = append(pairs, fmt.Sprintf("'%s', i.%s", c, strconv.Quote(c)))
+		}
+		conflict = fmt.Sprintf("jsonb_build_object(%s)", strings.Join(pairs, ", "))
+	}
+	return fmt.Sprintf(`
+WITH incoming AS (
+	SELECT %[2]s FROM jsonb_populate_recordset(NULL::%[1]s, ?::jsonb)
+), inserted AS (
+	INSERT INTO %[1]s (%[2]s) OVERRIDING SYSTEM VALUE
+	SELECT %[2]s FROM incoming
+	ON CONFLICT DO NOTHING
+	RETURNING %[3]s
+)
+SELECT
+	(SELECT count(*) FROM inserted) AS inserted,
+	(
+		SELECT %[8]s::text FROM incoming i
+		WHERE NOT EXISTS (%[5]s)
+		AND NOT EXISTS (SELECT 1 FROM %[1]s e WHERE %[6]s AND %[7]s = %[4]s)
+		LIMIT 1
+	) AS conflict`,
+		strconv.Quote(name), columnList("", columns), returning, content("i"), inserted, existing,
+		content("e"), conflict)
+}
+
+// primaryKey returns the primary key columns of a table, or none if it has no primary key.
+func primaryKey(ctx context.Context, tx bun.Tx, name string) ([]string, error) {
+	var key []string
+	if err := tx.NewRaw(`
+SELECT a.attname
+FROM pg_index i
+JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+WHERE i.indrelid = to_regclass(quote_ident(?)) AND i.indisprimary
+ORDER BY array_position(i.indkey::int2[], a.attnum)`, name).Scan(ctx, &key); err != nil {
+		return nil, fmt.Errorf("reading the primary key of %s: %w", name, err)
+	}
+	return key, nil
+}
+
+// resetSequence moves the sequence behind a table's id column past the restored IDs, so that rows
+// created later don't collide with them.
+func resetSequence(ctx context.Context, tx bun.Tx, name string) error {
+	sequence, err := idSequence(ctx, tx, name)
+	if err != nil {
+		return err
+	}
+	if sequence == nil {
+		return nil
+	}
+	if _, err := tx.NewRaw(fmt.Sprintf(
+		"SELECT setval(?, (SELECT max(id) FROM %s))", strconv.Quote(name),
+	), *sequence).Exec(ctx); err != nil {
+		return fmt.Errorf("resetting the id sequence of %s: %w", name, err)
+	}
+	return nil
+}
+
+// idSequence returns the sequence behind a table's id column, or nil if it has none.
+func idSequence(ctx context.Context, tx bun.Tx, name string) (*string, error) {
+	var sequence *string
+	if err := tx.NewRaw(`
+SELECT COALESCE(
+	pg_get_serial_sequence(quote_ident(?), 'id'),
+	substring(column_default FROM 'nextval\(''([^'']+)''')
+)
+FROM information_schema.columns
+WHERE table_schema = current_schema() AND table_name = ? AND column_name = 'id'`,
+		name, name).Scan(ctx, &sequence); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("finding the id sequence of %s: %w", name, err)
+	}
+	return sequence, nil
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -15,6 +16,7 @@ import (
 	"github.com/determined-ai/determined/master/internal/rm/tasklist"
 	"github.com/determined-ai/determined/master/internal/sproto"
 	"github.com/determined-ai/determined/master/internal/task"
+	"github.com/determined-ai/determined/master/internal/workspace"
 	"github.com/determined-ai/determined/master/pkg/logger"
 	"github.com/determined-ai/determined/master/pkg/model"
 	"github.com/determined-ai/determined/master/pkg/protoutils/protoconverter"
@@ -66,6 +68,14 @@ func runCheckpointGCTask(
 
 	taskSpec.AgentUserGroup = agentUserGroup
 	taskSpec.Owner = owner
+	// The experiment's environment variables may reference secrets of its workspace.
+	workspaceIDs, err := workspace.WorkspacesIDsByExperimentIDs(context.TODO(), []int{expID})
+	if err != nil {
+		return fmt.Errorf("getting the workspace of experiment %d: %w", expID, err)
+	}
+	if len(workspaceIDs) == 1 {
+		taskSpec.WorkspaceID = workspaceIDs[0]
+	}
 
 	gcSpec := tasks.GCCkptSpec{
 		Base:               taskSpec,
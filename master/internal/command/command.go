@@ -154,9 +154,11 @@ func (c *Command) Start(ctx context.Context) error {
 			JobSubmissionTime:   c.registeredTime,
 			IsUserVisible:       true,
 			Name:                c.Config.Description,
-			SlotsNeeded:         c.Config.Resources.Slots,
+			WorkspaceID:         int(c.Metadata.WorkspaceID),
+			SlotsNeeded:         c.Config.Resources.WholeSlots(),
+			SlotFraction:        c.Config.Resources.SlotFraction(),
 			ResourcePool:        c.Config.Resources.ResourcePool,
-			FittingRequirements: sproto.FittingRequirements{SingleAgent: true},
+			FittingRequirements: sproto.FittingRequirements{SingleAgent: !c.GenericCommandSpec.CanSpanAgents()},
 			ProxyPorts:          sproto.NewProxyPortConfig(c.GenericCommandSpec.ProxyPorts(), c.taskID),
 			IdleTimeout:         idleWatcherConfig,
 			Restore:             c.restored,
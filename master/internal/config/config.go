@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"path/filepath"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -129,7 +130,15 @@ func DefaultConfig() *Config {
 			CacheDir: "/var/cache/determined",
 		},
 		FeatureSwitches: []string{},
-		ResourceConfig:  *DefaultResourceConfig(),
+		Trash: TrashConfig{
+			RetentionPeriod: model.Duration(DefaultTrashRetentionPeriod),
+		},
+		SSHGateway: SSHGatewayConfig{
+			Port:           DefaultSSHGatewayPort,
+			CertificateTTL: model.Duration(DefaultSSHGatewayCertificateTTL),
+		},
+		DBMaintenance:  DefaultDBMaintenanceConfig(),
+		ResourceConfig: *DefaultResourceConfig(),
 	}
 }
 
@@ -158,6 +167,15 @@ type Config struct {
 	Webhooks              WebhooksConfig                    `json:"webhooks"`
 	FeatureSwitches       []string                          `json:"feature_switches"`
 	ReservedPorts         []int                             `json:"reserved_ports"`
+	Trash                 TrashConfig                       `json:"trash"`
+	DBMaintenance         DBMaintenanceConfig               `json:"db_maintenance"`
+	ModelExport           ModelExportConfig                 `json:"model_export"`
+	Secrets               SecretsConfig                     `json:"secrets"`
+	SSHGateway            SSHGatewayConfig                  `json:"ssh_gateway"`
+	ImagePolicy           ImagePolicyConfig                 `json:"image_policy"`
+	// EgressPolicy restricts the network traffic of the containers of tasks in workspaces without
+	// an egress policy of their own.
+	EgressPolicy *model.EgressPolicy `json:"egress_policy"`
 	ResourceConfig
 
 	// Internal contains "hidden" useful debugging configurations.
@@ -198,6 +216,9 @@ func (c Config) Printable() ([]byte, error) {
 	if c.Telemetry.SegmentWebUIKey != "" {
 		c.Telemetry.SegmentWebUIKey = hiddenValue
 	}
+	if c.Secrets.MasterKey != "" {
+		c.Secrets.MasterKey = hiddenValue
+	}
 	if c.TaskContainerDefaults.RegistryAuth != nil {
 		if c.TaskContainerDefaults.RegistryAuth.Password != "" {
 			// RegistryAuth is a pointer, so if we need to hide the password we need to be very
@@ -418,6 +439,26 @@ func (i *InternalConfig) Validate() []error {
 	return errs
 }
 
+// DefaultTrashRetentionPeriod is how long trashed entities are kept if not configured otherwise.
+const DefaultTrashRetentionPeriod = 7 * 24 * time.Hour
+
+// TrashConfig configures soft deletion of experiments, projects and models. When enabled,
+// deleting one of those moves it to the trash, from where it can be restored until the retention
+// period expires and it is deleted for good. The trash is emptied even while it is disabled.
+type TrashConfig struct {
+	Enabled         bool           `json:"enabled"`
+	RetentionPeriod model.Duration `json:"retention_period"`
+}
+
+// Validate implements the check.Validatable interface.
+func (t TrashConfig) Validate() []error {
+	var errs []error
+	if t.Enabled && t.RetentionPeriod <= 0 {
+		errs = append(errs, errors.New("trash.retention_period must be positive"))
+	}
+	return errs
+}
+
 // ObservabilityConfig is the configuration for observability metrics.
 type ObservabilityConfig struct {
 	EnablePrometheus bool `json:"enable_prometheus"`
@@ -457,6 +498,9 @@ func readRMPreemptionStatus(config *Config, rpName string) bool {
 		return config.ResourceManager.AgentRM.Scheduler.GetPreemption()
 	case config.ResourceManager.KubernetesRM != nil:
 		return config.ResourceManager.KubernetesRM.GetPreemption()
+	case config.ResourceManager.HPCRM() != nil:
+		// Slurm and PBS preempt jobs on their own, the master only passes the signal along.
+		return false
 	default:
 		panic("unexpected resource configuration")
 	}
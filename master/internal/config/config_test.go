@@ -3,7 +3,10 @@ package config
 
 import (
 	"bytes"
+	"encoding/base64"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -15,7 +18,9 @@ import (
 
 	"github.com/determined-ai/determined/master/internal/config/provconfig"
 	"github.com/determined-ai/determined/master/pkg/aproto"
+	"github.com/determined-ai/determined/master/pkg/check"
 	"github.com/determined-ai/determined/master/pkg/config"
+	"github.com/determined-ai/determined/master/pkg/device"
 	"github.com/determined-ai/determined/master/pkg/logger"
 	"github.com/determined-ai/determined/master/pkg/model"
 	"github.com/determined-ai/determined/master/pkg/ptrs"
@@ -414,6 +419,7 @@ func TestPrintableConfig(t *testing.T) {
 	masterSecret := "my_master_secret"
 	webuiSecret := "my_webui_secret"
 	registryAuthSecret := "i_love_cellos"
+	secretsMasterKey := "my_secrets_master_key"
 
 	raw := fmt.Sprintf(`
 db:
@@ -439,7 +445,10 @@ task_container_defaults:
     password: %v
     shm_size_bytes: 4294967296
     network_mode: bridge
-`, s3Key, s3Secret, masterSecret, webuiSecret, registryAuthSecret)
+
+secrets:
+  master_key: %v
+`, s3Key, s3Secret, masterSecret, webuiSecret, registryAuthSecret, secretsMasterKey)
 
 	expected := Config{
 		Logging: model.LoggingConfig{
@@ -471,6 +480,7 @@ task_container_defaults:
 			ShmSizeBytes: 4294967296,
 			NetworkMode:  "bridge",
 		},
+		Secrets: SecretsConfig{MasterKey: secretsMasterKey},
 	}
 
 	unmarshaled := Config{
@@ -491,6 +501,7 @@ task_container_defaults:
 	assert.Assert(t, !bytes.Contains(printable, []byte(masterSecret)))
 	assert.Assert(t, !bytes.Contains(printable, []byte(webuiSecret)))
 	assert.Assert(t, !bytes.Contains(printable, []byte(registryAuthSecret)))
+	assert.Assert(t, !bytes.Contains(printable, []byte(secretsMasterKey)))
 
 	// Ensure that the original was unmodified.
 	assert.DeepEqual(t, unmarshaled, expected)
@@ -650,3 +661,258 @@ resource_manager:
 		})
 	}
 }
+
+func TestTrashConfig(t *testing.T) {
+	raw := `
+trash:
+  enabled: true
+  retention_period: 72h
+`
+	unmarshaled := DefaultConfig()
+	err := yaml.Unmarshal([]byte(raw), unmarshaled, yaml.DisallowUnknownFields)
+	require.NoError(t, err)
+	require.True(t, unmarshaled.Trash.Enabled)
+	require.Equal(t, model.Duration(72*time.Hour), unmarshaled.Trash.RetentionPeriod)
+	require.Empty(t, unmarshaled.Trash.Validate())
+
+	require.Equal(t, model.Duration(DefaultTrashRetentionPeriod),
+		DefaultConfig().Trash.RetentionPeriod)
+	require.Len(t, TrashConfig{Enabled: true}.Validate(), 1)
+	require.Empty(t, TrashConfig{}.Validate())
+}
+
+func TestDBMaintenanceConfig(t *testing.T) {
+	raw := `
+db_maintenance:
+  enabled: true
+  window: "22:30-04:00"
+  time_budget: 5m
+  vacuum_tables: [task_logs]
+`
+	unmarshaled := DefaultConfig()
+	err := yaml.Unmarshal([]byte(raw), unmarshaled, yaml.DisallowUnknownFields)
+	require.NoError(t, err)
+	conf := unmarshaled.DBMaintenance
+	require.True(t, conf.Enabled)
+	require.Equal(t, model.Duration(5*time.Minute), conf.TimeBudget)
+	require.Equal(t, model.Duration(DefaultDBMaintenanceInterval), conf.Interval)
+	require.Equal(t, []string{"task_logs"}, conf.VacuumTables)
+	require.Empty(t, conf.Validate())
+	require.Empty(t, DefaultDBMaintenanceConfig().Validate())
+
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 1, 1, hour, minute, 0, 0, time.UTC)
+	}
+	require.True(t, conf.InWindow(at(23, 0)))
+	require.True(t, conf.InWindow(at(3, 59)))
+	require.False(t, conf.InWindow(at(4, 0)))
+	require.False(t, conf.InWindow(at(12, 0)))
+
+	conf.Window = "01:00-05:00"
+	require.True(t, conf.InWindow(at(1, 0)))
+	require.False(t, conf.InWindow(at(0, 59)))
+	require.True(t, conf.InWindow(time.Date(2024, 1, 1, 6, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60))),
+		"the window is in UTC")
+
+	conf.Window = ""
+	require.True(t, conf.InWindow(at(12, 0)))
+
+	for _, window := range []string{"1-2", "01:00", "01:00-01:00", "25:00-02:00"} {
+		conf.Window = window
+		require.Len(t, conf.Validate(), 1, window)
+		require.False(t, conf.InWindow(at(1, 30)), window)
+	}
+}
+
+func TestModelExportConfig(t *testing.T) {
+	raw := `
+model_export:
+  formats:
+    onnx:
+      image: example/onnx-export:1.0
+      command: ["python", "-m", "export_onnx"]
+      environment_variables: ["OPSET=17"]
+    safetensors:
+      command: ["convert-safetensors"]
+`
+	unmarshaled := DefaultConfig()
+	err := yaml.Unmarshal([]byte(raw), unmarshaled, yaml.DisallowUnknownFields)
+	require.NoError(t, err)
+	conf := unmarshaled.ModelExport
+	require.Equal(t, []string{"onnx", "safetensors"}, conf.FormatNames())
+	require.Equal(t, "example/onnx-export:1.0", conf.Formats["onnx"].Image)
+	require.Equal(t, []string{"OPSET=17"}, conf.Formats["onnx"].EnvironmentVariables)
+	require.Empty(t, conf.Validate())
+	require.Empty(t, DefaultConfig().ModelExport.Validate())
+
+	conf.Formats["ONNX"] = ModelExportFormatConfig{Command: []string{"x"}}
+	conf.Formats["torchscript"] = ModelExportFormatConfig{}
+	require.Len(t, conf.Validate(), 2)
+}
+
+func TestSecretsConfig(t *testing.T) {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	encoded := base64.StdEncoding.EncodeToString(key)
+
+	require.Empty(t, SecretsConfig{}.Validate())
+	require.False(t, SecretsConfig{}.Enabled())
+	decoded, err := SecretsConfig{MasterKey: encoded}.Key()
+	require.NoError(t, err)
+	require.Equal(t, key, decoded)
+
+	keyFile := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(keyFile, []byte(encoded+"\n"), 0o600))
+	decoded, err = SecretsConfig{MasterKeyFile: keyFile}.Key()
+	require.NoError(t, err)
+	require.Equal(t, key, decoded)
+
+	require.Len(t, SecretsConfig{MasterKey: encoded, MasterKeyFile: keyFile}.Validate(), 1)
+	require.Len(t, SecretsConfig{MasterKey: "not base64"}.Validate(), 1)
+	require.Len(t, SecretsConfig{MasterKey: base64.StdEncoding.EncodeToString(key[:16])}.Validate(), 1)
+}
+
+func TestSSHGatewayConfig(t *testing.T) {
+	raw := `
+ssh_gateway:
+  enabled: true
+  certificate_ttl: 8h
+`
+	unmarshaled := DefaultConfig()
+	err := yaml.Unmarshal([]byte(raw), unmarshaled, yaml.DisallowUnknownFields)
+	require.NoError(t, err)
+	conf := unmarshaled.SSHGateway
+	require.True(t, conf.Enabled)
+	require.Equal(t, DefaultSSHGatewayPort, conf.Port)
+	require.Equal(t, model.Duration(8*time.Hour), conf.CertificateTTL)
+	require.Empty(t, conf.Validate())
+
+	require.Empty(t, SSHGatewayConfig{}.Validate())
+	require.Len(t, SSHGatewayConfig{Enabled: true}.Validate(), 2)
+}
+
+func TestImagePolicyConfig(t *testing.T) {
+	raw := `
+image_policy:
+  admission_webhook:
+    url: https://admission.example.com/images
+    timeout: 5s
+`
+	unmarshaled := DefaultConfig()
+	err := yaml.Unmarshal([]byte(raw), unmarshaled, yaml.DisallowUnknownFields)
+	require.NoError(t, err)
+	webhook := unmarshaled.ImagePolicy.AdmissionWebhook
+	require.NotNil(t, webhook)
+	require.Equal(t, "https://admission.example.com/images", webhook.URL)
+	require.Equal(t, model.Duration(5*time.Second), webhook.Timeout)
+	require.Empty(t, webhook.Validate())
+
+	require.Nil(t, DefaultConfig().ImagePolicy.AdmissionWebhook)
+	require.Len(t, ImageAdmissionWebhookConfig{URL: "admission.example.com", Timeout: -1}.Validate(), 2)
+}
+
+func TestEgressPolicyConfig(t *testing.T) {
+	raw := `
+egress_policy:
+  mode: allow_list
+  allow: [10.0.0.0/8, pypi.org]
+  task_types:
+    NOTEBOOK:
+      mode: master_only
+`
+	unmarshaled := DefaultConfig()
+	err := yaml.Unmarshal([]byte(raw), unmarshaled, yaml.DisallowUnknownFields)
+	require.NoError(t, err)
+	policy := unmarshaled.EgressPolicy
+	require.NotNil(t, policy)
+	require.Empty(t, policy.Validate())
+	require.Equal(t, model.EgressRule{
+		Mode: model.EgressAllowList, Allow: []string{"10.0.0.0/8", "pypi.org"},
+	}, policy.ForTaskType(model.TaskTypeTrial))
+	require.Equal(t, model.EgressRule{Mode: model.EgressMasterOnly},
+		policy.ForTaskType(model.TaskTypeNotebook))
+
+	policy.TaskTypes["TRAINING"] = model.EgressRule{Mode: model.EgressMasterOnly}
+	policy.TaskTypes[model.TaskTypeShell] = model.EgressRule{Mode: "deny", Allow: []string{"not a host"}}
+	require.Len(t, policy.Validate(), 3)
+}
+
+func TestHPCResourceManagerConfig(t *testing.T) {
+	raw := `
+resource_manager:
+  type: slurm
+  job_storage_root: /shared/determined
+`
+	var unmarshaled Config
+	err := yaml.Unmarshal([]byte(raw), &unmarshaled, yaml.DisallowUnknownFields)
+	require.NoError(t, err)
+	require.NoError(t, unmarshaled.ResolveResource())
+	rm := unmarshaled.ResourceManager
+	require.Nil(t, rm.AgentRM)
+	require.Nil(t, rm.PbsRM)
+	require.Equal(t, rm.SlurmRM, rm.HPCRM())
+	require.Equal(t, HPCResourceManagerConfig{
+		JobStorageRoot:             "/shared/determined",
+		ContainerRunType:           SingularityContainerRunType,
+		PollInterval:               model.Duration(10 * time.Second),
+		SlotType:                   device.CUDA,
+		DefaultAuxResourcePool:     "default",
+		DefaultComputeResourcePool: "default",
+	}, *rm.SlurmRM)
+	require.Len(t, unmarshaled.ResourcePools, 1)
+	require.False(t, readRMPreemptionStatus(&unmarshaled, "default"))
+	require.NoError(t, check.Validate(rm.SlurmRM))
+
+	raw = `
+resource_manager:
+  type: pbs
+  job_storage_root: /shared/determined
+  container_run_type: podman
+  slot_type: gpu
+  master_host: login-1
+  no_default_resource_pools: true
+`
+	unmarshaled = Config{}
+	err = yaml.Unmarshal([]byte(raw), &unmarshaled, yaml.DisallowUnknownFields)
+	require.NoError(t, err)
+	rm = unmarshaled.ResourceManager
+	require.Nil(t, rm.SlurmRM)
+	require.Equal(t, rm.PbsRM, rm.HPCRM())
+	require.Equal(t, PodmanContainerRunType, rm.PbsRM.ContainerRunType)
+	require.Equal(t, device.CUDA, rm.PbsRM.SlotType)
+	require.Equal(t, "login-1", rm.PbsRM.MasterHost)
+	require.Empty(t, rm.PbsRM.DefaultComputeResourcePool)
+
+	invalid := *rm.PbsRM
+	invalid.JobStorageRoot = "relative"
+	invalid.ContainerRunType = "docker"
+	require.ErrorContains(t, check.Validate(invalid), "job_storage_root must be an absolute path")
+	require.ErrorContains(t, check.Validate(invalid), "container_run_type must be")
+}
+
+func TestWorkspaceNamespacesConfig(t *testing.T) {
+	raw := `
+resource_manager:
+  type: kubernetes
+  namespace: determined
+  workspace_namespaces:
+    labels:
+      team: research
+`
+	var unmarshaled Config
+	err := yaml.Unmarshal([]byte(raw), &unmarshaled, yaml.DisallowUnknownFields)
+	require.NoError(t, err)
+	conf := unmarshaled.ResourceManager.KubernetesRM.WorkspaceNamespaces
+	require.Equal(t, &WorkspaceNamespacesConfig{
+		NamePrefix:         "det-workspace-",
+		Labels:             map[string]string{"team": "research"},
+		ServiceAccountName: "determined-task",
+	}, conf)
+	require.NoError(t, check.Validate(conf))
+
+	invalid := *conf
+	invalid.NamePrefix = "Workspace_"
+	require.ErrorContains(t, check.Validate(invalid), "name_prefix")
+}
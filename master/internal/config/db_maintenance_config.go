@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+const (
+	// DefaultDBMaintenanceInterval is how often database maintenance runs if not configured
+	// otherwise.
+	DefaultDBMaintenanceInterval = time.Hour
+	// DefaultDBMaintenanceTimeBudget bounds a single maintenance run if not configured otherwise.
+	DefaultDBMaintenanceTimeBudget = 10 * time.Minute
+	// DefaultRolledBackMetricsRetention is how long metrics rolled back by a trial restart are kept
+	// if not configured otherwise.
+	DefaultRolledBackMetricsRetention = 30 * 24 * time.Hour
+	// DefaultVacuumDeadTupleRatio is the share of dead tuples at which a table is vacuumed if not
+	// configured otherwise.
+	DefaultVacuumDeadTupleRatio = 0.2
+)
+
+// DefaultVacuumTables are the tables that take the most updates and deletes.
+var DefaultVacuumTables = []string{
+	"raw_steps", "raw_validations", "generic_metrics", "metric_rollups",
+	"task_logs", "trial_logs", "allocations", "runs", "experiments",
+}
+
+// DBMaintenanceConfig configures the scheduled database maintenance done by the master: pruning
+// data nothing refers to anymore, vacuuming bloated tables and reporting table sizes.
+type DBMaintenanceConfig struct {
+	Enabled  bool           `json:"enabled"`
+	Interval model.Duration `json:"interval"`
+	// Window restricts scheduled runs to a time of day, in UTC, formatted as "HH:MM-HH:MM". The
+	// window may wrap around midnight. Runs may happen at any time if it is empty.
+	Window string `json:"window"`
+	// TimeBudget bounds how long a single run may take; whatever is left is picked up by the next.
+	TimeBudget model.Duration `json:"time_budget"`
+	// RolledBackMetricsRetention is how long metrics rolled back by a trial restart are kept
+	// before they are deleted. They are kept forever if it is zero.
+	RolledBackMetricsRetention model.Duration `json:"rolled_back_metrics_retention"`
+	// OrphanedLogsRetention is how long the logs of trials whose experiment was deleted are kept
+	// before they are deleted. They are kept forever if it is zero.
+	OrphanedLogsRetention model.Duration `json:"orphaned_logs_retention"`
+	VacuumTables          []string       `json:"vacuum_tables"`
+	VacuumDeadTupleRatio  float64        `json:"vacuum_dead_tuple_ratio"`
+}
+
+// DefaultDBMaintenanceConfig returns the default database maintenance configuration.
+func DefaultDBMaintenanceConfig() DBMaintenanceConfig {
+	return DBMaintenanceConfig{
+		Interval:                   model.Duration(DefaultDBMaintenanceInterval),
+		TimeBudget:                 model.Duration(DefaultDBMaintenanceTimeBudget),
+		RolledBackMetricsRetention: model.Duration(DefaultRolledBackMetricsRetention),
+		VacuumTables:               DefaultVacuumTables,
+		VacuumDeadTupleRatio:       DefaultVacuumDeadTupleRatio,
+	}
+}
+
+// Validate implements the check.Validatable interface.
+func (d DBMaintenanceConfig) Validate() []error {
+	var errs []error
+	if d.Interval <= 0 {
+		errs = append(errs, errors.New("db_maintenance.interval must be positive"))
+	}
+	if d.TimeBudget <= 0 {
+		errs = append(errs, errors.New("db_maintenance.time_budget must be positive"))
+	}
+	if d.RolledBackMetricsRetention < 0 {
+		errs = append(errs, errors.New("db_maintenance.rolled_back_metrics_retention must not be negative"))
+	}
+	if d.OrphanedLogsRetention < 0 {
+		errs = append(errs, errors.New("db_maintenance.orphaned_logs_retention must not be negative"))
+	}
+	if d.VacuumDeadTupleRatio <= 0 || d.VacuumDeadTupleRatio > 1 {
+		errs = append(errs, errors.New("db_maintenance.vacuum_dead_tuple_ratio must be in (0, 1]"))
+	}
+	if _, _, err := parseWindow(d.Window); err != nil {
+		errs = append(errs, errors.Wrap(err, "db_maintenance.window"))
+	}
+	return errs
+}
+
+// InWindow returns whether t falls within the maintenance window.
+func (d DBMaintenanceConfig) InWindow(t time.Time) bool {
+	start, end, err := parseWindow(d.Window)
+	if err != nil {
+		return false
+	}
+	if start == end {
+		return true
+	}
+	t = t.UTC()
+	now := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if start < end {
+		return start <= now && now < end
+	}
+	return now >= start || now < end
+}
+
+// parseWindow parses a window of the form "HH:MM-HH:MM" into offsets from midnight. An empty
+// window spans the whole day and is returned as two equal offsets.
+func parseWindow(window string) (start, end time.Duration, err error) {
+	if window == "" {
+		return 0, 0, nil
+	}
+	from, to, ok := strings.Cut(window, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("%q is not of the form HH:MM-HH:MM", window)
+	}
+	if start, err = parseTimeOfDay(strings.TrimSpace(from)); err != nil {
+		return 0, 0, err
+	}
+	if end, err = parseTimeOfDay(strings.TrimSpace(to)); err != nil {
+		return 0, 0, err
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("%q is empty", window)
+	}
+	return start, end, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day of the form HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// DefaultImageAdmissionTimeout is how long the master waits for the image admission webhook if
+// not configured otherwise.
+const DefaultImageAdmissionTimeout = 10 * time.Second
+
+// ImagePolicyConfig configures the checks the master runs on the images of tasks when they are
+// submitted, in addition to the image policies of their workspaces.
+type ImagePolicyConfig struct {
+	// AdmissionWebhook is an external service that approves or denies every image.
+	AdmissionWebhook *ImageAdmissionWebhookConfig `json:"admission_webhook"`
+}
+
+// ImageAdmissionWebhookConfig configures the image admission webhook. The master POSTs each image
+// it is about to run to the URL and only runs the image if the response allows it. Tasks are
+// denied if the webhook can't be reached.
+type ImageAdmissionWebhookConfig struct {
+	URL string `json:"url"`
+	// Timeout bounds each call to the webhook, or is DefaultImageAdmissionTimeout if unset.
+	Timeout model.Duration `json:"timeout"`
+}
+
+// Validate implements the check.Validatable interface.
+func (i ImageAdmissionWebhookConfig) Validate() []error {
+	var errs []error
+	if u, err := url.Parse(i.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		errs = append(errs, errors.Errorf(
+			"image_policy.admission_webhook.url must be an http or https URL, got %q", i.URL))
+	}
+	if i.Timeout < 0 {
+		errs = append(errs, errors.New("image_policy.admission_webhook.timeout must not be negative"))
+	}
+	return errs
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+var modelExportFormatPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// ModelExportConfig configures the formats that model versions can be exported to.
+type ModelExportConfig struct {
+	// Formats maps format tags, such as "onnx" or "torchscript", to how to convert checkpoints
+	// to them.
+	Formats map[string]ModelExportFormatConfig `json:"formats"`
+}
+
+// ModelExportFormatConfig describes how to convert the checkpoints of a model version to a format.
+type ModelExportFormatConfig struct {
+	// Image is the container image the conversion runs in. It defaults to the image of the task
+	// container defaults.
+	Image string `json:"image"`
+	// Command is run with the checkpoints of the model version in $DET_EXPORT_INPUT_DIR and must
+	// write the exported model to $DET_EXPORT_OUTPUT_DIR.
+	Command []string `json:"command"`
+	// EnvironmentVariables are set for Command, in the form NAME=VALUE.
+	EnvironmentVariables []string `json:"environment_variables"`
+}
+
+// FormatNames returns the configured format tags in order.
+func (m ModelExportConfig) FormatNames() []string {
+	names := make([]string, 0, len(m.Formats))
+	for name := range m.Formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate implements the check.Validatable interface.
+func (m ModelExportConfig) Validate() []error {
+	var errs []error
+	for _, name := range m.FormatNames() {
+		if !modelExportFormatPattern.MatchString(name) {
+			errs = append(errs, errors.Errorf(
+				"model_export.formats: %q must be lowercase letters, digits, '_', '.' or '-'", name))
+		}
+		if len(m.Formats[name].Command) == 0 {
+			errs = append(errs, errors.Errorf("model_export.formats.%s.command must not be empty", name))
+		}
+	}
+	return errs
+}
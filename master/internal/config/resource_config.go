@@ -24,11 +24,11 @@ func (r *ResourceConfig) ResolveResource() error {
 			AgentRM: &AgentResourceManagerConfig{},
 		}
 	}
-	if r.ResourceManager.AgentRM == nil && r.ResourceManager.KubernetesRM == nil {
+	if r.ResourceManager.AgentRM == nil && r.ResourceManager.KubernetesRM == nil &&
+		r.ResourceManager.HPCRM() == nil {
 		r.ResourceManager.AgentRM = &AgentResourceManagerConfig{}
 	}
-	if r.ResourcePools == nil &&
-		(r.ResourceManager.AgentRM != nil || r.ResourceManager.KubernetesRM != nil) {
+	if r.ResourcePools == nil {
 		defaultPool := defaultRPConfig()
 		defaultPool.PoolName = defaultResourcePoolName
 		r.ResourcePools = []ResourcePoolConfig{defaultPool}
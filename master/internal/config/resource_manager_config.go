@@ -2,11 +2,15 @@ package config
 
 import (
 	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/determined-ai/determined/master/pkg/check"
 	"github.com/determined-ai/determined/master/pkg/device"
+	"github.com/determined-ai/determined/master/pkg/model"
 	"github.com/determined-ai/determined/master/pkg/union"
 )
 
@@ -16,6 +20,16 @@ const defaultResourcePoolName = "default"
 type ResourceManagerConfig struct {
 	AgentRM      *AgentResourceManagerConfig      `union:"type,agent" json:"-"`
 	KubernetesRM *KubernetesResourceManagerConfig `union:"type,kubernetes" json:"-"`
+	SlurmRM      *HPCResourceManagerConfig        `union:"type,slurm" json:"-"`
+	PbsRM        *HPCResourceManagerConfig        `union:"type,pbs" json:"-"`
+}
+
+// HPCRM returns the config of the Slurm or PBS resource manager, or nil if neither is configured.
+func (r ResourceManagerConfig) HPCRM() *HPCResourceManagerConfig {
+	if r.SlurmRM != nil {
+		return r.SlurmRM
+	}
+	return r.PbsRM
 }
 
 // MarshalJSON implements the json.Marshaler interface.
@@ -35,7 +49,7 @@ func (r *ResourceManagerConfig) UnmarshalJSON(data []byte) error {
 	}
 
 	// Fill in the default config.
-	if r.AgentRM == nil && r.KubernetesRM == nil {
+	if r.AgentRM == nil && r.KubernetesRM == nil && r.HPCRM() == nil {
 		r.AgentRM = &AgentResourceManagerConfig{
 			Scheduler: &SchedulerConfig{
 				FittingPolicy: defaultFitPolicy,
@@ -133,6 +147,10 @@ type KubernetesResourceManagerConfig struct {
 	DefaultAuxResourcePool     string `json:"default_aux_resource_pool"`
 	DefaultComputeResourcePool string `json:"default_compute_resource_pool"`
 	NoDefaultResourcePools     bool   `json:"no_default_resource_pools"`
+
+	// WorkspaceNamespaces, if set, makes tasks of workspaces run in a namespace per workspace
+	// instead of the namespace of their resource pool.
+	WorkspaceNamespaces *WorkspaceNamespacesConfig `json:"workspace_namespaces"`
 }
 
 var defaultKubernetesResourceManagerConfig = KubernetesResourceManagerConfig{
@@ -165,6 +183,15 @@ func (k *KubernetesResourceManagerConfig) UnmarshalJSON(data []byte) error {
 	if err == nil && k.SlotType == "gpu" {
 		k.SlotType = device.CUDA
 	}
+
+	if w := k.WorkspaceNamespaces; w != nil {
+		if w.NamePrefix == "" {
+			w.NamePrefix = defaultWorkspaceNamespacePrefix
+		}
+		if w.ServiceAccountName == "" {
+			w.ServiceAccountName = defaultWorkspaceServiceAccountName
+		}
+	}
 	return err
 }
 
@@ -191,6 +218,39 @@ func (k KubernetesResourceManagerConfig) Validate() []error {
 	}
 }
 
+const (
+	defaultWorkspaceNamespacePrefix    = "det-workspace-"
+	defaultWorkspaceServiceAccountName = "determined-task"
+)
+
+var workspaceNamespacePrefixRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,47})$`)
+
+// WorkspaceNamespacesConfig configures the namespaces the kubernetes resource manager creates for
+// workspaces. The namespace of a workspace is created, along with a service account and a
+// ResourceQuota for the GPU limit of the workspace, the first time one of its tasks runs.
+type WorkspaceNamespacesConfig struct {
+	// NamePrefix is followed by the workspace ID in the name of the namespace.
+	NamePrefix string `json:"name_prefix"`
+	// Labels are added to every namespace, next to the label with the workspace ID.
+	Labels map[string]string `json:"labels"`
+	// ServiceAccountName is the service account that task pods run as, unless their pod spec
+	// sets another one.
+	ServiceAccountName string `json:"service_account_name"`
+}
+
+// Validate implements the check.Validatable interface.
+func (w WorkspaceNamespacesConfig) Validate() []error {
+	var errs []error
+	if !workspaceNamespacePrefixRegex.MatchString(w.NamePrefix) {
+		errs = append(errs, errors.Errorf("workspace_namespaces.name_prefix %q must be at most 48 "+
+			"lowercase letters, digits and dashes and start with a letter or digit", w.NamePrefix))
+	}
+	if w.ServiceAccountName == "" {
+		errs = append(errs, errors.New("workspace_namespaces.service_account_name must be set"))
+	}
+	return errs
+}
+
 // PodSlotResourceRequests contains the per-slot container requests.
 type PodSlotResourceRequests struct {
 	CPU float32 `json:"cpu"`
@@ -203,6 +263,81 @@ type FluentConfig struct {
 	GID   int    `json:"gid"`
 }
 
+// HPCResourceManagerConfig hosts configuration fields for the Slurm and PBS resource managers,
+// which submit each allocation as a batch job that runs its container with Singularity or Podman.
+type HPCResourceManagerConfig struct {
+	// JobStorageRoot is a directory shared by the master and the compute nodes. Each job keeps its
+	// scripts, the files of its container and its output in a directory under it.
+	JobStorageRoot string `json:"job_storage_root"`
+	// ContainerRunType is the runtime that runs task containers: singularity, apptainer or podman.
+	ContainerRunType string `json:"container_run_type"`
+	// BinDir is where the commands of the workload manager are found, instead of the PATH.
+	BinDir string `json:"bin_dir"`
+	// MasterHost and MasterPort are how tasks reach the master from the compute nodes. They
+	// default to the hostname of the master and the port it listens on.
+	MasterHost   string         `json:"master_host"`
+	MasterPort   int            `json:"master_port"`
+	PollInterval model.Duration `json:"poll_interval"`
+	SlotType     device.Type    `json:"slot_type"`
+
+	DefaultAuxResourcePool     string `json:"default_aux_resource_pool"`
+	DefaultComputeResourcePool string `json:"default_compute_resource_pool"`
+	NoDefaultResourcePools     bool   `json:"no_default_resource_pools"`
+}
+
+// Container runtimes of the Slurm and PBS resource managers.
+const (
+	SingularityContainerRunType = "singularity"
+	ApptainerContainerRunType   = "apptainer"
+	PodmanContainerRunType      = "podman"
+)
+
+var defaultHPCResourceManagerConfig = HPCResourceManagerConfig{
+	ContainerRunType: SingularityContainerRunType,
+	PollInterval:     model.Duration(10 * time.Second),
+	SlotType:         device.CUDA,
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (h *HPCResourceManagerConfig) UnmarshalJSON(data []byte) error {
+	*h = defaultHPCResourceManagerConfig
+	type DefaultParser *HPCResourceManagerConfig
+	if err := json.Unmarshal(data, DefaultParser(h)); err != nil {
+		return err
+	}
+
+	if h.NoDefaultResourcePools {
+		h.DefaultComputeResourcePool = ""
+		h.DefaultAuxResourcePool = ""
+	} else {
+		if h.DefaultComputeResourcePool == "" {
+			h.DefaultComputeResourcePool = defaultResourcePoolName
+		}
+		if h.DefaultAuxResourcePool == "" {
+			h.DefaultAuxResourcePool = defaultResourcePoolName
+		}
+	}
+
+	if h.SlotType == "gpu" {
+		h.SlotType = device.CUDA
+	}
+	return nil
+}
+
+// Validate implements the check.Validatable interface.
+func (h HPCResourceManagerConfig) Validate() []error {
+	return []error{
+		check.True(filepath.IsAbs(h.JobStorageRoot), "job_storage_root must be an absolute path"),
+		check.In(h.ContainerRunType, []string{
+			SingularityContainerRunType, ApptainerContainerRunType, PodmanContainerRunType,
+		}, "container_run_type must be singularity, apptainer or podman"),
+		check.GreaterThan(int64(h.PollInterval), int64(0), "poll_interval must be greater than 0"),
+		check.In(string(h.SlotType), []string{string(device.CPU), string(device.CUDA), string(device.ROCM)},
+			"slot_type must be cpu, cuda or rocm"),
+		check.GreaterThanOrEqualTo(int64(h.MasterPort), int64(0), "master_port must be >= 0"),
+	}
+}
+
 // PreemptionScheduler is the name of the preemption scheduler for k8.
 // HACK(Brad): Here because circular imports; Kubernetes probably needs its own
 // configuration package.
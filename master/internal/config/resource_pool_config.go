@@ -32,6 +32,9 @@ type ResourcePoolConfig struct {
 	// AgentReconnectWait define the time master will wait for agent
 	// before abandoning it.
 	AgentReconnectWait model.Duration `json:"agent_reconnect_wait"`
+	// TimeSlicesPerGPU, if greater than 1, makes the agents of the pool advertise each of their
+	// GPUs as that many slots, which tasks that request a fraction of a slot share.
+	TimeSlicesPerGPU int `json:"time_slices_per_gpu"`
 
 	// If empty, will behave as if the value is resource_manager.namespace,
 	// which in most cases will be the namespace the helm deployment is in.
@@ -64,6 +67,9 @@ func (r ResourcePoolConfig) Validate() []error {
 		check.True(len(r.PoolName) != 0, "resource pool name cannot be empty"),
 		check.True(r.MaxAuxContainersPerAgent >= 0,
 			"resource pool max cpu containers per agent should be >= 0"),
+		check.True(r.TimeSlicesPerGPU >= 0, "resource pool time slices per gpu should be >= 0"),
+		check.True(r.TimeSlicesPerGPU <= 1 || r.Provider == nil,
+			"resource pools with a provider cannot time-slice GPUs"),
 	}
 }
 
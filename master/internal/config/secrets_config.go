@@ -0,0 +1,60 @@
+package config
+
+import (
+	"encoding/base64"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// secretsMasterKeyLength is the length of the master key in bytes, for AES-256.
+const secretsMasterKeyLength = 32
+
+// SecretsConfig configures the encryption of the secrets that tasks can reference.
+type SecretsConfig struct {
+	// MasterKey is the base64 encoding of the 32 byte key secrets are encrypted with.
+	MasterKey string `json:"master_key"`
+	// MasterKeyFile is a file holding the master key, as an alternative to MasterKey.
+	MasterKeyFile string `json:"master_key_file"`
+}
+
+// Enabled returns whether a master key is configured.
+func (s SecretsConfig) Enabled() bool {
+	return s.MasterKey != "" || s.MasterKeyFile != ""
+}
+
+// Key returns the decoded master key, or nil if none is configured.
+func (s SecretsConfig) Key() ([]byte, error) {
+	encoded := s.MasterKey
+	if s.MasterKeyFile != "" {
+		bs, err := os.ReadFile(s.MasterKeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading secrets.master_key_file")
+		}
+		encoded = strings.TrimSpace(string(bs))
+	}
+	if encoded == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.Wrap(err, "secrets master key must be base64 encoded")
+	}
+	if len(key) != secretsMasterKeyLength {
+		return nil, errors.Errorf("secrets master key must be %d bytes, got %d",
+			secretsMasterKeyLength, len(key))
+	}
+	return key, nil
+}
+
+// Validate implements the check.Validatable interface.
+func (s SecretsConfig) Validate() []error {
+	if s.MasterKey != "" && s.MasterKeyFile != "" {
+		return []error{errors.New("secrets: only one of master_key and master_key_file may be set")}
+	}
+	if _, err := s.Key(); err != nil {
+		return []error{err}
+	}
+	return nil
+}
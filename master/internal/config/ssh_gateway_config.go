@@ -0,0 +1,41 @@
+package config
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+const (
+	// DefaultSSHGatewayPort is the port the SSH gateway listens on if not configured otherwise.
+	DefaultSSHGatewayPort = 2222
+	// DefaultSSHGatewayCertificateTTL is how long SSH certificates are valid if not configured
+	// otherwise.
+	DefaultSSHGatewayCertificateTTL = time.Hour
+)
+
+// SSHGatewayConfig configures the SSH server of the master, which lets users reach the sshd of
+// their shells with `ssh <shell-id>@<master>`.
+type SSHGatewayConfig struct {
+	Enabled bool `json:"enabled"`
+	Port    int  `json:"port"`
+	// CertificateTTL is how long the certificates the master signs for users' keys are valid.
+	CertificateTTL model.Duration `json:"certificate_ttl"`
+}
+
+// Validate implements the check.Validatable interface.
+func (s SSHGatewayConfig) Validate() []error {
+	if !s.Enabled {
+		return nil
+	}
+	var errs []error
+	if s.Port <= 0 || s.Port > 65535 {
+		errs = append(errs, errors.Errorf("ssh_gateway.port must be between 1 and 65535, got %d", s.Port))
+	}
+	if s.CertificateTTL <= 0 {
+		errs = append(errs, errors.New("ssh_gateway.certificate_ttl must be positive"))
+	}
+	return errs
+}
@@ -44,15 +44,19 @@ import (
 	"github.com/determined-ai/determined/master/internal/connsave"
 	detContext "github.com/determined-ai/determined/master/internal/context"
 	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/dbmaint"
 	"github.com/determined-ai/determined/master/internal/elastic"
 	"github.com/determined-ai/determined/master/internal/grpcutil"
 	"github.com/determined-ai/determined/master/internal/job/jobservice"
 	"github.com/determined-ai/determined/master/internal/logpattern"
 	"github.com/determined-ai/determined/master/internal/plugin/sso"
 	"github.com/determined-ai/determined/master/internal/portregistry"
+	"github.com/determined-ai/determined/master/internal/profiler"
 	"github.com/determined-ai/determined/master/internal/prom"
 	"github.com/determined-ai/determined/master/internal/proxy"
 	"github.com/determined-ai/determined/master/internal/rm"
+	"github.com/determined-ai/determined/master/internal/secrets"
+	"github.com/determined-ai/determined/master/internal/sshgateway"
 	"github.com/determined-ai/determined/master/internal/task"
 	"github.com/determined-ai/determined/master/internal/task/tasklogger"
 	"github.com/determined-ai/determined/master/internal/task/taskmodel"
@@ -65,6 +69,7 @@ import (
 	"github.com/determined-ai/determined/master/pkg/logger"
 	"github.com/determined-ai/determined/master/pkg/model"
 	opentelemetry "github.com/determined-ai/determined/master/pkg/opentelemetry"
+	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
 	"github.com/determined-ai/determined/master/pkg/tasks"
 	"github.com/determined-ai/determined/master/version"
 	"github.com/determined-ai/determined/proto/pkg/apiv1"
@@ -99,6 +104,10 @@ type Master struct {
 
 	trialLogBackend TrialLogBackend
 	taskLogBackend  TaskLogBackend
+
+	dbMaintenance   *dbmaint.Service
+	searcherStreams *searcherStreams
+	sshGateway      *sshgateway.Gateway
 }
 
 // New creates an instance of the Determined master.
@@ -712,6 +721,16 @@ func (m *Master) startServers(ctx context.Context, cert *tls.Certificate, gRPCLo
 
 	start("cmux listener", mux.Serve)
 
+	if m.sshGateway != nil {
+		sshListener, err := net.Listen("tcp", fmt.Sprintf(":%d", m.config.SSHGateway.Port))
+		if err != nil {
+			return errors.Wrap(err, "listening for ssh gateway connections")
+		}
+		defer closeWithErrCheck("ssh gateway", sshListener)
+		start("SSH gateway", func() error { return m.sshGateway.Serve(sshListener) })
+		log.Infof("accepting ssh gateway connections on port %d", m.config.SSHGateway.Port)
+	}
+
 	if systemdListener != nil {
 		log.Infof("accepting incoming connections on a socket inherited from systemd")
 	} else {
@@ -840,6 +859,15 @@ func updateClusterHeartbeat(ctx context.Context, db *db.PgDB) {
 	}
 }
 
+// checkStagedDataSupported returns an error if a task stages data but its resource manager can't.
+// Only agents have a data cache to stage data into.
+func (m *Master) checkStagedDataSupported(stagedData expconf.StagedDataConfig) error {
+	if len(stagedData) > 0 && m.config.ResourceManager.AgentRM == nil {
+		return errors.New("resources.staged_data is only supported by the agent resource manager")
+	}
+	return nil
+}
+
 func (m *Master) checkIfRMDefaultsAreUnbound(rmConfig *config.ResourceManagerConfig) error {
 	if rmConfig.AgentRM != nil {
 		err := db.CheckIfRPUnbound(rmConfig.AgentRM.DefaultComputeResourcePool)
@@ -857,6 +885,14 @@ func (m *Master) checkIfRMDefaultsAreUnbound(rmConfig *config.ResourceManagerCon
 		err = db.CheckIfRPUnbound(rmConfig.KubernetesRM.DefaultAuxResourcePool)
 		return err
 	}
+	if hpc := rmConfig.HPCRM(); hpc != nil {
+		err := db.CheckIfRPUnbound(hpc.DefaultComputeResourcePool)
+		if err != nil {
+			return err
+		}
+		err = db.CheckIfRPUnbound(hpc.DefaultAuxResourcePool)
+		return err
+	}
 	return fmt.Errorf("no Resource Manager found")
 }
 
@@ -865,6 +901,7 @@ func (m *Master) postTaskLogs(c echo.Context) (interface{}, error) {
 	if err := json.NewDecoder(c.Request().Body).Decode(&logs); err != nil {
 		return "", fmt.Errorf("decoding task logs: %w", err)
 	}
+	secrets.Redact(logs)
 	if err := m.taskLogBackend.AddTaskLogs(logs); err != nil {
 		return "", errors.Wrap(err, "receiving task logs")
 	}
@@ -948,12 +985,28 @@ func (m *Master) Run(ctx context.Context, gRPCLogInitDone chan struct{}) error {
 	}
 	tasklogger.SetDefaultLogger(tasklogger.New(m.taskLogBackend))
 
+	secretsKey, err := m.config.Secrets.Key()
+	if err != nil {
+		return err
+	}
+	if err := secrets.SetMasterKey(secretsKey); err != nil {
+		return err
+	}
+
 	user.InitService(m.db, &m.config.InternalConfig.ExternalSessions)
 	userService := user.GetService()
 
 	proxy.InitProxy(processProxyAuthentication)
 	portregistry.InitPortRegistry(config.GetMasterConfig().ReservedPorts)
 
+	if m.config.SSHGateway.Enabled {
+		m.sshGateway, err = sshgateway.New(ctx, time.Duration(m.config.SSHGateway.CertificateTTL),
+			resolveSSHGatewayTarget)
+		if err != nil {
+			return errors.Wrap(err, "initializing ssh gateway")
+		}
+	}
+
 	go periodicallyAggregateResourceAllocation(m.db)
 
 	// Initialize the HTTP server and listen for incoming requests.
@@ -1084,11 +1137,25 @@ func (m *Master) Run(ctx context.Context, gRPCLogInitDone chan struct{}) error {
 		return err
 	}
 
+	if err = failInterruptedModelExports(ctx); err != nil {
+		return err
+	}
+
 	// The below function call is intentionally made after the call to CloseOpenAllocations.
 	// This ensures that in the scenario where a cluster fails all open allocations are
 	// set to the last cluster heartbeat when the cluster was running.
 	go updateClusterHeartbeat(ctx, m.db)
 	go trials.MarkLostTrialsWorker(ctx)
+	go profiler.Summarize(ctx)
+	go db.BackfillMetricRollups(ctx)
+	// Empty the trash even with it disabled, so what was trashed before it was disabled still goes.
+	go m.periodicallyEmptyTrash(ctx)
+	go m.periodicallyDeleteOrphanedModelExports(ctx)
+	m.dbMaintenance = dbmaint.New(m.config.DBMaintenance)
+	m.searcherStreams = newSearcherStreams()
+	if m.config.DBMaintenance.Enabled {
+		go m.dbMaintenance.Start(ctx)
+	}
 
 	// Docs and WebUI.
 	webuiRoot := filepath.Join(m.config.Root, "webui")
@@ -1157,12 +1224,86 @@ func (m *Master) Run(ctx context.Context, gRPCLogInitDone chan struct{}) error {
 	experimentsGroup.GET("/:experiment_id/model_def", m.getExperimentModelDefinition)
 	experimentsGroup.GET("/:experiment_id/file/download", m.getExperimentModelFile)
 	experimentsGroup.GET("/:experiment_id/preview_gc", api.Route(m.getExperimentCheckpointsToGC))
+	experimentsGroup.GET("/:experiment_id/metadata", api.Route(m.getExperimentMetadata))
+	experimentsGroup.PATCH("/:experiment_id/metadata", api.Route(m.patchExperimentMetadata))
+	experimentsGroup.POST("/import", api.Route(m.postImportExperiments))
+	experimentsGroup.GET("/:experiment_id/profiler-summary", api.Route(m.getExperimentProfilerSummary))
+	experimentsGroup.GET("/searcher-stream", m.getSearcherStream)
+
+	trialsGroup := m.echo.Group("/trials")
+	trialsGroup.GET("/:trial_id/metadata", api.Route(m.getTrialMetadata))
+	trialsGroup.PATCH("/:trial_id/metadata", api.Route(m.patchTrialMetadata))
+	trialsGroup.GET("/:trial_id/metric-rollups", api.Route(m.getTrialMetricRollups))
+	trialsGroup.GET("/:trial_id/profiler-summary", api.Route(m.getTrialProfilerSummary))
+
+	projectsGroup := m.echo.Group("/projects")
+	projectsGroup.GET("/:project_id/saved-views", api.Route(m.getProjectSavedViews))
+	projectsGroup.POST("/:project_id/saved-views", api.Route(m.postProjectSavedView))
+
+	savedViewsGroup := m.echo.Group("/saved-views")
+	savedViewsGroup.GET("/:view_id", api.Route(m.getSavedView))
+	savedViewsGroup.PATCH("/:view_id", api.Route(m.patchSavedView))
+	savedViewsGroup.DELETE("/:view_id", api.Route(m.deleteSavedView))
+	savedViewsGroup.GET("/:view_id/experiments", api.Route(m.getSavedViewExperiments))
+
+	templatesGroup := m.echo.Group("/templates")
+	templatesGroup.GET("/:template_name/versions", api.Route(m.getTemplateVersions))
+	templatesGroup.GET("/:template_name/versions/:version", api.Route(m.getTemplateVersion))
+
+	trashGroup := m.echo.Group("/trash")
+	trashGroup.GET("", api.Route(m.getTrash))
+	trashGroup.POST("/experiments/restore", api.Route(m.postRestoreExperiments))
+	trashGroup.POST("/projects/:project_id/restore", api.Route(m.postRestoreProject))
+	trashGroup.POST("/models/:model_id/restore", api.Route(m.postRestoreModel))
+
+	dbGroup := m.echo.Group("/db")
+	dbGroup.GET("/maintenance", api.Route(m.getDBMaintenance))
+	dbGroup.POST("/maintenance/run", api.Route(m.postDBMaintenanceRun))
+
+	secretsGroup := m.echo.Group("/secrets")
+	secretsGroup.GET("", api.Route(m.getSecrets))
+	secretsGroup.POST("", api.Route(m.postSecret))
+	secretsGroup.PUT("/:secret_id", api.Route(m.putSecret))
+	secretsGroup.DELETE("/:secret_id", api.Route(m.deleteSecret))
+
+	sshGatewayGroup := m.echo.Group("/ssh-gateway")
+	sshGatewayGroup.GET("", api.Route(m.getSSHGateway))
+	sshGatewayGroup.GET("/keys", api.Route(m.getSSHKeys))
+	sshGatewayGroup.POST("/keys", api.Route(m.postSSHKey))
+	sshGatewayGroup.DELETE("/keys/:key_id", api.Route(m.deleteSSHKey))
+	sshGatewayGroup.POST("/certificates", api.Route(m.postSSHCertificate))
+
+	workspacesGroup := m.echo.Group("/workspaces")
+	workspacesGroup.GET("/:workspace_id/volumes", api.Route(m.getWorkspaceVolumes))
+	workspacesGroup.POST("/:workspace_id/volumes", api.Route(m.postWorkspaceVolume))
+	workspacesGroup.DELETE("/:workspace_id/volumes/:volume_name", api.Route(m.deleteWorkspaceVolume))
+	workspacesGroup.GET("/:workspace_id/gpu-limit", api.Route(m.getWorkspaceGPULimit))
+	workspacesGroup.PUT("/:workspace_id/gpu-limit", api.Route(m.putWorkspaceGPULimit))
+	workspacesGroup.GET("/:workspace_id/image-policy", api.Route(m.getWorkspaceImagePolicy))
+	workspacesGroup.PUT("/:workspace_id/image-policy", api.Route(m.putWorkspaceImagePolicy))
+	workspacesGroup.GET("/:workspace_id/egress-policy", api.Route(m.getWorkspaceEgressPolicy))
+	workspacesGroup.PUT("/:workspace_id/egress-policy", api.Route(m.putWorkspaceEgressPolicy))
+
+	resourcePoolsGroup := m.echo.Group("/resource-pools")
+	resourcePoolsGroup.GET("/:pool_name/workspaces/:workspace_id/binding",
+		api.Route(m.getRPWorkspaceBinding))
+	resourcePoolsGroup.PUT("/:pool_name/workspaces/:workspace_id/binding",
+		api.Route(m.putRPWorkspaceBinding))
 
 	checkpointsGroup := m.echo.Group("/checkpoints")
 	checkpointsGroup.GET("/:checkpoint_uuid", m.getCheckpoint)
 
+	modelsGroup := m.echo.Group("/models")
+	modelsGroup.POST("/:model_id/ensemble-versions", api.Route(m.postModelEnsembleVersion))
+	modelsGroup.GET("/:model_id/versions/:version/checkpoints", api.Route(m.getModelVersionCheckpoints))
+	modelsGroup.GET("/:model_id/versions/:version/archive", m.getModelVersionArchive)
+	modelsGroup.POST("/:model_id/versions/:version/exports", api.Route(m.postModelVersionExport))
+	modelsGroup.GET("/:model_id/versions/:version/exports", api.Route(m.getModelVersionExports))
+	modelsGroup.GET("/:model_id/versions/:version/exports/:export_id/archive", m.getModelVersionExportArchive)
+
 	searcherGroup := m.echo.Group("/searcher")
 	searcherGroup.POST("/preview", api.Route(m.getSearcherPreview))
+	searcherGroup.POST("/replay/:experiment_id", api.Route(m.postSearcherReplay))
 
 	resourcesGroup := m.echo.Group("/resources", cluster.CanGetUsageDetails())
 	resourcesGroup.GET("/allocation/raw", m.getRawResourceAllocation)
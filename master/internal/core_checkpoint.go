@@ -69,6 +69,16 @@ func newDelayWriter(w io.Writer, delayBytes int) *delayWriter {
 func (m *Master) getCheckpointStorageConfig(id uuid.UUID) (
 	*expconf.CheckpointStorageConfig, error,
 ) {
+	legacyConfig, err := m.getCheckpointLegacyConfig(id)
+	if err != nil || legacyConfig == nil {
+		return nil, err
+	}
+	return ptrs.Ptr(legacyConfig.CheckpointStorage), nil
+}
+
+// getCheckpointLegacyConfig returns the config of the experiment a checkpoint came from, or nil if
+// the checkpoint doesn't exist.
+func (m *Master) getCheckpointLegacyConfig(id uuid.UUID) (*expconf.LegacyConfig, error) {
 	checkpoint, err := m.db.CheckpointByUUID(id)
 	if err != nil || checkpoint == nil {
 		return nil, err
@@ -83,8 +93,7 @@ func (m *Master) getCheckpointStorageConfig(id uuid.UUID) (
 	if err != nil {
 		return nil, err
 	}
-
-	return ptrs.Ptr(legacyConfig.CheckpointStorage), nil
+	return &legacyConfig, nil
 }
 
 func (m *Master) getCheckpointImpl(
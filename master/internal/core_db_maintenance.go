@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/determined-ai/determined/master/internal/cluster"
+	detContext "github.com/determined-ai/determined/master/internal/context"
+	"github.com/determined-ai/determined/master/internal/dbmaint"
+)
+
+// dbMaintenanceStatus is the response of GET /db/maintenance.
+type dbMaintenanceStatus struct {
+	Enabled bool                `json:"enabled"`
+	LastRun *dbmaint.RunReport  `json:"last_run"`
+	Tables  []dbmaint.TableStat `json:"tables"`
+}
+
+//	@Summary	Get the size and bloat of database tables and the outcome of the last maintenance run.
+//	@Tags		Database
+//	@ID			get-db-maintenance
+//	@Produce	json
+//	@Success	200	{}	dbMaintenanceStatus
+//	@Router		/db/maintenance [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getDBMaintenance(c echo.Context) (interface{}, error) {
+	ctx := c.Request().Context()
+	curUser := c.(*detContext.DetContext).MustGetUser()
+	permErr, err := cluster.AuthZProvider.Get().CanGetMasterConfig(ctx, &curUser)
+	if err != nil {
+		return nil, err
+	}
+	if permErr != nil {
+		return nil, echo.NewHTTPError(http.StatusForbidden, permErr.Error())
+	}
+
+	tables, err := dbmaint.TableStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return dbMaintenanceStatus{
+		Enabled: m.config.DBMaintenance.Enabled,
+		LastRun: m.dbMaintenance.LastRun(),
+		Tables:  tables,
+	}, nil
+}
+
+//	@Summary	Run database maintenance now, ignoring the maintenance window.
+//	@Tags		Database
+//	@ID			post-db-maintenance-run
+//	@Produce	json
+//	@Success	200	{}	dbmaint.RunReport
+//	@Router		/db/maintenance/run [post]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) postDBMaintenanceRun(c echo.Context) (interface{}, error) {
+	ctx := c.Request().Context()
+	curUser := c.(*detContext.DetContext).MustGetUser()
+	permErr, err := cluster.AuthZProvider.Get().CanUpdateMasterConfig(ctx, &curUser)
+	if err != nil {
+		return nil, err
+	}
+	if permErr != nil {
+		return nil, echo.NewHTTPError(http.StatusForbidden, permErr.Error())
+	}
+
+	report, err := m.dbMaintenance.Run(ctx)
+	if errors.Is(err, dbmaint.ErrRunInProgress) {
+		return nil, echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	return report, err
+}
@@ -13,6 +13,9 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	k8sV1 "k8s.io/api/core/v1"
 
 	"github.com/determined-ai/determined/master/internal/api"
 	"github.com/determined-ai/determined/master/internal/authz"
@@ -24,6 +27,7 @@ import (
 	"github.com/determined-ai/determined/master/internal/user"
 	"github.com/determined-ai/determined/master/internal/workspace"
 	"github.com/determined-ai/determined/master/pkg/archive"
+	"github.com/determined-ai/determined/master/pkg/device"
 	"github.com/determined-ai/determined/master/pkg/model"
 	"github.com/determined-ai/determined/master/pkg/protoutils"
 	"github.com/determined-ai/determined/master/pkg/ptrs"
@@ -263,24 +267,53 @@ func getCreateExperimentsProject(
 	return p, nil
 }
 
+// applyRPWorkspaceBindingPolicy fills in the priority and weight of a workspace's binding to the
+// experiment's pool where the config leaves them unset. The pool enforces the binding's max slots
+// across all the jobs of the workspace.
+func applyRPWorkspaceBindingPolicy(
+	config *expconf.ExperimentConfig, policy db.RPWorkspaceBindingPolicy,
+) {
+	if config.RawResources == nil {
+		config.RawResources = &expconf.ResourcesConfig{}
+	}
+	r := config.RawResources
+	if r.RawPriority == nil {
+		r.RawPriority = policy.Priority
+	}
+	if r.RawWeight == nil {
+		r.RawWeight = policy.Weight
+	}
+}
+
 func (m *Master) parseCreateExperiment(req *apiv1.CreateExperimentRequest, owner *model.User) (
 	*model.Experiment, expconf.ExperimentConfig, *projectv1.Project, *tasks.TaskSpec, error,
 ) {
 	ctx := context.TODO()
-	// Read the config as the user provided it.
-	config, err := expconf.ParseAnyExperimentConfigYAML([]byte(req.Config))
+	// Read the config as the user provided it, minus the values for template parameters.
+	userConfig, templateValues, err := templates.SplitParameterValues(req.Config)
+	if err != nil {
+		return nil, expconf.ExperimentConfig{}, nil, nil,
+			errors.Wrap(err, "invalid experiment configuration")
+	}
+	config, err := expconf.ParseAnyExperimentConfigYAML([]byte(userConfig))
 	if err != nil {
 		return nil, config, nil, nil, errors.Wrap(err, "invalid experiment configuration")
 	}
 
 	// Apply the template that the user specified.
+	var templateVersion int
 	if req.Template != nil {
 		var tc expconf.ExperimentConfig
-		err := templates.UnmarshalTemplateConfig(ctx, *req.Template, owner, &tc, true)
+		templateVersion, err = templates.UnmarshalTemplateConfig(
+			ctx, *req.Template, owner, &tc, true, templateValues)
 		if err != nil {
 			return nil, config, nil, nil, err
 		}
 		config = schemas.Merge(config, tc)
+	} else if templateValues != nil {
+		return nil, config, nil, nil, errors.Errorf(
+			"%s can only be set when creating an experiment from a template",
+			templates.ParameterValuesKey)
 	}
 
 	defaulted := schemas.WithDefaults(config)
@@ -303,6 +336,16 @@ func (m *Master) parseCreateExperiment(req *apiv1.CreateExperimentRequest, owner
 	if err = m.rm.ValidateResources(poolName, resources.SlotsPerTrial(), false); err != nil {
 		return nil, config, nil, nil, errors.Wrapf(err, "error validating resources")
 	}
+	if err = m.checkStagedDataSupported(resources.StagedData()); err != nil {
+		return nil, config, nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	binding, err := db.GetRPWorkspaceBinding(ctx, workspaceID, poolName)
+	if err != nil {
+		return nil, config, nil, nil, err
+	}
+	if binding != nil {
+		applyRPWorkspaceBindingPolicy(&config, binding.RPWorkspaceBindingPolicy)
+	}
 	taskContainerDefaults, err := m.rm.TaskContainerDefaults(
 		poolName,
 		m.config.TaskContainerDefaults,
@@ -346,6 +389,23 @@ func (m *Master) parseCreateExperiment(req *apiv1.CreateExperimentRequest, owner
 		return nil, config, nil, nil, errors.Wrap(err, "invalid experiment configuration")
 	}
 
+	if req.Unmanaged == nil || !*req.Unmanaged {
+		deviceTypes, err := m.imageDeviceTypes(poolName, resources.SlotsPerTrial())
+		if err != nil {
+			return nil, config, nil, nil, err
+		}
+		image := config.RawEnvironment.RawImage
+		if err = enforceImagePolicy(ctx, workspaceID, owner, model.TaskTypeTrial,
+			config.RawEnvironment.RawRegistryAuth, deviceTypes, map[device.Type]*string{
+				device.CPU:  image.RawCPU,
+				device.CUDA: image.RawCUDA,
+				device.ROCM: image.RawROCM,
+			}, (*k8sV1.Pod)(config.RawEnvironment.RawPodSpec),
+		); err != nil {
+			return nil, config, nil, nil, err
+		}
+	}
+
 	modelBytes := []byte{}
 	var parentID *int
 	if req.ParentId != 0 {
@@ -394,9 +454,16 @@ func (m *Master) parseCreateExperiment(req *apiv1.CreateExperimentRequest, owner
 		dbExp.OwnerID = &owner.ID
 		dbExp.Username = owner.Username
 	}
+	if req.Template != nil {
+		dbExp.TemplateName = req.Template
+		if templateVersion > 0 {
+			dbExp.TemplateVersion = &templateVersion
+		}
+	}
 
 	taskSpec.Project = config.Project()
 	taskSpec.Workspace = config.Workspace()
+	taskSpec.WorkspaceID = int(p.WorkspaceId)
 	for label := range config.Labels() {
 		taskSpec.Labels = append(taskSpec.Labels, label)
 	}
@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/determined-ai/determined/master/internal/api"
+	"github.com/determined-ai/determined/master/internal/authz"
+	detContext "github.com/determined-ai/determined/master/internal/context"
+	"github.com/determined-ai/determined/master/internal/db"
+	expauth "github.com/determined-ai/determined/master/internal/experiment"
+	"github.com/determined-ai/determined/master/internal/importer"
+	"github.com/determined-ai/determined/master/internal/project"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/proto/pkg/projectv1"
+)
+
+const (
+	// maxImportUploadBytes bounds the size of the tarballs uploaded to import.
+	maxImportUploadBytes = 1 << 30
+	// maxImportExtractedBytes bounds the size of the files extracted from an uploaded tarball.
+	maxImportExtractedBytes = 4 << 30
+)
+
+//	@Summary	Import MLflow or TensorBoard runs into unmanaged experiments.
+//	@Description	The body is a gzipped tarball of an MLflow tracking directory, or of the
+//	@Description	directory of a single MLflow experiment, or of a TensorBoard log directory.
+//	@Description	Every MLflow experiment, or the TensorBoard log directory, becomes an unmanaged
+//	@Description	experiment with a trial per run. Importing again only adds new runs.
+//	@Tags		Experiments
+//	@ID			import-experiments
+//	@Accept		application/gzip
+//	@Produce	json
+//	@Param		format					query	string	true	"mlflow or tensorboard"
+//	@Param		project_id				query	int		false	"Project to import into"
+//	@Param		name					query	string	false	"Experiment name, for TensorBoard"
+//	@Param		searcher_metric			query	string	false	"Validation metric to rank trials by"
+//	@Param		smaller_is_better		query	bool	false	"Whether smaller searcher metrics are better"
+//	@Success	200						{}		[]importer.Result
+//	@Router		/experiments/import [post]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) postImportExperiments(c echo.Context) (interface{}, error) {
+	args := struct {
+		Format          string  `query:"format"`
+		ProjectID       *int    `query:"project_id"`
+		Name            *string `query:"name"`
+		SearcherMetric  *string `query:"searcher_metric"`
+		SmallerIsBetter *bool   `query:"smaller_is_better"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	user := c.(*detContext.DetContext).MustGetUser()
+
+	opts := importer.Options{
+		ProjectID:         model.DefaultProjectID,
+		Owner:             user,
+		CheckpointStorage: m.config.CheckpointStorage,
+		SmallerIsBetter:   true,
+	}
+	if args.ProjectID != nil {
+		opts.ProjectID = *args.ProjectID
+	}
+	if args.SearcherMetric != nil {
+		opts.SearcherMetric = *args.SearcherMetric
+	}
+	if args.SmallerIsBetter != nil {
+		opts.SmallerIsBetter = *args.SmallerIsBetter
+	}
+	name := "tensorboard-import"
+	if args.Name != nil {
+		name = *args.Name
+	}
+
+	errProjectNotFound := api.NotFoundErrs("project", fmt.Sprint(opts.ProjectID), true)
+	p := &projectv1.Project{}
+	if err := m.db.QueryProto("get_project", p, opts.ProjectID); errors.Is(err, db.ErrNotFound) {
+		return nil, errProjectNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if err := project.AuthZProvider.Get().CanGetProject(ctx, user, p); err != nil {
+		return nil, authz.SubIfUnauthorized(err, errProjectNotFound)
+	}
+	if err := expauth.AuthZProvider.Get().CanCreateExperiment(ctx, user, p); err != nil {
+		return nil, echo.NewHTTPError(http.StatusForbidden, err.Error())
+	}
+
+	dir, err := os.MkdirTemp("", "import-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			log.WithError(err).Errorf("removing import directory %s", dir)
+		}
+	}()
+	body := http.MaxBytesReader(c.Response(), c.Request().Body, maxImportUploadBytes)
+	if err := importer.ExtractTarGz(body, dir, maxImportExtractedBytes); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("reading uploaded tarball: %s", err))
+	}
+	exps, err := importer.Read(os.DirFS(dir), args.Format, name)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	var results []importer.Result
+	for i := range exps {
+		res, err := importer.Import(ctx, m.db, &exps[i], opts)
+		if err != nil {
+			return nil, fmt.Errorf("importing experiment %q: %w", exps[i].Name, err)
+		}
+		results = append(results, *res)
+	}
+	return results, nil
+}
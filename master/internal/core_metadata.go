@@ -0,0 +1,157 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+
+	"github.com/determined-ai/determined/master/internal/api"
+	"github.com/determined-ai/determined/master/internal/db"
+	expauth "github.com/determined-ai/determined/master/internal/experiment"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// decodeMetadataUpdates reads a metadata patch from the request body. A null value removes the
+// key.
+func decodeMetadataUpdates(c echo.Context) (map[string]*model.MetadataValue, error) {
+	var updates map[string]*model.MetadataValue
+	if err := json.NewDecoder(c.Request().Body).Decode(&updates); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("decoding body: %s", err))
+	}
+	for key := range updates {
+		if err := model.ValidateMetadataKey(key); err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+	return updates, nil
+}
+
+// echoGetTrialExperimentAndCheckCanDoActions looks up the experiment of a trial and checks the
+// user's permissions on it, returning a not found error for trials the user can't see.
+func echoGetTrialExperimentAndCheckCanDoActions(ctx context.Context, c echo.Context, m *Master,
+	trialID int, actions ...func(context.Context, model.User, *model.Experiment) error,
+) error {
+	trial, err := db.TrialByID(ctx, trialID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return api.NotFoundErrs("trial", fmt.Sprint(trialID), false)
+	} else if err != nil {
+		return err
+	}
+	_, _, err = echoGetExperimentAndCheckCanDoActions(ctx, c, m, trial.ExperimentID, actions...)
+	return err
+}
+
+//	@Summary	Get the typed metadata of an experiment.
+//	@Tags		Experiments
+//	@ID			get-experiment-metadata
+//	@Produce	json
+//	@Param		experiment_id	path	int	true	"Experiment ID"
+//	@Success	200				{}		map[string]model.MetadataValue
+//	@Router		/experiments/{experiment_id}/metadata [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getExperimentMetadata(c echo.Context) (interface{}, error) {
+	args := struct {
+		ExperimentID int `path:"experiment_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	if _, _, err := echoGetExperimentAndCheckCanDoActions(ctx, c, m, args.ExperimentID); err != nil {
+		return nil, err
+	}
+	return db.ExperimentMetadata(ctx, args.ExperimentID)
+}
+
+//	@Summary	Set or remove typed metadata keys of an experiment.
+//	@Tags		Experiments
+//	@ID			patch-experiment-metadata
+//	@Accept		json
+//	@Produce	json
+//	@Param		experiment_id	path	int	true	"Experiment ID"
+//	@Success	200				{}		map[string]model.MetadataValue
+//	@Router		/experiments/{experiment_id}/metadata [patch]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) patchExperimentMetadata(c echo.Context) (interface{}, error) {
+	args := struct {
+		ExperimentID int `path:"experiment_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	if _, _, err := echoGetExperimentAndCheckCanDoActions(ctx, c, m, args.ExperimentID,
+		expauth.AuthZProvider.Get().CanEditExperimentsMetadata); err != nil {
+		return nil, err
+	}
+	updates, err := decodeMetadataUpdates(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.UpdateExperimentMetadata(ctx, args.ExperimentID, updates); err != nil {
+		return nil, err
+	}
+	return db.ExperimentMetadata(ctx, args.ExperimentID)
+}
+
+//	@Summary	Get the typed metadata of a trial.
+//	@Tags		Trials
+//	@ID			get-trial-metadata
+//	@Produce	json
+//	@Param		trial_id	path	int	true	"Trial ID"
+//	@Success	200			{}		map[string]model.MetadataValue
+//	@Router		/trials/{trial_id}/metadata [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getTrialMetadata(c echo.Context) (interface{}, error) {
+	args := struct {
+		TrialID int `path:"trial_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	if err := echoGetTrialExperimentAndCheckCanDoActions(ctx, c, m, args.TrialID); err != nil {
+		return nil, err
+	}
+	return db.TrialMetadata(ctx, args.TrialID)
+}
+
+//	@Summary	Set or remove typed metadata keys of a trial, e.g. from within the running trial.
+//	@Tags		Trials
+//	@ID			patch-trial-metadata
+//	@Accept		json
+//	@Produce	json
+//	@Param		trial_id	path	int	true	"Trial ID"
+//	@Success	200			{}		map[string]model.MetadataValue
+//	@Router		/trials/{trial_id}/metadata [patch]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) patchTrialMetadata(c echo.Context) (interface{}, error) {
+	args := struct {
+		TrialID int `path:"trial_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	if err := echoGetTrialExperimentAndCheckCanDoActions(ctx, c, m, args.TrialID,
+		expauth.AuthZProvider.Get().CanEditExperimentsMetadata); err != nil {
+		return nil, err
+	}
+	updates, err := decodeMetadataUpdates(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.UpdateTrialMetadata(ctx, args.TrialID, updates); err != nil {
+		return nil, err
+	}
+	return db.TrialMetadata(ctx, args.TrialID)
+}
@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/exp/slices"
+
+	"github.com/determined-ai/determined/master/internal/api"
+	"github.com/determined-ai/determined/master/internal/db"
+	expauth "github.com/determined-ai/determined/master/internal/experiment"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+//	@Summary	Get the rolled up metrics of a trial at a resolution, for charting long trials.
+//	@Tags		Trials
+//	@ID			get-trial-metric-rollups
+//	@Produce	json
+//	@Param		trial_id		path	int		true	"Trial ID"
+//	@Param		group			query	string	false	"Metric group, defaults to training"
+//	@Param		resolution		query	int		false	"Bucket width in batches: 10, 100 or 1000"
+//	@Param		metric_name		query	string	false	"Metric to return, repeatable; all if unset"
+//	@Param		start_batches	query	int		false	"Lowest batch to return"
+//	@Param		end_batches		query	int		false	"Highest batch to return"
+//	@Param		limit			query	int		false	"Most buckets to return; all if unset"
+//	@Success	200				{}		[]db.MetricRollup
+//	@Router		/trials/{trial_id}/metric-rollups [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getTrialMetricRollups(c echo.Context) (interface{}, error) {
+	args := struct {
+		TrialID      int     `path:"trial_id"`
+		Group        *string `query:"group"`
+		Resolution   *int    `query:"resolution"`
+		StartBatches *int    `query:"start_batches"`
+		EndBatches   *int    `query:"end_batches"`
+		Limit        *int    `query:"limit"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+
+	mGroup := model.TrainingMetricGroup
+	if args.Group != nil {
+		mGroup = model.MetricGroup(*args.Group)
+	}
+	resolution := db.MetricRollupResolutions[0]
+	if args.Resolution != nil {
+		if !slices.Contains(db.MetricRollupResolutions, *args.Resolution) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf(
+				"resolution must be one of %v", db.MetricRollupResolutions))
+		}
+		resolution = *args.Resolution
+	}
+	startBatches, endBatches, limit := 0, math.MaxInt32, 0
+	if args.StartBatches != nil {
+		startBatches = *args.StartBatches
+	}
+	if args.EndBatches != nil {
+		endBatches = *args.EndBatches
+	}
+	if args.Limit != nil {
+		limit = *args.Limit
+	}
+
+	ctx := c.Request().Context()
+	if err := echoGetTrialExperimentAndCheckCanDoActions(ctx, c, m, args.TrialID,
+		expauth.AuthZProvider.Get().CanGetExperimentArtifacts); err != nil {
+		return nil, err
+	}
+	return db.MetricRollups(ctx, args.TrialID, mGroup, resolution,
+		c.QueryParams()["metric_name"], startBatches, endBatches, limit)
+}
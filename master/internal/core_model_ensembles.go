@@ -0,0 +1,429 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/determined-ai/determined/master/internal/api"
+	detContext "github.com/determined-ai/determined/master/internal/context"
+	"github.com/determined-ai/determined/master/internal/db"
+	expauth "github.com/determined-ai/determined/master/internal/experiment"
+	modelauth "github.com/determined-ai/determined/master/internal/model"
+	"github.com/determined-ai/determined/master/pkg/checkpoints"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/proto/pkg/modelv1"
+)
+
+// ensembleManifestName is the name of the file describing the members of a model version in its
+// archive.
+const ensembleManifestName = "model_version.json"
+
+// ensembleMemberRequest is a checkpoint to include in a model version made of several.
+type ensembleMemberRequest struct {
+	UUID string `json:"uuid"`
+	// Role is free-form, such as "shard" or "member".
+	Role   string   `json:"role"`
+	Weight *float64 `json:"weight"`
+}
+
+// ensembleVersionRequest is the body of POST /models/:model_id/ensemble-versions.
+type ensembleVersionRequest struct {
+	Name     string                 `json:"name"`
+	Comment  string                 `json:"comment"`
+	Notes    string                 `json:"notes"`
+	Labels   []string               `json:"labels"`
+	Metadata map[string]interface{} `json:"metadata"`
+	// Checkpoints are the members of the version, in order.
+	Checkpoints []ensembleMemberRequest `json:"checkpoints"`
+}
+
+// modelVersionCheckpointRow is a row of model_version_checkpoints.
+type modelVersionCheckpointRow struct {
+	bun.BaseModel `bun:"table:model_version_checkpoints"`
+
+	ModelVersionID int       `bun:"model_version_id"`
+	Ordinal        int       `bun:"ordinal"`
+	CheckpointUUID uuid.UUID `bun:"checkpoint_uuid"`
+	Role           string    `bun:"role"`
+	Weight         *float64  `bun:"weight"`
+}
+
+// modelVersionCheckpoint is a member of a model version along with the trial it came from.
+type modelVersionCheckpoint struct {
+	Ordinal        int       `bun:"ordinal" json:"ordinal"`
+	CheckpointUUID uuid.UUID `bun:"checkpoint_uuid" json:"checkpoint_uuid"`
+	Role           string    `bun:"role" json:"role"`
+	Weight         *float64  `bun:"weight" json:"weight"`
+	// State, TrialID, ExperimentID and StepsCompleted are nil if the checkpoint is missing.
+	State          *string `bun:"state" json:"state"`
+	TrialID        *int    `bun:"trial_id" json:"trial_id"`
+	ExperimentID   *int    `bun:"experiment_id" json:"experiment_id"`
+	StepsCompleted *int    `bun:"steps_completed" json:"steps_completed"`
+	// Dir is the directory of the member in the archive of the version.
+	Dir string `bun:"-" json:"dir,omitempty"`
+}
+
+// modelVersionCheckpoints lists the checkpoints a model version is made of.
+type modelVersionCheckpoints struct {
+	ModelID int32 `json:"model_id"`
+	Version int32 `json:"version"`
+	// Ensemble is set if the version was registered from several checkpoints.
+	Ensemble    bool                     `json:"ensemble"`
+	Checkpoints []modelVersionCheckpoint `json:"checkpoints"`
+}
+
+// getModelVersionCheckpoints returns the members of a model version in order. A version
+// registered from a single checkpoint has it as its only member.
+func getModelVersionCheckpoints(
+	ctx context.Context, mv *modelv1.ModelVersion,
+) (*modelVersionCheckpoints, error) {
+	res := &modelVersionCheckpoints{ModelID: mv.Model.Id, Version: mv.Version}
+	if err := db.Bun().NewSelect().
+		TableExpr("model_version_checkpoints AS mvc").
+		Column("mvc.ordinal", "mvc.checkpoint_uuid", "mvc.role", "mvc.weight").
+		ColumnExpr("c.state, c.trial_id, c.experiment_id, c.steps_completed").
+		Join("LEFT JOIN checkpoints_view AS c ON c.uuid = mvc.checkpoint_uuid").
+		Where("mvc.model_version_id = ?", mv.Id).
+		Order("mvc.ordinal").
+		Scan(ctx, &res.Checkpoints); err != nil {
+		return nil, fmt.Errorf("getting checkpoints of model version %d: %w", mv.Id, err)
+	}
+	if len(res.Checkpoints) > 0 {
+		res.Ensemble = true
+		return res, nil
+	}
+
+	if err := db.Bun().NewSelect().
+		TableExpr("model_versions AS mv").
+		ColumnExpr("0 AS ordinal, mv.checkpoint_uuid, '' AS role, NULL AS weight").
+		ColumnExpr("c.state, c.trial_id, c.experiment_id, c.steps_completed").
+		Join("LEFT JOIN checkpoints_view AS c ON c.uuid = mv.checkpoint_uuid").
+		Where("mv.id = ?", mv.Id).
+		Scan(ctx, &res.Checkpoints); err != nil {
+		return nil, fmt.Errorf("getting checkpoint of model version %d: %w", mv.Id, err)
+	}
+	return res, nil
+}
+
+// echoGetModelVersion returns a model version if the current user can see its model.
+func (m *Master) echoGetModelVersion(
+	ctx context.Context, c echo.Context, modelID, version int,
+) (*modelv1.ModelVersion, error) {
+	curUser := c.(*detContext.DetContext).MustGetUser()
+	notFound := api.NotFoundErrs(fmt.Sprintf("version of model %d", modelID), fmt.Sprint(version), false)
+	mv, err := (&apiServer{m: m}).ModelVersionFromID(fmt.Sprint(modelID), int32(version))
+	if err != nil {
+		return nil, notFound
+	}
+	if err := modelauth.AuthZProvider.Get().CanGetModel(ctx, curUser, mv.Model,
+		mv.Model.WorkspaceId); err != nil {
+		return nil, notFound
+	}
+	return mv, nil
+}
+
+// echoCanGetCheckpointsArtifacts checks that the current user can get the artifacts of every
+// checkpoint from the experiment it came from. Checkpoints the user can't get are not found.
+func (m *Master) echoCanGetCheckpointsArtifacts(
+	ctx context.Context, curUser model.User, ids []uuid.UUID,
+) error {
+	for _, id := range ids {
+		err := m.canDoActionOnCheckpoint(ctx, curUser, id.String(),
+			expauth.AuthZProvider.Get().CanGetExperimentArtifacts)
+		switch status.Code(err) {
+		case codes.OK:
+		case codes.NotFound, codes.PermissionDenied:
+			return api.NotFoundErrs("checkpoint", id.String(), false)
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+//	@Summary	Register a model version made of several checkpoints, such as an ensemble or shards.
+//	@Tags		Models
+//	@ID			post-model-ensemble-version
+//	@Accept		json
+//	@Produce	json
+//	@Param		model_id	path	int	true	"Model ID"
+//	@Success	200			{}		modelVersionCheckpoints
+//	@Router		/models/{model_id}/ensemble-versions [post]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) postModelEnsembleVersion(c echo.Context) (interface{}, error) {
+	args := struct {
+		ModelID int `path:"model_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	var req ensembleVersionRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("decoding body: %s", err))
+	}
+	ctx := c.Request().Context()
+	curUser := c.(*detContext.DetContext).MustGetUser()
+
+	notFound := api.NotFoundErrs("model", fmt.Sprint(args.ModelID), false)
+	modelPb, err := (&apiServer{m: m}).ModelFromIdentifier(fmt.Sprint(args.ModelID))
+	if err != nil {
+		return nil, notFound
+	}
+	authz := modelauth.AuthZProvider.Get()
+	if err := authz.CanGetModel(ctx, curUser, modelPb, modelPb.WorkspaceId); err != nil {
+		return nil, notFound
+	}
+	if err := authz.CanEditModel(ctx, curUser, modelPb, modelPb.WorkspaceId); err != nil {
+		return nil, echo.NewHTTPError(http.StatusForbidden, err.Error())
+	}
+	if modelPb.Archived {
+		return nil, echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("model %q is archived and cannot register new versions", modelPb.Name))
+	}
+
+	rows, err := validateEnsembleMembers(ctx, req.Checkpoints)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, 0, len(rows))
+	for _, r := range rows {
+		ids = append(ids, r.CheckpointUUID)
+	}
+	if err := m.echoCanGetCheckpointsArtifacts(ctx, curUser, ids); err != nil {
+		return nil, err
+	}
+	metadata, err := json.Marshal(req.Metadata)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid metadata: %s", err))
+	}
+	if req.Metadata == nil {
+		metadata = []byte("{}")
+	}
+	labels := req.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+
+	var mvID int
+	var version int32
+	if err := db.Bun().RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := tx.NewRaw(`
+INSERT INTO model_versions (model_id, version, checkpoint_uuid, name, comment, metadata, labels, notes,
+	user_id, creation_time, last_updated_time)
+VALUES (?, (SELECT COALESCE(MAX(version), 0) + 1 FROM model_versions WHERE model_id = ?), ?, ?, ?,
+	?::jsonb, ?, ?, ?, now(), now())
+RETURNING id, version`,
+			modelPb.Id, modelPb.Id, rows[0].CheckpointUUID, req.Name, req.Comment, string(metadata),
+			pgdialect.Array(labels), req.Notes, curUser.ID,
+		).Scan(ctx, &mvID, &version); err != nil {
+			return err
+		}
+		for i := range rows {
+			rows[i].ModelVersionID = mvID
+		}
+		_, err := tx.NewInsert().Model(&rows).Exec(ctx)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("adding model version to model %q: %w", modelPb.Name, err)
+	}
+
+	mv, err := m.echoGetModelVersion(ctx, c, int(modelPb.Id), int(version))
+	if err != nil {
+		return nil, err
+	}
+	return getModelVersionCheckpoints(ctx, mv)
+}
+
+// validateEnsembleMembers checks that the members of a new model version are distinct, completed
+// checkpoints and returns their rows.
+func validateEnsembleMembers(
+	ctx context.Context, members []ensembleMemberRequest,
+) ([]modelVersionCheckpointRow, error) {
+	if len(members) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "at least one checkpoint is required")
+	}
+	rows := make([]modelVersionCheckpointRow, 0, len(members))
+	seen := map[uuid.UUID]bool{}
+	for i, member := range members {
+		id, err := uuid.Parse(member.UUID)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Sprintf("unable to parse checkpoint UUID %s: %s", member.UUID, err))
+		}
+		if seen[id] {
+			return nil, echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Sprintf("checkpoint %s is listed more than once", id))
+		}
+		seen[id] = true
+		if member.Weight != nil && *member.Weight < 0 {
+			return nil, echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Sprintf("weight of checkpoint %s must not be negative", id))
+		}
+		rows = append(rows, modelVersionCheckpointRow{
+			Ordinal:        i,
+			CheckpointUUID: id,
+			Role:           member.Role,
+			Weight:         member.Weight,
+		})
+	}
+
+	var states []struct {
+		UUID  uuid.UUID   `bun:"uuid"`
+		State model.State `bun:"state"`
+	}
+	ids := make([]uuid.UUID, 0, len(rows))
+	for _, r := range rows {
+		ids = append(ids, r.CheckpointUUID)
+	}
+	if err := db.Bun().NewSelect().
+		Table("checkpoints_v2").
+		Column("uuid", "state").
+		Where("uuid IN (?)", bun.In(ids)).
+		Scan(ctx, &states); err != nil {
+		return nil, fmt.Errorf("getting checkpoints: %w", err)
+	}
+	found := map[uuid.UUID]model.State{}
+	for _, s := range states {
+		found[s.UUID] = s.State
+	}
+	for _, id := range ids {
+		state, ok := found[id]
+		switch {
+		case !ok:
+			return nil, api.NotFoundErrs("checkpoint", id.String(), false)
+		case state != model.CompletedState:
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf(
+				"checkpoint %s is in %s state. checkpoints for model versions must be in a COMPLETED state",
+				id, state))
+		}
+	}
+	return rows, nil
+}
+
+//	@Summary	Get the checkpoints a model version is made of and the trials they came from.
+//	@Tags		Models
+//	@ID			get-model-version-checkpoints
+//	@Produce	json
+//	@Param		model_id	path	int	true	"Model ID"
+//	@Param		version		path	int	true	"Model version"
+//	@Success	200			{}		modelVersionCheckpoints
+//	@Router		/models/{model_id}/versions/{version}/checkpoints [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getModelVersionCheckpoints(c echo.Context) (interface{}, error) {
+	args := struct {
+		ModelID int `path:"model_id"`
+		Version int `path:"version"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	mv, err := m.echoGetModelVersion(ctx, c, args.ModelID, args.Version)
+	if err != nil {
+		return nil, err
+	}
+	return getModelVersionCheckpoints(ctx, mv)
+}
+
+//	@Summary	Get the contents of every checkpoint of a model version in one tgz or zip file.
+//	@Tags		Models
+//	@ID			get-model-version-archive
+//	@Produce	application/gzip,application/zip
+//	@Param		model_id	path	int	true	"Model ID"
+//	@Param		version		path	int	true	"Model version"
+//	@Success	200			{}		string	""
+//	@Router		/models/{model_id}/versions/{version}/archive [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getModelVersionArchive(c echo.Context) error {
+	mimeType := c.Request().Header.Get("Accept")
+	if mimeType != MIMEApplicationGZip && mimeType != MIMEApplicationZip {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType,
+			fmt.Sprintf("unsupported media type to download a model version: '%s'", mimeType))
+	}
+	args := struct {
+		ModelID int `path:"model_id"`
+		Version int `path:"version"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	mv, err := m.echoGetModelVersion(ctx, c, args.ModelID, args.Version)
+	if err != nil {
+		return err
+	}
+	members, err := getModelVersionCheckpoints(ctx, mv)
+	if err != nil {
+		return err
+	}
+	curUser := c.(*detContext.DetContext).MustGetUser()
+	ids := make([]uuid.UUID, 0, len(members.Checkpoints))
+	for _, member := range members.Checkpoints {
+		ids = append(ids, member.CheckpointUUID)
+	}
+	if err := m.echoCanGetCheckpointsArtifacts(ctx, curUser, ids); err != nil {
+		return err
+	}
+
+	var downloads []checkpoints.EnsembleMember
+	for i, member := range members.Checkpoints {
+		storageConfig, err := m.getCheckpointStorageConfig(member.CheckpointUUID)
+		switch {
+		case err != nil:
+			return echo.NewHTTPError(http.StatusInternalServerError,
+				fmt.Sprintf("unable to retrieve experiment config for checkpoint %s: %s",
+					member.CheckpointUUID, err))
+		case storageConfig == nil:
+			return api.NotFoundErrs("checkpoint", member.CheckpointUUID.String(), false)
+		}
+		dir := fmt.Sprintf("%d-%s", member.Ordinal, member.CheckpointUUID)
+		members.Checkpoints[i].Dir = dir
+		downloads = append(downloads, checkpoints.EnsembleMember{
+			ID:            member.CheckpointUUID.String(),
+			Dir:           dir,
+			StorageConfig: storageConfig,
+		})
+	}
+	manifest, err := json.MarshalIndent(members, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, mimeType)
+	dw := newDelayWriter(c.Response(), 16*1024)
+	downloader, err := checkpoints.NewEnsembleDownloader(
+		dw, downloads, ensembleManifestName, manifest, mimeToArchiveType(mimeType))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	switch err := downloader.Download(ctx); {
+	case err != nil && errors.Is(err, context.Canceled):
+		return err
+	case err != nil:
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			fmt.Sprintf("unable to download version %d of model %d: %s",
+				args.Version, args.ModelID, err))
+	}
+	if err := downloader.Close(); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			fmt.Sprintf("failed to complete model version download: %s", err))
+	}
+	if err := dw.Close(); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			fmt.Sprintf("failed to complete model version download: %s", err))
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+//go:build integration
+// +build integration
+
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	apiPkg "github.com/determined-ai/determined/master/internal/api"
+	authz2 "github.com/determined-ai/determined/master/internal/authz"
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/pkg/ptrs"
+	"github.com/determined-ai/determined/proto/pkg/apiv1"
+)
+
+func TestModelEnsembleVersion(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+	exp := db.RequireMockExperiment(t, api.m.db, curUser)
+
+	var ids []uuid.UUID
+	var trialIDs []int
+	for i := 0; i < 2; i++ {
+		trial, task := db.RequireMockTrial(t, api.m.db, exp)
+		allocation := db.RequireMockAllocation(t, api.m.db, task.TaskID)
+		ckpt := db.MockModelCheckpoint(uuid.New(), allocation)
+		require.NoError(t, db.AddCheckpointMetadata(context.TODO(), &ckpt))
+		ids = append(ids, ckpt.UUID)
+		trialIDs = append(trialIDs, trial.ID)
+	}
+
+	modelResp, err := api.PostModel(ctx, &apiv1.PostModelRequest{Name: uuid.NewString()})
+	require.NoError(t, err)
+	modelID := fmt.Sprint(modelResp.Model.Id)
+
+	body, err := json.Marshal(ensembleVersionRequest{
+		Name:   "ensemble",
+		Labels: []string{"a", "b"},
+		Checkpoints: []ensembleMemberRequest{
+			{UUID: ids[1].String(), Role: "member", Weight: ptrs.Ptr(0.25)},
+			{UUID: ids[0].String(), Role: "member", Weight: ptrs.Ptr(0.75)},
+		},
+	})
+	require.NoError(t, err)
+	res, err := api.m.postModelEnsembleVersion(savedViewEchoContext(
+		curUser, http.MethodPost, string(body), map[string]string{"model_id": modelID}))
+	require.NoError(t, err)
+	members := res.(*modelVersionCheckpoints)
+	require.True(t, members.Ensemble)
+	require.Equal(t, int32(1), members.Version)
+	require.Len(t, members.Checkpoints, 2)
+	for i, j := range []int{1, 0} {
+		require.Equal(t, i, members.Checkpoints[i].Ordinal)
+		require.Equal(t, ids[j], members.Checkpoints[i].CheckpointUUID)
+		require.Equal(t, trialIDs[j], *members.Checkpoints[i].TrialID)
+		require.Equal(t, exp.ID, *members.Checkpoints[i].ExperimentID)
+	}
+
+	// The version points at its first member for clients that only know about one checkpoint.
+	mv, err := api.GetModelVersion(ctx, &apiv1.GetModelVersionRequest{
+		ModelName: modelID, ModelVersionNum: 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, ids[1].String(), mv.ModelVersion.Checkpoint.Uuid)
+
+	// Every member is protected from garbage collection and deletion.
+	registered, err := api.m.db.GetRegisteredCheckpoints(ids)
+	require.NoError(t, err)
+	require.Equal(t, map[uuid.UUID]bool{ids[0]: true, ids[1]: true}, registered)
+	inRegistry, err := api.m.db.ExperimentHasCheckpointsInRegistry(exp.ID)
+	require.NoError(t, err)
+	require.True(t, inRegistry)
+
+	res, err = api.m.getModelVersionCheckpoints(savedViewEchoContext(
+		curUser, http.MethodGet, "", map[string]string{"model_id": modelID, "version": "1"}))
+	require.NoError(t, err)
+	require.Equal(t, members, res)
+
+	// Members must be distinct, completed checkpoints.
+	for _, checkpoints := range [][]ensembleMemberRequest{
+		nil,
+		{{UUID: ids[0].String()}, {UUID: ids[0].String()}},
+		{{UUID: uuid.NewString()}},
+		{{UUID: ids[0].String(), Weight: ptrs.Ptr(-1.0)}},
+	} {
+		body, err := json.Marshal(ensembleVersionRequest{Checkpoints: checkpoints})
+		require.NoError(t, err)
+		_, err = api.m.postModelEnsembleVersion(savedViewEchoContext(
+			curUser, http.MethodPost, string(body), map[string]string{"model_id": modelID}))
+		require.Error(t, err)
+	}
+
+	// Deleting the version releases its members.
+	_, err = api.DeleteModelVersion(ctx, &apiv1.DeleteModelVersionRequest{
+		ModelName: modelID, ModelVersionNum: 1,
+	})
+	require.NoError(t, err)
+	registered, err = api.m.db.GetRegisteredCheckpoints(ids)
+	require.NoError(t, err)
+	require.Empty(t, registered)
+}
+
+func TestModelEnsembleVersionAuthZ(t *testing.T) {
+	api, authZExp, _, curUser, _ := setupExpAuthTest(t, nil)
+	authZModel := getMockModelAuth()
+	exp := db.RequireMockExperiment(t, api.m.db, curUser)
+
+	var ids []uuid.UUID
+	for i := 0; i < 2; i++ {
+		_, task := db.RequireMockTrial(t, api.m.db, exp)
+		allocation := db.RequireMockAllocation(t, api.m.db, task.TaskID)
+		ckpt := db.MockModelCheckpoint(uuid.New(), allocation)
+		require.NoError(t, db.AddCheckpointMetadata(context.TODO(), &ckpt))
+		ids = append(ids, ckpt.UUID)
+	}
+	modelID := fmt.Sprint(RegisterCheckpointAsModelVersion(t, api.m.db, ids[0]).Model.Id)
+
+	body, err := json.Marshal(ensembleVersionRequest{
+		Checkpoints: []ensembleMemberRequest{{UUID: ids[0].String()}, {UUID: ids[1].String()}},
+	})
+	require.NoError(t, err)
+	post := func() error {
+		authZModel.On("CanGetModel", mock.Anything, curUser, mock.Anything, mock.Anything).
+			Return(nil).Once()
+		authZModel.On("CanEditModel", mock.Anything, curUser, mock.Anything, mock.Anything).
+			Return(nil).Once()
+		_, err := api.m.postModelEnsembleVersion(savedViewEchoContext(
+			curUser, http.MethodPost, string(body), map[string]string{"model_id": modelID}))
+		return err
+	}
+
+	// A member the user can't get the artifacts of is not found, the same as one whose experiment
+	// the user can't see.
+	authZExp.On("CanGetExperiment", mock.Anything, curUser, mock.Anything).Return(nil).Once()
+	authZExp.On("CanGetExperimentArtifacts", mock.Anything, curUser, mock.Anything).
+		Return(nil).Once()
+	authZExp.On("CanGetExperiment", mock.Anything, curUser, mock.Anything).Return(nil).Once()
+	authZExp.On("CanGetExperimentArtifacts", mock.Anything, curUser, mock.Anything).
+		Return(fmt.Errorf("canGetArtifactsError")).Once()
+	require.Equal(t, apiPkg.NotFoundErrs("checkpoint", ids[1].String(), false), post())
+
+	authZExp.On("CanGetExperiment", mock.Anything, curUser, mock.Anything).
+		Return(authz2.PermissionDeniedError{}).Once()
+	require.Equal(t, apiPkg.NotFoundErrs("checkpoint", ids[0].String(), false), post())
+
+	authZExp.On("CanGetExperiment", mock.Anything, curUser, mock.Anything).Return(nil).Twice()
+	authZExp.On("CanGetExperimentArtifacts", mock.Anything, curUser, mock.Anything).
+		Return(nil).Twice()
+	require.NoError(t, post())
+
+	// Downloading the version checks every member too.
+	ctx := savedViewEchoContext(curUser, http.MethodGet, "",
+		map[string]string{"model_id": modelID, "version": "2"})
+	ctx.Request().Header.Set("Accept", MIMEApplicationZip)
+	authZModel.On("CanGetModel", mock.Anything, curUser, mock.Anything, mock.Anything).
+		Return(nil).Once()
+	authZExp.On("CanGetExperiment", mock.Anything, curUser, mock.Anything).Return(nil).Once()
+	authZExp.On("CanGetExperimentArtifacts", mock.Anything, curUser, mock.Anything).
+		Return(fmt.Errorf("canGetArtifactsError")).Once()
+	require.Equal(t, apiPkg.NotFoundErrs("checkpoint", ids[0].String(), false),
+		api.m.getModelVersionArchive(ctx))
+}
@@ -0,0 +1,291 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+	"github.com/uptrace/bun"
+
+	"github.com/determined-ai/determined/master/internal/api"
+	"github.com/determined-ai/determined/master/internal/config"
+	detContext "github.com/determined-ai/determined/master/internal/context"
+	"github.com/determined-ai/determined/master/internal/db"
+	modelauth "github.com/determined-ai/determined/master/internal/model"
+	"github.com/determined-ai/determined/master/internal/user"
+	"github.com/determined-ai/determined/master/pkg/checkpoints"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
+	"github.com/determined-ai/determined/master/pkg/tasks"
+)
+
+// modelVersionExport is a row of model_version_exports: the output of running the command of a
+// format on the checkpoints of a model version. Once the version is deleted, ModelVersionID is
+// zero until the files of the export are deleted along with the row.
+type modelVersionExport struct {
+	bun.BaseModel `bun:"table:model_version_exports"`
+
+	ID             int           `bun:"id,pk,autoincrement" json:"id"`
+	UUID           uuid.UUID     `bun:"uuid" json:"uuid"`
+	ModelVersionID int           `bun:"model_version_id,nullzero" json:"model_version_id"`
+	CheckpointUUID uuid.UUID     `bun:"checkpoint_uuid" json:"-"`
+	Format         string        `bun:"format" json:"format"`
+	State          string        `bun:"state" json:"state"`
+	TaskID         *model.TaskID `bun:"task_id" json:"task_id"`
+	UserID         *model.UserID `bun:"user_id" json:"user_id"`
+	Error          *string       `bun:"error" json:"error"`
+	StartTime      time.Time     `bun:"start_time" json:"start_time"`
+	EndTime        *time.Time    `bun:"end_time" json:"end_time"`
+}
+
+// modelVersionExportRequest is the body of POST /models/:model_id/versions/:version/exports.
+type modelVersionExportRequest struct {
+	// Format is the name of a format in the model_export section of the master config.
+	Format string `json:"format"`
+}
+
+//	@Summary	Export a model version to a serving format by running the command configured for it.
+//	@Tags		Models
+//	@ID			post-model-version-export
+//	@Accept		json
+//	@Produce	json
+//	@Param		model_id	path	int	true	"Model ID"
+//	@Param		version		path	int	true	"Model version"
+//	@Success	200			{}		modelVersionExport
+//	@Router		/models/{model_id}/versions/{version}/exports [post]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) postModelVersionExport(c echo.Context) (interface{}, error) {
+	args := struct {
+		ModelID int `path:"model_id"`
+		Version int `path:"version"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	var req modelVersionExportRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("decoding body: %s", err))
+	}
+	ctx := c.Request().Context()
+	curUser := c.(*detContext.DetContext).MustGetUser()
+
+	mv, err := m.echoGetModelVersion(ctx, c, args.ModelID, args.Version)
+	if err != nil {
+		return nil, err
+	}
+	if err := modelauth.AuthZProvider.Get().CanEditModel(ctx, curUser, mv.Model,
+		mv.Model.WorkspaceId); err != nil {
+		return nil, echo.NewHTTPError(http.StatusForbidden, err.Error())
+	}
+
+	exportConfig := config.GetMasterConfig().ModelExport
+	format, ok := exportConfig.Formats[req.Format]
+	if !ok {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf(
+			"unknown export format %q, the formats configured on the master are: %s",
+			req.Format, strings.Join(exportConfig.FormatNames(), ", ")))
+	}
+
+	members, err := getModelVersionCheckpoints(ctx, mv)
+	if err != nil {
+		return nil, err
+	}
+	var legacyConfig *expconf.LegacyConfig
+	var exportCheckpoints []tasks.ModelExportCheckpoint
+	for _, member := range members.Checkpoints {
+		memberConfig, err := m.getCheckpointLegacyConfig(member.CheckpointUUID)
+		switch {
+		case err != nil:
+			return nil, echo.NewHTTPError(http.StatusInternalServerError,
+				fmt.Sprintf("unable to retrieve experiment config for checkpoint %s: %s",
+					member.CheckpointUUID, err))
+		case memberConfig == nil:
+			return nil, api.NotFoundErrs("checkpoint", member.CheckpointUUID.String(), false)
+		case legacyConfig == nil:
+			legacyConfig = memberConfig
+		case !reflect.DeepEqual(legacyConfig.CheckpointStorage, memberConfig.CheckpointStorage):
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf(
+				"checkpoint %s is not in the same checkpoint storage as checkpoint %s, "+
+					"model versions can only be exported from a single checkpoint storage",
+				member.CheckpointUUID, members.Checkpoints[0].CheckpointUUID))
+		}
+		dir := ""
+		if members.Ensemble {
+			dir = fmt.Sprintf("%d-%s", member.Ordinal, member.CheckpointUUID)
+		}
+		exportCheckpoints = append(exportCheckpoints, tasks.ModelExportCheckpoint{
+			UUID: member.CheckpointUUID.String(),
+			Dir:  dir,
+		})
+	}
+
+	agentUserGroup, err := user.GetAgentUserGroup(ctx, curUser.ID, int(mv.Model.WorkspaceId))
+	if err != nil {
+		return nil, err
+	}
+
+	jobID := model.NewJobID()
+	if err := m.db.AddJob(&model.Job{
+		JobID:   jobID,
+		JobType: model.JobTypeModelExport,
+		OwnerID: &curUser.ID,
+	}); err != nil {
+		return nil, fmt.Errorf("persisting new job: %w", err)
+	}
+	export := modelVersionExport{
+		UUID:           uuid.New(),
+		ModelVersionID: int(mv.Id),
+		CheckpointUUID: members.Checkpoints[0].CheckpointUUID,
+		Format:         req.Format,
+		State:          modelExportRunning,
+		UserID:         &curUser.ID,
+		StartTime:      time.Now().UTC().Truncate(time.Millisecond),
+	}
+	if _, err := db.Bun().NewInsert().Model(&export).Returning("id").Exec(ctx); err != nil {
+		return nil, fmt.Errorf("adding export of model version %d: %w", mv.Id, err)
+	}
+
+	taskSpec := *m.taskSpec
+	taskSpec.AgentUserGroup = agentUserGroup
+	taskSpec.Owner = &curUser
+	taskSpec.WorkspaceID = int(mv.Model.WorkspaceId)
+	exportSpec := tasks.ModelExportSpec{
+		Base:                 taskSpec,
+		ExportUUID:           export.UUID.String(),
+		Format:               req.Format,
+		Image:                format.Image,
+		Command:              format.Command,
+		EnvironmentVariables: format.EnvironmentVariables,
+		LegacyConfig:         *legacyConfig,
+		Checkpoints:          exportCheckpoints,
+		Description: fmt.Sprintf("Model Export (%s, Version %d of Model %d)",
+			req.Format, mv.Version, mv.Model.Id),
+	}
+	taskID := model.NewTaskID()
+	go runModelExportTask(m.rm, m.db, []int{export.ID}, taskID, jobID, export.StartTime, exportSpec, nil)
+
+	log.Infof("exporting version %d of model %d to %s as %s",
+		mv.Version, mv.Model.Id, req.Format, export.UUID)
+	return export, nil
+}
+
+//	@Summary	Get the exports of a model version.
+//	@Tags		Models
+//	@ID			get-model-version-exports
+//	@Produce	json
+//	@Param		model_id	path	int	true	"Model ID"
+//	@Param		version		path	int	true	"Model version"
+//	@Success	200			{}		[]modelVersionExport
+//	@Router		/models/{model_id}/versions/{version}/exports [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getModelVersionExports(c echo.Context) (interface{}, error) {
+	args := struct {
+		ModelID int `path:"model_id"`
+		Version int `path:"version"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	mv, err := m.echoGetModelVersion(ctx, c, args.ModelID, args.Version)
+	if err != nil {
+		return nil, err
+	}
+	exports := []modelVersionExport{}
+	if err := db.Bun().NewSelect().Model(&exports).
+		Where("model_version_id = ?", mv.Id).
+		Order("id").
+		Scan(ctx); err != nil {
+		return nil, fmt.Errorf("getting exports of model version %d: %w", mv.Id, err)
+	}
+	return exports, nil
+}
+
+//	@Summary	Get the files of a completed model version export in a tgz or zip file.
+//	@Tags		Models
+//	@ID			get-model-version-export-archive
+//	@Produce	application/gzip,application/zip
+//	@Param		model_id	path	int	true	"Model ID"
+//	@Param		version		path	int	true	"Model version"
+//	@Param		export_id	path	int	true	"Export ID"
+//	@Success	200			{}		string	""
+//	@Router		/models/{model_id}/versions/{version}/exports/{export_id}/archive [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getModelVersionExportArchive(c echo.Context) error {
+	mimeType := c.Request().Header.Get("Accept")
+	if mimeType != MIMEApplicationGZip && mimeType != MIMEApplicationZip {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType,
+			fmt.Sprintf("unsupported media type to download a model version export: '%s'", mimeType))
+	}
+	args := struct {
+		ModelID  int `path:"model_id"`
+		Version  int `path:"version"`
+		ExportID int `path:"export_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	mv, err := m.echoGetModelVersion(ctx, c, args.ModelID, args.Version)
+	if err != nil {
+		return err
+	}
+
+	var export modelVersionExport
+	if err := db.Bun().NewSelect().Model(&export).
+		Where("id = ?", args.ExportID).
+		Where("model_version_id = ?", mv.Id).
+		Scan(ctx); err != nil {
+		return api.NotFoundErrs("model version export", fmt.Sprint(args.ExportID), false)
+	}
+	if export.State != modelExportCompleted {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("model version export %d is in %s state", export.ID, export.State))
+	}
+
+	// The export is stored next to the first checkpoint of the version.
+	storageConfig, err := m.getCheckpointStorageConfig(export.CheckpointUUID)
+	switch {
+	case err != nil:
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			fmt.Sprintf("unable to retrieve experiment config for checkpoint %s: %s",
+				export.CheckpointUUID, err))
+	case storageConfig == nil:
+		return api.NotFoundErrs("checkpoint", export.CheckpointUUID.String(), false)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, mimeType)
+	dw := newDelayWriter(c.Response(), 16*1024)
+	downloader, err := checkpoints.NewDownloader(
+		dw, export.UUID.String(), storageConfig, mimeToArchiveType(mimeType))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	switch err := downloader.Download(ctx); {
+	case err != nil && errors.Is(err, context.Canceled):
+		return err
+	case err != nil:
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			fmt.Sprintf("unable to download model version export %d: %s", export.ID, err))
+	}
+	if err := downloader.Close(); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			fmt.Sprintf("failed to complete model version export download: %s", err))
+	}
+	if err := dw.Close(); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError,
+			fmt.Sprintf("failed to complete model version export download: %s", err))
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+//go:build integration
+// +build integration
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/proto/pkg/apiv1"
+)
+
+func TestModelVersionExports(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+	exp := db.RequireMockExperiment(t, api.m.db, curUser)
+	_, task := db.RequireMockTrial(t, api.m.db, exp)
+	allocation := db.RequireMockAllocation(t, api.m.db, task.TaskID)
+	ckpt := db.MockModelCheckpoint(uuid.New(), allocation)
+	require.NoError(t, db.AddCheckpointMetadata(context.TODO(), &ckpt))
+
+	modelResp, err := api.PostModel(ctx, &apiv1.PostModelRequest{Name: uuid.NewString()})
+	require.NoError(t, err)
+	_, err = api.PostModelVersion(ctx, &apiv1.PostModelVersionRequest{
+		ModelName: modelResp.Model.Name, CheckpointUuid: ckpt.UUID.String(),
+	})
+	require.NoError(t, err)
+	params := map[string]string{"model_id": fmt.Sprint(modelResp.Model.Id), "version": "1"}
+
+	// Only formats configured on the master can be exported to.
+	_, err = api.m.postModelVersionExport(savedViewEchoContext(
+		curUser, http.MethodPost, `{"format": "not-configured"}`, params))
+	require.ErrorContains(t, err, "unknown export format")
+
+	res, err := api.m.getModelVersionExports(savedViewEchoContext(curUser, http.MethodGet, "", params))
+	require.NoError(t, err)
+	require.Empty(t, res)
+
+	mv, err := api.GetModelVersion(ctx, &apiv1.GetModelVersionRequest{
+		ModelName: modelResp.Model.Name, ModelVersionNum: 1,
+	})
+	require.NoError(t, err)
+	failed := modelVersionExport{
+		UUID:           uuid.New(),
+		ModelVersionID: int(mv.ModelVersion.Id),
+		CheckpointUUID: ckpt.UUID,
+		Format:         "onnx",
+		State:          modelExportError,
+		UserID:         &curUser.ID,
+		StartTime:      time.Now().UTC().Truncate(time.Millisecond),
+	}
+	_, err = db.Bun().NewInsert().Model(&failed).Returning("id").Exec(ctx)
+	require.NoError(t, err)
+
+	res, err = api.m.getModelVersionExports(savedViewEchoContext(curUser, http.MethodGet, "", params))
+	require.NoError(t, err)
+	exports := res.([]modelVersionExport)
+	require.Len(t, exports, 1)
+	require.Equal(t, failed.UUID, exports[0].UUID)
+	require.Equal(t, modelExportError, exports[0].State)
+
+	// Only completed exports can be downloaded.
+	params["export_id"] = fmt.Sprint(failed.ID)
+	c := savedViewEchoContext(curUser, http.MethodGet, "", params)
+	c.Request().Header.Set("Accept", MIMEApplicationGZip)
+	require.ErrorContains(t, api.m.getModelVersionExportArchive(c), "ERROR state")
+}
+
+func TestFailInterruptedModelExports(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+	exp := db.RequireMockExperiment(t, api.m.db, curUser)
+	_, task := db.RequireMockTrial(t, api.m.db, exp)
+	allocation := db.RequireMockAllocation(t, api.m.db, task.TaskID)
+	ckpt := db.MockModelCheckpoint(uuid.New(), allocation)
+	require.NoError(t, db.AddCheckpointMetadata(context.TODO(), &ckpt))
+
+	modelResp, err := api.PostModel(ctx, &apiv1.PostModelRequest{Name: uuid.NewString()})
+	require.NoError(t, err)
+	mvResp, err := api.PostModelVersion(ctx, &apiv1.PostModelVersionRequest{
+		ModelName: modelResp.Model.Name, CheckpointUuid: ckpt.UUID.String(),
+	})
+	require.NoError(t, err)
+	running := modelVersionExport{
+		UUID:           uuid.New(),
+		ModelVersionID: int(mvResp.ModelVersion.Id),
+		CheckpointUUID: ckpt.UUID,
+		Format:         "onnx",
+		State:          modelExportRunning,
+		UserID:         &curUser.ID,
+		StartTime:      time.Now().UTC().Truncate(time.Millisecond),
+	}
+	_, err = db.Bun().NewInsert().Model(&running).Returning("id").Exec(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, failInterruptedModelExports(ctx))
+	var export modelVersionExport
+	require.NoError(t, db.Bun().NewSelect().Model(&export).Where("id = ?", running.ID).Scan(ctx))
+	require.Equal(t, modelExportError, export.State)
+	require.NotNil(t, export.EndTime)
+
+	// Deleting the version keeps the export until its files are deleted.
+	_, err = api.DeleteModelVersion(ctx, &apiv1.DeleteModelVersionRequest{
+		ModelName: modelResp.Model.Name, ModelVersionNum: 1,
+	})
+	require.NoError(t, err)
+	export = modelVersionExport{}
+	require.NoError(t, db.Bun().NewSelect().Model(&export).Where("id = ?", running.ID).Scan(ctx))
+	require.Zero(t, export.ModelVersionID)
+	require.Equal(t, ckpt.UUID, export.CheckpointUUID)
+}
@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/determined-ai/determined/master/internal/api"
+	"github.com/determined-ai/determined/master/internal/db"
+	expauth "github.com/determined-ai/determined/master/internal/experiment"
+	"github.com/determined-ai/determined/master/internal/profiler"
+)
+
+//	@Summary	Get the summary of the profiler metrics of a trial and the bottlenecks they point at.
+//	@Tags		Trials
+//	@ID			get-trial-profiler-summary
+//	@Produce	json
+//	@Param		trial_id	path	int	true	"Trial ID"
+//	@Success	200			{}		profiler.TrialSummary
+//	@Router		/trials/{trial_id}/profiler-summary [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getTrialProfilerSummary(c echo.Context) (interface{}, error) {
+	args := struct {
+		TrialID int `path:"trial_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	if err := echoGetTrialExperimentAndCheckCanDoActions(ctx, c, m, args.TrialID,
+		expauth.AuthZProvider.Get().CanGetExperimentArtifacts); err != nil {
+		return nil, err
+	}
+
+	summary, err := profiler.GetTrialSummary(ctx, args.TrialID)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, api.NotFoundErrs("profiler summary of trial", fmt.Sprint(args.TrialID), false)
+	}
+	return summary, err
+}
+
+//	@Summary	Get the summary of the profiler metrics of an experiment and each of its trials.
+//	@Tags		Experiments
+//	@ID			get-experiment-profiler-summary
+//	@Produce	json
+//	@Param		experiment_id	path	int	true	"Experiment ID"
+//	@Success	200				{}		profiler.ExperimentSummary
+//	@Router		/experiments/{experiment_id}/profiler-summary [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getExperimentProfilerSummary(c echo.Context) (interface{}, error) {
+	args := struct {
+		ExperimentID int `path:"experiment_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	if _, _, err := echoGetExperimentAndCheckCanDoActions(ctx, c, m, args.ExperimentID,
+		expauth.AuthZProvider.Get().CanGetExperimentArtifacts); err != nil {
+		return nil, err
+	}
+
+	summary, err := profiler.GetExperimentSummary(ctx, args.ExperimentID)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, api.NotFoundErrs("profiler summary of experiment", fmt.Sprint(args.ExperimentID),
+			false)
+	}
+	return summary, err
+}
@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+
+	"github.com/determined-ai/determined/master/internal/api"
+	"github.com/determined-ai/determined/master/internal/authz"
+	detContext "github.com/determined-ai/determined/master/internal/context"
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/sproto"
+	workspaceauth "github.com/determined-ai/determined/master/internal/workspace"
+)
+
+type rpWorkspaceBindingArgs struct {
+	PoolName    string `path:"pool_name"`
+	WorkspaceID int    `path:"workspace_id"`
+}
+
+// pushWorkspaceMaxSlots sends the max slots of the bindings to a pool to the resource manager,
+// after the bindings to the pool change.
+func (m *Master) pushWorkspaceMaxSlots(ctx context.Context, poolName string) error {
+	maxSlots, err := db.GetRPWorkspaceMaxSlots(ctx, poolName)
+	if err != nil {
+		return fmt.Errorf("getting the workspace max slots of pool %s: %w", poolName, err)
+	}
+	m.rm.SetWorkspaceMaxSlots(sproto.SetWorkspaceMaxSlots{
+		ResourcePool: poolName,
+		MaxSlots:     maxSlots,
+	})
+	return nil
+}
+
+func rpWorkspaceBindingNotFound(args rpWorkspaceBindingArgs) error {
+	return api.NotFoundErrs("resource pool binding",
+		fmt.Sprintf("%s/%d", args.PoolName, args.WorkspaceID), false)
+}
+
+//	@Summary	Get the scheduling policy of a resource pool's binding to a workspace.
+//	@Tags		Cluster
+//	@ID			get-rp-workspace-binding
+//	@Produce	json
+//	@Param		pool_name		path	string	true	"Resource pool name"
+//	@Param		workspace_id	path	int		true	"Workspace ID"
+//	@Success	200				{}		db.RPWorkspaceBindingPolicy
+//	@Router		/resource-pools/{pool_name}/workspaces/{workspace_id}/binding [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getRPWorkspaceBinding(c echo.Context) (interface{}, error) {
+	var args rpWorkspaceBindingArgs
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	user := c.(*detContext.DetContext).MustGetUser()
+	if err := workspaceauth.AuthZProvider.Get().CanGetWorkspaceID(
+		ctx, user, int32(args.WorkspaceID)); err != nil {
+		return nil, authz.SubIfUnauthorized(err, rpWorkspaceBindingNotFound(args))
+	}
+
+	binding, err := db.GetRPWorkspaceBinding(ctx, args.WorkspaceID, args.PoolName)
+	switch {
+	case err != nil:
+		return nil, err
+	case binding == nil:
+		return nil, rpWorkspaceBindingNotFound(args)
+	}
+	return binding.RPWorkspaceBindingPolicy, nil
+}
+
+//	@Summary	Set the scheduling policy of an existing resource pool binding to a workspace.
+//	@Tags		Cluster
+//	@ID			put-rp-workspace-binding
+//	@Accept		json
+//	@Produce	json
+//	@Param		pool_name		path	string	true	"Resource pool name"
+//	@Param		workspace_id	path	int		true	"Workspace ID"
+//	@Success	200				{}		db.RPWorkspaceBindingPolicy
+//	@Router		/resource-pools/{pool_name}/workspaces/{workspace_id}/binding [put]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) putRPWorkspaceBinding(c echo.Context) (interface{}, error) {
+	var args rpWorkspaceBindingArgs
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	user := c.(*detContext.DetContext).MustGetUser()
+	if err := workspaceauth.AuthZProvider.Get().CanModifyRPWorkspaceBindings(
+		ctx, user, []int32{int32(args.WorkspaceID)}); err != nil {
+		return nil, authz.SubIfUnauthorized(err, echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf(
+			"current user %q doesn't have permissions to modify resource pool bindings",
+			user.Username)))
+	}
+
+	var policy db.RPWorkspaceBindingPolicy
+	dec := json.NewDecoder(c.Request().Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&policy); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("decoding body: %s", err))
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	err := db.SetRPWorkspaceBindingPolicy(ctx, args.WorkspaceID, args.PoolName, policy)
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		return nil, rpWorkspaceBindingNotFound(args)
+	case errors.Is(err, db.ErrInvalidInput):
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case err != nil:
+		return nil, err
+	}
+	if err := m.pushWorkspaceMaxSlots(ctx, args.PoolName); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
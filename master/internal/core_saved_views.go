@@ -0,0 +1,391 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/determined-ai/determined/master/internal/api"
+	detContext "github.com/determined-ai/determined/master/internal/context"
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/ptrs"
+	"github.com/determined-ai/determined/proto/pkg/apiv1"
+	"github.com/determined-ai/determined/proto/pkg/projectv1"
+)
+
+// savedViewRequest is the body accepted when creating or updating a saved view. Unset fields are
+// left untouched on update.
+type savedViewRequest struct {
+	Name       *string                    `json:"name"`
+	Visibility *model.SavedViewVisibility `json:"visibility"`
+	Filter     json.RawMessage            `json:"filter"`
+	Sort       *string                    `json:"sort"`
+	Columns    []string                   `json:"columns"`
+}
+
+// validateSavedViewSearch checks that the filter, sort and columns of a saved view are accepted by
+// SearchExperiments, so that broken views are rejected at save time rather than at run time.
+func validateSavedViewSearch(filter json.RawMessage, sort *string, columns []string) error {
+	q := db.Bun().NewSelect().TableExpr("experiments AS e")
+	if filter != nil {
+		var efr experimentFilterRoot
+		if err := json.Unmarshal(filter, &efr); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Sprintf("invalid filter: %s", err))
+		}
+		if _, err := efr.toSQL(q); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest,
+				fmt.Sprintf("invalid filter: %s", err))
+		}
+	}
+	if err := sortExperiments(sort, q); err != nil {
+		if ok, echoErr := api.GrpcErrToEcho(err); ok {
+			return echoErr
+		}
+		return err
+	}
+	// Columns are the names that experiments can be sorted by.
+	for _, col := range columns {
+		if col == "" || strings.ContainsAny(col, "=,") {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid column %q", col))
+		}
+		if err := sortExperiments(ptrs.Ptr(col+"=asc"), q); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid column %q", col))
+		}
+	}
+	return nil
+}
+
+// savedViewFilter returns the filter tree of a request, which may also be given as a JSON-encoded
+// string like SearchExperiments takes it. A null filter clears the view's filter.
+func savedViewFilter(raw json.RawMessage) (json.RawMessage, error) {
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return nil, nil
+	}
+	var encoded string
+	if err := json.Unmarshal(raw, &encoded); err == nil {
+		raw = json.RawMessage(encoded)
+	}
+	if !json.Valid(raw) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid filter: not valid JSON")
+	}
+	return raw, nil
+}
+
+func (m *Master) echoGetProject(
+	ctx context.Context, user model.User, projectID int,
+) (*projectv1.Project, error) {
+	p, err := (&apiServer{m: m}).GetProjectByID(ctx, int32(projectID), user)
+	if err != nil {
+		if ok, echoErr := api.GrpcErrToEcho(err); ok {
+			return nil, echoErr
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// savedViewByID returns the saved view if the user can see it, and a not found error otherwise.
+func (m *Master) savedViewByID(
+	ctx context.Context, user model.User, viewID int,
+) (*model.SavedView, error) {
+	notFound := api.NotFoundErrs("saved view", fmt.Sprint(viewID), false)
+
+	var view model.SavedView
+	err := db.Bun().NewSelect().Model(&view).Where("id = ?", viewID).Scan(ctx)
+	switch {
+	case errors.Is(db.MatchSentinelError(err), db.ErrNotFound):
+		return nil, notFound
+	case err != nil:
+		return nil, fmt.Errorf("fetching saved view %d: %w", viewID, err)
+	}
+
+	if _, err := m.echoGetProject(ctx, user, view.ProjectID); err != nil {
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) && httpErr.Code == http.StatusNotFound {
+			return nil, notFound
+		}
+		return nil, err
+	}
+	if !view.VisibleTo(user) {
+		return nil, notFound
+	}
+	return &view, nil
+}
+
+//	@Summary	List the saved experiment views in a project visible to the current user.
+//	@Tags		Projects
+//	@ID			get-project-saved-views
+//	@Produce	json
+//	@Param		project_id	path	int	true	"Project ID"
+//	@Success	200			{}		[]model.SavedView
+//	@Router		/projects/{project_id}/saved-views [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getProjectSavedViews(c echo.Context) (interface{}, error) {
+	args := struct {
+		ProjectID int `path:"project_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	user := c.(*detContext.DetContext).MustGetUser()
+	if _, err := m.echoGetProject(ctx, user, args.ProjectID); err != nil {
+		return nil, err
+	}
+
+	views := []*model.SavedView{}
+	q := db.Bun().NewSelect().Model(&views).
+		Where("project_id = ?", args.ProjectID).
+		Order("name ASC")
+	if !user.Admin {
+		q.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("visibility = ?", model.SavedViewVisibilityProject).
+				WhereOr("owner_id = ?", user.ID)
+		})
+	}
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("fetching saved views for project %d: %w", args.ProjectID, err)
+	}
+	return views, nil
+}
+
+//	@Summary	Create a saved experiment view in a project.
+//	@Tags		Projects
+//	@ID			post-project-saved-view
+//	@Accept		json
+//	@Produce	json
+//	@Param		project_id	path	int	true	"Project ID"
+//	@Success	200			{}		model.SavedView
+//	@Router		/projects/{project_id}/saved-views [post]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) postProjectSavedView(c echo.Context) (interface{}, error) {
+	args := struct {
+		ProjectID int `path:"project_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	var req savedViewRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("decoding body: %s", err))
+	}
+	ctx := c.Request().Context()
+	user := c.(*detContext.DetContext).MustGetUser()
+	if _, err := m.echoGetProject(ctx, user, args.ProjectID); err != nil {
+		return nil, err
+	}
+
+	view := &model.SavedView{
+		ProjectID:  args.ProjectID,
+		OwnerID:    user.ID,
+		Visibility: model.SavedViewVisibilityPrivate,
+		Columns:    []string{},
+	}
+	if err := applySavedViewRequest(view, req); err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Bun().NewInsert().Model(view).Returning("*").Exec(ctx); err != nil {
+		if errors.Is(db.MatchSentinelError(err), db.ErrDuplicateRecord) {
+			return nil, echo.NewHTTPError(http.StatusConflict,
+				fmt.Sprintf("a saved view named %q already exists", view.Name))
+		}
+		return nil, fmt.Errorf("creating saved view: %w", err)
+	}
+	return view, nil
+}
+
+//	@Summary	Get a saved experiment view.
+//	@Tags		Projects
+//	@ID			get-saved-view
+//	@Produce	json
+//	@Param		view_id	path	int	true	"Saved view ID"
+//	@Success	200		{}		model.SavedView
+//	@Router		/saved-views/{view_id} [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getSavedView(c echo.Context) (interface{}, error) {
+	args := struct {
+		ViewID int `path:"view_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	return m.savedViewByID(
+		c.Request().Context(), c.(*detContext.DetContext).MustGetUser(), args.ViewID)
+}
+
+//	@Summary	Update a saved experiment view. Only the owner or an admin may do so.
+//	@Tags		Projects
+//	@ID			patch-saved-view
+//	@Accept		json
+//	@Produce	json
+//	@Param		view_id	path	int	true	"Saved view ID"
+//	@Success	200		{}		model.SavedView
+//	@Router		/saved-views/{view_id} [patch]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) patchSavedView(c echo.Context) (interface{}, error) {
+	args := struct {
+		ViewID int `path:"view_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	var req savedViewRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("decoding body: %s", err))
+	}
+	ctx := c.Request().Context()
+	user := c.(*detContext.DetContext).MustGetUser()
+	view, err := m.savedViewByID(ctx, user, args.ViewID)
+	if err != nil {
+		return nil, err
+	}
+	if !view.EditableBy(user) {
+		return nil, echo.NewHTTPError(http.StatusForbidden,
+			"only the owner of a saved view or an admin can modify it")
+	}
+
+	if err := applySavedViewRequest(view, req); err != nil {
+		return nil, err
+	}
+	view.UpdatedAt = time.Now().UTC()
+	if _, err := db.Bun().NewUpdate().Model(view).
+		Column("name", "visibility", "filter", "sort", "columns", "updated_at").
+		WherePK().
+		Exec(ctx); err != nil {
+		if errors.Is(db.MatchSentinelError(err), db.ErrDuplicateRecord) {
+			return nil, echo.NewHTTPError(http.StatusConflict,
+				fmt.Sprintf("a saved view named %q already exists", view.Name))
+		}
+		return nil, fmt.Errorf("updating saved view %d: %w", view.ID, err)
+	}
+	return view, nil
+}
+
+//	@Summary	Delete a saved experiment view. Only the owner or an admin may do so.
+//	@Tags		Projects
+//	@ID			delete-saved-view
+//	@Param		view_id	path	int	true	"Saved view ID"
+//	@Success	204
+//	@Router		/saved-views/{view_id} [delete]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) deleteSavedView(c echo.Context) (interface{}, error) {
+	args := struct {
+		ViewID int `path:"view_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	user := c.(*detContext.DetContext).MustGetUser()
+	view, err := m.savedViewByID(ctx, user, args.ViewID)
+	if err != nil {
+		return nil, err
+	}
+	if !view.EditableBy(user) {
+		return nil, echo.NewHTTPError(http.StatusForbidden,
+			"only the owner of a saved view or an admin can delete it")
+	}
+
+	if _, err := db.Bun().NewDelete().Model(view).WherePK().Exec(ctx); err != nil {
+		return nil, fmt.Errorf("deleting saved view %d: %w", view.ID, err)
+	}
+	return nil, nil
+}
+
+//	@Summary	Run a saved experiment view and return the matching experiments.
+//	@Description	The response has the same shape as SearchExperiments. Experiments are
+//	@Description	filtered further by what the calling user is allowed to see.
+//	@Tags		Projects
+//	@ID			get-saved-view-experiments
+//	@Produce	json
+//	@Param		view_id	path	int	true	"Saved view ID"
+//	@Param		offset	query	int	false	"Skip the first N experiments"
+//	@Param		limit	query	int	false	"Return at most N experiments"
+//	@Success	200		{}		apiv1.SearchExperimentsResponse
+//	@Router		/saved-views/{view_id}/experiments [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getSavedViewExperiments(c echo.Context) (interface{}, error) {
+	args := struct {
+		ViewID int  `path:"view_id"`
+		Offset *int `query:"offset"`
+		Limit  *int `query:"limit"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	user := c.(*detContext.DetContext).MustGetUser()
+	view, err := m.savedViewByID(ctx, user, args.ViewID)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &apiv1.SearchExperimentsRequest{
+		ProjectId: ptrs.Ptr(int32(view.ProjectID)),
+		Sort:      view.Sort,
+	}
+	if view.Filter != nil {
+		req.Filter = ptrs.Ptr(string(view.Filter))
+	}
+	if args.Offset != nil {
+		req.Offset = int32(*args.Offset)
+	}
+	if args.Limit != nil {
+		req.Limit = int32(*args.Limit)
+	}
+	resp, err := (&apiServer{m: m}).searchExperiments(ctx, &user, req)
+	if err != nil {
+		if ok, echoErr := api.GrpcErrToEcho(err); ok {
+			return nil, echoErr
+		}
+		return nil, err
+	}
+	return protojson.Marshal(resp)
+}
+
+func applySavedViewRequest(view *model.SavedView, req savedViewRequest) error {
+	if req.Name != nil {
+		view.Name = strings.TrimSpace(*req.Name)
+	}
+	if view.Name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "saved view name must be non-empty")
+	}
+	if req.Visibility != nil {
+		if err := req.Visibility.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		view.Visibility = *req.Visibility
+	}
+	if req.Filter != nil {
+		filter, err := savedViewFilter(req.Filter)
+		if err != nil {
+			return err
+		}
+		view.Filter = filter
+	}
+	if req.Sort != nil {
+		view.Sort = req.Sort
+	}
+	if req.Columns != nil {
+		view.Columns = req.Columns
+	}
+	return validateSavedViewSearch(view.Filter, view.Sort, view.Columns)
+}
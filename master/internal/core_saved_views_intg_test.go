@@ -0,0 +1,135 @@
+//go:build integration
+// +build integration
+
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	detContext "github.com/determined-ai/determined/master/internal/context"
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/proto/pkg/apiv1"
+)
+
+func savedViewEchoContext(
+	user model.User, method, body string, params map[string]string,
+) *detContext.DetContext {
+	e := echo.New()
+	req := httptest.NewRequest(method, "/", strings.NewReader(body))
+	ctx := &detContext.DetContext{Context: e.NewContext(req, httptest.NewRecorder())}
+	ctx.SetUser(user)
+	for k, v := range params {
+		ctx.SetParamNames(append(ctx.ParamNames(), k)...)
+		ctx.SetParamValues(append(ctx.ParamValues(), v)...)
+	}
+	return ctx
+}
+
+func TestSavedViewsCRUDAndRun(t *testing.T) {
+	api, curUser, _ := setupAPITest(t, nil)
+	exp := db.RequireMockExperiment(t, api.m.db, curUser)
+
+	filter := fmt.Sprintf(`{"filterGroup": {"kind": "group", "conjunction": "and", "children": [
+		{"kind": "field", "columnName": "id", "location": "LOCATION_TYPE_EXPERIMENT",
+		 "operator": "=", "value": %d}]}, "showArchived": true}`, exp.ID)
+	body, err := json.Marshal(map[string]any{
+		"name":       "just-this-one",
+		"visibility": model.SavedViewVisibilityPrivate,
+		"filter":     filter,
+		"sort":       "id=desc",
+		"columns":    []string{"id", "name"},
+	})
+	require.NoError(t, err)
+
+	projectID := fmt.Sprint(exp.ProjectID)
+	res, err := api.m.postProjectSavedView(savedViewEchoContext(
+		curUser, http.MethodPost, string(body), map[string]string{"project_id": projectID}))
+	require.NoError(t, err)
+	view := res.(*model.SavedView)
+	require.Equal(t, "just-this-one", view.Name)
+	require.Equal(t, curUser.ID, view.OwnerID)
+	require.Equal(t, []string{"id", "name"}, view.Columns)
+
+	viewID := fmt.Sprint(view.ID)
+	res, err = api.m.getSavedViewExperiments(savedViewEchoContext(
+		curUser, http.MethodGet, "", map[string]string{"view_id": viewID}))
+	require.NoError(t, err)
+	var resp apiv1.SearchExperimentsResponse
+	require.NoError(t, protojson.Unmarshal(res.([]byte), &resp))
+	require.Len(t, resp.Experiments, 1)
+	require.Equal(t, int32(exp.ID), resp.Experiments[0].Experiment.Id)
+
+	// Private views are invisible to other non-admin users.
+	otherUser := db.RequireMockUser(t, api.m.db)
+	_, err = api.m.getSavedView(savedViewEchoContext(
+		otherUser, http.MethodGet, "", map[string]string{"view_id": viewID}))
+	require.Error(t, err)
+	res, err = api.m.getProjectSavedViews(savedViewEchoContext(
+		otherUser, http.MethodGet, "", map[string]string{"project_id": projectID}))
+	require.NoError(t, err)
+	for _, v := range res.([]*model.SavedView) {
+		require.NotEqual(t, view.ID, v.ID)
+	}
+
+	// Sharing the view with the project makes it visible, but not editable.
+	_, err = api.m.patchSavedView(savedViewEchoContext(
+		curUser, http.MethodPatch, `{"visibility": "PROJECT"}`,
+		map[string]string{"view_id": viewID}))
+	require.NoError(t, err)
+	res, err = api.m.getSavedView(savedViewEchoContext(
+		otherUser, http.MethodGet, "", map[string]string{"view_id": viewID}))
+	require.NoError(t, err)
+	require.Equal(t, model.SavedViewVisibilityProject, res.(*model.SavedView).Visibility)
+	_, err = api.m.deleteSavedView(savedViewEchoContext(
+		otherUser, http.MethodDelete, "", map[string]string{"view_id": viewID}))
+	require.Error(t, err)
+
+	// Invalid searches are rejected at save time.
+	_, err = api.m.patchSavedView(savedViewEchoContext(
+		curUser, http.MethodPatch, `{"sort": "id=sideways"}`,
+		map[string]string{"view_id": viewID}))
+	require.Error(t, err)
+	_, err = api.m.patchSavedView(savedViewEchoContext(
+		curUser, http.MethodPatch, `{"columns": ["id", "not a column"]}`,
+		map[string]string{"view_id": viewID}))
+	require.Error(t, err)
+
+	// Filters can be given as a tree instead of an encoded string.
+	res, err = api.m.patchSavedView(savedViewEchoContext(
+		curUser, http.MethodPatch, `{"filter": `+filter+`, "columns": ["id", "hp.lr"]}`,
+		map[string]string{"view_id": viewID}))
+	require.NoError(t, err)
+	require.JSONEq(t, filter, string(res.(*model.SavedView).Filter))
+
+	// A null filter clears it.
+	_, err = api.m.patchSavedView(savedViewEchoContext(
+		curUser, http.MethodPatch, `{"filter": null}`,
+		map[string]string{"view_id": viewID}))
+	require.NoError(t, err)
+	res, err = api.m.getSavedView(savedViewEchoContext(
+		curUser, http.MethodGet, "", map[string]string{"view_id": viewID}))
+	require.NoError(t, err)
+	require.Nil(t, res.(*model.SavedView).Filter)
+	var filterIsNull bool
+	require.NoError(t, db.Bun().NewSelect().Table("saved_views").
+		ColumnExpr("filter IS NULL").Where("id = ?", view.ID).Scan(context.Background(), &filterIsNull))
+	require.True(t, filterIsNull)
+
+	_, err = api.m.deleteSavedView(savedViewEchoContext(
+		curUser, http.MethodDelete, "", map[string]string{"view_id": viewID}))
+	require.NoError(t, err)
+	_, err = api.m.getSavedView(savedViewEchoContext(
+		curUser, http.MethodGet, "", map[string]string{"view_id": viewID}))
+	require.Error(t, err)
+}
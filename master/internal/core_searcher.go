@@ -2,31 +2,40 @@ package internal
 
 import (
 	"io"
+	"net/http"
 
 	"github.com/labstack/echo/v4"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/determined-ai/determined/master/internal/api"
+	"github.com/determined-ai/determined/master/internal/db"
 	"github.com/determined-ai/determined/master/pkg/schemas"
 	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
 	"github.com/determined-ai/determined/master/pkg/searcher"
 )
 
-func (m *Master) getSearcherPreview(c echo.Context) (interface{}, error) {
+// parseSearcherPreviewConfig reads the searcher and hyperparameters of an experiment config from
+// the request body.
+func parseSearcherPreviewConfig(
+	c echo.Context,
+) (expconf.ExperimentConfig, expconf.SearcherConfig, expconf.Hyperparameters, error) {
 	bytes, err := io.ReadAll(c.Request().Body)
 	if err != nil {
-		return nil, err
+		return expconf.ExperimentConfig{}, expconf.SearcherConfig{}, nil, err
 	}
 
 	// Parse the provided experiment config.
 	config, err := expconf.ParseAnyExperimentConfigYAML(bytes)
 	if err != nil {
-		return nil, errors.Wrapf(err, "invalid experiment configuration")
+		return config, expconf.SearcherConfig{}, nil,
+			errors.Wrapf(err, "invalid experiment configuration")
 	}
 
 	// Get the useful subconfigs for preview search.
 	if config.RawSearcher == nil {
-		return nil, errors.New("invalid experiment configuration; missing searcher")
+		return config, expconf.SearcherConfig{}, nil,
+			errors.New("invalid experiment configuration; missing searcher")
 	}
 	sc := *config.RawSearcher
 	hc := config.RawHyperparameters
@@ -37,15 +46,23 @@ func (m *Master) getSearcherPreview(c echo.Context) (interface{}, error) {
 
 	// Make sure the searcher config has all eventuallyRequired fields.
 	if err = schemas.IsComplete(sc); err != nil {
-		return nil, errors.Wrapf(err, "invalid searcher configuration")
+		return config, sc, hc, errors.Wrapf(err, "invalid searcher configuration")
 	}
 	if err = schemas.IsComplete(hc); err != nil {
-		return nil, errors.Wrapf(err, "invalid hyperparameters configuration")
+		return config, sc, hc, errors.Wrapf(err, "invalid hyperparameters configuration")
 	}
 
 	// Disallow EOL searchers.
 	if err = sc.AssertCurrent(); err != nil {
-		return nil, errors.Wrap(err, "invalid experiment configuration")
+		return config, sc, hc, errors.Wrap(err, "invalid experiment configuration")
+	}
+	return config, sc, hc, nil
+}
+
+func (m *Master) getSearcherPreview(c echo.Context) (interface{}, error) {
+	config, sc, hc, err := parseSearcherPreviewConfig(c)
+	if err != nil {
+		return nil, err
 	}
 
 	sm := searcher.NewSearchMethod(sc)
@@ -53,6 +70,102 @@ func (m *Master) getSearcherPreview(c echo.Context) (interface{}, error) {
 	return searcher.Simulate(s, nil, searcher.RandomValidation, true, config.Searcher().Metric())
 }
 
+// batchesToSearcherUnits returns the number of searcher units in a batch of a recorded trial.
+func batchesToSearcherUnits(unit expconf.Unit, trial db.TrialSearcherHistory) (float64, error) {
+	if unit == expconf.Batches {
+		return 1, nil
+	}
+	globalBatchSize, ok := trial.HParams["global_batch_size"].(float64)
+	if !ok || globalBatchSize <= 0 {
+		return 0, errors.Errorf(
+			"trial %d has no global_batch_size to convert batches to %s", trial.TrialID, unit)
+	}
+	switch unit {
+	case expconf.Records:
+		return globalBatchSize, nil
+	case expconf.Epochs:
+		if trial.RecordsPerEpoch == nil || *trial.RecordsPerEpoch <= 0 {
+			return 0, errors.Errorf("experiment has no records_per_epoch to convert batches to epochs")
+		}
+		return globalBatchSize / float64(*trial.RecordsPerEpoch), nil
+	default:
+		return 0, errors.Errorf("cannot replay a searcher measured in %s", unit)
+	}
+}
+
+//	@Summary	Replay a searcher config against the recorded validations of an experiment.
+//	@Description	Each simulated trial takes its metrics from the recorded trials nearest to it by
+//	@Description	hyperparameters, interpolated between the nearest `neighbors` trials. The result
+//	@Description	estimates the GPU hours the search would have used and the best metric it found.
+//	@Tags		Experiments
+//	@ID			post-searcher-replay
+//	@Accept		json
+//	@Produce	json
+//	@Param		experiment_id	path	int	true	"ID of the recorded experiment"
+//	@Param		neighbors		query	int	false	"Number of recorded trials to interpolate between"
+//	@Param		seed			query	int	false	"Seed of the simulation"
+//	@Success	200				{}		searcher.ReplayResult
+//	@Router		/searcher/replay/{experiment_id} [post]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) postSearcherReplay(c echo.Context) (interface{}, error) {
+	args := struct {
+		ExperimentID int    `path:"experiment_id"`
+		Neighbors    *int   `query:"neighbors"`
+		Seed         *int64 `query:"seed"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	if _, _, err := echoGetExperimentAndCheckCanDoActions(ctx, c, m, args.ExperimentID); err != nil {
+		return nil, err
+	}
+
+	_, sc, hc, err := parseSearcherPreviewConfig(c)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if sc.RawCustomConfig != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "custom searchers cannot be replayed")
+	}
+	neighbors := 1
+	if args.Neighbors != nil {
+		neighbors = *args.Neighbors
+	}
+
+	history, err := db.ExperimentSearcherHistory(ctx, args.ExperimentID, sc.Metric())
+	if err != nil {
+		return nil, err
+	}
+	trials := make([]searcher.ReplayTrial, 0, len(history))
+	for _, h := range history {
+		unitsPerBatch, err := batchesToSearcherUnits(sc.Unit(), h)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		trial := searcher.ReplayTrial{TrialID: h.TrialID, Hparams: h.HParams}
+		for _, v := range h.Validations {
+			trial.Validations = append(trial.Validations, searcher.ReplayValidation{
+				Length: float64(v.TotalBatches) * unitsPerBatch,
+				Metric: v.Metric,
+			})
+		}
+		if h.TotalBatches > 0 {
+			trial.SlotSecondsPerUnit = h.SlotSeconds / (float64(h.TotalBatches) * unitsPerBatch)
+		}
+		trials = append(trials, trial)
+	}
+
+	s := searcher.NewSearcher(0, searcher.NewSearchMethod(sc), hc)
+	result, err := searcher.Replay(
+		s, args.Seed, trials, neighbors, sc.SmallerIsBetter())
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return result, nil
+}
+
 // cleanUpExperimentSnapshots deletes all snapshots for terminal state experiments from
 // the database.
 func (m *Master) cleanUpExperimentSnapshots() {
@@ -0,0 +1,475 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/determined-ai/determined/master/internal/db"
+	expauth "github.com/determined-ai/determined/master/internal/experiment"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/ws"
+	"github.com/determined-ai/determined/proto/pkg/apiv1"
+	"github.com/determined-ai/determined/proto/pkg/experimentv1"
+)
+
+const (
+	// defaultSearcherReconnectTimeout is how long a custom searcher has to reconnect after its
+	// stream drops before its experiment is paused.
+	defaultSearcherReconnectTimeout = 5 * time.Minute
+	// defaultSearcherAckTimeout is how long a custom searcher has to acknowledge an event before
+	// it is considered hung and its experiment is paused.
+	defaultSearcherAckTimeout = 10 * time.Minute
+	// searcherStreamCheckInterval is how often a stream checks that its experiment is still
+	// active and that its searcher is keeping up with acknowledgements.
+	searcherStreamCheckInterval = 15 * time.Second
+)
+
+// searcherStreamConn is one connection of a custom searcher to the StreamSearcherEvents RPC,
+// either a gRPC stream or a WebSocket carrying the same messages.
+type searcherStreamConn interface {
+	Send(*apiv1.StreamSearcherEventsResponse) error
+	Recv() (*apiv1.StreamSearcherEventsRequest, error)
+}
+
+// searcherSession is what the master remembers about the custom searcher of an experiment
+// across its connections. The last event it acknowledged is kept with the experiment instead, so
+// that it survives master restarts.
+type searcherSession struct {
+	// gen identifies the latest stream of the searcher.
+	gen uint64
+	// cancel stops the connected stream, or is nil if the searcher is disconnected.
+	cancel context.CancelFunc
+	timer  *time.Timer
+}
+
+// searcherStreams tracks the custom searcher streams of every experiment, so a searcher that
+// reconnects takes over from its old stream and one that doesn't come back pauses its experiment.
+type searcherStreams struct {
+	mu       sync.Mutex
+	sessions map[int]*searcherSession
+}
+
+func newSearcherStreams() *searcherStreams {
+	return &searcherStreams{sessions: map[int]*searcherSession{}}
+}
+
+// attach registers a new stream for an experiment, taking over from any stream still connected,
+// and returns its generation.
+func (s *searcherStreams) attach(expID int, cancel context.CancelFunc) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[expID]
+	if !ok {
+		sess = &searcherSession{}
+		s.sessions[expID] = sess
+	}
+	// A searcher that restarted before its old connection timed out replaces it.
+	if sess.cancel != nil {
+		sess.cancel()
+	}
+	if sess.timer != nil {
+		sess.timer.Stop()
+		sess.timer = nil
+	}
+	sess.gen++
+	sess.cancel = cancel
+	return sess.gen
+}
+
+// detach unregisters a stream. If timeout is positive and no other stream attaches within it,
+// onDead is called.
+func (s *searcherStreams) detach(expID int, gen uint64, timeout time.Duration, onDead func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[expID]
+	if !ok || sess.gen != gen {
+		return
+	}
+	sess.cancel = nil
+	if timeout <= 0 {
+		return
+	}
+	sess.timer = time.AfterFunc(timeout, func() {
+		s.mu.Lock()
+		if sess.gen != gen || sess.cancel != nil {
+			s.mu.Unlock()
+			return
+		}
+		sess.timer = nil
+		s.mu.Unlock()
+		onDead()
+	})
+}
+
+// forget drops the session of an experiment that is no longer active.
+func (s *searcherStreams) forget(expID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sess, ok := s.sessions[expID]; ok {
+		if sess.timer != nil {
+			sess.timer.Stop()
+		}
+		delete(s.sessions, expID)
+	}
+}
+
+// searcherStream serves one connection of a custom searcher.
+type searcherStream struct {
+	expID      int
+	exp        expauth.Experiment
+	conn       searcherStreamConn
+	streams    *searcherStreams
+	gen        uint64
+	ackTimeout time.Duration
+
+	// sent is the ID of the last event sent and acked the ID of the last event acknowledged.
+	sent  int32
+	acked int32
+	// ackDeadline is when the searcher must have acknowledged another event, or zero if it has
+	// acknowledged every event sent.
+	ackDeadline time.Time
+	// paused is set once the stream paused the experiment.
+	paused bool
+	// inactive is set once the experiment is found to no longer be active.
+	inactive bool
+}
+
+// serveSearcherStream serves a StreamSearcherEvents stream. The first message from the searcher
+// subscribes it to an experiment, which authorize loads after checking that the user may run a
+// custom search for it. The master then sends events as they come in, and the searcher
+// acknowledges them and posts operations. A searcher that reconnects gets the events after the
+// last one it acknowledged, or after resume_from. The experiment is paused if the searcher stays
+// disconnected longer than the reconnect timeout or leaves an event unacknowledged longer than the
+// ack timeout.
+func (m *Master) serveSearcherStream(
+	ctx context.Context,
+	conn searcherStreamConn,
+	authorize func(ctx context.Context, expID int) (*model.Experiment, error),
+) error {
+	msg, err := conn.Recv()
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	sub := msg.GetSubscribe()
+	if sub == nil {
+		return status.Error(codes.InvalidArgument, "the first message must be a subscription")
+	}
+	expID := int(sub.ExperimentId)
+	exp, err := authorize(ctx, expID)
+	if err != nil {
+		return err
+	}
+
+	reconnectTimeout, ackTimeout := defaultSearcherReconnectTimeout, defaultSearcherAckTimeout
+	if sub.ReconnectTimeoutSeconds != nil {
+		reconnectTimeout = time.Duration(*sub.ReconnectTimeoutSeconds) * time.Second
+	}
+	if sub.AckTimeoutSeconds != nil {
+		ackTimeout = time.Duration(*sub.AckTimeoutSeconds) * time.Second
+	}
+
+	e, ok := expauth.ExperimentRegistry.Load(expID)
+	if !ok || !isActiveExperimentState(model.StateToProto(exp.State)) {
+		m.searcherStreams.forget(expID)
+		return conn.Send(searcherExperimentInactive(exp.State))
+	}
+
+	lastAck, err := e.LastAckedSearcherEvent()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	gen := m.searcherStreams.attach(expID, cancel)
+	s := &searcherStream{
+		expID:      expID,
+		exp:        e,
+		conn:       conn,
+		streams:    m.searcherStreams,
+		gen:        gen,
+		ackTimeout: ackTimeout,
+		sent:       lastAck,
+		acked:      lastAck,
+	}
+	if sub.ResumeFrom != nil {
+		s.sent, s.acked = *sub.ResumeFrom, *sub.ResumeFrom
+	}
+	defer func() {
+		if s.paused || s.inactive {
+			reconnectTimeout = 0
+		}
+		m.searcherStreams.detach(s.expID, s.gen, reconnectTimeout, func() {
+			pauseForDeadSearcher(s.expID, fmt.Sprintf(
+				"custom searcher did not reconnect within %s", reconnectTimeout))
+		})
+	}()
+	return s.run(ctx)
+}
+
+// run serves the stream until the searcher disconnects, another stream takes over or the
+// experiment stops being active.
+func (s *searcherStream) run(ctx context.Context) error {
+	w, err := s.exp.StreamSearcherEvents(s.sent)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := s.exp.UnwatchEvents(w.ID); err != nil {
+			log.WithError(err).Errorf("error unwatching searcher events")
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	inbox := make(chan *apiv1.StreamSearcherEventsRequest)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := s.conn.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case inbox <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(searcherStreamCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case evs := <-w.C:
+			if err := s.sendEvents(evs); err != nil {
+				return err
+			}
+		case msg := <-inbox:
+			if err := s.handle(msg); err != nil {
+				return err
+			}
+		case err := <-recvErr:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case <-ticker.C:
+			if done, err := s.check(); done || err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (s *searcherStream) handle(msg *apiv1.StreamSearcherEventsRequest) error {
+	switch m := msg.Message.(type) {
+	case *apiv1.StreamSearcherEventsRequest_Subscribe:
+		return status.Error(codes.InvalidArgument, "the stream is already subscribed")
+	case *apiv1.StreamSearcherEventsRequest_Ack:
+		if err := s.exp.AckSearcherEvents(m.Ack); err != nil {
+			return err
+		}
+		s.ack(m.Ack)
+	case *apiv1.StreamSearcherEventsRequest_Operations:
+		req := m.Operations
+		if req.TriggeredByEvent == nil {
+			return s.conn.Send(&apiv1.StreamSearcherEventsResponse{
+				Error: "operations must set triggered_by_event",
+			})
+		}
+		req.ExperimentId = int32(s.expID)
+		if err := s.exp.PerformSearcherOperations(req); err != nil {
+			return s.conn.Send(&apiv1.StreamSearcherEventsResponse{
+				Error: fmt.Sprintf("failed to post operations: %s", err),
+			})
+		}
+		// Operations are only ever sent in response to an event the searcher has processed, and
+		// performing them records that.
+		s.ack(req.TriggeredByEvent.Id)
+	}
+	return nil
+}
+
+func (s *searcherStream) ack(eventID int32) {
+	if eventID <= s.acked {
+		return
+	}
+	s.acked = eventID
+	switch {
+	case s.acked >= s.sent:
+		s.ackDeadline = time.Time{}
+	case s.ackTimeout > 0:
+		// A searcher working through a backlog of events is only hung if it stops making progress.
+		s.ackDeadline = time.Now().Add(s.ackTimeout)
+	}
+}
+
+// check ends the stream if the experiment is no longer active and pauses the experiment if the
+// searcher fell behind on acknowledgements.
+func (s *searcherStream) check() (done bool, err error) {
+	if _, ok := expauth.ExperimentRegistry.Load(s.expID); !ok {
+		s.inactive = true
+		s.streams.forget(s.expID)
+		exp, err := db.ExperimentByID(context.TODO(), s.expID)
+		if err != nil {
+			return true, err
+		}
+		return true, s.conn.Send(searcherExperimentInactive(exp.State))
+	}
+	if !s.ackDeadline.IsZero() && time.Now().After(s.ackDeadline) {
+		s.paused = true
+		pauseForDeadSearcher(s.expID, fmt.Sprintf(
+			"custom searcher did not acknowledge event %d within %s", s.sent, s.ackTimeout))
+		return true, nil
+	}
+	return false, nil
+}
+
+func (s *searcherStream) sendEvents(events []*experimentv1.SearcherEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if err := s.conn.Send(&apiv1.StreamSearcherEventsResponse{SearcherEvents: events}); err != nil {
+		return err
+	}
+	s.sent = events[len(events)-1].Id
+	if s.ackTimeout > 0 && s.ackDeadline.IsZero() {
+		s.ackDeadline = time.Now().Add(s.ackTimeout)
+	}
+	return nil
+}
+
+// searcherExperimentInactive tells a searcher that its experiment is no longer active the same way
+// GetSearcherEvents does.
+func searcherExperimentInactive(state model.State) *apiv1.StreamSearcherEventsResponse {
+	return &apiv1.StreamSearcherEventsResponse{
+		SearcherEvents: []*experimentv1.SearcherEvent{{
+			Id: -1,
+			Event: &experimentv1.SearcherEvent_ExperimentInactive{
+				ExperimentInactive: &experimentv1.ExperimentInactive{
+					ExperimentState: model.StateToProto(state),
+				},
+			},
+		}},
+	}
+}
+
+// searcherStreamRequestJSON and searcherStreamResponseJSON are the messages of the
+// StreamSearcherEvents RPC in their protobuf JSON form, as they are carried over a WebSocket.
+type (
+	searcherStreamRequestJSON struct {
+		msg *apiv1.StreamSearcherEventsRequest
+	}
+	searcherStreamResponseJSON struct {
+		msg *apiv1.StreamSearcherEventsResponse
+	}
+)
+
+func (r *searcherStreamRequestJSON) UnmarshalJSON(data []byte) error {
+	r.msg = &apiv1.StreamSearcherEventsRequest{}
+	return protojson.Unmarshal(data, r.msg)
+}
+
+func (r *searcherStreamResponseJSON) MarshalJSON() ([]byte, error) {
+	return protojson.Marshal(r.msg)
+}
+
+// searcherStreamSocket carries the StreamSearcherEvents RPC over a WebSocket.
+type searcherStreamSocket struct {
+	socket *ws.WebSocket[searcherStreamRequestJSON, searcherStreamResponseJSON]
+}
+
+func (s searcherStreamSocket) Send(msg *apiv1.StreamSearcherEventsResponse) error {
+	select {
+	case s.socket.Outbox <- searcherStreamResponseJSON{msg: msg}:
+		return nil
+	case <-s.socket.Done:
+		return s.closed()
+	}
+}
+
+func (s searcherStreamSocket) Recv() (*apiv1.StreamSearcherEventsRequest, error) {
+	msg, ok := <-s.socket.Inbox
+	if !ok {
+		return nil, s.closed()
+	}
+	return msg.msg, nil
+}
+
+func (s searcherStreamSocket) closed() error {
+	if err := s.socket.Error(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+//	@Summary	Serve the StreamSearcherEvents RPC over a WebSocket.
+//	@Tags		Experiments
+//	@ID			get-searcher-stream
+//	@Success	101
+//	@Router		/experiments/searcher-stream [get]
+//
+// getSearcherStream serves the StreamSearcherEvents RPC over a WebSocket, for searchers that don't
+// make gRPC calls. Every message is a StreamSearcherEventsRequest or StreamSearcherEventsResponse
+// in its protobuf JSON form. An error that ends the stream is sent as a last response.
+func (m *Master) getSearcherStream(c echo.Context) error {
+	conn, err := ws.UpgradeEchoConnection(c)
+	if err != nil {
+		return err
+	}
+	socket, err := ws.Wrap[searcherStreamRequestJSON, searcherStreamResponseJSON](
+		"searcher-stream", conn)
+	if err != nil {
+		return errors.Wrap(err, "failed to accept websocket connection")
+	}
+	defer func() {
+		if err := socket.Close(); err != nil {
+			log.WithError(err).Debug("closing searcher stream")
+		}
+	}()
+
+	stream := searcherStreamSocket{socket: socket}
+	err = m.serveSearcherStream(c.Request().Context(), stream,
+		func(ctx context.Context, expID int) (*model.Experiment, error) {
+			exp, _, err := echoGetExperimentAndCheckCanDoActions(ctx, c, m, expID,
+				expauth.AuthZProvider.Get().CanRunCustomSearch)
+			return exp, err
+		})
+	if err != nil {
+		return stream.Send(&apiv1.StreamSearcherEventsResponse{Error: err.Error()})
+	}
+	return nil
+}
+
+// pauseForDeadSearcher pauses an experiment whose custom searcher appears to be dead, so its
+// trials don't keep running without anything to decide what comes next.
+func pauseForDeadSearcher(expID int, reason string) {
+	e, ok := expauth.ExperimentRegistry.Load(expID)
+	if !ok {
+		return
+	}
+	syslog := log.WithField("experiment-id", expID)
+	syslog.Warnf("pausing experiment: %s", reason)
+	if err := e.PauseExperiment(); err != nil {
+		syslog.WithError(err).Warn("failed to pause experiment of dead custom searcher")
+	}
+}
@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	expauth "github.com/determined-ai/determined/master/internal/experiment"
+	"github.com/determined-ai/determined/master/pkg/searcher"
+	"github.com/determined-ai/determined/proto/pkg/apiv1"
+	"github.com/determined-ai/determined/proto/pkg/experimentv1"
+)
+
+func TestSearcherStreamsTakeOver(t *testing.T) {
+	s := newSearcherStreams()
+
+	canceled := false
+	oldGen := s.attach(1, func() { canceled = true })
+	newGen := s.attach(1, func() {})
+	require.True(t, canceled)
+	require.Greater(t, newGen, oldGen)
+
+	// The old stream no longer counts once it has been replaced.
+	s.detach(1, oldGen, time.Millisecond, func() { t.Fatal("replaced stream paused the experiment") })
+	time.Sleep(10 * time.Millisecond)
+	s.detach(1, newGen, 0, func() {})
+}
+
+func TestSearcherStreamsDeadSearcher(t *testing.T) {
+	s := newSearcherStreams()
+
+	dead := make(chan struct{})
+	gen := s.attach(1, func() {})
+	s.detach(1, gen, time.Millisecond, func() { close(dead) })
+	select {
+	case <-dead:
+	case <-time.After(time.Second):
+		t.Fatal("searcher that did not reconnect was not considered dead")
+	}
+
+	// Reconnecting in time keeps the experiment going.
+	gen = s.attach(2, func() {})
+	s.detach(2, gen, 50*time.Millisecond, func() { t.Error("searcher reconnected but was considered dead") })
+	s.attach(2, func() {})
+	time.Sleep(100 * time.Millisecond)
+}
+
+type fakeSearcherExperiment struct {
+	expauth.Experiment
+	events  chan []*experimentv1.SearcherEvent
+	watches int
+	acked   int32
+}
+
+func (e *fakeSearcherExperiment) StreamSearcherEvents(int32) (*searcher.EventsWatcher, error) {
+	e.watches++
+	return &searcher.EventsWatcher{ID: uuid.New(), C: e.events}, nil
+}
+
+func (e *fakeSearcherExperiment) UnwatchEvents(uuid.UUID) error {
+	return nil
+}
+
+func (e *fakeSearcherExperiment) AckSearcherEvents(eventID int32) error {
+	e.acked = eventID
+	return nil
+}
+
+type fakeSearcherConn struct {
+	in  chan *apiv1.StreamSearcherEventsRequest
+	out chan *apiv1.StreamSearcherEventsResponse
+}
+
+func (c *fakeSearcherConn) Send(msg *apiv1.StreamSearcherEventsResponse) error {
+	c.out <- msg
+	return nil
+}
+
+func (c *fakeSearcherConn) Recv() (*apiv1.StreamSearcherEventsRequest, error) {
+	msg, ok := <-c.in
+	if !ok {
+		return nil, io.EOF
+	}
+	return msg, nil
+}
+
+func TestSearcherStreamWatchesOnce(t *testing.T) {
+	exp := &fakeSearcherExperiment{events: make(chan []*experimentv1.SearcherEvent, 1)}
+	conn := &fakeSearcherConn{
+		in:  make(chan *apiv1.StreamSearcherEventsRequest),
+		out: make(chan *apiv1.StreamSearcherEventsResponse, 1),
+	}
+	s := &searcherStream{expID: 1, exp: exp, conn: conn, ackTimeout: time.Minute}
+	done := make(chan error)
+	go func() { done <- s.run(context.Background()) }()
+
+	receive := func() []int32 {
+		var ids []int32
+		for _, ev := range (<-conn.out).SearcherEvents {
+			ids = append(ids, ev.Id)
+		}
+		return ids
+	}
+	exp.events <- []*experimentv1.SearcherEvent{{Id: 1}, {Id: 2}}
+	require.Equal(t, []int32{1, 2}, receive())
+	conn.in <- &apiv1.StreamSearcherEventsRequest{
+		Message: &apiv1.StreamSearcherEventsRequest_Ack{Ack: 2},
+	}
+	exp.events <- []*experimentv1.SearcherEvent{{Id: 3}}
+	require.Equal(t, []int32{3}, receive())
+
+	// Closing the stream ends it without an error.
+	close(conn.in)
+	require.NoError(t, <-done)
+	require.Equal(t, 1, exp.watches)
+	require.Equal(t, int32(2), exp.acked)
+	require.Equal(t, int32(2), s.acked)
+	require.Equal(t, int32(3), s.sent)
+}
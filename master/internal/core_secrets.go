@@ -0,0 +1,216 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/determined-ai/determined/master/internal/api"
+	"github.com/determined-ai/determined/master/internal/cluster"
+	detContext "github.com/determined-ai/determined/master/internal/context"
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/secrets"
+	workspaceauth "github.com/determined-ai/determined/master/internal/workspace"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// secretRequest is the body of POST /secrets and PUT /secrets/:secret_id. Only Value is used to
+// update a secret.
+type secretRequest struct {
+	Name  string        `json:"name"`
+	Scope secrets.Scope `json:"scope"`
+	// WorkspaceID is required for workspace secrets.
+	WorkspaceID *int   `json:"workspace_id"`
+	Value       string `json:"value"`
+}
+
+// canEditSecret checks that the current user can manage secrets of a scope. Only the owner of a
+// user secret can manage it, workspace secrets need the permission to manage the secrets of the
+// workspace and cluster secrets need the permission to update the master config.
+func (m *Master) canEditSecret(
+	ctx context.Context, curUser model.User, scope secrets.Scope,
+	userID *model.UserID, workspaceID *int,
+) error {
+	switch scope {
+	case secrets.ScopeUser:
+		if userID == nil || *userID != curUser.ID {
+			return echo.NewHTTPError(http.StatusForbidden, "user secrets can only be managed by their owner")
+		}
+		return nil
+	case secrets.ScopeWorkspace:
+		if workspaceID == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "workspace_id is required for workspace secrets")
+		}
+		w, err := (&apiServer{m: m}).GetWorkspaceByID(ctx, int32(*workspaceID), curUser, false)
+		if err != nil {
+			return api.NotFoundErrs("workspace", fmt.Sprint(*workspaceID), false)
+		}
+		if err := workspaceauth.AuthZProvider.Get().CanManageWorkspaceSecrets(
+			ctx, curUser, w); err != nil {
+			return echo.NewHTTPError(http.StatusForbidden, err.Error())
+		}
+		return nil
+	case secrets.ScopeCluster:
+		permErr, err := cluster.AuthZProvider.Get().CanUpdateMasterConfig(ctx, &curUser)
+		if err != nil {
+			return err
+		}
+		if permErr != nil {
+			return echo.NewHTTPError(http.StatusForbidden, permErr.Error())
+		}
+		return nil
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("scope must be one of %s, %s or %s, got %q",
+				secrets.ScopeUser, secrets.ScopeWorkspace, secrets.ScopeCluster, scope))
+	}
+}
+
+// echoGetSecret returns a secret the current user can manage.
+func (m *Master) echoGetSecret(ctx context.Context, c echo.Context) (*secrets.Secret, error) {
+	args := struct {
+		SecretID int `path:"secret_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	curUser := c.(*detContext.DetContext).MustGetUser()
+	notFound := api.NotFoundErrs("secret", fmt.Sprint(args.SecretID), false)
+	s, err := secrets.ByID(ctx, args.SecretID)
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		return nil, notFound
+	case err != nil:
+		return nil, err
+	}
+	if err := m.canEditSecret(ctx, curUser, s.Scope, s.UserID, s.WorkspaceID); err != nil {
+		if s.Scope == secrets.ScopeUser {
+			return nil, notFound
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+//	@Summary	Get the secrets the current user can reference, without their values.
+//	@Tags		Secrets
+//	@ID			get-secrets
+//	@Produce	json
+//	@Success	200	{}	[]secrets.Secret
+//	@Router		/secrets [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getSecrets(c echo.Context) (interface{}, error) {
+	ctx := c.Request().Context()
+	curUser := c.(*detContext.DetContext).MustGetUser()
+
+	workspaces, err := workspaceauth.AllWorkspaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int32, 0, len(workspaces))
+	for _, w := range workspaces {
+		ids = append(ids, int32(w.ID))
+	}
+	visible, err := workspaceauth.AuthZProvider.Get().FilterWorkspaceIDs(ctx, curUser, ids)
+	if err != nil {
+		return nil, err
+	}
+	workspaceIDs := make([]int, 0, len(visible))
+	for _, id := range visible {
+		workspaceIDs = append(workspaceIDs, int(id))
+	}
+	return secrets.List(ctx, curUser.ID, workspaceIDs)
+}
+
+//	@Summary	Create a secret that tasks can reference by name.
+//	@Tags		Secrets
+//	@ID			post-secret
+//	@Accept		json
+//	@Produce	json
+//	@Success	200	{}	secrets.Secret
+//	@Router		/secrets [post]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) postSecret(c echo.Context) (interface{}, error) {
+	ctx := c.Request().Context()
+	curUser := c.(*detContext.DetContext).MustGetUser()
+	var req secretRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("decoding body: %s", err))
+	}
+
+	s := secrets.Secret{Name: req.Name, Scope: req.Scope, CreatedBy: &curUser.ID}
+	switch req.Scope {
+	case secrets.ScopeUser:
+		s.UserID = &curUser.ID
+	case secrets.ScopeWorkspace:
+		s.WorkspaceID = req.WorkspaceID
+	}
+	if err := m.canEditSecret(ctx, curUser, s.Scope, s.UserID, s.WorkspaceID); err != nil {
+		return nil, err
+	}
+	if err := secrets.ValidateName(req.Name); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	switch err := secrets.Create(ctx, &s, req.Value); {
+	case errors.Is(err, secrets.ErrNotEnabled):
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, db.ErrDuplicateRecord):
+		return nil, echo.NewHTTPError(http.StatusConflict,
+			fmt.Sprintf("a %s secret named %q already exists", s.Scope, s.Name))
+	case err != nil:
+		return nil, err
+	}
+	return s, nil
+}
+
+//	@Summary	Replace the value of a secret.
+//	@Tags		Secrets
+//	@ID			put-secret
+//	@Accept		json
+//	@Produce	json
+//	@Param		secret_id	path	int	true	"Secret ID"
+//	@Success	200			{}		secrets.Secret
+//	@Router		/secrets/{secret_id} [put]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) putSecret(c echo.Context) (interface{}, error) {
+	ctx := c.Request().Context()
+	s, err := m.echoGetSecret(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	var req secretRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("decoding body: %s", err))
+	}
+	switch err := secrets.UpdateValue(ctx, s.ID, req.Value); {
+	case errors.Is(err, secrets.ErrNotEnabled):
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case err != nil:
+		return nil, err
+	}
+	return secrets.ByID(ctx, s.ID)
+}
+
+//	@Summary	Delete a secret. Tasks that reference it fail to start afterwards.
+//	@Tags		Secrets
+//	@ID			delete-secret
+//	@Param		secret_id	path	int	true	"Secret ID"
+//	@Success	200			{}		string	""
+//	@Router		/secrets/{secret_id} [delete]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) deleteSecret(c echo.Context) (interface{}, error) {
+	ctx := c.Request().Context()
+	s, err := m.echoGetSecret(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return "", secrets.Delete(ctx, s.ID)
+}
@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	sshlib "golang.org/x/crypto/ssh"
+
+	"github.com/determined-ai/determined/master/internal/api"
+	"github.com/determined-ai/determined/master/internal/authz"
+	"github.com/determined-ai/determined/master/internal/command"
+	detContext "github.com/determined-ai/determined/master/internal/context"
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/proxy"
+	"github.com/determined-ai/determined/master/internal/sshgateway"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/proto/pkg/apiv1"
+)
+
+// sshGatewayInfo is the response of GET /ssh-gateway.
+type sshGatewayInfo struct {
+	Port int `json:"port"`
+	// HostKey is the host key of the gateway in the authorized_keys format, for known_hosts.
+	HostKey string `json:"host_key"`
+	// UserCAKey is the key of the authority that signs user certificates.
+	UserCAKey string `json:"user_ca_key"`
+}
+
+// sshKeyRequest is the body of POST /ssh-gateway/keys and POST /ssh-gateway/certificates.
+type sshKeyRequest struct {
+	// Name is optional and defaults to the comment of the key.
+	Name string `json:"name"`
+	// PublicKey is in the authorized_keys format, such as the contents of ~/.ssh/id_ed25519.pub.
+	PublicKey string `json:"public_key"`
+}
+
+// sshCertificate is the response of POST /ssh-gateway/certificates.
+type sshCertificate struct {
+	// Certificate is in the authorized_keys format, for ~/.ssh/id_ed25519-cert.pub.
+	Certificate string    `json:"certificate"`
+	ValidBefore time.Time `json:"valid_before"`
+}
+
+// resolveSSHGatewayTarget returns the sshd of a shell that curUser can see. Users who can see a
+// shell can already get its private key, so they may log in to it through the gateway as well.
+func resolveSSHGatewayTarget(
+	ctx context.Context, curUser model.User, shellID string,
+) (*sshgateway.Target, error) {
+	notFound := fmt.Errorf("shell %s not found", shellID)
+	resp, err := command.DefaultCmdService.GetShell(&apiv1.GetShellRequest{ShellId: shellID})
+	if err != nil {
+		return nil, notFound
+	}
+	shell := resp.Shell
+	if err := command.AuthZProvider.Get().CanGetNSC(
+		ctx, curUser, model.AccessScopeID(shell.WorkspaceId)); err != nil {
+		return nil, authz.SubIfUnauthorized(err, notFound)
+	}
+	service := proxy.DefaultProxy.GetService(shellID)
+	if service == nil {
+		return nil, fmt.Errorf("shell %s is not running", shellID)
+	}
+	signer, err := sshlib.ParsePrivateKey([]byte(shell.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("parsing key of shell %s: %w", shellID, err)
+	}
+	username := shell.AgentUserGroup.GetFields()["user"].GetStringValue()
+	if username == "" {
+		username = "root"
+	}
+	return &sshgateway.Target{
+		TaskID: model.TaskID(shellID),
+		Addr:   service.URL.Host,
+		User:   username,
+		Signer: signer,
+	}, nil
+}
+
+// echoSSHGateway returns the gateway, or an error if it is not enabled.
+func (m *Master) echoSSHGateway() (*sshgateway.Gateway, error) {
+	if m.sshGateway == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound,
+			"the ssh gateway is not enabled, set ssh_gateway.enabled in the master config")
+	}
+	return m.sshGateway, nil
+}
+
+//	@Summary	Get the port and keys of the SSH gateway.
+//	@Tags		SSH Gateway
+//	@ID			get-ssh-gateway
+//	@Produce	json
+//	@Success	200	{}	sshGatewayInfo
+//	@Router		/ssh-gateway [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getSSHGateway(c echo.Context) (interface{}, error) {
+	g, err := m.echoSSHGateway()
+	if err != nil {
+		return nil, err
+	}
+	return sshGatewayInfo{
+		Port:      m.config.SSHGateway.Port,
+		HostKey:   strings.TrimSpace(string(sshlib.MarshalAuthorizedKey(g.HostKey()))),
+		UserCAKey: strings.TrimSpace(string(sshlib.MarshalAuthorizedKey(g.CAPublicKey()))),
+	}, nil
+}
+
+//	@Summary	Get the public keys the current user logs in to the SSH gateway with.
+//	@Tags		SSH Gateway
+//	@ID			get-ssh-keys
+//	@Produce	json
+//	@Success	200	{}	[]sshgateway.Key
+//	@Router		/ssh-gateway/keys [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getSSHKeys(c echo.Context) (interface{}, error) {
+	curUser := c.(*detContext.DetContext).MustGetUser()
+	return sshgateway.ListKeys(c.Request().Context(), curUser.ID)
+}
+
+//	@Summary	Add a public key the current user logs in to the SSH gateway with.
+//	@Tags		SSH Gateway
+//	@ID			post-ssh-key
+//	@Accept		json
+//	@Produce	json
+//	@Success	200	{}	sshgateway.Key
+//	@Router		/ssh-gateway/keys [post]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) postSSHKey(c echo.Context) (interface{}, error) {
+	curUser := c.(*detContext.DetContext).MustGetUser()
+	var req sshKeyRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("decoding body: %s", err))
+	}
+	if _, _, err := sshgateway.ParsePublicKey(req.PublicKey); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	switch key, err := sshgateway.AddKey(c.Request().Context(), curUser.ID, req.Name, req.PublicKey); {
+	case errors.Is(err, db.ErrDuplicateRecord):
+		return nil, echo.NewHTTPError(http.StatusConflict, "the key has already been added")
+	case err != nil:
+		return nil, err
+	default:
+		return key, nil
+	}
+}
+
+//	@Summary	Remove a public key of the current user from the SSH gateway.
+//	@Tags		SSH Gateway
+//	@ID			delete-ssh-key
+//	@Param		key_id	path	int	true	"Key ID"
+//	@Success	200		{}		string	""
+//	@Router		/ssh-gateway/keys/{key_id} [delete]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) deleteSSHKey(c echo.Context) (interface{}, error) {
+	args := struct {
+		KeyID int `path:"key_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	curUser := c.(*detContext.DetContext).MustGetUser()
+	switch err := sshgateway.DeleteKey(c.Request().Context(), curUser.ID, args.KeyID); {
+	case errors.Is(err, db.ErrNotFound):
+		return nil, api.NotFoundErrs("ssh key", fmt.Sprint(args.KeyID), false)
+	case err != nil:
+		return nil, err
+	default:
+		return "", nil
+	}
+}
+
+//	@Summary	Sign a short-lived certificate that logs in to the SSH gateway as the current user.
+//	@Tags		SSH Gateway
+//	@ID			post-ssh-certificate
+//	@Accept		json
+//	@Produce	json
+//	@Success	200	{}	sshCertificate
+//	@Router		/ssh-gateway/certificates [post]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) postSSHCertificate(c echo.Context) (interface{}, error) {
+	g, err := m.echoSSHGateway()
+	if err != nil {
+		return nil, err
+	}
+	curUser := c.(*detContext.DetContext).MustGetUser()
+	var req sshKeyRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("decoding body: %s", err))
+	}
+	if _, _, err := sshgateway.ParsePublicKey(req.PublicKey); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	cert, err := g.SignUserKey(curUser, req.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return sshCertificate{
+		Certificate: strings.TrimSpace(string(sshlib.MarshalAuthorizedKey(cert))),
+		ValidBefore: time.Unix(int64(cert.ValidBefore), 0).UTC(),
+	}, nil
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"context"
+	"errors"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/determined-ai/determined/master/internal/api"
+	detContext "github.com/determined-ai/determined/master/internal/context"
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/templates"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// checkCanViewTemplate returns a not found error unless the template exists and the user can see
+// it.
+func checkCanViewTemplate(ctx context.Context, user model.User, name string) error {
+	notFound := api.NotFoundErrs("template", name, false)
+	tpl, err := templates.TemplateByName(ctx, name)
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		return notFound
+	case err != nil:
+		return err
+	}
+	permErr, err := templates.AuthZProvider.Get().CanViewTemplate(
+		ctx, &user, model.AccessScopeID(tpl.WorkspaceID))
+	switch {
+	case err != nil:
+		return err
+	case permErr != nil:
+		return notFound
+	}
+	return nil
+}
+
+//	@Summary	List the versions of a template, newest first.
+//	@Tags		Templates
+//	@ID			get-template-versions
+//	@Produce	json
+//	@Param		template_name	path	string	true	"Template name"
+//	@Success	200				{}		[]model.TemplateVersion
+//	@Router		/templates/{template_name}/versions [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getTemplateVersions(c echo.Context) (interface{}, error) {
+	args := struct {
+		TemplateName string `path:"template_name"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	user := c.(*detContext.DetContext).MustGetUser()
+	if err := checkCanViewTemplate(ctx, user, args.TemplateName); err != nil {
+		return nil, err
+	}
+	versions, err := templates.TemplateVersions(ctx, args.TemplateName)
+	if err != nil {
+		return nil, err
+	}
+	visible := []model.TemplateVersion{}
+	for _, v := range versions {
+		ok, err := canViewTemplateVersion(ctx, user, v)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			visible = append(visible, v)
+		}
+	}
+	return visible, nil
+}
+
+//	@Summary	Get a single version of a template.
+//	@Tags		Templates
+//	@ID			get-template-version
+//	@Produce	json
+//	@Param		template_name	path	string	true	"Template name"
+//	@Param		version			path	int		true	"Template version"
+//	@Success	200				{}		model.TemplateVersion
+//	@Router		/templates/{template_name}/versions/{version} [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getTemplateVersion(c echo.Context) (interface{}, error) {
+	args := struct {
+		TemplateName string `path:"template_name"`
+		Version      int    `path:"version"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	user := c.(*detContext.DetContext).MustGetUser()
+	if err := checkCanViewTemplate(ctx, user, args.TemplateName); err != nil {
+		return nil, err
+	}
+	notFound := api.NotFoundErrs("template version", args.TemplateName+"@"+c.Param("version"), false)
+	v, err := templates.TemplateVersionByNumber(ctx, args.TemplateName, args.Version)
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		return nil, notFound
+	case err != nil:
+		return nil, err
+	}
+	switch ok, err := canViewTemplateVersion(ctx, user, *v); {
+	case err != nil:
+		return nil, err
+	case !ok:
+		return nil, notFound
+	}
+	return v, nil
+}
+
+// canViewTemplateVersion reports whether the user can see the workspace a version was written in.
+func canViewTemplateVersion(
+	ctx context.Context, user model.User, v model.TemplateVersion,
+) (bool, error) {
+	permErr, err := templates.AuthZProvider.Get().CanViewTemplate(
+		ctx, &user, model.AccessScopeID(v.WorkspaceID))
+	if err != nil {
+		return false, err
+	}
+	return permErr == nil, nil
+}
@@ -0,0 +1,376 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/uptrace/bun"
+
+	"github.com/determined-ai/determined/master/internal/api"
+	"github.com/determined-ai/determined/master/internal/config"
+	detContext "github.com/determined-ai/determined/master/internal/context"
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/experiment"
+	modelauth "github.com/determined-ai/determined/master/internal/model"
+	"github.com/determined-ai/determined/master/internal/project"
+	"github.com/determined-ai/determined/master/internal/user"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/proto/pkg/apiv1"
+	"github.com/determined-ai/determined/proto/pkg/modelv1"
+	"github.com/determined-ai/determined/proto/pkg/projectv1"
+	"github.com/determined-ai/determined/proto/pkg/rbacv1"
+)
+
+// trashedItem is a single entry in the trash listing.
+type trashedItem struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	TrashedAt time.Time `json:"trashed_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+// trashListing is the response of GET /trash.
+type trashListing struct {
+	Experiments []trashedItem `json:"experiments"`
+	Projects    []trashedItem `json:"projects"`
+	Models      []trashedItem `json:"models"`
+}
+
+func (m *Master) withPurgeAt(items []trashedItem) []trashedItem {
+	retention := time.Duration(m.config.Trash.RetentionPeriod)
+	for i := range items {
+		items[i].PurgeAt = items[i].TrashedAt.Add(retention)
+	}
+	return items
+}
+
+//	@Summary	List the experiments, projects and models in the trash.
+//	@Tags		Trash
+//	@ID			get-trash
+//	@Produce	json
+//	@Success	200	{}	trashListing
+//	@Router		/trash [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getTrash(c echo.Context) (interface{}, error) {
+	ctx := c.Request().Context()
+	curUser := c.(*detContext.DetContext).MustGetUser()
+	a := &apiServer{m: m}
+	listing := trashListing{
+		Experiments: []trashedItem{},
+		Projects:    []trashedItem{},
+		Models:      []trashedItem{},
+	}
+
+	expQuery := db.Bun().NewSelect().
+		ModelTableExpr("experiments AS e").
+		ColumnExpr("e.id").
+		ColumnExpr("e.config->>'name' AS name").
+		ColumnExpr("e.trashed_at").
+		Join("JOIN projects p ON e.project_id = p.id").
+		Where("e.trashed_at IS NOT NULL").
+		Order("e.trashed_at DESC")
+	expQuery, err := experiment.AuthZProvider.Get().FilterExperimentsQuery(ctx, curUser, nil,
+		expQuery,
+		[]rbacv1.PermissionType{rbacv1.PermissionType_PERMISSION_TYPE_VIEW_EXPERIMENT_METADATA})
+	if err != nil {
+		return nil, err
+	}
+	if err := expQuery.Scan(ctx, &listing.Experiments); err != nil {
+		return nil, fmt.Errorf("listing trashed experiments: %w", err)
+	}
+
+	var projects []trashedItem
+	if err := db.Bun().NewSelect().
+		Table("projects").
+		Column("id", "name", "trashed_at").
+		Where("trashed_at IS NOT NULL").
+		Order("trashed_at DESC").
+		Scan(ctx, &projects); err != nil {
+		return nil, fmt.Errorf("listing trashed projects: %w", err)
+	}
+	for _, p := range projects {
+		if _, err := a.GetProjectByID(ctx, int32(p.ID), curUser); err != nil {
+			continue
+		}
+		listing.Projects = append(listing.Projects, p)
+	}
+
+	var models []trashedItem
+	if err := db.Bun().NewSelect().
+		Table("models").
+		Column("id", "name", "trashed_at").
+		Where("trashed_at IS NOT NULL").
+		Order("trashed_at DESC").
+		Scan(ctx, &models); err != nil {
+		return nil, fmt.Errorf("listing trashed models: %w", err)
+	}
+	for _, mod := range models {
+		modelPb := &modelv1.Model{}
+		if err := m.db.QueryProto("get_trashed_model_by_id", modelPb, mod.ID); err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				// Restored or purged since it was listed.
+				continue
+			}
+			return nil, err
+		}
+		if err := modelauth.AuthZProvider.Get().CanGetModel(ctx, curUser, modelPb,
+			modelPb.WorkspaceId); err != nil {
+			continue
+		}
+		listing.Models = append(listing.Models, mod)
+	}
+
+	listing.Experiments = m.withPurgeAt(listing.Experiments)
+	listing.Projects = m.withPurgeAt(listing.Projects)
+	listing.Models = m.withPurgeAt(listing.Models)
+	return listing, nil
+}
+
+//	@Summary	Restore experiments from the trash.
+//	@Tags		Trash
+//	@ID			post-trash-experiments-restore
+//	@Accept		json
+//	@Produce	json
+//	@Success	200	{}	apiv1.DeleteExperimentsResponse
+//	@Router		/trash/experiments/restore [post]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) postRestoreExperiments(c echo.Context) (interface{}, error) {
+	var req struct {
+		ExperimentIDs []int32 `json:"experiment_ids"`
+	}
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("decoding body: %s", err))
+	}
+	if len(req.ExperimentIDs) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "experiment_ids must not be empty")
+	}
+
+	results, err := experiment.RestoreExperiments(c.Request().Context(),
+		c.(*detContext.DetContext).MustGetUser(), req.ExperimentIDs)
+	if err != nil {
+		return nil, err
+	}
+	return &apiv1.DeleteExperimentsResponse{Results: experiment.ToAPIResults(results)}, nil
+}
+
+//	@Summary	Restore a project from the trash.
+//	@Tags		Trash
+//	@ID			post-trash-project-restore
+//	@Produce	json
+//	@Param		project_id	path	int	true	"Project ID"
+//	@Success	200			{}		projectv1.Project
+//	@Router		/trash/projects/{project_id}/restore [post]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) postRestoreProject(c echo.Context) (interface{}, error) {
+	args := struct {
+		ProjectID int `path:"project_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	curUser := c.(*detContext.DetContext).MustGetUser()
+
+	p, err := m.echoGetProject(ctx, curUser, args.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := project.AuthZProvider.Get().CanDeleteProject(ctx, curUser, p); err != nil {
+		return nil, echo.NewHTTPError(http.StatusForbidden, err.Error())
+	}
+	if err := restoreFromTrash(ctx, "projects", args.ProjectID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, api.NotFoundErrs("trashed project", fmt.Sprint(args.ProjectID), false)
+		}
+		return nil, err
+	}
+	return m.echoGetProject(ctx, curUser, args.ProjectID)
+}
+
+//	@Summary	Restore a model from the trash.
+//	@Tags		Trash
+//	@ID			post-trash-model-restore
+//	@Produce	json
+//	@Param		model_id	path	int	true	"Model ID"
+//	@Success	200			{}		modelv1.Model
+//	@Router		/trash/models/{model_id}/restore [post]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) postRestoreModel(c echo.Context) (interface{}, error) {
+	args := struct {
+		ModelID int `path:"model_id"`
+	}{}
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	curUser := c.(*detContext.DetContext).MustGetUser()
+	a := &apiServer{m: m}
+
+	notFound := api.NotFoundErrs("trashed model", fmt.Sprint(args.ModelID), false)
+	modelPb := &modelv1.Model{}
+	if err := m.db.QueryProto("get_trashed_model_by_id", modelPb, args.ModelID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, notFound
+		}
+		return nil, err
+	}
+	authz := modelauth.AuthZProvider.Get()
+	if err := authz.CanGetModel(ctx, curUser, modelPb, modelPb.WorkspaceId); err != nil {
+		return nil, notFound
+	}
+	if err := authz.CanDeleteModel(ctx, curUser, modelPb, modelPb.WorkspaceId); err != nil {
+		return nil, echo.NewHTTPError(http.StatusForbidden, err.Error())
+	}
+	if err := restoreFromTrash(ctx, "models", args.ModelID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, notFound
+		}
+		return nil, err
+	}
+	return a.ModelFromIdentifier(fmt.Sprint(args.ModelID))
+}
+
+// restoreFromTrash clears trashed_at on a single row, returning db.ErrNotFound if the row is
+// missing or not in the trash.
+func restoreFromTrash(ctx context.Context, table string, id int) error {
+	res, err := db.Bun().NewUpdate().
+		Table(table).
+		Set("trashed_at = NULL").
+		Where("id = ?", id).
+		Where("trashed_at IS NOT NULL").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("restoring %d from %s: %w", id, table, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return db.ErrNotFound
+	}
+	return nil
+}
+
+// periodicallyEmptyTrash permanently deletes everything that has been in the trash for longer
+// than the configured retention period.
+func (m *Master) periodicallyEmptyTrash(ctx context.Context) {
+	t := time.NewTicker(10 * time.Minute)
+	defer t.Stop()
+	retention := time.Duration(m.config.Trash.RetentionPeriod)
+	if retention <= 0 {
+		// Only a disabled trash may have no retention period; keep what is left in it for the default.
+		retention = config.DefaultTrashRetentionPeriod
+	}
+	for {
+		cutoff := time.Now().Add(-retention)
+		if err := m.emptyTrash(ctx, cutoff); err != nil {
+			log.WithError(err).Error("failed to empty the trash")
+		}
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (m *Master) emptyTrash(ctx context.Context, cutoff time.Time) error {
+	a := &apiServer{m: m}
+
+	var exps []*model.Experiment
+	if _, err := db.Bun().NewUpdate().
+		ModelTableExpr("experiments as e").
+		Set("state = ?", model.DeletingState).
+		Where("trashed_at < ?", cutoff).
+		Where("state IN (?)", bun.In(model.StatesToStrings(model.TerminalStates))).
+		Returning(`id, state, config, start_time, end_time, archived,
+			   owner_id, notes, job_id, '' as username, project_id`).
+		Model(&exps).
+		Exec(ctx); err != nil {
+		return fmt.Errorf("claiming trashed experiments: %w", err)
+	}
+	for _, exp := range exps {
+		owner, err := user.ByID(ctx, *exp.OwnerID)
+		if err != nil {
+			log.WithError(err).Errorf("purging trashed experiment %d", exp.ID)
+			continue
+		}
+		ownerUser := owner.ToUser()
+		if err := a.deleteExperiments([]*model.Experiment{exp}, &ownerUser); err != nil {
+			log.WithError(err).Errorf("purging trashed experiment %d", exp.ID)
+			if _, err := db.Bun().NewUpdate().Table("experiments").
+				Set("state = ?", model.DeleteFailedState).
+				Where("id = ?", exp.ID).
+				Exec(ctx); err != nil {
+				log.WithError(err).Errorf("transitioning experiment %d to %s", exp.ID,
+					model.DeleteFailedState)
+			}
+			continue
+		}
+		log.Infof("purged trashed experiment %d", exp.ID)
+	}
+
+	var projects []struct {
+		ID     int32
+		UserID model.UserID
+	}
+	if err := db.Bun().NewSelect().
+		Table("projects").
+		Column("id", "user_id").
+		Where("trashed_at < ?", cutoff).
+		Where("state NOT IN ('DELETING', 'DELETE_FAILED')").
+		Where(`NOT EXISTS (
+			SELECT 1 FROM experiments e WHERE e.project_id = projects.id AND e.state NOT IN (?)
+		)`, bun.In(model.StatesToStrings(model.TerminalStates))).
+		Scan(ctx, &projects); err != nil {
+		return fmt.Errorf("listing trashed projects: %w", err)
+	}
+	for _, p := range projects {
+		holder := &projectv1.Project{}
+		if err := m.db.QueryProto("deletable_project", holder, p.ID); err != nil || holder.Id == 0 {
+			log.WithError(err).Errorf("purging trashed project %d", p.ID)
+			continue
+		}
+		expList, err := m.db.ProjectExperiments(int(p.ID))
+		if err != nil {
+			log.WithError(err).Errorf("purging trashed project %d", p.ID)
+			continue
+		}
+		owner, err := user.ByID(ctx, p.UserID)
+		if err != nil {
+			log.WithError(err).Errorf("purging trashed project %d", p.ID)
+			continue
+		}
+		ownerUser := owner.ToUser()
+		if err := a.deleteProject(ctx, p.ID, expList, &ownerUser); err == nil {
+			log.Infof("purged trashed project %d", p.ID)
+		}
+	}
+
+	var modelNames []string
+	if err := db.Bun().NewSelect().
+		Table("models").
+		Column("name").
+		Where("trashed_at < ?", cutoff).
+		Scan(ctx, &modelNames); err != nil {
+		return fmt.Errorf("listing trashed models: %w", err)
+	}
+	for _, name := range modelNames {
+		holder := &modelv1.Model{}
+		if err := m.db.QueryProto("delete_model", holder, name); err != nil {
+			log.WithError(err).Errorf("purging trashed model %q", name)
+			continue
+		}
+		log.Infof("purged trashed model %q", name)
+	}
+	return nil
+}
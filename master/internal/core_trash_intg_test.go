@@ -0,0 +1,176 @@
+//go:build integration
+// +build integration
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/proto/pkg/apiv1"
+)
+
+// completedTestExp creates an experiment in a terminal state, so that it can be deleted.
+func completedTestExp(t *testing.T, api *apiServer, curUser model.User, projectID int) int32 {
+	exp := createTestExpWithProjectID(t, api, curUser, projectID)
+	_, err := db.Bun().NewUpdate().Table("experiments").
+		Set("state = ?", model.CompletedState).
+		Where("id = ?", exp.ID).Exec(context.Background())
+	require.NoError(t, err)
+	return int32(exp.ID)
+}
+
+func requireProjectExperiments(
+	ctx context.Context, t *testing.T, api *apiServer, projectID int, expected ...int32,
+) {
+	resp, err := api.GetExperiments(ctx, &apiv1.GetExperimentsRequest{ProjectId: int32(projectID)})
+	require.NoError(t, err)
+	var ids []int32
+	for _, e := range resp.Experiments {
+		ids = append(ids, e.Id)
+	}
+	require.ElementsMatch(t, expected, ids)
+}
+
+func TestTrashAndRestore(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+	api.m.config.Trash.Enabled = true
+	_, projectID := createProjectAndWorkspace(ctx, t, api)
+
+	expID := completedTestExp(t, api, curUser, projectID)
+	modelResp, err := api.PostModel(ctx, &apiv1.PostModelRequest{Name: uuid.NewString()})
+	require.NoError(t, err)
+	modelID := fmt.Sprint(modelResp.Model.Id)
+
+	_, err = api.DeleteExperiment(ctx, &apiv1.DeleteExperimentRequest{ExperimentId: expID})
+	require.NoError(t, err)
+	_, err = api.DeleteModel(ctx, &apiv1.DeleteModelRequest{ModelName: modelResp.Model.Name})
+	require.NoError(t, err)
+
+	// Trashed items are hidden but listed in the trash.
+	requireProjectExperiments(ctx, t, api, projectID)
+	_, err = api.ModelFromIdentifier(modelID)
+	require.Error(t, err)
+	res, err := api.m.getTrash(savedViewEchoContext(curUser, http.MethodGet, "", nil))
+	require.NoError(t, err)
+	listing := res.(trashListing)
+	require.Contains(t, trashedIDs(listing.Experiments), int(expID))
+	require.Contains(t, trashedIDs(listing.Models), int(modelResp.Model.Id))
+
+	// Restoring brings them back.
+	res, err = api.m.postRestoreExperiments(savedViewEchoContext(curUser, http.MethodPost,
+		fmt.Sprintf(`{"experiment_ids": [%d]}`, expID), nil))
+	require.NoError(t, err)
+	results := res.(*apiv1.DeleteExperimentsResponse).Results
+	require.Len(t, results, 1)
+	require.Empty(t, results[0].Error)
+	requireProjectExperiments(ctx, t, api, projectID, expID)
+
+	_, err = api.m.postRestoreModel(savedViewEchoContext(curUser, http.MethodPost, "",
+		map[string]string{"model_id": modelID}))
+	require.NoError(t, err)
+	restored, err := api.ModelFromIdentifier(modelID)
+	require.NoError(t, err)
+	require.Equal(t, modelResp.Model.Name, restored.Name)
+
+	// Restoring something that is not in the trash is rejected.
+	_, err = api.m.postRestoreModel(savedViewEchoContext(curUser, http.MethodPost, "",
+		map[string]string{"model_id": modelID}))
+	require.Error(t, err)
+}
+
+func TestTrashRejectsNonDeletableExperiments(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+	api.m.config.Trash.Enabled = true
+	_, projectID := createProjectAndWorkspace(ctx, t, api)
+
+	// Experiments that are still running cannot be deleted, so they cannot be trashed either.
+	exp := createTestExpWithProjectID(t, api, curUser, projectID)
+	_, err := api.DeleteExperiment(ctx, &apiv1.DeleteExperimentRequest{ExperimentId: int32(exp.ID)})
+	require.Error(t, err)
+
+	resp, err := api.DeleteExperiments(ctx, &apiv1.DeleteExperimentsRequest{
+		ExperimentIds: []int32{int32(exp.ID)},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 1)
+	require.NotEmpty(t, resp.Results[0].Error)
+
+	var trashed bool
+	require.NoError(t, db.Bun().NewSelect().Table("experiments").
+		ColumnExpr("trashed_at IS NOT NULL").
+		Where("id = ?", exp.ID).
+		Scan(ctx, &trashed))
+	require.False(t, trashed)
+	requireProjectExperiments(ctx, t, api, projectID, int32(exp.ID))
+}
+
+func TestEmptyTrashPurgesOnlyExpiredItems(t *testing.T) {
+	api, curUser, ctx := setupAPITest(t, nil)
+	api.m.config.Trash.Enabled = true
+	_, projectID := createProjectAndWorkspace(ctx, t, api)
+
+	expired := completedTestExp(t, api, curUser, projectID)
+	retained := completedTestExp(t, api, curUser, projectID)
+	_, err := api.DeleteExperiments(ctx, &apiv1.DeleteExperimentsRequest{
+		ExperimentIds: []int32{expired, retained},
+	})
+	require.NoError(t, err)
+
+	var modelIDs []int32
+	for i := 0; i < 2; i++ {
+		resp, err := api.PostModel(ctx, &apiv1.PostModelRequest{Name: uuid.NewString()})
+		require.NoError(t, err)
+		_, err = api.DeleteModel(ctx, &apiv1.DeleteModelRequest{ModelName: resp.Model.Name})
+		require.NoError(t, err)
+		modelIDs = append(modelIDs, resp.Model.Id)
+	}
+
+	// Backdate the first experiment and model past the cutoff.
+	_, err = db.Bun().NewUpdate().Table("experiments").
+		Set("trashed_at = now() - interval '2 hours'").
+		Where("id = ?", expired).Exec(ctx)
+	require.NoError(t, err)
+	_, err = db.Bun().NewUpdate().Table("models").
+		Set("trashed_at = now() - interval '2 hours'").
+		Where("id = ?", modelIDs[0]).Exec(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, api.m.emptyTrash(ctx, time.Now().Add(-time.Hour)))
+
+	expExists, err := db.Bun().NewSelect().Table("experiments").Where("id = ?", expired).Exists(ctx)
+	require.NoError(t, err)
+	require.False(t, expExists)
+	expExists, err = db.Bun().NewSelect().Table("experiments").
+		Where("id = ?", retained).
+		Where("trashed_at IS NOT NULL").
+		Exists(ctx)
+	require.NoError(t, err)
+	require.True(t, expExists)
+
+	modelExists, err := db.Bun().NewSelect().Table("models").Where("id = ?", modelIDs[0]).Exists(ctx)
+	require.NoError(t, err)
+	require.False(t, modelExists)
+	modelExists, err = db.Bun().NewSelect().Table("models").
+		Where("id = ?", modelIDs[1]).
+		Where("trashed_at IS NOT NULL").
+		Exists(ctx)
+	require.NoError(t, err)
+	require.True(t, modelExists)
+}
+
+func trashedIDs(items []trashedItem) []int {
+	var ids []int
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+
+	"github.com/determined-ai/determined/master/internal/api"
+	"github.com/determined-ai/determined/master/internal/authz"
+	"github.com/determined-ai/determined/master/internal/cluster"
+	detContext "github.com/determined-ai/determined/master/internal/context"
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/egress"
+	workspaceauth "github.com/determined-ai/determined/master/internal/workspace"
+	"github.com/determined-ai/determined/master/pkg/check"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// workspaceEgressPolicy is the body of GET and PUT /workspaces/:workspace_id/egress-policy. A null
+// policy means the egress policy of the master, if any, applies to the tasks of the workspace.
+type workspaceEgressPolicy struct {
+	EgressPolicy *model.EgressPolicy `json:"egress_policy"`
+}
+
+//	@Summary	Get the policy that restricts the network traffic of the tasks of a workspace.
+//	@Tags		Workspaces
+//	@ID			get-workspace-egress-policy
+//	@Produce	json
+//	@Param		workspace_id	path	int	true	"Workspace ID"
+//	@Success	200				{}		workspaceEgressPolicy
+//	@Router		/workspaces/{workspace_id}/egress-policy [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getWorkspaceEgressPolicy(c echo.Context) (interface{}, error) {
+	var args workspaceVolumeArgs
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	curUser := c.(*detContext.DetContext).MustGetUser()
+	notFound := api.NotFoundErrs("workspace", fmt.Sprint(args.WorkspaceID), false)
+	if err := workspaceauth.AuthZProvider.Get().CanGetWorkspaceID(
+		ctx, curUser, int32(args.WorkspaceID)); err != nil {
+		return nil, authz.SubIfUnauthorized(err, notFound)
+	}
+
+	policy, err := egress.Get(ctx, args.WorkspaceID)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, notFound
+	} else if err != nil {
+		return nil, err
+	}
+	return workspaceEgressPolicy{EgressPolicy: policy}, nil
+}
+
+//	@Summary	Set the policy that restricts the network traffic of the tasks of a workspace.
+//	@Tags		Workspaces
+//	@ID			put-workspace-egress-policy
+//	@Accept		json
+//	@Produce	json
+//	@Param		workspace_id	path	int	true	"Workspace ID"
+//	@Success	200				{}		workspaceEgressPolicy
+//	@Router		/workspaces/{workspace_id}/egress-policy [put]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) putWorkspaceEgressPolicy(c echo.Context) (interface{}, error) {
+	var args workspaceVolumeArgs
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	curUser := c.(*detContext.DetContext).MustGetUser()
+	// Like image policies, egress policies are set by cluster admins, since they are what keeps
+	// the data of a workspace from leaving the cluster.
+	permErr, err := cluster.AuthZProvider.Get().CanUpdateMasterConfig(ctx, &curUser)
+	if err != nil {
+		return nil, err
+	}
+	if permErr != nil {
+		return nil, echo.NewHTTPError(http.StatusForbidden, permErr.Error())
+	}
+
+	var req workspaceEgressPolicy
+	dec := json.NewDecoder(c.Request().Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("decoding body: %s", err))
+	}
+	if err := check.Validate(req.EgressPolicy); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	err = egress.Set(ctx, args.WorkspaceID, req.EgressPolicy)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, api.NotFoundErrs("workspace", fmt.Sprint(args.WorkspaceID), false)
+	} else if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+
+	"github.com/determined-ai/determined/master/internal/api"
+	"github.com/determined-ai/determined/master/internal/authz"
+	"github.com/determined-ai/determined/master/internal/cluster"
+	detContext "github.com/determined-ai/determined/master/internal/context"
+	"github.com/determined-ai/determined/master/internal/db"
+	workspaceauth "github.com/determined-ai/determined/master/internal/workspace"
+)
+
+// workspaceGPULimit is the body of GET and PUT /workspaces/:workspace_id/gpu-limit. A null limit
+// means the workspace is not limited.
+type workspaceGPULimit struct {
+	GPULimit *int `json:"gpu_limit"`
+}
+
+//	@Summary	Get how many GPUs the tasks of a workspace can request at once.
+//	@Tags		Workspaces
+//	@ID			get-workspace-gpu-limit
+//	@Produce	json
+//	@Param		workspace_id	path	int	true	"Workspace ID"
+//	@Success	200				{}		workspaceGPULimit
+//	@Router		/workspaces/{workspace_id}/gpu-limit [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getWorkspaceGPULimit(c echo.Context) (interface{}, error) {
+	var args workspaceVolumeArgs
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	curUser := c.(*detContext.DetContext).MustGetUser()
+	notFound := api.NotFoundErrs("workspace", fmt.Sprint(args.WorkspaceID), false)
+	if err := workspaceauth.AuthZProvider.Get().CanGetWorkspaceID(
+		ctx, curUser, int32(args.WorkspaceID)); err != nil {
+		return nil, authz.SubIfUnauthorized(err, notFound)
+	}
+
+	limit, err := workspaceauth.GPULimit(ctx, args.WorkspaceID)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, notFound
+	} else if err != nil {
+		return nil, err
+	}
+	return workspaceGPULimit{GPULimit: limit}, nil
+}
+
+//	@Summary	Set how many GPUs the tasks of a workspace can request at once.
+//	@Tags		Workspaces
+//	@ID			put-workspace-gpu-limit
+//	@Accept		json
+//	@Produce	json
+//	@Param		workspace_id	path	int	true	"Workspace ID"
+//	@Success	200				{}		workspaceGPULimit
+//	@Router		/workspaces/{workspace_id}/gpu-limit [put]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) putWorkspaceGPULimit(c echo.Context) (interface{}, error) {
+	var args workspaceVolumeArgs
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	curUser := c.(*detContext.DetContext).MustGetUser()
+	// Limits are set by cluster admins, not by the admins of the workspace they limit.
+	permErr, err := cluster.AuthZProvider.Get().CanUpdateMasterConfig(ctx, &curUser)
+	if err != nil {
+		return nil, err
+	}
+	if permErr != nil {
+		return nil, echo.NewHTTPError(http.StatusForbidden, permErr.Error())
+	}
+
+	var req workspaceGPULimit
+	dec := json.NewDecoder(c.Request().Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("decoding body: %s", err))
+	}
+	if req.GPULimit != nil && *req.GPULimit < 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "gpu_limit must be >= 0")
+	}
+
+	err = workspaceauth.SetGPULimit(ctx, args.WorkspaceID, req.GPULimit)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, api.NotFoundErrs("workspace", fmt.Sprint(args.WorkspaceID), false)
+	} else if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
@@ -0,0 +1,209 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/docker/docker/api/types/registry"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	k8sV1 "k8s.io/api/core/v1"
+
+	"github.com/determined-ai/determined/master/internal/api"
+	"github.com/determined-ai/determined/master/internal/authz"
+	"github.com/determined-ai/determined/master/internal/cluster"
+	detContext "github.com/determined-ai/determined/master/internal/context"
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/imagepolicy"
+	workspaceauth "github.com/determined-ai/determined/master/internal/workspace"
+	"github.com/determined-ai/determined/master/pkg/device"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/proto/pkg/apiv1"
+	"github.com/determined-ai/determined/proto/pkg/devicev1"
+)
+
+// workspaceImagePolicy is the body of GET and PUT /workspaces/:workspace_id/image-policy. A null
+// policy means the tasks of the workspace can run any image.
+type workspaceImagePolicy struct {
+	ImagePolicy *model.ImagePolicy `json:"image_policy"`
+}
+
+//	@Summary	Get the policy that restricts the images the tasks of a workspace can run.
+//	@Tags		Workspaces
+//	@ID			get-workspace-image-policy
+//	@Produce	json
+//	@Param		workspace_id	path	int	true	"Workspace ID"
+//	@Success	200				{}		workspaceImagePolicy
+//	@Router		/workspaces/{workspace_id}/image-policy [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getWorkspaceImagePolicy(c echo.Context) (interface{}, error) {
+	var args workspaceVolumeArgs
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	curUser := c.(*detContext.DetContext).MustGetUser()
+	notFound := api.NotFoundErrs("workspace", fmt.Sprint(args.WorkspaceID), false)
+	if err := workspaceauth.AuthZProvider.Get().CanGetWorkspaceID(
+		ctx, curUser, int32(args.WorkspaceID)); err != nil {
+		return nil, authz.SubIfUnauthorized(err, notFound)
+	}
+
+	policy, err := imagepolicy.Get(ctx, args.WorkspaceID)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, notFound
+	} else if err != nil {
+		return nil, err
+	}
+	return workspaceImagePolicy{ImagePolicy: policy}, nil
+}
+
+//	@Summary	Set the policy that restricts the images the tasks of a workspace can run.
+//	@Tags		Workspaces
+//	@ID			put-workspace-image-policy
+//	@Accept		json
+//	@Produce	json
+//	@Param		workspace_id	path	int	true	"Workspace ID"
+//	@Success	200				{}		workspaceImagePolicy
+//	@Router		/workspaces/{workspace_id}/image-policy [put]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) putWorkspaceImagePolicy(c echo.Context) (interface{}, error) {
+	var args workspaceVolumeArgs
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	curUser := c.(*detContext.DetContext).MustGetUser()
+	// Image policies are set by cluster admins, not by the admins of the workspace they restrict.
+	permErr, err := cluster.AuthZProvider.Get().CanUpdateMasterConfig(ctx, &curUser)
+	if err != nil {
+		return nil, err
+	}
+	if permErr != nil {
+		return nil, echo.NewHTTPError(http.StatusForbidden, permErr.Error())
+	}
+
+	var req workspaceImagePolicy
+	dec := json.NewDecoder(c.Request().Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("decoding body: %s", err))
+	}
+	if req.ImagePolicy != nil {
+		if err := imagepolicy.Validate(*req.ImagePolicy); err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	err = imagepolicy.Set(ctx, args.WorkspaceID, req.ImagePolicy)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, api.NotFoundErrs("workspace", fmt.Sprint(args.WorkspaceID), false)
+	} else if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// imageDeviceTypes returns the device types whose images a task may run in a resource pool. Tasks
+// without slots run the CPU image, and other tasks the image of the pool's slot type. The slot type
+// of a pool isn't known until it has agents, in which case its tasks may run any image.
+func (m *Master) imageDeviceTypes(poolName string, slots int) ([]device.Type, error) {
+	if slots == 0 {
+		return []device.Type{device.CPU}, nil
+	}
+	resp, err := m.rm.GetResourcePools(&apiv1.GetResourcePoolsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("getting the slot type of resource pool %s: %w", poolName, err)
+	}
+	for _, pool := range resp.ResourcePools {
+		if pool.Name != poolName {
+			continue
+		}
+		switch pool.SlotType {
+		case devicev1.Type_TYPE_CPU:
+			return []device.Type{device.CPU}, nil
+		case devicev1.Type_TYPE_CUDA:
+			return []device.Type{device.CUDA}, nil
+		case devicev1.Type_TYPE_ROCM:
+			return []device.Type{device.ROCM}, nil
+		}
+	}
+	return []device.Type{device.CPU, device.CUDA, device.ROCM}, nil
+}
+
+// enforceImagePolicy checks the images a task may run, which are those of deviceTypes, against the
+// image policy of its workspace and the image admission webhook, and replaces them with the images
+// to run, which are pinned to digests if the policy requires it. The images of other device types
+// are left alone, so that a policy doesn't need to allow default images the task can't run. The
+// images of the containers and init containers the task's pod spec adds are checked and replaced
+// too, since Kubernetes runs them alongside the task's own container.
+func enforceImagePolicy(
+	ctx context.Context,
+	workspaceID int,
+	owner *model.User,
+	taskType model.TaskType,
+	auth *registry.AuthConfig,
+	deviceTypes []device.Type,
+	images map[device.Type]*string,
+	podSpec *k8sV1.Pod,
+) error {
+	req := imagepolicy.Request{
+		WorkspaceID:  workspaceID,
+		TaskType:     taskType,
+		RegistryAuth: auth,
+	}
+	if owner != nil {
+		req.Username = owner.Username
+	}
+	var checked []*string
+	for _, t := range deviceTypes {
+		if image := images[t]; image != nil && *image != "" {
+			checked = append(checked, image)
+		}
+	}
+	checked = append(checked, podSpecImages(podSpec)...)
+	for _, image := range checked {
+		req.Images = append(req.Images, *image)
+	}
+
+	resolved, err := imagepolicy.Enforce(ctx, req)
+	switch {
+	case errors.Is(err, imagepolicy.ErrDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case err != nil:
+		return status.Errorf(codes.FailedPrecondition, "checking image policy: %s", err)
+	}
+	for _, image := range checked {
+		if resolved[*image] != "" {
+			*image = resolved[*image]
+		}
+	}
+	return nil
+}
+
+// podSpecImages returns the images of the containers and init containers of a pod spec. The
+// Determined container is left out, since it only contributes its settings to the container that
+// runs the task's image.
+func podSpecImages(podSpec *k8sV1.Pod) []*string {
+	if podSpec == nil {
+		return nil
+	}
+	var images []*string
+	for i, c := range podSpec.Spec.Containers {
+		if c.Name != model.DeterminedK8ContainerName && c.Image != "" {
+			images = append(images, &podSpec.Spec.Containers[i].Image)
+		}
+	}
+	for i, c := range podSpec.Spec.InitContainers {
+		if c.Image != "" {
+			images = append(images, &podSpec.Spec.InitContainers[i].Image)
+		}
+	}
+	return images
+}
@@ -0,0 +1,40 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	k8sV1 "k8s.io/api/core/v1"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func TestPodSpecImages(t *testing.T) {
+	require.Empty(t, podSpecImages(nil))
+
+	podSpec := &k8sV1.Pod{Spec: k8sV1.PodSpec{
+		Containers: []k8sV1.Container{
+			{Name: model.DeterminedK8ContainerName, Image: "ignored:latest"},
+			{Name: "sidecar", Image: "sidecar:latest"},
+			{Name: "no-image"},
+		},
+		InitContainers: []k8sV1.Container{
+			{Name: "init", Image: "init:latest"},
+		},
+	}}
+	images := podSpecImages(podSpec)
+	var got []string
+	for _, image := range images {
+		got = append(got, *image)
+	}
+	require.Equal(t, []string{"sidecar:latest", "init:latest"}, got)
+
+	// The images are returned in place, so that they can be pinned to the digests the policy
+	// resolves them to.
+	for _, image := range images {
+		*image += "@sha256:abc"
+	}
+	require.Equal(t, "ignored:latest", podSpec.Spec.Containers[0].Image)
+	require.Equal(t, "sidecar:latest@sha256:abc", podSpec.Spec.Containers[1].Image)
+	require.Equal(t, "init:latest@sha256:abc", podSpec.Spec.InitContainers[0].Image)
+}
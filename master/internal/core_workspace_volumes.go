@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+
+	"github.com/determined-ai/determined/master/internal/api"
+	"github.com/determined-ai/determined/master/internal/authz"
+	detContext "github.com/determined-ai/determined/master/internal/context"
+	"github.com/determined-ai/determined/master/internal/db"
+	workspaceauth "github.com/determined-ai/determined/master/internal/workspace"
+)
+
+type workspaceVolumeArgs struct {
+	WorkspaceID int    `path:"workspace_id"`
+	VolumeName  string `path:"volume_name"`
+}
+
+// workspaceVolumeRequest is the body of POST /workspaces/:workspace_id/volumes.
+type workspaceVolumeRequest struct {
+	Name         string  `json:"name"`
+	Size         string  `json:"size"`
+	StorageClass *string `json:"storage_class"`
+	MountPath    string  `json:"mount_path"`
+	ReadOnly     bool    `json:"read_only"`
+}
+
+// canEditWorkspaceVolumes checks that the current user can manage the shared volumes of a
+// workspace.
+func (m *Master) canEditWorkspaceVolumes(c echo.Context, workspaceID int) error {
+	ctx := c.Request().Context()
+	curUser := c.(*detContext.DetContext).MustGetUser()
+	w, err := (&apiServer{m: m}).GetWorkspaceByID(ctx, int32(workspaceID), curUser, false)
+	if err != nil {
+		return api.NotFoundErrs("workspace", fmt.Sprint(workspaceID), false)
+	}
+	if err := workspaceauth.AuthZProvider.Get().CanManageWorkspaceVolumes(ctx, curUser, w); err != nil {
+		return authz.SubIfUnauthorized(err, echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf(
+			"current user %q doesn't have permissions to manage the volumes of workspace %d",
+			curUser.Username, workspaceID)))
+	}
+	return nil
+}
+
+//	@Summary	Get the shared volumes that are mounted into every task of a workspace.
+//	@Tags		Workspaces
+//	@ID			get-workspace-volumes
+//	@Produce	json
+//	@Param		workspace_id	path	int	true	"Workspace ID"
+//	@Success	200				{}		[]db.WorkspaceVolume
+//	@Router		/workspaces/{workspace_id}/volumes [get]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) getWorkspaceVolumes(c echo.Context) (interface{}, error) {
+	var args workspaceVolumeArgs
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	ctx := c.Request().Context()
+	curUser := c.(*detContext.DetContext).MustGetUser()
+	if err := workspaceauth.AuthZProvider.Get().CanGetWorkspaceID(
+		ctx, curUser, int32(args.WorkspaceID)); err != nil {
+		return nil, authz.SubIfUnauthorized(err,
+			api.NotFoundErrs("workspace", fmt.Sprint(args.WorkspaceID), false))
+	}
+	return db.WorkspaceVolumes(ctx, args.WorkspaceID)
+}
+
+//	@Summary	Declare a shared volume that is mounted into every task of a workspace.
+//	@Tags		Workspaces
+//	@ID			post-workspace-volume
+//	@Accept		json
+//	@Produce	json
+//	@Param		workspace_id	path	int	true	"Workspace ID"
+//	@Success	200				{}		db.WorkspaceVolume
+//	@Router		/workspaces/{workspace_id}/volumes [post]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) postWorkspaceVolume(c echo.Context) (interface{}, error) {
+	var args workspaceVolumeArgs
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	if err := m.canEditWorkspaceVolumes(c, args.WorkspaceID); err != nil {
+		return nil, err
+	}
+
+	var req workspaceVolumeRequest
+	dec := json.NewDecoder(c.Request().Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("decoding body: %s", err))
+	}
+	v := db.WorkspaceVolume{
+		WorkspaceID:  args.WorkspaceID,
+		Name:         req.Name,
+		Size:         req.Size,
+		StorageClass: req.StorageClass,
+		MountPath:    req.MountPath,
+		ReadOnly:     req.ReadOnly,
+	}
+	if err := v.Validate(); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	switch err := db.AddWorkspaceVolume(c.Request().Context(), &v); {
+	case errors.Is(err, db.ErrDuplicateRecord):
+		return nil, echo.NewHTTPError(http.StatusConflict, fmt.Sprintf(
+			"workspace %d already has a volume named %q or mounted at %q",
+			args.WorkspaceID, v.Name, v.MountPath))
+	case err != nil:
+		return nil, err
+	}
+	return v, nil
+}
+
+//	@Summary	Stop mounting a shared volume into new tasks of a workspace and delete its claims once unused.
+//	@Tags		Workspaces
+//	@ID			delete-workspace-volume
+//	@Param		workspace_id	path	int		true	"Workspace ID"
+//	@Param		volume_name		path	string	true	"Volume name"
+//	@Success	200				{}		string	""
+//	@Router		/workspaces/{workspace_id}/volumes/{volume_name} [delete]
+//
+// Read why this line exists on the comment on getAggregatedResourceAllocation in core.go.
+func (m *Master) deleteWorkspaceVolume(c echo.Context) (interface{}, error) {
+	var args workspaceVolumeArgs
+	if err := api.BindArgs(&args, c); err != nil {
+		return nil, err
+	}
+	if err := m.canEditWorkspaceVolumes(c, args.WorkspaceID); err != nil {
+		return nil, err
+	}
+
+	err := db.DeleteWorkspaceVolume(c.Request().Context(), args.WorkspaceID, args.VolumeName)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, api.NotFoundErrs("volume", args.VolumeName, false)
+	}
+	return "", err
+}
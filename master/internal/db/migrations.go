@@ -7,6 +7,7 @@ import (
 
 	"github.com/go-pg/migrations/v8"
 	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
 	"github.com/jackc/pgconn"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -33,7 +34,7 @@ func makeGoPgOpts(dbURL string) (*pg.Options, error) {
 	return opts, nil
 }
 
-func tablesExist(tx *pg.Tx, tableNames []string) (map[string]bool, error) {
+func tablesExist(tx orm.DB, tableNames []string) (map[string]bool, error) {
 	existingTables := []string{}
 	result := map[string]bool{}
 	for _, tn := range tableNames {
@@ -160,20 +161,10 @@ func (db *PgDB) Migrate(migrationURL string, actions []string) error {
 
 	log.Infof("running DB migrations from %s; this might take a while...", migrationURL)
 
-	re := regexp.MustCompile(`file://(.+)`)
-	match := re.FindStringSubmatch(migrationURL)
-	if len(match) != 2 {
-		return fmt.Errorf("failed to parse migrationsURL: %s", migrationURL)
-	}
-
-	collection := migrations.NewCollection()
-	collection.DisableSQLAutodiscover(true)
-	if err = collection.DiscoverSQLMigrations(match[1]); err != nil {
+	collection, err := discoverMigrations(migrationURL)
+	if err != nil {
 		return err
 	}
-	if len(collection.Migrations()) == 0 {
-		return errors.New("failed to discover any migrations")
-	}
 
 	oldVersion, newVersion, err := collection.Run(pgConn, actions...)
 	if err != nil {
@@ -189,3 +180,58 @@ func (db *PgDB) Migrate(migrationURL string, actions []string) error {
 	log.Info("DB migrations completed")
 	return nil
 }
+
+// MigrationStatus returns the version the database is migrated to and the latest version of the
+// migrations in the specified directory URL, without applying any of them.
+func (db *PgDB) MigrationStatus(migrationURL string) (current, latest int64, err error) {
+	collection, err := discoverMigrations(migrationURL)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, m := range collection.Migrations() {
+		if m.Version > latest {
+			latest = m.Version
+		}
+	}
+
+	pgOpts, err := makeGoPgOpts(db.url)
+	if err != nil {
+		return 0, 0, err
+	}
+	pgConn := pg.Connect(pgOpts)
+	defer func() {
+		if errd := pgConn.Close(); errd != nil {
+			log.Errorf("error closing pg connection: %s", errd)
+		}
+	}()
+
+	exist, err := tablesExist(pgConn, []string{"gopg_migrations"})
+	if err != nil {
+		return 0, 0, err
+	}
+	if !exist["gopg_migrations"] {
+		return 0, latest, nil
+	}
+	if current, err = migrations.Version(pgConn); err != nil {
+		return 0, 0, errors.Wrap(err, "error reading migration version")
+	}
+	return current, latest, nil
+}
+
+func discoverMigrations(migrationURL string) (*migrations.Collection, error) {
+	re := regexp.MustCompile(`file://(.+)`)
+	match := re.FindStringSubmatch(migrationURL)
+	if len(match) != 2 {
+		return nil, fmt.Errorf("failed to parse migrationsURL: %s", migrationURL)
+	}
+
+	collection := migrations.NewCollection()
+	collection.DisableSQLAutodiscover(true)
+	if err := collection.DiscoverSQLMigrations(match[1]); err != nil {
+		return nil, err
+	}
+	if len(collection.Migrations()) == 0 {
+		return nil, errors.New("failed to discover any migrations")
+	}
+	return collection, nil
+}
@@ -42,7 +42,7 @@ func (db *PgDB) CheckpointByUUIDs(ckptUUIDs []uuid.UUID) ([]model.Checkpoint, er
 func GetModelIDsAssociatedWithCheckpoint(ctx context.Context, ckptUUID uuid.UUID) ([]int32, error) {
 	var modelIDs []int32
 	if err := Bun().NewRaw(`
-	SELECT DISTINCT(model_id) as ID FROM model_versions m INNER JOIN checkpoints_view c
+	SELECT DISTINCT(model_id) as ID FROM registered_checkpoints m INNER JOIN checkpoints_view c
 	ON m.checkpoint_uuid = c.uuid WHERE c.uuid = ?`,
 		ckptUUID.String()).Scan(ctx, &modelIDs); err != nil {
 		return nil, fmt.Errorf("getting model ids associated with checkpoint uuid: %w", err)
@@ -59,7 +59,7 @@ func (db *PgDB) GetRegisteredCheckpoints(checkpoints []uuid.UUID) (map[uuid.UUID
 	}
 
 	if err := db.queryRows(`
-	SELECT DISTINCT(mv.checkpoint_uuid) as ID FROM model_versions AS mv
+	SELECT DISTINCT(mv.checkpoint_uuid) as ID FROM registered_checkpoints AS mv
 	WHERE mv.checkpoint_uuid IN (SELECT UNNEST($1::uuid[]));
 `, &checkpointIDRows, checkpoints); err != nil {
 		return nil, fmt.Errorf(
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// StaleExperiment is a non-terminal experiment that nothing is left running for.
+type StaleExperiment struct {
+	ID    int         `bun:"id" json:"id"`
+	State model.State `bun:"state" json:"state"`
+}
+
+// OpenAllocation is an allocation without an end time.
+type OpenAllocation struct {
+	AllocationID model.AllocationID `bun:"allocation_id" json:"allocation_id"`
+	TaskID       model.TaskID       `bun:"task_id" json:"task_id"`
+	StartTime    *time.Time         `bun:"start_time" json:"start_time"`
+	// TaskEnded is set if the allocation's task has already ended.
+	TaskEnded bool `bun:"task_ended" json:"task_ended"`
+}
+
+// ClusterHeartbeat returns the last time a running master recorded a heartbeat, or nil if no
+// master has yet.
+func ClusterHeartbeat(ctx context.Context) (*time.Time, error) {
+	var heartbeat *time.Time
+	err := Bun().NewSelect().Table("cluster_id").Column("cluster_heartbeat").Scan(ctx, &heartbeat)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading cluster heartbeat: %w", err)
+	}
+	return heartbeat, nil
+}
+
+// StaleExperiments returns the managed experiments which are stuck in a non-terminal state with
+// no allocation left open for any of their trials: stopping or deleting experiments, and active
+// experiments whose trials have all finished.
+func StaleExperiments(ctx context.Context) ([]StaleExperiment, error) {
+	var exps []StaleExperiment
+	err := Bun().NewRaw(`
+SELECT e.id, e.state
+FROM experiments e
+WHERE NOT e.unmanaged
+  AND (e.state IN (?) OR (e.state = ? AND EXISTS (
+	SELECT 1 FROM trials t WHERE t.experiment_id = e.id
+  ) AND NOT EXISTS (
+	SELECT 1 FROM trials t WHERE t.experiment_id = e.id AND t.state NOT IN (?)
+  )))
+  AND NOT EXISTS (
+	SELECT 1
+	FROM trials t
+	JOIN trial_id_task_id tt ON tt.trial_id = t.id
+	JOIN allocations a ON a.task_id = tt.task_id
+	WHERE t.experiment_id = e.id AND a.end_time IS NULL
+  )
+ORDER BY e.id`,
+		bun.In(append(model.StatesToStrings(model.StoppingStates), string(model.DeletingState))),
+		model.ActiveState,
+		bun.In(model.StatesToStrings(model.TerminalStates)),
+	).Scan(ctx, &exps)
+	if err != nil {
+		return nil, fmt.Errorf("querying stale experiments: %w", err)
+	}
+	return exps, nil
+}
+
+// OpenAllocations returns every allocation without an end time, oldest first.
+func OpenAllocations(ctx context.Context) ([]OpenAllocation, error) {
+	var allocs []OpenAllocation
+	err := Bun().NewRaw(`
+SELECT a.allocation_id, a.task_id, a.start_time, t.end_time IS NOT NULL AS task_ended
+FROM allocations a
+JOIN tasks t ON t.task_id = a.task_id
+WHERE a.end_time IS NULL
+ORDER BY a.start_time NULLS FIRST`).Scan(ctx, &allocs)
+	if err != nil {
+		return nil, fmt.Errorf("querying open allocations: %w", err)
+	}
+	return allocs, nil
+}
@@ -0,0 +1,83 @@
+//go:build integration
+// +build integration
+
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/pkg/etc"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/ptrs"
+)
+
+func TestStaleExperimentsAndOpenAllocations(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, etc.SetRootPath(RootFromDB))
+	db := MustResolveTestPostgres(t)
+	MustMigrateTestPostgres(t, db, MigrationsFromDB)
+
+	user := RequireMockUser(t, db)
+	exp := RequireMockExperimentParams(t, db, user, MockExperimentParams{
+		State: ptrs.Ptr(model.StoppingCanceledState),
+	})
+	_, task := RequireMockTrial(t, db, exp)
+	alloc := model.Allocation{
+		AllocationID: model.AllocationID(task.TaskID + "-1"),
+		TaskID:       task.TaskID,
+		StartTime:    ptrs.Ptr(time.Now().UTC()),
+	}
+	require.NoError(t, db.AddAllocation(&alloc))
+
+	isStale := func() bool {
+		exps, err := StaleExperiments(ctx)
+		require.NoError(t, err)
+		for _, e := range exps {
+			if e.ID == exp.ID {
+				require.Equal(t, model.StoppingCanceledState, e.State)
+				return true
+			}
+		}
+		return false
+	}
+	findOpen := func() *OpenAllocation {
+		allocs, err := OpenAllocations(ctx)
+		require.NoError(t, err)
+		for _, a := range allocs {
+			if a.AllocationID == alloc.AllocationID {
+				return &a
+			}
+		}
+		return nil
+	}
+
+	// A stopping experiment is not stale while one of its trials still has an allocation.
+	require.False(t, isStale())
+	open := findOpen()
+	require.NotNil(t, open)
+	require.False(t, open.TaskEnded)
+
+	// An allocation is still reported if its task ended without it being closed.
+	require.NoError(t, db.CompleteTask(task.TaskID, time.Now().UTC()))
+	open = findOpen()
+	require.NotNil(t, open)
+	require.True(t, open.TaskEnded)
+
+	alloc.EndTime = ptrs.Ptr(time.Now().UTC())
+	require.NoError(t, db.CompleteAllocation(&alloc))
+	require.Nil(t, findOpen())
+	require.True(t, isStale())
+
+	_, err := db.GetOrCreateClusterID("")
+	require.NoError(t, err)
+	heartbeat := time.Now().UTC().Truncate(time.Millisecond)
+	require.NoError(t, db.UpdateClusterHeartBeat(heartbeat))
+	got, err := ClusterHeartbeat(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	require.True(t, heartbeat.Equal(*got))
+}
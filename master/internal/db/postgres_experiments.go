@@ -901,7 +901,7 @@ EXISTS(
    SELECT 1
    FROM experiments e
    JOIN checkpoints_view c ON c.experiment_id = e.id
-   JOIN model_versions mv ON mv.checkpoint_uuid = c.uuid
+   JOIN registered_checkpoints mv ON mv.checkpoint_uuid = c.uuid
    WHERE e.id = $1
 )`, id).Scan(&exists)
 	return exists, err
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+type metadataRow struct {
+	Key string `bun:"key"`
+	model.MetadataValue
+}
+
+func entityMetadata(
+	ctx context.Context, table, idColumn string, id int,
+) (map[string]model.MetadataValue, error) {
+	var rows []metadataRow
+	if err := Bun().NewSelect().Table(table).
+		Column("key", "type", "string_value", "number_value", "bool_value", "timestamp_value").
+		Where("? = ?", bun.Ident(idColumn), id).
+		Scan(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("fetching %s for %d: %w", table, id, err)
+	}
+	out := make(map[string]model.MetadataValue, len(rows))
+	for _, r := range rows {
+		out[r.Key] = r.MetadataValue
+	}
+	return out, nil
+}
+
+// ExperimentMetadata returns the typed metadata of an experiment, keyed by name.
+func ExperimentMetadata(ctx context.Context, experimentID int) (map[string]model.MetadataValue, error) {
+	return entityMetadata(ctx, "experiment_metadata", "experiment_id", experimentID)
+}
+
+// TrialMetadata returns the typed metadata of a trial, keyed by name.
+func TrialMetadata(ctx context.Context, trialID int) (map[string]model.MetadataValue, error) {
+	return entityMetadata(ctx, "trial_metadata", "trial_id", trialID)
+}
+
+// UpdateExperimentMetadata sets or, for nil values, removes metadata keys of an experiment.
+// Keys that are not mentioned are left untouched.
+func UpdateExperimentMetadata(
+	ctx context.Context, experimentID int, updates map[string]*model.MetadataValue,
+) error {
+	return Bun().RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		for key, v := range updates {
+			if v == nil {
+				if _, err := tx.NewDelete().Model((*model.ExperimentMetadata)(nil)).
+					Where("experiment_id = ?", experimentID).
+					Where("key = ?", key).
+					Exec(ctx); err != nil {
+					return fmt.Errorf("deleting metadata %q of experiment %d: %w", key, experimentID, err)
+				}
+				continue
+			}
+			row := &model.ExperimentMetadata{ExperimentID: experimentID, Key: key, MetadataValue: *v}
+			if _, err := upsertMetadata(tx.NewInsert().Model(row), "experiment_id").
+				Exec(ctx); err != nil {
+				return fmt.Errorf("setting metadata %q of experiment %d: %w", key, experimentID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateTrialMetadata sets or, for nil values, removes metadata keys of a trial. Keys that are
+// not mentioned are left untouched.
+func UpdateTrialMetadata(
+	ctx context.Context, trialID int, updates map[string]*model.MetadataValue,
+) error {
+	return Bun().RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		return UpdateTrialMetadataTx(ctx, tx, trialID, updates)
+	})
+}
+
+// UpdateTrialMetadataTx is UpdateTrialMetadata as part of a transaction.
+func UpdateTrialMetadataTx(
+	ctx context.Context, idb bun.IDB, trialID int, updates map[string]*model.MetadataValue,
+) error {
+	for key, v := range updates {
+		if v == nil {
+			if _, err := idb.NewDelete().Model((*model.TrialMetadata)(nil)).
+				Where("trial_id = ?", trialID).
+				Where("key = ?", key).
+				Exec(ctx); err != nil {
+				return fmt.Errorf("deleting metadata %q of trial %d: %w", key, trialID, err)
+			}
+			continue
+		}
+		row := &model.TrialMetadata{TrialID: trialID, Key: key, MetadataValue: *v}
+		if _, err := upsertMetadata(idb.NewInsert().Model(row), "trial_id").
+			Exec(ctx); err != nil {
+			return fmt.Errorf("setting metadata %q of trial %d: %w", key, trialID, err)
+		}
+	}
+	return nil
+}
+
+func upsertMetadata(q *bun.InsertQuery, idColumn string) *bun.InsertQuery {
+	return q.On("CONFLICT (?, key) DO UPDATE", bun.Ident(idColumn)).
+		Set("type = EXCLUDED.type").
+		Set("string_value = EXCLUDED.string_value").
+		Set("number_value = EXCLUDED.number_value").
+		Set("bool_value = EXCLUDED.bool_value").
+		Set("timestamp_value = EXCLUDED.timestamp_value")
+}
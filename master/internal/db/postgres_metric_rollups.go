@@ -0,0 +1,274 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/uptrace/bun"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// MetricRollupResolutions are the bucket widths, in batches, that metrics are rolled up at.
+var MetricRollupResolutions = []int{10, 100, 1000}
+
+// MetricRollup is a row of the metric_rollups table: the aggregate of the values a metric took
+// in a bucket of batches.
+type MetricRollup struct {
+	bun.BaseModel `bun:"table:metric_rollups"`
+
+	TrialID     int       `bun:"trial_id" json:"trial_id"`
+	MetricGroup string    `bun:"metric_group" json:"metric_group"`
+	Resolution  int       `bun:"resolution" json:"resolution"`
+	Bucket      int64     `bun:"bucket" json:"bucket"`
+	MetricName  string    `bun:"metric_name" json:"metric_name"`
+	Count       int       `bun:"count" json:"count"`
+	Sum         float64   `bun:"sum" json:"-"`
+	Min         float64   `bun:"min" json:"min"`
+	Max         float64   `bun:"max" json:"max"`
+	Last        float64   `bun:"last" json:"last"`
+	LastBatches int       `bun:"last_batches" json:"last_batches"`
+	LastEndTime time.Time `bun:"last_end_time" json:"last_end_time"`
+	// Mean is computed from Sum and Count when the rollup is read.
+	Mean float64 `bun:"-" json:"mean"`
+}
+
+func rollupResolutionsSQL() string {
+	res := make([]string, 0, len(MetricRollupResolutions))
+	for _, r := range MetricRollupResolutions {
+		res = append(res, fmt.Sprint(r))
+	}
+	return "ARRAY[" + strings.Join(res, ", ") + "]"
+}
+
+// computeMetricRollupsSQL returns a statement that rolls up the unarchived metrics matching where
+// into metric_rollups. The metrics are aliased as m and the resolution as res.
+func computeMetricRollupsSQL(where string) string {
+	return fmt.Sprintf(`
+INSERT INTO metric_rollups (trial_id, metric_group, resolution, bucket, metric_name,
+	count, sum, min, max, last, last_batches, last_end_time)
+SELECT m.trial_id, g.metric_group, res, m.total_batches / res, kv.key,
+	count(*), sum(n.v), min(n.v), max(n.v),
+	(array_agg(n.v ORDER BY m.total_batches DESC))[1],
+	max(m.total_batches), max(m.end_time)
+FROM metrics m
+CROSS JOIN unnest(%s) AS res
+CROSS JOIN LATERAL (
+	SELECT CASE m.partition_type
+		WHEN 'TRAINING' THEN '%s'
+		WHEN 'VALIDATION' THEN '%s'
+		ELSE m.metric_group
+	END AS metric_group
+) AS g
+CROSS JOIN LATERAL jsonb_each(
+	m.metrics->(CASE WHEN m.partition_type = 'VALIDATION' THEN '%s' ELSE '%s' END)
+) AS kv
+CROSS JOIN LATERAL (
+	SELECT (kv.value #>> '{}')::float8 AS v WHERE jsonb_typeof(kv.value) = 'number'
+) AS n
+WHERE NOT m.archived AND %s
+GROUP BY m.trial_id, g.metric_group, res, m.total_batches / res, kv.key`,
+		rollupResolutionsSQL(), model.TrainingMetricGroup, model.ValidationMetricGroup,
+		model.TrialMetricsJSONPath(true), model.TrialMetricsJSONPath(false), where,
+	)
+}
+
+// rollbackMetricRollupsTx recomputes the rollups of a trial from its unarchived metrics, starting
+// at the buckets that hold fromBatches. Buckets before it are left as they are, since a rollback
+// only archives the metrics reported after the batch being rolled back to.
+func rollbackMetricRollupsTx(ctx context.Context, tx *sqlx.Tx, trialID, fromBatches int32) error {
+	if _, err := tx.ExecContext(ctx, `
+DELETE FROM metric_rollups WHERE trial_id = $1 AND bucket >= $2::bigint / resolution`,
+		trialID, fromBatches); err != nil {
+		return errors.Wrap(err, "deleting metric rollups")
+	}
+	if _, err := tx.ExecContext(ctx, computeMetricRollupsSQL(
+		"m.trial_id = $1 AND m.total_batches / res >= $2::bigint / res",
+	), trialID, fromBatches); err != nil {
+		return errors.Wrap(err, "computing metric rollups")
+	}
+	return nil
+}
+
+const (
+	// metricRollupsBackfillBatchSize is how many trials are rolled up per transaction.
+	metricRollupsBackfillBatchSize = 100
+	// metricRollupsBackfillRetryInterval is how long the backfill waits to retry after a failure.
+	metricRollupsBackfillRetryInterval = time.Minute
+)
+
+// BackfillMetricRollups rolls up the metrics that trials reported before rollups existed, a batch
+// of trials per transaction, until every trial is rolled up or ctx is canceled. The migration that
+// added rollups only queues the trials, so that upgrading doesn't scan every metric.
+func BackfillMetricRollups(ctx context.Context) {
+	for {
+		done, err := backfillMetricRollups(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			log.WithError(err).Error("failed to backfill metric rollups")
+			select {
+			case <-time.After(metricRollupsBackfillRetryInterval):
+			case <-ctx.Done():
+				return
+			}
+		case done:
+			return
+		}
+	}
+}
+
+// backfillMetricRollups recomputes the rollups of the next batch of queued trials from their
+// metrics and dequeues them, returning whether no trials were left to roll up.
+func backfillMetricRollups(ctx context.Context) (bool, error) {
+	var done bool
+	err := Bun().RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var trialIDs []int32
+		if err := tx.NewRaw(`
+SELECT trial_id FROM metric_rollups_backfill ORDER BY trial_id LIMIT ? FOR UPDATE SKIP LOCKED`,
+			metricRollupsBackfillBatchSize).Scan(ctx, &trialIDs); err != nil {
+			return errors.Wrap(err, "getting trials to roll up")
+		}
+		if len(trialIDs) == 0 {
+			done = true
+			return nil
+		}
+
+		// Lock the trials as reporting metrics does, so that rollups reported meanwhile are
+		// either recomputed here or applied on top of the recomputed ones.
+		if _, err := tx.NewRaw("SELECT id FROM trials WHERE id IN (?) ORDER BY id FOR UPDATE",
+			bun.In(trialIDs)).Exec(ctx); err != nil {
+			return errors.Wrap(err, "locking trials")
+		}
+		if _, err := tx.NewRaw("DELETE FROM metric_rollups WHERE trial_id IN (?)",
+			bun.In(trialIDs)).Exec(ctx); err != nil {
+			return errors.Wrap(err, "deleting metric rollups")
+		}
+		if _, err := tx.NewRaw(computeMetricRollupsSQL("m.trial_id IN (?)"),
+			bun.In(trialIDs)).Exec(ctx); err != nil {
+			return errors.Wrap(err, "computing metric rollups")
+		}
+		if _, err := tx.NewRaw("DELETE FROM metric_rollups_backfill WHERE trial_id IN (?)",
+			bun.In(trialIDs)).Exec(ctx); err != nil {
+			return errors.Wrap(err, "dequeuing rolled up trials")
+		}
+		return nil
+	})
+	return done, err
+}
+
+// updateMetricRollupsTx folds newly added metrics into the bucket of each resolution they fall in.
+func updateMetricRollupsTx(ctx context.Context, tx *sqlx.Tx, trialID, totalBatches int32,
+	mGroup model.MetricGroup, metrics *structpb.Struct,
+) error {
+	values := map[string]float64{}
+	for name, v := range metrics.GetFields() {
+		n, ok := v.GetKind().(*structpb.Value_NumberValue)
+		if !ok || math.IsNaN(n.NumberValue) || math.IsInf(n.NumberValue, 0) {
+			continue
+		}
+		values[name] = n.NumberValue
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	valuesJSON, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+INSERT INTO metric_rollups AS r (trial_id, metric_group, resolution, bucket, metric_name,
+	count, sum, min, max, last, last_batches, last_end_time)
+SELECT $1, $2, res, $3::bigint / res, kv.key, 1, kv.value, kv.value, kv.value, kv.value, $3, now()
+FROM unnest(%s) AS res
+CROSS JOIN LATERAL (
+	SELECT key, (value #>> '{}')::float8 AS value FROM jsonb_each($4::jsonb)
+) AS kv
+ON CONFLICT (trial_id, metric_group, resolution, metric_name, bucket) DO UPDATE SET
+	count = r.count + 1,
+	sum = r.sum + EXCLUDED.sum,
+	min = LEAST(r.min, EXCLUDED.min),
+	max = GREATEST(r.max, EXCLUDED.max),
+	last = CASE WHEN EXCLUDED.last_batches >= r.last_batches THEN EXCLUDED.last ELSE r.last END,
+	last_batches = GREATEST(r.last_batches, EXCLUDED.last_batches),
+	last_end_time = GREATEST(r.last_end_time, EXCLUDED.last_end_time)`,
+		rollupResolutionsSQL(),
+	), trialID, string(mGroup), totalBatches, string(valuesJSON)); err != nil {
+		return errors.Wrap(err, "updating metric rollups")
+	}
+	return nil
+}
+
+// MetricRollupBucketCounts returns, per resolution, how many buckets of a metric fall in the
+// range of batches.
+func MetricRollupBucketCounts(ctx context.Context, trialID int, mGroup model.MetricGroup,
+	metricName string, startBatches, endBatches int,
+) (map[int]int, error) {
+	var rows []struct {
+		Resolution int `bun:"resolution"`
+		Count      int `bun:"count"`
+	}
+	err := Bun().NewSelect().Table("metric_rollups").
+		Column("resolution").
+		ColumnExpr("count(*) AS count").
+		Where("trial_id = ?", trialID).
+		Where("metric_group = ?", mGroup).
+		Where("metric_name = ?", metricName).
+		Where("last_batches >= ?", startBatches).
+		Where("last_batches <= ?", endBatches).
+		Group("resolution").
+		Scan(ctx, &rows)
+	if err != nil {
+		return nil, fmt.Errorf("counting metric rollups of trial %d: %w", trialID, err)
+	}
+	counts := make(map[int]int, len(rows))
+	for _, r := range rows {
+		counts[r.Resolution] = r.Count
+	}
+	return counts, nil
+}
+
+// MetricRollups returns the rollups of the metrics of a trial at a resolution, ordered by bucket.
+// Buckets are kept if the last batch reported in them falls in the range. If limit is positive,
+// only the first limit buckets are returned.
+func MetricRollups(ctx context.Context, trialID int, mGroup model.MetricGroup, resolution int,
+	metricNames []string, startBatches, endBatches, limit int,
+) ([]MetricRollup, error) {
+	filter := func(q *bun.SelectQuery) *bun.SelectQuery {
+		q = q.Where("trial_id = ?", trialID).
+			Where("metric_group = ?", mGroup).
+			Where("resolution = ?", resolution).
+			Where("last_batches >= ?", startBatches).
+			Where("last_batches <= ?", endBatches)
+		if len(metricNames) > 0 {
+			q = q.Where("metric_name IN (?)", bun.In(metricNames))
+		}
+		return q
+	}
+
+	var rollups []MetricRollup
+	q := filter(Bun().NewSelect().Model(&rollups)).Order("bucket", "metric_name")
+	if limit > 0 {
+		q.Where("bucket IN (?)", filter(Bun().NewSelect().Table("metric_rollups")).
+			ColumnExpr("DISTINCT bucket").
+			Order("bucket").
+			Limit(limit))
+	}
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("getting metric rollups of trial %d: %w", trialID, err)
+	}
+	for i := range rollups {
+		rollups[i].Mean = rollups[i].Sum / float64(rollups[i].Count)
+	}
+	return rollups, nil
+}
@@ -0,0 +1,93 @@
+//go:build integration
+// +build integration
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/pkg/etc"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func TestMetricRollups(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, etc.SetRootPath(RootFromDB))
+	db := MustResolveTestPostgres(t)
+	MustMigrateTestPostgres(t, db, MigrationsFromDB)
+
+	user := RequireMockUser(t, db)
+	exp := RequireMockExperiment(t, db, user)
+	trialID := RequireMockTrialID(t, db, exp)
+
+	// Batches 1 through 25; a non-numeric value is left out of the rollups.
+	var training []string
+	for i := 1; i <= 25; i++ {
+		training = append(training, fmt.Sprintf(`{"loss": %d, "tag": "x"}`, i))
+	}
+	addTestTrialMetrics(ctx, t, db, trialID,
+		fmt.Sprintf(`{"training": [%s]}`, strings.Join(training, ",")))
+
+	counts, err := MetricRollupBucketCounts(ctx, trialID, model.TrainingMetricGroup, "loss",
+		0, math.MaxInt32)
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{10: 3, 100: 1, 1000: 1}, counts)
+
+	rollups, err := MetricRollups(ctx, trialID, model.TrainingMetricGroup, 10, nil,
+		0, math.MaxInt32, 0)
+	require.NoError(t, err)
+	require.Len(t, rollups, 3)
+	// Bucket 0 holds batches 1 through 9, bucket 1 holds 10 through 19.
+	require.Equal(t, "loss", rollups[1].MetricName)
+	require.Equal(t, int64(1), rollups[1].Bucket)
+	require.Equal(t, 10, rollups[1].Count)
+	require.Equal(t, 10.0, rollups[1].Min)
+	require.Equal(t, 19.0, rollups[1].Max)
+	require.Equal(t, 19.0, rollups[1].Last)
+	require.Equal(t, 14.5, rollups[1].Mean)
+
+	// Recomputing the buckets from batch 15 on gives the same rollups as the incremental updates.
+	tx, err := db.sql.BeginTxx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, rollbackMetricRollupsTx(ctx, tx, int32(trialID), 15))
+	require.NoError(t, tx.Commit())
+	rebuilt, err := MetricRollups(ctx, trialID, model.TrainingMetricGroup, 10, nil,
+		0, math.MaxInt32, 0)
+	require.NoError(t, err)
+	require.Len(t, rebuilt, len(rollups))
+	for i := range rollups {
+		rebuilt[i].LastEndTime = rollups[i].LastEndTime
+		require.Equal(t, rollups[i], rebuilt[i])
+	}
+
+	// Backfilling a queued trial recomputes all of its rollups.
+	_, err = Bun().NewRaw("DELETE FROM metric_rollups WHERE trial_id = ?", trialID).Exec(ctx)
+	require.NoError(t, err)
+	_, err = Bun().NewRaw("INSERT INTO metric_rollups_backfill (trial_id) VALUES (?)", trialID).
+		Exec(ctx)
+	require.NoError(t, err)
+	for done := false; !done; {
+		done, err = backfillMetricRollups(ctx)
+		require.NoError(t, err)
+	}
+	backfilled, err := MetricRollups(ctx, trialID, model.TrainingMetricGroup, 10, nil,
+		0, math.MaxInt32, 0)
+	require.NoError(t, err)
+	require.Len(t, backfilled, len(rollups))
+	for i := range rollups {
+		backfilled[i].LastEndTime = rollups[i].LastEndTime
+		require.Equal(t, rollups[i], backfilled[i])
+	}
+
+	limited, err := MetricRollups(ctx, trialID, model.TrainingMetricGroup, 10, nil,
+		0, math.MaxInt32, 2)
+	require.NoError(t, err)
+	require.Len(t, limited, 2)
+	require.Equal(t, int64(1), limited[1].Bucket)
+}
@@ -16,6 +16,45 @@ import (
 	"github.com/determined-ai/determined/proto/pkg/apiv1"
 )
 
+// RPWorkspaceBindingPolicy is the scheduling policy a binding applies to the jobs of its
+// workspace in its pool. Unset fields leave the job's own config and the pool's defaults alone.
+type RPWorkspaceBindingPolicy struct {
+	// Priority is the default priority of the workspace's jobs in the pool.
+	Priority *int `bun:"priority" json:"priority"`
+	// Weight is the default fair share weight of the workspace's jobs in the pool.
+	Weight *float64 `bun:"weight" json:"weight"`
+	// MaxSlots caps the slots all the jobs of the workspace may use in the pool at once.
+	MaxSlots *int `bun:"max_slots" json:"max_slots"`
+	// Exclusive bindings dedicate the pool to the workspace; no other workspace may be bound.
+	Exclusive bool `bun:"exclusive" json:"exclusive"`
+}
+
+// Validate checks that the policy values are in range.
+func (p RPWorkspaceBindingPolicy) Validate() error {
+	for _, err := range model.ValidatePrioritySetting(p.Priority) {
+		if err != nil {
+			return err
+		}
+	}
+	if p.Weight != nil && *p.Weight <= 0 {
+		return errors.New("binding weight must be greater than 0")
+	}
+	if p.MaxSlots != nil && *p.MaxSlots < 0 {
+		return errors.New("binding max slots must not be negative")
+	}
+	return nil
+}
+
+// CheckSlots returns an error if a job asking for the given number of slots can never be
+// scheduled under the binding's max slots.
+func (p RPWorkspaceBindingPolicy) CheckSlots(slots int) error {
+	if p.MaxSlots != nil && slots > *p.MaxSlots {
+		return errors.Errorf(
+			"%d slots requested exceeds the binding's limit of %d slots", slots, *p.MaxSlots)
+	}
+	return nil
+}
+
 // RPWorkspaceBinding is a struct reflecting the db table rp_workspace_bindings.
 type RPWorkspaceBinding struct {
 	bun.BaseModel `bun:"table:rp_workspace_bindings"`
@@ -23,29 +62,43 @@ type RPWorkspaceBinding struct {
 	WorkspaceID int    `bun:"workspace_id"`
 	PoolName    string `bun:"pool_name"`
 	Valid       bool   `bun:"valid"`
+	RPWorkspaceBindingPolicy
 }
 
-// AddRPWorkspaceBindings inserts new bindings between workspaceIds and poolName.
-func AddRPWorkspaceBindings(ctx context.Context, workspaceIds []int32, poolName string,
-	resourcePools []config.ResourcePoolConfig,
-) error {
-	if len(workspaceIds) == 0 {
-		return nil
+// checkRPExclusivity returns an error if the pool has an exclusive binding alongside any other
+// binding. It is run after bindings change, in the same transaction.
+func checkRPExclusivity(ctx context.Context, idb bun.IDB, poolName string) error {
+	var counts struct {
+		Total     int `bun:"total"`
+		Exclusive int `bun:"exclusive"`
 	}
-	// Check if pool exists
-	poolExists := false
+	err := idb.NewSelect().
+		Table("rp_workspace_bindings").
+		ColumnExpr("count(*) AS total").
+		ColumnExpr("count(*) FILTER (WHERE exclusive) AS exclusive").
+		Where("pool_name = ?", poolName).
+		Scan(ctx, &counts)
+	if err != nil {
+		return err
+	}
+	if counts.Exclusive > 0 && counts.Total > 1 {
+		return errors.Wrapf(ErrInvalidInput,
+			"pool with name %v is bound exclusively and can only be bound to one workspace", poolName)
+	}
+	return nil
+}
+
+func checkPoolExists(poolName string, resourcePools []config.ResourcePoolConfig) error {
 	for _, pool := range resourcePools {
 		if poolName == pool.PoolName {
-			poolExists = true
-			break
+			return nil
 		}
 	}
+	return errors.Errorf("pool with name %v doesn't exist",
+		poolName)
+}
 
-	if !poolExists {
-		return errors.Errorf("pool with name %v doesn't exist",
-			poolName)
-	}
-
+func newRPWorkspaceBindings(workspaceIds []int32, poolName string) []RPWorkspaceBinding {
 	var bindings []RPWorkspaceBinding
 	for _, workspaceID := range workspaceIds {
 		bindings = append(bindings, RPWorkspaceBinding{
@@ -54,9 +107,27 @@ func AddRPWorkspaceBindings(ctx context.Context, workspaceIds []int32, poolName
 			Valid:       true,
 		})
 	}
+	return bindings
+}
 
-	_, err := Bun().NewInsert().Model(&bindings).Exec(ctx)
-	return err
+// AddRPWorkspaceBindings inserts new bindings between workspaceIds and poolName.
+func AddRPWorkspaceBindings(ctx context.Context, workspaceIds []int32, poolName string,
+	resourcePools []config.ResourcePoolConfig,
+) error {
+	if len(workspaceIds) == 0 {
+		return nil
+	}
+	if err := checkPoolExists(poolName, resourcePools); err != nil {
+		return err
+	}
+
+	bindings := newRPWorkspaceBindings(workspaceIds, poolName)
+	return Bun().RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().Model(&bindings).Exec(ctx); err != nil {
+			return err
+		}
+		return checkRPExclusivity(ctx, tx, poolName)
+	})
 }
 
 // RemoveRPWorkspaceBindings removes the bindings between workspaceIds and poolName.
@@ -137,27 +208,95 @@ func ReadWorkspacesBoundToRP(
 func OverwriteRPWorkspaceBindings(ctx context.Context,
 	workspaceIds []int32, poolName string, resourcePools []config.ResourcePoolConfig,
 ) error {
-	// Check if pool exists
-	poolExists := false
-	for _, pool := range resourcePools {
-		if poolName == pool.PoolName {
-			poolExists = true
-		}
+	if err := checkPoolExists(poolName, resourcePools); err != nil {
+		return err
 	}
 
-	if !poolExists {
-		return errors.Errorf("pool with name %v doesn't exist",
-			poolName)
+	// Bindings that survive the overwrite keep their policy.
+	return Bun().RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		q := tx.NewDelete().Table("rp_workspace_bindings").Where("pool_name = ?", poolName)
+		if len(workspaceIds) > 0 {
+			q.Where("workspace_id NOT IN (?)", bun.In(workspaceIds))
+		}
+		if _, err := q.Exec(ctx); err != nil {
+			return err
+		}
+		if len(workspaceIds) == 0 {
+			return nil
+		}
+
+		bindings := newRPWorkspaceBindings(workspaceIds, poolName)
+		_, err := tx.NewInsert().Model(&bindings).
+			On("CONFLICT (workspace_id, pool_name) DO NOTHING").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		return checkRPExclusivity(ctx, tx, poolName)
+	})
+}
+
+// GetRPWorkspaceBinding returns the binding between a workspace and a pool, or nil if the
+// workspace isn't bound to the pool.
+func GetRPWorkspaceBinding(
+	ctx context.Context, workspaceID int, poolName string,
+) (*RPWorkspaceBinding, error) {
+	var binding RPWorkspaceBinding
+	err := Bun().NewSelect().Model(&binding).
+		Where("workspace_id = ?", workspaceID).
+		Where("pool_name = ?", poolName).
+		Scan(ctx)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, nil
+	case err != nil:
+		return nil, err
 	}
-	// Remove existing ones with this pool name
-	_, err := Bun().NewDelete().Table("rp_workspace_bindings").
-		Where("pool_name = ?", poolName).Exec(ctx)
+	return &binding, nil
+}
+
+// GetRPWorkspaceMaxSlots returns the max slots of the bindings to a pool that have them, by
+// workspace ID.
+func GetRPWorkspaceMaxSlots(ctx context.Context, poolName string) (map[int]int, error) {
+	var bindings []RPWorkspaceBinding
+	err := Bun().NewSelect().Model(&bindings).
+		Where("pool_name = ?", poolName).
+		Where("max_slots IS NOT NULL").
+		Scan(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	maxSlots := make(map[int]int, len(bindings))
+	for _, binding := range bindings {
+		maxSlots[binding.WorkspaceID] = *binding.MaxSlots
 	}
+	return maxSlots, nil
+}
 
-	err = AddRPWorkspaceBindings(ctx, workspaceIds, poolName, resourcePools)
-	return err
+// SetRPWorkspaceBindingPolicy replaces the policy of an existing binding between a workspace and
+// a pool.
+func SetRPWorkspaceBindingPolicy(
+	ctx context.Context, workspaceID int, poolName string, policy RPWorkspaceBindingPolicy,
+) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	return Bun().RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		res, err := tx.NewUpdate().Model(&RPWorkspaceBinding{RPWorkspaceBindingPolicy: policy}).
+			Column("priority", "weight", "max_slots", "exclusive").
+			Where("workspace_id = ?", workspaceID).
+			Where("pool_name = ?", poolName).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrNotFound
+		}
+		return checkRPExclusivity(ctx, tx, poolName)
+	})
 }
 
 // GetAllBindings gets all valid rp-workspace bindings.
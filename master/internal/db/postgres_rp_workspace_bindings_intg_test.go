@@ -13,6 +13,7 @@ import (
 	"github.com/determined-ai/determined/master/internal/config"
 	"github.com/determined-ai/determined/master/pkg/etc"
 	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/ptrs"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
@@ -480,3 +481,66 @@ func TestRemoveInvalidBinding(t *testing.T) {
 	err = RemoveRPWorkspaceBindings(ctx, workspaceIDs, testPoolName)
 	require.ErrorContains(t, err, " binding doesn't exist")
 }
+
+func TestRPWorkspaceBindingPolicy(t *testing.T) {
+	require.NoError(t, etc.SetRootPath(RootFromDB))
+	db := MustResolveTestPostgres(t)
+	MustMigrateTestPostgres(t, db, MigrationsFromDB)
+	ctx := context.Background()
+	user := RequireMockUser(t, db)
+
+	existingPools := []config.ResourcePoolConfig{{PoolName: testPoolName}}
+	workspaceIDs, err := MockWorkspaces([]string{"test1", "test2", "test3"}, user.ID)
+	require.NoError(t, err)
+	defer func() {
+		err = CleanupMockWorkspace(workspaceIDs)
+		if err != nil {
+			log.Errorf("error when cleaning up mock workspaces")
+		}
+	}()
+	ws0 := int(workspaceIDs[0])
+
+	err = SetRPWorkspaceBindingPolicy(ctx, ws0, testPoolName, RPWorkspaceBindingPolicy{})
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, AddRPWorkspaceBindings(ctx, workspaceIDs[:2], testPoolName, existingPools))
+	policy := RPWorkspaceBindingPolicy{
+		Priority: ptrs.Ptr(10),
+		Weight:   ptrs.Ptr(2.5),
+		MaxSlots: ptrs.Ptr(8),
+	}
+	require.NoError(t, SetRPWorkspaceBindingPolicy(ctx, ws0, testPoolName, policy))
+
+	binding, err := GetRPWorkspaceBinding(ctx, ws0, testPoolName)
+	require.NoError(t, err)
+	require.Equal(t, policy, binding.RPWorkspaceBindingPolicy)
+
+	binding, err = GetRPWorkspaceBinding(ctx, int(workspaceIDs[2]), testPoolName)
+	require.NoError(t, err)
+	require.Nil(t, binding)
+
+	// Overwriting keeps the policy of bindings that survive.
+	require.NoError(t, OverwriteRPWorkspaceBindings(ctx, workspaceIDs[:1], testPoolName, existingPools))
+	binding, err = GetRPWorkspaceBinding(ctx, ws0, testPoolName)
+	require.NoError(t, err)
+	require.Equal(t, policy, binding.RPWorkspaceBindingPolicy)
+
+	// An exclusive binding keeps every other workspace off the pool.
+	policy.Exclusive = true
+	require.NoError(t, SetRPWorkspaceBindingPolicy(ctx, ws0, testPoolName, policy))
+	err = AddRPWorkspaceBindings(ctx, workspaceIDs[1:2], testPoolName, existingPools)
+	require.ErrorIs(t, err, ErrInvalidInput)
+	err = OverwriteRPWorkspaceBindings(ctx, workspaceIDs, testPoolName, existingPools)
+	require.ErrorIs(t, err, ErrInvalidInput)
+	bindings, _, err := ReadWorkspacesBoundToRP(ctx, testPoolName, 0, 0, existingPools)
+	require.NoError(t, err)
+	require.Len(t, bindings, 1)
+
+	// A pool shared by several workspaces can't be made exclusive.
+	policy.Exclusive = false
+	require.NoError(t, SetRPWorkspaceBindingPolicy(ctx, ws0, testPoolName, policy))
+	require.NoError(t, AddRPWorkspaceBindings(ctx, workspaceIDs[1:2], testPoolName, existingPools))
+	policy.Exclusive = true
+	err = SetRPWorkspaceBindingPolicy(ctx, ws0, testPoolName, policy)
+	require.ErrorIs(t, err, ErrInvalidInput)
+}
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/pkg/ptrs"
+)
+
+func TestRPWorkspaceBindingPolicyValidate(t *testing.T) {
+	require.NoError(t, RPWorkspaceBindingPolicy{}.Validate())
+	require.NoError(t, RPWorkspaceBindingPolicy{
+		Priority: ptrs.Ptr(42), Weight: ptrs.Ptr(0.5), MaxSlots: ptrs.Ptr(0),
+	}.Validate())
+
+	require.Error(t, RPWorkspaceBindingPolicy{Priority: ptrs.Ptr(0)}.Validate())
+	require.Error(t, RPWorkspaceBindingPolicy{Priority: ptrs.Ptr(100)}.Validate())
+	require.Error(t, RPWorkspaceBindingPolicy{Weight: ptrs.Ptr(0.0)}.Validate())
+	require.Error(t, RPWorkspaceBindingPolicy{MaxSlots: ptrs.Ptr(-1)}.Validate())
+}
+
+func TestRPWorkspaceBindingPolicySlots(t *testing.T) {
+	require.NoError(t, RPWorkspaceBindingPolicy{}.CheckSlots(64))
+
+	capped := RPWorkspaceBindingPolicy{MaxSlots: ptrs.Ptr(8)}
+	require.NoError(t, capped.CheckSlots(8))
+	require.ErrorContains(t, capped.CheckSlots(9), "limit of 8 slots")
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// TrialSearcherValidation is a recorded value of a searcher metric.
+type TrialSearcherValidation struct {
+	TotalBatches int     `json:"total_batches"`
+	Metric       float64 `json:"metric"`
+}
+
+// TrialSearcherHistory is the recorded training history of a trial, as needed to replay a
+// searcher against it.
+type TrialSearcherHistory struct {
+	TrialID         int            `bun:"trial_id"`
+	HParams         map[string]any `bun:"hparams"`
+	TotalBatches    int            `bun:"total_batches"`
+	RecordsPerEpoch *int           `bun:"records_per_epoch"`
+	// SlotSeconds is the sum over the trial's finished allocations of their duration times their
+	// slots.
+	SlotSeconds float64                   `bun:"slot_seconds"`
+	Validations []TrialSearcherValidation `bun:"validations,type:jsonb"`
+}
+
+// ExperimentSearcherHistory returns the recorded history of every trial of an experiment for the
+// given metric, with validations ordered by batches.
+func ExperimentSearcherHistory(
+	ctx context.Context, experimentID int, metricName string,
+) ([]TrialSearcherHistory, error) {
+	var history []TrialSearcherHistory
+	err := Bun().NewRaw(`
+SELECT t.id AS trial_id, t.hparams, t.total_batches,
+	(e.config->>'records_per_epoch')::int AS records_per_epoch,
+	COALESCE((
+		SELECT extract(epoch FROM sum((a.end_time - a.start_time) * a.slots))
+		FROM allocations a
+		JOIN trial_id_task_id tt ON a.task_id = tt.task_id
+		WHERE tt.trial_id = t.id AND a.end_time IS NOT NULL
+	), 0) AS slot_seconds,
+	COALESCE((
+		SELECT jsonb_agg(jsonb_build_object(
+			'total_batches', v.total_batches,
+			'metric', (v.metrics->'validation_metrics'->>?0)::float8
+		) ORDER BY v.total_batches)
+		FROM validations v
+		WHERE v.trial_id = t.id AND v.metrics->'validation_metrics'->>?0 IS NOT NULL
+	), '[]'::jsonb) AS validations
+FROM trials t
+JOIN experiments e ON t.experiment_id = e.id
+WHERE t.experiment_id = ?1
+ORDER BY t.id`, metricName, experimentID).Scan(ctx, &history)
+	if err != nil {
+		return nil, fmt.Errorf("getting searcher history of experiment %d: %w", experimentID, err)
+	}
+	return history, nil
+}
@@ -374,6 +374,10 @@ func (db *PgDB) _addTrialMetricsTx(
 		if err := db.fullTrialSummaryMetricsRecompute(ctx, tx, int(m.TrialId)); err != nil {
 			return rollbacks, errors.Wrap(err, "error on rollback compute of summary metrics")
 		}
+
+		if err := rollbackMetricRollupsTx(ctx, tx, m.TrialId, m.StepsCompleted); err != nil {
+			return rollbacks, errors.Wrap(err, "rollback")
+		}
 	default: // no rollbacks happened.
 		summaryMetricsJSONPath := model.TrialSummaryMetricsJSONPath(mGroup)
 		if _, ok := summaryMetrics[summaryMetricsJSONPath]; !ok {
@@ -441,6 +445,11 @@ WHERE id = $1;
 `, m.TrialId, m.StepsCompleted, summaryMetrics, latestValidationID); err != nil {
 			return rollbacks, errors.Wrap(err, "updating trial total batches")
 		}
+
+		if err := updateMetricRollupsTx(ctx, tx, m.TrialId, m.StepsCompleted, mGroup,
+			addedMetrics.AvgMetrics); err != nil {
+			return rollbacks, err
+		}
 	}
 
 	if isValidation {
@@ -633,25 +642,30 @@ func calculateNewSummaryMetrics(
 
 // AddCheckpointMetadata persists metadata for a completed checkpoint to the database.
 func AddCheckpointMetadata(ctx context.Context, m *model.CheckpointV2) error {
+	err := Bun().RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		return AddCheckpointMetadataTx(ctx, tx, m)
+	})
+	if err != nil {
+		return fmt.Errorf("error adding checkpoint metadata: %w", err)
+	}
+
+	return nil
+}
+
+// AddCheckpointMetadataTx is AddCheckpointMetadata as part of a transaction.
+func AddCheckpointMetadataTx(ctx context.Context, idb bun.IDB, m *model.CheckpointV2) error {
 	var size int64
 	for _, v := range m.Resources {
 		size += v
 	}
 	m.Size = size
 
-	err := Bun().RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
-		if _, err := tx.NewInsert().Model(m).Exec(context.TODO()); err != nil {
-			return errors.Wrap(err, "inserting checkpoint")
-		}
-
-		if err := UpdateCheckpointSizeTx(ctx, tx, []uuid.UUID{m.UUID}); err != nil {
-			return errors.Wrap(err, "updating checkpoint size")
-		}
+	if _, err := idb.NewInsert().Model(m).Exec(ctx); err != nil {
+		return errors.Wrap(err, "inserting checkpoint")
+	}
 
-		return nil
-	})
-	if err != nil {
-		return fmt.Errorf("error adding checkpoint metadata: %w", err)
+	if err := UpdateCheckpointSizeTx(ctx, idb, []uuid.UUID{m.UUID}); err != nil {
+		return errors.Wrap(err, "updating checkpoint size")
 	}
 
 	return nil
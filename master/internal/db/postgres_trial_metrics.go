@@ -288,6 +288,37 @@ func (db *PgDB) AddTrialMetrics(
 	return err
 }
 
+// AddTrialMetricsAtTx persists the given trial metrics like AddTrialMetrics, but as part of tx
+// and recorded as reported at endTime rather than now, e.g. for metrics imported from other tools.
+func (db *PgDB) AddTrialMetricsAtTx(ctx context.Context, tx bun.Tx,
+	m *trialv1.TrialMetrics, mGroup model.MetricGroup, endTime time.Time,
+) error {
+	// The metrics are written with sqlx; run those writes on the same transaction.
+	sqlxTx := &sqlx.Tx{Tx: tx.Tx, Mapper: db.sql.Mapper}
+	if _, err := db._addTrialMetricsTx(ctx, sqlxTx, m, mGroup); err != nil {
+		return err
+	}
+	metricGroup := string(mGroup)
+	if _, err := tx.ExecContext(ctx, `
+UPDATE metrics SET end_time = ?
+WHERE archived = false
+AND trial_id = ?
+AND partition_type = ?
+AND metric_group = ?
+AND total_batches = ?`,
+		endTime, m.TrialId, customMetricGroupToPartitionType(&metricGroup), mGroup,
+		m.StepsCompleted); err != nil {
+		return errors.Wrap(err, "setting metrics end time")
+	}
+	if _, err := tx.ExecContext(ctx, `
+UPDATE metric_rollups SET last_end_time = ?
+WHERE trial_id = ? AND metric_group = ? AND last_batches = ?`,
+		endTime, m.TrialId, mGroup, m.StepsCompleted); err != nil {
+		return errors.Wrap(err, "setting metric rollups end time")
+	}
+	return nil
+}
+
 // GetMetrics returns a subset metrics of the requested type for the given trial ID.
 func GetMetrics(ctx context.Context, trialID, afterBatches, limit int,
 	mGroup *string, // model.MetricGroup,
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+)
+
+var (
+	workspaceVolumeNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,38}[a-z0-9])?$`)
+	workspaceVolumeSizeRegex = regexp.MustCompile(`^[0-9]+([.][0-9]+)?([KMGTPE]i|[kMGTPE])?$`)
+)
+
+// WorkspaceVolume is a volume that is shared by all tasks of a workspace. The Kubernetes resource
+// manager creates a ReadWriteMany claim for it the first time a task of the workspace runs in a
+// namespace and mounts it into every task container of the workspace.
+type WorkspaceVolume struct {
+	bun.BaseModel `bun:"table:workspace_volumes"`
+
+	WorkspaceID int    `bun:"workspace_id,pk" json:"workspace_id"`
+	Name        string `bun:"name,pk" json:"name"`
+	// Size is the requested capacity of the claim as a Kubernetes quantity, like 500Gi.
+	Size string `bun:"size" json:"size"`
+	// StorageClass is the storage class of the claim; the cluster's default class if unset.
+	StorageClass *string   `bun:"storage_class" json:"storage_class"`
+	MountPath    string    `bun:"mount_path" json:"mount_path"`
+	ReadOnly     bool      `bun:"read_only" json:"read_only"`
+	CreatedAt    time.Time `bun:"created_at" json:"created_at"`
+}
+
+// Validate checks that the volume can be turned into a claim and mounted into task containers.
+func (v WorkspaceVolume) Validate() error {
+	if !workspaceVolumeNameRegex.MatchString(v.Name) {
+		return errors.Errorf("volume name %q must be at most 40 lowercase letters, digits and "+
+			"dashes and start and end with a letter or digit", v.Name)
+	}
+	if !workspaceVolumeSizeRegex.MatchString(v.Size) {
+		return errors.Errorf("volume size %q must be a Kubernetes quantity, like 500Gi", v.Size)
+	}
+	if !path.IsAbs(v.MountPath) {
+		return errors.Errorf("volume mount path %q must be absolute", v.MountPath)
+	}
+	if p := path.Clean(v.MountPath); p == "/" || p == "/run/determined" ||
+		strings.HasPrefix(p, "/run/determined/") {
+		return errors.Errorf("volume mount path %q is reserved", v.MountPath)
+	}
+	return nil
+}
+
+// AddWorkspaceVolume declares a shared volume for a workspace. It returns ErrDuplicateRecord if
+// the workspace already has a volume with the same name or mount path.
+func AddWorkspaceVolume(ctx context.Context, v *WorkspaceVolume) error {
+	v.CreatedAt = time.Now().UTC()
+	if _, err := Bun().NewInsert().Model(v).Exec(ctx); err != nil {
+		return fmt.Errorf("adding volume %q: %w", v.Name, MatchSentinelError(err))
+	}
+	return nil
+}
+
+// WorkspaceVolumes returns the shared volumes of a workspace, ordered by name.
+func WorkspaceVolumes(ctx context.Context, workspaceID int) ([]WorkspaceVolume, error) {
+	volumes := []WorkspaceVolume{}
+	if err := Bun().NewSelect().Model(&volumes).
+		Where("workspace_id = ?", workspaceID).
+		Order("name").
+		Scan(ctx); err != nil {
+		return nil, fmt.Errorf("getting volumes of workspace %d: %w", workspaceID, err)
+	}
+	return volumes, nil
+}
+
+// DeleteWorkspaceVolume removes the declaration of a shared volume, so that it is no longer mounted
+// into new tasks of the workspace. It returns ErrNotFound if there is no such volume.
+func DeleteWorkspaceVolume(ctx context.Context, workspaceID int, name string) error {
+	res, err := Bun().NewDelete().Model(&WorkspaceVolume{}).
+		Where("workspace_id = ?", workspaceID).
+		Where("name = ?", name).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("deleting volume %q: %w", name, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("deleting volume %q: %w", name, ErrNotFound)
+	}
+	return nil
+}
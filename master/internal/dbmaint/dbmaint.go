@@ -0,0 +1,232 @@
+// Package dbmaint runs scheduled maintenance on the master database: it prunes data that nothing
+// refers to anymore, vacuums bloated tables and reports table sizes, within a time budget per run.
+package dbmaint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/internal/prom"
+)
+
+// ErrRunInProgress is returned when a run is requested while another one is in progress.
+var ErrRunInProgress = errors.New("database maintenance is already running")
+
+// Task names, as they appear in reports and metrics.
+const (
+	TaskExperimentSnapshots = "experiment_snapshots"
+	TaskRolledBackMetrics   = "rolled_back_metrics"
+	TaskOrphanedTaskLogs    = "orphaned_task_logs"
+	TaskVacuum              = "vacuum"
+)
+
+// TaskReport is the outcome of one maintenance task.
+type TaskReport struct {
+	Name        string   `json:"name"`
+	RowsDeleted int64    `json:"rows_deleted"`
+	Vacuumed    []string `json:"vacuumed,omitempty"`
+	// Skipped says why the task didn't run, if it didn't.
+	Skipped string `json:"skipped,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// RunReport is the outcome of one maintenance run.
+type RunReport struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// BudgetExhausted is set if the run was cut short by its time budget. Whatever was left is
+	// picked up by the next run.
+	BudgetExhausted bool         `json:"budget_exhausted"`
+	Tasks           []TaskReport `json:"tasks"`
+}
+
+// Service runs database maintenance, either on a schedule or on demand.
+type Service struct {
+	conf config.DBMaintenanceConfig
+
+	running sync.Mutex
+
+	mu      sync.Mutex
+	lastRun *RunReport
+}
+
+// New returns a service that maintains the database according to conf.
+func New(conf config.DBMaintenanceConfig) *Service {
+	return &Service{conf: conf}
+}
+
+// Start runs maintenance every interval while inside the maintenance window, and refreshes the
+// table metrics every interval regardless, until ctx is canceled.
+func (s *Service) Start(ctx context.Context) {
+	t := time.NewTicker(time.Duration(s.conf.Interval))
+	defer t.Stop()
+	for {
+		if s.conf.InWindow(time.Now()) {
+			if _, err := s.Run(ctx); err != nil {
+				log.WithError(err).Error("failed to run database maintenance")
+			}
+		} else if _, err := TableStats(ctx); err != nil {
+			log.WithError(err).Error("failed to collect database table statistics")
+		}
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// LastRun returns the report of the last finished run, or nil if there was none yet.
+func (s *Service) LastRun() *RunReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastRun
+}
+
+// Run runs every maintenance task once, regardless of the maintenance window, and returns
+// ErrRunInProgress if a run is already in progress. Tasks run in order until the time budget is
+// spent; a failing task does not stop the ones after it.
+func (s *Service) Run(ctx context.Context) (*RunReport, error) {
+	if !s.running.TryLock() {
+		return nil, ErrRunInProgress
+	}
+	defer s.running.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.conf.TimeBudget))
+	defer cancel()
+
+	r := &RunReport{StartedAt: time.Now().UTC()}
+	tasks := []struct {
+		name string
+		run  func(context.Context, *TaskReport) error
+	}{
+		{TaskExperimentSnapshots, s.pruneExperimentSnapshots},
+		{TaskRolledBackMetrics, s.pruneRolledBackMetrics},
+		{TaskOrphanedTaskLogs, s.pruneOrphanedTaskLogs},
+		{TaskVacuum, s.vacuum},
+	}
+	for _, task := range tasks {
+		tr := TaskReport{Name: task.name}
+		if r.BudgetExhausted {
+			tr.Skipped = "time budget exhausted"
+			r.Tasks = append(r.Tasks, tr)
+			continue
+		}
+		err := task.run(ctx, &tr)
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			r.BudgetExhausted = true
+		case err != nil:
+			tr.Error = err.Error()
+			log.WithError(err).Errorf("database maintenance task %s failed", task.name)
+		}
+		r.Tasks = append(r.Tasks, tr)
+	}
+	if ctx.Err() != nil && !r.BudgetExhausted {
+		return nil, ctx.Err()
+	}
+	r.FinishedAt = time.Now().UTC()
+
+	deleted := make(map[string]int64, len(r.Tasks))
+	for _, tr := range r.Tasks {
+		if tr.RowsDeleted > 0 {
+			deleted[tr.Name] = tr.RowsDeleted
+		}
+	}
+	prom.ObserveDBMaintenanceRun(r.FinishedAt, deleted)
+	// The budget may be spent by now, but the metrics are worth refreshing after a run.
+	if _, err := TableStats(context.WithoutCancel(ctx)); err != nil {
+		log.WithError(err).Error("failed to collect database table statistics")
+	}
+
+	s.mu.Lock()
+	s.lastRun = r
+	s.mu.Unlock()
+	log.WithField("budget_exhausted", r.BudgetExhausted).
+		Infof("database maintenance finished in %s", r.FinishedAt.Sub(r.StartedAt))
+	return r, nil
+}
+
+func (s *Service) pruneRolledBackMetrics(ctx context.Context, tr *TaskReport) error {
+	if s.conf.RolledBackMetricsRetention == 0 {
+		tr.Skipped = "rolled back metrics are kept forever"
+		return nil
+	}
+	cutoff := time.Now().Add(-time.Duration(s.conf.RolledBackMetricsRetention))
+	n, err := deleteInBatches(ctx, func(ctx context.Context) (int64, error) {
+		return deleteRolledBackMetrics(ctx, cutoff, batchSize)
+	})
+	tr.RowsDeleted = n
+	if err != nil {
+		return fmt.Errorf("deleting rolled back metrics: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) pruneOrphanedTaskLogs(ctx context.Context, tr *TaskReport) error {
+	if s.conf.OrphanedLogsRetention == 0 {
+		tr.Skipped = "orphaned logs are kept forever"
+		return nil
+	}
+	cutoff := time.Now().Add(-time.Duration(s.conf.OrphanedLogsRetention))
+	n, err := deleteInBatches(ctx, func(ctx context.Context) (int64, error) {
+		return deleteOrphanedTaskLogs(ctx, cutoff, batchSize)
+	})
+	tr.RowsDeleted = n
+	if err != nil {
+		return fmt.Errorf("deleting orphaned task logs: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) pruneExperimentSnapshots(ctx context.Context, tr *TaskReport) error {
+	n, err := deleteInBatches(ctx, func(ctx context.Context) (int64, error) {
+		return deleteTerminalExperimentSnapshots(ctx, batchSize)
+	})
+	tr.RowsDeleted = n
+	if err != nil {
+		return fmt.Errorf("deleting experiment snapshots: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) vacuum(ctx context.Context, tr *TaskReport) error {
+	stats, err := TableStats(ctx)
+	if err != nil {
+		return err
+	}
+	for _, t := range vacuumCandidates(stats, s.conf.VacuumTables, s.conf.VacuumDeadTupleRatio) {
+		if err := vacuumAnalyze(ctx, t); err != nil {
+			return fmt.Errorf("vacuuming %s: %w", t, err)
+		}
+		tr.Vacuumed = append(tr.Vacuumed, t)
+	}
+	return nil
+}
+
+// deleteInBatches calls deleteBatch until it deletes less than a full batch, so that a single
+// statement never holds locks for long and the time budget is checked between batches.
+func deleteInBatches(
+	ctx context.Context, deleteBatch func(context.Context) (int64, error),
+) (int64, error) {
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		n, err := deleteBatch(ctx)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < batchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,110 @@
+//go:build integration
+// +build integration
+
+package dbmaint
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/pkg/etc"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, etc.SetRootPath(db.RootFromDB))
+	pgDB := db.MustResolveTestPostgres(t)
+	db.MustMigrateTestPostgres(t, pgDB, db.MigrationsFromDB)
+
+	user := db.RequireMockUser(t, pgDB)
+	exp := db.RequireMockExperiment(t, pgDB, user)
+	trialID := db.RequireMockTrialID(t, pgDB, exp)
+
+	old := time.Now().Add(-90 * 24 * time.Hour)
+	addMetric := func(endTime time.Time, archived bool) int {
+		var id int
+		require.NoError(t, db.Bun().NewRaw(`
+INSERT INTO metrics (trial_id, end_time, metrics, total_batches, archived, partition_type, metric_group)
+VALUES (?, ?, '{}', 1, ?, 'GENERIC', 'test')
+RETURNING id`, trialID, endTime, archived).Scan(ctx, &id))
+		return id
+	}
+	oldArchived := addMetric(old, true)
+	recentArchived := addMetric(time.Now(), true)
+	oldCurrent := addMetric(old, false)
+
+	// The logs of a trial outlive its experiment, since task logs don't reference trials.
+	deletedExp := db.RequireMockExperiment(t, pgDB, user)
+	_, task := db.RequireMockTrial(t, pgDB, deletedExp)
+	require.NoError(t, pgDB.AddTaskLogs([]*model.TaskLog{
+		{TaskID: string(task.TaskID), Log: "orphaned\n"},
+	}))
+	_, err := db.Bun().NewUpdate().Table("tasks").
+		Set("end_time = ?", old.UTC()).
+		Where("task_id = ?", task.TaskID).
+		Exec(ctx)
+	require.NoError(t, err)
+	require.NoError(t, pgDB.DeleteExperiments(ctx, []int{deletedExp.ID}))
+
+	conf := config.DefaultDBMaintenanceConfig()
+	conf.OrphanedLogsRetention = model.Duration(24 * time.Hour)
+	conf.VacuumTables = []string{"task_logs"}
+	s := New(conf)
+	require.Nil(t, s.LastRun())
+
+	r, err := s.Run(ctx)
+	require.NoError(t, err)
+	require.False(t, r.BudgetExhausted)
+	require.Equal(t, r, s.LastRun())
+	names := make([]string, 0, len(r.Tasks))
+	for _, tr := range r.Tasks {
+		names = append(names, tr.Name)
+		require.Empty(t, tr.Error, tr.Name)
+	}
+	require.Equal(t, []string{
+		TaskExperimentSnapshots, TaskRolledBackMetrics, TaskOrphanedTaskLogs, TaskVacuum,
+	}, names)
+
+	exists := func(id int) bool {
+		ok, err := db.Bun().NewSelect().Table("metrics").Where("id = ?", id).Exists(ctx)
+		require.NoError(t, err)
+		return ok
+	}
+	require.False(t, exists(oldArchived))
+	require.True(t, exists(recentArchived))
+	require.True(t, exists(oldCurrent))
+
+	count, err := pgDB.TaskLogsCount(task.TaskID, nil)
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	stats, err := TableStats(ctx)
+	require.NoError(t, err)
+	found := false
+	for _, s := range stats {
+		if s.Name == "task_logs" {
+			found = true
+			require.Positive(t, s.TotalBytes)
+		}
+	}
+	require.True(t, found)
+}
+
+func TestRunTimeBudget(t *testing.T) {
+	require.NoError(t, etc.SetRootPath(db.RootFromDB))
+	pgDB := db.MustResolveTestPostgres(t)
+	db.MustMigrateTestPostgres(t, pgDB, db.MigrationsFromDB)
+
+	conf := config.DefaultDBMaintenanceConfig()
+	conf.TimeBudget = model.Duration(time.Nanosecond)
+	r, err := New(conf).Run(context.Background())
+	require.NoError(t, err)
+	require.True(t, r.BudgetExhausted)
+	require.Equal(t, "time budget exhausted", r.Tasks[len(r.Tasks)-1].Skipped)
+}
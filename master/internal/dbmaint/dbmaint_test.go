@@ -0,0 +1,54 @@
+package dbmaint
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVacuumCandidates(t *testing.T) {
+	stats := []TableStat{
+		{Name: "raw_steps", DeadTuples: 50000, DeadTupleRatio: 0.5},
+		{Name: "task_logs", DeadTuples: 900000, DeadTupleRatio: 0.3},
+		{Name: "allocations", DeadTuples: 20000, DeadTupleRatio: 0.1},
+		{Name: "experiments", DeadTuples: 100, DeadTupleRatio: 0.9},
+		{Name: "users", DeadTuples: 90000, DeadTupleRatio: 0.9},
+	}
+	tables := []string{"raw_steps", "task_logs", "allocations", "experiments"}
+	require.Equal(t, []string{"task_logs", "raw_steps"}, vacuumCandidates(stats, tables, 0.2))
+	require.Equal(t, []string{"task_logs", "raw_steps", "allocations"},
+		vacuumCandidates(stats, tables, 0.1))
+	require.Empty(t, vacuumCandidates(stats, nil, 0.1))
+}
+
+func TestDeleteInBatches(t *testing.T) {
+	ctx := context.Background()
+	batches := []int64{batchSize, batchSize, 3}
+	calls := 0
+	n, err := deleteInBatches(ctx, func(context.Context) (int64, error) {
+		calls++
+		return batches[calls-1], nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2*batchSize+3), n)
+	require.Equal(t, 3, calls)
+
+	errFailed := errors.New("failed")
+	n, err = deleteInBatches(ctx, func(context.Context) (int64, error) {
+		return 0, errFailed
+	})
+	require.ErrorIs(t, err, errFailed)
+	require.Zero(t, n)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	calls = 0
+	_, err = deleteInBatches(canceled, func(context.Context) (int64, error) {
+		calls++
+		return batchSize, nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Zero(t, calls)
+}
@@ -0,0 +1,154 @@
+package dbmaint
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/prom"
+)
+
+// batchSize is how many rows a single delete statement removes at most.
+const batchSize = 5000
+
+// minDeadTuples keeps small tables from being vacuumed just because most of their few rows are
+// dead; autovacuum handles those fine.
+const minDeadTuples = 10000
+
+// TableStat describes the size and bloat of one table.
+type TableStat struct {
+	Name       string `json:"name" bun:"name"`
+	TotalBytes int64  `json:"total_bytes" bun:"total_bytes"`
+	TableBytes int64  `json:"table_bytes" bun:"table_bytes"`
+	IndexBytes int64  `json:"index_bytes" bun:"index_bytes"`
+	LiveTuples int64  `json:"live_tuples" bun:"live_tuples"`
+	DeadTuples int64  `json:"dead_tuples" bun:"dead_tuples"`
+	// DeadTupleRatio is the share of dead tuples in the table, as estimated by Postgres.
+	DeadTupleRatio float64 `json:"dead_tuple_ratio" bun:"-"`
+	// EstimatedBloatBytes is the part of the table taken up by dead tuples, assuming they are as
+	// large as live ones on average.
+	EstimatedBloatBytes int64      `json:"estimated_bloat_bytes" bun:"-"`
+	LastVacuum          *time.Time `json:"last_vacuum" bun:"last_vacuum"`
+	LastAutovacuum      *time.Time `json:"last_autovacuum" bun:"last_autovacuum"`
+	LastAnalyze         *time.Time `json:"last_analyze" bun:"last_analyze"`
+	LastAutoanalyze     *time.Time `json:"last_autoanalyze" bun:"last_autoanalyze"`
+}
+
+// TableStats returns the size and bloat of every table, largest first, and exports them as
+// metrics.
+func TableStats(ctx context.Context) ([]TableStat, error) {
+	var stats []TableStat
+	if err := db.Bun().NewRaw(`
+SELECT
+	relname AS name,
+	pg_total_relation_size(relid) AS total_bytes,
+	pg_relation_size(relid) AS table_bytes,
+	pg_indexes_size(relid) AS index_bytes,
+	n_live_tup AS live_tuples,
+	n_dead_tup AS dead_tuples,
+	last_vacuum, last_autovacuum, last_analyze, last_autoanalyze
+FROM pg_stat_user_tables
+WHERE schemaname = current_schema()
+ORDER BY total_bytes DESC, name`).Scan(ctx, &stats); err != nil {
+		return nil, err
+	}
+	for i := range stats {
+		s := &stats[i]
+		if tuples := s.LiveTuples + s.DeadTuples; tuples > 0 {
+			s.DeadTupleRatio = float64(s.DeadTuples) / float64(tuples)
+			s.EstimatedBloatBytes = int64(float64(s.TableBytes) * s.DeadTupleRatio)
+		}
+		prom.SetDBTableStats(s.Name, s.TotalBytes, s.LiveTuples, s.DeadTuples)
+	}
+	return stats, nil
+}
+
+// vacuumCandidates returns the configured tables whose share of dead tuples is at least ratio,
+// most dead tuples first.
+func vacuumCandidates(stats []TableStat, tables []string, ratio float64) []string {
+	configured := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		configured[t] = true
+	}
+	var candidates []TableStat
+	for _, s := range stats {
+		if configured[s.Name] && s.DeadTuples >= minDeadTuples && s.DeadTupleRatio >= ratio {
+			candidates = append(candidates, s)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].DeadTuples > candidates[j].DeadTuples
+	})
+	names := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func vacuumAnalyze(ctx context.Context, table string) error {
+	// VACUUM can't run in a transaction, so this must not use one.
+	_, err := db.Bun().ExecContext(ctx, "VACUUM (ANALYZE) ?", bun.Ident(table))
+	return err
+}
+
+func rowsAffected(res sql.Result, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// deleteRolledBackMetrics deletes up to limit metrics that a trial restart rolled back, and so
+// archived, before cutoff.
+// Validations that runs still point at are kept.
+func deleteRolledBackMetrics(ctx context.Context, cutoff time.Time, limit int) (int64, error) {
+	return rowsAffected(db.Bun().NewRaw(`
+DELETE FROM metrics
+WHERE (partition_type, id) IN (
+	SELECT partition_type, id
+	FROM metrics
+	WHERE archived
+	AND end_time < ?
+	AND id NOT IN (
+		SELECT best_validation_id FROM runs WHERE best_validation_id IS NOT NULL
+		UNION
+		SELECT latest_validation_id FROM runs WHERE latest_validation_id IS NOT NULL
+	)
+	LIMIT ?
+)`, cutoff, limit).Exec(ctx))
+}
+
+// deleteOrphanedTaskLogs deletes up to limit logs of trial tasks that ended before cutoff and no
+// longer belong to a trial, because their experiment was deleted.
+func deleteOrphanedTaskLogs(ctx context.Context, cutoff time.Time, limit int) (int64, error) {
+	return rowsAffected(db.Bun().NewRaw(`
+DELETE FROM task_logs
+WHERE id IN (
+	SELECT l.id
+	FROM task_logs l
+	JOIN tasks t ON t.task_id = l.task_id
+	WHERE t.task_type = 'TRIAL'
+	AND t.end_time < ?
+	AND NOT EXISTS (SELECT 1 FROM trial_id_task_id tt WHERE tt.task_id = t.task_id)
+	LIMIT ?
+)`, cutoff.UTC(), limit).Exec(ctx))
+}
+
+// deleteTerminalExperimentSnapshots deletes up to limit searcher snapshots of experiments that
+// will not run again, like DeleteSnapshotsForTerminalExperiments does at startup.
+func deleteTerminalExperimentSnapshots(ctx context.Context, limit int) (int64, error) {
+	return rowsAffected(db.Bun().NewRaw(`
+DELETE FROM experiment_snapshots
+WHERE experiment_id IN (
+	SELECT s.experiment_id
+	FROM experiment_snapshots s
+	JOIN experiments e ON e.id = s.experiment_id
+	WHERE e.state IN ('COMPLETED', 'CANCELED', 'ERROR')
+	LIMIT ?
+)`, limit).Exec(ctx))
+}
@@ -0,0 +1,443 @@
+package doctor
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/elastic"
+	"github.com/determined-ai/determined/master/pkg/check"
+	"github.com/determined-ai/determined/master/pkg/checkpoints"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/schemas"
+	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
+)
+
+const (
+	// certExpiryWarning is how long before a TLS certificate expires that it is reported.
+	certExpiryWarning = 30 * 24 * time.Hour
+	// heartbeatStaleAfter is how old the cluster heartbeat, which a running master updates every
+	// ten minutes, must be for the master to be considered down.
+	heartbeatStaleAfter = 20 * time.Minute
+	// storageProbeTimeout bounds each checkpoint storage probe.
+	storageProbeTimeout = time.Minute
+	// maxDetails is the most items a check lists before summarizing the rest.
+	maxDetails = 20
+)
+
+func truncateDetails(details []string) []string {
+	if len(details) <= maxDetails {
+		return details
+	}
+	return append(details[:maxDetails:maxDetails],
+		fmt.Sprintf("... and %d more", len(details)-maxDetails))
+}
+
+func checkConfig(conf *config.Config) Check {
+	c := Check{Name: "config", Status: StatusOK, Summary: "configuration is valid"}
+	if err := check.Validate(conf); err != nil {
+		c.Status = StatusFail
+		c.Summary = "configuration is invalid"
+		lines := strings.Split(err.Error(), "\n")
+		for _, line := range lines[1:] {
+			c.Details = append(c.Details, strings.TrimSpace(line))
+		}
+	}
+	for _, deprecation := range conf.Deprecations() {
+		if c.Status == StatusOK {
+			c.Status = StatusWarn
+			c.Summary = "configuration uses deprecated options"
+		}
+		c.Details = append(c.Details, deprecation.Error())
+	}
+	return c
+}
+
+func checkReservedPorts(conf *config.Config) Check {
+	c := Check{Name: "reserved ports", Status: StatusOK}
+	seen := make(map[int]bool, len(conf.ReservedPorts))
+	for _, port := range conf.ReservedPorts {
+		switch {
+		case port < 1 || port > 65535:
+			c.Status = StatusFail
+			c.Details = append(c.Details, fmt.Sprintf("%d is not a valid port", port))
+		case seen[port]:
+			if c.Status == StatusOK {
+				c.Status = StatusWarn
+			}
+			c.Details = append(c.Details, fmt.Sprintf("%d is listed more than once", port))
+		}
+		seen[port] = true
+	}
+	switch c.Status {
+	case StatusOK:
+		c.Summary = fmt.Sprintf("%d reserved ports", len(conf.ReservedPorts))
+	case StatusWarn:
+		c.Summary = "reserved_ports contains duplicates"
+	default:
+		c.Summary = "reserved_ports contains invalid ports"
+	}
+	return c
+}
+
+func checkTLS(tlsConf config.TLSConfig) Check {
+	c := Check{Name: "tls"}
+	if errs := tlsConf.Validate(); len(errs) > 0 {
+		c.Status = StatusFail
+		c.Summary = "TLS is misconfigured"
+		for _, err := range errs {
+			c.Details = append(c.Details, err.Error())
+		}
+		return c
+	}
+	if !tlsConf.Enabled() {
+		c.Status = StatusSkip
+		c.Summary = "TLS is not configured"
+		return c
+	}
+
+	cert, err := tlsConf.ReadCertificate()
+	if err != nil {
+		c.Status = StatusFail
+		c.Summary = "could not load the certificate and key"
+		c.Details = []string{err.Error()}
+		return c
+	}
+
+	c.Status = StatusOK
+	now := time.Now()
+	var leafExpiry time.Time
+	for i, der := range cert.Certificate {
+		x, err := x509.ParseCertificate(der)
+		if err != nil {
+			c.Status = StatusFail
+			c.Details = append(c.Details, fmt.Sprintf("certificate %d can't be parsed: %s", i, err))
+			continue
+		}
+		if i == 0 {
+			leafExpiry = x.NotAfter
+		}
+		subject := x.Subject.String()
+		switch {
+		case now.After(x.NotAfter):
+			c.Status = StatusFail
+			c.Details = append(c.Details, fmt.Sprintf("%q expired at %s", subject, x.NotAfter))
+		case now.Before(x.NotBefore):
+			c.Status = StatusFail
+			c.Details = append(c.Details,
+				fmt.Sprintf("%q is not valid until %s", subject, x.NotBefore))
+		case x.NotAfter.Sub(now) < certExpiryWarning:
+			if c.Status == StatusOK {
+				c.Status = StatusWarn
+			}
+			c.Details = append(c.Details, fmt.Sprintf("%q expires at %s", subject, x.NotAfter))
+		}
+	}
+	switch c.Status {
+	case StatusOK:
+		c.Summary = fmt.Sprintf("certificate is valid until %s", leafExpiry)
+	case StatusWarn:
+		c.Summary = "certificate expires soon"
+	default:
+		c.Summary = "certificate is not valid"
+	}
+	return c
+}
+
+func checkResourcePools(rc config.ResourceConfig) Check {
+	c := Check{Name: "resource pools", Status: StatusOK}
+	var errs []error
+	errs = append(errs, rc.Validate()...)
+	names := make(map[string]bool, len(rc.ResourcePools))
+	for _, pool := range rc.ResourcePools {
+		errs = append(errs, pool.Validate()...)
+		names[pool.PoolName] = true
+	}
+
+	var defaultPools map[string]string
+	isAgentRM := false
+	if rm := rc.ResourceManager; rm != nil {
+		switch {
+		case rm.AgentRM != nil:
+			isAgentRM = true
+			if !rm.AgentRM.NoDefaultResourcePools {
+				defaultPools = map[string]string{
+					"default_compute_resource_pool": rm.AgentRM.DefaultComputeResourcePool,
+					"default_aux_resource_pool":     rm.AgentRM.DefaultAuxResourcePool,
+				}
+			}
+		case rm.KubernetesRM != nil:
+			if !rm.KubernetesRM.NoDefaultResourcePools {
+				defaultPools = map[string]string{
+					"default_compute_resource_pool": rm.KubernetesRM.DefaultComputeResourcePool,
+					"default_aux_resource_pool":     rm.KubernetesRM.DefaultAuxResourcePool,
+				}
+			}
+		case rm.HPCRM() != nil:
+			if hpc := rm.HPCRM(); !hpc.NoDefaultResourcePools {
+				defaultPools = map[string]string{
+					"default_compute_resource_pool": hpc.DefaultComputeResourcePool,
+					"default_aux_resource_pool":     hpc.DefaultAuxResourcePool,
+				}
+			}
+		}
+	}
+	for _, field := range []string{"default_compute_resource_pool", "default_aux_resource_pool"} {
+		if pool, ok := defaultPools[field]; ok && !names[pool] {
+			errs = append(errs, fmt.Errorf("%s %q is not a configured resource pool", field, pool))
+		}
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			c.Status = StatusFail
+			c.Details = append(c.Details, err.Error())
+		}
+	}
+	if isAgentRM {
+		for _, pool := range rc.ResourcePools {
+			if pool.KubernetesNamespace != "" {
+				if c.Status == StatusOK {
+					c.Status = StatusWarn
+				}
+				c.Details = append(c.Details, fmt.Sprintf(
+					"resource pool %q sets kubernetes_namespace, which the agent resource manager ignores",
+					pool.PoolName))
+			}
+		}
+	}
+
+	switch c.Status {
+	case StatusOK:
+		c.Summary = fmt.Sprintf("%d resource pools", len(rc.ResourcePools))
+	case StatusWarn:
+		c.Summary = "resource pools have ignored settings"
+	default:
+		c.Summary = "resource pools are misconfigured"
+	}
+	return c
+}
+
+func checkElastic(conf *model.ElasticLoggingConfig) Check {
+	c := Check{Name: "elasticsearch"}
+	if conf == nil {
+		c.Status = StatusSkip
+		c.Summary = "elastic logging is not configured"
+		return c
+	}
+	info, err := elastic.Ping(*conf)
+	if err != nil {
+		c.Status = StatusFail
+		c.Summary = fmt.Sprintf("could not reach %s:%d", conf.Host, conf.Port)
+		c.Details = []string{err.Error()}
+		return c
+	}
+	c.Status = StatusOK
+	c.Summary = fmt.Sprintf("reached %s:%d", conf.Host, conf.Port)
+	c.Details = []string{info}
+	return c
+}
+
+func checkDatabase(ctx context.Context) Check {
+	c := Check{Name: "database"}
+	var version string
+	if err := db.Bun().NewRaw("SELECT version()").Scan(ctx, &version); err != nil {
+		c.Status = StatusFail
+		c.Summary = "connected, but could not query"
+		c.Details = []string{err.Error()}
+		return c
+	}
+	c.Status = StatusOK
+	c.Summary = "connected"
+	c.Details = []string{version}
+	return c
+}
+
+func checkMigrations(pgDB *db.PgDB, migrationURL string) Check {
+	c := Check{Name: "migrations"}
+	current, latest, err := pgDB.MigrationStatus(migrationURL)
+	switch {
+	case err != nil:
+		c.Status = StatusFail
+		c.Summary = "could not read migration status"
+		c.Details = []string{err.Error()}
+	case current == latest:
+		c.Status = StatusOK
+		c.Summary = fmt.Sprintf("database is at the latest version %d", current)
+	case current < latest:
+		c.Status = StatusWarn
+		c.Summary = fmt.Sprintf("database is at version %d but this master ships %d; "+
+			"migrations will run when the master next starts", current, latest)
+	default:
+		c.Status = StatusFail
+		c.Summary = fmt.Sprintf("database is at version %d, newer than this master's latest %d; "+
+			"the master was downgraded", current, latest)
+	}
+	return c
+}
+
+func checkBindings(ctx context.Context, pools []config.ResourcePoolConfig) Check {
+	c := Check{Name: "resource pool bindings"}
+	bindings, err := db.GetAllBindings(ctx)
+	if err != nil {
+		c.Status = StatusFail
+		c.Summary = "could not read bindings"
+		c.Details = []string{err.Error()}
+		return c
+	}
+	names := make(map[string]bool, len(pools))
+	for _, pool := range pools {
+		names[pool.PoolName] = true
+	}
+	for _, b := range bindings {
+		if !names[b.PoolName] {
+			c.Details = append(c.Details, fmt.Sprintf(
+				"workspace %d is bound to unknown resource pool %q", b.WorkspaceID, b.PoolName))
+		}
+	}
+	if len(c.Details) > 0 {
+		c.Status = StatusWarn
+		c.Summary = fmt.Sprintf("%d bindings reference resource pools missing from the config",
+			len(c.Details))
+		c.Details = truncateDetails(c.Details)
+		return c
+	}
+	c.Status = StatusOK
+	c.Summary = fmt.Sprintf("%d bindings", len(bindings))
+	return c
+}
+
+// checkCheckpointStorage probes the master's checkpoint storage and the effective storage of every
+// workspace that overrides it.
+func checkCheckpointStorage(ctx context.Context, masterStorage expconf.CheckpointStorageConfig) []Check {
+	checks := []Check{probeStorage(ctx, "checkpoint storage", schemas.WithDefaults(masterStorage))}
+
+	var workspaces []model.Workspace
+	if err := db.Bun().NewSelect().Model(&workspaces).
+		Column("id", "name", "checkpoint_storage_config").
+		Where("checkpoint_storage_config IS NOT NULL").
+		Order("id").
+		Scan(ctx); err != nil {
+		return append(checks, Check{
+			Name:    "checkpoint storage (workspaces)",
+			Status:  StatusFail,
+			Summary: "could not read workspace overrides",
+			Details: []string{err.Error()},
+		})
+	}
+	for _, w := range workspaces {
+		// Experiments use the workspace's storage merged over the master's.
+		storage := schemas.WithDefaults(*schemas.Merge(w.CheckpointStorageConfig, &masterStorage))
+		checks = append(checks,
+			probeStorage(ctx, fmt.Sprintf("checkpoint storage (workspace %q)", w.Name), storage))
+	}
+	return checks
+}
+
+func probeStorage(ctx context.Context, name string, storage expconf.CheckpointStorageConfig) Check {
+	c := Check{Name: name}
+	ctx, cancel := context.WithTimeout(ctx, storageProbeTimeout)
+	defer cancel()
+	err := checkpoints.ProbeStorage(ctx, &storage)
+	switch {
+	case errors.Is(err, checkpoints.ErrProbeUnsupported):
+		c.Status = StatusSkip
+		c.Summary = "the master can't access this storage type"
+		c.Details = []string{err.Error()}
+	case err != nil:
+		c.Status = StatusFail
+		c.Summary = "could not write, read back and delete a test object"
+		c.Details = []string{err.Error()}
+	default:
+		c.Status = StatusOK
+		c.Summary = "read/write access verified"
+	}
+	return c
+}
+
+func masterIsDown(ctx context.Context) (bool, *time.Time, error) {
+	heartbeat, err := db.ClusterHeartbeat(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+	return heartbeat == nil || time.Since(*heartbeat) > heartbeatStaleAfter, heartbeat, nil
+}
+
+func checkStaleExperiments(ctx context.Context) Check {
+	c := Check{Name: "stale experiments"}
+	exps, err := db.StaleExperiments(ctx)
+	if err != nil {
+		c.Status = StatusFail
+		c.Summary = "could not query experiments"
+		c.Details = []string{err.Error()}
+		return c
+	}
+	if len(exps) == 0 {
+		c.Status = StatusOK
+		c.Summary = "no experiments are stuck in a non-terminal state"
+		return c
+	}
+	c.Status = StatusWarn
+	c.Summary = fmt.Sprintf("%d experiments are in a non-terminal state with nothing running",
+		len(exps))
+	for _, e := range exps {
+		c.Details = append(c.Details, fmt.Sprintf("experiment %d is %s", e.ID, e.State))
+	}
+	c.Details = truncateDetails(c.Details)
+	return c
+}
+
+func checkStaleAllocations(ctx context.Context) Check {
+	c := Check{Name: "stale allocations"}
+	down, heartbeat, err := masterIsDown(ctx)
+	if err != nil {
+		c.Status = StatusFail
+		c.Summary = "could not read the cluster heartbeat"
+		c.Details = []string{err.Error()}
+		return c
+	}
+	allocs, err := db.OpenAllocations(ctx)
+	if err != nil {
+		c.Status = StatusFail
+		c.Summary = "could not query allocations"
+		c.Details = []string{err.Error()}
+		return c
+	}
+
+	// While the master runs, open allocations are expected unless their task already ended. Once
+	// it is down, every open allocation was left behind and is closed when the master next starts.
+	for _, a := range allocs {
+		switch {
+		case a.TaskEnded:
+			c.Details = append(c.Details,
+				fmt.Sprintf("allocation %s is open but task %s has ended", a.AllocationID, a.TaskID))
+		case down:
+			started := "never started"
+			if a.StartTime != nil {
+				started = fmt.Sprintf("started %s", a.StartTime)
+			}
+			c.Details = append(c.Details, fmt.Sprintf("allocation %s %s", a.AllocationID, started))
+		}
+	}
+	if len(c.Details) == 0 {
+		c.Status = StatusOK
+		c.Summary = fmt.Sprintf("%d open allocations, none stale", len(allocs))
+		return c
+	}
+	c.Status = StatusWarn
+	c.Summary = fmt.Sprintf("%d allocations were left open", len(c.Details))
+	if down {
+		last := "never"
+		if heartbeat != nil {
+			last = heartbeat.String()
+		}
+		c.Summary += fmt.Sprintf(" (last master heartbeat: %s)", last)
+	}
+	c.Details = truncateDetails(c.Details)
+	return c
+}
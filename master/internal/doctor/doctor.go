@@ -0,0 +1,127 @@
+// Package doctor runs offline health checks against a master configuration and the cluster state
+// it points at, for users to attach to support requests.
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/internal/db"
+)
+
+// Status is the outcome of a single check.
+type Status string
+
+const (
+	// StatusOK means the check passed.
+	StatusOK Status = "ok"
+	// StatusWarn means the check found something that may need attention.
+	StatusWarn Status = "warn"
+	// StatusFail means the check found something that will break the cluster.
+	StatusFail Status = "fail"
+	// StatusSkip means the check did not apply or could not run.
+	StatusSkip Status = "skip"
+)
+
+// Check is the result of a single check.
+type Check struct {
+	Name    string   `json:"name"`
+	Status  Status   `json:"status"`
+	Summary string   `json:"summary"`
+	Details []string `json:"details,omitempty"`
+}
+
+// Report is the result of every check, in the order they ran.
+type Report struct {
+	Checks []Check `json:"checks"`
+}
+
+// Failed returns whether any check failed.
+func (r Report) Failed() bool {
+	for _, c := range r.Checks {
+		if c.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText writes the report in a human-readable format.
+func (r Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+	counts := map[Status]int{}
+	for _, c := range r.Checks {
+		counts[c.Status]++
+		fmt.Fprintf(&b, "[%-4s] %s: %s\n", strings.ToUpper(string(c.Status)), c.Name, c.Summary)
+		for _, d := range c.Details {
+			fmt.Fprintf(&b, "       - %s\n", d)
+		}
+	}
+	fmt.Fprintf(&b, "\n%d ok, %d warnings, %d failures, %d skipped\n",
+		counts[StatusOK], counts[StatusWarn], counts[StatusFail], counts[StatusSkip])
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes the report as JSON.
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// Run runs every check against the given master configuration. Checks that need the database are
+// skipped if it can't be reached. Run never modifies the database; in particular, it does not
+// apply migrations.
+func Run(ctx context.Context, conf *config.Config) Report {
+	var r Report
+	r.Checks = append(r.Checks,
+		checkConfig(conf),
+		checkReservedPorts(conf),
+		checkTLS(conf.Security.TLS),
+		checkResourcePools(conf.ResourceConfig),
+		checkElastic(conf.Logging.ElasticLoggingConfig),
+	)
+
+	pgDB, err := db.Connect(&conf.DB)
+	if err != nil {
+		r.Checks = append(r.Checks, Check{
+			Name:    "database",
+			Status:  StatusFail,
+			Summary: "could not connect",
+			Details: []string{err.Error()},
+		})
+		for _, name := range []string{
+			"migrations", "resource pool bindings", "checkpoint storage",
+			"stale experiments", "stale allocations",
+		} {
+			r.Checks = append(r.Checks, Check{
+				Name: name, Status: StatusSkip, Summary: "database is unreachable",
+			})
+		}
+		return r
+	}
+	defer func() {
+		if err := pgDB.Close(); err != nil {
+			log.WithError(err).Error("error closing pg connection")
+		}
+	}()
+
+	r.Checks = append(r.Checks,
+		checkDatabase(ctx),
+		checkMigrations(pgDB, conf.DB.Migrations),
+		checkBindings(ctx, conf.ResourcePools),
+	)
+	r.Checks = append(r.Checks, checkCheckpointStorage(ctx, conf.CheckpointStorage)...)
+	r.Checks = append(r.Checks,
+		checkStaleExperiments(ctx),
+		checkStaleAllocations(ctx),
+	)
+	return r
+}
@@ -0,0 +1,160 @@
+package doctor
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/pkg/checkpoints/local"
+)
+
+func writeCert(t *testing.T, notBefore, notAfter time.Time) config.TLSConfig {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "master"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	conf := config.TLSConfig{
+		Cert: filepath.Join(dir, "cert.pem"),
+		Key:  filepath.Join(dir, "key.pem"),
+	}
+	require.NoError(t, os.WriteFile(conf.Cert,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(conf.Key,
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return conf
+}
+
+func TestCheckTLS(t *testing.T) {
+	now := time.Now()
+	day := 24 * time.Hour
+
+	require.Equal(t, StatusSkip, checkTLS(config.TLSConfig{}).Status)
+	require.Equal(t, StatusFail, checkTLS(config.TLSConfig{Cert: "cert.pem"}).Status)
+	require.Equal(t, StatusFail,
+		checkTLS(config.TLSConfig{Cert: "missing.pem", Key: "missing.pem"}).Status)
+
+	require.Equal(t, StatusOK, checkTLS(writeCert(t, now.Add(-day), now.Add(365*day))).Status)
+	require.Equal(t, StatusWarn, checkTLS(writeCert(t, now.Add(-day), now.Add(7*day))).Status)
+	require.Equal(t, StatusFail, checkTLS(writeCert(t, now.Add(-2*day), now.Add(-day))).Status)
+	require.Equal(t, StatusFail, checkTLS(writeCert(t, now.Add(day), now.Add(2*day))).Status)
+}
+
+func TestCheckReservedPorts(t *testing.T) {
+	cases := []struct {
+		name   string
+		ports  []int
+		status Status
+	}{
+		{"none", nil, StatusOK},
+		{"valid", []int{8080, 8081}, StatusOK},
+		{"duplicate", []int{8080, 8080}, StatusWarn},
+		{"invalid", []int{8080, 8080, 70000}, StatusFail},
+		{"zero", []int{0}, StatusFail},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := checkReservedPorts(&config.Config{ReservedPorts: tc.ports})
+			require.Equal(t, tc.status, c.Status, c.Details)
+		})
+	}
+}
+
+func TestCheckResourcePools(t *testing.T) {
+	pool := func(name string) config.ResourcePoolConfig {
+		return config.ResourcePoolConfig{PoolName: name, MaxAuxContainersPerAgent: 100}
+	}
+	agentRM := func(compute, aux string) *config.ResourceManagerConfig {
+		return &config.ResourceManagerConfig{AgentRM: &config.AgentResourceManagerConfig{
+			DefaultComputeResourcePool: compute,
+			DefaultAuxResourcePool:     aux,
+		}}
+	}
+
+	c := checkResourcePools(config.ResourceConfig{
+		ResourceManager: agentRM("gpu", "cpu"),
+		ResourcePools:   []config.ResourcePoolConfig{pool("gpu"), pool("cpu")},
+	})
+	require.Equal(t, StatusOK, c.Status, c.Details)
+
+	c = checkResourcePools(config.ResourceConfig{
+		ResourceManager: agentRM("gpu", "cpu"),
+		ResourcePools:   []config.ResourcePoolConfig{pool("gpu"), pool("gpu")},
+	})
+	require.Equal(t, StatusFail, c.Status)
+	require.Len(t, c.Details, 2, "duplicate pool and missing default aux pool")
+
+	k8sPool := pool("cpu")
+	k8sPool.KubernetesNamespace = "team-a"
+	c = checkResourcePools(config.ResourceConfig{
+		ResourceManager: agentRM("cpu", "cpu"),
+		ResourcePools:   []config.ResourcePoolConfig{k8sPool},
+	})
+	require.Equal(t, StatusWarn, c.Status, c.Details)
+}
+
+func TestProbeLocal(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, local.Probe(dir, "probe", []byte("data")))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries, "the probe file is cleaned up")
+
+	require.Error(t, local.Probe(filepath.Join(dir, "missing"), "probe", []byte("data")))
+}
+
+func TestReportOutput(t *testing.T) {
+	r := Report{Checks: []Check{
+		{Name: "config", Status: StatusOK, Summary: "configuration is valid"},
+		{Name: "tls", Status: StatusFail, Summary: "certificate is not valid", Details: []string{"expired"}},
+		{Name: "elasticsearch", Status: StatusSkip, Summary: "elastic logging is not configured"},
+	}}
+	require.True(t, r.Failed())
+	require.False(t, Report{Checks: r.Checks[:1]}.Failed())
+
+	var text bytes.Buffer
+	require.NoError(t, r.WriteText(&text))
+	require.Equal(t, `[OK  ] config: configuration is valid
+[FAIL] tls: certificate is not valid
+       - expired
+[SKIP] elasticsearch: elastic logging is not configured
+
+1 ok, 0 warnings, 1 failures, 1 skipped
+`, text.String())
+
+	var out bytes.Buffer
+	require.NoError(t, r.WriteJSON(&out))
+	var decoded Report
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	require.Equal(t, r, decoded)
+}
+
+func TestTruncateDetails(t *testing.T) {
+	details := make([]string, maxDetails+5)
+	truncated := truncateDetails(details)
+	require.Len(t, truncated, maxDetails+1)
+	require.Equal(t, "... and 5 more", truncated[maxDetails])
+	require.Len(t, truncateDetails(details[:3]), 3)
+}
@@ -0,0 +1,69 @@
+// Package egress resolves the egress policies that restrict the network traffic of the containers
+// of tasks. Policies are set on workspaces, or on the master for workspaces without one, and are
+// enforced by the resource managers when they create containers.
+package egress
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// Get returns the egress policy of a workspace, or nil if it has none.
+func Get(ctx context.Context, workspaceID int) (*model.EgressPolicy, error) {
+	w := &model.Workspace{}
+	err := db.Bun().NewSelect().Model(w).Column("egress_policy").
+		Where("id = ?", workspaceID).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting egress policy of workspace %d: %w",
+			workspaceID, db.MatchSentinelError(err))
+	}
+	return w.EgressPolicy, nil
+}
+
+// Set sets the egress policy of a workspace; nil removes it.
+func Set(ctx context.Context, workspaceID int, p *model.EgressPolicy) error {
+	res, err := db.Bun().NewUpdate().Model(&model.Workspace{ID: workspaceID, EgressPolicy: p}).
+		Column("egress_policy").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("setting egress policy of workspace %d: %w", workspaceID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("setting egress policy of workspace %d: %w", workspaceID, db.ErrNotFound)
+	}
+	return nil
+}
+
+// Resolve returns the egress rule of a task, from the policy of its workspace or else the policy of
+// the master, or nil if the traffic of its containers isn't restricted.
+func Resolve(
+	ctx context.Context, workspaceID int, taskType model.TaskType,
+) (*model.EgressRule, error) {
+	policy := config.GetMasterConfig().EgressPolicy
+	if workspaceID != 0 {
+		p, err := Get(ctx, workspaceID)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			policy = p
+		}
+	}
+	if policy == nil {
+		return nil, nil
+	}
+
+	rule := policy.ForTaskType(taskType)
+	if !rule.Restricted() {
+		return nil, nil
+	}
+	return &rule, nil
+}
@@ -23,6 +23,49 @@ type Elastic struct {
 
 // Setup sets up a new elasticsearch client with the given configuration.
 func Setup(conf model.ElasticLoggingConfig) (*Elastic, error) {
+	es, err := newClient(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	// Try to connect to elastic - we'd rather fail hard here than on first log write.
+	numTries := 0
+	for {
+		i, err := es.Info()
+		if err == nil {
+			log.Infof("connected to elasticsearch cluster with info: %s", i.String())
+			return &Elastic{es}, nil
+		}
+		numTries++
+		// Elastic can take a really long time to come up and we'd rather not fail integrations on this.
+		if numTries >= 45 {
+			return nil, errors.Wrapf(err, "could not connect to elastic after %v tries", numTries)
+		}
+		toWait := 4 * time.Second
+		time.Sleep(toWait)
+		log.WithError(err).Warnf("failed to connect to elastic, trying again in %s", toWait)
+	}
+}
+
+// Ping makes a single attempt to reach the elasticsearch cluster with the given configuration
+// and returns the cluster info.
+func Ping(conf model.ElasticLoggingConfig) (string, error) {
+	es, err := newClient(conf)
+	if err != nil {
+		return "", err
+	}
+	res, err := es.Info()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to reach elastic")
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return "", errors.Errorf("elastic returned %s", res.Status())
+	}
+	return res.String(), nil
+}
+
+func newClient(conf model.ElasticLoggingConfig) (*elasticsearch.Client, error) {
 	tlsCfg, err := elasticTLSConfig(conf.Security.TLS)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to make elastic tls config")
@@ -53,24 +96,7 @@ func Setup(conf model.ElasticLoggingConfig) (*Elastic, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create elastic client from config")
 	}
-
-	// Try to connect to elastic - we'd rather fail hard here than on first log write.
-	numTries := 0
-	for {
-		i, err := es.Info()
-		if err == nil {
-			log.Infof("connected to elasticsearch cluster with info: %s", i.String())
-			return &Elastic{es}, nil
-		}
-		numTries++
-		// Elastic can take a really long time to come up and we'd rather not fail integrations on this.
-		if numTries >= 45 {
-			return nil, errors.Wrapf(err, "could not connect to elastic after %v tries", numTries)
-		}
-		toWait := 4 * time.Second
-		time.Sleep(toWait)
-		log.WithError(err).Warnf("failed to connect to elastic, trying again in %s", toWait)
-	}
+	return es, nil
 }
 
 func elasticTLSConfig(conf model.TLSClientConfig) (*tls.Config, error) {
@@ -550,6 +550,7 @@ func (e *internalExperiment) PerformSearcherOperations(msg *apiv1.PostSearcherOp
 	e.syslog.Infof("processing searcher operations %+v", ops)
 
 	// Remove newly processed events from queue.
+	queue.Ack(msg.TriggeredByEvent.Id)
 	if err := queue.RemoveUpTo(int(msg.TriggeredByEvent.Id)); err != nil {
 		return status.Error(codes.Internal, "failed to remove events from queue")
 	}
@@ -558,7 +559,7 @@ func (e *internalExperiment) PerformSearcherOperations(msg *apiv1.PostSearcherOp
 	return nil
 }
 
-func (e *internalExperiment) GetSearcherEventsWatcher() (*searcher.EventsWatcher, error) {
+func (e *internalExperiment) GetSearcherEventsWatcher(afterID int32) (*searcher.EventsWatcher, error) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
@@ -566,10 +567,52 @@ func (e *internalExperiment) GetSearcherEventsWatcher() (*searcher.EventsWatcher
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
-	watcher, err := queue.Watch()
+	watcher, err := queue.WatchAfter(afterID)
 	return &watcher, err
 }
 
+// StreamSearcherEvents returns a watcher that receives every searcher event after the given one
+// until it is unwatched.
+func (e *internalExperiment) StreamSearcherEvents(afterID int32) (*searcher.EventsWatcher, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	queue, err := e.searcher.GetCustomSearcherEventQueue()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	watcher, err := queue.Stream(afterID)
+	return &watcher, err
+}
+
+// AckSearcherEvents records that the custom searcher processed every event up to and including the
+// one with the given ID, so that it resumes after it even across master restarts.
+func (e *internalExperiment) AckSearcherEvents(eventID int32) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	queue, err := e.searcher.GetCustomSearcherEventQueue()
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if queue.Ack(eventID) {
+		e.snapshotAndSave()
+	}
+	return nil
+}
+
+// LastAckedSearcherEvent returns the ID of the last event the custom searcher acknowledged.
+func (e *internalExperiment) LastAckedSearcherEvent() (int32, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	queue, err := e.searcher.GetCustomSearcherEventQueue()
+	if err != nil {
+		return 0, status.Error(codes.Internal, err.Error())
+	}
+	return queue.LastAck(), nil
+}
+
 func (e *internalExperiment) UnwatchEvents(id uuid.UUID) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -75,6 +75,7 @@ func nonTerminalExperiments(
 func queryBulkExperiments(query *bun.SelectQuery,
 	filters *apiv1.BulkExperimentFilters,
 ) *bun.SelectQuery {
+	query = query.Where("e.trashed_at IS NULL")
 	if len(filters.ExcludedExperimentIds) > 0 {
 		query = query.Where("e.id NOT IN (?)", bun.In(filters.ExcludedExperimentIds))
 	}
@@ -361,16 +362,136 @@ func DeleteExperiments(ctx context.Context,
 		return nil, nil, err
 	}
 
+	results, validIDs, err := deletableExperiments(ctx, curUser, experimentIds, filters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var acceptedExperiments []*model.Experiment
+	if len(validIDs) > 0 {
+		_, err = db.Bun().NewUpdate().
+			ModelTableExpr("experiments as e").
+			Set("state = ?", model.DeletingState).
+			Where("id IN (?)", bun.In(validIDs)).
+			Returning(`id, state, config, start_time, end_time, archived,
+				   owner_id, notes, job_id, '' as username, project_id`).
+			Model(&acceptedExperiments).
+			Exec(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, exp := range acceptedExperiments {
+			results = append(results, ExperimentActionResult{
+				Error: nil,
+				ID:    int32(exp.ID),
+			})
+		}
+	}
+	return results, acceptedExperiments, nil
+}
+
+// TrashExperiments moves one or many experiments to the trash instead of deleting them. Trashed
+// experiments are hidden from listings and can be restored until the trash is emptied.
+func TrashExperiments(ctx context.Context,
+	experimentIds []int32, filters *apiv1.BulkExperimentFilters,
+) ([]ExperimentActionResult, error) {
+	curUser, _, err := grpcutil.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results, validIDs, err := deletableExperiments(ctx, curUser, experimentIds, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(validIDs) > 0 {
+		var trashedIDs []int32
+		_, err = db.Bun().NewUpdate().
+			Table("experiments").
+			Set("trashed_at = now()").
+			Where("id IN (?)", bun.In(validIDs)).
+			Where("trashed_at IS NULL").
+			Returning("id").
+			Exec(ctx, &trashedIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range validIDs {
+			if !slices.Contains(trashedIDs, id) {
+				results = append(results, ExperimentActionResult{
+					Error: status.Errorf(codes.FailedPrecondition, "experiment is already in the trash"),
+					ID:    id,
+				})
+				continue
+			}
+			results = append(results, ExperimentActionResult{Error: nil, ID: id})
+		}
+	}
+	return results, nil
+}
+
+// RestoreExperiments takes one or many experiments out of the trash.
+func RestoreExperiments(ctx context.Context,
+	curUser model.User, experimentIds []int32,
+) ([]ExperimentActionResult, error) {
+	var visibleIDs []int32
+	query := db.Bun().NewSelect().
+		ModelTableExpr("experiments as e").
+		Column("e.id").
+		Join("JOIN projects p ON e.project_id = p.id").
+		Where("e.id IN (?)", bun.In(experimentIds)).
+		Where("e.trashed_at IS NOT NULL")
+	query, err := AuthZProvider.Get().
+		FilterExperimentsQuery(ctx, curUser, nil, query,
+			[]rbacv1.PermissionType{rbacv1.PermissionType_PERMISSION_TYPE_DELETE_EXPERIMENT})
+	if err != nil {
+		return nil, err
+	}
+	if err = query.Scan(ctx, &visibleIDs); err != nil {
+		return nil, err
+	}
+
+	var results []ExperimentActionResult
+	for _, originalID := range experimentIds {
+		if !slices.Contains(visibleIDs, originalID) {
+			results = append(results, ExperimentActionResult{
+				Error: api.NotFoundErrs("trashed experiment", fmt.Sprint(originalID), true),
+				ID:    originalID,
+			})
+		}
+	}
+	if len(visibleIDs) > 0 {
+		if _, err = db.Bun().NewUpdate().
+			Table("experiments").
+			Set("trashed_at = NULL").
+			Where("id IN (?)", bun.In(visibleIDs)).
+			Exec(ctx); err != nil {
+			return nil, err
+		}
+		for _, id := range visibleIDs {
+			results = append(results, ExperimentActionResult{Error: nil, ID: id})
+		}
+	}
+	return results, nil
+}
+
+// deletableExperiments checks which of the requested experiments the user may delete and that
+// are in a state that allows deletion. It returns per-experiment errors for the rest.
+func deletableExperiments(ctx context.Context,
+	curUser *model.User, experimentIds []int32, filters *apiv1.BulkExperimentFilters,
+) ([]ExperimentActionResult, []int32, error) {
 	var expChecks []deleteExperimentOKResult
 	query := db.Bun().NewSelect().
 		ModelTableExpr("experiments as e").
 		Model(&expChecks).
 		Column("e.id").
 		ColumnExpr(ProtoStateDBCaseString(experimentv1.State_value, "e.state", "state", "STATE_")).
-		ColumnExpr("COUNT(model_versions.id) AS versions").
+		ColumnExpr("COUNT(rc.model_version_id) AS versions").
 		Join("JOIN projects p ON e.project_id = p.id").
 		Join("LEFT JOIN checkpoints_view c ON c.experiment_id = e.id").
-		Join("LEFT JOIN model_versions ON model_versions.checkpoint_uuid = c.uuid").
+		Join("LEFT JOIN registered_checkpoints rc ON rc.checkpoint_uuid = c.uuid").
 		Group("e.id")
 
 	if filters == nil {
@@ -380,7 +501,7 @@ func DeleteExperiments(ctx context.Context,
 			Where("e.state IN (?)", bun.In(model.StatesToStrings(model.TerminalStates)))
 	}
 
-	query, err = AuthZProvider.Get().
+	query, err := AuthZProvider.Get().
 		FilterExperimentsQuery(ctx, *curUser, nil, query,
 			[]rbacv1.PermissionType{rbacv1.PermissionType_PERMISSION_TYPE_DELETE_EXPERIMENT})
 	if err != nil {
@@ -422,29 +543,7 @@ func DeleteExperiments(ctx context.Context,
 			}
 		}
 	}
-
-	var acceptedExperiments []*model.Experiment
-	if len(validIDs) > 0 {
-		_, err = db.Bun().NewUpdate().
-			ModelTableExpr("experiments as e").
-			Set("state = ?", model.DeletingState).
-			Where("id IN (?)", bun.In(validIDs)).
-			Returning(`id, state, config, start_time, end_time, archived,
-				   owner_id, notes, job_id, '' as username, project_id`).
-			Model(&acceptedExperiments).
-			Exec(ctx)
-		if err != nil {
-			return nil, nil, err
-		}
-
-		for _, exp := range acceptedExperiments {
-			results = append(results, ExperimentActionResult{
-				Error: nil,
-				ID:    int32(exp.ID),
-			})
-		}
-	}
-	return results, acceptedExperiments, nil
+	return results, validIDs, nil
 }
 
 // ArchiveExperiments works on one or many experiments.
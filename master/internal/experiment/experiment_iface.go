@@ -67,8 +67,11 @@ type Experiment interface {
 	SetGroupWeight(weight float64) error
 	SetGroupPriority(priority int) error
 	PerformSearcherOperations(msg *apiv1.PostSearcherOperationsRequest) error
-	GetSearcherEventsWatcher() (*searcher.EventsWatcher, error)
+	GetSearcherEventsWatcher(afterID int32) (*searcher.EventsWatcher, error)
+	StreamSearcherEvents(afterID int32) (*searcher.EventsWatcher, error)
 	UnwatchEvents(id uuid.UUID) error
+	AckSearcherEvents(eventID int32) error
+	LastAckedSearcherEvent() (int32, error)
 	ActivateExperiment() error
 	PauseExperiment() error
 	CancelExperiment() error
@@ -32,6 +32,21 @@ const (
 	metricIDValidation    string = "validation"
 )
 
+// Filter locations and column types for typed experiment and trial metadata. They are not part
+// of the projectv1 enums, so they are only accepted as strings in JSON filters.
+const (
+	locationExperimentMetadata = "LOCATION_TYPE_EXPERIMENT_METADATA"
+	locationTrialMetadata      = "LOCATION_TYPE_TRIAL_METADATA"
+	columnTypeBoolean          = "COLUMN_TYPE_BOOLEAN"
+)
+
+// locationProfiler filters experiments by the summary of their profiler metrics. Like the
+// metadata locations, it is only accepted as a string in JSON filters.
+const locationProfiler = "LOCATION_TYPE_PROFILER"
+
+// profilerBottlenecksColumn is the profiler filter column holding the flagged bottlenecks.
+const profilerBottlenecksColumn = "bottlenecks"
+
 var metricIDTemplate = regexp.MustCompile(
 	`(?P<group>[[:print:]]+?)\.(?P<name>[[:print:]]+)\.(?P<qualifier>min|max|mean|last)`)
 
@@ -132,6 +147,162 @@ func expColumnNameToSQL(columnName string) (string, error) {
 	return col, nil
 }
 
+func profilerColumnNameToSQL(columnName string) (string, error) {
+	// To prevent SQL injection this function should never
+	// return a user generated field name
+	profilerColMap := map[string]string{
+		"gpuUtilMean":             "gpu_util_mean",
+		"gpuUtilP95":              "gpu_util_p95",
+		"cpuUtilMean":             "cpu_util_mean",
+		"dataloaderFraction":      "dataloader_fraction",
+		"minFreeHostMemoryGb":     "min_free_host_memory_gb",
+		"samplesPerSecondPerSlot": "samples_per_second_per_slot",
+	}
+	col, exists := profilerColMap[columnName]
+	if !exists {
+		return "", fmt.Errorf("invalid profiler column %s", columnName)
+	}
+	return fmt.Sprintf(
+		"(SELECT ps.%s FROM experiment_profiler_summaries ps WHERE ps.experiment_id = e.id)", col,
+	), nil
+}
+
+func profilerToSQL(columnName string, filterValue *interface{}, op *operator, q *bun.SelectQuery,
+	fc *filterConjunction,
+) (*bun.SelectQuery, error) {
+	var queryArgs []interface{}
+	var queryString string
+	if columnName == profilerBottlenecksColumn {
+		const summaries = "SELECT 1 FROM experiment_profiler_summaries ps WHERE ps.experiment_id = e.id"
+		const hasBottleneck = "EXISTS (" + summaries + " AND ? = ANY(ps.bottlenecks))"
+		const hasAnyBottleneck = "EXISTS (" + summaries + " AND cardinality(ps.bottlenecks) > 0)"
+		switch *op {
+		case equal, contains:
+			queryString = hasBottleneck
+			queryArgs = append(queryArgs, *filterValue)
+		case notEqual, doesNotContain:
+			queryString = "NOT " + hasBottleneck
+			queryArgs = append(queryArgs, *filterValue)
+		case empty:
+			queryString = "NOT " + hasAnyBottleneck
+		case notEmpty:
+			queryString = hasAnyBottleneck
+		default:
+			return nil, fmt.Errorf("invalid operator %v for profiler bottlenecks", *op)
+		}
+	} else {
+		col, err := profilerColumnNameToSQL(columnName)
+		if err != nil {
+			return nil, err
+		}
+		oSQL, err := op.toSQL()
+		if err != nil {
+			return nil, err
+		}
+		switch *op {
+		case contains, doesNotContain:
+			return nil, fmt.Errorf("invalid operator %v for profiler column %s", *op, columnName)
+		case empty, notEmpty:
+			queryString = "? ?"
+			queryArgs = append(queryArgs, bun.Safe(col), bun.Safe(oSQL))
+		default:
+			queryString = "? ? ?"
+			queryArgs = append(queryArgs, bun.Safe(col), bun.Safe(oSQL), *filterValue)
+		}
+	}
+	if fc != nil && *fc == or {
+		return q.WhereOr(queryString, queryArgs...), nil
+	}
+	return q.Where(queryString, queryArgs...), nil
+}
+
+// metadataTableToSQL returns the table holding metadata for the given location and the condition
+// joining it to the experiment search query. Trial metadata joins every trial of the experiment.
+func metadataTableToSQL(location string) (string, string) {
+	if location == locationTrialMetadata {
+		return "trial_metadata", "m.trial_id IN (SELECT mt.id FROM trials mt WHERE mt.experiment_id = e.id)"
+	}
+	return "experiment_metadata", "m.experiment_id = e.id"
+}
+
+// metadataValueColumn picks the typed value column to compare against, from the filter's column
+// type if it is given and from the JSON type of the value otherwise.
+func metadataValueColumn(filterColumnType *string, filterValue *interface{}) (string, error) {
+	if filterColumnType != nil {
+		switch *filterColumnType {
+		case projectv1.ColumnType_COLUMN_TYPE_TEXT.String():
+			return "string_value", nil
+		case projectv1.ColumnType_COLUMN_TYPE_NUMBER.String():
+			return "number_value", nil
+		case projectv1.ColumnType_COLUMN_TYPE_DATE.String():
+			return "timestamp_value", nil
+		case columnTypeBoolean:
+			return "bool_value", nil
+		}
+	}
+	if filterValue == nil {
+		return "", fmt.Errorf("metadata filter needs a column type or a value")
+	}
+	switch (*filterValue).(type) {
+	case string:
+		return "string_value", nil
+	case float64:
+		return "number_value", nil
+	case bool:
+		return "bool_value", nil
+	default:
+		return "", fmt.Errorf("invalid metadata filter value %v", *filterValue)
+	}
+}
+
+// metadataToSQL matches experiments with metadata satisfying the filter; for trial metadata,
+// experiments with any trial whose metadata satisfies it.
+func metadataToSQL(location string, key string, filterColumnType *string,
+	filterValue *interface{}, op *operator, q *bun.SelectQuery, fc *filterConjunction,
+) (*bun.SelectQuery, error) {
+	table, join := metadataTableToSQL(location)
+	queryArgs := []interface{}{key}
+	queryString := fmt.Sprintf("EXISTS (SELECT 1 FROM %s m WHERE %s AND m.key = ?", table, join)
+	switch *op {
+	case empty, notEmpty:
+		queryString += ")"
+		if *op == empty {
+			queryString = "NOT " + queryString
+		}
+	default:
+		if filterValue == nil {
+			return nil, fmt.Errorf("metadata field defined without value and without a valid operator")
+		}
+		valueCol, err := metadataValueColumn(filterColumnType, filterValue)
+		if err != nil {
+			return nil, err
+		}
+		if (*op == contains || *op == doesNotContain) && valueCol != "string_value" {
+			// Numbers, dates and booleans are matched by equality or ranges, not substrings.
+			return nil, fmt.Errorf("invalid operator %v for non-text metadata %s", *op, key)
+		}
+		switch *op {
+		case contains:
+			queryString += fmt.Sprintf(" AND m.%s ILIKE ?)", valueCol)
+			queryArgs = append(queryArgs, fmt.Sprintf("%%%s%%", *filterValue))
+		case doesNotContain:
+			queryString += fmt.Sprintf(" AND m.%s NOT ILIKE ?)", valueCol)
+			queryArgs = append(queryArgs, fmt.Sprintf("%%%s%%", *filterValue))
+		default:
+			oSQL, err := op.toSQL()
+			if err != nil {
+				return nil, err
+			}
+			queryString += fmt.Sprintf(" AND m.%s ? ?)", valueCol)
+			queryArgs = append(queryArgs, bun.Safe(oSQL), *filterValue)
+		}
+	}
+	if fc != nil && *fc == or {
+		return q.WhereOr(queryString, queryArgs...), nil
+	}
+	return q.Where(queryString, queryArgs...), nil
+}
+
 // nolint: lll
 func hpToSQL(c string, filterColumnType *string, filterValue *interface{},
 	op *operator, q *bun.SelectQuery,
@@ -420,6 +591,10 @@ func (e experimentFilter) toSQL(q *bun.SelectQuery,
 			}
 		case projectv1.LocationType_LOCATION_TYPE_HYPERPARAMETERS.String():
 			return hpToSQL(e.ColumnName, e.Type, e.Value, e.Operator, q, c)
+		case locationExperimentMetadata, locationTrialMetadata:
+			return metadataToSQL(location, e.ColumnName, e.Type, e.Value, e.Operator, q, c)
+		case locationProfiler:
+			return profilerToSQL(e.ColumnName, e.Value, e.Operator, q, c)
 		}
 	case group:
 		var co string
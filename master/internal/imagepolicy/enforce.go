@@ -0,0 +1,105 @@
+package imagepolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types/registry"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// ErrDenied is wrapped by the errors of images that aren't allowed to run.
+var ErrDenied = errors.New("image denied by policy")
+
+// Request is a set of images a task is submitted with.
+type Request struct {
+	WorkspaceID  int
+	Username     string
+	TaskType     model.TaskType
+	Images       []string
+	RegistryAuth *registry.AuthConfig
+}
+
+// Enforce checks the images of a task against the image policy of its workspace and the admission
+// webhook of the master. It returns the image to run in place of each requested image, which is
+// pinned to the digest of its tag if the policy requires it. Errors for images that aren't allowed
+// wrap ErrDenied; other errors mean the images couldn't be checked.
+func Enforce(ctx context.Context, req Request) (map[string]string, error) {
+	policy, err := Get(ctx, req.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	webhook := config.GetMasterConfig().ImagePolicy.AdmissionWebhook
+
+	resolved := make(map[string]string, len(req.Images))
+	for _, image := range req.Images {
+		if _, ok := resolved[image]; ok {
+			continue
+		}
+
+		run := image
+		if policy != nil {
+			if run, err = check(ctx, *policy, image, req.RegistryAuth); err != nil {
+				return nil, err
+			}
+		}
+
+		if webhook != nil {
+			admission, err := admit(ctx, *webhook, AdmissionRequest{
+				Image:         image,
+				ResolvedImage: run,
+				WorkspaceID:   req.WorkspaceID,
+				Username:      req.Username,
+				TaskType:      req.TaskType,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("%w: image %s could not be admitted: %s", ErrDenied, image, err)
+			}
+			if !admission.Allowed {
+				return nil, fmt.Errorf("%w: image %s was denied by the admission webhook: %s",
+					ErrDenied, image, admission.Reason)
+			}
+		}
+		resolved[image] = run
+	}
+	return resolved, nil
+}
+
+// check checks an image against a policy and returns the image to run.
+func check(
+	ctx context.Context, policy model.ImagePolicy, image string, auth *registry.AuthConfig,
+) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("%w: image %s is invalid: %s", ErrDenied, image, err)
+	}
+	if !allows(policy, named.Name()) {
+		return "", fmt.Errorf("%w: repository %s is not allowed in this workspace, allowed "+
+			"repositories are %v", ErrDenied, reference.FamiliarName(named), policy.AllowedRepositories)
+	}
+
+	if !policy.RequireDigests {
+		return image, nil
+	}
+	if _, ok := named.(reference.Canonical); ok {
+		return image, nil
+	}
+	tagged, ok := reference.TagNameOnly(named).(reference.NamedTagged)
+	if !ok {
+		return "", fmt.Errorf("image %s has no tag to resolve", image)
+	}
+	d, err := resolveDigest(ctx, tagged, auth)
+	if err != nil {
+		return "", fmt.Errorf("resolving the digest of image %s: %w", image, err)
+	}
+	pinned, err := reference.WithDigest(tagged, d)
+	if err != nil {
+		return "", err
+	}
+	// Keep the tag for readability; Docker pulls by the digest when an image has both.
+	return reference.FamiliarString(pinned), nil
+}
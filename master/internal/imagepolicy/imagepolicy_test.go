@@ -0,0 +1,150 @@
+package imagepolicy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/registry"
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func TestNormalizePattern(t *testing.T) {
+	for pattern, expected := range map[string]string{
+		"python":                      "docker.io/library/python",
+		"determinedai/pytorch-ngc":    "docker.io/determinedai/pytorch-ngc",
+		"determinedai/*":              "docker.io/determinedai/",
+		"docker.io/*":                 "docker.io/",
+		"registry.example.com/*":      "registry.example.com/",
+		"registry.example.com/team/*": "registry.example.com/team/",
+		"localhost:5000/*":            "localhost:5000/",
+	} {
+		normalized, err := normalizePattern(pattern)
+		require.NoError(t, err, pattern)
+		require.Equal(t, expected, normalized, pattern)
+	}
+
+	for _, pattern := range []string{"python:3.8", "Not A Repo", "registry.example.com/*/x"} {
+		_, err := normalizePattern(pattern)
+		require.Error(t, err, pattern)
+	}
+}
+
+func TestAllows(t *testing.T) {
+	p := model.ImagePolicy{AllowedRepositories: []string{"determinedai/*", "registry.example.com/ml/*", "python"}}
+	require.NoError(t, Validate(p))
+
+	require.True(t, allows(p, "docker.io/determinedai/pytorch-ngc"))
+	require.True(t, allows(p, "registry.example.com/ml/team/trainer"))
+	require.True(t, allows(p, "docker.io/library/python"))
+	require.False(t, allows(p, "docker.io/library/ubuntu"))
+	require.False(t, allows(p, "docker.io/determinedai-fork/pytorch-ngc"))
+	require.False(t, allows(p, "registry.example.com/other"))
+
+	require.True(t, allows(model.ImagePolicy{}, "docker.io/library/ubuntu"))
+}
+
+// fakeRegistry serves one manifest behind bearer token auth, like Docker Hub does.
+func fakeRegistry(t *testing.T, digest string) *httptest.Server {
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			user, pass, ok := r.BasicAuth()
+			if !ok || user != "user" || pass != "pass" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			require.Equal(t, "repository:team/trainer:pull", r.URL.Query().Get("scope"))
+			_, _ = w.Write([]byte(`{"token": "secret"}`))
+		case r.URL.Path == "/v2/team/trainer/manifests/v1":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.Header().Set("WWW-Authenticate",
+					fmt.Sprintf(`Bearer realm="%s/token",service="registry"`, srv.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", digest)
+			_, _ = w.Write([]byte("{}"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	oldClient := httpClient
+	httpClient = srv.Client()
+	t.Cleanup(func() { httpClient = oldClient })
+	return srv
+}
+
+func TestCheckPinsDigests(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	srv := fakeRegistry(t, digest)
+	host := strings.TrimPrefix(srv.URL, "https://")
+	auth := &registry.AuthConfig{Username: "user", Password: "pass"}
+	ctx := context.Background()
+
+	policy := model.ImagePolicy{AllowedRepositories: []string{host + "/team/*"}, RequireDigests: true}
+	image, err := check(ctx, policy, host+"/team/trainer:v1", auth)
+	require.NoError(t, err)
+	require.Equal(t, host+"/team/trainer:v1@"+digest, image)
+
+	// Images that are already pinned are left alone.
+	image, err = check(ctx, policy, host+"/team/trainer@"+digest, nil)
+	require.NoError(t, err)
+	require.Equal(t, host+"/team/trainer@"+digest, image)
+
+	_, err = check(ctx, policy, host+"/team/trainer:v1", nil)
+	require.ErrorContains(t, err, "getting token")
+	require.NotErrorIs(t, err, ErrDenied)
+
+	_, err = check(ctx, policy, "ubuntu:22.04", nil)
+	require.ErrorIs(t, err, ErrDenied)
+
+	// Without digests required, tags are kept and registries aren't contacted.
+	policy.RequireDigests = false
+	image, err = check(ctx, policy, host+"/team/trainer:v2", nil)
+	require.NoError(t, err)
+	require.Equal(t, host+"/team/trainer:v2", image)
+}
+
+func TestAdmit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var req AdmissionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, model.TaskTypeTrial, req.TaskType)
+		allowed := strings.HasPrefix(req.ResolvedImage, "approved/")
+		_ = json.NewEncoder(w).Encode(AdmissionResponse{Allowed: allowed, Reason: "not approved"})
+	}))
+	defer srv.Close()
+	webhook := config.ImageAdmissionWebhookConfig{URL: srv.URL}
+	ctx := context.Background()
+
+	resp, err := admit(ctx, webhook, AdmissionRequest{
+		ResolvedImage: "approved/image:v1", TaskType: model.TaskTypeTrial,
+	})
+	require.NoError(t, err)
+	require.True(t, resp.Allowed)
+
+	resp, err = admit(ctx, webhook, AdmissionRequest{
+		ResolvedImage: "other/image:v1", TaskType: model.TaskTypeTrial,
+	})
+	require.NoError(t, err)
+	require.False(t, resp.Allowed)
+	require.Equal(t, "not approved", resp.Reason)
+
+	_, err = admit(ctx, config.ImageAdmissionWebhookConfig{URL: srv.URL + "/missing"}, AdmissionRequest{})
+	require.Error(t, err)
+}
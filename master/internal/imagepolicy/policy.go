@@ -0,0 +1,103 @@
+// Package imagepolicy checks the container images of tasks before they are allocated, against the
+// image policy of their workspace and an admission webhook configured on the master.
+package imagepolicy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// Validate checks that the allowed repositories of a policy can be parsed.
+func Validate(p model.ImagePolicy) error {
+	for _, pattern := range p.AllowedRepositories {
+		if _, err := normalizePattern(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allows returns whether a policy allows a normalized repository, like docker.io/library/python.
+func allows(p model.ImagePolicy, repository string) bool {
+	if len(p.AllowedRepositories) == 0 {
+		return true
+	}
+	for _, pattern := range p.AllowedRepositories {
+		normalized, err := normalizePattern(pattern)
+		if err != nil {
+			continue
+		}
+		if strings.HasSuffix(normalized, "/") {
+			if strings.HasPrefix(repository, normalized) {
+				return true
+			}
+		} else if repository == normalized {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizePattern expands an allowed repository the way Docker expands image names, so that
+// determinedai/* becomes docker.io/determinedai/ and python becomes docker.io/library/python.
+// Prefixes keep a trailing slash.
+func normalizePattern(pattern string) (string, error) {
+	prefix, isPrefix := strings.CutSuffix(pattern, "/*")
+	if !isPrefix {
+		named, err := reference.ParseNormalizedNamed(pattern)
+		if err != nil {
+			return "", fmt.Errorf("allowed repository %q is invalid: %w", pattern, err)
+		}
+		if !reference.IsNameOnly(named) {
+			return "", fmt.Errorf("allowed repository %q must not have a tag or digest", pattern)
+		}
+		return named.Name(), nil
+	}
+
+	// Parse the prefix as a repository under it, to find out whether it names a registry.
+	named, err := reference.ParseNormalizedNamed(prefix + "/x")
+	if err != nil {
+		return "", fmt.Errorf("allowed repository %q is invalid: %w", pattern, err)
+	}
+	domain := reference.Domain(named)
+	if domain == prefix {
+		return domain + "/", nil
+	}
+	return domain + "/" + strings.TrimSuffix(reference.Path(named), "x"), nil
+}
+
+// Get returns the image policy of a workspace, or nil if it has none.
+func Get(ctx context.Context, workspaceID int) (*model.ImagePolicy, error) {
+	w := &model.Workspace{}
+	err := db.Bun().NewSelect().Model(w).Column("image_policy").
+		Where("id = ?", workspaceID).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting image policy of workspace %d: %w",
+			workspaceID, db.MatchSentinelError(err))
+	}
+	return w.ImagePolicy, nil
+}
+
+// Set sets the image policy of a workspace; nil removes it.
+func Set(ctx context.Context, workspaceID int, p *model.ImagePolicy) error {
+	res, err := db.Bun().NewUpdate().Model(&model.Workspace{ID: workspaceID, ImagePolicy: p}).
+		Column("image_policy").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("setting image policy of workspace %d: %w", workspaceID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("setting image policy of workspace %d: %w", workspaceID, db.ErrNotFound)
+	}
+	return nil
+}
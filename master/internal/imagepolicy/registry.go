@@ -0,0 +1,161 @@
+package imagepolicy
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/distribution/registry/client/auth/challenge"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/opencontainers/go-digest"
+)
+
+// manifestMediaTypes are the manifests a tag is resolved to, preferring multi-platform indexes so
+// that the digest is the one `docker pull` reports.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// maxManifestBytes bounds the manifests read to compute digests registries don't report.
+const maxManifestBytes = 4 << 20
+
+// httpClient is the client used to talk to registries and the admission webhook.
+var httpClient = cleanhttp.DefaultPooledClient()
+
+// resolveDigest returns the digest the tag of an image currently points to in its registry. The
+// registry auth of the task is used to log in, the same way the agent pulls the image.
+func resolveDigest(
+	ctx context.Context, ref reference.NamedTagged, auth *registry.AuthConfig,
+) (digest.Digest, error) {
+	host := reference.Domain(ref)
+	if host == "docker.io" {
+		host = "registry-1.docker.io"
+	}
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, reference.Path(ref), ref.Tag())
+
+	resp, err := getManifest(ctx, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		authorization, aErr := authorize(ctx, resp, reference.Path(ref), auth)
+		_ = resp.Body.Close()
+		if aErr != nil {
+			return "", fmt.Errorf("logging in to %s: %w", host, aErr)
+		}
+		if resp, err = getManifest(ctx, manifestURL, authorization); err != nil {
+			return "", err
+		}
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("getting manifest of %s: %s", reference.FamiliarString(ref), resp.Status)
+	}
+
+	if d, err := digest.Parse(resp.Header.Get("Docker-Content-Digest")); err == nil {
+		return d, nil
+	}
+	bs, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestBytes))
+	if err != nil {
+		return "", fmt.Errorf("reading manifest of %s: %w", reference.FamiliarString(ref), err)
+	}
+	return digest.FromBytes(bs), nil
+}
+
+func getManifest(ctx context.Context, manifestURL, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return httpClient.Do(req)
+}
+
+// authorize returns the Authorization header that answers the challenge of a registry, fetching
+// a pull token for the repository from its token server if it uses bearer tokens.
+func authorize(
+	ctx context.Context, resp *http.Response, repository string, auth *registry.AuthConfig,
+) (string, error) {
+	for _, c := range challenge.ResponseChallenges(resp) {
+		switch strings.ToLower(c.Scheme) {
+		case "basic":
+			if auth == nil || auth.Username == "" {
+				return "", errors.New("the registry requires a login, set registry_auth")
+			}
+			credentials := auth.Username + ":" + auth.Password
+			return "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials)), nil
+
+		case "bearer":
+			token, err := fetchToken(ctx, c.Parameters, repository, auth)
+			if err != nil {
+				return "", err
+			}
+			return "Bearer " + token, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported authentication challenge %q", resp.Header.Get("WWW-Authenticate"))
+}
+
+func fetchToken(
+	ctx context.Context, params map[string]string, repository string, auth *registry.AuthConfig,
+) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Scheme == "" {
+		return "", fmt.Errorf("invalid token realm %q", params["realm"])
+	}
+	q := realm.Query()
+	if service := params["service"]; service != "" {
+		q.Set("service", service)
+	}
+	q.Set("scope", fmt.Sprintf("repository:%s:pull", repository))
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if auth != nil && auth.Username != "" {
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("getting token from %s: %s", realm.Host, resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("parsing token from %s: %w", realm.Host, err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", fmt.Errorf("no token in response from %s", realm.Host)
+}
@@ -0,0 +1,76 @@
+package imagepolicy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// maxAdmissionResponseBytes bounds the responses read from the admission webhook.
+const maxAdmissionResponseBytes = 1 << 20
+
+// AdmissionRequest is the body the master POSTs to the image admission webhook.
+type AdmissionRequest struct {
+	// Image is the image as it was submitted.
+	Image string `json:"image"`
+	// ResolvedImage is the image that will run, which is pinned to a digest if the policy of the
+	// workspace requires it.
+	ResolvedImage string         `json:"resolved_image"`
+	WorkspaceID   int            `json:"workspace_id"`
+	Username      string         `json:"username"`
+	TaskType      model.TaskType `json:"task_type"`
+}
+
+// AdmissionResponse is the response of the image admission webhook. The reason of a denial is
+// shown to the user.
+type AdmissionResponse struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason"`
+}
+
+// admit asks the admission webhook whether an image can run.
+func admit(
+	ctx context.Context, webhook config.ImageAdmissionWebhookConfig, req AdmissionRequest,
+) (*AdmissionResponse, error) {
+	timeout := time.Duration(webhook.Timeout)
+	if timeout == 0 {
+		timeout = config.DefaultImageAdmissionTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("admission webhook responded %s", resp.Status)
+	}
+
+	var admission AdmissionResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxAdmissionResponseBytes)).
+		Decode(&admission); err != nil {
+		return nil, fmt.Errorf("parsing admission webhook response: %w", err)
+	}
+	return &admission, nil
+}
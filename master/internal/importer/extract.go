@@ -0,0 +1,71 @@
+package importer
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ExtractTarGz extracts the directories and regular files of a gzipped tarball into dst, e.g.
+// an uploaded MLflow tracking directory. Other entries, such as symlinks, are skipped, as are
+// entries whose paths would escape dst. It fails once the files extracted add up to more than
+// maxBytes.
+func ExtractTarGz(r io.Reader, dst string, maxBytes int64) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	remaining := maxBytes
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		name := filepath.FromSlash(header.Name)
+		if !filepath.IsLocal(name) {
+			continue
+		}
+		target := filepath.Join(dst, name)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+				return err
+			}
+			n, err := extractFile(tr, target, remaining)
+			if err != nil {
+				return fmt.Errorf("extracting %s: %w", header.Name, err)
+			}
+			remaining -= n
+		}
+	}
+}
+
+// extractFile writes r to target and returns how many bytes it wrote, failing if r holds more
+// than maxBytes.
+func extractFile(r io.Reader, target string, maxBytes int64) (int64, error) {
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600) // #nosec G304
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.CopyN(f, r, maxBytes+1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		_ = f.Close()
+		return n, err
+	}
+	if n > maxBytes {
+		_ = f.Close()
+		return n, fmt.Errorf("the tarball holds more than %d bytes", maxBytes)
+	}
+	return n, f.Close()
+}
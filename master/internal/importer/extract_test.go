@@ -0,0 +1,49 @@
+package importer
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+	for name, data := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0o600,
+			Size:     int64(len(data)),
+		}))
+		_, err := tw.Write([]byte(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return b.Bytes()
+}
+
+func TestExtractTarGz(t *testing.T) {
+	files := map[string]string{
+		"mlruns/1/meta.yaml": "name: mnist",
+		"../escaped":         "nope",
+	}
+
+	dst := t.TempDir()
+	require.NoError(t, ExtractTarGz(bytes.NewReader(tarGz(t, files)), dst, 11))
+	data, err := os.ReadFile(filepath.Join(dst, "mlruns", "1", "meta.yaml"))
+	require.NoError(t, err)
+	require.Equal(t, "name: mnist", string(data))
+	_, err = os.Stat(filepath.Join(filepath.Dir(dst), "escaped"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	err = ExtractTarGz(bytes.NewReader(tarGz(t, files)), t.TempDir(), 10)
+	require.ErrorContains(t, err, "more than 10 bytes")
+}
@@ -0,0 +1,372 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/uptrace/bun"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/trials"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/ptrs"
+	"github.com/determined-ai/determined/master/pkg/schemas"
+	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
+	"github.com/determined-ai/determined/proto/pkg/commonv1"
+	"github.com/determined-ai/determined/proto/pkg/trialv1"
+)
+
+// Sources that runs can be imported from.
+const (
+	SourceMLflow      = "mlflow"
+	SourceTensorBoard = "tensorboard"
+)
+
+// Experiment is an experiment read from another tracking tool, ready to be imported.
+type Experiment struct {
+	// Source is the tool the experiment was read from.
+	Source string
+	// ExternalID identifies the experiment in the source. Importing the same experiment again
+	// only adds the runs that weren't imported yet.
+	ExternalID  string
+	Name        string
+	Description string
+	Runs        []Run
+}
+
+// Run is a run read from another tracking tool, imported as a trial.
+type Run struct {
+	// ExternalID identifies the run within its experiment in the source.
+	ExternalID string
+	Hparams    map[string]any
+	// Tags are imported as trial metadata.
+	Tags      map[string]string
+	State     model.State
+	StartTime time.Time
+	EndTime   *time.Time
+	Metrics   map[model.MetricGroup][]Step
+	// ArtifactURI is where the source stored the artifacts of the run. The artifacts are not
+	// imported; the URI is recorded as trial metadata.
+	ArtifactURI string
+}
+
+// Step holds the metrics a run reported after training for a number of batches.
+type Step struct {
+	Batches int
+	Time    time.Time
+	Values  map[string]float64
+}
+
+// Read reads the experiments to import from the root of fsys, in the format of the source they
+// were recorded with. TensorBoard logs are read as a single experiment with the name.
+func Read(fsys fs.FS, source, name string) ([]Experiment, error) {
+	switch source {
+	case SourceMLflow:
+		return ReadMLflow(fsys)
+	case SourceTensorBoard:
+		exp, err := ReadTensorBoard(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		return []Experiment{*exp}, nil
+	default:
+		return nil, fmt.Errorf("format must be %q or %q", SourceMLflow, SourceTensorBoard)
+	}
+}
+
+// stepBuilder collects metric samples into steps, keeping the last value reported for a metric
+// at a step.
+type stepBuilder map[model.MetricGroup]map[int]*Step
+
+func (b stepBuilder) add(group model.MetricGroup, batches int, t time.Time, name string, v float64) {
+	if b[group] == nil {
+		b[group] = map[int]*Step{}
+	}
+	step, ok := b[group][batches]
+	if !ok {
+		step = &Step{Batches: batches, Values: map[string]float64{}}
+		b[group][batches] = step
+	}
+	step.Values[name] = v
+	if t.After(step.Time) {
+		step.Time = t
+	}
+}
+
+// build returns the steps of every group, ordered by batches.
+func (b stepBuilder) build() map[model.MetricGroup][]Step {
+	metrics := make(map[model.MetricGroup][]Step, len(b))
+	for group, steps := range b {
+		for _, s := range steps {
+			metrics[group] = append(metrics[group], *s)
+		}
+		sort.Slice(metrics[group], func(i, j int) bool {
+			return metrics[group][i].Batches < metrics[group][j].Batches
+		})
+	}
+	return metrics
+}
+
+// Options configure where and how experiments are imported.
+type Options struct {
+	ProjectID int
+	Owner     model.User
+	// CheckpointStorage completes the config of the imported experiments.
+	CheckpointStorage expconf.CheckpointStorageConfig
+	// SearcherMetric is the validation metric that ranks trials. It defaults to the first
+	// validation metric with "loss" in its name.
+	SearcherMetric  string
+	SmallerIsBetter bool
+}
+
+// Result describes an imported experiment.
+type Result struct {
+	ExperimentID int    `json:"experiment_id"`
+	Name         string `json:"name"`
+	TrialIDs     []int  `json:"trial_ids"`
+	// SkippedRuns counts the runs that were imported before.
+	SkippedRuns int `json:"skipped_runs"`
+}
+
+// defaultSearcherMetric picks the validation metric used to rank imported trials.
+func defaultSearcherMetric(exp *Experiment) string {
+	names := map[string]bool{}
+	for _, r := range exp.Runs {
+		for _, s := range r.Metrics[model.ValidationMetricGroup] {
+			for name := range s.Values {
+				names[name] = true
+			}
+		}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		if strings.Contains(name, "loss") {
+			return name
+		}
+	}
+	if len(sorted) > 0 {
+		return sorted[0]
+	}
+	return "loss"
+}
+
+// experimentConfig builds the config of the unmanaged experiment the runs are imported into.
+func experimentConfig(exp *Experiment, opts Options) (expconf.ExperimentConfig, string, error) {
+	metric := opts.SearcherMetric
+	smallerIsBetter := opts.SmallerIsBetter
+	if metric == "" {
+		metric = defaultSearcherMetric(exp)
+		smallerIsBetter = true
+	}
+	maxLength := 1
+	for _, r := range exp.Runs {
+		for _, steps := range r.Metrics {
+			if n := len(steps); n > 0 && steps[n-1].Batches > maxLength {
+				maxLength = steps[n-1].Batches
+			}
+		}
+	}
+	description := exp.Description
+	if description == "" {
+		description = fmt.Sprintf("Imported from %s experiment %s", exp.Source, exp.ExternalID)
+	}
+
+	raw, err := yaml.Marshal(map[string]any{
+		"name":        exp.Name,
+		"description": description,
+		"labels":      []string{exp.Source + "-import"},
+		"searcher": map[string]any{
+			"name":              "single",
+			"metric":            metric,
+			"smaller_is_better": smallerIsBetter,
+			"max_length":        map[string]any{"batches": maxLength},
+		},
+	})
+	if err != nil {
+		return expconf.ExperimentConfig{}, "", err
+	}
+	config, err := expconf.ParseAnyExperimentConfigYAML(raw)
+	if err != nil {
+		return config, "", errors.Wrap(err, "invalid experiment configuration")
+	}
+	config.RawCheckpointStorage = schemas.Merge(config.RawCheckpointStorage, &opts.CheckpointStorage)
+	config = schemas.WithDefaults(config)
+	if err := schemas.IsComplete(config); err != nil {
+		return config, "", errors.Wrap(err, "invalid experiment configuration")
+	}
+	return config, string(raw), nil
+}
+
+// Import creates an unmanaged experiment with a trial for each run, or adds the runs that weren't
+// imported before to the experiment created by an earlier import.
+func Import(ctx context.Context, pgDB *db.PgDB, exp *Experiment, opts Options) (*Result, error) {
+	config, rawConfig, err := experimentConfig(exp, opts)
+	if err != nil {
+		return nil, err
+	}
+	expModel, err := model.NewExperiment(
+		config, rawConfig, nil, nil, false, nil, nil, nil, nil, opts.ProjectID, true)
+	if err != nil {
+		return nil, err
+	}
+	expModel.Unmanaged = true
+	expModel.OwnerID = &opts.Owner.ID
+	expModel.Username = opts.Owner.Username
+	expModel.ExternalExperimentID = ptrs.Ptr(exp.Source + ":" + exp.ExternalID)
+	for _, r := range exp.Runs {
+		if !r.StartTime.IsZero() && r.StartTime.Before(expModel.StartTime) {
+			expModel.StartTime = r.StartTime
+		}
+	}
+	if err := db.AddExperimentTx(ctx, db.Bun(), expModel, config, true); err != nil {
+		return nil, err
+	}
+
+	result := &Result{ExperimentID: expModel.ID, Name: exp.Name}
+	for _, r := range exp.Runs {
+		// Each run is imported in a transaction, so that a failed import leaves no partially
+		// imported trial behind to be skipped when importing again.
+		var trialID int
+		var created bool
+		err := db.Bun().RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+			var err error
+			if trialID, created, err = createTrialTx(ctx, tx, expModel, r); err != nil || !created {
+				return err
+			}
+			if err := importRunTx(ctx, tx, pgDB, exp, r, trialID); err != nil {
+				return fmt.Errorf("importing run %s into trial %d: %w", r.ExternalID, trialID, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+		if !created {
+			result.SkippedRuns++
+			continue
+		}
+		result.TrialIDs = append(result.TrialIDs, trialID)
+	}
+
+	if err := trials.UpdateUnmanagedExperimentStatesTx(
+		ctx, db.Bun(), []*model.Experiment{expModel}); err != nil {
+		return result, fmt.Errorf("updating state of experiment %d: %w", expModel.ID, err)
+	}
+	log.Infof("imported %d runs of %s experiment %s into experiment %d, skipped %d",
+		len(result.TrialIDs), exp.Source, exp.ExternalID, expModel.ID, result.SkippedRuns)
+	return result, nil
+}
+
+// createTrialTx creates the trial of a run, unless an earlier import already did.
+func createTrialTx(ctx context.Context, tx bun.Tx, exp *model.Experiment, r Run) (int, bool, error) {
+	var trialID int
+	err := tx.NewSelect().Table("runs").Column("id").
+		Where("experiment_id = ?", exp.ID).
+		Where("external_run_id = ?", r.ExternalID).
+		Scan(ctx, &trialID)
+	switch {
+	case err == nil:
+		return trialID, false, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return 0, false, fmt.Errorf("looking up run %s: %w", r.ExternalID, err)
+	}
+
+	startTime := r.StartTime
+	if startTime.IsZero() {
+		startTime = time.Now()
+	}
+	// Trial task IDs must start with the experiment ID, see experimentIDFromTrialTaskID.
+	taskID := model.TaskID(fmt.Sprintf("%d.%s", exp.ID, model.NewTaskID()))
+	trial := model.NewTrial(model.PausedState, model.RequestID{}, exp.ID, r.Hparams, nil, 0)
+	trial.StartTime = startTime
+	trial.ExternalTrialID = ptrs.Ptr(r.ExternalID)
+	if err := db.AddTaskTx(ctx, tx, &model.Task{
+		TaskID:     taskID,
+		TaskType:   model.TaskTypeTrial,
+		StartTime:  startTime,
+		LogVersion: model.CurrentTaskLogVersion,
+	}); err != nil {
+		return 0, false, fmt.Errorf("creating task for run %s: %w", r.ExternalID, err)
+	}
+	if err := db.UpsertTrialByExternalIDTx(ctx, tx, trial, taskID); err != nil {
+		return 0, false, fmt.Errorf("creating trial for run %s: %w", r.ExternalID, err)
+	}
+	return trial.ID, true, nil
+}
+
+// importRunTx reports the metrics of a run to its trial, then records its tags and final state.
+func importRunTx(
+	ctx context.Context, tx bun.Tx, pgDB *db.PgDB, exp *Experiment, r Run, trialID int,
+) error {
+	// Report groups in a fixed order so that imports are reproducible.
+	groups := make([]string, 0, len(r.Metrics))
+	for group := range r.Metrics {
+		groups = append(groups, string(group))
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		for _, s := range r.Metrics[model.MetricGroup(group)] {
+			if s.Batches < 0 || s.Batches > math.MaxInt32 {
+				log.Warnf("skipping metrics of run %s at out of range step %d", r.ExternalID, s.Batches)
+				continue
+			}
+			values := make(map[string]any, len(s.Values))
+			for name, v := range s.Values {
+				values[name] = v
+			}
+			avgMetrics, err := structpb.NewStruct(values)
+			if err != nil {
+				return err
+			}
+			if err := pgDB.AddTrialMetricsAtTx(ctx, tx, &trialv1.TrialMetrics{
+				TrialId:        int32(trialID),
+				StepsCompleted: int32(s.Batches),
+				Metrics:        &commonv1.Metrics{AvgMetrics: avgMetrics},
+			}, model.MetricGroup(group), s.Time); err != nil {
+				return err
+			}
+		}
+	}
+
+	tags := map[string]*model.MetadataValue{
+		"import.source": {Type: model.MetadataTypeString, StringValue: &exp.Source},
+		"import.run_id": {Type: model.MetadataTypeString, StringValue: ptrs.Ptr(r.ExternalID)},
+	}
+	if r.ArtifactURI != "" {
+		tags["import.artifact_uri"] = &model.MetadataValue{
+			Type: model.MetadataTypeString, StringValue: ptrs.Ptr(r.ArtifactURI),
+		}
+	}
+	for k, v := range r.Tags {
+		if model.ValidateMetadataKey(k) != nil {
+			continue
+		}
+		tags[k] = &model.MetadataValue{Type: model.MetadataTypeString, StringValue: ptrs.Ptr(v)}
+	}
+	if err := db.UpdateTrialMetadataTx(ctx, tx, trialID, tags); err != nil {
+		return err
+	}
+
+	run := model.Run{ID: trialID, State: r.State, EndTime: r.EndTime}
+	if _, err := tx.NewUpdate().Model(&run).
+		Column("state", "end_time").
+		WherePK().
+		Exec(ctx); err != nil {
+		return fmt.Errorf("setting trial state: %w", err)
+	}
+	return nil
+}
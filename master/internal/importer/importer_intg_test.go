@@ -0,0 +1,96 @@
+//go:build integration
+
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/pkg/etc"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
+)
+
+var pgDB *db.PgDB
+
+func TestMain(m *testing.M) {
+	var err error
+	pgDB, err = db.ResolveTestPostgres()
+	if err != nil {
+		log.Panicln(err)
+	}
+
+	err = db.MigrateTestPostgres(pgDB, "file://../../static/migrations", "up")
+	if err != nil {
+		log.Panicln(err)
+	}
+
+	err = etc.SetRootPath("../../static/srv")
+	if err != nil {
+		log.Panicln(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	user := db.RequireMockUser(t, pgDB)
+
+	var storage expconf.CheckpointStorageConfig
+	require.NoError(t, json.Unmarshal(
+		[]byte(`{"type": "shared_fs", "host_path": "/tmp"}`), &storage))
+	opts := Options{
+		ProjectID:         model.DefaultProjectID,
+		Owner:             user,
+		CheckpointStorage: storage,
+	}
+
+	exps, err := ReadMLflow(mlflowFS())
+	require.NoError(t, err)
+	exp := exps[0]
+	// Every test run imports a fresh experiment.
+	exp.ExternalID = user.Username
+
+	res, err := Import(ctx, pgDB, &exp, opts)
+	require.NoError(t, err)
+	require.Len(t, res.TrialIDs, 2)
+
+	imported, err := db.ExperimentByID(ctx, res.ExperimentID)
+	require.NoError(t, err)
+	require.True(t, imported.Unmanaged)
+	require.Equal(t, "val_loss", imported.Config.Searcher.Metric)
+
+	trial, err := db.TrialByID(ctx, res.TrialIDs[0])
+	require.NoError(t, err)
+	require.Equal(t, model.CompletedState, trial.State)
+	require.Equal(t, "adam", trial.HParams["optimizer"])
+	require.Equal(t, time.UnixMilli(1700000060000).UTC(), trial.EndTime.UTC())
+
+	metadata, err := db.TrialMetadata(ctx, res.TrialIDs[0])
+	require.NoError(t, err)
+	require.Equal(t, "bold-fox", *metadata["mlflow.runName"].StringValue)
+	require.Equal(t, "abc", *metadata["import.run_id"].StringValue)
+	require.Equal(t, "file:///mlruns/1/abc/artifacts",
+		*metadata["import.artifact_uri"].StringValue)
+
+	group := model.TrainingMetricGroup.ToString()
+	metrics, err := db.GetMetrics(ctx, res.TrialIDs[0], -1, 10, &group)
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	require.Equal(t, int32(10), metrics[0].TotalBatches)
+	require.Equal(t, time.UnixMilli(1700000011000).UTC(), metrics[0].EndTime.AsTime())
+
+	// Importing again only adds new runs.
+	res, err = Import(ctx, pgDB, &exp, opts)
+	require.NoError(t, err)
+	require.Empty(t, res.TrialIDs)
+	require.Equal(t, 2, res.SkippedRuns)
+}
@@ -0,0 +1,303 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/ptrs"
+)
+
+// MLflow run statuses, as stored in the file store.
+const (
+	mlflowRunning   = 1
+	mlflowScheduled = 2
+	mlflowFinished  = 3
+	mlflowFailed    = 4
+	mlflowKilled    = 5
+)
+
+const mlflowDeleted = "deleted"
+
+// validationMetricPrefixes mark MLflow metrics that are imported as validation metrics; every
+// other metric is imported as a training metric.
+var validationMetricPrefixes = []string{
+	"val_", "val/", "validation_", "validation/", "eval_", "eval/", "test_", "test/",
+}
+
+type mlflowExperimentMeta struct {
+	ExperimentID     string `json:"experiment_id"`
+	Name             string `json:"name"`
+	LifecycleStage   string `json:"lifecycle_stage"`
+	ArtifactLocation string `json:"artifact_location"`
+}
+
+type mlflowRunMeta struct {
+	RunID          string `json:"run_id"`
+	RunUUID        string `json:"run_uuid"`
+	StartTime      *int64 `json:"start_time"`
+	EndTime        *int64 `json:"end_time"`
+	Status         int    `json:"status"`
+	LifecycleStage string `json:"lifecycle_stage"`
+	ArtifactURI    string `json:"artifact_uri"`
+}
+
+// ReadMLflow reads the experiments of an MLflow file store. The root of fsys is either a
+// tracking directory, usually named mlruns, or the directory of a single experiment. Deleted
+// experiments and runs are skipped.
+func ReadMLflow(fsys fs.FS) ([]Experiment, error) {
+	var meta mlflowExperimentMeta
+	switch err := readYAML(fsys, "meta.yaml", &meta); {
+	case err == nil:
+		exp, err := readMLflowExperiment(fsys, ".")
+		if err != nil || exp == nil {
+			return nil, err
+		}
+		return []Experiment{*exp}, nil
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	var exps []Experiment
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		if _, err := fs.Stat(fsys, path.Join(e.Name(), "meta.yaml")); err != nil {
+			continue
+		}
+		exp, err := readMLflowExperiment(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		if exp != nil {
+			exps = append(exps, *exp)
+		}
+	}
+	if len(exps) == 0 {
+		return nil, errors.New("no MLflow experiments found")
+	}
+	return exps, nil
+}
+
+func readMLflowExperiment(fsys fs.FS, dir string) (*Experiment, error) {
+	var meta mlflowExperimentMeta
+	if err := readYAML(fsys, path.Join(dir, "meta.yaml"), &meta); err != nil {
+		return nil, err
+	}
+	if meta.LifecycleStage == mlflowDeleted {
+		return nil, nil
+	}
+	tags, err := readKeyFiles(fsys, path.Join(dir, "tags"))
+	if err != nil {
+		return nil, err
+	}
+	exp := &Experiment{
+		Source:      SourceMLflow,
+		ExternalID:  meta.ExperimentID,
+		Name:        meta.Name,
+		Description: tags["mlflow.note.content"],
+	}
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() || e.Name() == "tags" {
+			continue
+		}
+		runDir := path.Join(dir, e.Name())
+		if _, err := fs.Stat(fsys, path.Join(runDir, "meta.yaml")); err != nil {
+			continue
+		}
+		run, err := readMLflowRun(fsys, runDir)
+		if err != nil {
+			return nil, fmt.Errorf("reading MLflow run %s: %w", runDir, err)
+		}
+		if run != nil {
+			exp.Runs = append(exp.Runs, *run)
+		}
+	}
+	sort.SliceStable(exp.Runs, func(i, j int) bool {
+		return exp.Runs[i].StartTime.Before(exp.Runs[j].StartTime)
+	})
+	return exp, nil
+}
+
+func readMLflowRun(fsys fs.FS, dir string) (*Run, error) {
+	var meta mlflowRunMeta
+	if err := readYAML(fsys, path.Join(dir, "meta.yaml"), &meta); err != nil {
+		return nil, err
+	}
+	if meta.LifecycleStage == mlflowDeleted {
+		return nil, nil
+	}
+
+	run := &Run{
+		ExternalID:  meta.RunID,
+		Hparams:     map[string]any{},
+		ArtifactURI: meta.ArtifactURI,
+	}
+	if run.ExternalID == "" {
+		run.ExternalID = meta.RunUUID
+	}
+	if meta.StartTime != nil {
+		run.StartTime = time.UnixMilli(*meta.StartTime)
+	}
+	if meta.EndTime != nil {
+		run.EndTime = ptrs.Ptr(time.UnixMilli(*meta.EndTime))
+	}
+	switch meta.Status {
+	case mlflowFinished:
+		run.State = model.CompletedState
+	case mlflowFailed:
+		run.State = model.ErrorState
+	case mlflowKilled:
+		run.State = model.CanceledState
+	case mlflowRunning, mlflowScheduled:
+		// Nothing will report the end of the run to Determined.
+		run.State = model.PausedState
+		run.EndTime = nil
+	default:
+		return nil, fmt.Errorf("unknown run status %d", meta.Status)
+	}
+
+	params, err := readKeyFiles(fsys, path.Join(dir, "params"))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range params {
+		run.Hparams[k] = parseParam(v)
+	}
+	if run.Tags, err = readKeyFiles(fsys, path.Join(dir, "tags")); err != nil {
+		return nil, err
+	}
+	if run.Metrics, err = readMLflowMetrics(fsys, path.Join(dir, "metrics")); err != nil {
+		return nil, err
+	}
+
+	return run, nil
+}
+
+// readMLflowMetrics reads metric files, each line of which is "timestamp value [step]".
+func readMLflowMetrics(fsys fs.FS, dir string) (map[model.MetricGroup][]Step, error) {
+	steps := stepBuilder{}
+	err := walkKeyFiles(fsys, dir, func(key, p string) error {
+		group := model.TrainingMetricGroup
+		for _, prefix := range validationMetricPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				group = model.ValidationMetricGroup
+			}
+		}
+
+		f, err := fsys.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for line := 1; scanner.Scan(); line++ {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 0 {
+				continue
+			}
+			if len(fields) < 2 || len(fields) > 3 {
+				return fmt.Errorf("metric %s line %d: expected 2 or 3 fields", key, line)
+			}
+			ts, err := strconv.ParseInt(fields[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("metric %s line %d: %w", key, line, err)
+			}
+			value, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				return fmt.Errorf("metric %s line %d: %w", key, line, err)
+			}
+			var step int64
+			if len(fields) == 3 {
+				if step, err = strconv.ParseInt(fields[2], 10, 64); err != nil {
+					return fmt.Errorf("metric %s line %d: %w", key, line, err)
+				}
+			}
+			steps.add(group, int(step), time.UnixMilli(ts), key, value)
+		}
+		return scanner.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return steps.build(), nil
+}
+
+// readKeyFiles reads a directory of files named by key that hold a value, e.g. params and tags.
+func readKeyFiles(fsys fs.FS, dir string) (map[string]string, error) {
+	values := map[string]string{}
+	err := walkKeyFiles(fsys, dir, func(key, p string) error {
+		b, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		values[key] = string(b)
+		return nil
+	})
+	return values, err
+}
+
+// walkKeyFiles calls fn for every file in the directory. Keys with slashes are stored in
+// subdirectories, so the key is the path of the file relative to the directory. A missing
+// directory has no keys.
+func walkKeyFiles(fsys fs.FS, dir string, fn func(key, p string) error) error {
+	err := fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		return fn(strings.TrimPrefix(p, dir+"/"), p)
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// parseParam converts an MLflow param, which is always stored as a string, back to a number or
+// bool where possible so that it can be compared across trials.
+func parseParam(v string) any {
+	if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+		return f
+	}
+	switch v {
+	case "True", "true":
+		return true
+	case "False", "false":
+		return false
+	}
+	return v
+}
+
+func readYAML(fsys fs.FS, p string, v any) error {
+	b, err := fs.ReadFile(fsys, p)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("parsing %s: %w", p, err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package importer
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func mlflowFS() fstest.MapFS {
+	return fstest.MapFS{
+		"1/meta.yaml": {Data: []byte(`artifact_location: file:///mlruns/1
+creation_time: 1700000000000
+experiment_id: '1'
+last_update_time: 1700000000000
+lifecycle_stage: active
+name: mnist
+`)},
+		"1/tags/mlflow.note.content": {Data: []byte("digits")},
+		"1/abc/meta.yaml": {Data: []byte(`artifact_uri: file:///mlruns/1/abc/artifacts
+end_time: 1700000060000
+experiment_id: '1'
+lifecycle_stage: active
+run_id: abc
+run_name: bold-fox
+run_uuid: abc
+start_time: 1700000000000
+status: 3
+user_id: alice
+`)},
+		"1/abc/params/lr":                  {Data: []byte("0.01")},
+		"1/abc/params/layers":              {Data: []byte("3")},
+		"1/abc/params/optimizer":           {Data: []byte("adam")},
+		"1/abc/params/nesterov":            {Data: []byte("True")},
+		"1/abc/tags/mlflow.runName":        {Data: []byte("bold-fox")},
+		"1/abc/metrics/loss":               {Data: []byte("1700000010000 0.5 10\n1700000020000 0.25 20\n")},
+		"1/abc/metrics/val_loss":           {Data: []byte("1700000021000 0.3 20\n")},
+		"1/abc/metrics/sys/gpu":            {Data: []byte("1700000011000 80 10\n")},
+		"1/abc/artifacts/model/model.pkl":  {Data: []byte("weights")},
+		"1/abc/artifacts/model/MLmodel":    {Data: []byte("flavors: {}")},
+		"1/old/meta.yaml":                  {Data: []byte("run_id: old\nstatus: 3\nlifecycle_stage: deleted\n")},
+		"2/meta.yaml":                      {Data: []byte("experiment_id: '2'\nname: gone\nlifecycle_stage: deleted\n")},
+		".trash/meta.yaml":                 {Data: []byte("experiment_id: '3'\nname: trash\n")},
+		"models/some-model/meta.yaml":      {Data: []byte("name: some-model\n")},
+		"1/running/meta.yaml":              {Data: []byte("run_id: running\nstatus: 1\nstart_time: 1700000100000\nend_time: null\n")},
+		"1/running/metrics/loss":           {Data: []byte("1700000110000 0.9\n")},
+		"1/running/params/optimizer":       {Data: []byte("sgd")},
+		"1/running/tags/mlflow.source.git": {Data: []byte("abcdef")},
+	}
+}
+
+func TestReadMLflow(t *testing.T) {
+	exps, err := ReadMLflow(mlflowFS())
+	require.NoError(t, err)
+	require.Len(t, exps, 1)
+	exp := exps[0]
+	require.Equal(t, SourceMLflow, exp.Source)
+	require.Equal(t, "1", exp.ExternalID)
+	require.Equal(t, "mnist", exp.Name)
+	require.Equal(t, "digits", exp.Description)
+	require.Len(t, exp.Runs, 2)
+
+	run := exp.Runs[0]
+	require.Equal(t, "abc", run.ExternalID)
+	require.Equal(t, model.CompletedState, run.State)
+	require.Equal(t, time.UnixMilli(1700000000000), run.StartTime)
+	require.Equal(t, time.UnixMilli(1700000060000), *run.EndTime)
+	require.Equal(t, map[string]any{
+		"lr": 0.01, "layers": int64(3), "optimizer": "adam", "nesterov": true,
+	}, run.Hparams)
+	require.Equal(t, map[string]string{"mlflow.runName": "bold-fox"}, run.Tags)
+	require.Equal(t, "file:///mlruns/1/abc/artifacts", run.ArtifactURI)
+	require.Equal(t, map[model.MetricGroup][]Step{
+		model.TrainingMetricGroup: {
+			{
+				Batches: 10,
+				Time:    time.UnixMilli(1700000011000),
+				Values:  map[string]float64{"loss": 0.5, "sys/gpu": 80},
+			},
+			{Batches: 20, Time: time.UnixMilli(1700000020000), Values: map[string]float64{"loss": 0.25}},
+		},
+		model.ValidationMetricGroup: {
+			{Batches: 20, Time: time.UnixMilli(1700000021000), Values: map[string]float64{"val_loss": 0.3}},
+		},
+	}, run.Metrics)
+
+	// Runs without a step report at step 0, and running runs are imported as paused.
+	running := exp.Runs[1]
+	require.Equal(t, model.PausedState, running.State)
+	require.Nil(t, running.EndTime)
+	require.Equal(t, 0, running.Metrics[model.TrainingMetricGroup][0].Batches)
+	require.Equal(t, "val_loss", defaultSearcherMetric(&exp))
+}
+
+func TestReadMLflowExperimentDir(t *testing.T) {
+	sub := fstest.MapFS{}
+	for p, f := range mlflowFS() {
+		if len(p) > 2 && p[:2] == "1/" {
+			sub[p[2:]] = f
+		}
+	}
+	exps, err := ReadMLflow(sub)
+	require.NoError(t, err)
+	require.Len(t, exps, 1)
+	require.Equal(t, "mnist", exps[0].Name)
+	require.Len(t, exps[0].Runs, 2)
+
+	_, err = ReadMLflow(fstest.MapFS{"README": {Data: []byte("hi")}})
+	require.ErrorContains(t, err, "no MLflow experiments found")
+
+	_, err = ReadMLflow(fstest.MapFS{
+		"meta.yaml":      {Data: []byte("experiment_id: '1'\nname: x\n")},
+		"r/meta.yaml":    {Data: []byte("run_id: r\nstatus: 3\n")},
+		"r/metrics/loss": {Data: []byte("not a metric\n")},
+	})
+	require.ErrorContains(t, err, "metric loss line 1")
+}
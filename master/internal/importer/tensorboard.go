@@ -0,0 +1,410 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// Field numbers of the TensorFlow protos that hold scalars in event files. The protos are decoded
+// by hand so that the master does not depend on TensorFlow.
+const (
+	eventWallTime = 1 // Event.wall_time, double
+	eventStep     = 2 // Event.step, int64
+	eventSummary  = 5 // Event.summary, Summary
+
+	summaryValue = 1 // Summary.value, repeated Summary.Value
+
+	valueTag         = 1 // Summary.Value.tag, string
+	valueSimpleValue = 2 // Summary.Value.simple_value, float
+	valueTensor      = 8 // Summary.Value.tensor, TensorProto
+	valueMetadata    = 9 // Summary.Value.metadata, SummaryMetadata
+
+	metadataPluginData = 1 // SummaryMetadata.plugin_data, PluginData
+	metadataDataClass  = 4 // SummaryMetadata.data_class, DataClass
+	pluginName         = 1 // PluginData.plugin_name, string
+
+	tensorDtype   = 1  // TensorProto.dtype, DataType
+	tensorContent = 4  // TensorProto.tensor_content, bytes
+	tensorFloat   = 5  // TensorProto.float_val, repeated float
+	tensorDouble  = 6  // TensorProto.double_val, repeated double
+	tensorInt     = 7  // TensorProto.int_val, repeated int32
+	tensorInt64   = 10 // TensorProto.int64_val, repeated int64
+
+	dtFloat  = 1
+	dtDouble = 2
+	dtInt32  = 3
+	dtInt64  = 9
+
+	dataClassScalar = 1
+	scalarsPlugin   = "scalars"
+)
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// tensorBoardGroups maps the subdirectories that frameworks such as Keras write event files to
+// onto metric groups. Event files in the root of a run hold training metrics; other
+// subdirectories become custom metric groups.
+var tensorBoardGroups = map[string]model.MetricGroup{
+	"train":      model.TrainingMetricGroup,
+	"training":   model.TrainingMetricGroup,
+	"val":        model.ValidationMetricGroup,
+	"validation": model.ValidationMetricGroup,
+	"eval":       model.ValidationMetricGroup,
+}
+
+// ReadTensorBoard reads the scalars of the TensorBoard event files under the root of fsys into an
+// experiment with the name. Every top-level directory that holds event files is a run, unless
+// the root itself holds event files, in which case it is the only run.
+func ReadTensorBoard(fsys fs.FS, name string) (*Experiment, error) {
+	var files []string
+	inRoot := false
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.Contains(d.Name(), "tfevents") {
+			return err
+		}
+		files = append(files, p)
+		inRoot = inRoot || !strings.Contains(p, "/")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, errors.New("no TensorBoard event files found")
+	}
+
+	runFiles := map[string][]string{}
+	for _, p := range files {
+		run := "."
+		if !inRoot {
+			run, _, _ = strings.Cut(p, "/")
+		}
+		runFiles[run] = append(runFiles[run], p)
+	}
+	runDirs := make([]string, 0, len(runFiles))
+	for run := range runFiles {
+		runDirs = append(runDirs, run)
+	}
+	sort.Strings(runDirs)
+
+	exp := &Experiment{Source: SourceTensorBoard, ExternalID: name, Name: name}
+	for _, dir := range runDirs {
+		run, err := readTensorBoardRun(fsys, dir, runFiles[dir])
+		if err != nil {
+			return nil, fmt.Errorf("reading TensorBoard run %s: %w", dir, err)
+		}
+		if dir == "." {
+			run.ExternalID = name
+		}
+		exp.Runs = append(exp.Runs, *run)
+	}
+	return exp, nil
+}
+
+func readTensorBoardRun(fsys fs.FS, dir string, files []string) (*Run, error) {
+	run := &Run{ExternalID: dir, Hparams: map[string]any{}, State: model.CompletedState}
+	steps := stepBuilder{}
+	var first, last time.Time
+	for _, p := range files {
+		rel := p
+		if dir != "." {
+			rel = strings.TrimPrefix(p, dir+"/")
+		}
+		group := model.TrainingMetricGroup
+		if sub := path.Dir(rel); sub != "." {
+			var ok bool
+			if group, ok = tensorBoardGroups[sub]; !ok {
+				group = model.MetricGroup(strings.NewReplacer(".", "_", "/", "_").Replace(sub))
+			}
+		}
+
+		f, err := fsys.Open(p)
+		if err != nil {
+			return nil, err
+		}
+		err = readEventFile(f, func(wallTime float64, step int64, tag string, v float64) {
+			sec, frac := math.Modf(wallTime)
+			t := time.Unix(int64(sec), int64(frac*1e9))
+			if first.IsZero() || t.Before(first) {
+				first = t
+			}
+			if t.After(last) {
+				last = t
+			}
+			steps.add(group, int(step), t, tag, v)
+		})
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	run.Metrics = steps.build()
+	run.StartTime = first
+	if !last.IsZero() {
+		run.EndTime = &last
+	}
+	return run, nil
+}
+
+// readEventFile calls fn for every scalar in an event file. Event files are TFRecord files: each
+// record is a little-endian uint64 length, a masked CRC32C of the length, the data and a masked
+// CRC32C of the data. A truncated last record, e.g. of a file still being written, is ignored.
+func readEventFile(
+	r io.Reader, fn func(wallTime float64, step int64, tag string, v float64),
+) error {
+	br := bufio.NewReader(r)
+	var header [12]byte
+	var footer [4]byte
+	// TF2 writes the summary metadata only with the first value of a tag.
+	scalarTags := map[string]bool{}
+	for {
+		if _, err := io.ReadFull(br, header[:]); errors.Is(err, io.EOF) ||
+			errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		length := binary.LittleEndian.Uint64(header[:8])
+		if maskedCRC(header[:8]) != binary.LittleEndian.Uint32(header[8:]) {
+			return errors.New("corrupt record length")
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(br, data); errors.Is(err, io.EOF) ||
+			errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(br, footer[:]); errors.Is(err, io.EOF) ||
+			errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if maskedCRC(data) != binary.LittleEndian.Uint32(footer[:]) {
+			return errors.New("corrupt record")
+		}
+		if err := parseEvent(data, scalarTags, fn); err != nil {
+			return err
+		}
+	}
+}
+
+func maskedCRC(b []byte) uint32 {
+	crc := crc32.Checksum(b, crc32c)
+	return ((crc >> 15) | (crc << 17)) + 0xa282ead8
+}
+
+// protoField is a field of an encoded proto message. Fixed32 and fixed64 values are both held in
+// fixed.
+type protoField struct {
+	num    protowire.Number
+	typ    protowire.Type
+	varint uint64
+	fixed  uint64
+	bytes  []byte
+}
+
+func parseFields(b []byte) ([]protoField, error) {
+	var fields []protoField
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		f := protoField{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.varint, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(b)
+			f.fixed = uint64(v)
+		case protowire.Fixed64Type:
+			f.fixed, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+func parseEvent(
+	b []byte, scalarTags map[string]bool, fn func(wallTime float64, step int64, tag string, v float64),
+) error {
+	fields, err := parseFields(b)
+	if err != nil {
+		return err
+	}
+	var wallTime float64
+	var step int64
+	var summary []byte
+	for _, f := range fields {
+		switch f.num {
+		case eventWallTime:
+			wallTime = math.Float64frombits(f.fixed)
+		case eventStep:
+			step = int64(f.varint)
+		case eventSummary:
+			summary = f.bytes
+		}
+	}
+	if summary == nil {
+		return nil
+	}
+
+	values, err := parseFields(summary)
+	if err != nil {
+		return err
+	}
+	for _, v := range values {
+		if v.num != summaryValue {
+			continue
+		}
+		tag, value, ok, err := parseSummaryValue(v.bytes, scalarTags)
+		if err != nil {
+			return err
+		}
+		if ok {
+			fn(wallTime, step, tag, value)
+		}
+	}
+	return nil
+}
+
+// parseSummaryValue returns the value of a scalar summary. Values of other kinds, e.g. images and
+// histograms, are skipped.
+func parseSummaryValue(b []byte, scalarTags map[string]bool) (string, float64, bool, error) {
+	fields, err := parseFields(b)
+	if err != nil {
+		return "", 0, false, err
+	}
+	var tag string
+	var simpleValue *float64
+	var tensor, metadata []byte
+	for _, f := range fields {
+		switch f.num {
+		case valueTag:
+			tag = string(f.bytes)
+		case valueSimpleValue:
+			v := float64(math.Float32frombits(uint32(f.fixed)))
+			simpleValue = &v
+		case valueTensor:
+			tensor = f.bytes
+		case valueMetadata:
+			metadata = f.bytes
+		}
+	}
+
+	if simpleValue != nil {
+		return tag, *simpleValue, true, nil
+	}
+	if metadata != nil {
+		isScalar, err := isScalarMetadata(metadata)
+		if err != nil {
+			return "", 0, false, err
+		}
+		scalarTags[tag] = isScalar
+	}
+	if tensor == nil || !scalarTags[tag] {
+		return "", 0, false, nil
+	}
+	v, ok, err := tensorScalar(tensor)
+	return tag, v, ok, err
+}
+
+func isScalarMetadata(b []byte) (bool, error) {
+	fields, err := parseFields(b)
+	if err != nil {
+		return false, err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case metadataDataClass:
+			if f.varint == dataClassScalar {
+				return true, nil
+			}
+		case metadataPluginData:
+			plugin, err := parseFields(f.bytes)
+			if err != nil {
+				return false, err
+			}
+			for _, p := range plugin {
+				if p.num == pluginName && string(p.bytes) == scalarsPlugin {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// tensorScalar returns the first element of a numeric tensor.
+func tensorScalar(b []byte) (float64, bool, error) {
+	fields, err := parseFields(b)
+	if err != nil {
+		return 0, false, err
+	}
+	var dtype uint64
+	for _, f := range fields {
+		if f.num == tensorDtype {
+			dtype = f.varint
+		}
+	}
+	for _, f := range fields {
+		switch {
+		case f.num == tensorContent:
+			switch {
+			case dtype == dtFloat && len(f.bytes) >= 4:
+				return float64(math.Float32frombits(binary.LittleEndian.Uint32(f.bytes))), true, nil
+			case dtype == dtDouble && len(f.bytes) >= 8:
+				return math.Float64frombits(binary.LittleEndian.Uint64(f.bytes)), true, nil
+			case dtype == dtInt32 && len(f.bytes) >= 4:
+				return float64(int32(binary.LittleEndian.Uint32(f.bytes))), true, nil
+			case dtype == dtInt64 && len(f.bytes) >= 8:
+				return float64(int64(binary.LittleEndian.Uint64(f.bytes))), true, nil
+			}
+		case f.num == tensorFloat && f.typ == protowire.Fixed32Type:
+			return float64(math.Float32frombits(uint32(f.fixed))), true, nil
+		case f.num == tensorFloat && len(f.bytes) >= 4:
+			return float64(math.Float32frombits(binary.LittleEndian.Uint32(f.bytes))), true, nil
+		case f.num == tensorDouble && f.typ == protowire.Fixed64Type:
+			return math.Float64frombits(f.fixed), true, nil
+		case f.num == tensorDouble && len(f.bytes) >= 8:
+			return math.Float64frombits(binary.LittleEndian.Uint64(f.bytes)), true, nil
+		case (f.num == tensorInt || f.num == tensorInt64) && f.typ == protowire.VarintType:
+			return float64(int64(f.varint)), true, nil
+		case (f.num == tensorInt || f.num == tensorInt64) && len(f.bytes) > 0:
+			v, n := protowire.ConsumeVarint(f.bytes)
+			if n < 0 {
+				return 0, false, protowire.ParseError(n)
+			}
+			return float64(int64(v)), true, nil
+		}
+	}
+	return 0, false, nil
+}
@@ -0,0 +1,135 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// tfRecord frames data as a TFRecord.
+func tfRecord(data []byte) []byte {
+	var b bytes.Buffer
+	length := binary.LittleEndian.AppendUint64(nil, uint64(len(data)))
+	b.Write(length)
+	b.Write(binary.LittleEndian.AppendUint32(nil, maskedCRC(length)))
+	b.Write(data)
+	b.Write(binary.LittleEndian.AppendUint32(nil, maskedCRC(data)))
+	return b.Bytes()
+}
+
+func event(wallTime float64, step int64, values ...[]byte) []byte {
+	var summary []byte
+	for _, v := range values {
+		summary = protowire.AppendTag(summary, summaryValue, protowire.BytesType)
+		summary = protowire.AppendBytes(summary, v)
+	}
+	var b []byte
+	b = protowire.AppendTag(b, eventWallTime, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, math.Float64bits(wallTime))
+	b = protowire.AppendTag(b, eventStep, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(step))
+	b = protowire.AppendTag(b, eventSummary, protowire.BytesType)
+	return protowire.AppendBytes(b, summary)
+}
+
+// simpleValue is a scalar as written by TF1 and PyTorch.
+func simpleValue(tag string, v float32) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, valueTag, protowire.BytesType)
+	b = protowire.AppendString(b, tag)
+	b = protowire.AppendTag(b, valueSimpleValue, protowire.Fixed32Type)
+	return protowire.AppendFixed32(b, math.Float32bits(v))
+}
+
+// tensorValue is a scalar as written by TF2, which only sets the metadata on the first value of
+// a tag.
+func tensorValue(tag string, v float32, withMetadata bool) []byte {
+	var tensor []byte
+	tensor = protowire.AppendTag(tensor, tensorDtype, protowire.VarintType)
+	tensor = protowire.AppendVarint(tensor, dtFloat)
+	tensor = protowire.AppendTag(tensor, tensorContent, protowire.BytesType)
+	tensor = protowire.AppendBytes(tensor, binary.LittleEndian.AppendUint32(nil, math.Float32bits(v)))
+
+	var b []byte
+	b = protowire.AppendTag(b, valueTag, protowire.BytesType)
+	b = protowire.AppendString(b, tag)
+	b = protowire.AppendTag(b, valueTensor, protowire.BytesType)
+	b = protowire.AppendBytes(b, tensor)
+	if withMetadata {
+		var plugin []byte
+		plugin = protowire.AppendTag(plugin, pluginName, protowire.BytesType)
+		plugin = protowire.AppendString(plugin, scalarsPlugin)
+		var metadata []byte
+		metadata = protowire.AppendTag(metadata, metadataPluginData, protowire.BytesType)
+		metadata = protowire.AppendBytes(metadata, plugin)
+		b = protowire.AppendTag(b, valueMetadata, protowire.BytesType)
+		b = protowire.AppendBytes(b, metadata)
+	}
+	return b
+}
+
+func TestReadTensorBoard(t *testing.T) {
+	var train bytes.Buffer
+	// The file version event has no summary.
+	train.Write(tfRecord(protowire.AppendString(
+		protowire.AppendTag(nil, 3, protowire.BytesType), "brain.Event:2")))
+	train.Write(tfRecord(event(1700000010, 10, tensorValue("loss", 0.5, true))))
+	train.Write(tfRecord(event(1700000020, 20, tensorValue("loss", 0.25, false))))
+	// A record that was cut off while being written is ignored.
+	train.Write(tfRecord(event(1700000030, 30, tensorValue("loss", 0.1, false)))[:10])
+
+	var val bytes.Buffer
+	val.Write(tfRecord(event(1700000021.5, 20, simpleValue("loss", 0.75))))
+
+	fsys := fstest.MapFS{
+		"run-a/train/events.out.tfevents.1.host":      {Data: train.Bytes()},
+		"run-a/validation/events.out.tfevents.1.host": {Data: val.Bytes()},
+		"run-a/train/plugins/profile/trace.json":      {Data: []byte("{}")},
+		"run-b/events.out.tfevents.2.host":            {Data: tfRecord(event(1700000040, 1, simpleValue("acc", 0.5)))},
+	}
+	exp, err := ReadTensorBoard(fsys, "logs")
+	require.NoError(t, err)
+	require.Equal(t, SourceTensorBoard, exp.Source)
+	require.Equal(t, "logs", exp.Name)
+	require.Len(t, exp.Runs, 2)
+
+	runA := exp.Runs[0]
+	require.Equal(t, "run-a", runA.ExternalID)
+	require.Equal(t, model.CompletedState, runA.State)
+	require.Equal(t, time.Unix(1700000010, 0), runA.StartTime)
+	require.Equal(t, time.Unix(1700000021, 5e8), *runA.EndTime)
+	require.Equal(t, map[model.MetricGroup][]Step{
+		model.TrainingMetricGroup: {
+			{Batches: 10, Time: time.Unix(1700000010, 0), Values: map[string]float64{"loss": 0.5}},
+			{Batches: 20, Time: time.Unix(1700000020, 0), Values: map[string]float64{"loss": 0.25}},
+		},
+		model.ValidationMetricGroup: {
+			{Batches: 20, Time: time.Unix(1700000021, 5e8), Values: map[string]float64{"loss": 0.75}},
+		},
+	}, runA.Metrics)
+	require.Equal(t, "run-b", exp.Runs[1].ExternalID)
+
+	// Event files in the root make it the only run.
+	fsys["events.out.tfevents.3.host"] = &fstest.MapFile{Data: val.Bytes()}
+	exp, err = ReadTensorBoard(fsys, "logs")
+	require.NoError(t, err)
+	require.Len(t, exp.Runs, 1)
+	require.Equal(t, "logs", exp.Runs[0].ExternalID)
+	require.Contains(t, exp.Runs[0].Metrics, model.MetricGroup("run-a_train"))
+
+	corrupt := tfRecord(event(1, 1, simpleValue("loss", 1)))
+	corrupt[len(corrupt)-1] ^= 0xff
+	_, err = ReadTensorBoard(fstest.MapFS{"events.out.tfevents": {Data: corrupt}}, "x")
+	require.ErrorContains(t, err, "corrupt record")
+
+	_, err = ReadTensorBoard(fstest.MapFS{"README": {Data: []byte("hi")}}, "x")
+	require.ErrorContains(t, err, "no TensorBoard event files found")
+}
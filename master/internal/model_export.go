@@ -0,0 +1,266 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/uptrace/bun"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/rm"
+	"github.com/determined-ai/determined/master/internal/rm/tasklist"
+	"github.com/determined-ai/determined/master/internal/sproto"
+	"github.com/determined-ai/determined/master/internal/task"
+	"github.com/determined-ai/determined/master/internal/user"
+	"github.com/determined-ai/determined/master/pkg/logger"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/tasks"
+)
+
+// Model version export states, matching the enum model_version_export_state in Postgres.
+const (
+	modelExportRunning   = "RUNNING"
+	modelExportCompleted = "COMPLETED"
+	modelExportError     = "ERROR"
+)
+
+// runModelExportTask runs the export exportIDs[0] of a model version and records how it ended.
+// Like checkpoint GC, it runs on a single agent without slots.
+func runModelExportTask(
+	rm rm.ResourceManager,
+	pgDB *db.PgDB,
+	exportIDs []int,
+	taskID model.TaskID,
+	jobID model.JobID,
+	jobSubmissionTime time.Time,
+	exportSpec tasks.ModelExportSpec,
+	logCtx logger.Context,
+) {
+	logCtx = logger.MergeContexts(logCtx, logger.Context{
+		"task-id":   taskID,
+		"task-type": model.TaskTypeModelExport,
+	})
+	syslog := logrus.WithField("component", "modelexport").WithFields(logCtx.Fields())
+
+	err := startModelExportTask(rm, pgDB, exportIDs, taskID, jobID, jobSubmissionTime, exportSpec, logCtx, syslog)
+	state, errMsg := modelExportCompleted, (*string)(nil)
+	if err != nil {
+		syslog.WithError(err).Errorf("model version export %d failed", exportIDs[0])
+		msg := err.Error()
+		state, errMsg = modelExportError, &msg
+	}
+	if _, err := db.Bun().NewUpdate().Table("model_version_exports").
+		Set("state = ?", state).
+		Set("error = ?", errMsg).
+		Set("end_time = ?", time.Now().UTC()).
+		Where("id IN (?)", bun.In(exportIDs)).
+		Exec(context.TODO()); err != nil {
+		syslog.WithError(err).Errorf("recording the end of model version export %d", exportIDs[0])
+	}
+}
+
+// startModelExportTask runs the task of exportSpec and links it to exportIDs.
+func startModelExportTask(
+	rm rm.ResourceManager,
+	pgDB *db.PgDB,
+	exportIDs []int,
+	taskID model.TaskID,
+	jobID model.JobID,
+	jobSubmissionTime time.Time,
+	exportSpec tasks.ModelExportSpec,
+	logCtx logger.Context,
+	syslog *logrus.Entry,
+) error {
+	rp, err := rm.ResolveResourcePool("", -1, 0)
+	if err != nil {
+		return fmt.Errorf("resolving resource pool: %w", err)
+	}
+
+	// exportSpec.Base is just a shallow copy of the m.taskSpec on the master, so
+	// use caution when mutating it.
+	tcd, err := rm.TaskContainerDefaults(
+		rp,
+		config.GetMasterConfig().TaskContainerDefaults)
+	if err != nil {
+		return fmt.Errorf("creating task container defaults: %v", err)
+	}
+	exportSpec.Base.TaskContainerDefaults = tcd
+
+	if err := pgDB.AddTask(&model.Task{
+		TaskID:     taskID,
+		TaskType:   model.TaskTypeModelExport,
+		StartTime:  time.Now().UTC(),
+		JobID:      &jobID,
+		LogVersion: model.CurrentTaskLogVersion,
+	}); err != nil {
+		return errors.Wrapf(err, "persisting model export task %s", taskID)
+	}
+	if _, err := db.Bun().NewUpdate().Table("model_version_exports").
+		Set("task_id = ?", taskID).
+		Where("id IN (?)", bun.In(exportIDs)).
+		Exec(context.TODO()); err != nil {
+		return errors.Wrapf(err, "linking model version exports %v to task %s", exportIDs, taskID)
+	}
+
+	allocationID := model.AllocationID(fmt.Sprintf("%s.%d", taskID, 1))
+	exportJobID := model.JobID(fmt.Sprintf("model_export-%s", allocationID))
+
+	resultChan := make(chan error, 1)
+	onExit := func(ae *task.AllocationExited) {
+		if err := pgDB.CompleteTask(taskID, time.Now().UTC()); err != nil {
+			syslog.WithError(err).Error("marking model export task complete")
+		}
+		if err := tasklist.GroupPriorityChangeRegistry.Delete(exportJobID); err != nil {
+			syslog.WithError(err).Error("deleting group priority change registry")
+		}
+		resultChan <- ae.Err
+	}
+
+	if err := tasklist.GroupPriorityChangeRegistry.Add(exportJobID, nil); err != nil {
+		return err
+	}
+	err = task.DefaultService.StartAllocation(logCtx, sproto.AllocateRequest{
+		TaskID:            taskID,
+		JobID:             exportJobID,
+		JobSubmissionTime: jobSubmissionTime,
+		AllocationID:      allocationID,
+		Name:              exportSpec.Description,
+		FittingRequirements: sproto.FittingRequirements{
+			SingleAgent: true,
+		},
+		ResourcePool: rp,
+	}, pgDB, rm, exportSpec, onExit)
+	if err != nil {
+		return err
+	}
+	return <-resultChan
+}
+
+// failInterruptedModelExports fails the exports that were running when the master stopped, since
+// nothing is left waiting on their tasks.
+func failInterruptedModelExports(ctx context.Context) error {
+	if _, err := db.Bun().NewUpdate().Table("model_version_exports").
+		Set("state = ?", modelExportError).
+		Set("error = ?", "the master restarted while the export was running").
+		Set("end_time = ?", time.Now().UTC()).
+		Where("state = ?", modelExportRunning).
+		Exec(ctx); err != nil {
+		return fmt.Errorf("failing interrupted model version exports: %w", err)
+	}
+	return nil
+}
+
+func (m *Master) periodicallyDeleteOrphanedModelExports(ctx context.Context) {
+	t := time.NewTicker(10 * time.Minute)
+	defer t.Stop()
+	for {
+		if err := m.deleteOrphanedModelExports(ctx); err != nil {
+			logrus.WithError(err).Error("failed to delete exports of deleted model versions")
+		}
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// deleteOrphanedModelExports deletes the files of the exports of deleted model versions, and then
+// their rows, with a task per checkpoint storage the exports are in.
+func (m *Master) deleteOrphanedModelExports(ctx context.Context) error {
+	var orphans []struct {
+		CheckpointUUID uuid.UUID
+		IDs            []int    `bun:",array"`
+		UUIDs          []string `bun:",array"`
+	}
+	if err := db.Bun().NewSelect().Table("model_version_exports").
+		ColumnExpr("checkpoint_uuid").
+		ColumnExpr("array_agg(id ORDER BY id) AS ids").
+		ColumnExpr("array_agg(uuid::text ORDER BY id) AS uuids").
+		Where("model_version_id IS NULL").
+		Where("state <> ?", modelExportRunning).
+		Group("checkpoint_uuid").
+		Scan(ctx, &orphans); err != nil {
+		return fmt.Errorf("listing exports of deleted model versions: %w", err)
+	}
+	for _, o := range orphans {
+		if err := m.deleteModelExportFiles(ctx, o.CheckpointUUID, o.IDs, o.UUIDs); err != nil {
+			logrus.WithError(err).Errorf("deleting model version exports %v", o.IDs)
+			continue
+		}
+		if _, err := db.Bun().NewDelete().Table("model_version_exports").
+			Where("id IN (?)", bun.In(o.IDs)).
+			Exec(ctx); err != nil {
+			return fmt.Errorf("deleting model version exports %v: %w", o.IDs, err)
+		}
+		logrus.Infof("deleted model version exports %v of deleted model versions", o.IDs)
+	}
+	return nil
+}
+
+// deleteModelExportFiles deletes the files of the exports exportUUIDs from the checkpoint storage of
+// checkpointUUID, as the owner of the experiment of the checkpoint.
+func (m *Master) deleteModelExportFiles(
+	ctx context.Context, checkpointUUID uuid.UUID, exportIDs []int, exportUUIDs []string,
+) error {
+	legacyConfig, err := m.getCheckpointLegacyConfig(checkpointUUID)
+	if err != nil {
+		return fmt.Errorf("retrieving experiment config for checkpoint %s: %w", checkpointUUID, err)
+	}
+	if legacyConfig == nil {
+		logrus.Warnf("checkpoint %s of model version exports %v no longer exists, "+
+			"leaving their files behind", checkpointUUID, exportIDs)
+		return nil
+	}
+	var exp struct {
+		OwnerID     model.UserID
+		WorkspaceID int
+	}
+	if err := db.Bun().NewSelect().
+		TableExpr("checkpoints_view AS c").
+		ColumnExpr("e.owner_id, p.workspace_id").
+		Join("JOIN experiments AS e ON e.id = c.experiment_id").
+		Join("JOIN projects AS p ON p.id = e.project_id").
+		Where("c.uuid = ?", checkpointUUID).
+		Scan(ctx, &exp); err != nil {
+		return fmt.Errorf("getting the experiment of checkpoint %s: %w", checkpointUUID, err)
+	}
+	owner, err := user.ByID(ctx, exp.OwnerID)
+	if err != nil {
+		return err
+	}
+	ownerUser := owner.ToUser()
+	agentUserGroup, err := user.GetAgentUserGroup(ctx, ownerUser.ID, exp.WorkspaceID)
+	if err != nil {
+		return err
+	}
+
+	jobID := model.NewJobID()
+	if err := m.db.AddJob(&model.Job{
+		JobID:   jobID,
+		JobType: model.JobTypeModelExport,
+		OwnerID: &ownerUser.ID,
+	}); err != nil {
+		return fmt.Errorf("persisting new job: %w", err)
+	}
+	taskSpec := *m.taskSpec
+	taskSpec.AgentUserGroup = agentUserGroup
+	taskSpec.Owner = &ownerUser
+	taskSpec.WorkspaceID = exp.WorkspaceID
+	exportSpec := tasks.ModelExportSpec{
+		Base:         taskSpec,
+		LegacyConfig: *legacyConfig,
+		Delete:       exportUUIDs,
+		Description:  fmt.Sprintf("Model Export Deletion (Checkpoint %s)", checkpointUUID),
+	}
+	taskID := model.NewTaskID()
+	logCtx := logger.Context{"task-id": taskID, "task-type": model.TaskTypeModelExport}
+	syslog := logrus.WithField("component", "modelexport").WithFields(logCtx.Fields())
+	return startModelExportTask(
+		m.rm, m.db, exportIDs, taskID, jobID, time.Now().UTC(), exportSpec, logCtx, syslog)
+}
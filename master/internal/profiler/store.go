@@ -0,0 +1,277 @@
+package profiler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/proto/pkg/trialv1"
+)
+
+// TrialSummary is the summary of the profiler metrics of a trial.
+type TrialSummary struct {
+	TrialID   int       `json:"trial_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Summary
+}
+
+// ExperimentSummary is the summary of the profiler metrics of every trial of an experiment,
+// along with the summary of each trial.
+type ExperimentSummary struct {
+	ExperimentID int       `json:"experiment_id"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Summary
+	Trials []TrialSummary `json:"trials"`
+}
+
+// summaryTable describes a table of summaries keyed by one ID column.
+type summaryTable struct {
+	name string
+	key  string
+}
+
+var (
+	trialSummaries      = summaryTable{name: "trial_profiler_summaries", key: "trial_id"}
+	experimentSummaries = summaryTable{name: "experiment_profiler_summaries", key: "experiment_id"}
+)
+
+type summaryRow struct {
+	Stats     Stats     `bun:"stats,type:jsonb"`
+	UpdatedAt time.Time `bun:"updated_at"`
+}
+
+const (
+	// summarizeInterval is how often newly reported profiler metrics are folded into summaries.
+	summarizeInterval = 10 * time.Second
+	// summarizeChunkSize is how many rows of raw profiler metrics are folded per transaction.
+	summarizeChunkSize = 10000
+)
+
+// Summarize folds newly reported profiler metrics into the summaries of their trials and
+// experiments every summarizeInterval, until ctx is canceled. Reports only insert the raw metrics,
+// so they never wait on each other for the summaries. Metrics reported before summaries existed
+// are folded in the same way.
+func Summarize(ctx context.Context) {
+	t := time.NewTicker(summarizeInterval)
+	defer t.Stop()
+	for {
+		if err := summarize(ctx); err != nil {
+			log.WithError(err).Error("failed to update profiler summaries")
+		}
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// summarize folds the raw profiler metrics that weren't folded yet into the summaries of their
+// trials and experiments.
+func summarize(ctx context.Context) error {
+	for {
+		var done bool
+		if err := db.Bun().RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+			var err error
+			done, err = summarizeChunkTx(ctx, tx)
+			return err
+		}); err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+type metricsRow struct {
+	ID           int64     `bun:"id"`
+	Values       []float32 `bun:"values,array"`
+	Labels       string    `bun:"labels"`
+	TrialID      *int      `bun:"trial_id"`
+	ExperimentID *int      `bun:"experiment_id"`
+	Slots        *int      `bun:"slots"`
+}
+
+// summarizeChunkTx folds the next chunk of raw profiler metrics that weren't folded yet into the
+// summaries and marks them as folded, returning whether nothing is left to fold. Rows are marked
+// one by one rather than tracked by a high-water mark, since IDs aren't committed in order.
+func summarizeChunkTx(ctx context.Context, tx bun.Tx) (bool, error) {
+	// Metrics of trials that were deleted since they were reported are skipped.
+	var rows []metricsRow
+	if err := tx.NewRaw(`
+SELECT m.id, m.values, m.labels::text AS labels, r.id AS trial_id, r.experiment_id,
+	(e.config->'resources'->>'slots_per_trial')::int AS slots
+FROM trial_profiler_metrics m
+LEFT JOIN runs r ON r.id = (m.labels->>'trialId')::int
+LEFT JOIN experiments e ON e.id = r.experiment_id
+WHERE NOT m.summarized
+ORDER BY m.id
+LIMIT ?
+FOR UPDATE OF m SKIP LOCKED`, summarizeChunkSize).Scan(ctx, &rows); err != nil {
+		return false, fmt.Errorf("getting profiler metrics to summarize: %w", err)
+	}
+	if len(rows) == 0 {
+		return true, nil
+	}
+
+	trialDeltas := map[int]*Stats{}
+	experimentDeltas := map[int]*Stats{}
+	experimentOf := map[int]int{}
+	for _, r := range rows {
+		if r.TrialID == nil || r.ExperimentID == nil {
+			continue
+		}
+		var labels trialv1.TrialProfilerMetricLabels
+		if err := protojson.Unmarshal([]byte(r.Labels), &labels); err != nil {
+			log.WithError(err).Warnf("skipping profiler metrics %d with malformed labels", r.ID)
+			continue
+		}
+		slots := 1
+		if r.Slots != nil {
+			slots = *r.Slots
+		}
+		if trialDeltas[*r.TrialID] == nil {
+			trialDeltas[*r.TrialID] = &Stats{}
+			experimentOf[*r.TrialID] = *r.ExperimentID
+		}
+		trialDeltas[*r.TrialID].Add(&trialv1.TrialProfilerMetricsBatch{
+			Values: r.Values,
+			Labels: &labels,
+		}, slots)
+	}
+	for trialID, delta := range trialDeltas {
+		if err := mergeTx(ctx, tx, trialSummaries, trialID, *delta); err != nil {
+			return false, fmt.Errorf("updating profiler summary of trial %d: %w", trialID, err)
+		}
+		experimentID := experimentOf[trialID]
+		if experimentDeltas[experimentID] == nil {
+			experimentDeltas[experimentID] = &Stats{}
+		}
+		experimentDeltas[experimentID].Merge(*delta)
+	}
+	for experimentID, delta := range experimentDeltas {
+		if err := mergeTx(ctx, tx, experimentSummaries, experimentID, *delta); err != nil {
+			return false, fmt.Errorf("updating profiler summary of experiment %d: %w", experimentID, err)
+		}
+	}
+
+	ids := make([]int64, 0, len(rows))
+	for _, r := range rows {
+		ids = append(ids, r.ID)
+	}
+	if _, err := tx.NewRaw("UPDATE trial_profiler_metrics SET summarized = true WHERE id IN (?)",
+		bun.In(ids),
+	).Exec(ctx); err != nil {
+		return false, fmt.Errorf("marking profiler metrics as summarized: %w", err)
+	}
+	return len(rows) < summarizeChunkSize, nil
+}
+
+// mergeTx folds stats into a summary and updates the figures derived from it.
+func mergeTx(ctx context.Context, tx bun.Tx, t summaryTable, id int, delta Stats) error {
+	if _, err := tx.NewRaw(
+		"INSERT INTO ? (?, stats, updated_at) VALUES (?, '{}', now()) ON CONFLICT DO NOTHING",
+		bun.Ident(t.name), bun.Ident(t.key), id,
+	).Exec(ctx); err != nil {
+		return err
+	}
+	var row summaryRow
+	if err := tx.NewRaw("SELECT stats, updated_at FROM ? WHERE ? = ? FOR UPDATE",
+		bun.Ident(t.name), bun.Ident(t.key), id,
+	).Scan(ctx, &row); err != nil {
+		return err
+	}
+
+	row.Stats.Merge(delta)
+	stats, err := json.Marshal(row.Stats)
+	if err != nil {
+		return err
+	}
+	s := row.Stats.Summary()
+	var dataloaderFraction *float64
+	if f, ok := s.TimeFractions[PhaseDataloader]; ok {
+		dataloaderFraction = &f
+	}
+	_, err = tx.NewRaw(`
+UPDATE ?
+SET stats = ?::jsonb, gpu_util_mean = ?, gpu_util_p95 = ?, cpu_util_mean = ?, dataloader_fraction = ?,
+	min_free_host_memory_gb = ?, samples_per_second_per_slot = ?, bottlenecks = ?,
+	updated_at = now()
+WHERE ? = ?`,
+		bun.Ident(t.name), string(stats), s.GPUUtilMean, s.GPUUtilP95, s.CPUUtilMean, dataloaderFraction,
+		s.MinFreeHostMemoryGB, s.SamplesPerSecondPerSlot, pgdialect.Array(s.Bottlenecks),
+		bun.Ident(t.key), id,
+	).Exec(ctx)
+	return err
+}
+
+// GetTrialSummary returns the summary of the profiler metrics of a trial, or db.ErrNotFound if
+// it hasn't reported any.
+func GetTrialSummary(ctx context.Context, trialID int) (*TrialSummary, error) {
+	var row summaryRow
+	if err := db.Bun().NewSelect().
+		Table(trialSummaries.name).
+		Column("stats", "updated_at").
+		Where("trial_id = ?", trialID).
+		Scan(ctx, &row); errors.Is(err, sql.ErrNoRows) {
+		return nil, db.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("getting profiler summary of trial %d: %w", trialID, err)
+	}
+	return &TrialSummary{TrialID: trialID, UpdatedAt: row.UpdatedAt, Summary: row.Stats.Summary()}, nil
+}
+
+// GetExperimentSummary returns the summary of the profiler metrics of an experiment, or
+// db.ErrNotFound if none of its trials has reported any.
+func GetExperimentSummary(ctx context.Context, experimentID int) (*ExperimentSummary, error) {
+	var row summaryRow
+	if err := db.Bun().NewSelect().
+		Table(experimentSummaries.name).
+		Column("stats", "updated_at").
+		Where("experiment_id = ?", experimentID).
+		Scan(ctx, &row); errors.Is(err, sql.ErrNoRows) {
+		return nil, db.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("getting profiler summary of experiment %d: %w", experimentID, err)
+	}
+
+	var trials []struct {
+		TrialID int `bun:"trial_id"`
+		summaryRow
+	}
+	if err := db.Bun().NewSelect().
+		TableExpr("trial_profiler_summaries AS s").
+		Column("s.trial_id", "s.stats", "s.updated_at").
+		Join("JOIN runs r ON r.id = s.trial_id").
+		Where("r.experiment_id = ?", experimentID).
+		Order("s.trial_id").
+		Scan(ctx, &trials); err != nil {
+		return nil, fmt.Errorf("getting profiler summaries of experiment %d: %w", experimentID, err)
+	}
+
+	summary := &ExperimentSummary{
+		ExperimentID: experimentID,
+		UpdatedAt:    row.UpdatedAt,
+		Summary:      row.Stats.Summary(),
+		Trials:       make([]TrialSummary, 0, len(trials)),
+	}
+	for _, t := range trials {
+		summary.Trials = append(summary.Trials, TrialSummary{
+			TrialID:   t.TrialID,
+			UpdatedAt: t.UpdatedAt,
+			Summary:   t.Stats.Summary(),
+		})
+	}
+	return summary, nil
+}
@@ -0,0 +1,88 @@
+//go:build integration
+// +build integration
+
+package profiler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/pkg/etc"
+	"github.com/determined-ai/determined/proto/pkg/trialv1"
+)
+
+func TestSummarize(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, etc.SetRootPath(db.RootFromDB))
+	pgDB := db.MustResolveTestPostgres(t)
+	db.MustMigrateTestPostgres(t, pgDB, db.MigrationsFromDB)
+
+	user := db.RequireMockUser(t, pgDB)
+	exp := db.RequireMockExperiment(t, pgDB, user)
+	trialA := db.RequireMockTrialID(t, pgDB, exp)
+	trialB := db.RequireMockTrialID(t, pgDB, exp)
+
+	_, err := GetTrialSummary(ctx, trialA)
+	require.ErrorIs(t, err, db.ErrNotFound)
+	_, err = GetExperimentSummary(ctx, exp.ID)
+	require.ErrorIs(t, err, db.ErrNotFound)
+
+	report := func(trialID int, b *trialv1.TrialProfilerMetricsBatch) {
+		b.Labels.TrialId = int32(trialID)
+		labels, err := protojson.Marshal(b.Labels)
+		require.NoError(t, err)
+		batches := make([]int32, len(b.Values))
+		timestamps := make([]time.Time, len(b.Values))
+		require.NoError(t, pgDB.InsertTrialProfilerMetricsBatch(b.Values, batches, timestamps, labels))
+	}
+	summarizeAll := func() {
+		require.NoError(t, summarize(ctx))
+	}
+
+	report(trialA, system("gpu_util", 20, 40))
+	report(trialA, timing("dataloader_next", 1))
+	report(trialA, timing("train_batch", 1))
+	report(trialB, system("gpu_util", 90))
+	// Take an ID before summarizing, like a report that commits only after the rows after it.
+	var lateID int64
+	require.NoError(t, db.Bun().NewRaw("SELECT nextval('trial_profiler_metrics_id_seq')").
+		Scan(ctx, &lateID))
+	summarizeAll()
+	// The late report is still summarized.
+	report(trialB, system("gpu_util", 90))
+	_, err = db.Bun().NewRaw(`
+UPDATE trial_profiler_metrics SET id = ? WHERE id = (SELECT max(id) FROM trial_profiler_metrics)`,
+		lateID).Exec(ctx)
+	require.NoError(t, err)
+	// Metrics of deleted trials are skipped.
+	report(-1, system("gpu_util", 0))
+	summarizeAll()
+
+	a, err := GetTrialSummary(ctx, trialA)
+	require.NoError(t, err)
+	require.Equal(t, 30.0, *a.GPUUtilMean)
+	require.Equal(t, []string{BottleneckInputBound, BottleneckGPUUnderutilized}, a.Bottlenecks)
+
+	e, err := GetExperimentSummary(ctx, exp.ID)
+	require.NoError(t, err)
+	require.Equal(t, 60.0, *e.GPUUtilMean)
+	require.Equal(t, 90.0, *e.GPUUtilP95)
+	require.Equal(t, []string{BottleneckInputBound}, e.Bottlenecks)
+	require.Len(t, e.Trials, 2)
+	require.Equal(t, trialA, e.Trials[0].TrialID)
+	require.Equal(t, 90.0, *e.Trials[1].GPUUtilMean)
+
+	var bottlenecks []string
+	var p95 float64
+	require.NoError(t, db.Bun().NewRaw(`
+SELECT gpu_util_p95, bottlenecks FROM experiment_profiler_summaries WHERE experiment_id = ?`,
+		exp.ID).Scan(ctx, &p95, pgdialect.Array(&bottlenecks)))
+	require.Equal(t, 90.0, p95)
+	require.Equal(t, []string{BottleneckInputBound}, bottlenecks)
+}
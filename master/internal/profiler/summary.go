@@ -0,0 +1,248 @@
+// Package profiler summarizes the profiler metrics reported by trials into a few numbers per trial
+// and experiment, and flags the bottlenecks they point at.
+package profiler
+
+import (
+	"math"
+	"strings"
+
+	"github.com/determined-ai/determined/proto/pkg/trialv1"
+)
+
+// Names of the profiler metrics that are summarized, as reported by the harness.
+const (
+	gpuUtilMetric          = "gpu_util"
+	cpuUtilMetric          = "cpu_util_simple"
+	freeMemoryMetric       = "free_memory"
+	samplesPerSecondMetric = "samples_per_second"
+)
+
+// Phases that training time is split into.
+const (
+	PhaseDataloader = "dataloader"
+	PhaseCompute    = "compute"
+	PhaseTransfer   = "transfer"
+	PhaseOther      = "other"
+)
+
+// timingPhases maps timings recorded by the harness to phases. Other top-level timings count
+// towards PhaseOther; nested timings, such as train_batch.backward, are already part of their
+// parent.
+var timingPhases = map[string]string{
+	"dataloader_next": PhaseDataloader,
+	"train_batch":     PhaseCompute,
+	"to_device":       PhaseTransfer,
+	"from_device":     PhaseTransfer,
+}
+
+// Bottlenecks that a summary may flag.
+const (
+	// BottleneckInputBound means a large share of time goes to waiting for data.
+	BottleneckInputBound = "input_bound"
+	// BottleneckTransferBound means a large share of time goes to copying data to and from GPUs.
+	BottleneckTransferBound = "transfer_bound"
+	// BottleneckGPUUnderutilized means GPUs are mostly idle.
+	BottleneckGPUUnderutilized = "gpu_underutilized"
+	// BottleneckCPUBound means CPUs are saturated while GPUs are mostly idle.
+	BottleneckCPUBound = "cpu_bound"
+	// BottleneckHostMemoryPressure means the hosts ran low on memory at some point.
+	BottleneckHostMemoryPressure = "host_memory_pressure"
+)
+
+// Thresholds at which bottlenecks are flagged.
+const (
+	inputBoundFraction       = 0.25
+	transferBoundFraction    = 0.25
+	gpuUnderutilizedPercent  = 50
+	cpuBoundPercent          = 90
+	hostMemoryPressureFreeGB = 1
+)
+
+// Mean accumulates the mean of a series.
+type Mean struct {
+	Count int64   `json:"count"`
+	Sum   float64 `json:"sum"`
+}
+
+func (m *Mean) add(v float64) {
+	m.Count++
+	m.Sum += v
+}
+
+func (m *Mean) merge(o Mean) {
+	m.Count += o.Count
+	m.Sum += o.Sum
+}
+
+// Value returns the mean, or nil if nothing was added.
+func (m Mean) Value() *float64 {
+	if m.Count == 0 {
+		return nil
+	}
+	v := m.Sum / float64(m.Count)
+	return &v
+}
+
+// Stats accumulates profiler metrics such that stats of different batches, trials or experiments
+// can be merged without keeping the raw samples around.
+type Stats struct {
+	GPUUtil Mean `json:"gpu_util"`
+	// GPUUtilHistogram counts GPU utilization samples by the percentage they round to.
+	GPUUtilHistogram []int64 `json:"gpu_util_histogram,omitempty"`
+	CPUUtil          Mean    `json:"cpu_util"`
+	// TimingSeconds is the total time spent in each phase.
+	TimingSeconds map[string]float64 `json:"timing_seconds,omitempty"`
+	// MinFreeHostMemoryGB is the least host memory available at any sample.
+	MinFreeHostMemoryGB     *float64 `json:"min_free_host_memory_gb,omitempty"`
+	SamplesPerSecondPerSlot Mean     `json:"samples_per_second_per_slot"`
+}
+
+// Add folds a batch of profiler metrics of a trial with the given number of slots into the stats.
+func (s *Stats) Add(batch *trialv1.TrialProfilerMetricsBatch, slots int) {
+	labels := batch.GetLabels()
+	if slots < 1 {
+		slots = 1
+	}
+	switch labels.GetMetricType() {
+	case trialv1.TrialProfilerMetricLabels_PROFILER_METRIC_TYPE_SYSTEM:
+		for _, v := range batch.Values {
+			switch labels.GetName() {
+			case gpuUtilMetric:
+				s.GPUUtil.add(float64(v))
+				if s.GPUUtilHistogram == nil {
+					s.GPUUtilHistogram = make([]int64, 101)
+				}
+				s.GPUUtilHistogram[int(math.Round(math.Max(0, math.Min(100, float64(v)))))]++
+			case cpuUtilMetric:
+				s.CPUUtil.add(float64(v))
+			case freeMemoryMetric:
+				if s.MinFreeHostMemoryGB == nil || float64(v) < *s.MinFreeHostMemoryGB {
+					free := float64(v)
+					s.MinFreeHostMemoryGB = &free
+				}
+			}
+		}
+	case trialv1.TrialProfilerMetricLabels_PROFILER_METRIC_TYPE_TIMING:
+		phase, ok := timingPhases[labels.GetName()]
+		if !ok {
+			if strings.Contains(labels.GetName(), ".") {
+				return
+			}
+			phase = PhaseOther
+		}
+		if s.TimingSeconds == nil {
+			s.TimingSeconds = map[string]float64{}
+		}
+		for _, v := range batch.Values {
+			s.TimingSeconds[phase] += float64(v)
+		}
+	case trialv1.TrialProfilerMetricLabels_PROFILER_METRIC_TYPE_MISC:
+		if labels.GetName() != samplesPerSecondMetric {
+			return
+		}
+		// The harness reports the throughput of the whole trial.
+		for _, v := range batch.Values {
+			s.SamplesPerSecondPerSlot.add(float64(v) / float64(slots))
+		}
+	}
+}
+
+// Merge folds other stats into s.
+func (s *Stats) Merge(o Stats) {
+	s.GPUUtil.merge(o.GPUUtil)
+	if o.GPUUtilHistogram != nil {
+		if s.GPUUtilHistogram == nil {
+			s.GPUUtilHistogram = make([]int64, len(o.GPUUtilHistogram))
+		}
+		for i, n := range o.GPUUtilHistogram {
+			s.GPUUtilHistogram[i] += n
+		}
+	}
+	s.CPUUtil.merge(o.CPUUtil)
+	for phase, seconds := range o.TimingSeconds {
+		if s.TimingSeconds == nil {
+			s.TimingSeconds = map[string]float64{}
+		}
+		s.TimingSeconds[phase] += seconds
+	}
+	if o.MinFreeHostMemoryGB != nil &&
+		(s.MinFreeHostMemoryGB == nil || *o.MinFreeHostMemoryGB < *s.MinFreeHostMemoryGB) {
+		free := *o.MinFreeHostMemoryGB
+		s.MinFreeHostMemoryGB = &free
+	}
+	s.SamplesPerSecondPerSlot.merge(o.SamplesPerSecondPerSlot)
+}
+
+// Summary is what the profiler metrics of a trial or experiment come down to. Fields are nil if
+// the metrics they are computed from weren't reported.
+type Summary struct {
+	GPUUtilMean *float64 `json:"gpu_util_mean"`
+	GPUUtilP95  *float64 `json:"gpu_util_p95"`
+	CPUUtilMean *float64 `json:"cpu_util_mean"`
+	// TimeFractions is the share of training time spent in each phase.
+	TimeFractions           map[string]float64 `json:"time_fractions"`
+	MinFreeHostMemoryGB     *float64           `json:"min_free_host_memory_gb"`
+	SamplesPerSecondPerSlot *float64           `json:"samples_per_second_per_slot"`
+	Bottlenecks             []string           `json:"bottlenecks"`
+}
+
+// Summary computes the summary of the stats.
+func (s Stats) Summary() Summary {
+	sum := Summary{
+		GPUUtilMean:             s.GPUUtil.Value(),
+		GPUUtilP95:              percentile(s.GPUUtilHistogram, 0.95),
+		CPUUtilMean:             s.CPUUtil.Value(),
+		TimeFractions:           map[string]float64{},
+		MinFreeHostMemoryGB:     s.MinFreeHostMemoryGB,
+		SamplesPerSecondPerSlot: s.SamplesPerSecondPerSlot.Value(),
+		Bottlenecks:             []string{},
+	}
+	var total float64
+	for _, seconds := range s.TimingSeconds {
+		total += seconds
+	}
+	if total > 0 {
+		for phase, seconds := range s.TimingSeconds {
+			sum.TimeFractions[phase] = seconds / total
+		}
+	}
+
+	if sum.TimeFractions[PhaseDataloader] >= inputBoundFraction {
+		sum.Bottlenecks = append(sum.Bottlenecks, BottleneckInputBound)
+	}
+	if sum.TimeFractions[PhaseTransfer] >= transferBoundFraction {
+		sum.Bottlenecks = append(sum.Bottlenecks, BottleneckTransferBound)
+	}
+	if sum.GPUUtilMean != nil && *sum.GPUUtilMean < gpuUnderutilizedPercent {
+		sum.Bottlenecks = append(sum.Bottlenecks, BottleneckGPUUnderutilized)
+		if sum.CPUUtilMean != nil && *sum.CPUUtilMean >= cpuBoundPercent {
+			sum.Bottlenecks = append(sum.Bottlenecks, BottleneckCPUBound)
+		}
+	}
+	if sum.MinFreeHostMemoryGB != nil && *sum.MinFreeHostMemoryGB < hostMemoryPressureFreeGB {
+		sum.Bottlenecks = append(sum.Bottlenecks, BottleneckHostMemoryPressure)
+	}
+	return sum
+}
+
+// percentile returns the value below which the given share of the samples in a histogram of
+// integer percentages fall, or nil if it is empty.
+func percentile(histogram []int64, p float64) *float64 {
+	var total int64
+	for _, n := range histogram {
+		total += n
+	}
+	if total == 0 {
+		return nil
+	}
+	rank := int64(math.Ceil(p * float64(total)))
+	var seen int64
+	for v, n := range histogram {
+		seen += n
+		if seen >= rank {
+			value := float64(v)
+			return &value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package profiler
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/pkg/ptrs"
+	"github.com/determined-ai/determined/proto/pkg/trialv1"
+)
+
+func batch(
+	metricType trialv1.TrialProfilerMetricLabels_ProfilerMetricType, name string, values ...float32,
+) *trialv1.TrialProfilerMetricsBatch {
+	return &trialv1.TrialProfilerMetricsBatch{
+		Values: values,
+		Labels: &trialv1.TrialProfilerMetricLabels{TrialId: 1, Name: name, MetricType: metricType},
+	}
+}
+
+func system(name string, values ...float32) *trialv1.TrialProfilerMetricsBatch {
+	return batch(trialv1.TrialProfilerMetricLabels_PROFILER_METRIC_TYPE_SYSTEM, name, values...)
+}
+
+func timing(name string, values ...float32) *trialv1.TrialProfilerMetricsBatch {
+	return batch(trialv1.TrialProfilerMetricLabels_PROFILER_METRIC_TYPE_TIMING, name, values...)
+}
+
+func misc(name string, values ...float32) *trialv1.TrialProfilerMetricsBatch {
+	return batch(trialv1.TrialProfilerMetricLabels_PROFILER_METRIC_TYPE_MISC, name, values...)
+}
+
+func TestSummary(t *testing.T) {
+	var s Stats
+	gpuUtil := make([]float32, 0, 100)
+	for i := 1; i <= 100; i++ {
+		gpuUtil = append(gpuUtil, float32(i))
+	}
+	s.Add(system("gpu_util", gpuUtil...), 4)
+	s.Add(system("cpu_util_simple", 20, 40), 4)
+	s.Add(system("free_memory", 12.5, 8, 10), 4)
+	s.Add(system("disk_iops", 1000), 4)
+	s.Add(timing("dataloader_next", 1, 1), 4)
+	s.Add(timing("train_batch", 5, 1), 4)
+	s.Add(timing("train_batch.backward", 3), 4)
+	s.Add(timing("to_device", 0.5, 0.5), 4)
+	s.Add(timing("step_lr_schedulers", 1), 4)
+	s.Add(misc("samples_per_second", 400, 800), 4)
+
+	sum := s.Summary()
+	require.InDelta(t, 50.5, *sum.GPUUtilMean, 1e-9)
+	require.Equal(t, 95.0, *sum.GPUUtilP95)
+	require.Equal(t, 30.0, *sum.CPUUtilMean)
+	require.Equal(t, 8.0, *sum.MinFreeHostMemoryGB)
+	require.Equal(t, 150.0, *sum.SamplesPerSecondPerSlot)
+	require.Equal(t, map[string]float64{
+		PhaseDataloader: 0.2,
+		PhaseCompute:    0.6,
+		PhaseTransfer:   0.1,
+		PhaseOther:      0.1,
+	}, sum.TimeFractions)
+	require.Empty(t, sum.Bottlenecks)
+}
+
+func TestSummaryEmpty(t *testing.T) {
+	sum := Stats{}.Summary()
+	require.Nil(t, sum.GPUUtilMean)
+	require.Nil(t, sum.GPUUtilP95)
+	require.Nil(t, sum.MinFreeHostMemoryGB)
+	require.Empty(t, sum.TimeFractions)
+	require.Empty(t, sum.Bottlenecks)
+}
+
+func TestBottlenecks(t *testing.T) {
+	cases := []struct {
+		name        string
+		batches     []*trialv1.TrialProfilerMetricsBatch
+		bottlenecks []string
+	}{
+		{
+			name:        "input bound",
+			batches:     []*trialv1.TrialProfilerMetricsBatch{timing("dataloader_next", 3), timing("train_batch", 7)},
+			bottlenecks: []string{BottleneckInputBound},
+		},
+		{
+			name:        "transfer bound",
+			batches:     []*trialv1.TrialProfilerMetricsBatch{timing("to_device", 3), timing("train_batch", 7)},
+			bottlenecks: []string{BottleneckTransferBound},
+		},
+		{
+			name:        "gpu underutilized",
+			batches:     []*trialv1.TrialProfilerMetricsBatch{system("gpu_util", 10, 30)},
+			bottlenecks: []string{BottleneckGPUUnderutilized},
+		},
+		{
+			name: "cpu bound",
+			batches: []*trialv1.TrialProfilerMetricsBatch{
+				system("gpu_util", 10), system("cpu_util_simple", 95),
+			},
+			bottlenecks: []string{BottleneckGPUUnderutilized, BottleneckCPUBound},
+		},
+		{
+			name:        "busy cpu with busy gpu",
+			batches:     []*trialv1.TrialProfilerMetricsBatch{system("gpu_util", 90), system("cpu_util_simple", 95)},
+			bottlenecks: []string{},
+		},
+		{
+			name:        "host memory pressure",
+			batches:     []*trialv1.TrialProfilerMetricsBatch{system("free_memory", 4, 0.5)},
+			bottlenecks: []string{BottleneckHostMemoryPressure},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var s Stats
+			for _, b := range tc.batches {
+				s.Add(b, 1)
+			}
+			require.Equal(t, tc.bottlenecks, s.Summary().Bottlenecks)
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	var a, b, all Stats
+	for _, batch := range []*trialv1.TrialProfilerMetricsBatch{
+		system("gpu_util", 10, 20), timing("train_batch", 2), system("free_memory", 6),
+	} {
+		a.Add(batch, 2)
+		all.Add(batch, 2)
+	}
+	for _, batch := range []*trialv1.TrialProfilerMetricsBatch{
+		system("gpu_util", 90), timing("dataloader_next", 1), system("free_memory", 3),
+		misc("samples_per_second", 100),
+	} {
+		b.Add(batch, 2)
+		all.Add(batch, 2)
+	}
+
+	var merged Stats
+	merged.Merge(a)
+	merged.Merge(b)
+	require.Equal(t, all, merged)
+	require.Equal(t, ptrs.Ptr(3.0), merged.MinFreeHostMemoryGB)
+
+	// Stats survive the round trip through the database.
+	raw, err := json.Marshal(merged)
+	require.NoError(t, err)
+	var decoded Stats
+	require.NoError(t, json.Unmarshal(raw, &decoded))
+	require.Equal(t, merged, decoded)
+}
@@ -0,0 +1,63 @@
+package prom
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	dbTableSizeBytes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "det",
+		Name:      "db_table_size_bytes",
+		Help:      "the size of a database table including its indexes and TOAST data",
+	}, []string{"table"})
+
+	dbTableDeadTuples = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "det",
+		Name:      "db_table_dead_tuples",
+		Help:      "the estimated number of dead tuples in a database table",
+	}, []string{"table"})
+
+	dbTableLiveTuples = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: "det",
+		Name:      "db_table_live_tuples",
+		Help:      "the estimated number of live tuples in a database table",
+	}, []string{"table"})
+
+	dbMaintenanceLastRun = promauto.NewGauge(prometheus.GaugeOpts{
+		Subsystem: "det",
+		Name:      "db_maintenance_last_run_timestamp_seconds",
+		Help:      "the time the last database maintenance run finished",
+	})
+
+	dbMaintenanceRowsDeleted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "det",
+		Name:      "db_maintenance_rows_deleted_total",
+		Help:      "the number of rows deleted by database maintenance, by task",
+	}, []string{"task"})
+)
+
+func init() { //nolint: gochecknoinits
+	DetStateMetrics.MustRegister(dbTableSizeBytes)
+	DetStateMetrics.MustRegister(dbTableDeadTuples)
+	DetStateMetrics.MustRegister(dbTableLiveTuples)
+	DetStateMetrics.MustRegister(dbMaintenanceLastRun)
+	DetStateMetrics.MustRegister(dbMaintenanceRowsDeleted)
+}
+
+// SetDBTableStats records the size and tuple counts of a database table.
+func SetDBTableStats(table string, sizeBytes, liveTuples, deadTuples int64) {
+	dbTableSizeBytes.WithLabelValues(table).Set(float64(sizeBytes))
+	dbTableLiveTuples.WithLabelValues(table).Set(float64(liveTuples))
+	dbTableDeadTuples.WithLabelValues(table).Set(float64(deadTuples))
+}
+
+// ObserveDBMaintenanceRun records a finished database maintenance run.
+func ObserveDBMaintenanceRun(finishedAt time.Time, rowsDeleted map[string]int64) {
+	dbMaintenanceLastRun.Set(float64(finishedAt.Unix()))
+	for task, n := range rowsDeleted {
+		dbMaintenanceRowsDeleted.WithLabelValues(task).Add(float64(n))
+	}
+}
@@ -118,6 +118,7 @@ func (m *Master) restoreExperiment(expModel *model.Experiment) error {
 		return errors.Wrapf(err, "retrieving full user on restart")
 	}
 	taskSpec.Owner = owner
+	taskSpec.WorkspaceID = workspaceID
 
 	log.WithField("experiment", expModel.ID).Debug("restoring experiment")
 	snapshot, err := m.retrieveExperimentSnapshot(expModel)
@@ -119,6 +119,9 @@ func newAgent(
 	restoredAgentState *agentState,
 	unregister func(),
 ) *agent {
+	// The agent slices its GPUs like its resource pool says.
+	agentOpts := *opts
+	agentOpts.TimeSlicesPerGPU = rpConfig.TimeSlicesPerGPU
 	a := &agent{
 		syslog:                logrus.WithField("component", "agent").WithField("id", id),
 		id:                    id,
@@ -127,7 +130,7 @@ func newAgent(
 		resourcePoolName:      resourcePoolName,
 		maxZeroSlotContainers: rpConfig.MaxAuxContainersPerAgent,
 		agentReconnectWait:    time.Duration(rpConfig.AgentReconnectWait),
-		opts:                  opts,
+		opts:                  &agentOpts,
 		agentState:            restoredAgentState,
 		unregister:            unregister,
 	}
@@ -88,6 +88,12 @@ func newAgentResourceManager(
 			a.syslog.WithError(err).Errorf("failed to create resource pool: %s", a.poolsConfig[ix].PoolName)
 			panic(err)
 		}
+		if a.db != nil {
+			if err := loadWorkspaceMaxSlots(rp); err != nil {
+				a.syslog.WithError(err).Errorf("failed to create resource pool: %s", a.poolsConfig[ix].PoolName)
+				panic(err)
+			}
+		}
 		a.pools[config.PoolName] = rp
 	}
 	go func() {
@@ -480,6 +486,16 @@ func (a *ResourceManager) ResolveResourcePool(name string, workspaceID int, slot
 			name, workspaceID)
 	}
 
+	binding, err := db.GetRPWorkspaceBinding(ctx, workspaceID, name)
+	if err != nil {
+		return "", err
+	}
+	if binding != nil {
+		if err := binding.CheckSlots(slots); err != nil {
+			return "", fmt.Errorf("resource pool %s: %w", name, err)
+		}
+	}
+
 	if err := a.ValidateResourcePool(name); err != nil {
 		return "", fmt.Errorf("validating pool: %w", err)
 	}
@@ -512,6 +528,17 @@ func (a *ResourceManager) SetGroupMaxSlots(msg sproto.SetGroupMaxSlots) {
 	go pool.SetGroupMaxSlots(msg)
 }
 
+// SetWorkspaceMaxSlots implements rm.ResourceManager.
+func (a *ResourceManager) SetWorkspaceMaxSlots(msg sproto.SetWorkspaceMaxSlots) {
+	pool, err := a.poolByName(msg.ResourcePool)
+	if err != nil {
+		a.syslog.WithError(err).Warnf("set workspace max slots found no resource pool with name %s",
+			msg.ResourcePool)
+		return
+	}
+	pool.SetWorkspaceMaxSlots(msg)
+}
+
 // SetGroupPriority implements rm.ResourceManager.
 func (a *ResourceManager) SetGroupPriority(msg sproto.SetGroupPriority) error {
 	pool, err := a.poolByName(msg.ResourcePool)
@@ -633,6 +660,20 @@ func (a *ResourceManager) createResourcePool(
 	)
 }
 
+// loadWorkspaceMaxSlots caps the workspaces of a new pool by the max slots of their bindings to
+// it. Later changes to the bindings are pushed through SetWorkspaceMaxSlots.
+func loadWorkspaceMaxSlots(rp *resourcePool) error {
+	maxSlots, err := db.GetRPWorkspaceMaxSlots(context.TODO(), rp.config.PoolName)
+	if err != nil {
+		return fmt.Errorf("loading the workspace max slots of pool %s: %w", rp.config.PoolName, err)
+	}
+	rp.SetWorkspaceMaxSlots(sproto.SetWorkspaceMaxSlots{
+		ResourcePool: rp.config.PoolName,
+		MaxSlots:     maxSlots,
+	})
+	return nil
+}
+
 func (a *ResourceManager) poolByName(name string) (*resourcePool, error) {
 	if name == "" {
 		return nil, errors.New("invalid call: cannot get a resource pool with no name")
@@ -3,6 +3,7 @@ package agentrm
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
 
 	"github.com/google/uuid"
@@ -161,18 +162,9 @@ func (a *agentState) allocateFreeDevices(slots int, cid cproto.ID) ([]device.Dev
 		return nil, nil
 	}
 
-	devices := make([]device.Device, 0, slots)
-	for d, dcid := range a.Devices {
-		if dcid == nil {
-			devices = append(devices, d)
-		}
-		if len(devices) == slots {
-			break
-		}
-	}
-
-	if len(devices) != slots {
-		return nil, errors.New("not enough devices")
+	devices, err := a.pickFreeDevices(slots)
+	if err != nil {
+		return nil, err
 	}
 
 	for _, d := range devices {
@@ -184,6 +176,66 @@ func (a *agentState) allocateFreeDevices(slots int, cid cproto.ID) ([]device.Dev
 	return devices, nil
 }
 
+// fitsSlots returns whether the free devices of the agent can run a container with slots.
+func (a *agentState) fitsSlots(slots int) bool {
+	if slots > a.numEmptySlots() {
+		return false
+	}
+	_, err := a.pickFreeDevices(slots)
+	return err == nil
+}
+
+// pickFreeDevices picks free devices for a container with slots. Agents in pools that time-slice
+// their GPUs have a device for each time slice, with the UUID of its GPU. A container that needs
+// less than a GPU gets slices of the fullest GPU it fits on, and other containers get all the
+// slices of the GPUs they need, so that they never share them.
+func (a *agentState) pickFreeDevices(slots int) ([]device.Device, error) {
+	slicesPerGPU := 1
+	total := make(map[string]int)
+	free := make(map[string][]device.Device)
+	for d, cid := range a.Devices {
+		gpu := d.UUID
+		if gpu == "" {
+			gpu = strconv.Itoa(int(d.ID))
+		}
+		total[gpu]++
+		slicesPerGPU = max(slicesPerGPU, total[gpu])
+		if cid == nil {
+			free[gpu] = append(free[gpu], d)
+		}
+	}
+
+	uuids := maps.Keys(free)
+	sort.Strings(uuids)
+	for _, u := range uuids {
+		sort.Slice(free[u], func(i, j int) bool { return free[u][i].ID < free[u][j].ID })
+	}
+
+	var devices []device.Device
+	if slots%slicesPerGPU != 0 {
+		best := ""
+		for _, u := range uuids {
+			if n := len(free[u]); n >= slots && (best == "" || n < len(free[best])) {
+				best = u
+			}
+		}
+		if best != "" {
+			devices = free[best][:slots]
+		}
+	} else {
+		for _, u := range uuids {
+			if len(free[u]) == total[u] && len(devices) < slots {
+				devices = append(devices, free[u]...)
+			}
+		}
+	}
+
+	if len(devices) != slots {
+		return nil, errors.New("not enough devices")
+	}
+	return devices, nil
+}
+
 // deallocateContainer deallocates containers.
 func (a *agentState) deallocateContainer(id cproto.ID) {
 	delete(a.containerState, id)
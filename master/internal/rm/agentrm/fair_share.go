@@ -50,7 +50,7 @@ func (g *groupState) String() string {
 
 func (f *fairShare) Schedule(rp *resourcePool) ([]*sproto.AllocateRequest, []model.AllocationID) {
 	return fairshareSchedule(
-		rp.taskList,
+		withinWorkspaceMaxSlots(rp.taskList, nil, rp.workspaceMaxSlots),
 		rp.groups,
 		rp.agentStatesCache,
 		rp.fittingMethod,
@@ -18,7 +18,7 @@ func agentPermittedSatisfied(req *sproto.AllocateRequest, agent *agentState) boo
 }
 
 func slotsSatisfied(req *sproto.AllocateRequest, agent *agentState) bool {
-	return req.SlotsNeeded <= agent.numEmptySlots()
+	return agent.fitsSlots(req.SlotsNeeded)
 }
 
 func maxZeroSlotContainersSatisfied(req *sproto.AllocateRequest, agent *agentState) bool {
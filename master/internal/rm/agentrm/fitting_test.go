@@ -8,6 +8,7 @@ import (
 	"gotest.tools/assert"
 
 	"github.com/determined-ai/determined/master/internal/sproto"
+	"github.com/determined-ai/determined/master/pkg/device"
 )
 
 func TestIsViable(t *testing.T) {
@@ -502,6 +503,46 @@ func TestFindFitDisallowedNodes(t *testing.T) {
 	assert.Equal(t, fits[0].Agent, agents[0])
 }
 
+func TestTimeSlicedFits(t *testing.T) {
+	// Two GPUs in four slices each.
+	state := newAgentState("agent", 100)
+	for i := 0; i < 8; i++ {
+		state.Devices[device.Device{ID: device.ID(i), UUID: fmt.Sprintf("gpu%d", i/4)}] = nil
+	}
+	uuids := func(devices []device.Device) (uuids []string) {
+		for _, d := range devices {
+			uuids = append(uuids, d.UUID)
+		}
+		return uuids
+	}
+
+	devices, err := state.allocateFreeDevices(1, "a")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, uuids(devices), []string{"gpu0"})
+	// Slices pack onto the fullest GPU.
+	devices, err = state.allocateFreeDevices(2, "b")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, uuids(devices), []string{"gpu0", "gpu0"})
+	// Whole GPUs are never shared.
+	assert.Assert(t, !state.fitsSlots(8))
+	devices, err = state.allocateFreeDevices(4, "c")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, uuids(devices), []string{"gpu1", "gpu1", "gpu1", "gpu1"})
+	assert.Assert(t, state.fitsSlots(1))
+	assert.Assert(t, !state.fitsSlots(2))
+
+	state.deallocateContainer("a")
+	state.deallocateContainer("c")
+	assert.Assert(t, state.fitsSlots(4))
+	assert.Assert(t, !state.fitsSlots(8))
+	devices, err = state.allocateFreeDevices(3, "d")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, uuids(devices), []string{"gpu1", "gpu1", "gpu1"})
+	assert.Equal(t, state.numEmptySlots(), 3)
+	assert.Assert(t, isViable(&sproto.AllocateRequest{SlotsNeeded: 2}, state, slotsSatisfied))
+	assert.Assert(t, !isViable(&sproto.AllocateRequest{SlotsNeeded: 3}, state, slotsSatisfied))
+}
+
 func byID(
 	handlers ...*agentState,
 ) (map[agentID]*agentState, []*agentState) {
@@ -28,6 +28,7 @@ type MockTask struct {
 	SlotsNeeded    int
 	NonPreemptible bool
 	ResourcePool   string
+	WorkspaceID    int
 	AllocatedAgent *MockAgent
 	// Any test that set this to false is half wrong. It is used as a proxy to oversubscribe agents.
 	ContainerStarted  bool
@@ -58,6 +59,7 @@ func MockTaskToAllocateRequest(mockTask *MockTask) *sproto.AllocateRequest {
 		TaskID:            mockTask.TaskID,
 		AllocationID:      mockTask.ID,
 		JobID:             model.JobID(jobID),
+		WorkspaceID:       mockTask.WorkspaceID,
 		SlotsNeeded:       mockTask.SlotsNeeded,
 		IsUserVisible:     true,
 		Preemptible:       !mockTask.NonPreemptible,
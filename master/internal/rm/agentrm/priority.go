@@ -31,8 +31,12 @@ func (p priorityScheduler) Schedule(rp *resourcePool) (
 	[]*sproto.AllocateRequest,
 	[]model.AllocationID,
 ) {
+	var order []*sproto.AllocateRequest
+	if len(rp.workspaceMaxSlots) > 0 {
+		order = tasklist.SortTasksWithPosition(rp.taskList, rp.groups, rp.queuePositions, false)
+	}
 	return p.prioritySchedule(
-		rp.taskList,
+		withinWorkspaceMaxSlots(rp.taskList, order, rp.workspaceMaxSlots),
 		rp.groups,
 		rp.queuePositions,
 		rp.agentStatesCache,
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"math"
 	"sync"
 	"time"
 
@@ -22,6 +23,7 @@ import (
 	"github.com/determined-ai/determined/master/pkg/aproto"
 	"github.com/determined-ai/determined/master/pkg/cproto"
 	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/ptrs"
 	"github.com/determined-ai/determined/master/pkg/set"
 	"github.com/determined-ai/determined/proto/pkg/jobv1"
 )
@@ -47,6 +49,9 @@ type resourcePool struct {
 	groups           map[model.JobID]*tasklist.Group
 	queuePositions   tasklist.JobSortState // secondary sort key based on job submission time
 	scalingInfo      *sproto.ScalingInfo
+	// workspaceMaxSlots caps the slots the tasks of a workspace may use at once, from the
+	// bindings of the workspaces to the pool.
+	workspaceMaxSlots map[int]int
 
 	reschedule      bool
 	rescheduleTimer *time.Timer
@@ -85,6 +90,8 @@ func newResourcePool(
 		queuePositions: tasklist.InitializeJobSortState(false),
 		scalingInfo:    &sproto.ScalingInfo{},
 
+		workspaceMaxSlots: make(map[int]int),
+
 		reschedule: false,
 		db:         db,
 	}
@@ -135,6 +142,7 @@ func (rp *resourcePool) allocateRequest(msg sproto.AllocateRequest) {
 	if len(msg.Name) == 0 {
 		msg.Name = "Unnamed Task"
 	}
+	msg.SlotsNeeded = rp.slotsNeeded(msg)
 
 	log.WithField("restore", msg.Restore).Infof(
 		"resources are requested by %s (Allocation ID: %s)",
@@ -167,6 +175,21 @@ func (rp *resourcePool) allocateRequest(msg sproto.AllocateRequest) {
 	rp.taskList.AddTask(&msg)
 }
 
+// slotsNeeded returns the number of slots of the pool the request needs. Pools that time-slice
+// their GPUs have a slot for each slice, so that requests for a fraction of a slot need some slices
+// of a GPU and others need all the slices of each GPU.
+func (rp *resourcePool) slotsNeeded(req sproto.AllocateRequest) int {
+	slices := rp.config.TimeSlicesPerGPU
+	switch {
+	case slices <= 1:
+		return req.SlotsNeeded
+	case req.SlotFraction > 0:
+		return int(math.Ceil(req.SlotFraction * float64(slices)))
+	default:
+		return req.SlotsNeeded * slices
+	}
+}
+
 func (rp *resourcePool) restoreResources(
 	req *sproto.AllocateRequest,
 ) error {
@@ -309,7 +332,23 @@ func (rp *resourcePool) SetGroupMaxSlots(msg sproto.SetGroupMaxSlots) {
 	defer rp.mu.Unlock()
 	rp.reschedule = true
 
-	rp.getOrCreateGroup(msg.JobID).MaxSlots = msg.MaxSlots
+	maxSlots := msg.MaxSlots
+	if maxSlots != nil && rp.config.TimeSlicesPerGPU > 1 {
+		maxSlots = ptrs.Ptr(*maxSlots * rp.config.TimeSlicesPerGPU)
+	}
+	rp.getOrCreateGroup(msg.JobID).MaxSlots = maxSlots
+}
+
+// SetWorkspaceMaxSlots replaces the caps on the slots the tasks of each workspace may use at once.
+func (rp *resourcePool) SetWorkspaceMaxSlots(msg sproto.SetWorkspaceMaxSlots) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.reschedule = true
+
+	rp.workspaceMaxSlots = make(map[int]int, len(msg.MaxSlots))
+	for workspaceID, maxSlots := range msg.MaxSlots {
+		rp.workspaceMaxSlots[workspaceID] = maxSlots * max(rp.config.TimeSlicesPerGPU, 1)
+	}
 }
 
 func (rp *resourcePool) SetGroupPriority(msg sproto.SetGroupPriority) error {
@@ -103,6 +103,29 @@ func TestCleanUpTaskWhenTaskActorReleaseResources(t *testing.T) {
 	assert.Equal(t, len(rp.GetAllocationSummaries(sproto.GetAllocationSummaries{})), 0)
 }
 
+func TestSlotsNeededTimeSliced(t *testing.T) {
+	rp := &resourcePool{config: &config.ResourcePoolConfig{TimeSlicesPerGPU: 4}}
+	require.Equal(t, 0, rp.slotsNeeded(sproto.AllocateRequest{}))
+	require.Equal(t, 1, rp.slotsNeeded(sproto.AllocateRequest{SlotsNeeded: 1, SlotFraction: 0.25}))
+	require.Equal(t, 2, rp.slotsNeeded(sproto.AllocateRequest{SlotsNeeded: 1, SlotFraction: 0.3}))
+	require.Equal(t, 8, rp.slotsNeeded(sproto.AllocateRequest{SlotsNeeded: 2}))
+
+	rp.config.TimeSlicesPerGPU = 0
+	require.Equal(t, 1, rp.slotsNeeded(sproto.AllocateRequest{SlotsNeeded: 1, SlotFraction: 0.25}))
+}
+
+func TestSetWorkspaceMaxSlots(t *testing.T) {
+	rp := &resourcePool{config: &config.ResourcePoolConfig{TimeSlicesPerGPU: 4}}
+	rp.SetWorkspaceMaxSlots(sproto.SetWorkspaceMaxSlots{MaxSlots: map[int]int{1: 2, 2: 1}})
+	require.Equal(t, map[int]int{1: 8, 2: 4}, rp.workspaceMaxSlots)
+
+	// The caps are replaced, so bindings that lost their max slots are no longer capped.
+	rp.SetWorkspaceMaxSlots(sproto.SetWorkspaceMaxSlots{MaxSlots: map[int]int{2: 3}})
+	require.Equal(t, map[int]int{2: 12}, rp.workspaceMaxSlots)
+	rp.SetWorkspaceMaxSlots(sproto.SetWorkspaceMaxSlots{})
+	require.Empty(t, rp.workspaceMaxSlots)
+}
+
 func TestScalingInfoAgentSummary(t *testing.T) {
 	agents := []*MockAgent{
 		{ID: "agent1", Slots: 1},
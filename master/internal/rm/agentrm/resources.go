@@ -43,6 +43,14 @@ func (c containerResources) Summary() sproto.ResourcesSummary {
 func (c containerResources) Start(
 	logCtx logger.Context, spec tasks.TaskSpec, rri sproto.ResourcesRuntimeInfo,
 ) error {
+	// Agents filter egress on their egress network, while tasks that span agents run with host
+	// networking, which the rules of the network can't see.
+	if spec.Egress != nil && rri.IsMultiAgent {
+		return fmt.Errorf("the egress policy of workspace %d can't be enforced for tasks that span "+
+			"multiple agents, which run with host networking; use fewer slots per task so that it "+
+			"fits on one agent, or remove the policy", spec.WorkspaceID)
+	}
+
 	spec.ContainerID = string(c.containerID)
 	spec.ResourcesID = string(c.containerID)
 	spec.AllocationID = string(c.req.AllocationID)
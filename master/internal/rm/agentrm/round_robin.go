@@ -21,7 +21,7 @@ func (p *roundRobinScheduler) Schedule(rp *resourcePool) (
 	[]model.AllocationID,
 ) {
 	return roundRobinSchedule(
-		rp.taskList,
+		withinWorkspaceMaxSlots(rp.taskList, nil, rp.workspaceMaxSlots),
 		rp.groups,
 		rp.agentStatesCache,
 		rp.fittingMethod,
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/internal/rm/tasklist"
 	"github.com/determined-ai/determined/master/internal/sproto"
 	"github.com/determined-ai/determined/master/pkg/model"
 )
@@ -32,3 +33,53 @@ func MakeScheduler(conf *config.SchedulerConfig) Scheduler {
 		panic(fmt.Sprintf("invalid scheduler: %s", conf.GetType()))
 	}
 }
+
+// withinWorkspaceMaxSlots returns the tasks the scheduler may consider: the scheduled tasks, and
+// the pending tasks that fit under the max slots of their workspace alongside its scheduled tasks
+// and the pending tasks before them in order, which defaults to the order of the task list. The
+// other pending tasks stay queued.
+func withinWorkspaceMaxSlots(
+	taskList *tasklist.TaskList, order []*sproto.AllocateRequest, workspaceMaxSlots map[int]int,
+) *tasklist.TaskList {
+	if len(workspaceMaxSlots) == 0 {
+		return taskList
+	}
+	if order == nil {
+		for it := taskList.Iterator(); it.Next(); {
+			order = append(order, it.Value())
+		}
+	}
+
+	usedSlots := make(map[int]int)
+	for it := taskList.Iterator(); it.Next(); {
+		if req := it.Value(); taskList.IsScheduled(req.AllocationID) {
+			usedSlots[req.WorkspaceID] += req.SlotsNeeded
+		}
+	}
+	admitted := make(map[model.AllocationID]bool)
+	for _, req := range order {
+		if taskList.IsScheduled(req.AllocationID) {
+			continue
+		}
+		if maxSlots, ok := workspaceMaxSlots[req.WorkspaceID]; ok {
+			if usedSlots[req.WorkspaceID]+req.SlotsNeeded > maxSlots {
+				continue
+			}
+			usedSlots[req.WorkspaceID] += req.SlotsNeeded
+		}
+		admitted[req.AllocationID] = true
+	}
+
+	filtered := tasklist.New()
+	for it := taskList.Iterator(); it.Next(); {
+		req := it.Value()
+		if !taskList.IsScheduled(req.AllocationID) && !admitted[req.AllocationID] {
+			continue
+		}
+		filtered.AddTask(req)
+		if allocated := taskList.Allocation(req.AllocationID); allocated != nil {
+			filtered.AddAllocationRaw(req.AllocationID, allocated)
+		}
+	}
+	return filtered
+}
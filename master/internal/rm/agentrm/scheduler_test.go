@@ -542,3 +542,45 @@ func setupSchedulerStates(
 
 	return taskList, groups, agents
 }
+
+func TestWorkspaceMaxSlots(t *testing.T) {
+	priority := 50
+	agents := []*MockAgent{{ID: "agent1", Slots: 8, MaxZeroSlotContainers: 100}}
+	groups := []*MockGroup{
+		{ID: "job1", Priority: &priority, Weight: 1},
+		{ID: "job2", Priority: &priority, Weight: 1},
+		{ID: "job3", Priority: &priority, Weight: 1},
+	}
+	tasks := []*MockTask{
+		{ID: "task1", JobID: "job1", SlotsNeeded: 2, Group: groups[0], WorkspaceID: 1},
+		{ID: "task2", JobID: "job1", SlotsNeeded: 2, Group: groups[0], WorkspaceID: 1},
+		{ID: "task3", JobID: "job2", SlotsNeeded: 2, Group: groups[1], WorkspaceID: 1},
+		{ID: "task4", JobID: "job2", SlotsNeeded: 0, Group: groups[1], WorkspaceID: 1},
+		{ID: "task5", JobID: "job3", SlotsNeeded: 2, Group: groups[2], WorkspaceID: 2},
+	}
+
+	for _, scheduler := range []Scheduler{
+		NewPriorityScheduler(&config.SchedulerConfig{Priority: &config.PrioritySchedulerConfig{}}),
+		NewFairShareScheduler(),
+		NewRoundRobinScheduler(),
+	} {
+		taskList, groupMap, agentMap := setupSchedulerStates(t, tasks, groups, agents)
+		forceSetTaskAllocations(t, taskList, "task1", 1)
+		rp := &resourcePool{
+			config: &config.ResourcePoolConfig{
+				Scheduler: &config.SchedulerConfig{},
+			},
+			taskList:          taskList,
+			groups:            groupMap,
+			queuePositions:    tasklist.InitializeJobSortState(false),
+			agentStatesCache:  agentMap,
+			fittingMethod:     BestFit,
+			workspaceMaxSlots: map[int]int{1: 4},
+		}
+
+		// The first workspace already uses 2 of its 4 slots, so only one more of its tasks that
+		// need slots fits; the second workspace is not capped.
+		toAllocate, _ := scheduler.Schedule(rp)
+		assertEqualToAllocate(t, toAllocate, []*MockTask{tasks[1], tasks[3], tasks[4]})
+	}
+}
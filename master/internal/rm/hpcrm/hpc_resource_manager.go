@@ -0,0 +1,542 @@
+package hpcrm
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/rm/rmerrors"
+	"github.com/determined-ai/determined/master/internal/rm/rmevents"
+	"github.com/determined-ai/determined/master/internal/rm/rmutils"
+	"github.com/determined-ai/determined/master/internal/sproto"
+	"github.com/determined-ai/determined/master/pkg/command"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/proto/pkg/agentv1"
+	"github.com/determined-ai/determined/proto/pkg/apiv1"
+	"github.com/determined-ai/determined/proto/pkg/jobv1"
+	"github.com/determined-ai/determined/proto/pkg/resourcepoolv1"
+)
+
+// scheduleInterval is how often the pools hand out resources to new requests.
+const scheduleInterval = 500 * time.Millisecond
+
+// ResourceManager is a resource manager that runs each allocation as a batch job of Slurm or PBS.
+type ResourceManager struct {
+	syslog *logrus.Entry
+
+	config      *config.HPCResourceManagerConfig
+	poolsConfig []config.ResourcePoolConfig
+
+	jobs  *jobs
+	pools map[string]*hpcResourcePool // immutable after initialization in new.
+
+	db *db.PgDB
+}
+
+// New returns a new ResourceManager, which submits the allocations of its pools to the workload
+// manager of the cluster.
+func New(db *db.PgDB, rmConfigs *config.ResourceConfig, cert *tls.Certificate) *ResourceManager {
+	tlsConfig, err := model.MakeTLSConfig(cert)
+	if err != nil {
+		panic(fmt.Errorf("failed to set up TLS config: %w", err))
+	}
+
+	name, cfg := slurmName, rmConfigs.ResourceManager.SlurmRM
+	if cfg == nil {
+		name, cfg = pbsName, rmConfigs.ResourceManager.PbsRM
+	}
+	if cfg.MasterPort == 0 {
+		cfg.MasterPort = config.GetMasterConfig().Port
+	}
+	if cfg.MasterHost == "" {
+		if cfg.MasterHost, err = os.Hostname(); err != nil {
+			panic(fmt.Errorf("getting hostname for master_host: %w", err))
+		}
+	}
+	wm, err := newWorkloadManager(name, cfg.BinDir)
+	if err != nil {
+		panic(err)
+	}
+
+	h := &ResourceManager{
+		syslog: logrus.WithField("component", name+"rm"),
+
+		config:      cfg,
+		poolsConfig: rmConfigs.ResourcePools,
+
+		jobs:  newJobs(wm, cfg, tlsConfig),
+		pools: make(map[string]*hpcResourcePool),
+
+		db: db,
+	}
+
+	for _, poolConfig := range h.poolsConfig {
+		poolConfig := poolConfig
+		rp := newResourcePool(&poolConfig, h.jobs)
+		go func() {
+			t := time.NewTicker(scheduleInterval)
+			defer t.Stop()
+			for range t.C {
+				rp.Schedule()
+			}
+		}()
+		h.pools[poolConfig.PoolName] = rp
+	}
+
+	go func() {
+		t := time.NewTicker(time.Duration(cfg.PollInterval))
+		defer t.Stop()
+		for range t.C {
+			h.jobs.poll()
+		}
+	}()
+	return h
+}
+
+// Allocate implements rm.ResourceManager.
+func (h *ResourceManager) Allocate(msg sproto.AllocateRequest) (*sproto.ResourcesSubscription, error) {
+	if len(msg.ResourcePool) == 0 {
+		if msg.SlotsNeeded == 0 {
+			msg.ResourcePool = h.config.DefaultAuxResourcePool
+		} else {
+			msg.ResourcePool = h.config.DefaultComputeResourcePool
+		}
+	}
+
+	rp, err := h.poolByName(msg.ResourcePool)
+	if err != nil {
+		return nil, err
+	}
+	sub := rmevents.Subscribe(msg.AllocationID)
+	rp.AllocateRequest(msg)
+	return sub, nil
+}
+
+// DeleteJob implements rm.ResourceManager.
+func (ResourceManager) DeleteJob(sproto.DeleteJob) (sproto.DeleteJobResponse, error) {
+	// Batch jobs are cleaned up when their allocations are released.
+	return sproto.EmptyDeleteJobResponse(), nil
+}
+
+// ExternalPreemptionPending implements rm.ResourceManager. The workload manager is about to stop
+// the job, so the allocation is asked to checkpoint and exit first.
+func (h *ResourceManager) ExternalPreemptionPending(msg sproto.PendingPreemption) error {
+	rmevents.Publish(msg.AllocationID, &sproto.ReleaseResources{
+		Reason:          fmt.Sprintf("preempted by %s", h.jobs.wm.name()),
+		ForcePreemption: true,
+	})
+	return nil
+}
+
+// GetAgent implements rm.ResourceManager.
+func (ResourceManager) GetAgent(*apiv1.GetAgentRequest) (*apiv1.GetAgentResponse, error) {
+	return nil, rmerrors.ErrNotSupported
+}
+
+// GetAgents implements rm.ResourceManager. The nodes of the cluster belong to the workload manager.
+func (ResourceManager) GetAgents(*apiv1.GetAgentsRequest) (*apiv1.GetAgentsResponse, error) {
+	return &apiv1.GetAgentsResponse{Agents: []*agentv1.Agent{}}, nil
+}
+
+// GetAllocationSummaries implements rm.ResourceManager.
+func (h *ResourceManager) GetAllocationSummaries(
+	sproto.GetAllocationSummaries,
+) (map[model.AllocationID]sproto.AllocationSummary, error) {
+	summaries := make(map[model.AllocationID]sproto.AllocationSummary)
+	for _, rp := range h.pools {
+		maps.Copy(summaries, rp.GetAllocationSummaries())
+	}
+	return summaries, nil
+}
+
+// GetAllocationSummary implements rm.ResourceManager.
+func (h *ResourceManager) GetAllocationSummary(msg sproto.GetAllocationSummary) (*sproto.AllocationSummary, error) {
+	for _, rp := range h.pools {
+		if resp := rp.GetAllocationSummary(msg); resp != nil {
+			return resp, nil
+		}
+	}
+	return nil, fmt.Errorf("allocation not found: %s", msg.ID)
+}
+
+// GetDefaultAuxResourcePool implements rm.ResourceManager.
+func (h *ResourceManager) GetDefaultAuxResourcePool(
+	sproto.GetDefaultAuxResourcePoolRequest,
+) (sproto.GetDefaultAuxResourcePoolResponse, error) {
+	if h.config.DefaultAuxResourcePool == "" {
+		return sproto.GetDefaultAuxResourcePoolResponse{}, rmerrors.ErrNoDefaultResourcePool
+	}
+	return sproto.GetDefaultAuxResourcePoolResponse{PoolName: h.config.DefaultAuxResourcePool}, nil
+}
+
+// GetDefaultComputeResourcePool implements rm.ResourceManager.
+func (h *ResourceManager) GetDefaultComputeResourcePool(
+	sproto.GetDefaultComputeResourcePoolRequest,
+) (sproto.GetDefaultComputeResourcePoolResponse, error) {
+	if h.config.DefaultComputeResourcePool == "" {
+		return sproto.GetDefaultComputeResourcePoolResponse{}, rmerrors.ErrNoDefaultResourcePool
+	}
+	return sproto.GetDefaultComputeResourcePoolResponse{PoolName: h.config.DefaultComputeResourcePool}, nil
+}
+
+// GetExternalJobs implements rm.ResourceManager.
+func (ResourceManager) GetExternalJobs(sproto.GetExternalJobs) ([]*jobv1.Job, error) {
+	return nil, rmerrors.ErrNotSupported
+}
+
+// GetJobQ implements rm.ResourceManager.
+func (h *ResourceManager) GetJobQ(msg sproto.GetJobQ) (map[model.JobID]*sproto.RMJobInfo, error) {
+	if msg.ResourcePool == "" {
+		msg.ResourcePool = h.config.DefaultComputeResourcePool
+	}
+
+	rp, err := h.poolByName(msg.ResourcePool)
+	if err != nil {
+		return nil, err
+	}
+	return rp.GetJobQ(), nil
+}
+
+// GetJobQueueStatsRequest implements rm.ResourceManager.
+func (h *ResourceManager) GetJobQueueStatsRequest(
+	*apiv1.GetJobQueueStatsRequest,
+) (*apiv1.GetJobQueueStatsResponse, error) {
+	resp := &apiv1.GetJobQueueStatsResponse{
+		Results: make([]*apiv1.RPQueueStat, 0),
+	}
+	for poolName, rp := range h.pools {
+		aggregates, err := h.fetchAvgQueuedTime(poolName)
+		if err != nil {
+			return nil, fmt.Errorf("fetch average queued time: %s", err)
+		}
+		resp.Results = append(resp.Results, &apiv1.RPQueueStat{
+			ResourcePool: poolName,
+			Stats:        rp.GetJobQStats(),
+			Aggregates:   aggregates,
+		})
+	}
+	return resp, nil
+}
+
+// GetResourcePools implements rm.ResourceManager.
+func (h *ResourceManager) GetResourcePools(*apiv1.GetResourcePoolsRequest) (*apiv1.GetResourcePoolsResponse, error) {
+	summaries := make([]*resourcepoolv1.ResourcePool, 0, len(h.poolsConfig))
+	for _, pool := range h.poolsConfig {
+		summary, err := h.createResourcePoolSummary(pool)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, summary)
+	}
+	return &apiv1.GetResourcePoolsResponse{ResourcePools: summaries}, nil
+}
+
+// GetSlot implements rm.ResourceManager.
+func (ResourceManager) GetSlot(*apiv1.GetSlotRequest) (*apiv1.GetSlotResponse, error) {
+	return nil, rmerrors.ErrNotSupported
+}
+
+// GetSlots implements rm.ResourceManager.
+func (ResourceManager) GetSlots(*apiv1.GetSlotsRequest) (*apiv1.GetSlotsResponse, error) {
+	return nil, rmerrors.ErrNotSupported
+}
+
+// MoveJob implements rm.ResourceManager.
+func (h *ResourceManager) MoveJob(sproto.MoveJob) error {
+	return rmerrors.UnsupportedError(
+		fmt.Sprintf("the queue of jobs is managed by %s", h.jobs.wm.name()))
+}
+
+// RecoverJobPosition implements rm.ResourceManager.
+func (ResourceManager) RecoverJobPosition(sproto.RecoverJobPosition) {}
+
+// Release implements rm.ResourceManager.
+func (h *ResourceManager) Release(msg sproto.ResourcesReleased) {
+	rp, err := h.poolByName(msg.ResourcePool)
+	if err != nil {
+		h.syslog.WithError(err).Warnf("release found no resource pool with name %s",
+			msg.ResourcePool)
+		return
+	}
+	rp.ResourcesReleased(msg)
+	if msg.ResourcesID == nil {
+		h.jobs.release(msg.AllocationID)
+	}
+}
+
+// SetAllocationName implements rm.ResourceManager.
+func (h *ResourceManager) SetAllocationName(msg sproto.SetAllocationName) {
+	rp, err := h.poolByName(msg.ResourcePool)
+	if err != nil {
+		h.syslog.WithError(err).Warnf("set allocation name found no resource pool with name %s",
+			msg.ResourcePool)
+		return
+	}
+	rp.SetAllocationName(msg)
+}
+
+// SetGroupMaxSlots implements rm.ResourceManager.
+func (h *ResourceManager) SetGroupMaxSlots(msg sproto.SetGroupMaxSlots) {
+	rp, err := h.poolByName(msg.ResourcePool)
+	if err != nil {
+		h.syslog.WithError(err).Warnf("set group max slots found no resource pool with name %s",
+			msg.ResourcePool)
+		return
+	}
+	rp.SetGroupMaxSlots(msg)
+}
+
+// SetWorkspaceMaxSlots implements rm.ResourceManager. The workload manager schedules the jobs of
+// the launcher, so workspace max slots are not enforced.
+func (h *ResourceManager) SetWorkspaceMaxSlots(sproto.SetWorkspaceMaxSlots) {}
+
+// SetGroupPriority implements rm.ResourceManager.
+func (h *ResourceManager) SetGroupPriority(sproto.SetGroupPriority) error {
+	return rmerrors.UnsupportedError(
+		fmt.Sprintf("set group priority is unsupported in %s", h.jobs.wm.name()))
+}
+
+// SetGroupWeight implements rm.ResourceManager.
+func (h *ResourceManager) SetGroupWeight(sproto.SetGroupWeight) error {
+	return rmerrors.UnsupportedError(
+		fmt.Sprintf("set group weight is unsupported in %s", h.jobs.wm.name()))
+}
+
+// ValidateCommandResources implements rm.ResourceManager. Whether the cluster can fit a command is
+// up to the workload manager.
+func (h *ResourceManager) ValidateCommandResources(
+	msg sproto.ValidateCommandResourcesRequest,
+) (sproto.ValidateCommandResourcesResponse, error) {
+	if _, err := h.poolByName(msg.ResourcePool); err != nil {
+		return sproto.ValidateCommandResourcesResponse{}, err
+	}
+	return sproto.ValidateCommandResourcesResponse{Fulfillable: true}, nil
+}
+
+// ValidateResources implements rm.ResourceManager. This is a no-op for Slurm and PBS.
+func (ResourceManager) ValidateResources(name string, slots int, command bool) error {
+	return nil
+}
+
+// ValidateResourcePool validates that the named resource pool exists.
+func (h *ResourceManager) ValidateResourcePool(name string) error {
+	if _, err := h.poolByName(name); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ResolveResourcePool resolves the resource pool completely.
+func (h *ResourceManager) ResolveResourcePool(name string, workspaceID, slots int) (string, error) {
+	ctx := context.TODO()
+	defaultComputePool, defaultAuxPool, err := db.GetDefaultPoolsForWorkspace(ctx, workspaceID)
+	if err != nil {
+		return "", err
+	}
+	// If the resource pool isn't set, fill in the default at creation time.
+	if name == "" && slots == 0 {
+		if defaultAuxPool == "" {
+			resp, err := h.GetDefaultAuxResourcePool(sproto.GetDefaultAuxResourcePoolRequest{})
+			if err != nil {
+				return "", fmt.Errorf("defaulting to aux pool: %w", err)
+			}
+			return resp.PoolName, nil
+		}
+		name = defaultAuxPool
+	}
+	if name == "" && slots >= 0 {
+		if defaultComputePool == "" {
+			resp, err := h.GetDefaultComputeResourcePool(sproto.GetDefaultComputeResourcePoolRequest{})
+			if err != nil {
+				return "", fmt.Errorf("defaulting to compute pool: %w", err)
+			}
+			return resp.PoolName, nil
+		}
+		name = defaultComputePool
+	}
+
+	resp, err := h.GetResourcePools(&apiv1.GetResourcePoolsRequest{})
+	if err != nil {
+		return "", err
+	}
+	poolNames, _, err := db.ReadRPsAvailableToWorkspace(
+		ctx, int32(workspaceID), 0, -1, rmutils.ResourcePoolsToConfig(resp.ResourcePools))
+	if err != nil {
+		return "", err
+	}
+	found := false
+	for _, poolName := range poolNames {
+		if name == poolName {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return "", fmt.Errorf(
+			"resource pool %s does not exist or is not available to workspace ID %d",
+			name, workspaceID)
+	}
+
+	binding, err := db.GetRPWorkspaceBinding(ctx, workspaceID, name)
+	if err != nil {
+		return "", err
+	}
+	if binding != nil {
+		if err := binding.CheckSlots(slots); err != nil {
+			return "", fmt.Errorf("resource pool %s: %w", name, err)
+		}
+	}
+
+	if err := h.ValidateResourcePool(name); err != nil {
+		return "", fmt.Errorf("validating pool: %w", err)
+	}
+	return name, nil
+}
+
+// ValidateResourcePoolAvailability implements rm.ResourceManager.
+func (h *ResourceManager) ValidateResourcePoolAvailability(
+	v *sproto.ValidateResourcePoolAvailabilityRequest,
+) ([]command.LaunchWarning, error) {
+	if _, err := h.poolByName(v.Name); err != nil {
+		return nil, fmt.Errorf("%s is an invalid resource pool", v.Name)
+	}
+	return nil, nil
+}
+
+// NotifyContainerRunning receives a notification from the container of each node of a job to let
+// the master know that it is running.
+func (h *ResourceManager) NotifyContainerRunning(msg sproto.NotifyContainerRunning) error {
+	return h.jobs.containerRunning(msg)
+}
+
+// IsReattachableOnlyAfterStarted always returns false, since jobs are restored as soon as they
+// are submitted.
+func (ResourceManager) IsReattachableOnlyAfterStarted() bool {
+	return false
+}
+
+// TaskContainerDefaults returns TaskContainerDefaults for the specified pool.
+func (h *ResourceManager) TaskContainerDefaults(
+	pool string,
+	fallbackConfig model.TaskContainerDefaultsConfig,
+) (model.TaskContainerDefaultsConfig, error) {
+	for _, p := range h.poolsConfig {
+		if p.PoolName == pool && p.TaskContainerDefaults != nil {
+			return *p.TaskContainerDefaults, nil
+		}
+	}
+	return fallbackConfig, nil
+}
+
+// EnableAgent implements rm.ResourceManager.
+func (ResourceManager) EnableAgent(*apiv1.EnableAgentRequest) (*apiv1.EnableAgentResponse, error) {
+	return nil, rmerrors.ErrNotSupported
+}
+
+// DisableAgent implements rm.ResourceManager.
+func (ResourceManager) DisableAgent(*apiv1.DisableAgentRequest) (*apiv1.DisableAgentResponse, error) {
+	return nil, rmerrors.ErrNotSupported
+}
+
+// EnableSlot implements rm.ResourceManager.
+func (ResourceManager) EnableSlot(*apiv1.EnableSlotRequest) (*apiv1.EnableSlotResponse, error) {
+	return nil, rmerrors.ErrNotSupported
+}
+
+// DisableSlot implements rm.ResourceManager.
+func (ResourceManager) DisableSlot(*apiv1.DisableSlotRequest) (*apiv1.DisableSlotResponse, error) {
+	return nil, rmerrors.ErrNotSupported
+}
+
+func (h *ResourceManager) poolByName(resourcePool string) (*hpcResourcePool, error) {
+	if resourcePool == "" {
+		return nil, errors.New("invalid call: cannot get a resource pool with no name")
+	}
+	rp, ok := h.pools[resourcePool]
+	if !ok {
+		return nil, fmt.Errorf("cannot find resource pool %s", resourcePool)
+	}
+	return rp, nil
+}
+
+func (h *ResourceManager) createResourcePoolSummary(
+	pool config.ResourcePoolConfig,
+) (*resourcepoolv1.ResourcePool, error) {
+	rp, err := h.poolByName(pool.PoolName)
+	if err != nil {
+		return nil, err
+	}
+
+	const na = "n/a"
+	schedulerType := resourcepoolv1.SchedulerType_SCHEDULER_TYPE_SLURM
+	fittingPolicy := resourcepoolv1.FittingPolicy_FITTING_POLICY_SLURM
+	if h.jobs.wm.name() == pbsName {
+		schedulerType = resourcepoolv1.SchedulerType_SCHEDULER_TYPE_PBS
+		fittingPolicy = resourcepoolv1.FittingPolicy_FITTING_POLICY_PBS
+	}
+	location := rp.partition
+	if location == "" {
+		location = na
+	}
+	return &resourcepoolv1.ResourcePool{
+		Name:                   pool.PoolName,
+		Description:            pool.Description,
+		Type:                   resourcepoolv1.ResourcePoolType_RESOURCE_POOL_TYPE_STATIC,
+		SlotType:               h.config.SlotType.Proto(),
+		DefaultAuxPool:         h.config.DefaultAuxResourcePool == pool.PoolName,
+		DefaultComputePool:     h.config.DefaultComputeResourcePool == pool.PoolName,
+		SchedulerType:          schedulerType,
+		SchedulerFittingPolicy: fittingPolicy,
+		Location:               location,
+		InstanceType:           na,
+		Details:                &resourcepoolv1.ResourcePoolDetail{},
+		SlotsUsed:              int32(rp.slotsUsed()),
+		Stats:                  rp.GetJobQStats(),
+	}, nil
+}
+
+func (h *ResourceManager) fetchAvgQueuedTime(pool string) (
+	[]*jobv1.AggregateQueueStats, error,
+) {
+	aggregates := []model.ResourceAggregates{}
+	err := db.Bun().NewSelect().Model(&aggregates).
+		Where("aggregation_type = ?", "queued").
+		Where("aggregation_key = ?", pool).
+		Where("date >= CURRENT_TIMESTAMP - interval '30 days'").
+		Order("date ASC").Scan(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*jobv1.AggregateQueueStats, 0)
+	for _, record := range aggregates {
+		res = append(res, &jobv1.AggregateQueueStats{
+			PeriodStart: record.Date.Format("2006-01-02"),
+			Seconds:     record.Seconds,
+		})
+	}
+	today := float32(0)
+	subq := db.Bun().NewSelect().TableExpr("allocations").Column("allocation_id").
+		Where("resource_pool = ?", pool).
+		Where("start_time >= CURRENT_DATE")
+	err = db.Bun().NewSelect().TableExpr("task_stats").ColumnExpr(
+		"avg(extract(epoch FROM end_time - start_time))",
+	).Where("event_type = ?", "QUEUED").
+		Where("end_time >= CURRENT_DATE AND allocation_id IN (?) ", subq).
+		Scan(context.TODO(), &today)
+	if err != nil {
+		return nil, err
+	}
+	res = append(res, &jobv1.AggregateQueueStats{
+		PeriodStart: time.Now().Format("2006-01-02"),
+		Seconds:     today,
+	})
+	return res, nil
+}
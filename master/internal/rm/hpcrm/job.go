@@ -0,0 +1,331 @@
+package hpcrm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/mount"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/internal/sproto"
+	"github.com/determined-ai/determined/master/pkg/archive"
+	"github.com/determined-ai/determined/master/pkg/cproto"
+	"github.com/determined-ai/determined/master/pkg/device"
+	"github.com/determined-ai/determined/master/pkg/tasks"
+)
+
+// The files of the directory of a batch job, which is on storage shared by the master and the
+// nodes of the cluster.
+const (
+	jobScriptFile  = "job.sh"
+	taskScriptFile = "task.sh"
+	envFile        = "env"
+	jobLogFile     = "job.log"
+	archivesDir    = "archives"
+	// exitCodePrefix is followed by the rank of the node whose container exited with the code.
+	exitCodePrefix = "exit-code."
+)
+
+// wrapperEntrypoint runs before the entrypoint of the task in the container, to decode the
+// environment variables that are encoded in the env file.
+var wrapperEntrypoint = path.Join(tasks.RunDir, tasks.SingularityEntrypointWrapperScript)
+
+// ignoredMountPoints are directories of the container that the archives are not mounted over, since
+// the mount would hide what the image has there. Their children are mounted instead.
+var ignoredMountPoints = map[string]bool{
+	"/": true, "/etc": true, "/opt": true, "/run": true, "/etc/ssh": true,
+}
+
+// plainEnvValue matches the values that are written to the env file as they are.
+var plainEnvValue = regexp.MustCompile(`^[A-Za-z0-9_./:,@%+=-]*$`)
+
+// jobSpec is what the directory of a batch job is written from.
+type jobSpec struct {
+	layout     jobLayout
+	runType    string
+	image      string
+	env        map[string]string
+	workDir    string
+	mounts     []mount.Mount
+	shmSize    int64
+	entrypoint []string
+	archives   []cproto.RunArchive
+}
+
+// writeJob writes the directory of a batch job and returns the path of its batch script.
+func writeJob(wm workloadManager, js jobSpec) (string, error) {
+	dir := js.layout.dir
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("creating job directory: %w", err)
+	}
+
+	archivesPath := filepath.Join(dir, archivesDir)
+	mountPoints := make(map[string]bool)
+	for _, a := range js.archives {
+		// The owners of the files can't be set without root, and needn't be: the containers run as
+		// the user that submitted the job.
+		ignore := func(string, string) error { return nil }
+		if err := archive.Write(filepath.Join(archivesPath, a.Path), a.Archive, ignore); err != nil {
+			return "", fmt.Errorf("writing archive for %s: %w", a.Path, err)
+		}
+		for _, item := range a.Archive {
+			if p := mountPoint(path.Join(a.Path, item.Path)); p != "" {
+				mountPoints[p] = true
+			}
+		}
+	}
+
+	envPath := filepath.Join(dir, envFile)
+	if err := os.WriteFile(envPath, []byte(envFileContents(js.env)), 0o600); err != nil {
+		return "", fmt.Errorf("writing env file: %w", err)
+	}
+
+	binds := make([]string, 0, len(mountPoints))
+	for p := range mountPoints {
+		binds = append(binds, p)
+	}
+	sort.Strings(binds)
+	command := containerCommand(js, envPath, archivesPath, binds)
+
+	taskPath := filepath.Join(dir, taskScriptFile)
+	if err := os.WriteFile(taskPath, []byte(taskScript(js, command)), 0o700); err != nil {
+		return "", fmt.Errorf("writing task script: %w", err)
+	}
+
+	var script strings.Builder
+	script.WriteString("#!/usr/bin/env bash\n")
+	for _, d := range wm.directives(js.layout) {
+		script.WriteString(d + "\n")
+	}
+	script.WriteString(wm.launch(taskPath) + "\n")
+	jobPath := filepath.Join(dir, jobScriptFile)
+	if err := os.WriteFile(jobPath, []byte(script.String()), 0o700); err != nil {
+		return "", fmt.Errorf("writing job script: %w", err)
+	}
+	return jobPath, nil
+}
+
+// mountPoint returns the shallowest directory of p, or p itself, that the archives may be mounted
+// at, or "" if there is none.
+func mountPoint(p string) string {
+	p = path.Clean("/" + p)
+	for prefix, rest := "", strings.Split(strings.TrimPrefix(p, "/"), "/"); len(rest) > 0; {
+		prefix, rest = prefix+"/"+rest[0], rest[1:]
+		if !ignoredMountPoints[prefix] {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// envFileContents returns the env file of a job. Values that a shell would mangle are base64
+// encoded and listed in DET_B64_ENCODED_ENVVARS, for the wrapper entrypoint to decode.
+func envFileContents(env map[string]string) string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	var encoded []string
+	for _, k := range keys {
+		v := env[k]
+		if !plainEnvValue.MatchString(v) {
+			v = base64.StdEncoding.EncodeToString([]byte(v))
+			encoded = append(encoded, k)
+		}
+		fmt.Fprintf(&b, "%s=%s\n", k, v)
+	}
+	fmt.Fprintf(&b, "DET_B64_ENCODED_ENVVARS=%s\n", strings.Join(encoded, ","))
+	return b.String()
+}
+
+// containerCommand returns the command that runs the container of the task on a node.
+func containerCommand(js jobSpec, envPath, archivesPath string, binds []string) []string {
+	var args []string
+	switch js.runType {
+	case config.PodmanContainerRunType:
+		args = []string{
+			"podman", "run", "--rm", "--network=host",
+			"--workdir", js.workDir, "--env-file", envPath,
+			// These are set on the node, by the workload manager or by task.sh.
+			"--env", "SLURM_*", "--env", "DET_AGENT_ID",
+			"--env", "CUDA_VISIBLE_DEVICES", "--env", "ROCR_VISIBLE_DEVICES",
+		}
+		switch js.layout.slotType {
+		case device.CUDA:
+			if js.layout.slotsPerNode > 0 {
+				args = append(args, "--device", "nvidia.com/gpu=all")
+			}
+		case device.ROCM:
+			if js.layout.slotsPerNode > 0 {
+				args = append(args, "--device", "/dev/kfd", "--device", "/dev/dri")
+			}
+		}
+		if js.shmSize > 0 {
+			args = append(args, "--shm-size", strconv.FormatInt(js.shmSize, 10))
+		}
+		for _, b := range binds {
+			args = append(args, "--volume", filepath.Join(archivesPath, b)+":"+b)
+		}
+		for _, m := range js.mounts {
+			args = append(args, "--volume", bindArg(m))
+		}
+		args = append(args, js.image)
+	default:
+		args = []string{
+			js.runType, "run", "--writable-tmpfs",
+			"--pwd", js.workDir, "--env-file", envPath,
+		}
+		if js.layout.slotsPerNode > 0 {
+			switch js.layout.slotType {
+			case device.CUDA:
+				args = append(args, "--nv")
+			case device.ROCM:
+				args = append(args, "--rocm")
+			}
+		}
+		for _, b := range binds {
+			args = append(args, "--bind", filepath.Join(archivesPath, b)+":"+b)
+		}
+		for _, m := range js.mounts {
+			args = append(args, "--bind", bindArg(m))
+		}
+		args = append(args, canonicalizeImage(js.image))
+	}
+	args = append(args, wrapperEntrypoint)
+	return append(args, js.entrypoint...)
+}
+
+// bindArg returns the argument of --bind or --volume that mounts m.
+func bindArg(m mount.Mount) string {
+	arg := m.Source + ":" + m.Target
+	if m.ReadOnly {
+		arg += ":ro"
+	}
+	return arg
+}
+
+// canonicalizeImage returns the image as Singularity expects it: a path to a local image, or a URI.
+// Images without a scheme are from a Docker registry.
+func canonicalizeImage(image string) string {
+	if filepath.IsAbs(image) || strings.Contains(image, "://") {
+		return image
+	}
+	return "docker://" + image
+}
+
+// taskScript returns the script that runs once on each node of the job. It records the exit code
+// of the container, since the workload manager reports the state of the job but not why it ended.
+func taskScript(js jobSpec, command []string) string {
+	quoted := make([]string, 0, len(command))
+	for _, arg := range command {
+		quoted = append(quoted, shellQuote(arg))
+	}
+
+	var b strings.Builder
+	b.WriteString("#!/usr/bin/env bash\n")
+	b.WriteString("rank=${SLURM_PROCID:-${PBS_VNODENUM:-0}}\n")
+	fmt.Fprintf(&b, "export SLURM_PROCID=$rank SLURM_NPROCS=%d\n", js.layout.nodes)
+	b.WriteString("export DET_AGENT_ID=\"$(hostname)\"\n")
+	b.WriteString(strings.Join(quoted, " ") + "\n")
+	b.WriteString("code=$?\n")
+	fmt.Fprintf(&b, "echo $code > %s\"$rank\"\n", shellQuote(filepath.Join(js.layout.dir, exitCodePrefix)))
+	b.WriteString("exit $code\n")
+	return b.String()
+}
+
+// readExitCode returns the exit code of the task of a job: the first non-zero exit code by rank, or
+// 0. It returns nil if no container recorded one, because the job ended before they exited.
+func readExitCode(dir string) (*sproto.ExitCode, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, exitCodePrefix+"*"))
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make(map[int]int)
+	for _, p := range paths {
+		rank, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(p), exitCodePrefix))
+		if err != nil {
+			continue
+		}
+		contents, err := os.ReadFile(p) // #nosec G304 // The path is ours.
+		if err != nil {
+			return nil, err
+		}
+		if codes[rank], err = strconv.Atoi(strings.TrimSpace(string(contents))); err != nil {
+			return nil, fmt.Errorf("parsing exit code of rank %d: %w", rank, err)
+		}
+	}
+	if len(codes) == 0 {
+		return nil, nil
+	}
+
+	ranks := make([]int, 0, len(codes))
+	for rank := range codes {
+		ranks = append(ranks, rank)
+	}
+	sort.Ints(ranks)
+	code := sproto.ExitCode(sproto.SuccessExitCode)
+	for _, rank := range ranks {
+		if codes[rank] != 0 {
+			code = sproto.ExitCode(codes[rank])
+			break
+		}
+	}
+	return &code, nil
+}
+
+// readLog returns the complete lines that were added to the log of a job since offset, and the
+// offset that follows them.
+func readLog(dir string, offset int64) ([]string, int64, error) {
+	f, err := os.Open(filepath.Join(dir, jobLogFile)) // #nosec G304 // The path is ours.
+	switch {
+	case os.IsNotExist(err):
+		return nil, offset, nil
+	case err != nil:
+		return nil, offset, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset, err
+	}
+	contents, err := io.ReadAll(f)
+	if err != nil {
+		return nil, offset, err
+	}
+	// A partial line is read again once it is complete.
+	end := bytes.LastIndexByte(contents, '\n')
+	if end < 0 {
+		return nil, offset, nil
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(contents[:end+1]))
+	scanner.Buffer(nil, end+1)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, offset + int64(end+1), scanner.Err()
+}
+
+// logSize returns the size of the log of a job, or 0 if it has none yet.
+func logSize(dir string) int64 {
+	info, err := os.Stat(filepath.Join(dir, jobLogFile))
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
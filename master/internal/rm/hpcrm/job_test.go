@@ -0,0 +1,166 @@
+package hpcrm
+
+import (
+	"archive/tar"
+	"encoding/base64"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/mount"
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/internal/sproto"
+	"github.com/determined-ai/determined/master/pkg/archive"
+	"github.com/determined-ai/determined/master/pkg/cproto"
+	"github.com/determined-ai/determined/master/pkg/device"
+)
+
+func TestMountPoint(t *testing.T) {
+	require.Equal(t, "/run/determined", mountPoint("/run/determined/train/entrypoint.sh"))
+	require.Equal(t, "/etc/ssh/ssh_config", mountPoint("/etc/ssh/ssh_config"))
+	require.Equal(t, "/opt/determined", mountPoint("/opt/determined/wheels/det.whl"))
+	require.Equal(t, "/work", mountPoint("work/model_def"))
+	require.Equal(t, "", mountPoint("/etc"))
+}
+
+func TestEnvFileContents(t *testing.T) {
+	contents := envFileContents(map[string]string{
+		"DET_MASTER":    "https://master:8080",
+		"DET_SLOT_IDS":  "[0,1]",
+		"DET_TASK_NAME": "it's mine",
+	})
+	require.Equal(t, "DET_MASTER=https://master:8080\n"+
+		"DET_SLOT_IDS="+base64.StdEncoding.EncodeToString([]byte("[0,1]"))+"\n"+
+		"DET_TASK_NAME="+base64.StdEncoding.EncodeToString([]byte("it's mine"))+"\n"+
+		"DET_B64_ENCODED_ENVVARS=DET_SLOT_IDS,DET_TASK_NAME\n", contents)
+}
+
+func TestWriteJob(t *testing.T) {
+	root := t.TempDir()
+	binDir := t.TempDir()
+	wm, err := newWorkloadManager(slurmName, binDir)
+	require.NoError(t, err)
+
+	js := jobSpec{
+		layout: jobLayout{
+			name: "det-cmd", dir: filepath.Join(root, "a1"), nodes: 2, slotsPerNode: 1,
+			slotType: device.CUDA,
+		},
+		runType: config.SingularityContainerRunType,
+		image:   "determinedai/environments:cuda",
+		env:     map[string]string{"DET_TASK_ID": "t1"},
+		workDir: "/run/determined/workdir",
+		mounts: []mount.Mount{
+			{Source: "/shared/data", Target: "/data", ReadOnly: true},
+		},
+		entrypoint: []string{"/run/determined/ship-logs.sh", "python3", "train.py"},
+		archives: []cproto.RunArchive{{
+			Path: "/run/determined",
+			Archive: archive.Archive{{
+				Path: "train/entrypoint.sh", Type: tar.TypeReg, Content: []byte("echo hi"),
+				FileMode: 0o700,
+			}},
+		}},
+	}
+	script, err := writeJob(wm, js)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(root, "a1", jobScriptFile), script)
+
+	contents, err := os.ReadFile(script) // #nosec G304
+	require.NoError(t, err)
+	require.Contains(t, string(contents), "#SBATCH --gpus-per-node=1\n")
+	require.True(t, strings.HasSuffix(string(contents),
+		"srun '"+filepath.Join(root, "a1", taskScriptFile)+"'\n"))
+
+	entrypoint, err := os.ReadFile(filepath.Join(root, "a1", archivesDir, "run/determined/train/entrypoint.sh")) // #nosec G304
+	require.NoError(t, err)
+	require.Equal(t, "echo hi", string(entrypoint))
+
+	// Run the task of rank 1 with a fake singularity that prints its arguments and fails.
+	writeCommand(t, binDir, "singularity", `echo "$SLURM_PROCID/$SLURM_NPROCS"; exit 3`)
+	cmd := exec.Command(filepath.Join(root, "a1", taskScriptFile)) // #nosec G204
+	cmd.Env = append(os.Environ(), "PATH="+binDir+":"+os.Getenv("PATH"), "SLURM_PROCID=1")
+	out, err := cmd.Output()
+	require.Error(t, err)
+	require.Equal(t, "1/2\n", string(out))
+	require.Equal(t, "run --writable-tmpfs --pwd /run/determined/workdir"+
+		" --env-file "+filepath.Join(root, "a1", envFile)+" --nv"+
+		" --bind "+filepath.Join(root, "a1", archivesDir, "run/determined")+":/run/determined"+
+		" --bind /shared/data:/data:ro"+
+		" docker://determinedai/environments:cuda"+
+		" /run/determined/singularity-entrypoint-wrapper.sh"+
+		" /run/determined/ship-logs.sh python3 train.py\n", readArgs(t, binDir, "singularity"))
+
+	code, err := readExitCode(js.layout.dir)
+	require.NoError(t, err)
+	require.Equal(t, sproto.ExitCode(3), *code)
+}
+
+func TestPodmanCommand(t *testing.T) {
+	js := jobSpec{
+		layout:     jobLayout{slotsPerNode: 2, slotType: device.CUDA},
+		runType:    config.PodmanContainerRunType,
+		image:      "determinedai/environments:cuda",
+		workDir:    "/work",
+		shmSize:    1024,
+		entrypoint: []string{"python3"},
+	}
+	require.Equal(t, []string{
+		"podman", "run", "--rm", "--network=host", "--workdir", "/work", "--env-file", "/a/env",
+		"--env", "SLURM_*", "--env", "DET_AGENT_ID",
+		"--env", "CUDA_VISIBLE_DEVICES", "--env", "ROCR_VISIBLE_DEVICES",
+		"--device", "nvidia.com/gpu=all", "--shm-size", "1024",
+		"--volume", "/a/archives/run/determined:/run/determined",
+		"determinedai/environments:cuda",
+		"/run/determined/singularity-entrypoint-wrapper.sh", "python3",
+	}, containerCommand(js, "/a/env", "/a/archives", []string{"/run/determined"}))
+}
+
+func TestReadExitCode(t *testing.T) {
+	dir := t.TempDir()
+	code, err := readExitCode(dir)
+	require.NoError(t, err)
+	require.Nil(t, code)
+
+	write := func(rank, code string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, exitCodePrefix+rank), []byte(code+"\n"), 0o600))
+	}
+	write("0", "0")
+	write("1", "0")
+	code, err = readExitCode(dir)
+	require.NoError(t, err)
+	require.Equal(t, sproto.ExitCode(0), *code)
+
+	write("10", "137")
+	write("2", "1")
+	code, err = readExitCode(dir)
+	require.NoError(t, err)
+	require.Equal(t, sproto.ExitCode(1), *code)
+}
+
+func TestReadLog(t *testing.T) {
+	dir := t.TempDir()
+	lines, offset, err := readLog(dir, 0)
+	require.NoError(t, err)
+	require.Empty(t, lines)
+	require.Zero(t, offset)
+
+	path := filepath.Join(dir, jobLogFile)
+	require.NoError(t, os.WriteFile(path, []byte("first\nsecond\npart"), 0o600))
+	lines, offset, err = readLog(dir, offset)
+	require.NoError(t, err)
+	require.Equal(t, []string{"first", "second"}, lines)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600) // #nosec G304
+	require.NoError(t, err)
+	_, err = f.WriteString("ial\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	lines, _, err = readLog(dir, offset)
+	require.NoError(t, err)
+	require.Equal(t, []string{"partial"}, lines)
+}
@@ -0,0 +1,437 @@
+package hpcrm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/sirupsen/logrus"
+	"github.com/uptrace/bun"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/rm/rmevents"
+	"github.com/determined-ai/determined/master/internal/sproto"
+	"github.com/determined-ai/determined/master/pkg/aproto"
+	"github.com/determined-ai/determined/master/pkg/cproto"
+	"github.com/determined-ai/determined/master/pkg/device"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/tasks"
+)
+
+// commandTimeout bounds each command of the workload manager.
+const commandTimeout = time.Minute
+
+// hpcJob is the batch job of an allocation, which is kept to follow the job again after the master
+// restarts.
+type hpcJob struct {
+	bun.BaseModel `bun:"table:hpc_jobs"`
+
+	AllocationID    model.AllocationID `bun:"allocation_id,pk"`
+	ResourcesID     sproto.ResourcesID `bun:"resources_id"`
+	WorkloadManager string             `bun:"workload_manager"`
+	JobID           string             `bun:"job_id"`
+	Dir             string             `bun:"dir"`
+	NumNodes        int                `bun:"num_nodes"`
+	SubmittedAt     time.Time          `bun:"submitted_at"`
+}
+
+// trackedJob is a submitted job whose state is forwarded to its allocation.
+type trackedJob struct {
+	hpcJob
+	state     cproto.State
+	running   map[int32]bool
+	killed    bool
+	logOffset int64
+	// restored jobs were submitted before the master restarted, and their containers may have
+	// reported they are running to the previous master.
+	restored bool
+}
+
+// jobLaunch is what jobs submits a batch job from.
+type jobLaunch struct {
+	resourcesID  sproto.ResourcesID
+	allocationID model.AllocationID
+	partition    string
+	slots        int
+	spec         tasks.TaskSpec
+}
+
+// jobs submits the batch jobs of allocations and follows them, publishing the changes of their
+// states to the allocations.
+type jobs struct {
+	mu sync.Mutex
+
+	wm              workloadManager
+	config          *config.HPCResourceManagerConfig
+	masterTLSConfig model.TLSClientConfig
+	tracked         map[model.AllocationID]*trackedJob
+
+	syslog *logrus.Entry
+}
+
+func newJobs(
+	wm workloadManager, cfg *config.HPCResourceManagerConfig, masterTLSConfig model.TLSClientConfig,
+) *jobs {
+	return &jobs{
+		wm:              wm,
+		config:          cfg,
+		masterTLSConfig: masterTLSConfig,
+		tracked:         make(map[model.AllocationID]*trackedJob),
+		syslog:          logrus.WithField("component", wm.name()+"-jobs"),
+	}
+}
+
+// submit writes the directory of the batch job of an allocation and submits it.
+func (j *jobs) submit(l jobLaunch) error {
+	js, err := j.jobSpec(l)
+	if err != nil {
+		return err
+	}
+	script, err := writeJob(j.wm, js)
+	if err != nil {
+		j.removeDir(js.layout.dir)
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	id, err := j.wm.submit(ctx, script)
+	if err != nil {
+		j.removeDir(js.layout.dir)
+		return fmt.Errorf("submitting %s job: %w", j.wm.name(), err)
+	}
+
+	job := hpcJob{
+		AllocationID:    l.allocationID,
+		ResourcesID:     l.resourcesID,
+		WorkloadManager: j.wm.name(),
+		JobID:           id,
+		Dir:             js.layout.dir,
+		NumNodes:        js.layout.nodes,
+		SubmittedAt:     time.Now().UTC(),
+	}
+	if _, err := db.Bun().NewInsert().Model(&job).Exec(ctx); err != nil {
+		if cErr := j.wm.cancel(ctx, id); cErr != nil {
+			j.syslog.WithError(cErr).Warnf("canceling job %s", id)
+		}
+		j.removeDir(js.layout.dir)
+		return fmt.Errorf("saving %s job %s: %w", j.wm.name(), id, err)
+	}
+
+	j.syslog.WithField("allocation-id", l.allocationID).Infof("submitted job %s", id)
+	j.track(job, false)
+	return nil
+}
+
+// jobSpec lays out the batch job of an allocation.
+func (j *jobs) jobSpec(l jobLaunch) (jobSpec, error) {
+	spec := l.spec
+	slotsPerNode, gpuType, extraArgs := spec.SlurmConfig.SlotsPerNode(),
+		spec.SlurmConfig.GpuType(), spec.SlurmConfig.SbatchArgs()
+	if j.wm.name() == pbsName {
+		slotsPerNode, gpuType, extraArgs = spec.PbsConfig.SlotsPerNode(), nil, spec.PbsConfig.SbatchArgs()
+	}
+
+	layout := jobLayout{
+		name:         "det-" + spec.Description,
+		dir:          filepath.Join(j.config.JobStorageRoot, string(l.allocationID)),
+		partition:    l.partition,
+		nodes:        1,
+		slotsPerNode: l.slots,
+		slotType:     j.config.SlotType,
+		extraArgs:    extraArgs,
+	}
+	if gpuType != nil {
+		layout.gpuType = *gpuType
+	}
+	if slotsPerNode != nil && *slotsPerNode > 0 && l.slots > *slotsPerNode {
+		if l.slots%*slotsPerNode != 0 {
+			return jobSpec{}, fmt.Errorf(
+				"the slots of the task (%d) are not a multiple of slots_per_node (%d)",
+				l.slots, *slotsPerNode)
+		}
+		layout.nodes, layout.slotsPerNode = l.slots / *slotsPerNode, *slotsPerNode
+	}
+
+	deviceType := layout.slotType
+	if layout.slotsPerNode == 0 {
+		deviceType = device.CPU
+	}
+
+	env := spec.EnvVars()
+	for _, v := range spec.Environment.EnvironmentVariables().For(deviceType) {
+		if key, val, found := strings.Cut(v, "="); found {
+			env[key] = val
+		} else {
+			env[v] = ""
+		}
+	}
+	slotIDs := make([]string, 0, layout.slotsPerNode)
+	for i := 0; i < layout.slotsPerNode; i++ {
+		slotIDs = append(slotIDs, strconv.Itoa(i))
+	}
+	masterScheme := "http"
+	if j.masterTLSConfig.Enabled {
+		masterScheme = "https"
+	}
+	env["DET_CLUSTER_ID"] = spec.ClusterID
+	env["DET_MASTER"] = fmt.Sprintf("%s://%s:%d", masterScheme, j.config.MasterHost, j.config.MasterPort)
+	env["DET_MASTER_HOST"] = j.config.MasterHost
+	env["DET_MASTER_ADDR"] = j.config.MasterHost
+	env["DET_MASTER_PORT"] = strconv.Itoa(j.config.MasterPort)
+	env["DET_SLOT_IDS"] = fmt.Sprintf("[%s]", strings.Join(slotIDs, ","))
+	if j.masterTLSConfig.CertificateName != "" {
+		env["DET_MASTER_CERT_NAME"] = j.masterTLSConfig.CertificateName
+	}
+	// The containers log to the master, so the job log only keeps what goes wrong around them.
+	env["DET_SHIPPER_EMIT_STDOUT_LOGS"] = "False"
+	if _, ok := env["DET_DEBUG"]; !ok {
+		env["DET_DEBUG"] = "0"
+	}
+
+	shmSize := spec.ShmSize
+	if shmSize == 0 {
+		shmSize = spec.TaskContainerDefaults.ShmSizeBytes
+	}
+	user, root := spec.Archives()
+	return jobSpec{
+		layout:     layout,
+		runType:    j.config.ContainerRunType,
+		image:      spec.Environment.Image().For(deviceType),
+		env:        env,
+		workDir:    spec.WorkDir,
+		mounts:     spec.Mounts,
+		shmSize:    shmSize,
+		entrypoint: spec.LogShipperWrappedEntrypoint(),
+		archives:   append(user, root...),
+	}, nil
+}
+
+// restore loads the batch job of an allocation that was submitted before the master restarted.
+func (j *jobs) restore(ctx context.Context, allocationID model.AllocationID) (*hpcJob, error) {
+	var job hpcJob
+	err := db.Bun().NewSelect().Model(&job).Where("allocation_id = ?", allocationID).Scan(ctx)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("allocation %s has no batch job", allocationID)
+	case err != nil:
+		return nil, fmt.Errorf("loading batch job of %s: %w", allocationID, err)
+	case job.WorkloadManager != j.wm.name():
+		return nil, fmt.Errorf("allocation %s has a %s job, but the resource manager is %s",
+			allocationID, job.WorkloadManager, j.wm.name())
+	}
+	return &job, nil
+}
+
+// track starts to follow a job. Restored jobs skip the parts of the log the allocation has seen.
+func (j *jobs) track(job hpcJob, restored bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	t := &trackedJob{
+		hpcJob: job, state: cproto.Assigned, running: make(map[int32]bool), restored: restored,
+	}
+	if restored {
+		t.logOffset = logSize(job.Dir)
+	}
+	j.tracked[job.AllocationID] = t
+}
+
+// isRunning returns whether the job of an allocation has its nodes.
+func (j *jobs) isRunning(allocationID model.AllocationID) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	t, ok := j.tracked[allocationID]
+	return ok && t.state != cproto.Assigned
+}
+
+// containerRunning records that the container of a rank is running. Once all of them are, the
+// allocation is told its resources are running.
+func (j *jobs) containerRunning(msg sproto.NotifyContainerRunning) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	t, ok := j.tracked[msg.AllocationID]
+	if !ok {
+		return fmt.Errorf("allocation %s has no %s job", msg.AllocationID, j.wm.name())
+	}
+	t.running[msg.Rank] = true
+	if len(t.running) >= t.NumNodes && t.state != cproto.Running && t.state != cproto.Terminated {
+		j.publish(t, cproto.Running, nil)
+	}
+	return nil
+}
+
+// kill cancels the job of an allocation. Allocations whose job was never submitted are told their
+// resources stopped, so that they can exit.
+func (j *jobs) kill(allocationID model.AllocationID, resourcesID sproto.ResourcesID) {
+	j.mu.Lock()
+	t, ok := j.tracked[allocationID]
+	if !ok {
+		j.mu.Unlock()
+		rmevents.Publish(allocationID, &sproto.ResourcesStateChanged{
+			ResourcesID:    resourcesID,
+			ResourcesState: sproto.Terminated,
+			ResourcesStopped: &sproto.ResourcesStopped{
+				Failure: sproto.NewResourcesFailure(sproto.TaskAborted, "killed before it was submitted", nil),
+			},
+		})
+		return
+	}
+	t.killed = true
+	id := t.JobID
+	j.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	if err := j.wm.cancel(ctx, id); err != nil {
+		j.syslog.WithError(err).WithField("allocation-id", allocationID).Warnf("canceling job %s", id)
+	}
+}
+
+// release stops following the job of an allocation and cleans up after it.
+func (j *jobs) release(allocationID model.AllocationID) {
+	j.mu.Lock()
+	t, ok := j.tracked[allocationID]
+	delete(j.tracked, allocationID)
+	j.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	if t.state != cproto.Terminated {
+		if err := j.wm.cancel(ctx, t.JobID); err != nil {
+			j.syslog.WithError(err).Warnf("canceling released job %s", t.JobID)
+		}
+	}
+	if _, err := db.Bun().NewDelete().Model((*hpcJob)(nil)).
+		Where("allocation_id = ?", allocationID).Exec(ctx); err != nil {
+		j.syslog.WithError(err).Warnf("deleting job %s", t.JobID)
+	}
+	j.removeDir(t.Dir)
+}
+
+// poll asks the workload manager for the states of the followed jobs and publishes their changes.
+func (j *jobs) poll() {
+	j.mu.Lock()
+	polled := make(map[model.AllocationID]string, len(j.tracked))
+	ids := make([]string, 0, len(j.tracked))
+	for allocationID, t := range j.tracked {
+		if t.state != cproto.Terminated {
+			polled[allocationID] = t.JobID
+			ids = append(ids, t.JobID)
+		}
+	}
+	j.mu.Unlock()
+	if len(ids) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	statuses, err := j.wm.statuses(ctx, ids)
+	if err != nil {
+		j.syslog.WithError(err).Warn("polling job states")
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for allocationID, id := range polled {
+		t, ok := j.tracked[allocationID]
+		if !ok || t.JobID != id || t.state == cproto.Terminated {
+			continue
+		}
+		j.forwardLog(t)
+
+		status, ok := statuses[id]
+		if !ok {
+			status = jobStatus{state: jobDone, native: "ENDED"}
+		}
+		switch status.state {
+		case jobRunning:
+			switch {
+			case t.state == cproto.Assigned && t.restored:
+				j.publish(t, cproto.Running, nil)
+			case t.state == cproto.Assigned:
+				j.publish(t, cproto.Pulling, nil)
+			}
+		case jobDone:
+			j.publish(t, cproto.Terminated, &sproto.ResourcesStopped{Failure: j.failure(t, status)})
+		}
+	}
+}
+
+// failure returns why a job that is done failed, or nil if its task succeeded.
+func (j *jobs) failure(t *trackedJob, status jobStatus) *sproto.ResourcesRestoreError {
+	code, err := readExitCode(t.Dir)
+	switch {
+	case err != nil:
+		return sproto.NewResourcesFailure(sproto.TaskError,
+			fmt.Sprintf("reading exit code of %s job %s: %s", j.wm.name(), t.JobID, err), nil)
+	case code == nil && t.killed:
+		return sproto.NewResourcesFailure(sproto.TaskAborted,
+			fmt.Sprintf("%s job %s was canceled", j.wm.name(), t.JobID), nil)
+	case code == nil:
+		return sproto.NewResourcesFailure(sproto.TaskError, fmt.Sprintf(
+			"%s job %s ended (%s) before its task exited", j.wm.name(), t.JobID, status.native), nil)
+	case *code != sproto.SuccessExitCode:
+		return sproto.NewResourcesFailure(sproto.ResourcesFailed, fmt.Sprintf(
+			"%s job %s ended (%s)", j.wm.name(), t.JobID, status.native), code)
+	default:
+		return nil
+	}
+}
+
+// publish records the new state of a job and tells its allocation. j.mu must be held.
+func (j *jobs) publish(t *trackedJob, state cproto.State, stopped *sproto.ResourcesStopped) {
+	t.state = state
+	msg := &sproto.ResourcesStateChanged{
+		ResourcesID:      t.ResourcesID,
+		ResourcesState:   sproto.FromContainerState(state),
+		ResourcesStopped: stopped,
+		Container:        &cproto.Container{ID: cproto.ID(t.ResourcesID), State: state},
+	}
+	if state == cproto.Running {
+		msg.ResourcesStarted = &sproto.ResourcesStarted{NativeResourcesID: t.JobID}
+	}
+	rmevents.Publish(t.AllocationID, msg)
+}
+
+// forwardLog sends the lines that were added to the log of a job to its allocation. j.mu must be
+// held.
+func (j *jobs) forwardLog(t *trackedJob) {
+	lines, offset, err := readLog(t.Dir, t.logOffset)
+	if err != nil {
+		j.syslog.WithError(err).Debugf("reading log of job %s", t.JobID)
+		return
+	}
+	t.logOffset = offset
+	agentID := aproto.ID(j.wm.name())
+	for _, line := range lines {
+		rmevents.Publish(t.AllocationID, &sproto.ContainerLog{
+			ContainerID: cproto.ID(t.ResourcesID),
+			Timestamp:   time.Now().UTC(),
+			RunMessage:  &aproto.RunMessage{Value: line + "\n", StdType: stdcopy.Stdout},
+			AgentID:     (*string)(&agentID),
+		})
+	}
+}
+
+func (j *jobs) removeDir(dir string) {
+	if err := os.RemoveAll(dir); err != nil {
+		j.syslog.WithError(err).Warnf("removing job directory %s", dir)
+	}
+}
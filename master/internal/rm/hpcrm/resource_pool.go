@@ -0,0 +1,334 @@
+package hpcrm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/internal/rm/rmevents"
+	"github.com/determined-ai/determined/master/internal/rm/tasklist"
+	"github.com/determined-ai/determined/master/internal/sproto"
+	"github.com/determined-ai/determined/master/pkg/aproto"
+	"github.com/determined-ai/determined/master/pkg/cproto"
+	"github.com/determined-ai/determined/master/pkg/device"
+	"github.com/determined-ai/determined/master/pkg/logger"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/tasks"
+	"github.com/determined-ai/determined/proto/pkg/jobv1"
+)
+
+// hpcResourcePool is a partition of the cluster. It hands out resources as soon as it is asked and
+// leaves the queueing to the workload manager, which knows what the cluster can run.
+type hpcResourcePool struct {
+	mu sync.Mutex
+
+	poolConfig *config.ResourcePoolConfig
+	partition  string
+
+	reqList           *tasklist.TaskList
+	groups            map[model.JobID]*tasklist.Group
+	slotsUsedPerGroup map[*tasklist.Group]int
+
+	jobs *jobs
+
+	reschedule bool
+
+	syslog *logrus.Entry
+}
+
+func newResourcePool(poolConfig *config.ResourcePoolConfig, jobs *jobs) *hpcResourcePool {
+	partition := ""
+	if poolConfig.Provider != nil && poolConfig.Provider.HPC != nil {
+		partition = poolConfig.Provider.HPC.Partition
+	}
+	return &hpcResourcePool{
+		poolConfig:        poolConfig,
+		partition:         partition,
+		reqList:           tasklist.New(),
+		groups:            map[model.JobID]*tasklist.Group{},
+		slotsUsedPerGroup: map[*tasklist.Group]int{},
+		jobs:              jobs,
+		syslog:            logrus.WithField("component", jobs.wm.name()+"-rp"),
+	}
+}
+
+func (h *hpcResourcePool) SetGroupMaxSlots(msg sproto.SetGroupMaxSlots) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.reschedule = true
+
+	h.getOrCreateGroup(msg.JobID).MaxSlots = msg.MaxSlots
+}
+
+func (h *hpcResourcePool) SetAllocationName(msg sproto.SetAllocationName) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if task, found := h.reqList.TaskByID(msg.AllocationID); found {
+		task.Name = msg.Name
+	}
+}
+
+func (h *hpcResourcePool) AllocateRequest(msg sproto.AllocateRequest) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.reschedule = true
+
+	if len(msg.AllocationID) == 0 {
+		msg.AllocationID = model.AllocationID(uuid.New().String())
+	}
+	h.getOrCreateGroup(msg.JobID)
+	if len(msg.Name) == 0 {
+		msg.Name = "Unnamed-HPC-Task"
+	}
+	h.syslog.Infof(
+		"resources are requested by %s (Allocation ID: %s)",
+		msg.Name, msg.AllocationID,
+	)
+	h.reqList.AddTask(&msg)
+}
+
+func (h *hpcResourcePool) ResourcesReleased(msg sproto.ResourcesReleased) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.reschedule = true
+
+	req, ok := h.reqList.TaskByID(msg.AllocationID)
+	if !ok {
+		h.syslog.Debugf("ignoring release for task not allocated to pool %s", msg.AllocationID)
+		return
+	}
+	if msg.ResourcesID != nil {
+		// Each allocation has one job, which is cleaned up once the whole allocation is released.
+		return
+	}
+
+	h.syslog.Infof("resources are released for %s", msg.AllocationID)
+	if group := h.groups[req.JobID]; group != nil {
+		h.slotsUsedPerGroup[group] -= req.SlotsNeeded
+	}
+	h.reqList.RemoveTaskByID(msg.AllocationID)
+	rmevents.Publish(msg.AllocationID, sproto.ResourcesReleasedEvent{})
+}
+
+func (h *hpcResourcePool) GetJobQ() map[model.JobID]*sproto.RMJobInfo {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.refreshStates()
+	reqs := tasklist.SortTasksWithPosition(h.reqList, h.groups, tasklist.InitializeJobSortState(true), true)
+	return tasklist.ReduceToJobQInfo(reqs)
+}
+
+func (h *hpcResourcePool) GetJobQStats() *jobv1.QueueStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.refreshStates()
+	return tasklist.JobStats(h.reqList)
+}
+
+func (h *hpcResourcePool) GetAllocationSummary(msg sproto.GetAllocationSummary) *sproto.AllocationSummary {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.reqList.TaskSummary(msg.ID, h.groups, h.jobs.wm.name())
+}
+
+func (h *hpcResourcePool) GetAllocationSummaries() map[model.AllocationID]sproto.AllocationSummary {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.reqList.TaskSummaries(h.groups, h.jobs.wm.name())
+}
+
+// slotsUsed returns the slots of the allocations of the pool that have resources.
+func (h *hpcResourcePool) slotsUsed() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	used := 0
+	for _, slots := range h.slotsUsedPerGroup {
+		used += slots
+	}
+	return used
+}
+
+func (h *hpcResourcePool) Schedule() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.reschedule {
+		h.schedulePendingTasks()
+	}
+	h.reschedule = false
+}
+
+func (h *hpcResourcePool) JobStopped(jobID model.JobID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.slotsUsedPerGroup, h.groups[jobID])
+	delete(h.groups, jobID)
+}
+
+// refreshStates marks the allocations whose jobs got their nodes as scheduled. Until then, they
+// wait in the queue of the workload manager.
+func (h *hpcResourcePool) refreshStates() {
+	for it := h.reqList.Iterator(); it.Next(); {
+		req := it.Value()
+		if h.jobs.isRunning(req.AllocationID) {
+			req.State = sproto.SchedulingStateScheduled
+		} else {
+			req.State = sproto.SchedulingStateQueued
+		}
+	}
+}
+
+func (h *hpcResourcePool) schedulePendingTasks() {
+	for it := h.reqList.Iterator(); it.Next(); {
+		req := it.Value()
+		group := h.groups[req.JobID]
+		if group == nil {
+			h.syslog.Warnf("schedulePendingTasks cannot find group for job %s", req.JobID)
+			continue
+		}
+		if !h.reqList.IsScheduled(req.AllocationID) {
+			if maxSlots := group.MaxSlots; maxSlots != nil {
+				if h.slotsUsedPerGroup[group]+req.SlotsNeeded > *maxSlots {
+					continue
+				}
+			}
+			h.assignResources(req)
+		}
+	}
+}
+
+func (h *hpcResourcePool) assignResources(req *sproto.AllocateRequest) {
+	resources := &hpcJobResources{
+		req:       req,
+		jobs:      h.jobs,
+		id:        sproto.ResourcesID(uuid.New().String()),
+		slots:     req.SlotsNeeded,
+		partition: h.partition,
+	}
+	var restored *hpcJob
+	if req.Restore {
+		job, err := h.jobs.restore(context.TODO(), req.AllocationID)
+		if err != nil {
+			h.syslog.
+				WithField("allocation-id", req.AllocationID).
+				WithError(err).Error("unable to restore allocation")
+			unknownExit := sproto.ExitCode(-1)
+			rmevents.Publish(req.AllocationID, &sproto.ResourcesRestoreError{
+				FailureType: sproto.ResourcesMissing,
+				ErrMsg:      fmt.Sprintf("unable to restore allocation: %s", err),
+				ExitCode:    &unknownExit,
+			})
+			return
+		}
+		resources.id = job.ResourcesID
+		restored = job
+	}
+	h.slotsUsedPerGroup[h.groups[req.JobID]] += req.SlotsNeeded
+
+	assigned := sproto.ResourcesAllocated{
+		ID:        req.AllocationID,
+		Resources: sproto.ResourceList{resources.id: resources},
+	}
+	h.reqList.AddAllocationRaw(req.AllocationID, &assigned)
+	rmevents.Publish(req.AllocationID, assigned.Clone())
+
+	if restored != nil {
+		h.syslog.
+			WithField("allocation-id", req.AllocationID).
+			WithField("task-handler", req.Name).
+			Infof("resources restored with job %s", restored.JobID)
+		// This must happen after we publish ResourcesAllocated, otherwise the allocation could
+		// receive an update for resources it does not know about yet.
+		h.jobs.track(*restored, true)
+	} else {
+		h.syslog.
+			WithField("allocation-id", req.AllocationID).
+			WithField("task-handler", req.Name).
+			Infof("resources assigned with %d slots", req.SlotsNeeded)
+	}
+}
+
+func (h *hpcResourcePool) getOrCreateGroup(jobID model.JobID) *tasklist.Group {
+	if g, ok := h.groups[jobID]; ok {
+		return g
+	}
+	priority := config.DefaultSchedulingPriority
+	g := &tasklist.Group{JobID: jobID, Weight: 1, Priority: &priority}
+
+	h.groups[jobID] = g
+	h.slotsUsedPerGroup[g] = 0
+
+	tasklist.GroupPriorityChangeRegistry.OnDelete(jobID, func() {
+		h.JobStopped(jobID)
+	})
+	return g
+}
+
+// hpcJobResources are the resources of an allocation: one batch job, over as many nodes as the
+// slots of the allocation need.
+type hpcJobResources struct {
+	req       *sproto.AllocateRequest
+	jobs      *jobs
+	id        sproto.ResourcesID
+	slots     int
+	partition string
+}
+
+// Summary summarizes the resources.
+func (r *hpcJobResources) Summary() sproto.ResourcesSummary {
+	containerID := cproto.ID(r.id)
+	return sproto.ResourcesSummary{
+		AllocationID: r.req.AllocationID,
+		ResourcesID:  r.id,
+		// The harness treats jobs of Slurm and PBS alike.
+		ResourcesType: sproto.ResourcesTypeSlurmJob,
+		AgentDevices: map[aproto.ID][]device.Device{
+			aproto.ID(r.jobs.wm.name()): make([]device.Device, r.slots),
+		},
+		ContainerID: &containerID,
+	}
+}
+
+// Start submits the batch job that runs the task.
+func (r *hpcJobResources) Start(
+	_ logger.Context, spec tasks.TaskSpec, rri sproto.ResourcesRuntimeInfo,
+) error {
+	spec.ContainerID = string(r.id)
+	spec.ResourcesID = string(r.id)
+	spec.AllocationID = string(r.req.AllocationID)
+	spec.AllocationSessionToken = rri.Token
+	spec.TaskID = string(r.req.TaskID)
+	spec.UseHostMode = true
+	if spec.LoggingFields == nil {
+		spec.LoggingFields = map[string]string{}
+	}
+	spec.LoggingFields["allocation_id"] = spec.AllocationID
+	spec.LoggingFields["task_id"] = spec.TaskID
+	if spec.ExtraEnvVars == nil {
+		spec.ExtraEnvVars = map[string]string{}
+	}
+	spec.ExtraEnvVars[sproto.ResourcesTypeEnvVar] = string(sproto.ResourcesTypeSlurmJob)
+	return r.jobs.submit(jobLaunch{
+		resourcesID:  r.id,
+		allocationID: r.req.AllocationID,
+		partition:    r.partition,
+		slots:        r.slots,
+		spec:         spec,
+	})
+}
+
+// Kill cancels the batch job.
+func (r *hpcJobResources) Kill(_ logger.Context) {
+	r.jobs.kill(r.req.AllocationID, r.id)
+}
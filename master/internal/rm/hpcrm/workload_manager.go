@@ -0,0 +1,288 @@
+package hpcrm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/determined-ai/determined/master/pkg/device"
+)
+
+// jobState is the state of a batch job, as far as the master is concerned.
+type jobState int
+
+const (
+	// jobPending jobs wait in the queue of the workload manager.
+	jobPending jobState = iota
+	// jobRunning jobs have their nodes and are starting or running their containers.
+	jobRunning
+	// jobDone jobs have ended, for whatever reason.
+	jobDone
+)
+
+// jobStatus is the state of a batch job along with the state the workload manager reported.
+type jobStatus struct {
+	state  jobState
+	native string
+}
+
+// jobLayout is the shape of a batch job: where it runs and what it asks for on each node.
+type jobLayout struct {
+	name         string
+	dir          string
+	partition    string
+	nodes        int
+	slotsPerNode int
+	slotType     device.Type
+	gpuType      string
+	extraArgs    []string
+}
+
+// workloadManager submits, follows and cancels batch jobs with the commands of Slurm or PBS.
+type workloadManager interface {
+	// name is the type of the resource manager, like slurm.
+	name() string
+	// directives returns the header of a batch script that asks for the resources of the job.
+	directives(l jobLayout) []string
+	// launch returns the line of a batch script that runs taskScript once on each node of the job.
+	launch(taskScript string) string
+	submit(ctx context.Context, script string) (string, error)
+	// statuses returns the status of the given jobs. Jobs that the workload manager no longer
+	// knows about are left out, and are done.
+	statuses(ctx context.Context, ids []string) (map[string]jobStatus, error)
+	cancel(ctx context.Context, id string) error
+}
+
+// newWorkloadManager returns the workload manager of the given type, whose commands are in binDir,
+// or on the PATH if binDir is empty.
+func newWorkloadManager(name, binDir string) (workloadManager, error) {
+	switch name {
+	case slurmName:
+		return &slurm{binDir: binDir}, nil
+	case pbsName:
+		return &pbs{binDir: binDir}, nil
+	default:
+		return nil, fmt.Errorf("unknown workload manager %s", name)
+	}
+}
+
+// run runs a command of a workload manager and returns its output.
+func run(ctx context.Context, binDir, command string, args ...string) (string, error) {
+	if binDir != "" {
+		command = filepath.Join(binDir, command)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command, args...) // #nosec G204 // The arguments are ours.
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.String(), fmt.Errorf("%s %s: %w: %s",
+			filepath.Base(command), strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+const slurmName = "slurm"
+
+// slurm runs jobs with sbatch, srun, squeue and scancel.
+type slurm struct {
+	binDir string
+}
+
+func (*slurm) name() string {
+	return slurmName
+}
+
+func (*slurm) directives(l jobLayout) []string {
+	args := []string{
+		"--job-name=" + l.name,
+		"--output=" + filepath.Join(l.dir, jobLogFile),
+		fmt.Sprintf("--nodes=%d", l.nodes),
+		"--ntasks-per-node=1",
+	}
+	if l.partition != "" {
+		args = append(args, "--partition="+l.partition)
+	}
+	switch {
+	case l.slotsPerNode == 0:
+	case l.slotType == device.CPU:
+		args = append(args, fmt.Sprintf("--cpus-per-task=%d", l.slotsPerNode))
+	case l.gpuType != "":
+		args = append(args, fmt.Sprintf("--gpus-per-node=%s:%d", l.gpuType, l.slotsPerNode))
+	default:
+		args = append(args, fmt.Sprintf("--gpus-per-node=%d", l.slotsPerNode))
+	}
+	args = append(args, l.extraArgs...)
+
+	lines := make([]string, 0, len(args))
+	for _, arg := range args {
+		lines = append(lines, "#SBATCH "+arg)
+	}
+	return lines
+}
+
+func (*slurm) launch(taskScript string) string {
+	return "srun " + shellQuote(taskScript)
+}
+
+func (s *slurm) submit(ctx context.Context, script string) (string, error) {
+	out, err := run(ctx, s.binDir, "sbatch", "--parsable", script)
+	if err != nil {
+		return "", err
+	}
+	// The output is "<job id>" or "<job id>;<cluster>".
+	id, _, _ := strings.Cut(strings.TrimSpace(out), ";")
+	if id == "" {
+		return "", fmt.Errorf("sbatch did not print a job ID: %q", out)
+	}
+	return id, nil
+}
+
+func (s *slurm) statuses(ctx context.Context, ids []string) (map[string]jobStatus, error) {
+	out, err := run(ctx, s.binDir, "squeue",
+		"--noheader", "--states=all", "--format=%i|%T", "--jobs="+strings.Join(ids, ","))
+	switch {
+	case err != nil && strings.Contains(err.Error(), "Invalid job id"):
+		// squeue fails when it knows none of the jobs, which have all ended a while ago.
+		return map[string]jobStatus{}, nil
+	case err != nil:
+		return nil, err
+	}
+
+	statuses := make(map[string]jobStatus)
+	for scanner := bufio.NewScanner(strings.NewReader(out)); scanner.Scan(); {
+		id, state, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "|")
+		if !ok {
+			continue
+		}
+		statuses[id] = jobStatus{state: slurmJobState(state), native: state}
+	}
+	return statuses, nil
+}
+
+func (s *slurm) cancel(ctx context.Context, id string) error {
+	_, err := run(ctx, s.binDir, "scancel", id)
+	return err
+}
+
+func slurmJobState(state string) jobState {
+	switch state {
+	case "PENDING", "CONFIGURING", "REQUEUED", "REQUEUE_HOLD", "REQUEUE_FED", "RESV_DEL_HOLD",
+		"SUSPENDED", "STOPPED":
+		return jobPending
+	case "RUNNING", "COMPLETING", "SIGNALING", "STAGE_OUT", "RESIZING":
+		return jobRunning
+	default:
+		return jobDone
+	}
+}
+
+const pbsName = "pbs"
+
+// pbs runs jobs with qsub, pbsdsh, qstat and qdel.
+type pbs struct {
+	binDir string
+}
+
+func (*pbs) name() string {
+	return pbsName
+}
+
+func (*pbs) directives(l jobLayout) []string {
+	chunk := fmt.Sprintf("select=%d", l.nodes)
+	switch {
+	case l.slotsPerNode == 0:
+	case l.slotType == device.CPU:
+		chunk += fmt.Sprintf(":ncpus=%d", l.slotsPerNode)
+	default:
+		chunk += fmt.Sprintf(":ngpus=%d", l.slotsPerNode)
+	}
+	if l.gpuType != "" && l.slotType != device.CPU {
+		chunk += ":gpu_type=" + l.gpuType
+	}
+	chunk += ":mpiprocs=1"
+
+	args := []string{
+		"-N " + l.name,
+		"-o " + filepath.Join(l.dir, jobLogFile),
+		"-j oe",
+		"-l " + chunk,
+		"-l place=scatter",
+	}
+	if l.partition != "" {
+		args = append(args, "-q "+l.partition)
+	}
+	args = append(args, l.extraArgs...)
+
+	lines := make([]string, 0, len(args))
+	for _, arg := range args {
+		lines = append(lines, "#PBS "+arg)
+	}
+	return lines
+}
+
+func (*pbs) launch(taskScript string) string {
+	return "pbsdsh -- " + shellQuote(taskScript)
+}
+
+func (p *pbs) submit(ctx context.Context, script string) (string, error) {
+	out, err := run(ctx, p.binDir, "qsub", script)
+	if err != nil {
+		return "", err
+	}
+	id := strings.TrimSpace(out)
+	if id == "" {
+		return "", fmt.Errorf("qsub did not print a job ID")
+	}
+	return id, nil
+}
+
+func (p *pbs) statuses(ctx context.Context, ids []string) (map[string]jobStatus, error) {
+	out, err := run(ctx, p.binDir, "qstat", append([]string{"-x", "-f"}, ids...)...)
+	if err != nil && !strings.Contains(err.Error(), "Unknown Job Id") {
+		// qstat fails if it does not know one of the jobs, but still prints the others.
+		return nil, err
+	}
+
+	statuses := make(map[string]jobStatus)
+	var id string
+	for scanner := bufio.NewScanner(strings.NewReader(out)); scanner.Scan(); {
+		line := strings.TrimSpace(scanner.Text())
+		if rest, ok := strings.CutPrefix(line, "Job Id:"); ok {
+			id = strings.TrimSpace(rest)
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || id == "" || strings.TrimSpace(key) != "job_state" {
+			continue
+		}
+		state := strings.TrimSpace(value)
+		statuses[id] = jobStatus{state: pbsJobState(state), native: state}
+	}
+	return statuses, nil
+}
+
+func (p *pbs) cancel(ctx context.Context, id string) error {
+	_, err := run(ctx, p.binDir, "qdel", id)
+	return err
+}
+
+func pbsJobState(state string) jobState {
+	switch state {
+	case "Q", "H", "W", "T", "S", "U":
+		return jobPending
+	case "R", "E", "B":
+		return jobRunning
+	default:
+		return jobDone
+	}
+}
+
+// shellQuote quotes s as a single word for bash.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
@@ -0,0 +1,136 @@
+package hpcrm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/pkg/device"
+)
+
+// writeCommand writes a fake command of a workload manager to binDir, which records its arguments
+// in <name>.args and runs script.
+func writeCommand(t *testing.T, binDir, name, script string) {
+	contents := "#!/usr/bin/env bash\n" +
+		`echo "$@" > "$(dirname "$0")/` + name + ".args\"\n" + script + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, name), []byte(contents), 0o700)) // #nosec G306
+}
+
+func readArgs(t *testing.T, binDir, name string) string {
+	args, err := os.ReadFile(filepath.Join(binDir, name+".args")) // #nosec G304
+	require.NoError(t, err)
+	return string(args)
+}
+
+func TestSlurm(t *testing.T) {
+	ctx := context.Background()
+	binDir := t.TempDir()
+	wm, err := newWorkloadManager(slurmName, binDir)
+	require.NoError(t, err)
+
+	layout := jobLayout{
+		name: "det-exp-1-trial-1", dir: "/shared/a1", partition: "gpus",
+		nodes: 2, slotsPerNode: 4, slotType: device.CUDA, gpuType: "a100",
+		extraArgs: []string{"--exclusive"},
+	}
+	require.Equal(t, []string{
+		"#SBATCH --job-name=det-exp-1-trial-1",
+		"#SBATCH --output=/shared/a1/job.log",
+		"#SBATCH --nodes=2",
+		"#SBATCH --ntasks-per-node=1",
+		"#SBATCH --partition=gpus",
+		"#SBATCH --gpus-per-node=a100:4",
+		"#SBATCH --exclusive",
+	}, wm.directives(layout))
+	layout.slotType, layout.partition, layout.extraArgs = device.CPU, "", nil
+	require.Contains(t, wm.directives(layout), "#SBATCH --cpus-per-task=4")
+	require.Equal(t, "srun '/shared/a1/task.sh'", wm.launch("/shared/a1/task.sh"))
+
+	writeCommand(t, binDir, "sbatch", `echo "1234;cluster"`)
+	id, err := wm.submit(ctx, "/shared/a1/job.sh")
+	require.NoError(t, err)
+	require.Equal(t, "1234", id)
+	require.Equal(t, "--parsable /shared/a1/job.sh\n", readArgs(t, binDir, "sbatch"))
+
+	writeCommand(t, binDir, "sbatch", `echo "Batch job submission failed" >&2; exit 1`)
+	_, err = wm.submit(ctx, "/shared/a1/job.sh")
+	require.ErrorContains(t, err, "Batch job submission failed")
+
+	writeCommand(t, binDir, "squeue", `printf "1234|PENDING\n1235|RUNNING\n1236|FAILED\n"`)
+	statuses, err := wm.statuses(ctx, []string{"1234", "1235", "1236", "1237"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]jobStatus{
+		"1234": {state: jobPending, native: "PENDING"},
+		"1235": {state: jobRunning, native: "RUNNING"},
+		"1236": {state: jobDone, native: "FAILED"},
+	}, statuses)
+	require.Contains(t, readArgs(t, binDir, "squeue"), "--jobs=1234,1235,1236,1237")
+
+	writeCommand(t, binDir, "squeue", `echo "slurm_load_jobs error: Invalid job id specified" >&2; exit 1`)
+	statuses, err = wm.statuses(ctx, []string{"1234"})
+	require.NoError(t, err)
+	require.Empty(t, statuses)
+
+	writeCommand(t, binDir, "scancel", "")
+	require.NoError(t, wm.cancel(ctx, "1234"))
+	require.Equal(t, "1234\n", readArgs(t, binDir, "scancel"))
+}
+
+func TestPBS(t *testing.T) {
+	ctx := context.Background()
+	binDir := t.TempDir()
+	wm, err := newWorkloadManager(pbsName, binDir)
+	require.NoError(t, err)
+
+	layout := jobLayout{
+		name: "det-cmd", dir: "/shared/a2", partition: "workq",
+		nodes: 3, slotsPerNode: 2, slotType: device.CUDA,
+	}
+	require.Equal(t, []string{
+		"#PBS -N det-cmd",
+		"#PBS -o /shared/a2/job.log",
+		"#PBS -j oe",
+		"#PBS -l select=3:ngpus=2:mpiprocs=1",
+		"#PBS -l place=scatter",
+		"#PBS -q workq",
+	}, wm.directives(layout))
+	require.Equal(t, "pbsdsh -- '/shared/a2/task.sh'", wm.launch("/shared/a2/task.sh"))
+
+	writeCommand(t, binDir, "qsub", `echo "42.pbs-server"`)
+	id, err := wm.submit(ctx, "/shared/a2/job.sh")
+	require.NoError(t, err)
+	require.Equal(t, "42.pbs-server", id)
+
+	writeCommand(t, binDir, "qstat", `cat <<'EOT'
+Job Id: 42.pbs-server
+    Job_Name = det-cmd
+    job_state = Q
+
+Job Id: 43.pbs-server
+    job_state = R
+
+Job Id: 44.pbs-server
+    job_state = F
+EOT
+echo "qstat: Unknown Job Id 45.pbs-server" >&2
+exit 153`)
+	statuses, err := wm.statuses(ctx, []string{"42.pbs-server", "43.pbs-server", "44.pbs-server", "45.pbs-server"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]jobStatus{
+		"42.pbs-server": {state: jobPending, native: "Q"},
+		"43.pbs-server": {state: jobRunning, native: "R"},
+		"44.pbs-server": {state: jobDone, native: "F"},
+	}, statuses)
+
+	writeCommand(t, binDir, "qdel", "")
+	require.NoError(t, wm.cancel(ctx, "42.pbs-server"))
+	require.Equal(t, "42.pbs-server\n", readArgs(t, binDir, "qdel"))
+}
+
+func TestShellQuote(t *testing.T) {
+	require.Equal(t, `'plain'`, shellQuote("plain"))
+	require.Equal(t, `'it'"'"'s $HOME'`, shellQuote("it's $HOME"))
+}
@@ -0,0 +1,124 @@
+package kubernetesrm
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+
+	k8sV1 "k8s.io/api/core/v1"
+	networkingV1 "k8s.io/api/networking/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+const (
+	// dnsNamespace is where the DNS servers of the cluster run, which pods with restricted egress
+	// can still query.
+	dnsNamespace = "kube-system"
+	// namespaceNameLabel is set by Kubernetes on every namespace to its name.
+	namespaceNameLabel = "kubernetes.io/metadata.name"
+)
+
+// lookupNetIP resolves the hostnames that egress rules allow; it is replaced in tests.
+var lookupNetIP = net.DefaultResolver.LookupNetIP
+
+// configureNetworkPolicy returns the NetworkPolicy that restricts the egress of the pod, or nil if
+// its task has no egress rule. The policy selects all pods of the allocation, which can reach each
+// other, the master, the DNS servers of the cluster and what the rule allows. NetworkPolicies only
+// allow IP blocks, so hostnames are resolved here, when the pod is created.
+func (p *pod) configureNetworkPolicy(ctx context.Context) (*networkingV1.NetworkPolicy, error) {
+	rule := p.submissionInfo.taskSpec.Egress
+	if rule == nil {
+		return nil, nil
+	}
+	allocationPods := metaV1.LabelSelector{
+		MatchLabels: map[string]string{determinedLabel: p.submissionInfo.taskSpec.AllocationID},
+	}
+
+	tcp, udp := k8sV1.ProtocolTCP, k8sV1.ProtocolUDP
+	dnsPort, masterPort := intstr.FromInt(53), intstr.FromInt(int(p.masterPort))
+	egress := []networkingV1.NetworkPolicyEgressRule{
+		{To: []networkingV1.NetworkPolicyPeer{{PodSelector: &allocationPods}}},
+		{
+			To: []networkingV1.NetworkPolicyPeer{{NamespaceSelector: &metaV1.LabelSelector{
+				MatchLabels: map[string]string{namespaceNameLabel: dnsNamespace},
+			}}},
+			Ports: []networkingV1.NetworkPolicyPort{
+				{Protocol: &udp, Port: &dnsPort},
+				{Protocol: &tcp, Port: &dnsPort},
+			},
+		},
+	}
+
+	masterBlocks, err := resolveIPBlocks(ctx, p.masterIP)
+	if err != nil {
+		return nil, fmt.Errorf("resolving master: %w", err)
+	}
+	egress = append(egress, networkingV1.NetworkPolicyEgressRule{
+		To:    masterBlocks,
+		Ports: []networkingV1.NetworkPolicyPort{{Protocol: &tcp, Port: &masterPort}},
+	})
+	// Traffic to the master's service may only be matched after it is translated to the
+	// addresses of the master's pods, whose port can differ from the service's.
+	if p.masterPeer != nil {
+		egress = append(egress, networkingV1.NetworkPolicyEgressRule{
+			To: []networkingV1.NetworkPolicyPeer{*p.masterPeer},
+		})
+	}
+
+	var allowed []networkingV1.NetworkPolicyPeer
+	for _, dest := range rule.Allow {
+		blocks, err := resolveIPBlocks(ctx, dest)
+		if err != nil {
+			return nil, err
+		}
+		allowed = append(allowed, blocks...)
+	}
+	if len(allowed) > 0 {
+		egress = append(egress, networkingV1.NetworkPolicyEgressRule{To: allowed})
+	}
+
+	return &networkingV1.NetworkPolicy{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      p.podName,
+			Namespace: p.namespace,
+			Labels:    map[string]string{determinedLabel: p.submissionInfo.taskSpec.AllocationID},
+		},
+		Spec: networkingV1.NetworkPolicySpec{
+			PodSelector: allocationPods,
+			PolicyTypes: []networkingV1.PolicyType{networkingV1.PolicyTypeEgress},
+			Egress:      egress,
+		},
+	}, nil
+}
+
+// resolveIPBlocks returns the IP blocks of a CIDR, an IP address or the addresses of a hostname.
+func resolveIPBlocks(ctx context.Context, dest string) ([]networkingV1.NetworkPolicyPeer, error) {
+	prefix, host, err := model.ParseEgressDestination(dest)
+	if err != nil {
+		return nil, err
+	}
+	prefixes := []netip.Prefix{prefix}
+	if host != "" {
+		addrs, err := lookupNetIP(ctx, "ip", host)
+		if err != nil {
+			return nil, fmt.Errorf("resolving %s: %w", host, err)
+		}
+		prefixes = prefixes[:0]
+		for _, a := range addrs {
+			a = a.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(a, a.BitLen()))
+		}
+	}
+
+	var peers []networkingV1.NetworkPolicyPeer
+	for _, p := range prefixes {
+		peers = append(peers, networkingV1.NetworkPolicyPeer{
+			IPBlock: &networkingV1.IPBlock{CIDR: p.String()},
+		})
+	}
+	return peers, nil
+}
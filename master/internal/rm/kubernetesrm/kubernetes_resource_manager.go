@@ -16,6 +16,7 @@ import (
 	"github.com/determined-ai/determined/master/internal/rm/rmevents"
 	"github.com/determined-ai/determined/master/internal/rm/rmutils"
 	"github.com/determined-ai/determined/master/internal/sproto"
+	"github.com/determined-ai/determined/master/internal/workspace"
 	"github.com/determined-ai/determined/master/pkg/aproto"
 	"github.com/determined-ai/determined/master/pkg/command"
 	"github.com/determined-ai/determined/master/pkg/model"
@@ -96,6 +97,7 @@ func New(
 	k.podsService = newPodsService(
 		k.config.Namespace,
 		poolNamespaces,
+		k.config.WorkspaceNamespaces,
 		k.config.MasterServiceName,
 		k.masterTLSConfig,
 		k.loggingConfig,
@@ -123,6 +125,9 @@ func New(
 
 		poolConfig := poolConfig
 		rp := newResourcePool(maxSlotsPerPod, &poolConfig, k.podsService, k.db)
+		if err := loadWorkspaceMaxSlots(rp); err != nil {
+			panic(err)
+		}
 		go func() {
 			t := time.NewTicker(podSubmissionInterval)
 			defer t.Stop()
@@ -155,6 +160,13 @@ func (k *ResourceManager) Allocate(msg sproto.AllocateRequest) (*sproto.Resource
 	if err != nil {
 		return nil, err
 	}
+	if msg.WorkspaceID != 0 {
+		gpuLimit, err := workspace.GPULimit(context.TODO(), msg.WorkspaceID)
+		if err != nil {
+			return nil, err
+		}
+		k.podsService.workspaceGPULimits.setLimit(msg.WorkspaceID, gpuLimit)
+	}
 	sub := rmevents.Subscribe(msg.AllocationID)
 	rp.AllocateRequest(msg)
 	return sub, nil
@@ -353,6 +365,17 @@ func (k *ResourceManager) SetGroupMaxSlots(msg sproto.SetGroupMaxSlots) {
 	rp.SetGroupMaxSlots(msg)
 }
 
+// SetWorkspaceMaxSlots implements rm.ResourceManager.
+func (k *ResourceManager) SetWorkspaceMaxSlots(msg sproto.SetWorkspaceMaxSlots) {
+	rp, err := k.poolByName(msg.ResourcePool)
+	if err != nil {
+		k.syslog.WithError(err).Warnf("set workspace max slots found no resource pool with name %s",
+			msg.ResourcePool)
+		return
+	}
+	rp.SetWorkspaceMaxSlots(msg)
+}
+
 // SetGroupPriority implements rm.ResourceManager.
 func (k *ResourceManager) SetGroupPriority(msg sproto.SetGroupPriority) error {
 	rp, err := k.poolByName(msg.ResourcePool)
@@ -460,6 +483,16 @@ func (k ResourceManager) ResolveResourcePool(
 			name, workspaceID)
 	}
 
+	binding, err := db.GetRPWorkspaceBinding(ctx, workspaceID, name)
+	if err != nil {
+		return "", err
+	}
+	if binding != nil {
+		if err := binding.CheckSlots(slots); err != nil {
+			return "", fmt.Errorf("resource pool %s: %w", name, err)
+		}
+	}
+
 	if err := k.ValidateResourcePool(name); err != nil {
 		return "", fmt.Errorf("validating pool: %w", err)
 	}
@@ -13,7 +13,9 @@ import (
 
 	k8sV1 "k8s.io/api/core/v1"
 	"k8s.io/api/policy/v1beta1"
+	k8error "k8s.io/apimachinery/pkg/api/errors"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/rest"
@@ -97,6 +99,102 @@ func (m *mockConfigMapInterface) Patch(
 	panic("implement me")
 }
 
+type mockPVCInterface struct {
+	pvcs map[string]*k8sV1.PersistentVolumeClaim
+	mux  sync.Mutex
+}
+
+func newMockPVCInterface() *mockPVCInterface {
+	return &mockPVCInterface{pvcs: make(map[string]*k8sV1.PersistentVolumeClaim)}
+}
+
+func (m *mockPVCInterface) hasPVC(name string) bool {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	_, ok := m.pvcs[name]
+	return ok
+}
+
+func (m *mockPVCInterface) Create(
+	ctx context.Context, pvc *k8sV1.PersistentVolumeClaim, opts metaV1.CreateOptions,
+) (*k8sV1.PersistentVolumeClaim, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if _, present := m.pvcs[pvc.Name]; present {
+		return nil, k8error.NewAlreadyExists(
+			schema.GroupResource{Resource: "persistentvolumeclaims"}, pvc.Name)
+	}
+
+	m.pvcs[pvc.Name] = pvc.DeepCopy()
+	return m.pvcs[pvc.Name], nil
+}
+
+func (m *mockPVCInterface) Update(
+	context.Context, *k8sV1.PersistentVolumeClaim, metaV1.UpdateOptions,
+) (*k8sV1.PersistentVolumeClaim, error) {
+	panic("implement me")
+}
+
+func (m *mockPVCInterface) UpdateStatus(
+	context.Context, *k8sV1.PersistentVolumeClaim, metaV1.UpdateOptions,
+) (*k8sV1.PersistentVolumeClaim, error) {
+	panic("implement me")
+}
+
+func (m *mockPVCInterface) Delete(
+	ctx context.Context, name string, options metaV1.DeleteOptions,
+) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if _, present := m.pvcs[name]; !present {
+		return errors.Errorf("persistentVolumeClaim with name %s doesn't exists", name)
+	}
+
+	delete(m.pvcs, name)
+	return nil
+}
+
+func (m *mockPVCInterface) DeleteCollection(
+	ctx context.Context, options metaV1.DeleteOptions, listOptions metaV1.ListOptions,
+) error {
+	panic("implement me")
+}
+
+func (m *mockPVCInterface) Get(
+	ctx context.Context, name string, options metaV1.GetOptions,
+) (*k8sV1.PersistentVolumeClaim, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	pvc, present := m.pvcs[name]
+	if !present {
+		return nil, k8error.NewNotFound(
+			schema.GroupResource{Resource: "persistentvolumeclaims"}, name)
+	}
+	return pvc.DeepCopy(), nil
+}
+
+func (m *mockPVCInterface) List(
+	ctx context.Context, opts metaV1.ListOptions,
+) (*k8sV1.PersistentVolumeClaimList, error) {
+	panic("implement me")
+}
+
+func (m *mockPVCInterface) Watch(
+	ctx context.Context, opts metaV1.ListOptions,
+) (watch.Interface, error) {
+	panic("implement me")
+}
+
+func (m *mockPVCInterface) Patch(
+	ctx context.Context, name string, pt types.PatchType, data []byte, opts metaV1.PatchOptions,
+	subresources ...string,
+) (result *k8sV1.PersistentVolumeClaim, err error) {
+	panic("implement me")
+}
+
 type mockPodInterface struct {
 	pods map[string]*k8sV1.Pod
 	// Simulates latency of the real k8 API server.
@@ -26,6 +26,7 @@ import (
 	"github.com/determined-ai/determined/master/pkg/tasks"
 
 	k8sV1 "k8s.io/api/core/v1"
+	networkingV1 "k8s.io/api/networking/v1"
 	k8sClient "k8s.io/client-go/kubernetes"
 	typedV1 "k8s.io/client-go/kubernetes/typed/core/v1"
 )
@@ -78,11 +79,24 @@ type pod struct {
 	scheduler            string
 	slotType             device.Type
 	slotResourceRequests config.PodSlotResourceRequests
+	// serviceAccountName is the service account the pod runs as if its pod spec doesn't set one.
+	serviceAccountName string
+	// prepareNamespace is set if the pod runs in the namespace of its workspace.
+	prepareNamespace *prepareWorkspaceNamespace
 
 	pod           *k8sV1.Pod
 	podName       string
 	configMap     *k8sV1.ConfigMap
 	configMapName string
+	// scratchPVC is the claim of the pod's scratch volume, if the task asked for one.
+	scratchPVC     *k8sV1.PersistentVolumeClaim
+	scratchPVCName string
+	workspacePVCs  []*k8sV1.PersistentVolumeClaim
+	// networkPolicy restricts the egress of the pod, if its task has an egress rule.
+	networkPolicy *networkingV1.NetworkPolicy
+	masterPeer    *networkingV1.NetworkPolicyPeer
+	// secret holds the values of the secrets the task references, if any.
+	secret *k8sV1.Secret
 	// TODO(DET-10013) : Remove container field from pod struct.
 	container        cproto.Container
 	ports            []int
@@ -314,6 +328,7 @@ func (p *pod) kill() {
 		p.namespace,
 		p.podName,
 		p.configMapName,
+		p.scratchPVCName,
 	)
 }
 
@@ -346,7 +361,9 @@ func (p *pod) createPodSpecAndSubmit() error {
 		return err
 	}
 
-	p.resourceRequestQueue.createKubernetesResources(p.pod, p.configMap)
+	p.resourceRequestQueue.createKubernetesResources(
+		p.pod, p.configMap, p.scratchPVC, p.workspacePVCs, p.networkPolicy, p.secret,
+		p.prepareNamespace)
 	return nil
 }
 
@@ -26,6 +26,7 @@ import (
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sClient "k8s.io/client-go/kubernetes"
 	typedV1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	typedNetworkingV1 "k8s.io/client-go/kubernetes/typed/networking/v1"
 )
 
 func createPod(
@@ -108,6 +109,9 @@ func createPodWithMockQueue(t *testing.T, k8sRequestQueue *requestQueue) (
 		k8sRequestQueue = startRequestQueue(
 			map[string]typedV1.PodInterface{"default": podInterface},
 			map[string]typedV1.ConfigMapInterface{"default": configMapInterface},
+			map[string]typedV1.PersistentVolumeClaimInterface{"default": newMockPVCInterface()},
+			map[string]typedNetworkingV1.NetworkPolicyInterface{},
+			map[string]typedV1.SecretInterface{},
 			failures,
 		)
 	}
@@ -672,6 +676,9 @@ func TestKillTaskPod(t *testing.T) {
 	k8sRequestQueue := startRequestQueue(
 		map[string]typedV1.PodInterface{"default": podInterface},
 		map[string]typedV1.ConfigMapInterface{"default": configMapInterface},
+		map[string]typedV1.PersistentVolumeClaimInterface{"default": newMockPVCInterface()},
+		map[string]typedNetworkingV1.NetworkPolicyInterface{},
+		map[string]typedV1.SecretInterface{},
 		failures,
 	)
 	ref, _, _ := createPodWithMockQueue(t, k8sRequestQueue)
@@ -697,6 +704,9 @@ func TestResourceCreationCancelled(t *testing.T) {
 	k8sRequestQueue := startRequestQueue(
 		map[string]typedV1.PodInterface{"default": podInterface},
 		map[string]typedV1.ConfigMapInterface{"default": configMapInterface},
+		map[string]typedV1.PersistentVolumeClaimInterface{"default": newMockPVCInterface()},
+		map[string]typedNetworkingV1.NetworkPolicyInterface{},
+		map[string]typedV1.SecretInterface{},
 		failures,
 	)
 
@@ -746,6 +756,9 @@ func TestResourceDeletionFailed(t *testing.T) {
 	k8sRequestQueue := startRequestQueue(
 		map[string]typedV1.PodInterface{"default": podInterface},
 		map[string]typedV1.ConfigMapInterface{"default": configMapInterface},
+		map[string]typedV1.PersistentVolumeClaimInterface{"default": newMockPVCInterface()},
+		map[string]typedNetworkingV1.NetworkPolicyInterface{},
+		map[string]typedV1.SecretInterface{},
 		failures,
 	)
 
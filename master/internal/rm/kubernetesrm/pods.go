@@ -16,6 +16,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"golang.org/x/exp/maps"
 	k8sV1 "k8s.io/api/core/v1"
+	networkingV1 "k8s.io/api/networking/v1"
 	k8error "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -23,6 +24,7 @@ import (
 	"k8s.io/apimachinery/pkg/watch"
 	k8sClient "k8s.io/client-go/kubernetes"
 	typedV1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	typedNetworkingV1 "k8s.io/client-go/kubernetes/typed/networking/v1"
 	"k8s.io/client-go/rest"
 
 	"github.com/determined-ai/determined/master/internal/config"
@@ -78,6 +80,13 @@ type pods struct {
 	baseContainerDefaults *model.TaskContainerDefaultsConfig
 	credsDir              string
 
+	// workspaceNamespacesConfig is set if tasks of workspaces run in namespaces of their own,
+	// which are tracked in workspaceNamespaces by name.
+	workspaceNamespacesConfig *config.WorkspaceNamespacesConfig
+	workspaceNamespaces       map[string]*workspaceNamespace
+	// workspaceGPULimits accounts for the GPUs of each workspace across the resource pools.
+	workspaceGPULimits *workspaceGPULimits
+
 	clientSet        k8sClient.Interface
 	masterIP         string
 	masterPort       int32
@@ -98,6 +107,14 @@ type pods struct {
 
 	podInterfaces       map[string]typedV1.PodInterface
 	configMapInterfaces map[string]typedV1.ConfigMapInterface
+	pvcInterfaces       map[string]typedV1.PersistentVolumeClaimInterface
+	// networkPolicyInterfaces create the NetworkPolicies that restrict the egress of pods.
+	networkPolicyInterfaces map[string]typedNetworkingV1.NetworkPolicyInterface
+	// secretInterfaces create the Secrets that hold the values of the secrets of pods.
+	secretInterfaces map[string]typedV1.SecretInterface
+	// masterPeer selects the pods of the master, if it runs in the cluster, so that pods with
+	// restricted egress can reach it.
+	masterPeer *networkingV1.NetworkPolicyPeer
 
 	summarizeCacheLock sync.RWMutex
 	summarizeCache     summarizeResult
@@ -145,6 +162,7 @@ type refreshPodStates struct {
 func newPodsService(
 	namespace string,
 	namespaceToPoolName map[string]string,
+	workspaceNamespacesConfig *config.WorkspaceNamespacesConfig,
 	masterServiceName string,
 	masterTLSConfig model.TLSClientConfig,
 	loggingConfig model.LoggingConfig,
@@ -167,6 +185,9 @@ func newPodsService(
 
 		namespace:                    namespace,
 		namespaceToPoolName:          namespaceToPoolName,
+		workspaceNamespacesConfig:    workspaceNamespacesConfig,
+		workspaceNamespaces:          make(map[string]*workspaceNamespace),
+		workspaceGPULimits:           newWorkspaceGPULimits(),
 		masterServiceName:            masterServiceName,
 		masterTLSConfig:              masterTLSConfig,
 		scheduler:                    scheduler,
@@ -189,6 +210,9 @@ func newPodsService(
 		nodeToSystemResourceRequests: make(map[string]int64),
 		podInterfaces:                make(map[string]typedV1.PodInterface),
 		configMapInterfaces:          make(map[string]typedV1.ConfigMapInterface),
+		pvcInterfaces:                make(map[string]typedV1.PersistentVolumeClaimInterface),
+		networkPolicyInterfaces:      make(map[string]typedNetworkingV1.NetworkPolicyInterface),
+		secretInterfaces:             make(map[string]typedV1.SecretInterface),
 		syslog:                       logrus.WithField("namespace", namespace),
 		podStatusUpdateCallback:      podStatusUpdateCallback,
 	}
@@ -208,6 +232,7 @@ func newPodsService(
 	if err := p.deleteDoomedKubernetesResources(); err != nil {
 		panic(err)
 	}
+	p.wg.Go(p.periodicallyDeleteOrphanedWorkspaceVolumeClaims)
 
 	err := p.startPodInformer()
 	if err != nil {
@@ -368,8 +393,12 @@ func (p *pods) startClientSet() error {
 	}
 
 	for _, ns := range append(maps.Keys(p.namespaceToPoolName), p.namespace) {
-		p.podInterfaces[ns] = p.clientSet.CoreV1().Pods(ns)
-		p.configMapInterfaces[ns] = p.clientSet.CoreV1().ConfigMaps(ns)
+		p.addNamespaceInterfaces(ns)
+	}
+	if p.workspaceNamespacesConfig != nil {
+		if err := p.findWorkspaceNamespaces(); err != nil {
+			return err
+		}
 	}
 
 	p.syslog.Infof("kubernetes clientSet initialized")
@@ -389,6 +418,14 @@ func (p *pods) getMasterIPAndPort() error {
 
 	p.masterIP = masterService.Spec.ClusterIP
 	p.masterPort = masterService.Spec.Ports[0].Port
+	if len(masterService.Spec.Selector) > 0 {
+		p.masterPeer = &networkingV1.NetworkPolicyPeer{
+			NamespaceSelector: &metaV1.LabelSelector{
+				MatchLabels: map[string]string{namespaceNameLabel: p.namespace},
+			},
+			PodSelector: &metaV1.LabelSelector{MatchLabels: masterService.Spec.Selector},
+		}
+	}
 	p.syslog.Infof("master URL set to %s:%d", p.masterIP, p.masterPort)
 	return nil
 }
@@ -425,18 +462,23 @@ func (p *pods) reattachAllocationPods(msg reattachAllocationPods) ([]reattachPod
 	}
 	existingConfigMaps := make(set.Set[string])
 	for _, cm := range configMaps.Items {
-		if _, ok := p.namespaceToPoolName[cm.Namespace]; !ok {
+		if !p.isTaskNamespace(cm.Namespace) {
 			continue
 		}
 		existingConfigMaps.Insert(cm.Name)
 	}
 
+	pvcs, err := p.listPVCsInAllNamespaces(context.TODO(), listOptions)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing persistent volume claims checking if they can be restored")
+	}
+
 	var containerIDs []string
 	var k8sPods []*k8sV1.Pod
 	var ports [][]int
 	var resourcePool string
 	for _, pod := range pods.Items {
-		if _, ok := p.namespaceToPoolName[pod.Namespace]; !ok {
+		if !p.isTaskNamespace(pod.Namespace) {
 			continue
 		}
 
@@ -447,7 +489,7 @@ func (p *pods) reattachAllocationPods(msg reattachAllocationPods) ([]reattachPod
 				switch env.Name {
 				case "DET_CONTAINER_ID":
 					if !existingConfigMaps.Contains(pod.Name) {
-						p.deleteKubernetesResources(pods, configMaps)
+						p.deleteKubernetesResources(pods, configMaps, pvcs)
 						return nil, fmt.Errorf("pod missing config map %s", pod.Name)
 					}
 
@@ -474,12 +516,12 @@ func (p *pods) reattachAllocationPods(msg reattachAllocationPods) ([]reattachPod
 	}
 
 	if len(k8sPods) != msg.numPods {
-		p.deleteKubernetesResources(pods, configMaps)
+		p.deleteKubernetesResources(pods, configMaps, pvcs)
 		return nil, fmt.Errorf("not enough pods found for allocation expected %d got %d instead",
 			msg.numPods, len(k8sPods))
 	}
 
-	if err := p.dontReattachQueuedPreAgentDisabledPods(pods, configMaps); err != nil {
+	if err := p.dontReattachQueuedPreAgentDisabledPods(pods, configMaps, pvcs); err != nil {
 		return nil, err
 	}
 
@@ -488,7 +530,7 @@ func (p *pods) reattachAllocationPods(msg reattachAllocationPods) ([]reattachPod
 		resp, err := p.reattachPod(msg.req, msg.allocationID, resourcePool, containerID,
 			k8sPods[i], ports[i], msg.slots, msg.logContext)
 		if err != nil {
-			p.deleteKubernetesResources(pods, configMaps)
+			p.deleteKubernetesResources(pods, configMaps, pvcs)
 			return nil, errors.Wrapf(err,
 				"error restoring pod with containerID %s", containerID)
 		}
@@ -499,7 +541,7 @@ func (p *pods) reattachAllocationPods(msg reattachAllocationPods) ([]reattachPod
 }
 
 func (p *pods) dontReattachQueuedPreAgentDisabledPods(
-	pods *k8sV1.PodList, configMaps *k8sV1.ConfigMapList,
+	pods *k8sV1.PodList, configMaps *k8sV1.ConfigMapList, pvcs *k8sV1.PersistentVolumeClaimList,
 ) error {
 	// This is needed to label pods created before Determined supported k8s agent enable disable.
 	// We will not reattach pods that are queued and don't have the affinity that respects
@@ -513,7 +555,7 @@ func (p *pods) dontReattachQueuedPreAgentDisabledPods(
 			addNodeDisabledAffinityToPodSpec(&pod, clusterIDNodeLabel())
 
 			if !reflect.DeepEqual(pod.Spec, before.Spec) {
-				p.deleteKubernetesResources(pods, configMaps)
+				p.deleteKubernetesResources(pods, configMaps, pvcs)
 				return fmt.Errorf(
 					"unable to restore pod %s since it was queued and does not have the needed "+
 						"Determined's affinity to prevent scheduling on disabled nodes. "+
@@ -569,6 +611,7 @@ func (p *pods) reattachPod(
 	newPodHandler.restore = true
 	newPodHandler.podName = pod.Name
 	newPodHandler.configMapName = pod.Name
+	newPodHandler.scratchPVCName = scratchClaimName(pod)
 	newPodHandler.ports = ports
 
 	state, err := newPodHandler.getPodState(pod, newPodHandler.containerNames)
@@ -620,7 +663,7 @@ func (p *pods) refreshPodStates(allocationID model.AllocationID) error {
 	}
 
 	for _, pod := range pods.Items {
-		if _, ok := p.namespaceToPoolName[pod.Namespace]; !ok {
+		if !p.isTaskNamespace(pod.Namespace) {
 			continue
 		}
 		pod := pod
@@ -630,15 +673,76 @@ func (p *pods) refreshPodStates(allocationID model.AllocationID) error {
 }
 
 func (p *pods) deleteKubernetesResources(
-	pods *k8sV1.PodList, configMaps *k8sV1.ConfigMapList,
+	pods *k8sV1.PodList, configMaps *k8sV1.ConfigMapList, pvcs *k8sV1.PersistentVolumeClaimList,
 ) {
 	for _, pod := range pods.Items {
-		p.resourceRequestQueue.deleteKubernetesResources(pod.Namespace, pod.Name, "")
+		p.resourceRequestQueue.deleteKubernetesResources(pod.Namespace, pod.Name, "", "")
 	}
 
 	for _, configMap := range configMaps.Items {
-		p.resourceRequestQueue.deleteKubernetesResources(configMap.Namespace, "", configMap.Name)
+		p.resourceRequestQueue.deleteKubernetesResources(configMap.Namespace, "", configMap.Name, "")
+	}
+
+	for _, pvc := range pvcs.Items {
+		// The claims of workspace volumes outlive the pods that mount them. They are deleted by
+		// deleteOrphanedWorkspaceVolumeClaims once their volume is.
+		if _, ok := pvc.Labels[workspaceVolumeLabel]; ok {
+			continue
+		}
+		p.resourceRequestQueue.deleteKubernetesResources(pvc.Namespace, "", "", pvc.Name)
+	}
+}
+
+func (p *pods) periodicallyDeleteOrphanedWorkspaceVolumeClaims(ctx context.Context) {
+	t := time.NewTicker(10 * time.Minute)
+	defer t.Stop()
+	for {
+		if err := p.deleteOrphanedWorkspaceVolumeClaims(ctx); err != nil {
+			p.syslog.WithError(err).Error("failed to delete claims of deleted workspace volumes")
+		}
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// deleteOrphanedWorkspaceVolumeClaims deletes the claims of workspace volumes that were deleted,
+// once no pod mounts them.
+func (p *pods) deleteOrphanedWorkspaceVolumeClaims(ctx context.Context) error {
+	var volumes []db.WorkspaceVolume
+	if err := db.Bun().NewSelect().Model(&volumes).Scan(ctx); err != nil {
+		return errors.Wrap(err, "error querying the database for workspace volumes")
+	}
+	declared := make(set.Set[string])
+	for _, v := range volumes {
+		declared.Insert(workspaceVolumeClaimName(v))
+	}
+
+	p.mu.RLock()
+	pvcInterfaces := maps.Clone(p.pvcInterfaces)
+	podInterfaces := maps.Clone(p.podInterfaces)
+	p.mu.RUnlock()
+	for namespace, pvcInterface := range pvcInterfaces {
+		pvcs, err := pvcInterface.List(ctx, metaV1.ListOptions{LabelSelector: workspaceVolumeLabel})
+		if err != nil {
+			return errors.Wrapf(err, "error listing persistent volume claims for namespace %s",
+				namespace)
+		}
+		if len(pvcs.Items) == 0 {
+			continue
+		}
+		pods, err := podInterfaces[namespace].List(ctx, metaV1.ListOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "error listing pods for namespace %s", namespace)
+		}
+		for _, name := range orphanedWorkspaceVolumeClaims(pvcs.Items, pods.Items, declared) {
+			p.syslog.Infof("deleting persistent volume claim %s of a deleted workspace volume", name)
+			p.resourceRequestQueue.deleteKubernetesResources(namespace, "", "", name)
+		}
 	}
+	return nil
 }
 
 func (p *pods) deleteDoomedKubernetesResources() error {
@@ -661,7 +765,7 @@ func (p *pods) deleteDoomedKubernetesResources() error {
 	toKillPods := &k8sV1.PodList{}
 	savedPodNames := make(set.Set[string])
 	for _, pod := range pods.Items {
-		if _, ok := p.namespaceToPoolName[pod.Namespace]; !ok {
+		if !p.isTaskNamespace(pod.Namespace) {
 			continue
 		}
 
@@ -698,7 +802,7 @@ func (p *pods) deleteDoomedKubernetesResources() error {
 	}
 	toKillConfigMaps := &k8sV1.ConfigMapList{}
 	for _, cm := range configMaps.Items {
-		if _, ok := p.namespaceToPoolName[cm.Namespace]; !ok {
+		if !p.isTaskNamespace(cm.Namespace) {
 			continue
 		}
 
@@ -711,18 +815,48 @@ func (p *pods) deleteDoomedKubernetesResources() error {
 		toKillConfigMaps.Items = append(toKillConfigMaps.Items, cm)
 	}
 
-	p.deleteKubernetesResources(toKillPods, toKillConfigMaps)
+	// Only scratch volume claims carry the determined label; the claims of workspace volumes are
+	// kept.
+	pvcs, err := p.listPVCsInAllNamespaces(context.TODO(), listOptions)
+	if err != nil {
+		return errors.Wrap(err, "error listing existing persistent volume claims")
+	}
+	toKillPVCs := &k8sV1.PersistentVolumeClaimList{}
+	for _, pvc := range pvcs.Items {
+		if !p.isTaskNamespace(pvc.Namespace) {
+			continue
+		}
+
+		if savedPodNames.Contains(pvc.Name) { // PodName is same as scratch volume claim name.
+			continue
+		}
+
+		p.syslog.Debugf("Deleting persistent volume claim '%s' did not find a matching pod that "+
+			"will be restored", pvc.Name)
+		toKillPVCs.Items = append(toKillPVCs.Items, pvc)
+	}
+
+	p.deleteKubernetesResources(toKillPods, toKillConfigMaps, toKillPVCs)
 	return nil
 }
 
+// informerNamespaces returns the namespaces whose pods and events are watched. Namespaces of
+// workspaces are created while the master runs, so all namespaces are watched if they are used.
+func (p *pods) informerNamespaces() []string {
+	if p.workspaceNamespacesConfig != nil {
+		return []string{metaV1.NamespaceAll}
+	}
+	return maps.Keys(p.namespaceToPoolName)
+}
+
 func (p *pods) startPodInformer() error {
-	for namespace := range p.namespaceToPoolName {
+	for _, namespace := range p.informerNamespaces() {
 		i, err := newPodInformer(
 			context.TODO(),
 			determinedLabel,
 			"pod",
 			namespace,
-			p.podInterfaces[namespace],
+			p.clientSet.CoreV1().Pods(namespace),
 			func(event watch.Event) {
 				p.mu.Lock()
 				defer p.mu.Unlock()
@@ -756,7 +890,7 @@ func (p *pods) startNodeInformer() error {
 }
 
 func (p *pods) startEventListeners() error {
-	for namespace := range p.namespaceToPoolName {
+	for _, namespace := range p.informerNamespaces() {
 		l, err := newEventInformer(
 			context.TODO(),
 			p.clientSet.CoreV1().Events(namespace),
@@ -775,7 +909,7 @@ func (p *pods) startEventListeners() error {
 }
 
 func (p *pods) startPreemptionListeners() error {
-	for namespace := range p.namespaceToPoolName {
+	for _, namespace := range p.informerNamespaces() {
 		l, err := newPodInformer(
 			context.TODO(),
 			determinedPreemptionLabel,
@@ -797,7 +931,9 @@ func (p *pods) startPreemptionListeners() error {
 
 func (p *pods) startResourceRequestQueue() {
 	failures := make(chan resourcesRequestFailure, 16)
-	p.resourceRequestQueue = startRequestQueue(p.podInterfaces, p.configMapInterfaces, failures)
+	p.resourceRequestQueue = startRequestQueue(
+		p.podInterfaces, p.configMapInterfaces, p.pvcInterfaces, p.networkPolicyInterfaces,
+		p.secretInterfaces, failures)
 	p.wg.Go(func(ctx context.Context) {
 		for {
 			select {
@@ -839,6 +975,18 @@ func (p *pods) handleResourceRequestFailure(msg resourcesRequestFailure) {
 }
 
 func (p *pods) receiveStartTaskPod(msg StartTaskPod) error {
+	var serviceAccountName string
+	var prepareNamespace *prepareWorkspaceNamespace
+	if p.workspaceNamespacesConfig != nil && msg.Spec.WorkspaceID != 0 {
+		namespace := p.workspaceNamespace(msg.Spec.WorkspaceID)
+		msg.Namespace = namespace.name
+		serviceAccountName = p.workspaceNamespacesConfig.ServiceAccountName
+		prepareNamespace = &prepareWorkspaceNamespace{
+			namespace: namespace,
+			gpuLimit:  p.workspaceGPULimits.limit(msg.Spec.WorkspaceID),
+		}
+	}
+
 	newPodHandler := newPod(
 		msg,
 		msg.Spec.ClusterID,
@@ -857,6 +1005,10 @@ func (p *pods) receiveStartTaskPod(msg StartTaskPod) error {
 		p.scheduler,
 	)
 
+	newPodHandler.serviceAccountName = serviceAccountName
+	newPodHandler.prepareNamespace = prepareNamespace
+	newPodHandler.masterPeer = p.masterPeer
+
 	if _, alreadyExists := p.podNameToPodHandler[newPodHandler.podName]; alreadyExists {
 		return errors.Errorf(
 			"attempting to register same pod name: %s multiple times", newPodHandler.podName)
@@ -1657,6 +1809,22 @@ func (p *pods) listConfigMapsInAllNamespaces(
 	return res, nil
 }
 
+func (p *pods) listPVCsInAllNamespaces(
+	ctx context.Context, opts metaV1.ListOptions,
+) (*k8sV1.PersistentVolumeClaimList, error) {
+	res := &k8sV1.PersistentVolumeClaimList{}
+	for n, i := range p.pvcInterfaces {
+		pvcs, err := i.List(ctx, opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error listing persistent volume claims for namespace %s", n)
+		}
+
+		res.Items = append(res.Items, pvcs.Items...)
+	}
+
+	return res, nil
+}
+
 func extractTCDs(resourcePoolConfigs []config.ResourcePoolConfig,
 ) map[string]*model.TaskContainerDefaultsConfig {
 	result := map[string]*model.TaskContainerDefaultsConfig{}
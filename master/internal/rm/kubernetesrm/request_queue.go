@@ -5,8 +5,11 @@ import (
 	"sync"
 
 	"github.com/sirupsen/logrus"
+	"golang.org/x/exp/maps"
 	k8sV1 "k8s.io/api/core/v1"
+	networkingV1 "k8s.io/api/networking/v1"
 	typedV1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	typedNetworkingV1 "k8s.io/client-go/kubernetes/typed/networking/v1"
 
 	"github.com/determined-ai/determined/master/pkg/set"
 )
@@ -21,12 +24,27 @@ type (
 	createKubernetesResources struct {
 		podSpec       *k8sV1.Pod
 		configMapSpec *k8sV1.ConfigMap
+		// scratchPVCSpec is the claim of the pod's scratch volume, which is deleted with the pod.
+		scratchPVCSpec *k8sV1.PersistentVolumeClaim
+		// workspacePVCSpecs are the claims of the shared volumes of the pod's workspace. They are
+		// only created if they don't exist yet and outlive the pod.
+		workspacePVCSpecs []*k8sV1.PersistentVolumeClaim
+		// networkPolicySpec restricts the egress of the pod, if its task has an egress rule. It is
+		// owned by the configMap, so that it is deleted with it.
+		networkPolicySpec *networkingV1.NetworkPolicy
+		// secretSpec holds the values of the secrets of the pod, if it references any. Like the
+		// networkPolicy, it is owned by the configMap.
+		secretSpec *k8sV1.Secret
+		// prepareNamespace is set if the pod runs in the namespace of its workspace, which is
+		// created before the pod's resources if it doesn't exist yet.
+		prepareNamespace *prepareWorkspaceNamespace
 	}
 
 	deleteKubernetesResources struct {
 		namespace     string
 		podName       string
 		configMapName string
+		pvcName       string
 	}
 )
 
@@ -101,9 +119,8 @@ type queuedResourceRequest struct {
 //     requestProcessingWorkers notify the requestQueue that they are available to receive work
 //     by sending a `workerAvailable` message.
 type requestQueue struct {
-	podInterfaces       map[string]typedV1.PodInterface
-	configMapInterfaces map[string]typedV1.ConfigMapInterface
-	failures            chan<- resourcesRequestFailure
+	interfaces *namespacedInterfaces
+	failures   chan<- resourcesRequestFailure
 
 	mu         sync.Mutex
 	workerChan chan interface{}
@@ -119,15 +136,65 @@ type requestQueue struct {
 
 type requestID string
 
+// namespacedInterfaces are the clients the workers use in each namespace. Namespaces are added
+// while the workers run when workspaces get namespaces of their own.
+type namespacedInterfaces struct {
+	mu              sync.RWMutex
+	pods            map[string]typedV1.PodInterface
+	configMaps      map[string]typedV1.ConfigMapInterface
+	pvcs            map[string]typedV1.PersistentVolumeClaimInterface
+	networkPolicies map[string]typedNetworkingV1.NetworkPolicyInterface
+	secrets         map[string]typedV1.SecretInterface
+}
+
+func (n *namespacedInterfaces) get(namespace string) (
+	typedV1.PodInterface,
+	typedV1.ConfigMapInterface,
+	typedV1.PersistentVolumeClaimInterface,
+	typedNetworkingV1.NetworkPolicyInterface,
+	typedV1.SecretInterface,
+) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.pods[namespace], n.configMaps[namespace], n.pvcs[namespace],
+		n.networkPolicies[namespace], n.secrets[namespace]
+}
+
+// addNamespace lets the workers create and delete resources in a namespace.
+func (r *requestQueue) addNamespace(
+	namespace string,
+	podInterface typedV1.PodInterface,
+	configMapInterface typedV1.ConfigMapInterface,
+	pvcInterface typedV1.PersistentVolumeClaimInterface,
+	networkPolicyInterface typedNetworkingV1.NetworkPolicyInterface,
+	secretInterface typedV1.SecretInterface,
+) {
+	r.interfaces.mu.Lock()
+	defer r.interfaces.mu.Unlock()
+	r.interfaces.pods[namespace] = podInterface
+	r.interfaces.configMaps[namespace] = configMapInterface
+	r.interfaces.pvcs[namespace] = pvcInterface
+	r.interfaces.networkPolicies[namespace] = networkPolicyInterface
+	r.interfaces.secrets[namespace] = secretInterface
+}
+
 func startRequestQueue(
 	podInterfaces map[string]typedV1.PodInterface,
 	configMapInterfaces map[string]typedV1.ConfigMapInterface,
+	pvcInterfaces map[string]typedV1.PersistentVolumeClaimInterface,
+	networkPolicyInterfaces map[string]typedNetworkingV1.NetworkPolicyInterface,
+	secretInterfaces map[string]typedV1.SecretInterface,
 	failures chan<- resourcesRequestFailure,
 ) *requestQueue {
 	r := &requestQueue{
-		podInterfaces:       podInterfaces,
-		configMapInterfaces: configMapInterfaces,
-		failures:            failures,
+		interfaces: &namespacedInterfaces{
+			pods:            maps.Clone(podInterfaces),
+			configMaps:      maps.Clone(configMapInterfaces),
+			pvcs:            maps.Clone(pvcInterfaces),
+			networkPolicies: maps.Clone(networkPolicyInterfaces),
+			secrets:         maps.Clone(secretInterfaces),
+		},
+		failures: failures,
 
 		workerChan: make(chan interface{}),
 
@@ -146,8 +213,7 @@ func startRequestQueue(
 func (r *requestQueue) startWorkers() {
 	for i := 0; i < numKubernetesWorkers; i++ {
 		startRequestProcessingWorker(
-			r.podInterfaces,
-			r.configMapInterfaces,
+			r.interfaces,
 			strconv.Itoa(i),
 			r.workerChan,
 			r.workerReady,
@@ -173,17 +239,28 @@ func keyForDelete(msg deleteKubernetesResources) requestID {
 	if msg.configMapName != "" {
 		return requestID(msg.namespace + "/" + msg.configMapName)
 	}
+	if msg.pvcName != "" {
+		return requestID(msg.namespace + "/" + msg.pvcName)
+	}
 	panic("invalid deleteKubernetesResources message")
 }
 
 func (r *requestQueue) createKubernetesResources(
 	podSpec *k8sV1.Pod,
 	configMapSpec *k8sV1.ConfigMap,
+	scratchPVCSpec *k8sV1.PersistentVolumeClaim,
+	workspacePVCSpecs []*k8sV1.PersistentVolumeClaim,
+	networkPolicySpec *networkingV1.NetworkPolicy,
+	secretSpec *k8sV1.Secret,
+	prepareNamespace *prepareWorkspaceNamespace,
 ) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	msg := createKubernetesResources{podSpec, configMapSpec}
+	msg := createKubernetesResources{
+		podSpec, configMapSpec, scratchPVCSpec, workspacePVCSpecs, networkPolicySpec, secretSpec,
+		prepareNamespace,
+	}
 	ref := keyForCreate(msg)
 
 	if _, requestAlreadyExists := r.pendingResourceCreations[ref]; requestAlreadyExists {
@@ -205,11 +282,12 @@ func (r *requestQueue) deleteKubernetesResources(
 	namespace string,
 	podName string,
 	configMapName string,
+	pvcName string,
 ) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	msg := deleteKubernetesResources{namespace, podName, configMapName}
+	msg := deleteKubernetesResources{namespace, podName, configMapName, pvcName}
 	ref := keyForDelete(msg)
 
 	// If the request has not been processed yet, cancel it and inform the handler.
@@ -12,8 +12,11 @@ import (
 	"gotest.tools/assert"
 
 	k8sV1 "k8s.io/api/core/v1"
+	networkingV1 "k8s.io/api/networking/v1"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 	typedV1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	typedNetworkingV1 "k8s.io/client-go/kubernetes/typed/networking/v1"
 )
 
 type mockPod struct {
@@ -91,11 +94,11 @@ func (m *mockPod) create() {
 		Name:      m.name,
 		Namespace: "default",
 	}}
-	m.requestQueue.createKubernetesResources(&podSpec, &cmSpec)
+	m.requestQueue.createKubernetesResources(&podSpec, &cmSpec, nil, nil, nil, nil, nil)
 }
 
 func (m *mockPod) delete() {
-	m.requestQueue.deleteKubernetesResources("default", m.name, m.name)
+	m.requestQueue.deleteKubernetesResources("default", m.name, m.name, "")
 }
 
 func getNumberOfActivePods(podInterface typedV1.PodInterface) int {
@@ -139,6 +142,9 @@ func TestRequestQueueCreatingManyPod(t *testing.T) {
 	k8sRequestQueue := startRequestQueue(
 		map[string]typedV1.PodInterface{"default": podInterface},
 		map[string]typedV1.ConfigMapInterface{"default": configMapInterface},
+		map[string]typedV1.PersistentVolumeClaimInterface{"default": newMockPVCInterface()},
+		map[string]typedNetworkingV1.NetworkPolicyInterface{},
+		map[string]typedV1.SecretInterface{},
 		failures,
 	)
 
@@ -163,6 +169,9 @@ func TestRequestQueueCreatingAndDeletingManyPod(t *testing.T) {
 	k8sRequestQueue := startRequestQueue(
 		map[string]typedV1.PodInterface{"default": podInterface},
 		map[string]typedV1.ConfigMapInterface{"default": configMapInterface},
+		map[string]typedV1.PersistentVolumeClaimInterface{"default": newMockPVCInterface()},
+		map[string]typedNetworkingV1.NetworkPolicyInterface{},
+		map[string]typedV1.SecretInterface{},
 		failures,
 	)
 
@@ -189,6 +198,9 @@ func TestRequestQueueCreatingThenDeletingManyPods(t *testing.T) {
 	k8sRequestQueue := startRequestQueue(
 		map[string]typedV1.PodInterface{"default": podInterface},
 		map[string]typedV1.ConfigMapInterface{"default": configMapInterface},
+		map[string]typedV1.PersistentVolumeClaimInterface{"default": newMockPVCInterface()},
+		map[string]typedNetworkingV1.NetworkPolicyInterface{},
+		map[string]typedV1.SecretInterface{},
 		failures,
 	)
 
@@ -222,6 +234,9 @@ func TestRequestQueueCreatingAndDeletingManyPodWithDelay(t *testing.T) {
 	k8sRequestQueue := startRequestQueue(
 		map[string]typedV1.PodInterface{"default": podInterface},
 		map[string]typedV1.ConfigMapInterface{"default": configMapInterface},
+		map[string]typedV1.PersistentVolumeClaimInterface{"default": newMockPVCInterface()},
+		map[string]typedNetworkingV1.NetworkPolicyInterface{},
+		map[string]typedV1.SecretInterface{},
 		failures,
 	)
 
@@ -251,6 +266,9 @@ func TestRequestQueueCreationCancelled(t *testing.T) {
 	k8sRequestQueue := startRequestQueue(
 		map[string]typedV1.PodInterface{"default": podInterface},
 		map[string]typedV1.ConfigMapInterface{"default": configMapInterface},
+		map[string]typedV1.PersistentVolumeClaimInterface{"default": newMockPVCInterface()},
+		map[string]typedNetworkingV1.NetworkPolicyInterface{},
+		map[string]typedV1.SecretInterface{},
 		failures,
 	)
 
@@ -290,6 +308,9 @@ func TestRequestQueueCreationFailed(t *testing.T) {
 	k8sRequestQueue := startRequestQueue(
 		map[string]typedV1.PodInterface{"default": podInterface},
 		map[string]typedV1.ConfigMapInterface{"default": configMapInterface},
+		map[string]typedV1.PersistentVolumeClaimInterface{"default": newMockPVCInterface()},
+		map[string]typedNetworkingV1.NetworkPolicyInterface{},
+		map[string]typedV1.SecretInterface{},
 		failures,
 	)
 
@@ -326,6 +347,9 @@ func TestRequestQueueDeletionFailed(t *testing.T) {
 	k8sRequestQueue := startRequestQueue(
 		map[string]typedV1.PodInterface{"default": podInterface},
 		map[string]typedV1.ConfigMapInterface{"default": configMapInterface},
+		map[string]typedV1.PersistentVolumeClaimInterface{"default": newMockPVCInterface()},
+		map[string]typedNetworkingV1.NetworkPolicyInterface{},
+		map[string]typedV1.SecretInterface{},
 		failures,
 	)
 
@@ -357,3 +381,113 @@ func TestRequestQueueDeletionFailed(t *testing.T) {
 	wg.Wait()
 	assert.Equal(t, deleteFailed, true)
 }
+
+func TestRequestQueuePersistentVolumeClaims(t *testing.T) {
+	podInterface := &mockPodInterface{pods: make(map[string]*k8sV1.Pod)}
+	configMapInterface := &mockConfigMapInterface{configMaps: make(map[string]*k8sV1.ConfigMap)}
+	pvcInterface := newMockPVCInterface()
+
+	failures := make(chan resourcesRequestFailure, 64)
+	k8sRequestQueue := startRequestQueue(
+		map[string]typedV1.PodInterface{"default": podInterface},
+		map[string]typedV1.ConfigMapInterface{"default": configMapInterface},
+		map[string]typedV1.PersistentVolumeClaimInterface{"default": pvcInterface},
+		map[string]typedNetworkingV1.NetworkPolicyInterface{},
+		map[string]typedV1.SecretInterface{},
+		failures,
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go runDefaultErrorHandler(ctx, failures)
+
+	workspacePVC := &k8sV1.PersistentVolumeClaim{
+		ObjectMeta: metaV1.ObjectMeta{Name: "det-ws-1-data", Namespace: "default"},
+	}
+	for _, name := range []string{"pod-a", "pod-b"} {
+		meta := metaV1.ObjectMeta{Name: name, Namespace: "default"}
+		k8sRequestQueue.createKubernetesResources(
+			&k8sV1.Pod{ObjectMeta: meta},
+			&k8sV1.ConfigMap{ObjectMeta: meta},
+			&k8sV1.PersistentVolumeClaim{ObjectMeta: meta},
+			[]*k8sV1.PersistentVolumeClaim{workspacePVC},
+			nil,
+			nil,
+			nil,
+		)
+	}
+	waitForPendingRequestToFinish(k8sRequestQueue)
+	// The second pod must still be created although its workspace claim already exists.
+	assert.Equal(t, getNumberOfActivePods(podInterface), 2)
+	assert.Assert(t, pvcInterface.hasPVC("pod-a"))
+	assert.Assert(t, pvcInterface.hasPVC("pod-b"))
+	assert.Assert(t, pvcInterface.hasPVC("det-ws-1-data"))
+
+	// A pod isn't created if the existing workspace claim doesn't match its volume.
+	mismatched := workspacePVC.DeepCopy()
+	mismatched.Spec.AccessModes = []k8sV1.PersistentVolumeAccessMode{k8sV1.ReadWriteOnce}
+	meta := metaV1.ObjectMeta{Name: "pod-c", Namespace: "default"}
+	k8sRequestQueue.createKubernetesResources(
+		&k8sV1.Pod{ObjectMeta: meta},
+		&k8sV1.ConfigMap{ObjectMeta: meta},
+		nil,
+		[]*k8sV1.PersistentVolumeClaim{mismatched},
+		nil,
+		nil,
+		nil,
+	)
+	waitForPendingRequestToFinish(k8sRequestQueue)
+	assert.Equal(t, getNumberOfActivePods(podInterface), 2)
+
+	k8sRequestQueue.deleteKubernetesResources("default", "pod-a", "pod-a", "pod-a")
+	waitForPendingRequestToFinish(k8sRequestQueue)
+	assert.Equal(t, getNumberOfActivePods(podInterface), 1)
+	assert.Assert(t, !pvcInterface.hasPVC("pod-a"))
+	assert.Assert(t, pvcInterface.hasPVC("pod-b"))
+	assert.Assert(t, pvcInterface.hasPVC("det-ws-1-data"))
+}
+
+func TestRequestQueueOwnedResources(t *testing.T) {
+	podInterface := &mockPodInterface{pods: make(map[string]*k8sV1.Pod)}
+	configMapInterface := &mockConfigMapInterface{configMaps: make(map[string]*k8sV1.ConfigMap)}
+	clientSet := fake.NewSimpleClientset()
+	networkPolicyInterface := clientSet.NetworkingV1().NetworkPolicies("default")
+	secretInterface := clientSet.CoreV1().Secrets("default")
+
+	failures := make(chan resourcesRequestFailure, 64)
+	k8sRequestQueue := startRequestQueue(
+		map[string]typedV1.PodInterface{"default": podInterface},
+		map[string]typedV1.ConfigMapInterface{"default": configMapInterface},
+		map[string]typedV1.PersistentVolumeClaimInterface{"default": newMockPVCInterface()},
+		map[string]typedNetworkingV1.NetworkPolicyInterface{"default": networkPolicyInterface},
+		map[string]typedV1.SecretInterface{"default": secretInterface},
+		failures,
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go runDefaultErrorHandler(ctx, failures)
+
+	meta := metaV1.ObjectMeta{Name: "pod-a", Namespace: "default"}
+	k8sRequestQueue.createKubernetesResources(
+		&k8sV1.Pod{ObjectMeta: meta},
+		&k8sV1.ConfigMap{ObjectMeta: meta},
+		nil,
+		nil,
+		&networkingV1.NetworkPolicy{ObjectMeta: meta},
+		&k8sV1.Secret{ObjectMeta: meta},
+		nil,
+	)
+	waitForPendingRequestToFinish(k8sRequestQueue)
+	assert.Equal(t, getNumberOfActivePods(podInterface), 1)
+
+	// The policy and the secret are deleted with the configMap that owns them.
+	policy, err := networkPolicyInterface.Get(context.Background(), "pod-a", metaV1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(policy.OwnerReferences), 1)
+	assert.Equal(t, policy.OwnerReferences[0].Kind, "ConfigMap")
+	assert.Equal(t, policy.OwnerReferences[0].Name, "pod-a")
+
+	secret, err := secretInterface.Get(context.Background(), "pod-a", metaV1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(secret.OwnerReferences), 1)
+	assert.Equal(t, secret.OwnerReferences[0].Name, "pod-a")
+}
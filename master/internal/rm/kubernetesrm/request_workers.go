@@ -6,22 +6,20 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	k8error "k8s.io/apimachinery/pkg/api/errors"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	typedV1 "k8s.io/client-go/kubernetes/typed/core/v1"
 )
 
 type requestProcessingWorker struct {
-	podInterfaces       map[string]typedV1.PodInterface
-	configMapInterfaces map[string]typedV1.ConfigMapInterface
-	failures            chan<- resourcesRequestFailure
-	syslog              *logrus.Entry
+	interfaces *namespacedInterfaces
+	failures   chan<- resourcesRequestFailure
+	syslog     *logrus.Entry
 }
 
 type readyCallbackFunc func(createRef requestID)
 
 func startRequestProcessingWorker(
-	podInterfaces map[string]typedV1.PodInterface,
-	configMapInterfaces map[string]typedV1.ConfigMapInterface,
+	interfaces *namespacedInterfaces,
 	id string,
 	in <-chan interface{},
 	ready readyCallbackFunc,
@@ -29,10 +27,9 @@ func startRequestProcessingWorker(
 ) *requestProcessingWorker {
 	syslog := logrus.New().WithField("component", "kubernetesrm-worker").WithField("id", id)
 	r := &requestProcessingWorker{
-		podInterfaces:       podInterfaces,
-		configMapInterfaces: configMapInterfaces,
-		failures:            failures,
-		syslog:              syslog,
+		interfaces: interfaces,
+		failures:   failures,
+		syslog:     syslog,
 	}
 	go r.receive(in, ready)
 	return r
@@ -59,8 +56,56 @@ func (r *requestProcessingWorker) receive(in <-chan interface{}, ready readyCall
 func (r *requestProcessingWorker) receiveCreateKubernetesResources(
 	msg createKubernetesResources,
 ) {
+	if msg.prepareNamespace != nil {
+		err := msg.prepareNamespace.namespace.prepare(context.TODO(), msg.prepareNamespace.gpuLimit)
+		if err != nil {
+			r.syslog.WithError(err).Errorf(
+				"error preparing namespace %s", msg.prepareNamespace.namespace.name)
+			r.failures <- resourceCreationFailed{podName: msg.podSpec.Name, err: err}
+			return
+		}
+	}
+
+	podInterface, configMapInterface, pvcInterface, networkPolicyInterface, secretInterface :=
+		r.interfaces.get(msg.podSpec.Namespace)
+	for _, pvcSpec := range msg.workspacePVCSpecs {
+		pvc, err := pvcInterface.Create(
+			context.TODO(), pvcSpec, metaV1.CreateOptions{})
+		if k8error.IsAlreadyExists(err) {
+			existing, err := pvcInterface.Get(context.TODO(), pvcSpec.Name, metaV1.GetOptions{})
+			if err == nil {
+				err = workspaceClaimMismatch(existing, pvcSpec)
+			}
+			if err != nil {
+				r.syslog.WithError(err).Errorf("checking persistentVolumeClaim %s", pvcSpec.Name)
+				r.failures <- resourceCreationFailed{podName: msg.podSpec.Name, err: err}
+				return
+			}
+			continue
+		}
+		if err != nil {
+			r.syslog.WithError(err).Errorf("error creating persistentVolumeClaim %s", pvcSpec.Name)
+			r.failures <- resourceCreationFailed{podName: msg.podSpec.Name, err: err}
+			return
+		}
+		r.syslog.Infof("created persistentVolumeClaim %s", pvc.Name)
+	}
+
+	if msg.scratchPVCSpec != nil {
+		r.syslog.Debugf("creating persistentVolumeClaim with spec %v", msg.scratchPVCSpec)
+		pvc, err := pvcInterface.Create(
+			context.TODO(), msg.scratchPVCSpec, metaV1.CreateOptions{})
+		if err != nil {
+			r.syslog.WithError(err).Errorf(
+				"error creating persistentVolumeClaim %s", msg.scratchPVCSpec.Name)
+			r.failures <- resourceCreationFailed{podName: msg.podSpec.Name, err: err}
+			return
+		}
+		r.syslog.Infof("created persistentVolumeClaim %s", pvc.Name)
+	}
+
 	r.syslog.Debugf("creating configMap with spec %v", msg.configMapSpec)
-	configMap, err := r.configMapInterfaces[msg.podSpec.Namespace].Create(
+	configMap, err := configMapInterface.Create(
 		context.TODO(), msg.configMapSpec, metaV1.CreateOptions{})
 	if err != nil {
 		r.syslog.WithError(err).Errorf("error creating configMap %s", msg.configMapSpec.Name)
@@ -68,9 +113,41 @@ func (r *requestProcessingWorker) receiveCreateKubernetesResources(
 		return
 	}
 	r.syslog.Infof("created configMap %s", configMap.Name)
+	owner := []metaV1.OwnerReference{{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Name:       configMap.Name,
+		UID:        configMap.UID,
+	}}
+
+	if msg.secretSpec != nil {
+		msg.secretSpec.OwnerReferences = owner
+		secret, err := secretInterface.Create(context.TODO(), msg.secretSpec, metaV1.CreateOptions{})
+		if err != nil {
+			r.syslog.WithError(err).Errorf("error creating secret %s", msg.secretSpec.Name)
+			r.failures <- resourceCreationFailed{podName: msg.podSpec.Name, err: err}
+			return
+		}
+		r.syslog.Infof("created secret %s", secret.Name)
+	}
+
+	// The pod is only created once its egress is restricted.
+	if msg.networkPolicySpec != nil {
+		msg.networkPolicySpec.OwnerReferences = owner
+		r.syslog.Debugf("creating networkPolicy with spec %v", msg.networkPolicySpec)
+		networkPolicy, err := networkPolicyInterface.Create(
+			context.TODO(), msg.networkPolicySpec, metaV1.CreateOptions{})
+		if err != nil {
+			r.syslog.WithError(err).Errorf(
+				"error creating networkPolicy %s", msg.networkPolicySpec.Name)
+			r.failures <- resourceCreationFailed{podName: msg.podSpec.Name, err: err}
+			return
+		}
+		r.syslog.Infof("created networkPolicy %s", networkPolicy.Name)
+	}
 
 	r.syslog.Debugf("launching pod with spec %v", msg.podSpec)
-	pod, err := r.podInterfaces[msg.podSpec.Namespace].Create(
+	pod, err := podInterface.Create(
 		context.TODO(), msg.podSpec, metaV1.CreateOptions{},
 	)
 	if err != nil {
@@ -86,11 +163,12 @@ func (r *requestProcessingWorker) receiveDeleteKubernetesResources(
 ) {
 	var gracePeriod int64 = deletionGracePeriod
 	var err error
+	podInterface, configMapInterface, pvcInterface, _, _ := r.interfaces.get(msg.namespace)
 
 	// If resource creation failed, we will still try to delete those resources which
 	// will also result in a failure.
 	if len(msg.podName) > 0 {
-		err = r.podInterfaces[msg.namespace].Delete(
+		err = podInterface.Delete(
 			context.TODO(), msg.podName, metaV1.DeleteOptions{GracePeriodSeconds: &gracePeriod})
 		if err != nil {
 			r.syslog.WithError(err).Errorf("failed to delete pod %s", msg.podName)
@@ -100,7 +178,7 @@ func (r *requestProcessingWorker) receiveDeleteKubernetesResources(
 	}
 
 	if len(msg.configMapName) > 0 {
-		errDeletingConfigMap := r.configMapInterfaces[msg.namespace].Delete(
+		errDeletingConfigMap := configMapInterface.Delete(
 			context.TODO(), msg.configMapName,
 			metaV1.DeleteOptions{GracePeriodSeconds: &gracePeriod})
 		if errDeletingConfigMap != nil {
@@ -111,6 +189,18 @@ func (r *requestProcessingWorker) receiveDeleteKubernetesResources(
 		}
 	}
 
+	if len(msg.pvcName) > 0 {
+		errDeletingPVC := pvcInterface.Delete(
+			context.TODO(), msg.pvcName, metaV1.DeleteOptions{})
+		if errDeletingPVC != nil {
+			r.syslog.WithError(errDeletingPVC).Errorf(
+				"failed to delete persistentVolumeClaim %s", msg.pvcName)
+			err = errDeletingPVC
+		} else {
+			r.syslog.Infof("deleted persistentVolumeClaim %s", msg.pvcName)
+		}
+	}
+
 	// It is possible that the creator of the message is no longer around.
 	// However this should have no impact on correctness.
 	if err != nil {
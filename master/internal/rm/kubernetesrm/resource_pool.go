@@ -1,7 +1,9 @@
 package kubernetesrm
 
 import (
+	"context"
 	"fmt"
+	"maps"
 	"sync"
 
 	"github.com/google/uuid"
@@ -47,6 +49,10 @@ type kubernetesResourcePool struct {
 	allocationIDToJobID       map[model.AllocationID]model.JobID
 	slotsUsedPerGroup         map[*tasklist.Group]int
 	allocationIDToRunningPods map[model.AllocationID]int
+	// workspaceMaxSlots caps the slots the tasks of a workspace may use at once, from the
+	// bindings of the workspaces to the pool.
+	workspaceMaxSlots     map[int]int
+	slotsUsedPerWorkspace map[int]int
 
 	podsService *pods
 
@@ -75,6 +81,8 @@ func newResourcePool(
 		allocationIDToJobID:       map[model.AllocationID]model.JobID{},
 		slotsUsedPerGroup:         map[*tasklist.Group]int{},
 		allocationIDToRunningPods: map[model.AllocationID]int{},
+		workspaceMaxSlots:         map[int]int{},
+		slotsUsedPerWorkspace:     map[int]int{},
 		podsService:               podsService,
 		queuePositions:            tasklist.InitializeJobSortState(true),
 		db:                        db,
@@ -90,6 +98,32 @@ func (k *kubernetesResourcePool) SetGroupMaxSlots(msg sproto.SetGroupMaxSlots) {
 	k.getOrCreateGroup(msg.JobID).MaxSlots = msg.MaxSlots
 }
 
+// loadWorkspaceMaxSlots caps the workspaces of a new pool by the max slots of their bindings to
+// it. Later changes to the bindings are pushed through SetWorkspaceMaxSlots.
+func loadWorkspaceMaxSlots(k *kubernetesResourcePool) error {
+	maxSlots, err := db.GetRPWorkspaceMaxSlots(context.TODO(), k.poolConfig.PoolName)
+	if err != nil {
+		return fmt.Errorf("loading the workspace max slots of pool %s: %w", k.poolConfig.PoolName, err)
+	}
+	k.SetWorkspaceMaxSlots(sproto.SetWorkspaceMaxSlots{
+		ResourcePool: k.poolConfig.PoolName,
+		MaxSlots:     maxSlots,
+	})
+	return nil
+}
+
+// SetWorkspaceMaxSlots replaces the caps on the slots the tasks of each workspace may use at once.
+func (k *kubernetesResourcePool) SetWorkspaceMaxSlots(msg sproto.SetWorkspaceMaxSlots) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.reschedule = true
+
+	k.workspaceMaxSlots = maps.Clone(msg.MaxSlots)
+	if k.workspaceMaxSlots == nil {
+		k.workspaceMaxSlots = map[int]int{}
+	}
+}
+
 func (k *kubernetesResourcePool) SetAllocationName(msg sproto.SetAllocationName) {
 	k.mu.Lock()
 	defer k.mu.Unlock()
@@ -537,6 +571,7 @@ func (k *kubernetesResourcePool) assignResources(
 
 	assigned := sproto.ResourcesAllocated{ID: req.AllocationID, Resources: allocations}
 	k.reqList.AddAllocationRaw(req.AllocationID, &assigned)
+	k.slotsUsedPerWorkspace[req.WorkspaceID] += req.SlotsNeeded
 	rmevents.Publish(req.AllocationID, assigned.Clone())
 
 	if req.Restore {
@@ -627,10 +662,14 @@ func (k *kubernetesResourcePool) resourcesReleased(
 	}
 
 	k.syslog.Infof("resources are released for %s", msg.AllocationID)
+	k.podsService.workspaceGPULimits.release(msg.AllocationID)
 	group := k.groups[req.JobID]
 	if group != nil {
 		k.slotsUsedPerGroup[group] -= req.SlotsNeeded
 	}
+	if k.reqList.IsScheduled(msg.AllocationID) {
+		k.slotsUsedPerWorkspace[req.WorkspaceID] -= req.SlotsNeeded
+	}
 
 	k.reqList.RemoveTaskByID(msg.AllocationID)
 	delete(k.allocationIDToContainerID, msg.AllocationID)
@@ -675,11 +714,28 @@ func (k *kubernetesResourcePool) schedulePendingTasks() {
 					continue
 				}
 			}
+			if maxSlots, ok := k.workspaceMaxSlots[req.WorkspaceID]; ok {
+				if k.slotsUsedPerWorkspace[req.WorkspaceID]+req.SlotsNeeded > maxSlots {
+					continue
+				}
+			}
+			if !k.reserveWorkspaceGPUs(req) {
+				continue
+			}
 			k.assignResources(req)
 		}
 	}
 }
 
+// reserveWorkspaceGPUs returns whether the GPUs of a task fit under the GPU limit of its
+// workspace, and if so, counts them against it until the task's resources are released.
+func (k *kubernetesResourcePool) reserveWorkspaceGPUs(req *sproto.AllocateRequest) bool {
+	if k.podsService.slotType != device.CUDA || req.WorkspaceID == 0 || req.SlotsNeeded == 0 {
+		return true
+	}
+	return k.podsService.workspaceGPULimits.reserve(req)
+}
+
 type k8sPodResources struct {
 	req             *sproto.AllocateRequest
 	podsService     *pods
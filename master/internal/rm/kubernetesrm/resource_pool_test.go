@@ -0,0 +1,58 @@
+package kubernetesrm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/internal/sproto"
+	"github.com/determined-ai/determined/master/pkg/device"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func TestWorkspaceMaxSlots(t *testing.T) {
+	podsService := &pods{slotType: device.CPU, workspaceGPULimits: newWorkspaceGPULimits()}
+	rp := newResourcePool(4, &config.ResourcePoolConfig{PoolName: "default"}, podsService, nil)
+	rp.SetWorkspaceMaxSlots(sproto.SetWorkspaceMaxSlots{
+		ResourcePool: "default",
+		MaxSlots:     map[int]int{1: 4},
+	})
+
+	// Each task is its own job, so only the workspace cap holds back the third task of workspace 1.
+	var allocationIDs []model.AllocationID
+	start := time.Now()
+	for i, task := range []struct{ workspaceID, slots int }{{1, 2}, {1, 2}, {1, 2}, {2, 4}} {
+		allocationID := model.AllocationID(model.NewTaskID())
+		rp.addTask(sproto.AllocateRequest{
+			AllocationID: allocationID,
+			JobID:        model.NewJobID(),
+			RequestTime:  start.Add(time.Duration(i) * time.Second),
+			WorkspaceID:  task.workspaceID,
+			SlotsNeeded:  task.slots,
+		})
+		allocationIDs = append(allocationIDs, allocationID)
+	}
+	rp.schedulePendingTasks()
+	for i, scheduled := range []bool{true, true, false, true} {
+		require.Equal(t, scheduled, rp.reqList.IsScheduled(allocationIDs[i]), "task %d", i)
+	}
+
+	rp.resourcesReleased(sproto.ResourcesReleased{AllocationID: allocationIDs[0]})
+	rp.schedulePendingTasks()
+	require.True(t, rp.reqList.IsScheduled(allocationIDs[2]))
+	require.Equal(t, 4, rp.slotsUsedPerWorkspace[1])
+
+	// Lifting the cap lets the workspace use more slots.
+	rp.SetWorkspaceMaxSlots(sproto.SetWorkspaceMaxSlots{ResourcePool: "default"})
+	allocationID := model.AllocationID(model.NewTaskID())
+	rp.addTask(sproto.AllocateRequest{
+		AllocationID: allocationID,
+		JobID:        model.NewJobID(),
+		WorkspaceID:  1,
+		SlotsNeeded:  2,
+	})
+	rp.schedulePendingTasks()
+	require.True(t, rp.reqList.IsScheduled(allocationID))
+}
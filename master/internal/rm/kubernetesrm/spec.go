@@ -16,6 +16,7 @@ import (
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/determined-ai/determined/master/internal/db"
 	"github.com/determined-ai/determined/master/internal/sproto"
 	"github.com/determined-ai/determined/master/pkg/archive"
 	"github.com/determined-ai/determined/master/pkg/cproto"
@@ -111,8 +112,22 @@ func (p *pod) configureEnvVars(
 		envVarsMap["NVIDIA_VISIBLE_DEVICES"] = "void"
 	}
 
+	var secretEnvVars map[string]string
+	if p.submissionInfo != nil {
+		secretEnvVars = p.submissionInfo.taskSpec.SecretEnvVars
+	}
 	envVars := make([]k8sV1.EnvVar, 0, len(envVarsMap))
 	for envVarKey, envVarValue := range envVarsMap {
+		if v, ok := secretEnvVars[envVarKey]; ok && v == envVarValue {
+			envVars = append(envVars, k8sV1.EnvVar{
+				Name: envVarKey,
+				ValueFrom: &k8sV1.EnvVarSource{SecretKeyRef: &k8sV1.SecretKeySelector{
+					LocalObjectReference: k8sV1.LocalObjectReference{Name: p.configMapName},
+					Key:                  secretEnvVarKey(envVarKey),
+				}},
+			})
+			continue
+		}
 		envVars = append(envVars, k8sV1.EnvVar{Name: envVarKey, Value: envVarValue})
 	}
 	envVars = append(envVars, k8sV1.EnvVar{
@@ -151,6 +166,43 @@ func (p *pod) configureConfigMapSpec(
 	}, nil
 }
 
+// secretEnvVarKey is the key of the value of an environment variable in the Secret of a pod. Keys
+// of Secrets are more restricted than names of environment variables.
+func secretEnvVarKey(name string) string {
+	return fmt.Sprintf("env-%x", name)
+}
+
+// configureSecretSpec returns the Secret that holds the values of the secrets the task references,
+// or nil if it references none. It has a key per environment variable, and the archives of the
+// files of secrets are numbered after the firstArchive other archives of the pod.
+func (p *pod) configureSecretSpec(
+	secretArchives []cproto.RunArchive, firstArchive int,
+) (*k8sV1.Secret, error) {
+	spec := p.submissionInfo.taskSpec
+	if len(spec.SecretEnvVars) == 0 && len(secretArchives) == 0 {
+		return nil, nil
+	}
+	data := make(map[string][]byte, len(spec.SecretEnvVars)+len(secretArchives))
+	for k, v := range spec.SecretEnvVars {
+		data[secretEnvVarKey(k)] = []byte(v)
+	}
+	for idx, runArchive := range secretArchives {
+		zippedArchive, err := archive.ToTarGz(runArchive.Archive)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to zip archive")
+		}
+		data[fmt.Sprintf("%d.tar.gz", firstArchive+idx)] = zippedArchive
+	}
+	return &k8sV1.Secret{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      p.configMapName,
+			Namespace: p.namespace,
+			Labels:    map[string]string{determinedLabel: spec.AllocationID},
+		},
+		Data: data,
+	}, nil
+}
+
 func (p *pod) configureVolumes(
 	dockerMounts []mount.Mount,
 	runArchives []cproto.RunArchive,
@@ -170,9 +222,14 @@ func (p *pod) configureVolumes(
 	volumeMounts = append(volumeMounts, shmVolumeMount)
 	volumes = append(volumes, shmVolume)
 
+	secretName := ""
+	if p.secret != nil {
+		secretName = p.secret.Name
+	}
 	// //nolint:lll // There isn't a great way to break this line that makes it more readable.
 	initContainerVolumeMounts, mainContainerRunArchiveVolumeMounts, runArchiveVolumes := configureAdditionalFilesVolumes(
 		p.configMapName,
+		secretName,
 		runArchives,
 	)
 
@@ -182,6 +239,42 @@ func (p *pod) configureVolumes(
 	return initContainerVolumeMounts, volumeMounts, volumes
 }
 
+// configurePersistentVolumes sets the claims of the task's scratch volume and of the shared volumes
+// of its workspace and returns the volumes that mount them.
+func (p *pod) configurePersistentVolumes() ([]k8sV1.VolumeMount, []k8sV1.Volume, error) {
+	spec := p.submissionInfo.taskSpec
+	var volumeMounts []k8sV1.VolumeMount
+	var volumes []k8sV1.Volume
+
+	if scratch := spec.ResourcesConfig.Scratch(); scratch != nil {
+		pvc, volumeMount, volume, err := configureScratchVolume(
+			p.podName, p.namespace, spec.AllocationID, *scratch)
+		if err != nil {
+			return nil, nil, err
+		}
+		p.scratchPVC, p.scratchPVCName = pvc, pvc.Name
+		volumeMounts = append(volumeMounts, volumeMount)
+		volumes = append(volumes, volume)
+	}
+
+	if spec.WorkspaceID != 0 {
+		workspaceVolumes, err := db.WorkspaceVolumes(context.TODO(), spec.WorkspaceID)
+		if err != nil {
+			return nil, nil, err
+		}
+		pvcs, workspaceVolumeMounts, claimVolumes, err := configureWorkspaceVolumes(
+			p.namespace, workspaceVolumes)
+		if err != nil {
+			return nil, nil, err
+		}
+		p.workspacePVCs = pvcs
+		volumeMounts = append(volumeMounts, workspaceVolumeMounts...)
+		volumes = append(volumes, claimVolumes...)
+	}
+
+	return volumeMounts, volumes, nil
+}
+
 func (p *pod) modifyPodSpec(newPod *k8sV1.Pod, scheduler string) {
 	if p.submissionInfo.taskSpec.Description == cmdTask {
 		return
@@ -362,6 +455,9 @@ func (p *pod) configurePodSpec(
 		podSpec.ObjectMeta.Labels = make(map[string]string)
 	}
 	podSpec.ObjectMeta.Labels[determinedLabel] = p.submissionInfo.taskSpec.AllocationID
+	if podSpec.Spec.ServiceAccountName == "" {
+		podSpec.Spec.ServiceAccountName = p.serviceAccountName
+	}
 
 	p.modifyPodSpec(podSpec, scheduler)
 
@@ -401,7 +497,10 @@ func (p *pod) configurePodSpec(
 	podSpec.Spec.Containers = append(podSpec.Spec.Containers, sidecarContainers...)
 	podSpec.Spec.Containers = append(podSpec.Spec.Containers, determinedContainer)
 	podSpec.Spec.Volumes = append(podSpec.Spec.Volumes, volumes...)
-	podSpec.Spec.HostNetwork = p.submissionInfo.taskSpec.TaskContainerDefaults.NetworkMode.IsHost()
+	// NetworkPolicies don't apply to pods on the host network, so pods with restricted egress never
+	// run on it.
+	podSpec.Spec.HostNetwork = p.submissionInfo.taskSpec.TaskContainerDefaults.NetworkMode.IsHost() &&
+		p.submissionInfo.taskSpec.Egress == nil
 	podSpec.Spec.InitContainers = append(podSpec.Spec.InitContainers, determinedInitContainers)
 	podSpec.Spec.RestartPolicy = k8sV1.RestartPolicyNever
 
@@ -418,9 +517,25 @@ func (p *pod) createPodSpec(scheduler string) error {
 
 	spec := p.submissionInfo.taskSpec
 
+	// The files of secrets go in a Secret instead of the configMap, after the other archives.
+	secretArchives := spec.SecretArchives
+	spec.SecretArchives = nil
 	runArchives, rootArchives := spec.Archives()
+	var err error
+	p.secret, err = p.configureSecretSpec(secretArchives, len(runArchives))
+	if err != nil {
+		return err
+	}
+	allRunArchives := append(append([]cproto.RunArchive{}, runArchives...), secretArchives...)
+
+	initContainerVolumeMounts, volumeMounts, volumes := p.configureVolumes(spec.Mounts, allRunArchives)
 
-	initContainerVolumeMounts, volumeMounts, volumes := p.configureVolumes(spec.Mounts, runArchives)
+	pvcVolumeMounts, pvcVolumes, err := p.configurePersistentVolumes()
+	if err != nil {
+		return err
+	}
+	volumeMounts = append(volumeMounts, pvcVolumeMounts...)
+	volumes = append(volumes, pvcVolumes...)
 
 	env := spec.Environment
 
@@ -441,7 +556,7 @@ func (p *pod) createPodSpec(scheduler string) error {
 	}
 
 	initContainer := configureInitContainer(
-		len(runArchives),
+		len(allRunArchives),
 		initContainerVolumeMounts,
 		env.Image().For(deviceType),
 		configureImagePullPolicy(env),
@@ -478,6 +593,11 @@ func (p *pod) createPodSpec(scheduler string) error {
 	volumes = append(volumes, rootVolumes...)
 	container.VolumeMounts = append(container.VolumeMounts, rootVolumeMounts...)
 
+	p.networkPolicy, err = p.configureNetworkPolicy(context.TODO())
+	if err != nil {
+		return fmt.Errorf("configuring egress: %w", err)
+	}
+
 	p.pod = p.configurePodSpec(
 		volumes, initContainer, container, sidecars, (*k8sV1.Pod)(env.PodSpec()), scheduler)
 	return nil
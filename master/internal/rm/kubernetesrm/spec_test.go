@@ -2,18 +2,29 @@
 package kubernetesrm
 
 import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"net/netip"
 	"testing"
 	"unicode"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/determined-ai/determined/master/internal/db"
 	"github.com/determined-ai/determined/master/internal/sproto"
+	"github.com/determined-ai/determined/master/pkg/archive"
+	"github.com/determined-ai/determined/master/pkg/cproto"
 	"github.com/determined-ai/determined/master/pkg/device"
 	"github.com/determined-ai/determined/master/pkg/model"
 	"github.com/determined-ai/determined/master/pkg/ptrs"
 	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
+	"github.com/determined-ai/determined/master/pkg/set"
+	"github.com/determined-ai/determined/master/pkg/tasks"
 
 	k8sV1 "k8s.io/api/core/v1"
+	networkingV1 "k8s.io/api/networking/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestGetDetContainerSecurityContext(t *testing.T) {
@@ -219,3 +230,146 @@ func TestAllPrintableCharactersInEnv(t *testing.T) {
 	require.Contains(t, actual, k8sV1.EnvVar{Name: "test2", Value: ""})
 	require.Contains(t, actual, k8sV1.EnvVar{Name: "func", Value: "f(x)=x"})
 }
+
+func TestConfigureSecrets(t *testing.T) {
+	p := pod{
+		configMapName: "pod-a",
+		namespace:     "default",
+		submissionInfo: &podSubmissionInfo{taskSpec: tasks.TaskSpec{
+			AllocationID:  "alloc-a",
+			SecretEnvVars: map[string]string{"TOKEN": "secret-token"},
+		}},
+	}
+	secretArchives := []cproto.RunArchive{{
+		Path:    "/run/determined/secrets",
+		Archive: archive.Archive{archive.UserItem("creds", []byte("secret-creds"), 0o600, tar.TypeReg, 1000, 1000)},
+	}}
+
+	// The files of secrets are numbered after the other archives of the pod.
+	secret, err := p.configureSecretSpec(secretArchives, 2)
+	require.NoError(t, err)
+	require.Equal(t, "pod-a", secret.Name)
+	require.Equal(t, []byte("secret-token"), secret.Data[secretEnvVarKey("TOKEN")])
+	require.Contains(t, secret.Data, "2.tar.gz")
+
+	env := expconf.EnvironmentConfig{RawEnvironmentVariables: &expconf.EnvironmentVariablesMap{}}
+	actual, err := p.configureEnvVars(
+		map[string]string{"A": "1", "TOKEN": "secret-token"}, env, device.CPU)
+	require.NoError(t, err)
+	require.Contains(t, actual, k8sV1.EnvVar{Name: "A", Value: "1"})
+	require.Contains(t, actual, k8sV1.EnvVar{
+		Name: "TOKEN",
+		ValueFrom: &k8sV1.EnvVarSource{SecretKeyRef: &k8sV1.SecretKeySelector{
+			LocalObjectReference: k8sV1.LocalObjectReference{Name: "pod-a"},
+			Key:                  secretEnvVarKey("TOKEN"),
+		}},
+	})
+
+	// Without secrets, there is no Secret.
+	p.submissionInfo.taskSpec.SecretEnvVars = nil
+	secret, err = p.configureSecretSpec(nil, 2)
+	require.NoError(t, err)
+	require.Nil(t, secret)
+}
+
+func TestConfigureNetworkPolicy(t *testing.T) {
+	oldLookup := lookupNetIP
+	lookupNetIP = func(_ context.Context, _, host string) ([]netip.Addr, error) {
+		if host != "pypi.org" {
+			return nil, fmt.Errorf("no such host %s", host)
+		}
+		return []netip.Addr{netip.MustParseAddr("198.51.100.7"), netip.MustParseAddr("2001:db8::1")}, nil
+	}
+	t.Cleanup(func() { lookupNetIP = oldLookup })
+
+	masterPeer := networkingV1.NetworkPolicyPeer{
+		PodSelector: &metaV1.LabelSelector{MatchLabels: map[string]string{"app": "determined-master"}},
+	}
+	p := pod{
+		submissionInfo: &podSubmissionInfo{taskSpec: tasks.TaskSpec{AllocationID: "alloc.1"}},
+		podName:        "pod-1",
+		namespace:      "det",
+		masterIP:       "10.96.0.10",
+		masterPort:     8080,
+		masterPeer:     &masterPeer,
+	}
+
+	policy, err := p.configureNetworkPolicy(context.Background())
+	require.NoError(t, err)
+	require.Nil(t, policy)
+
+	p.submissionInfo.taskSpec.Egress = &model.EgressRule{
+		Mode:  model.EgressAllowList,
+		Allow: []string{"10.1.0.0/16", "pypi.org"},
+	}
+	policy, err = p.configureNetworkPolicy(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "pod-1", policy.Name)
+	require.Equal(t, "det", policy.Namespace)
+	require.Equal(t, map[string]string{determinedLabel: "alloc.1"}, policy.Spec.PodSelector.MatchLabels)
+	require.Equal(t, []networkingV1.PolicyType{networkingV1.PolicyTypeEgress}, policy.Spec.PolicyTypes)
+
+	rules := policy.Spec.Egress
+	require.Len(t, rules, 5)
+	require.Equal(t, &policy.Spec.PodSelector, rules[0].To[0].PodSelector, "pods of the allocation")
+	require.Equal(t, int32(53), rules[1].Ports[0].Port.IntVal, "DNS")
+	require.Equal(t, "10.96.0.10/32", rules[2].To[0].IPBlock.CIDR, "master service")
+	require.Equal(t, int32(8080), rules[2].Ports[0].Port.IntVal)
+	require.Equal(t, masterPeer, rules[3].To[0], "master pods")
+	var allowed []string
+	for _, peer := range rules[4].To {
+		allowed = append(allowed, peer.IPBlock.CIDR)
+	}
+	require.Equal(t, []string{"10.1.0.0/16", "198.51.100.7/32", "2001:db8::1/128"}, allowed)
+	require.Empty(t, rules[4].Ports)
+
+	p.submissionInfo.taskSpec.Egress.Allow = []string{"unknown.example.com"}
+	_, err = p.configureNetworkPolicy(context.Background())
+	require.ErrorContains(t, err, "no such host")
+}
+
+func TestWorkspaceVolumeClaims(t *testing.T) {
+	volume := db.WorkspaceVolume{
+		WorkspaceID: 1,
+		Name:        "data",
+		Size:        "10Gi",
+		MountPath:   "/data",
+	}
+	pvcs, _, _, err := configureWorkspaceVolumes("default", []db.WorkspaceVolume{volume})
+	require.NoError(t, err)
+	want := pvcs[0]
+
+	existing := want.DeepCopy()
+	existing.Spec.StorageClassName = ptrs.Ptr("standard")
+	require.NoError(t, workspaceClaimMismatch(existing, want))
+
+	volume.Size = "20Gi"
+	pvcs, _, _, err = configureWorkspaceVolumes("default", []db.WorkspaceVolume{volume})
+	require.NoError(t, err)
+	require.ErrorContains(t, workspaceClaimMismatch(existing, pvcs[0]), "size 10Gi instead of 20Gi")
+
+	volume.Size = "10Gi"
+	volume.StorageClass = ptrs.Ptr("fast")
+	pvcs, _, _, err = configureWorkspaceVolumes("default", []db.WorkspaceVolume{volume})
+	require.NoError(t, err)
+	require.ErrorContains(t, workspaceClaimMismatch(existing, pvcs[0]), "storage class")
+
+	unused := want.DeepCopy()
+	unused.Name = "det-ws-1-unused"
+	mounted := want.DeepCopy()
+	mounted.Name = "det-ws-1-mounted"
+	scratch := k8sV1.PersistentVolumeClaim{ObjectMeta: metaV1.ObjectMeta{Name: "pod-a"}}
+	pod := k8sV1.Pod{Spec: k8sV1.PodSpec{Volumes: []k8sV1.Volume{{
+		VolumeSource: k8sV1.VolumeSource{
+			PersistentVolumeClaim: &k8sV1.PersistentVolumeClaimVolumeSource{
+				ClaimName: mounted.Name,
+			},
+		},
+	}}}}
+	orphaned := orphanedWorkspaceVolumeClaims(
+		[]k8sV1.PersistentVolumeClaim{*want, *unused, *mounted, scratch},
+		[]k8sV1.Pod{pod},
+		set.FromSlice([]string{want.Name}),
+	)
+	require.Equal(t, []string{"det-ws-1-unused"}, orphaned)
+}
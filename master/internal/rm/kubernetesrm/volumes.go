@@ -3,14 +3,31 @@ package kubernetesrm
 import (
 	"fmt"
 	"path"
+	"slices"
+	"strconv"
 
 	"github.com/determined-ai/determined/master/pkg/etc"
 
 	"github.com/docker/docker/api/types/mount"
+	"github.com/pkg/errors"
 
 	k8sV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/determined-ai/determined/master/internal/db"
 	"github.com/determined-ai/determined/master/pkg/cproto"
+	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
+	"github.com/determined-ai/determined/master/pkg/set"
+)
+
+const (
+	scratchVolumeName = "det-scratch-volume"
+	// workspaceIDLabel and workspaceVolumeLabel mark the claims of workspace volumes. They don't
+	// carry the determined label, so they aren't deleted with the resources of any allocation.
+	// workspaceIDLabel also marks the namespaces created for workspaces.
+	workspaceIDLabel     = "determined-workspace-id"
+	workspaceVolumeLabel = "determined-workspace-volume"
 )
 
 func configureMountPropagation(b *mount.BindOptions) *k8sV1.MountPropagationMode {
@@ -79,6 +96,7 @@ func configureShmVolume(_ int64) (k8sV1.VolumeMount, k8sV1.Volume) {
 
 func configureAdditionalFilesVolumes(
 	configMapName string,
+	secretName string,
 	runArchives []cproto.RunArchive,
 ) ([]k8sV1.VolumeMount, []k8sV1.VolumeMount, []k8sV1.Volume) {
 	initContainerVolumeMounts := make([]k8sV1.VolumeMount, 0)
@@ -98,6 +116,21 @@ func configureAdditionalFilesVolumes(
 			},
 		},
 	}
+	// The archives of secrets are in a Secret, which is projected next to the configMap.
+	if secretName != "" {
+		archiveVolume.VolumeSource = k8sV1.VolumeSource{
+			Projected: &k8sV1.ProjectedVolumeSource{
+				Sources: []k8sV1.VolumeProjection{
+					{ConfigMap: &k8sV1.ConfigMapProjection{
+						LocalObjectReference: k8sV1.LocalObjectReference{Name: configMapName},
+					}},
+					{Secret: &k8sV1.SecretProjection{
+						LocalObjectReference: k8sV1.LocalObjectReference{Name: secretName},
+					}},
+				},
+			},
+		}
+	}
 	volumes = append(volumes, archiveVolume)
 	archiveVolumeMount := k8sV1.VolumeMount{
 		Name:      archiveVolumeName,
@@ -154,3 +187,160 @@ func configureAdditionalFilesVolumes(
 
 	return initContainerVolumeMounts, mainContainerVolumeMounts, volumes
 }
+
+func newPersistentVolumeClaim(
+	name, namespace string,
+	labels map[string]string,
+	accessMode k8sV1.PersistentVolumeAccessMode,
+	size string,
+	storageClass *string,
+) (*k8sV1.PersistentVolumeClaim, error) {
+	quantity, err := resource.ParseQuantity(size)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid size %q of volume %s", size, name)
+	}
+	return &k8sV1.PersistentVolumeClaim{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: k8sV1.PersistentVolumeClaimSpec{
+			AccessModes:      []k8sV1.PersistentVolumeAccessMode{accessMode},
+			StorageClassName: storageClass,
+			Resources: k8sV1.ResourceRequirements{
+				Requests: k8sV1.ResourceList{k8sV1.ResourceStorage: quantity},
+			},
+		},
+	}, nil
+}
+
+func claimVolume(
+	volumeName, claimName, mountPath string, readOnly bool,
+) (k8sV1.VolumeMount, k8sV1.Volume) {
+	volumeMount := k8sV1.VolumeMount{
+		Name:      volumeName,
+		ReadOnly:  readOnly,
+		MountPath: mountPath,
+	}
+	volume := k8sV1.Volume{
+		Name: volumeName,
+		VolumeSource: k8sV1.VolumeSource{
+			PersistentVolumeClaim: &k8sV1.PersistentVolumeClaimVolumeSource{
+				ClaimName: claimName,
+				ReadOnly:  readOnly,
+			},
+		},
+	}
+	return volumeMount, volume
+}
+
+// configureScratchVolume returns the claim of a task container's scratch volume. The claim carries
+// the determined label like the pod and its configMap, so it is deleted along with them.
+func configureScratchVolume(
+	claimName, namespace, allocationID string, scratch expconf.ScratchVolumeConfig,
+) (*k8sV1.PersistentVolumeClaim, k8sV1.VolumeMount, k8sV1.Volume, error) {
+	pvc, err := newPersistentVolumeClaim(
+		claimName, namespace, map[string]string{determinedLabel: allocationID},
+		k8sV1.ReadWriteOnce, scratch.Size(), scratch.StorageClass(),
+	)
+	if err != nil {
+		return nil, k8sV1.VolumeMount{}, k8sV1.Volume{}, err
+	}
+	volumeMount, volume := claimVolume(scratchVolumeName, claimName, scratch.MountPath(), false)
+	return pvc, volumeMount, volume, nil
+}
+
+// scratchClaimName returns the name of the claim of a pod's scratch volume, or an empty string if
+// the pod has none.
+func scratchClaimName(pod *k8sV1.Pod) string {
+	for _, v := range pod.Spec.Volumes {
+		if v.Name == scratchVolumeName && v.PersistentVolumeClaim != nil {
+			return v.PersistentVolumeClaim.ClaimName
+		}
+	}
+	return ""
+}
+
+func workspaceVolumeClaimName(v db.WorkspaceVolume) string {
+	return fmt.Sprintf("det-ws-%d-%s", v.WorkspaceID, v.Name)
+}
+
+// configureWorkspaceVolumes returns the claims of the shared volumes of a workspace in a namespace
+// and the volumes that mount them. Claims are ReadWriteMany, since tasks of the workspace on any
+// node use them at the same time.
+func configureWorkspaceVolumes(
+	namespace string, workspaceVolumes []db.WorkspaceVolume,
+) ([]*k8sV1.PersistentVolumeClaim, []k8sV1.VolumeMount, []k8sV1.Volume, error) {
+	pvcs := make([]*k8sV1.PersistentVolumeClaim, 0, len(workspaceVolumes))
+	volumeMounts := make([]k8sV1.VolumeMount, 0, len(workspaceVolumes))
+	volumes := make([]k8sV1.Volume, 0, len(workspaceVolumes))
+
+	for _, v := range workspaceVolumes {
+		claimName := workspaceVolumeClaimName(v)
+		pvc, err := newPersistentVolumeClaim(claimName, namespace, map[string]string{
+			workspaceIDLabel:     strconv.Itoa(v.WorkspaceID),
+			workspaceVolumeLabel: v.Name,
+		}, k8sV1.ReadWriteMany, v.Size, v.StorageClass)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		pvcs = append(pvcs, pvc)
+
+		volumeMount, volume := claimVolume(
+			"det-workspace-volume-"+v.Name, claimName, v.MountPath, v.ReadOnly)
+		volumeMounts = append(volumeMounts, volumeMount)
+		volumes = append(volumes, volume)
+	}
+
+	return pvcs, volumeMounts, volumes, nil
+}
+
+// workspaceClaimMismatch returns an error if an existing claim of a workspace volume doesn't have
+// the access mode, size or storage class the volume declares, since tasks would otherwise mount
+// something other than what the workspace asked for. A claim without a storage class is given the
+// cluster's default class, so the class is only compared if the volume sets one.
+func workspaceClaimMismatch(existing, want *k8sV1.PersistentVolumeClaim) error {
+	if !slices.Equal(existing.Spec.AccessModes, want.Spec.AccessModes) {
+		return fmt.Errorf("claim %s already exists with access modes %v instead of %v",
+			want.Name, existing.Spec.AccessModes, want.Spec.AccessModes)
+	}
+	existingSize := existing.Spec.Resources.Requests[k8sV1.ResourceStorage]
+	wantSize := want.Spec.Resources.Requests[k8sV1.ResourceStorage]
+	if existingSize.Cmp(wantSize) != 0 {
+		return fmt.Errorf("claim %s already exists with size %s instead of %s",
+			want.Name, existingSize.String(), wantSize.String())
+	}
+	if want.Spec.StorageClassName != nil && (existing.Spec.StorageClassName == nil ||
+		*existing.Spec.StorageClassName != *want.Spec.StorageClassName) {
+		return fmt.Errorf("claim %s already exists with another storage class than %s",
+			want.Name, *want.Spec.StorageClassName)
+	}
+	return nil
+}
+
+// orphanedWorkspaceVolumeClaims returns the names of the claims of workspace volumes that are no
+// longer declared and that no pod mounts.
+func orphanedWorkspaceVolumeClaims(
+	pvcs []k8sV1.PersistentVolumeClaim, pods []k8sV1.Pod, declared set.Set[string],
+) []string {
+	inUse := make(set.Set[string])
+	for _, pod := range pods {
+		for _, v := range pod.Spec.Volumes {
+			if v.PersistentVolumeClaim != nil {
+				inUse.Insert(v.PersistentVolumeClaim.ClaimName)
+			}
+		}
+	}
+	var orphaned []string
+	for _, pvc := range pvcs {
+		if _, ok := pvc.Labels[workspaceVolumeLabel]; !ok {
+			continue
+		}
+		if declared.Contains(pvc.Name) || inUse.Contains(pvc.Name) {
+			continue
+		}
+		orphaned = append(orphaned, pvc.Name)
+	}
+	return orphaned
+}
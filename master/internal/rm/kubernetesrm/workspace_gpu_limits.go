@@ -0,0 +1,90 @@
+package kubernetesrm
+
+import (
+	"sync"
+
+	"github.com/determined-ai/determined/master/internal/sproto"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// workspaceGPULimits accounts for the GPUs the allocations of each workspace hold across the
+// resource pools, so that allocations that would exceed the GPU limit of their workspace stay
+// queued instead of having their pods rejected by the GPU quota of its namespace.
+type workspaceGPULimits struct {
+	mu sync.Mutex
+	// limits are the GPU limits of workspaces as of their last allocate request; workspaces
+	// without a limit are missing.
+	limits      map[int]int
+	used        map[int]int
+	allocations map[model.AllocationID]workspaceGPUs
+}
+
+type workspaceGPUs struct {
+	workspaceID int
+	gpus        int
+}
+
+func newWorkspaceGPULimits() *workspaceGPULimits {
+	return &workspaceGPULimits{
+		limits:      make(map[int]int),
+		used:        make(map[int]int),
+		allocations: make(map[model.AllocationID]workspaceGPUs),
+	}
+}
+
+// setLimit sets the GPU limit of a workspace; nil removes it.
+func (w *workspaceGPULimits) setLimit(workspaceID int, limit *int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if limit == nil {
+		delete(w.limits, workspaceID)
+		return
+	}
+	w.limits[workspaceID] = *limit
+}
+
+// limit returns the GPU limit of a workspace, or nil if it isn't limited.
+func (w *workspaceGPULimits) limit(workspaceID int) *int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if limit, ok := w.limits[workspaceID]; ok {
+		return &limit
+	}
+	return nil
+}
+
+// reserve records the GPUs of an allocation and returns true if they fit under the GPU limit of its
+// workspace alongside the GPUs its other allocations hold. Otherwise nothing is recorded. Restored
+// allocations already hold their GPUs, so they are always recorded.
+func (w *workspaceGPULimits) reserve(req *sproto.AllocateRequest) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.allocations[req.AllocationID]; ok {
+		return true
+	}
+	limit, ok := w.limits[req.WorkspaceID]
+	if ok && !req.Restore && w.used[req.WorkspaceID]+req.SlotsNeeded > limit {
+		return false
+	}
+	w.used[req.WorkspaceID] += req.SlotsNeeded
+	w.allocations[req.AllocationID] = workspaceGPUs{
+		workspaceID: req.WorkspaceID,
+		gpus:        req.SlotsNeeded,
+	}
+	return true
+}
+
+// release forgets the GPUs of an allocation.
+func (w *workspaceGPULimits) release(allocationID model.AllocationID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	a, ok := w.allocations[allocationID]
+	if !ok {
+		return
+	}
+	w.used[a.workspaceID] -= a.gpus
+	if w.used[a.workspaceID] == 0 {
+		delete(w.used, a.workspaceID)
+	}
+	delete(w.allocations, allocationID)
+}
@@ -0,0 +1,215 @@
+package kubernetesrm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	k8sV1 "k8s.io/api/core/v1"
+	k8error "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sClient "k8s.io/client-go/kubernetes"
+
+	"github.com/determined-ai/determined/master/internal/config"
+)
+
+// workspaceGPUQuotaName is the name of the ResourceQuota that enforces the GPU limit of a
+// workspace in its namespace.
+const workspaceGPUQuotaName = "determined-gpu-limit"
+
+// workspaceNamespace is a namespace that tasks of a workspace run in. It is registered when the
+// first task of the workspace starts, but only created by the request worker that creates the
+// resources of that task, so that the pods service doesn't call the Kubernetes API while it holds
+// its lock.
+type workspaceNamespace struct {
+	name        string
+	workspaceID int
+	config      *config.WorkspaceNamespacesConfig
+	clientSet   k8sClient.Interface
+	syslog      *logrus.Entry
+
+	// mu serializes the preparation of the namespace by the request workers.
+	mu sync.Mutex
+	// created is set once the namespace and its service account exist.
+	created bool
+	// gpuLimit is the limit of the GPU quota of the namespace, or nil if it has none. It is only
+	// known once quotaSynced is set, which it isn't for namespaces found when the master starts.
+	gpuLimit    *int
+	quotaSynced bool
+}
+
+// prepareWorkspaceNamespace asks a request worker to prepare the namespace of a workspace before it
+// creates the resources of a pod in it.
+type prepareWorkspaceNamespace struct {
+	namespace *workspaceNamespace
+	gpuLimit  *int
+}
+
+func workspaceNamespaceName(prefix string, workspaceID int) string {
+	return prefix + strconv.Itoa(workspaceID)
+}
+
+func sameGPULimit(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func newWorkspaceNamespace(name string, workspaceID int, labels map[string]string) *k8sV1.Namespace {
+	nsLabels := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		nsLabels[k] = v
+	}
+	nsLabels[workspaceIDLabel] = strconv.Itoa(workspaceID)
+	return &k8sV1.Namespace{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:   name,
+			Labels: nsLabels,
+		},
+	}
+}
+
+func newWorkspaceGPUQuota(namespace string, gpuLimit int) *k8sV1.ResourceQuota {
+	// Quotas on extended resources like GPUs can only be set on requests.
+	gpuRequests := k8sV1.ResourceName(k8sV1.DefaultResourceRequestsPrefix + ResourceTypeNvidia)
+	return &k8sV1.ResourceQuota{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      workspaceGPUQuotaName,
+			Namespace: namespace,
+		},
+		Spec: k8sV1.ResourceQuotaSpec{
+			Hard: k8sV1.ResourceList{
+				gpuRequests: *resource.NewQuantity(int64(gpuLimit), resource.DecimalSI),
+			},
+		},
+	}
+}
+
+// addNamespaceInterfaces creates the clients of a namespace tasks can run in.
+func (p *pods) addNamespaceInterfaces(namespace string) {
+	p.podInterfaces[namespace] = p.clientSet.CoreV1().Pods(namespace)
+	p.configMapInterfaces[namespace] = p.clientSet.CoreV1().ConfigMaps(namespace)
+	p.pvcInterfaces[namespace] = p.clientSet.CoreV1().PersistentVolumeClaims(namespace)
+	p.networkPolicyInterfaces[namespace] = p.clientSet.NetworkingV1().NetworkPolicies(namespace)
+	p.secretInterfaces[namespace] = p.clientSet.CoreV1().Secrets(namespace)
+}
+
+// isTaskNamespace returns whether tasks run in the namespace, either because it is the namespace
+// of a resource pool or because it was created for a workspace.
+func (p *pods) isTaskNamespace(namespace string) bool {
+	if _, ok := p.namespaceToPoolName[namespace]; ok {
+		return true
+	}
+	_, ok := p.workspaceNamespaces[namespace]
+	return ok
+}
+
+// findWorkspaceNamespaces registers the namespaces that were created for workspaces before the
+// master started, so that their tasks are restored or cleaned up.
+func (p *pods) findWorkspaceNamespaces() error {
+	namespaces, err := p.clientSet.CoreV1().Namespaces().List(
+		context.TODO(), metaV1.ListOptions{LabelSelector: workspaceIDLabel})
+	if err != nil {
+		return fmt.Errorf("listing workspace namespaces: %w", err)
+	}
+	for _, ns := range namespaces.Items {
+		workspaceID, err := strconv.Atoi(ns.Labels[workspaceIDLabel])
+		if err != nil ||
+			ns.Name != workspaceNamespaceName(p.workspaceNamespacesConfig.NamePrefix, workspaceID) {
+			continue
+		}
+		p.addNamespaceInterfaces(ns.Name)
+		state := p.newWorkspaceNamespaceState(ns.Name, workspaceID)
+		state.created = true
+		p.workspaceNamespaces[ns.Name] = state
+	}
+	return nil
+}
+
+func (p *pods) newWorkspaceNamespaceState(name string, workspaceID int) *workspaceNamespace {
+	return &workspaceNamespace{
+		name:        name,
+		workspaceID: workspaceID,
+		config:      p.workspaceNamespacesConfig,
+		clientSet:   p.clientSet,
+		syslog:      p.syslog.WithField("namespace", name),
+	}
+}
+
+// workspaceNamespace returns the namespace of a workspace, registering it with the request queue
+// if it is the first task of the workspace. The namespace itself is created by prepare.
+func (p *pods) workspaceNamespace(workspaceID int) *workspaceNamespace {
+	name := workspaceNamespaceName(p.workspaceNamespacesConfig.NamePrefix, workspaceID)
+	if ns, ok := p.workspaceNamespaces[name]; ok {
+		return ns
+	}
+	p.addNamespaceInterfaces(name)
+	p.resourceRequestQueue.addNamespace(
+		name, p.podInterfaces[name], p.configMapInterfaces[name], p.pvcInterfaces[name],
+		p.networkPolicyInterfaces[name], p.secretInterfaces[name])
+	ns := p.newWorkspaceNamespaceState(name, workspaceID)
+	p.workspaceNamespaces[name] = ns
+	return ns
+}
+
+// prepare creates the namespace with its service account if it doesn't exist yet, and makes its
+// GPU quota match the limit of the workspace.
+func (n *workspaceNamespace) prepare(ctx context.Context, gpuLimit *int) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if !n.created {
+		_, err := n.clientSet.CoreV1().Namespaces().Create(ctx,
+			newWorkspaceNamespace(n.name, n.workspaceID, n.config.Labels), metaV1.CreateOptions{})
+		if err != nil && !k8error.IsAlreadyExists(err) {
+			return fmt.Errorf("creating namespace %s: %w", n.name, err)
+		}
+		_, err = n.clientSet.CoreV1().ServiceAccounts(n.name).Create(ctx, &k8sV1.ServiceAccount{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name:      n.config.ServiceAccountName,
+				Namespace: n.name,
+			},
+		}, metaV1.CreateOptions{})
+		if err != nil && !k8error.IsAlreadyExists(err) {
+			return fmt.Errorf("creating service account in namespace %s: %w", n.name, err)
+		}
+		n.syslog.Infof("created namespace %s for workspace %d", n.name, n.workspaceID)
+		n.created = true
+	}
+
+	if n.quotaSynced && sameGPULimit(n.gpuLimit, gpuLimit) {
+		return nil
+	}
+	if err := n.syncGPUQuota(ctx, gpuLimit); err != nil {
+		return err
+	}
+	n.gpuLimit = gpuLimit
+	n.quotaSynced = true
+	return nil
+}
+
+func (n *workspaceNamespace) syncGPUQuota(ctx context.Context, gpuLimit *int) error {
+	quotas := n.clientSet.CoreV1().ResourceQuotas(n.name)
+	if gpuLimit == nil {
+		err := quotas.Delete(ctx, workspaceGPUQuotaName, metaV1.DeleteOptions{})
+		if err != nil && !k8error.IsNotFound(err) {
+			return fmt.Errorf("deleting GPU quota of namespace %s: %w", n.name, err)
+		}
+		return nil
+	}
+
+	quota := newWorkspaceGPUQuota(n.name, *gpuLimit)
+	_, err := quotas.Create(ctx, quota, metaV1.CreateOptions{})
+	if k8error.IsAlreadyExists(err) {
+		_, err = quotas.Update(ctx, quota, metaV1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("setting GPU quota of namespace %s: %w", n.name, err)
+	}
+	n.syslog.Infof("set GPU quota of namespace %s to %d", n.name, *gpuLimit)
+	return nil
+}
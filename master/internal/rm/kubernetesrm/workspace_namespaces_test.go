@@ -0,0 +1,128 @@
+package kubernetesrm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	k8sV1 "k8s.io/api/core/v1"
+	k8error "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	typedV1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	typedNetworkingV1 "k8s.io/client-go/kubernetes/typed/networking/v1"
+
+	"github.com/determined-ai/determined/master/internal/config"
+	"github.com/determined-ai/determined/master/internal/sproto"
+	"github.com/determined-ai/determined/master/pkg/ptrs"
+)
+
+func newTestWorkspaceNamespacesPods(clientSet *fake.Clientset) *pods {
+	return &pods{
+		clientSet: clientSet,
+		workspaceNamespacesConfig: &config.WorkspaceNamespacesConfig{
+			NamePrefix:         "det-workspace-",
+			Labels:             map[string]string{"team": "nlp"},
+			ServiceAccountName: "determined-task",
+		},
+		workspaceNamespaces:     make(map[string]*workspaceNamespace),
+		podInterfaces:           make(map[string]typedV1.PodInterface),
+		configMapInterfaces:     make(map[string]typedV1.ConfigMapInterface),
+		pvcInterfaces:           make(map[string]typedV1.PersistentVolumeClaimInterface),
+		networkPolicyInterfaces: make(map[string]typedNetworkingV1.NetworkPolicyInterface),
+		secretInterfaces:        make(map[string]typedV1.SecretInterface),
+		resourceRequestQueue: startRequestQueue(
+			map[string]typedV1.PodInterface{},
+			map[string]typedV1.ConfigMapInterface{},
+			map[string]typedV1.PersistentVolumeClaimInterface{},
+			map[string]typedNetworkingV1.NetworkPolicyInterface{},
+			map[string]typedV1.SecretInterface{},
+			make(chan resourcesRequestFailure, 16),
+		),
+		syslog: logrus.WithField("component", "pods"),
+	}
+}
+
+func TestPrepareWorkspaceNamespace(t *testing.T) {
+	ctx := context.Background()
+	clientSet := fake.NewSimpleClientset()
+	p := newTestWorkspaceNamespacesPods(clientSet)
+	gpuRequests := k8sV1.ResourceName("requests." + ResourceTypeNvidia)
+
+	// The namespace is registered when the first task of the workspace starts, but only created
+	// by the request worker that creates the resources of the task.
+	namespace := p.workspaceNamespace(3)
+	name := namespace.name
+	require.Equal(t, "det-workspace-3", name)
+	require.Same(t, namespace, p.workspaceNamespace(3))
+	require.True(t, p.isTaskNamespace(name))
+	podInterface, _, _, networkPolicyInterface, secretInterface := p.resourceRequestQueue.interfaces.get(name)
+	require.NotNil(t, podInterface)
+	require.NotNil(t, networkPolicyInterface)
+	require.NotNil(t, secretInterface)
+	_, err := clientSet.CoreV1().Namespaces().Get(ctx, name, metaV1.GetOptions{})
+	require.True(t, k8error.IsNotFound(err))
+
+	require.NoError(t, namespace.prepare(ctx, ptrs.Ptr(4)))
+	ns, err := clientSet.CoreV1().Namespaces().Get(ctx, name, metaV1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"team": "nlp", workspaceIDLabel: "3"}, ns.Labels)
+	_, err = clientSet.CoreV1().ServiceAccounts(name).Get(ctx, "determined-task", metaV1.GetOptions{})
+	require.NoError(t, err)
+	quota, err := clientSet.CoreV1().ResourceQuotas(name).Get(
+		ctx, workspaceGPUQuotaName, metaV1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, int64(4), quota.Spec.Hard.Name(gpuRequests, "").Value())
+
+	require.NoError(t, namespace.prepare(ctx, ptrs.Ptr(8)))
+	quota, err = clientSet.CoreV1().ResourceQuotas(name).Get(
+		ctx, workspaceGPUQuotaName, metaV1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, int64(8), quota.Spec.Hard.Name(gpuRequests, "").Value())
+
+	require.NoError(t, namespace.prepare(ctx, nil))
+	_, err = clientSet.CoreV1().ResourceQuotas(name).Get(
+		ctx, workspaceGPUQuotaName, metaV1.GetOptions{})
+	require.True(t, k8error.IsNotFound(err))
+
+	// After a restart, the namespace is found again, but only namespaces with the configured
+	// prefix are used.
+	_, err = clientSet.CoreV1().Namespaces().Create(ctx,
+		newWorkspaceNamespace("other-3", 3, nil), metaV1.CreateOptions{})
+	require.NoError(t, err)
+	restarted := newTestWorkspaceNamespacesPods(clientSet)
+	require.NoError(t, restarted.findWorkspaceNamespaces())
+	require.True(t, restarted.isTaskNamespace(name))
+	require.True(t, restarted.workspaceNamespaces[name].created)
+	require.Contains(t, restarted.podInterfaces, name)
+	require.False(t, restarted.isTaskNamespace("other-3"))
+}
+
+func TestWorkspaceGPULimits(t *testing.T) {
+	limits := newWorkspaceGPULimits()
+	limits.setLimit(3, ptrs.Ptr(4))
+	require.Equal(t, 4, *limits.limit(3))
+	require.Nil(t, limits.limit(4))
+
+	first := &sproto.AllocateRequest{AllocationID: "a", WorkspaceID: 3, SlotsNeeded: 3}
+	second := &sproto.AllocateRequest{AllocationID: "b", WorkspaceID: 3, SlotsNeeded: 2}
+	other := &sproto.AllocateRequest{AllocationID: "c", WorkspaceID: 4, SlotsNeeded: 8}
+	require.True(t, limits.reserve(first))
+	require.True(t, limits.reserve(first))
+	require.True(t, limits.reserve(other))
+	// The second allocation stays queued until the first one releases its GPUs.
+	require.False(t, limits.reserve(second))
+	limits.release(first.AllocationID)
+	require.True(t, limits.reserve(second))
+
+	// Restored allocations already hold their GPUs, so they are counted regardless of the limit.
+	restored := &sproto.AllocateRequest{
+		AllocationID: "d", WorkspaceID: 3, SlotsNeeded: 4, Restore: true,
+	}
+	require.True(t, limits.reserve(restored))
+	require.False(t, limits.reserve(first))
+
+	limits.setLimit(3, nil)
+	require.True(t, limits.reserve(first))
+}
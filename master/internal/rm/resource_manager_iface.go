@@ -23,6 +23,7 @@ type ResourceManager interface {
 
 	// Scheduling related stuff
 	SetGroupMaxSlots(sproto.SetGroupMaxSlots)
+	SetWorkspaceMaxSlots(sproto.SetWorkspaceMaxSlots)
 	SetGroupWeight(sproto.SetGroupWeight) error
 	SetGroupPriority(sproto.SetGroupPriority) error
 	ExternalPreemptionPending(sproto.PendingPreemption) error
@@ -0,0 +1,254 @@
+package secrets
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/uptrace/bun"
+
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/pkg/archive"
+	"github.com/determined-ai/determined/master/pkg/cproto"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
+	"github.com/determined-ai/determined/master/pkg/tasks"
+)
+
+// Dir is where secrets referenced with ${secret_file:NAME} are written in task containers.
+var Dir = filepath.Join(tasks.RunDir, "secrets")
+
+// redactedValue replaces the values of secrets in task logs.
+const redactedValue = "********"
+
+// minRedactedLength is the length below which values aren't redacted from logs, since they would
+// hide too much unrelated output.
+const minRedactedLength = 4
+
+// referencePattern matches the value of an environment variable that references a secret, either
+// as its value, ${secret:NAME}, or as a file, ${secret_file:NAME}.
+var referencePattern = regexp.MustCompile(`^\$\{(secret|secret_file):([^}]*)\}$`)
+
+// reference is an environment variable that references a secret.
+type reference struct {
+	envVar string
+	name   string
+	file   bool
+}
+
+// parseReferences splits environment variables of the form NAME=VALUE into those that reference
+// secrets and the others.
+func parseReferences(envVars []string) (refs []reference, rest []string, err error) {
+	for _, envVar := range envVars {
+		name, value, ok := strings.Cut(envVar, "=")
+		match := referencePattern.FindStringSubmatch(value)
+		if !ok || match == nil {
+			rest = append(rest, envVar)
+			continue
+		}
+		if err := ValidateName(match[2]); err != nil {
+			return nil, nil, fmt.Errorf("environment variable %s: %w", name, err)
+		}
+		refs = append(refs, reference{envVar: name, name: match[2], file: match[1] == "secret_file"})
+	}
+	return refs, rest, nil
+}
+
+// Resolve replaces the environment variables of spec that reference secrets with the values of
+// those secrets, or with the paths of files holding them, in spec.SecretEnvVars and
+// spec.SecretArchives. A secret of the owner of the task takes
+// precedence over one of its workspace with the same name, which takes precedence over one of the
+// cluster. The values are redacted from the task's logs until Release is called.
+func Resolve(ctx context.Context, spec *tasks.TaskSpec, allocationID model.AllocationID) error {
+	if spec.Environment.RawEnvironmentVariables == nil {
+		return nil
+	}
+	envVars := *spec.Environment.RawEnvironmentVariables
+	var refs []reference
+	var resolved expconf.EnvironmentVariablesMapV0
+	for _, l := range []struct {
+		in  []string
+		out *[]string
+	}{
+		{envVars.RawCPU, &resolved.RawCPU},
+		{envVars.RawCUDA, &resolved.RawCUDA},
+		{envVars.RawROCM, &resolved.RawROCM},
+	} {
+		listRefs, rest, err := parseReferences(l.in)
+		if err != nil {
+			return err
+		}
+		refs = append(refs, listRefs...)
+		*l.out = rest
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+	if !Enabled() {
+		return ErrNotEnabled
+	}
+
+	var ownerID *model.UserID
+	if spec.Owner != nil {
+		ownerID = &spec.Owner.ID
+	}
+	values, err := lookup(ctx, refs, ownerID, spec.WorkspaceID)
+	if err != nil {
+		return err
+	}
+
+	// The environment may be shared with the spec the task was made from, so it is replaced rather
+	// than modified.
+	spec.Environment.RawEnvironmentVariables = &resolved
+	spec.SecretEnvVars = map[string]string{}
+	spec.SecretArchives = nil
+	var files archive.Archive
+	written := map[string]bool{}
+	for _, ref := range refs {
+		if !ref.file {
+			spec.SecretEnvVars[ref.envVar] = values[ref.name]
+			continue
+		}
+		spec.SecretEnvVars[ref.envVar] = filepath.Join(Dir, ref.name)
+		if !written[ref.name] {
+			written[ref.name] = true
+			files = append(files, spec.AgentUserGroup.OwnedArchiveItem(
+				ref.name, []byte(values[ref.name]), 0o600, tar.TypeReg))
+		}
+	}
+	if len(files) > 0 {
+		files = append(archive.Archive{
+			spec.AgentUserGroup.OwnedArchiveItem(".", nil, 0o700, tar.TypeDir),
+		}, files...)
+		spec.SecretArchives = []cproto.RunArchive{{Path: Dir, Archive: files}}
+	}
+
+	var redacted []string
+	for _, v := range values {
+		redacted = append(redacted, v)
+	}
+	defaultRedactor.register(model.TaskID(spec.TaskID), allocationID, redacted)
+	return nil
+}
+
+// lookup returns the values of the referenced secrets by name.
+func lookup(
+	ctx context.Context, refs []reference, ownerID *model.UserID, workspaceID int,
+) (map[string]string, error) {
+	var names []string
+	for _, ref := range refs {
+		names = append(names, ref.name)
+	}
+	var candidates []Secret
+	q := db.Bun().NewSelect().Model(&candidates).
+		Where("name IN (?)", bun.In(names)).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			q = q.WhereOr("scope = ?", ScopeCluster)
+			if ownerID != nil {
+				q = q.WhereOr("scope = ? AND user_id = ?", ScopeUser, *ownerID)
+			}
+			if workspaceID != 0 {
+				q = q.WhereOr("scope = ? AND workspace_id = ?", ScopeWorkspace, workspaceID)
+			}
+			return q
+		})
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("getting secrets: %w", err)
+	}
+
+	precedence := map[Scope]int{ScopeUser: 0, ScopeWorkspace: 1, ScopeCluster: 2}
+	sort.Slice(candidates, func(i, j int) bool {
+		return precedence[candidates[i].Scope] < precedence[candidates[j].Scope]
+	})
+	values := map[string]string{}
+	for _, c := range candidates {
+		if _, ok := values[c.Name]; ok {
+			continue
+		}
+		value, err := decrypt(c.Ciphertext)
+		if err != nil {
+			return nil, fmt.Errorf("secret %q: %w", c.Name, err)
+		}
+		values[c.Name] = value
+	}
+	for _, ref := range refs {
+		if _, ok := values[ref.name]; !ok {
+			return nil, fmt.Errorf(
+				"secret %q referenced by environment variable %s does not exist or is not visible to the task",
+				ref.name, ref.envVar)
+		}
+	}
+	return values, nil
+}
+
+// Release stops redacting the secrets of an allocation from the logs of its task.
+func Release(taskID model.TaskID, allocationID model.AllocationID) {
+	defaultRedactor.release(taskID, allocationID)
+}
+
+// Redact replaces the values of the secrets resolved for running tasks in their logs.
+func Redact(logs []*model.TaskLog) {
+	defaultRedactor.redact(logs)
+}
+
+var defaultRedactor = newRedactor()
+
+// redactor tracks the secret values of each running allocation by task.
+type redactor struct {
+	mu     sync.RWMutex
+	values map[model.TaskID]map[model.AllocationID][]string
+}
+
+func newRedactor() *redactor {
+	return &redactor{values: map[model.TaskID]map[model.AllocationID][]string{}}
+}
+
+func (r *redactor) register(taskID model.TaskID, allocationID model.AllocationID, values []string) {
+	var redacted []string
+	for _, v := range values {
+		if len(v) >= minRedactedLength {
+			redacted = append(redacted, v)
+		}
+	}
+	if len(redacted) == 0 {
+		return
+	}
+	// Replace longer values first so that a value containing another is hidden entirely.
+	sort.Slice(redacted, func(i, j int) bool { return len(redacted[i]) > len(redacted[j]) })
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.values[taskID] == nil {
+		r.values[taskID] = map[model.AllocationID][]string{}
+	}
+	r.values[taskID][allocationID] = redacted
+}
+
+func (r *redactor) release(taskID model.TaskID, allocationID model.AllocationID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.values[taskID], allocationID)
+	if len(r.values[taskID]) == 0 {
+		delete(r.values, taskID)
+	}
+}
+
+func (r *redactor) redact(logs []*model.TaskLog) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.values) == 0 {
+		return
+	}
+	for _, l := range logs {
+		for _, values := range r.values[model.TaskID(l.TaskID)] {
+			for _, v := range values {
+				l.Log = strings.ReplaceAll(l.Log, v, redactedValue)
+			}
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Package secrets stores credentials that tasks reference by name, encrypted with the master key,
+// and resolves those references when tasks launch.
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+// Scope is who can reference a secret, matching the enum secret_scope in Postgres.
+type Scope string
+
+const (
+	// ScopeUser secrets are referenced by the tasks of one user.
+	ScopeUser Scope = "USER"
+	// ScopeWorkspace secrets are referenced by the tasks of one workspace.
+	ScopeWorkspace Scope = "WORKSPACE"
+	// ScopeCluster secrets are referenced by every task.
+	ScopeCluster Scope = "CLUSTER"
+)
+
+// ErrNotEnabled is returned when secrets are used without a master key.
+var ErrNotEnabled = errors.New("secrets are not enabled, set secrets.master_key in the master config")
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ValidateName checks that name can be referenced by tasks and used as a file name.
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf(
+			"secret name %q must start with a letter or digit and contain only letters, digits, '_', '.' or '-'",
+			name)
+	}
+	return nil
+}
+
+// Secret is a row of secrets. Its value is only ever held encrypted.
+type Secret struct {
+	bun.BaseModel `bun:"table:secrets"`
+
+	ID          int           `bun:"id,pk,autoincrement" json:"id"`
+	Name        string        `bun:"name" json:"name"`
+	Scope       Scope         `bun:"scope" json:"scope"`
+	UserID      *model.UserID `bun:"user_id" json:"user_id"`
+	WorkspaceID *int          `bun:"workspace_id" json:"workspace_id"`
+	Ciphertext  []byte        `bun:"ciphertext" json:"-"`
+	CreatedBy   *model.UserID `bun:"created_by" json:"created_by"`
+	CreatedAt   time.Time     `bun:"created_at" json:"created_at"`
+	UpdatedAt   time.Time     `bun:"updated_at" json:"updated_at"`
+}
+
+var (
+	aeadMu sync.RWMutex
+	aead   cipher.AEAD
+)
+
+// SetMasterKey sets the key secrets are encrypted with. Until it is called, or if key is nil,
+// secrets can't be created or resolved.
+func SetMasterKey(key []byte) error {
+	aeadMu.Lock()
+	defer aeadMu.Unlock()
+	if key == nil {
+		aead = nil
+		return nil
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return fmt.Errorf("creating secrets cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return fmt.Errorf("creating secrets cipher: %w", err)
+	}
+	aead = gcm
+	return nil
+}
+
+// Enabled returns whether a master key is set.
+func Enabled() bool {
+	aeadMu.RLock()
+	defer aeadMu.RUnlock()
+	return aead != nil
+}
+
+func encrypt(value string) ([]byte, error) {
+	aeadMu.RLock()
+	defer aeadMu.RUnlock()
+	if aead == nil {
+		return nil, ErrNotEnabled
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, []byte(value), nil), nil
+}
+
+func decrypt(ciphertext []byte) (string, error) {
+	aeadMu.RLock()
+	defer aeadMu.RUnlock()
+	if aead == nil {
+		return "", ErrNotEnabled
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return "", errors.New("secret ciphertext is too short")
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		// This happens when the master key changed since the secret was stored.
+		return "", fmt.Errorf("decrypting secret: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// Create stores a new secret with the given value and sets its ID.
+func Create(ctx context.Context, s *Secret, value string) error {
+	if err := ValidateName(s.Name); err != nil {
+		return err
+	}
+	ciphertext, err := encrypt(value)
+	if err != nil {
+		return err
+	}
+	s.Ciphertext = ciphertext
+	now := time.Now().UTC()
+	s.CreatedAt, s.UpdatedAt = now, now
+	if _, err := db.Bun().NewInsert().Model(s).Returning("id").Exec(ctx); err != nil {
+		return fmt.Errorf("adding secret %q: %w", s.Name, db.MatchSentinelError(err))
+	}
+	return nil
+}
+
+// ByID returns a secret, or db.ErrNotFound.
+func ByID(ctx context.Context, id int) (*Secret, error) {
+	var s Secret
+	if err := db.Bun().NewSelect().Model(&s).Where("id = ?", id).Scan(ctx); err != nil {
+		return nil, fmt.Errorf("getting secret %d: %w", id, db.MatchSentinelError(err))
+	}
+	return &s, nil
+}
+
+// List returns the secrets a user can reference: their own, those of the given workspaces and
+// those of the cluster, ordered by scope and name.
+func List(ctx context.Context, userID model.UserID, workspaceIDs []int) ([]Secret, error) {
+	secrets := []Secret{}
+	q := db.Bun().NewSelect().Model(&secrets).
+		WhereOr("scope = ?", ScopeCluster).
+		WhereOr("scope = ? AND user_id = ?", ScopeUser, userID)
+	if len(workspaceIDs) > 0 {
+		q = q.WhereOr("scope = ? AND workspace_id IN (?)", ScopeWorkspace, bun.In(workspaceIDs))
+	}
+	if err := q.Order("scope", "workspace_id", "name").Scan(ctx); err != nil {
+		return nil, fmt.Errorf("listing secrets: %w", err)
+	}
+	return secrets, nil
+}
+
+// UpdateValue replaces the value of a secret.
+func UpdateValue(ctx context.Context, id int, value string) error {
+	ciphertext, err := encrypt(value)
+	if err != nil {
+		return err
+	}
+	if err := db.MustHaveAffectedRows(db.Bun().NewUpdate().Table("secrets").
+		Set("ciphertext = ?", ciphertext).
+		Set("updated_at = ?", time.Now().UTC()).
+		Where("id = ?", id).
+		Exec(ctx)); err != nil {
+		return fmt.Errorf("updating secret %d: %w", id, err)
+	}
+	return nil
+}
+
+// Delete deletes a secret.
+func Delete(ctx context.Context, id int) error {
+	if err := db.MustHaveAffectedRows(
+		db.Bun().NewDelete().Table("secrets").Where("id = ?", id).Exec(ctx)); err != nil {
+		return fmt.Errorf("deleting secret %d: %w", id, err)
+	}
+	return nil
+}
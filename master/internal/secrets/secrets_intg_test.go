@@ -0,0 +1,126 @@
+//go:build integration
+// +build integration
+
+package secrets
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/pkg/etc"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
+	"github.com/determined-ai/determined/master/pkg/tasks"
+)
+
+func TestResolve(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, etc.SetRootPath(db.RootFromDB))
+	pgDB := db.MustResolveTestPostgres(t)
+	db.MustMigrateTestPostgres(t, pgDB, db.MigrationsFromDB)
+	require.NoError(t, SetMasterKey(testKey(1)))
+	defer func() { require.NoError(t, SetMasterKey(nil)) }()
+
+	owner := db.RequireMockUser(t, pgDB)
+	other := db.RequireMockUser(t, pgDB)
+	workspaceID := db.RequireMockWorkspaceID(t, pgDB)
+
+	create := func(name string, scope Scope, userID *model.UserID, workspaceID *int, value string) *Secret {
+		s := &Secret{Name: name, Scope: scope, UserID: userID, WorkspaceID: workspaceID}
+		require.NoError(t, Create(ctx, s, value))
+		return s
+	}
+	prefix := filepath.Base(t.Name()) + "-" + owner.Username[:8]
+	token := prefix + "-token"
+	creds := prefix + "-creds"
+	create(token, ScopeCluster, nil, nil, "cluster-token")
+	create(token, ScopeWorkspace, nil, &workspaceID, "workspace-token")
+	mine := create(token, ScopeUser, &owner.ID, nil, "owner-token")
+	create(token, ScopeUser, &other.ID, nil, "other-token")
+	create(creds, ScopeWorkspace, nil, &workspaceID, `{"key": "workspace-creds"}`)
+
+	err := Create(ctx, &Secret{Name: token, Scope: ScopeUser, UserID: &owner.ID}, "again")
+	require.ErrorIs(t, err, db.ErrDuplicateRecord)
+
+	spec := func(userID model.UserID, workspaceID int) *tasks.TaskSpec {
+		envVars := []string{"A=1", "TOKEN=${secret:" + token + "}", "CREDS=${secret_file:" + creds + "}"}
+		return &tasks.TaskSpec{
+			TaskID:       "task-" + prefix,
+			Owner:        &model.User{ID: userID},
+			WorkspaceID:  workspaceID,
+			ExtraEnvVars: map[string]string{"B": "2"},
+			Environment: expconf.EnvironmentConfig{
+				RawEnvironmentVariables: &expconf.EnvironmentVariablesMapV0{
+					RawCPU: envVars, RawCUDA: envVars, RawROCM: envVars,
+				},
+			},
+		}
+	}
+
+	// The owner's secret wins over the workspace's, which wins over the cluster's.
+	s := spec(owner.ID, workspaceID)
+	require.NoError(t, Resolve(ctx, s, "alloc-1"))
+	defer Release(model.TaskID(s.TaskID), "alloc-1")
+	require.Equal(t, []string{"A=1"}, s.Environment.EnvironmentVariables().RawCPU)
+	require.Equal(t, map[string]string{"B": "2"}, s.ExtraEnvVars)
+	require.Equal(t, map[string]string{
+		"TOKEN": "owner-token",
+		"CREDS": filepath.Join(Dir, creds),
+	}, s.SecretEnvVars)
+	require.Equal(t, "owner-token", s.EnvVars()["TOKEN"])
+	require.Empty(t, s.ExtraArchives)
+	require.Len(t, s.SecretArchives, 1)
+	require.Equal(t, Dir, s.SecretArchives[0].Path)
+	require.Equal(t, `{"key": "workspace-creds"}`, string(s.SecretArchives[0].Archive[1].Content))
+
+	logs := []*model.TaskLog{{TaskID: s.TaskID, Log: "printing owner-token"}}
+	Redact(logs)
+	require.Equal(t, "printing ********", logs[0].Log)
+
+	// Without a secret of its own, a task gets its workspace's.
+	require.NoError(t, Delete(ctx, mine.ID))
+	s = spec(owner.ID, workspaceID)
+	require.NoError(t, Resolve(ctx, s, "alloc-2"))
+	Release(model.TaskID(s.TaskID), "alloc-2")
+	require.Equal(t, "workspace-token", s.SecretEnvVars["TOKEN"])
+
+	// Outside the workspace, only the cluster's secret is visible.
+	s = spec(owner.ID, 0)
+	s.Environment.RawEnvironmentVariables.RawCPU = []string{"TOKEN=${secret:" + token + "}"}
+	s.Environment.RawEnvironmentVariables.RawCUDA = nil
+	s.Environment.RawEnvironmentVariables.RawROCM = nil
+	require.NoError(t, Resolve(ctx, s, "alloc-3"))
+	Release(model.TaskID(s.TaskID), "alloc-3")
+	require.Equal(t, "cluster-token", s.SecretEnvVars["TOKEN"])
+
+	// References to missing secrets keep the task from starting.
+	require.ErrorContains(t, Resolve(ctx, spec(owner.ID, 0), "alloc-4"), creds)
+
+	// Users see their own secrets and those of their workspaces and the cluster.
+	listed, err := List(ctx, owner.ID, []int{workspaceID})
+	require.NoError(t, err)
+	var names []string
+	var workspaceToken int
+	for _, l := range listed {
+		if l.Name == token || l.Name == creds {
+			names = append(names, string(l.Scope)+"/"+l.Name)
+		}
+		if l.Name == token && l.Scope == ScopeWorkspace {
+			workspaceToken = l.ID
+		}
+	}
+	require.ElementsMatch(t, []string{
+		"WORKSPACE/" + token, "WORKSPACE/" + creds, "CLUSTER/" + token,
+	}, names)
+	require.NoError(t, UpdateValue(ctx, workspaceToken, "rotated"))
+	s = spec(owner.ID, workspaceID)
+	require.NoError(t, Resolve(ctx, s, "alloc-5"))
+	Release(model.TaskID(s.TaskID), "alloc-5")
+	require.Equal(t, "rotated", s.SecretEnvVars["TOKEN"])
+	require.ErrorIs(t, UpdateValue(ctx, -1, "rotated"), db.ErrNotFound)
+	require.ErrorIs(t, Delete(ctx, -1), db.ErrNotFound)
+}
@@ -0,0 +1,84 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func testKey(b byte) []byte {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = b
+	}
+	return key
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	defer func() { require.NoError(t, SetMasterKey(nil)) }()
+
+	require.NoError(t, SetMasterKey(nil))
+	_, err := encrypt("hf_token")
+	require.ErrorIs(t, err, ErrNotEnabled)
+
+	require.NoError(t, SetMasterKey(testKey(1)))
+	a, err := encrypt("hf_token")
+	require.NoError(t, err)
+	b, err := encrypt("hf_token")
+	require.NoError(t, err)
+	require.NotEqual(t, a, b, "nonces must not be reused")
+	require.NotContains(t, string(a), "hf_token")
+
+	value, err := decrypt(a)
+	require.NoError(t, err)
+	require.Equal(t, "hf_token", value)
+
+	// Values can't be read back with another key.
+	require.NoError(t, SetMasterKey(testKey(2)))
+	_, err = decrypt(a)
+	require.Error(t, err)
+}
+
+func TestParseReferences(t *testing.T) {
+	refs, rest, err := parseReferences([]string{
+		"HF_TOKEN=${secret:hf-token}",
+		"GOOGLE_APPLICATION_CREDENTIALS=${secret_file:gcs.json}",
+		"PLAIN=value",
+		"NOT_WHOLE=prefix-${secret:hf-token}",
+		"NO_VALUE",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []reference{
+		{envVar: "HF_TOKEN", name: "hf-token"},
+		{envVar: "GOOGLE_APPLICATION_CREDENTIALS", name: "gcs.json", file: true},
+	}, refs)
+	require.Equal(t, []string{"PLAIN=value", "NOT_WHOLE=prefix-${secret:hf-token}", "NO_VALUE"}, rest)
+
+	_, _, err = parseReferences([]string{"BAD=${secret_file:../etc/passwd}"})
+	require.Error(t, err)
+}
+
+func TestRedactor(t *testing.T) {
+	r := newRedactor()
+	r.register("task", "task.1", []string{"abc", "s3cr3t", "s3cr3t-longer"})
+	r.register("task", "task.2", []string{"other-value"})
+
+	logs := []*model.TaskLog{
+		{TaskID: "task", Log: "token=s3cr3t-longer other-value abc"},
+		{TaskID: "another-task", Log: "s3cr3t"},
+	}
+	r.redact(logs)
+	// Values too short to redact are left alone.
+	require.Equal(t, "token=******** ******** abc", logs[0].Log)
+	require.Equal(t, "s3cr3t", logs[1].Log)
+
+	r.release("task", "task.1")
+	logs = []*model.TaskLog{{TaskID: "task", Log: "s3cr3t other-value"}}
+	r.redact(logs)
+	require.Equal(t, "s3cr3t ********", logs[0].Log)
+
+	r.release("task", "task.2")
+	require.Empty(t, r.values)
+}
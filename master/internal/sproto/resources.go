@@ -38,6 +38,8 @@ const (
 	Assigned ResourcesState = "ASSIGNED"
 	// Pulling state means that the resources are pulling container images.
 	Pulling ResourcesState = "PULLING"
+	// Staging state means that the resources are staging datasets into a local cache.
+	Staging ResourcesState = "STAGING"
 	// Starting state means the service running on the resources is being started.
 	Starting ResourcesState = "STARTING"
 	// Running state means that the service on the resources is running.
@@ -56,6 +58,8 @@ func FromContainerState(state cproto.State) ResourcesState {
 		return Assigned
 	case cproto.Pulling:
 		return Pulling
+	case cproto.Staging:
+		return Staging
 	case cproto.Starting:
 		return Starting
 	case cproto.Running:
@@ -34,9 +34,15 @@ type (
 		IsUserVisible bool
 		State         SchedulingState
 		Name          string
+		// WorkspaceID is the workspace of the task, whose binding to the resource pool may cap the
+		// slots of its tasks. Zero if the task is not capped.
+		WorkspaceID int
 
 		// Resource configuration.
-		SlotsNeeded         int
+		SlotsNeeded int
+		// SlotFraction is the fraction of a slot that is needed, if less than one. SlotsNeeded is
+		// then 1, which resource pools that time-slice their GPUs reduce to some of the slices.
+		SlotFraction        float64
 		ResourcePool        string
 		FittingRequirements FittingRequirements
 
@@ -291,6 +297,9 @@ type (
 const (
 	// ResourcesTypeEnvVar is the name of the env var indicating the resource type to a task.
 	ResourcesTypeEnvVar = "DET_RESOURCES_TYPE"
+	// NumContainersEnvVar is the name of the env var indicating how many containers an
+	// allocation spans; commands and shells only rendezvous when it is more than one.
+	NumContainersEnvVar = "DET_NUM_CONTAINERS"
 	// SlurmRendezvousIfaceEnvVar is the name of the env var for indicating the net iface on which
 	// to rendezvous (horovodrun will use the IPs of the nodes on this interface to launch).
 	SlurmRendezvousIfaceEnvVar = "DET_SLURM_RENDEZVOUS_IFACE"
@@ -43,6 +43,13 @@ type (
 		ResourcePool string
 		JobID        model.JobID
 	}
+
+	// SetWorkspaceMaxSlots sets the maximum number of slots that the tasks of each workspace can
+	// consume in a resource pool, replacing the maximums it had before.
+	SetWorkspaceMaxSlots struct {
+		ResourcePool string
+		MaxSlots     map[int]int
+	}
 )
 
 // Message returns the textual content of this log message.
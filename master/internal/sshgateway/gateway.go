@@ -0,0 +1,399 @@
+// Package sshgateway is an SSH server on the master that routes `ssh <shell-id>@<master>` to the
+// sshd of the shell, so that plain ssh clients, IDEs and rsync reach shells without the CLI's
+// websocket tunnel.
+package sshgateway
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	sshlib "golang.org/x/crypto/ssh"
+
+	"github.com/determined-ai/determined/master/internal/user"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/ssh"
+)
+
+// auditLogType marks the log entries of gateway sessions, alongside the echo_audit_log entries
+// of API requests.
+const auditLogType = "ssh_audit_log"
+
+// dialTimeout bounds connecting to the sshd of a task.
+const dialTimeout = 30 * time.Second
+
+// handshakeTimeout bounds the handshake of clients, so that clients that stall it don't hold
+// connections open.
+var handshakeTimeout = 30 * time.Second
+
+const (
+	userIDExtension      = "determined-user-id"
+	fingerprintExtension = "determined-key-fingerprint"
+)
+
+// Target is the sshd of a task that a session is routed to.
+type Target struct {
+	TaskID model.TaskID
+	// Addr is the host:port the sshd is reachable at from the master.
+	Addr string
+	// User is the user to log in to the sshd as.
+	User string
+	// Signer authenticates the gateway to the sshd.
+	Signer sshlib.Signer
+}
+
+// ResolveFunc returns the sshd that a user reaches by logging in as name, such as a shell ID. It
+// returns an error if the user may not reach it.
+type ResolveFunc func(ctx context.Context, curUser model.User, name string) (*Target, error)
+
+// Gateway authenticates users by their public keys or by certificates it signed, and proxies
+// their sessions to the sshd of their tasks.
+type Gateway struct {
+	config  *sshlib.ServerConfig
+	hostKey sshlib.Signer
+	ca      *ssh.CertAuthority
+	certTTL time.Duration
+	resolve ResolveFunc
+	syslog  *logrus.Entry
+}
+
+// New returns a gateway whose host and certificate authority keys are loaded from the database,
+// or generated on first use.
+func New(ctx context.Context, certTTL time.Duration, resolve ResolveFunc) (*Gateway, error) {
+	hostKeyBytes, err := loadOrGenerateKey(ctx, hostKeyKind)
+	if err != nil {
+		return nil, err
+	}
+	hostKey, err := sshlib.ParsePrivateKey(hostKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing ssh gateway host key: %w", err)
+	}
+	caKeyBytes, err := loadOrGenerateKey(ctx, userCAKind)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := ssh.NewCertAuthority(caKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Gateway{
+		hostKey: hostKey,
+		ca:      ca,
+		certTTL: certTTL,
+		resolve: resolve,
+		syslog:  logrus.WithField("component", "ssh-gateway"),
+	}
+	g.config = &sshlib.ServerConfig{PublicKeyCallback: g.authenticate}
+	g.config.AddHostKey(hostKey)
+	return g, nil
+}
+
+// HostKey returns the public host key of the gateway, for clients' known_hosts.
+func (g *Gateway) HostKey() sshlib.PublicKey {
+	return g.hostKey.PublicKey()
+}
+
+// CAPublicKey returns the public key of the authority that signs user certificates.
+func (g *Gateway) CAPublicKey() sshlib.PublicKey {
+	return g.ca.PublicKey()
+}
+
+// SignUserKey returns a short-lived certificate for a public key in the authorized_keys format
+// that logs in to the gateway as curUser.
+func (g *Gateway) SignUserKey(curUser model.User, authorizedKey string) (*sshlib.Certificate, error) {
+	key, _, err := ParsePublicKey(authorizedKey)
+	if err != nil {
+		return nil, err
+	}
+	return g.ca.SignUserKey(key, curUser.Username, g.certTTL)
+}
+
+// authenticate accepts certificates signed by the gateway and the public keys users uploaded.
+func (g *Gateway) authenticate(conn sshlib.ConnMetadata, key sshlib.PublicKey) (*sshlib.Permissions, error) {
+	ctx := context.Background()
+	var u *model.User
+	extensions := map[string]string{}
+	if cert, ok := key.(*sshlib.Certificate); ok {
+		principal, err := g.ca.CheckUserCert(cert)
+		if err != nil {
+			return nil, err
+		}
+		if u, err = user.ByUsername(ctx, principal); err != nil {
+			return nil, fmt.Errorf("looking up user %q: %w", principal, err)
+		}
+	} else {
+		userID, err := userByKey(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("looking up key %s: %w", sshlib.FingerprintSHA256(key), err)
+		}
+		fu, err := user.ByID(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("looking up user %d: %w", userID, err)
+		}
+		fuser := fu.ToUser()
+		u = &fuser
+		extensions[fingerprintExtension] = sshlib.FingerprintSHA256(key)
+	}
+	if !u.Active {
+		return nil, fmt.Errorf("user %q is not active", u.Username)
+	}
+	extensions[userIDExtension] = strconv.Itoa(int(u.ID))
+	return &sshlib.Permissions{Extensions: extensions}, nil
+}
+
+// Serve accepts connections on l until it is closed.
+func (g *Gateway) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go g.handle(conn)
+	}
+}
+
+func (g *Gateway) handle(nConn net.Conn) {
+	defer func() {
+		if err := nConn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			g.syslog.WithError(err).Debug("error closing ssh connection")
+		}
+	}()
+	ctx := context.Background()
+	remoteIP, _, _ := net.SplitHostPort(nConn.RemoteAddr().String())
+	log := g.syslog.WithFields(logrus.Fields{"type": auditLogType, "remote_ip": remoteIP})
+
+	if err := nConn.SetDeadline(time.Now().Add(handshakeTimeout)); err != nil {
+		log.WithError(err).Debug("setting ssh handshake deadline")
+		return
+	}
+	conn, chans, reqs, err := sshlib.NewServerConn(nConn, g.config)
+	if err != nil {
+		log.WithField("unauthorized", true).Infof("ssh handshake failed: %s", err)
+		return
+	}
+	if err := nConn.SetDeadline(time.Time{}); err != nil {
+		log.WithError(err).Debug("clearing ssh handshake deadline")
+		return
+	}
+	log = log.WithFields(logrus.Fields{
+		"session_id": hex.EncodeToString(conn.SessionID()[:8]),
+		"task_id":    conn.User(),
+	})
+
+	u, err := g.sessionUser(ctx, conn.Permissions)
+	if err != nil {
+		log.WithError(err).Error("looking up user of ssh session")
+		reject(conn, chans, reqs, sshlib.ConnectionFailed, "internal error")
+		return
+	}
+	log = log.WithField("determined_user", u.Username)
+
+	target, err := g.resolve(ctx, *u, conn.User())
+	if err != nil {
+		log.WithField("unauthorized", true).Infof("rejected ssh session: %s", err)
+		reject(conn, chans, reqs, sshlib.Prohibited, err.Error())
+		return
+	}
+	upstream, upChans, upReqs, err := dial(target)
+	if err != nil {
+		log.WithError(err).Warnf("connecting ssh session to %s", target.Addr)
+		reject(conn, chans, reqs, sshlib.ConnectionFailed,
+			fmt.Sprintf("connecting to %s: %s", conn.User(), err))
+		return
+	}
+
+	start := time.Now()
+	log.WithField("unauthorized", false).Info("opened ssh session")
+	// Global requests of the sshd, such as its host keys, are about a connection the client
+	// doesn't see, so they aren't passed on.
+	go sshlib.DiscardRequests(upReqs)
+	go forwardGlobalRequests(reqs, upstream)
+	go forwardChannels(upChans, conn, nil)
+	go forwardChannels(chans, upstream, log)
+
+	// Whichever side disconnects first ends the session.
+	go func() {
+		_ = upstream.Wait()
+		_ = conn.Close()
+	}()
+	_ = conn.Wait()
+	_ = upstream.Close()
+	log.Infof("closed ssh session after %s", time.Since(start).Round(time.Second))
+}
+
+// sessionUser returns the user that authenticate accepted.
+func (g *Gateway) sessionUser(ctx context.Context, perms *sshlib.Permissions) (*model.User, error) {
+	id, err := strconv.Atoi(perms.Extensions[userIDExtension])
+	if err != nil {
+		return nil, err
+	}
+	if fingerprint := perms.Extensions[fingerprintExtension]; fingerprint != "" {
+		if err := markKeyUsed(ctx, fingerprint); err != nil {
+			return nil, err
+		}
+	}
+	fu, err := user.ByID(ctx, model.UserID(id))
+	if err != nil {
+		return nil, err
+	}
+	u := fu.ToUser()
+	return &u, nil
+}
+
+// dial connects to the sshd of a task. The sshd generates its host key when the task starts, so
+// it can't be verified; it's reached over the cluster network, like the websocket proxy does.
+func dial(target *Target) (sshlib.Conn, <-chan sshlib.NewChannel, <-chan *sshlib.Request, error) {
+	nConn, err := net.DialTimeout("tcp", target.Addr, dialTimeout)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	conn, chans, reqs, err := sshlib.NewClientConn(nConn, target.Addr, &sshlib.ClientConfig{
+		User:            target.User,
+		Auth:            []sshlib.AuthMethod{sshlib.PublicKeys(target.Signer)},
+		HostKeyCallback: sshlib.InsecureIgnoreHostKey(), //nolint:gosec
+		Timeout:         dialTimeout,
+	})
+	if err != nil {
+		_ = nConn.Close()
+		return nil, nil, nil, err
+	}
+	return conn, chans, reqs, nil
+}
+
+// reject refuses every channel of a connection with message until the client disconnects.
+func reject(
+	conn sshlib.Conn, chans <-chan sshlib.NewChannel, reqs <-chan *sshlib.Request,
+	reason sshlib.RejectionReason, message string,
+) {
+	go sshlib.DiscardRequests(reqs)
+	for nc := range chans {
+		if err := nc.Reject(reason, message); err != nil {
+			break
+		}
+	}
+	_ = conn.Close()
+}
+
+func forwardGlobalRequests(reqs <-chan *sshlib.Request, dst sshlib.Conn) {
+	for r := range reqs {
+		ok, payload, err := dst.SendRequest(r.Type, r.WantReply, r.Payload)
+		if err != nil {
+			ok = false
+		}
+		if r.WantReply {
+			_ = r.Reply(ok, payload)
+		}
+	}
+}
+
+// forwardChannels opens each channel opened on one side of a session on the other side, and
+// copies between them. Channels opened by the client are recorded in log.
+func forwardChannels(chans <-chan sshlib.NewChannel, dst sshlib.Conn, log *logrus.Entry) {
+	for nc := range chans {
+		go func(nc sshlib.NewChannel) {
+			if log != nil {
+				logChannel(log, nc)
+			}
+			dstCh, dstReqs, err := dst.OpenChannel(nc.ChannelType(), nc.ExtraData())
+			if err != nil {
+				var openErr *sshlib.OpenChannelError
+				if errors.As(err, &openErr) {
+					_ = nc.Reject(openErr.Reason, openErr.Message)
+				} else {
+					_ = nc.Reject(sshlib.ConnectionFailed, err.Error())
+				}
+				return
+			}
+			srcCh, srcReqs, err := nc.Accept()
+			if err != nil {
+				_ = dstCh.Close()
+				return
+			}
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				pipe(dstCh, srcCh, srcReqs, log)
+			}()
+			go func() {
+				defer wg.Done()
+				pipe(srcCh, dstCh, dstReqs, nil)
+			}()
+			wg.Wait()
+		}(nc)
+	}
+}
+
+// pipe copies the data and requests of src to dst until src is closed, then closes dst.
+func pipe(dst, src sshlib.Channel, srcReqs <-chan *sshlib.Request, log *logrus.Entry) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(dst, src)
+		_ = dst.CloseWrite()
+	}()
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(dst.Stderr(), src.Stderr())
+	}()
+	for r := range srcReqs {
+		if log != nil {
+			logRequest(log, r)
+		}
+		ok, err := dst.SendRequest(r.Type, r.WantReply, r.Payload)
+		if r.WantReply {
+			_ = r.Reply(ok && err == nil, nil)
+		}
+	}
+	wg.Wait()
+	_ = dst.Close()
+}
+
+// logChannel records a channel the client opened, with the destination of port forwards.
+func logChannel(log *logrus.Entry, nc sshlib.NewChannel) {
+	if nc.ChannelType() != "direct-tcpip" {
+		log.Infof("opened ssh %s channel", nc.ChannelType())
+		return
+	}
+	var forward struct {
+		Host     string
+		Port     uint32
+		OrigHost string
+		OrigPort uint32
+	}
+	if err := sshlib.Unmarshal(nc.ExtraData(), &forward); err != nil {
+		log.Infof("opened ssh %s channel", nc.ChannelType())
+		return
+	}
+	log.Infof("opened ssh port forward to %s", net.JoinHostPort(forward.Host, fmt.Sprint(forward.Port)))
+}
+
+// logRequest records the commands and subsystems the client runs.
+func logRequest(log *logrus.Entry, r *sshlib.Request) {
+	switch r.Type {
+	case "shell":
+		log.Info("started ssh shell")
+	case "exec":
+		var exec struct{ Command string }
+		if err := sshlib.Unmarshal(r.Payload, &exec); err == nil {
+			log.Infof("ran ssh command: %s", exec.Command)
+		}
+	case "subsystem":
+		var subsystem struct{ Name string }
+		if err := sshlib.Unmarshal(r.Payload, &subsystem); err == nil {
+			log.Infof("started ssh subsystem: %s", subsystem.Name)
+		}
+	}
+}
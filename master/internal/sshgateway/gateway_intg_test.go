@@ -0,0 +1,192 @@
+//go:build integration
+// +build integration
+
+package sshgateway
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	sshlib "golang.org/x/crypto/ssh"
+
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/pkg/etc"
+	"github.com/determined-ai/determined/master/pkg/model"
+)
+
+func newSigner(t *testing.T) sshlib.Signer {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := sshlib.NewSignerFromKey(key)
+	require.NoError(t, err)
+	return signer
+}
+
+func authorizedKey(signer sshlib.Signer) string {
+	return string(sshlib.MarshalAuthorizedKey(signer.PublicKey()))
+}
+
+// serveFakeSSHD runs an sshd that accepts key and answers each command with "ran: <command>".
+func serveFakeSSHD(t *testing.T, key sshlib.PublicKey) string {
+	config := &sshlib.ServerConfig{
+		PublicKeyCallback: func(conn sshlib.ConnMetadata, k sshlib.PublicKey) (*sshlib.Permissions, error) {
+			if conn.User() != "det" || string(k.Marshal()) != string(key.Marshal()) {
+				return nil, errors.New("unknown key")
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(newSigner(t))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		for {
+			nConn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := sshlib.NewServerConn(nConn, config)
+				if err != nil {
+					return
+				}
+				go sshlib.DiscardRequests(reqs)
+				for nc := range chans {
+					ch, chReqs, err := nc.Accept()
+					if err != nil {
+						return
+					}
+					go func() {
+						for r := range chReqs {
+							var exec struct{ Command string }
+							if r.Type != "exec" || sshlib.Unmarshal(r.Payload, &exec) != nil {
+								_ = r.Reply(false, nil)
+								continue
+							}
+							_ = r.Reply(true, nil)
+							_, _ = ch.Write([]byte("ran: " + exec.Command))
+							_, _ = ch.SendRequest("exit-status", false, sshlib.Marshal(struct{ Status uint32 }{0}))
+							_ = ch.Close()
+						}
+					}()
+				}
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestGateway(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, etc.SetRootPath(db.RootFromDB))
+	pgDB := db.MustResolveTestPostgres(t)
+	db.MustMigrateTestPostgres(t, pgDB, db.MigrationsFromDB)
+
+	owner := db.RequireMockUser(t, pgDB)
+	other := db.RequireMockUser(t, pgDB)
+
+	shellKey := newSigner(t)
+	sshdAddr := serveFakeSSHD(t, shellKey.PublicKey())
+	resolve := func(ctx context.Context, curUser model.User, name string) (*Target, error) {
+		if name != "shell-1" || curUser.ID != owner.ID {
+			return nil, errors.New("shell " + name + " not found")
+		}
+		return &Target{TaskID: "shell-1", Addr: sshdAddr, User: "det", Signer: shellKey}, nil
+	}
+	g, err := New(ctx, time.Hour, resolve)
+	require.NoError(t, err)
+
+	// The keys of the gateway are generated once and kept.
+	again, err := New(ctx, time.Hour, resolve)
+	require.NoError(t, err)
+	require.Equal(t, g.HostKey().Marshal(), again.HostKey().Marshal())
+	require.Equal(t, g.CAPublicKey().Marshal(), again.CAPublicKey().Marshal())
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = l.Close() }()
+	go func() { _ = g.Serve(l) }()
+
+	run := func(name string, signer sshlib.Signer, command string) (string, error) {
+		client, err := sshlib.Dial("tcp", l.Addr().String(), &sshlib.ClientConfig{
+			User:            name,
+			Auth:            []sshlib.AuthMethod{sshlib.PublicKeys(signer)},
+			HostKeyCallback: sshlib.FixedHostKey(g.HostKey()),
+		})
+		if err != nil {
+			return "", err
+		}
+		defer func() { _ = client.Close() }()
+		session, err := client.NewSession()
+		if err != nil {
+			return "", err
+		}
+		defer func() { _ = session.Close() }()
+		out, err := session.Output(command)
+		return string(out), err
+	}
+
+	// Users log in with the keys they uploaded.
+	ownerKey := newSigner(t)
+	_, err = run("shell-1", ownerKey, "hostname")
+	require.ErrorContains(t, err, "unable to authenticate")
+	key, err := AddKey(ctx, owner.ID, "", authorizedKey(ownerKey)+" laptop")
+	require.NoError(t, err)
+	require.Equal(t, "laptop", key.Name)
+	require.Equal(t, sshlib.FingerprintSHA256(ownerKey.PublicKey()), key.Fingerprint)
+	_, err = AddKey(ctx, other.ID, "", authorizedKey(ownerKey))
+	require.ErrorIs(t, err, db.ErrDuplicateRecord)
+
+	out, err := run("shell-1", ownerKey, "hostname")
+	require.NoError(t, err)
+	require.Equal(t, "ran: hostname", out)
+
+	keys, err := ListKeys(ctx, owner.ID)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.NotNil(t, keys[0].LastUsedAt)
+
+	// Sessions are only routed to tasks the user may reach.
+	otherKey := newSigner(t)
+	_, err = AddKey(ctx, other.ID, "", authorizedKey(otherKey))
+	require.NoError(t, err)
+	_, err = run("shell-1", otherKey, "hostname")
+	require.ErrorContains(t, err, "shell shell-1 not found")
+	_, err = run("shell-2", ownerKey, "hostname")
+	require.ErrorContains(t, err, "shell shell-2 not found")
+
+	// Certificates signed by the gateway log in without uploading the key.
+	certKey := newSigner(t)
+	cert, err := g.SignUserKey(owner, authorizedKey(certKey))
+	require.NoError(t, err)
+	certSigner, err := sshlib.NewCertSigner(cert, certKey)
+	require.NoError(t, err)
+	out, err = run("shell-1", certSigner, "whoami")
+	require.NoError(t, err)
+	require.Equal(t, "ran: whoami", out)
+
+	// Deleted keys no longer log in.
+	require.ErrorIs(t, DeleteKey(ctx, other.ID, key.ID), db.ErrNotFound)
+	require.NoError(t, DeleteKey(ctx, owner.ID, key.ID))
+	_, err = run("shell-1", ownerKey, "hostname")
+	require.ErrorContains(t, err, "unable to authenticate")
+
+	// Clients that stall the handshake are disconnected.
+	handshakeTimeout = 100 * time.Millisecond
+	defer func() { handshakeTimeout = 30 * time.Second }()
+	stalled, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = stalled.Close() }()
+	require.NoError(t, stalled.SetReadDeadline(time.Now().Add(10*time.Second)))
+	_, err = io.ReadAll(stalled)
+	require.NoError(t, err)
+}
@@ -0,0 +1,151 @@
+package sshgateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+	sshlib "golang.org/x/crypto/ssh"
+
+	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/ssh"
+)
+
+// gatewayKeySize is the RSA key size of the host and certificate authority keys of the gateway.
+const gatewayKeySize = 4096
+
+const (
+	hostKeyKind = "host"
+	userCAKind  = "user_ca"
+)
+
+// Key is a row of user_ssh_keys, a public key a user logs in to the gateway with.
+type Key struct {
+	bun.BaseModel `bun:"table:user_ssh_keys"`
+
+	ID          int          `bun:"id,pk,autoincrement" json:"id"`
+	UserID      model.UserID `bun:"user_id" json:"user_id"`
+	Name        string       `bun:"name" json:"name"`
+	PublicKey   string       `bun:"public_key" json:"public_key"`
+	Fingerprint string       `bun:"fingerprint" json:"fingerprint"`
+	CreatedAt   time.Time    `bun:"created_at,nullzero,default:current_timestamp" json:"created_at"`
+	LastUsedAt  *time.Time   `bun:"last_used_at" json:"last_used_at"`
+}
+
+// ParsePublicKey parses a public key in the authorized_keys format, such as the contents of
+// ~/.ssh/id_ed25519.pub. Certificates are not accepted, since they are checked by their
+// authority instead.
+func ParsePublicKey(authorizedKey string) (sshlib.PublicKey, string, error) {
+	key, comment, _, _, err := sshlib.ParseAuthorizedKey([]byte(authorizedKey))
+	if err != nil {
+		return nil, "", fmt.Errorf("parsing public key: %w", err)
+	}
+	if _, ok := key.(*sshlib.Certificate); ok {
+		return nil, "", fmt.Errorf("expected a public key, got a certificate")
+	}
+	return key, comment, nil
+}
+
+// AddKey adds a public key in the authorized_keys format to the keys of a user. If name is empty,
+// the comment of the key, or else its fingerprint, is used. A key can only belong to one user.
+func AddKey(ctx context.Context, userID model.UserID, name, authorizedKey string) (*Key, error) {
+	key, comment, err := ParsePublicKey(authorizedKey)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = comment
+	}
+	if name == "" {
+		name = sshlib.FingerprintSHA256(key)
+	}
+	k := &Key{
+		UserID:      userID,
+		Name:        name,
+		PublicKey:   strings.TrimSpace(string(sshlib.MarshalAuthorizedKey(key))),
+		Fingerprint: sshlib.FingerprintSHA256(key),
+	}
+	if _, err := db.Bun().NewInsert().Model(k).Returning("*").Exec(ctx); err != nil {
+		return nil, db.MatchSentinelError(err)
+	}
+	return k, nil
+}
+
+// ListKeys returns the keys of a user.
+func ListKeys(ctx context.Context, userID model.UserID) ([]Key, error) {
+	keys := []Key{}
+	if err := db.Bun().NewSelect().Model(&keys).
+		Where("user_id = ?", userID).
+		Order("id").
+		Scan(ctx); err != nil {
+		return nil, fmt.Errorf("getting ssh keys of user %d: %w", userID, err)
+	}
+	return keys, nil
+}
+
+// DeleteKey deletes a key of a user.
+func DeleteKey(ctx context.Context, userID model.UserID, id int) error {
+	res, err := db.Bun().NewDelete().Model((*Key)(nil)).
+		Where("id = ?", id).
+		Where("user_id = ?", userID).
+		Exec(ctx)
+	return db.MustHaveAffectedRows(res, err)
+}
+
+// userByKey returns the user a public key belongs to.
+func userByKey(ctx context.Context, key sshlib.PublicKey) (model.UserID, error) {
+	var k Key
+	err := db.Bun().NewSelect().Model(&k).
+		Where("fingerprint = ?", sshlib.FingerprintSHA256(key)).
+		Scan(ctx)
+	if err != nil {
+		return 0, db.MatchSentinelError(err)
+	}
+	return k.UserID, nil
+}
+
+// markKeyUsed records that a key was used to log in.
+func markKeyUsed(ctx context.Context, fingerprint string) error {
+	_, err := db.Bun().NewUpdate().Model((*Key)(nil)).
+		Set("last_used_at = now()").
+		Where("fingerprint = ?", fingerprint).
+		Exec(ctx)
+	return err
+}
+
+// gatewayKey is a row of ssh_gateway_keys.
+type gatewayKey struct {
+	bun.BaseModel `bun:"table:ssh_gateway_keys"`
+
+	Kind       string `bun:"kind,pk"`
+	PrivateKey []byte `bun:"private_key"`
+}
+
+// loadOrGenerateKey returns the PEM encoded private key of a kind, generating it the first time.
+// Masters that start at the same time agree on the key that was inserted first.
+func loadOrGenerateKey(ctx context.Context, kind string) ([]byte, error) {
+	var k gatewayKey
+	switch err := db.Bun().NewSelect().Model(&k).Where("kind = ?", kind).Scan(ctx); {
+	case err == nil:
+		return k.PrivateKey, nil
+	case !errors.Is(db.MatchSentinelError(err), db.ErrNotFound):
+		return nil, fmt.Errorf("loading ssh gateway %s key: %w", kind, err)
+	}
+
+	keys, err := ssh.GenerateKey(gatewayKeySize, nil)
+	if err != nil {
+		return nil, err
+	}
+	k = gatewayKey{Kind: kind, PrivateKey: keys.PrivateKey}
+	if _, err := db.Bun().NewInsert().Model(&k).On("CONFLICT (kind) DO NOTHING").Exec(ctx); err != nil {
+		return nil, fmt.Errorf("saving ssh gateway %s key: %w", kind, err)
+	}
+	if err := db.Bun().NewSelect().Model(&k).Where("kind = ?", kind).Scan(ctx); err != nil {
+		return nil, fmt.Errorf("loading ssh gateway %s key: %w", kind, err)
+	}
+	return k.PrivateKey, nil
+}
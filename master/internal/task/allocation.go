@@ -15,10 +15,12 @@ import (
 
 	"github.com/determined-ai/determined/master/internal/cluster"
 	"github.com/determined-ai/determined/master/internal/db"
+	"github.com/determined-ai/determined/master/internal/egress"
 	"github.com/determined-ai/determined/master/internal/portregistry"
 	"github.com/determined-ai/determined/master/internal/prom"
 	"github.com/determined-ai/determined/master/internal/proxy"
 	"github.com/determined-ai/determined/master/internal/rm"
+	"github.com/determined-ai/determined/master/internal/secrets"
 	"github.com/determined-ai/determined/master/internal/sproto"
 	"github.com/determined-ai/determined/master/internal/task/idle"
 	"github.com/determined-ai/determined/master/internal/task/preemptible"
@@ -654,6 +656,16 @@ func (a *allocation) resourcesAllocated(msg *sproto.ResourcesAllocated) error {
 			portregistry.RestorePort(port)
 		}
 		a.portsRegistered = true
+		// The values of secrets to redact from the task's logs are only kept in memory, so they
+		// are looked up again. A secret changed since the allocation started is redacted with its
+		// new value.
+		spec := a.specifier.ToTaskSpec()
+		if err := secrets.Resolve(context.TODO(), &spec, a.model.AllocationID); err != nil {
+			a.syslog.WithError(err).Warn("resolving secrets to redact from the logs of a restored allocation")
+		}
+		a.closers = append(a.closers, func() {
+			secrets.Release(a.model.TaskID, a.model.AllocationID)
+		})
 		if a.getModelState() == model.AllocationStateRunning {
 			// Restore proxies.
 			if len(a.req.ProxyPorts) > 0 {
@@ -692,10 +704,28 @@ func (a *allocation) resourcesAllocated(msg *sproto.ResourcesAllocated) error {
 			return fmt.Errorf("updating allocation db")
 		}
 
+		if spec.ExtraEnvVars == nil {
+			spec.ExtraEnvVars = map[string]string{}
+		}
 		for portName, port := range a.model.Ports {
 			spec.Environment.RawPorts[portName] = port
 			spec.ExtraEnvVars[portName] = strconv.Itoa(port)
 		}
+		spec.ExtraEnvVars[sproto.NumContainersEnvVar] = strconv.Itoa(len(a.resources))
+
+		// The policy is resolved for each allocation, so that changes to it apply to tasks the
+		// next time they start.
+		spec.Egress, err = egress.Resolve(context.TODO(), spec.WorkspaceID, spec.TaskType)
+		if err != nil {
+			return fmt.Errorf("resolving egress policy: %w", err)
+		}
+
+		if err := secrets.Resolve(context.TODO(), &spec, a.model.AllocationID); err != nil {
+			return fmt.Errorf("resolving secrets: %w", err)
+		}
+		a.closers = append(a.closers, func() {
+			secrets.Release(a.model.TaskID, a.model.AllocationID)
+		})
 
 		for cID, r := range a.resources {
 			if err := r.Start(a.logCtx, spec, sproto.ResourcesRuntimeInfo{
@@ -731,6 +761,8 @@ func (a *allocation) resourcesStateChanged(msg *sproto.ResourcesStateChanged) {
 		if a.model.StartTime == nil {
 			a.markResourcesStarted()
 		}
+	case sproto.Staging:
+		a.setMostProgressedModelState(model.AllocationStateStaging)
 	case sproto.Starting:
 		a.setMostProgressedModelState(model.AllocationStateStarting)
 	case sproto.Running:
@@ -1252,7 +1284,9 @@ func (a *allocation) enrichLog(log *model.TaskLog) *model.TaskLog {
 
 // sendTaskLog is called without a lock.
 func (a *allocation) sendTaskLog(log *model.TaskLog) {
-	tasklogger.Insert(a.enrichLog(log))
+	log = a.enrichLog(log)
+	secrets.Redact([]*model.TaskLog{log})
+	tasklogger.Insert(log)
 }
 
 func (a *allocation) state() AllocationState {
@@ -174,19 +174,30 @@ func (a *TemplateAPIServer) PostTemplate(
 		return nil, permErr
 	}
 
+	configMap := req.Template.Config.AsMap()
+	if _, err := ParseParameters(configMap); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 	// json.Marshal + AsMap is 2x faster than protojson.Marshal or just json.Marshal because
 	// marshaling structpb.Struct is really slow.
-	configBytes, err := json.Marshal(req.Template.Config.AsMap())
+	configBytes, err := json.Marshal(configMap)
 	if err != nil {
 		return nil, err
 	}
 
 	var inserted templatev1.Template
-	err = db.Bun().NewInsert().
-		Model(&model.Template{Name: req.Template.Name, WorkspaceID: workspaceID}).
-		Value("config", "?", string(configBytes)).
-		Returning("*").
-		Scan(ctx, &inserted)
+	err = db.Bun().RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		err := tx.NewInsert().
+			Model(&model.Template{Name: req.Template.Name, WorkspaceID: workspaceID}).
+			Value("config", "?", string(configBytes)).
+			Returning("*").
+			Scan(ctx, &inserted)
+		if err != nil {
+			return err
+		}
+		_, err = addTemplateVersion(ctx, tx, req.Template.Name, workspaceID, configBytes, &user.ID)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create template %s: %w", req.Template.Name, err)
 	}
@@ -221,17 +232,29 @@ func (a *TemplateAPIServer) PatchTemplateConfig(
 		return nil, permErr
 	}
 
-	configBytes, err := json.Marshal(req.Config.AsMap())
+	configMap := req.Config.AsMap()
+	if _, err := ParseParameters(configMap); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	configBytes, err := json.Marshal(configMap)
 	if err != nil {
 		return nil, err
 	}
 
+	// Every update creates a new version; experiments keep pointing at the version they used.
 	var updated templatev1.Template
-	err = db.Bun().NewUpdate().Model(&model.Template{}).
-		Set("config = ?", string(configBytes)).
-		Where("name = ?", req.TemplateName).
-		Returning("*").
-		Scan(ctx, &updated)
+	err = db.Bun().RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		err := tx.NewUpdate().Model(&model.Template{}).
+			Set("config = ?", string(configBytes)).
+			Where("name = ?", req.TemplateName).
+			Returning("*").
+			Scan(ctx, &updated)
+		if err != nil {
+			return err
+		}
+		_, err = addTemplateVersion(ctx, tx, req.TemplateName, tpl.WorkspaceID, configBytes, &user.ID)
+		return err
+	})
 	switch {
 	case errors.Is(err, sql.ErrNoRows):
 		return nil, api.NotFoundErrs("template", req.TemplateName, true)
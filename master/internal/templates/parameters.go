@@ -0,0 +1,269 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/ghodss/yaml"
+)
+
+const (
+	// ParametersKey is the top-level template config key under which parameters are declared.
+	ParametersKey = "parameters"
+	// ParameterValuesKey is the top-level key of a submitted config that holds the values for the
+	// parameters of the template it is merged with.
+	ParameterValuesKey = "template_parameters"
+)
+
+// ParameterType is the type of a template parameter.
+type ParameterType string
+
+const (
+	// ParameterTypeString parameters accept any string.
+	ParameterTypeString ParameterType = "string"
+	// ParameterTypeInt parameters accept integers.
+	ParameterTypeInt ParameterType = "int"
+	// ParameterTypeFloat parameters accept any number.
+	ParameterTypeFloat ParameterType = "float"
+	// ParameterTypeBool parameters accept booleans.
+	ParameterTypeBool ParameterType = "bool"
+)
+
+// Parameter declares a typed variable that is substituted into a template config at submit
+// time. A parameter without a default must be given a value by every submission.
+type Parameter struct {
+	Type        ParameterType `json:"type"`
+	Default     any           `json:"default,omitempty"`
+	Description string        `json:"description,omitempty"`
+}
+
+var parameterNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// wholeParameterRegex matches strings that consist of nothing but a single parameter reference.
+// Those are replaced by the typed value rather than its string representation, so that e.g.
+// `slots_per_trial: "{{ .slots }}"` renders as a number.
+var wholeParameterRegex = regexp.MustCompile(`^\s*\{\{\s*\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}\s*$`)
+
+// coerce converts a raw value into the Go type corresponding to the parameter type.
+func (p Parameter) coerce(v any) (any, error) {
+	switch p.Type {
+	case ParameterTypeString:
+		switch v := v.(type) {
+		case string:
+			return v, nil
+		case float64, bool:
+			return fmt.Sprint(v), nil
+		}
+	case ParameterTypeInt:
+		switch v := v.(type) {
+		case float64:
+			if v == math.Trunc(v) {
+				return int64(v), nil
+			}
+		case int64:
+			return v, nil
+		case string:
+			if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return i, nil
+			}
+		}
+	case ParameterTypeFloat:
+		switch v := v.(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f, nil
+			}
+		}
+	case ParameterTypeBool:
+		switch v := v.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b, nil
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown type %q", p.Type)
+	}
+	return nil, fmt.Errorf("%v is not a valid %s", v, p.Type)
+}
+
+// ParseParameters extracts and validates the parameter declarations of a template config. It
+// returns nil if the template declares no parameters.
+func ParseParameters(config map[string]any) (map[string]Parameter, error) {
+	raw, ok := config[ParametersKey]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	bs, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var params map[string]Parameter
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&params); err != nil {
+		return nil, fmt.Errorf("invalid template parameters: %w", err)
+	}
+	for name, p := range params {
+		if !parameterNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("invalid template parameter name %q", name)
+		}
+		if p.Default == nil {
+			continue
+		}
+		if _, err := p.coerce(p.Default); err != nil {
+			return nil, fmt.Errorf("invalid default for template parameter %q: %w", name, err)
+		}
+	}
+	return params, nil
+}
+
+// resolveParameterValues checks the submitted values against the declared parameters and fills
+// in defaults.
+func resolveParameterValues(
+	params map[string]Parameter, values map[string]any,
+) (map[string]any, error) {
+	var errs []string
+	for name := range values {
+		if _, ok := params[name]; !ok {
+			errs = append(errs, fmt.Sprintf("unknown template parameter %q", name))
+		}
+	}
+	resolved := make(map[string]any, len(params))
+	for name, p := range params {
+		v, ok := values[name]
+		if !ok {
+			if p.Default == nil {
+				errs = append(errs, fmt.Sprintf("template parameter %q is required", name))
+				continue
+			}
+			v = p.Default
+		}
+		coerced, err := p.coerce(v)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("template parameter %q: %s", name, err))
+			continue
+		}
+		resolved[name] = coerced
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return nil, fmt.Errorf("invalid template parameters: %s", strings.Join(errs, "; "))
+	}
+	return resolved, nil
+}
+
+// RenderConfig substitutes parameter values into a template config and drops the parameter
+// declarations. Templates that declare no parameters are returned untouched, so literal `{{`
+// in existing templates keeps working.
+func RenderConfig(config map[string]any, values map[string]any) (map[string]any, error) {
+	params, err := ParseParameters(config)
+	if err != nil {
+		return nil, err
+	}
+	if params == nil {
+		if len(values) > 0 {
+			return nil, fmt.Errorf("template does not declare any parameters")
+		}
+		return config, nil
+	}
+	resolved, err := resolveParameterValues(params, values)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]any, len(config))
+	for k, v := range config {
+		if k == ParametersKey {
+			continue
+		}
+		rendered, err := renderValue(v, resolved)
+		if err != nil {
+			return nil, fmt.Errorf("rendering template field %q: %w", k, err)
+		}
+		out[k] = rendered
+	}
+	return out, nil
+}
+
+func renderValue(v any, values map[string]any) (any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, elem := range v {
+			rendered, err := renderValue(elem, values)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = rendered
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, elem := range v {
+			rendered, err := renderValue(elem, values)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = rendered
+		}
+		return out, nil
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v, nil
+		}
+		if m := wholeParameterRegex.FindStringSubmatch(v); m != nil {
+			if val, ok := values[m[1]]; ok {
+				return val, nil
+			}
+		}
+		tmpl, err := template.New("").Option("missingkey=error").Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		var buf strings.Builder
+		if err := tmpl.Execute(&buf, values); err != nil {
+			return nil, err
+		}
+		return buf.String(), nil
+	default:
+		return v, nil
+	}
+}
+
+// SplitParameterValues removes the template parameter values from a submitted YAML config and
+// returns the remaining config alongside the values. Configs without values, and configs that are
+// not a YAML map, are returned as is for the config parsing to handle.
+func SplitParameterValues(configYAML string) (string, map[string]any, error) {
+	var config map[string]any
+	if err := yaml.Unmarshal([]byte(configYAML), &config); err != nil {
+		return configYAML, nil, nil
+	}
+	raw, ok := config[ParameterValuesKey]
+	if !ok {
+		return configYAML, nil, nil
+	}
+	values, ok := raw.(map[string]any)
+	if raw != nil && !ok {
+		return "", nil, fmt.Errorf("%s must be a map", ParameterValuesKey)
+	}
+	delete(config, ParameterValuesKey)
+	stripped, err := yaml.Marshal(config)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(stripped), values, nil
+}
@@ -0,0 +1,94 @@
+package templates
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderConfig(t *testing.T) {
+	config := map[string]any{
+		"parameters": map[string]any{
+			"image_tag": map[string]any{"type": "string", "default": "latest"},
+			"slots":     map[string]any{"type": "int"},
+			"lr":        map[string]any{"type": "float", "default": 0.1},
+			"debug":     map[string]any{"type": "bool", "default": false},
+		},
+		"environment": map[string]any{
+			"image": "determinedai/environments:{{ .image_tag }}",
+		},
+		"resources":  map[string]any{"slots_per_trial": "{{ .slots }}"},
+		"debug":      "{{.debug}}",
+		"entrypoint": []any{"python3", "train.py", "--lr={{ .lr }}"},
+	}
+
+	rendered, err := RenderConfig(config, map[string]any{"slots": "4"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"environment": map[string]any{"image": "determinedai/environments:latest"},
+		"resources":   map[string]any{"slots_per_trial": int64(4)},
+		"debug":       false,
+		"entrypoint":  []any{"python3", "train.py", "--lr=0.1"},
+	}, rendered)
+
+	_, err = RenderConfig(config, nil)
+	require.ErrorContains(t, err, `template parameter "slots" is required`)
+
+	_, err = RenderConfig(config, map[string]any{"slots": 1.5})
+	require.ErrorContains(t, err, "1.5 is not a valid int")
+
+	_, err = RenderConfig(config, map[string]any{"slots": 1, "nope": 2})
+	require.ErrorContains(t, err, `unknown template parameter "nope"`)
+}
+
+func TestRenderConfigWithoutParameters(t *testing.T) {
+	// Templates without parameters are left alone, even if they contain template syntax.
+	config := map[string]any{"entrypoint": "docker inspect --format '{{.Id}}'"}
+	rendered, err := RenderConfig(config, nil)
+	require.NoError(t, err)
+	require.Equal(t, config, rendered)
+
+	_, err = RenderConfig(config, map[string]any{"x": 1})
+	require.ErrorContains(t, err, "does not declare any parameters")
+}
+
+func TestParseParameters(t *testing.T) {
+	_, err := ParseParameters(map[string]any{
+		"parameters": map[string]any{"x": map[string]any{"type": "int", "default": "abc"}},
+	})
+	require.ErrorContains(t, err, `invalid default for template parameter "x"`)
+
+	_, err = ParseParameters(map[string]any{
+		"parameters": map[string]any{"x-y": map[string]any{"type": "int"}},
+	})
+	require.ErrorContains(t, err, "invalid template parameter name")
+
+	_, err = ParseParameters(map[string]any{
+		"parameters": map[string]any{"x": map[string]any{"type": "int", "typo": 1}},
+	})
+	require.ErrorContains(t, err, "unknown field")
+}
+
+func TestSplitParameterValues(t *testing.T) {
+	config := "name: foo\n"
+	stripped, values, err := SplitParameterValues(config)
+	require.NoError(t, err)
+	require.Equal(t, config, stripped)
+	require.Nil(t, values)
+
+	stripped, values, err = SplitParameterValues(
+		"name: foo\ntemplate_parameters:\n  image_tag: v1\n")
+	require.NoError(t, err)
+	require.Equal(t, config, stripped)
+	require.Equal(t, map[string]any{"image_tag": "v1"}, values)
+
+	_, _, err = SplitParameterValues("template_parameters: [1]\n")
+	require.ErrorContains(t, err, "must be a map")
+
+	// Only the top-level key holds values, not text that mentions it.
+	config = "name: foo\ndescription: set template_parameters\nhp:\n  template_parameters: 1\n"
+	stripped, values, err = SplitParameterValues(config)
+	require.NoError(t, err)
+	require.Equal(t, config, stripped)
+	require.Nil(t, values)
+}
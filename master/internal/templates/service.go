@@ -3,10 +3,14 @@ package templates
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 
 	"github.com/ghodss/yaml"
 	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/determined-ai/determined/master/internal/api"
 	"github.com/determined-ai/determined/master/internal/db"
@@ -29,20 +33,22 @@ func TemplateByName(ctx context.Context, name string) (model.Template, error) {
 	return dest, nil
 }
 
-// UnmarshalTemplateConfig unmarshals the template config into `o` and returns api-ready errors.
+// UnmarshalTemplateConfig renders the template config with the given parameter values,
+// unmarshals it into `o` and returns the template version that was used. Errors are api-ready.
 func UnmarshalTemplateConfig(
 	ctx context.Context,
 	name string,
 	user *model.User,
 	out interface{},
 	disallowUnknownFields bool,
-) error {
+	values map[string]any,
+) (int, error) {
 	tpl, err := TemplateByName(ctx, name)
 	switch {
 	case errors.Is(err, db.ErrNotFound):
-		return api.NotFoundErrs("template", name, true)
+		return 0, api.NotFoundErrs("template", name, true)
 	case err != nil:
-		return err
+		return 0, err
 	}
 
 	permErr, err := AuthZProvider.Get().CanViewTemplate(
@@ -52,20 +58,128 @@ func UnmarshalTemplateConfig(
 	)
 	switch {
 	case err != nil:
-		return err
+		return 0, err
 	case permErr != nil:
-		return api.NotFoundErrs("template", name, true)
+		return 0, api.NotFoundErrs("template", name, true)
+	}
+
+	version, err := LatestTemplateVersion(ctx, db.Bun(), name)
+	if err != nil {
+		return 0, err
+	}
+
+	// Read the config from the immutable version row so the config and the recorded version
+	// always agree, even if the template is updated concurrently.
+	var config map[string]any
+	if version > 0 {
+		v, err := TemplateVersionByNumber(ctx, name, version)
+		if err != nil {
+			return 0, err
+		}
+		config = v.Config
+	} else if err := json.Unmarshal(tpl.Config, &config); err != nil {
+		return 0, fmt.Errorf("json.Unmarshal(template=%s): %w", name, err)
+	}
+	config, err = RenderConfig(config, values)
+	if err != nil {
+		return 0, status.Errorf(codes.InvalidArgument, "template %s: %s", name, err)
+	}
+	rendered, err := json.Marshal(config)
+	if err != nil {
+		return 0, err
 	}
 
 	var opts []yaml.JSONOpt
 	if disallowUnknownFields {
 		opts = append(opts, yaml.DisallowUnknownFields)
 	}
-	err = yaml.Unmarshal(tpl.Config, out, opts...)
+	err = yaml.Unmarshal(rendered, out, opts...)
+	if err != nil {
+		return 0, fmt.Errorf("yaml.Unmarshal(template=%s): %w", name, err)
+	}
+	return version, nil
+}
+
+// LatestTemplateVersion returns the current version of a template, or 0 if the template has
+// never been versioned.
+func LatestTemplateVersion(ctx context.Context, idb bun.IDB, name string) (int, error) {
+	var version int
+	err := idb.NewSelect().Table("template_versions").
+		ColumnExpr("COALESCE(MAX(version), 0)").
+		Where("template_name = ?", name).
+		Scan(ctx, &version)
+	if err != nil {
+		return 0, fmt.Errorf("fetching latest version of template %s: %w", name, err)
+	}
+	return version, nil
+}
+
+// addTemplateVersion records config as the next version of the template in its workspace.
+func addTemplateVersion(
+	ctx context.Context, idb bun.IDB, name string, workspaceID int, config []byte,
+	createdBy *model.UserID,
+) (int, error) {
+	if _, err := idb.NewRaw("SELECT pg_advisory_xact_lock(hashtext(?))",
+		"template_versions/"+name).Exec(ctx); err != nil {
+		return 0, fmt.Errorf("locking versions of template %s: %w", name, err)
+	}
+	latest, err := LatestTemplateVersion(ctx, idb, name)
+	if err != nil {
+		return 0, err
+	}
+	// Versions are deleted with their template, but experiments still record the versions of
+	// deleted templates, so never hand out one of those again.
+	var latestUsed int
+	if err := idb.NewSelect().Table("experiments").
+		ColumnExpr("COALESCE(MAX(template_version), 0)").
+		Where("template_name = ?", name).
+		Scan(ctx, &latestUsed); err != nil {
+		return 0, fmt.Errorf("fetching versions of template %s used by experiments: %w", name, err)
+	}
+	latest = max(latest, latestUsed)
+	_, err = idb.NewInsert().Model(&model.TemplateVersion{
+		TemplateName: name,
+		Version:      latest + 1,
+		WorkspaceID:  workspaceID,
+		CreatedBy:    createdBy,
+	}).
+		Value("config", "?", string(config)).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("recording version of template %s: %w", name, err)
+	}
+	return latest + 1, nil
+}
+
+// TemplateVersions returns every recorded version of a template, newest first.
+func TemplateVersions(ctx context.Context, name string) ([]model.TemplateVersion, error) {
+	versions := []model.TemplateVersion{}
+	err := db.Bun().NewSelect().Model(&versions).
+		Where("template_name = ?", name).
+		Order("version DESC").
+		Scan(ctx)
 	if err != nil {
-		return fmt.Errorf("yaml.Unmarshal(template=%s): %w", name, err)
+		return nil, fmt.Errorf("fetching versions of template %s: %w", name, err)
+	}
+	return versions, nil
+}
+
+// TemplateVersionByNumber returns a single version of a template.
+func TemplateVersionByNumber(
+	ctx context.Context, name string, version int,
+) (*model.TemplateVersion, error) {
+	var v model.TemplateVersion
+	err := db.Bun().NewSelect().Model(&v).
+		Where("template_name = ?", name).
+		Where("version = ?", version).
+		Scan(ctx)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, db.ErrNotFound
+	case err != nil:
+		return nil, fmt.Errorf("fetching version %d of template %s: %w", version, name, err)
 	}
-	return nil
+	return &v, nil
 }
 
 // DeleteWorkspaceTemplates deletes all the templates in a workspace.
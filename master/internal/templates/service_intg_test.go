@@ -12,6 +12,7 @@ import (
 
 	"github.com/determined-ai/determined/master/internal/db"
 	"github.com/determined-ai/determined/master/internal/user"
+	"github.com/determined-ai/determined/master/pkg/model"
 	"github.com/determined-ai/determined/master/pkg/ptrs"
 	"github.com/determined-ai/determined/master/pkg/schemas"
 	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
@@ -29,7 +30,7 @@ func TestUnmarshalTemplateConfig(t *testing.T) {
 
 	t.Run("UnmarshalTemplateConfig that does not exist", func(t *testing.T) {
 		var m map[string]any
-		err = UnmarshalTemplateConfig(ctx, uuid.NewString(), u, &m, false)
+		_, err = UnmarshalTemplateConfig(ctx, uuid.NewString(), u, &m, false, nil)
 		require.ErrorContains(t, err, "not found")
 	})
 
@@ -79,13 +80,91 @@ func TestUnmarshalTemplateConfig(t *testing.T) {
 				RawMetric: ptrs.Ptr("loss_of_something"),
 			},
 		})
-		err = UnmarshalTemplateConfig(ctx, input.Name, u, &fakeConfig, false)
+		version, err := UnmarshalTemplateConfig(ctx, input.Name, u, &fakeConfig, false, nil)
 		require.NoError(t, err)
+		require.Equal(t, 1, version)
 		require.NotNil(t, fakeConfig.CheckpointStorage().RawGCSConfig)
 		require.Equal(t, cfgBucket, fakeConfig.CheckpointStorage().RawGCSConfig.Bucket())
 	})
 }
 
+func TestTemplateVersionsAndParameters(t *testing.T) {
+	api := TemplateAPIServer{}
+	ctx := apitest.WithCredentials(context.Background())
+
+	u, err := user.ByUsername(ctx, "determined")
+	require.NoError(t, err)
+
+	cfg, err := structpb.NewStruct(map[string]any{
+		"parameters": map[string]any{
+			"bucket": map[string]any{"type": "string"},
+			"slots":  map[string]any{"type": "int", "default": 2},
+		},
+		"checkpoint_storage": map[string]any{
+			"type":   "gcs",
+			"bucket": "ckpts-{{ .bucket }}",
+		},
+		"resources": map[string]any{"slots_per_trial": "{{ .slots }}"},
+	})
+	require.NoError(t, err)
+	name := uuid.NewString()
+	_, err = api.PutTemplate(ctx, &apiv1.PutTemplateRequest{
+		Template: &templatev1.Template{Name: name, Config: cfg},
+	})
+	require.NoError(t, err)
+
+	var out map[string]any
+	_, err = UnmarshalTemplateConfig(ctx, name, u, &out, false, nil)
+	require.ErrorContains(t, err, `template parameter "bucket" is required`)
+
+	version, err := UnmarshalTemplateConfig(ctx, name, u, &out, false,
+		map[string]any{"bucket": "a"})
+	require.NoError(t, err)
+	require.Equal(t, 1, version)
+	require.Equal(t, map[string]any{
+		"checkpoint_storage": map[string]any{"type": "gcs", "bucket": "ckpts-a"},
+		"resources":          map[string]any{"slots_per_trial": float64(2)},
+	}, out)
+
+	// Updating the template creates a new version and leaves the old one untouched.
+	cfg.Fields["description"] = structpb.NewStringValue("v2")
+	_, err = api.PutTemplate(ctx, &apiv1.PutTemplateRequest{
+		Template: &templatev1.Template{Name: name, Config: cfg},
+	})
+	require.NoError(t, err)
+	versions, err := TemplateVersions(ctx, name)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	require.Equal(t, 2, versions[0].Version)
+	require.Equal(t, "v2", versions[0].Config["description"])
+	require.NotContains(t, versions[1].Config, "description")
+
+	require.Equal(t, model.DefaultWorkspaceID, versions[0].WorkspaceID)
+
+	// Versions are deleted with the template, so a re-created template starts a new history.
+	_, err = api.DeleteTemplate(ctx, &apiv1.DeleteTemplateRequest{TemplateName: name})
+	require.NoError(t, err)
+	versions, err = TemplateVersions(ctx, name)
+	require.NoError(t, err)
+	require.Empty(t, versions)
+	_, err = api.PutTemplate(ctx, &apiv1.PutTemplateRequest{
+		Template: &templatev1.Template{Name: name, Config: cfg},
+	})
+	require.NoError(t, err)
+	v, err := TemplateVersionByNumber(ctx, name, 1)
+	require.NoError(t, err)
+	require.Equal(t, u.ID, *v.CreatedBy)
+	_, err = TemplateVersionByNumber(ctx, name, 2)
+	require.ErrorIs(t, err, db.ErrNotFound)
+
+	// Invalid parameter declarations are rejected at save time.
+	cfg.Fields["parameters"] = structpb.NewStringValue("nope")
+	_, err = api.PutTemplate(ctx, &apiv1.PutTemplateRequest{
+		Template: &templatev1.Template{Name: name, Config: cfg},
+	})
+	require.ErrorContains(t, err, "invalid template parameters")
+}
+
 func TestDeleteWorkspaceTemplates(t *testing.T) {
 	api := TemplateAPIServer{}
 	ctx := apitest.WithCredentials(context.Background())
@@ -400,6 +400,7 @@ func (t *trial) maybeAllocateTask() error {
 			RequestTime:       time.Now().UTC(),
 			IsUserVisible:     true,
 			Name:              name,
+			WorkspaceID:       t.taskSpec.WorkspaceID,
 			SlotsNeeded:       t.config.Resources().SlotsPerTrial(),
 			ResourcePool:      t.config.Resources().ResourcePool(),
 			FittingRequirements: sproto.FittingRequirements{
@@ -444,6 +445,7 @@ func (t *trial) maybeAllocateTask() error {
 		JobSubmissionTime: t.jobSubmissionTime,
 		IsUserVisible:     true,
 		Name:              name,
+		WorkspaceID:       t.taskSpec.WorkspaceID,
 
 		SlotsNeeded:  t.config.Resources().SlotsPerTrial(),
 		ResourcePool: t.config.Resources().ResourcePool(),
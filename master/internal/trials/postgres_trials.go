@@ -11,6 +11,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/uptrace/bun"
 	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 
 	"github.com/determined-ai/determined/master/internal/db"
 	"github.com/determined-ai/determined/master/pkg/model"
@@ -60,6 +61,7 @@ func MetricsTimeSeries(trialID int32, startTime time.Time,
 		return nil, fmt.Errorf("getting summary metrics for trial %d: %w", trialID, err)
 	}
 
+	allNumeric := true
 	for _, metricName := range append(metricNames, "epoch") {
 		metricType := db.MetricTypeString
 		if curSummary, ok := summaryMetrics.Metrics[metricName].(map[string]any); ok {
@@ -67,6 +69,9 @@ func MetricsTimeSeries(trialID int32, startTime time.Time,
 				metricType = m
 			}
 		}
+		if metricType != db.MetricTypeNumber && metricName != "epoch" {
+			allNumeric = false
+		}
 
 		cast := "text"
 		switch metricType {
@@ -80,6 +85,17 @@ func MetricsTimeSeries(trialID int32, startTime time.Time,
 			metricName, bun.Safe(cast), bun.Ident(strings.ReplaceAll(metricName, ".", "·")))
 	}
 
+	// Long series of numeric metrics are served from the rollups rather than sampled.
+	if allNumeric && timeSeriesFilter == nil && startTime.IsZero() {
+		rolledUp, ok, err := metricsTimeSeriesFromRollups(context.TODO(), trialID, metricNames,
+			startBatches, endBatches, maxDatapoints, metricGroup)
+		if err != nil {
+			return nil, err
+		} else if ok {
+			return rolledUp, nil
+		}
+	}
+
 	subq = subq.Where("trial_id = ?", trialID).OrderExpr("random()").
 		Limit(maxDatapoints)
 	switch timeSeriesFilter {
@@ -145,6 +161,93 @@ func MetricsTimeSeries(trialID int32, startTime time.Time,
 	return metricMeasurements, nil
 }
 
+// metricsTimeSeriesFromRollups returns the mean of each metric per rollup bucket, at the finest
+// resolution that fits in maxDatapoints. It returns false if the trial has so few datapoints that
+// sampling the raw metrics is just as cheap.
+func metricsTimeSeriesFromRollups(ctx context.Context, trialID int32, metricNames []string,
+	startBatches, endBatches, maxDatapoints int, metricGroup model.MetricGroup,
+) ([]db.MetricMeasurements, bool, error) {
+	if len(metricNames) == 0 {
+		return nil, false, nil
+	}
+
+	counts, err := db.MetricRollupBucketCounts(ctx, int(trialID), metricGroup, metricNames[0],
+		startBatches, endBatches)
+	if err != nil {
+		return nil, false, err
+	}
+	resolutions := db.MetricRollupResolutions
+	if counts[resolutions[0]] <= maxDatapoints {
+		return nil, false, nil
+	}
+	resolution := resolutions[len(resolutions)-1]
+	for _, r := range resolutions {
+		if counts[r] <= maxDatapoints {
+			resolution = r
+			break
+		}
+	}
+	// Even the coarsest buckets may be too many; merge every stride consecutive ones.
+	stride := (counts[resolution] + maxDatapoints - 1) / maxDatapoints
+
+	rollups, err := db.MetricRollups(ctx, int(trialID), metricGroup, resolution,
+		append(metricNames, "epoch"), startBatches, endBatches, 0)
+	if err != nil {
+		return nil, false, err
+	}
+
+	type aggregate struct {
+		sum   float64
+		count int
+	}
+	var measurements []db.MetricMeasurements
+	var aggregates []map[string]*aggregate
+	var epochBatches int
+	bucketIdx := -1
+	var lastBucket int64 = -1
+	for _, r := range rollups {
+		if r.Bucket != lastBucket {
+			lastBucket = r.Bucket
+			bucketIdx++
+			if bucketIdx%stride == 0 {
+				measurements = append(measurements, db.MetricMeasurements{
+					TrialID: trialID,
+					Values:  map[string]any{},
+				})
+				aggregates = append(aggregates, map[string]*aggregate{})
+				epochBatches = -1
+			}
+		}
+		m := &measurements[len(measurements)-1]
+		if uint(r.LastBatches) > m.Batches {
+			m.Batches = uint(r.LastBatches)
+		}
+		if r.LastEndTime.After(m.Time) {
+			m.Time = r.LastEndTime
+		}
+		if r.MetricName == "epoch" && r.LastBatches > epochBatches {
+			epoch := r.Last
+			m.Epoch = &epoch
+			epochBatches = r.LastBatches
+		}
+		if slices.Contains(metricNames, r.MetricName) {
+			agg, ok := aggregates[len(aggregates)-1][r.MetricName]
+			if !ok {
+				agg = &aggregate{}
+				aggregates[len(aggregates)-1][r.MetricName] = agg
+			}
+			agg.sum += r.Sum
+			agg.count += r.Count
+		}
+	}
+	for i, aggs := range aggregates {
+		for name, agg := range aggs {
+			measurements[i].Values[name] = agg.sum / float64(agg.count)
+		}
+	}
+	return measurements, true, nil
+}
+
 // CreateTrialSourceInfo creates a TrialSourceInfo object, which allows us to keep
 // track of the linkage between an inference/fine tuning trial and its checkpoint/model version.
 func CreateTrialSourceInfo(ctx context.Context, tsi *trialv1.TrialSourceInfo,
@@ -151,6 +151,28 @@ func (a *WorkspaceAuthZBasic) CanSetWorkspacesCheckpointStorageConfig(
 	return nil
 }
 
+// CanManageWorkspaceSecrets returns an error if the user is not an admin or owner of the
+// workspace.
+func (a *WorkspaceAuthZBasic) CanManageWorkspaceSecrets(
+	ctx context.Context, curUser model.User, workspace *workspacev1.Workspace,
+) error {
+	if !curUser.Admin && curUser.ID != model.UserID(workspace.UserId) {
+		return fmt.Errorf("only admins may manage the secrets of other user's workspaces")
+	}
+	return nil
+}
+
+// CanManageWorkspaceVolumes returns an error if the user is not an admin or owner of the
+// workspace.
+func (a *WorkspaceAuthZBasic) CanManageWorkspaceVolumes(
+	ctx context.Context, curUser model.User, workspace *workspacev1.Workspace,
+) error {
+	if !curUser.Admin && curUser.ID != model.UserID(workspace.UserId) {
+		return fmt.Errorf("only admins may manage the volumes of other user's workspaces")
+	}
+	return nil
+}
+
 // CanCreateWorkspaceWithCheckpointStorageConfig returns an nil error.
 func (a *WorkspaceAuthZBasic) CanCreateWorkspaceWithCheckpointStorageConfig(
 	ctx context.Context, curUser model.User,
@@ -58,6 +58,15 @@ type WorkspaceAuthZ interface {
 	CanSetWorkspacesDefaultPools(
 		ctx context.Context, curUser model.User, workspace *workspacev1.Workspace,
 	) error
+
+	// POST /secrets, PUT and DELETE /secrets/:secret_id for secrets of the workspace
+	CanManageWorkspaceSecrets(
+		ctx context.Context, curUser model.User, workspace *workspacev1.Workspace,
+	) error
+	// POST /workspaces/:workspace_id/volumes and DELETE /workspaces/:workspace_id/volumes/:name
+	CanManageWorkspaceVolumes(
+		ctx context.Context, curUser model.User, workspace *workspacev1.Workspace,
+	) error
 	// TODO: we should consider userID as an arg instead of model.User
 
 	// DELETE /api/v1/workspaces/:workspace_id
@@ -139,3 +139,34 @@ func AllWorkspaces(ctx context.Context) ([]*model.Workspace, error) {
 	}
 	return w, nil
 }
+
+// GPULimit returns how many GPUs the tasks of a workspace can request at once, or nil if the
+// workspace is not limited.
+func GPULimit(ctx context.Context, workspaceID int) (*int, error) {
+	var limit *int
+	err := db.Bun().NewSelect().Table("workspaces").Column("gpu_limit").
+		Where("id = ?", workspaceID).
+		Scan(ctx, &limit)
+	if err != nil {
+		return nil, fmt.Errorf("getting GPU limit of workspace %d: %w", workspaceID, db.MatchSentinelError(err))
+	}
+	return limit, nil
+}
+
+// SetGPULimit sets how many GPUs the tasks of a workspace can request at once; nil removes the
+// limit.
+func SetGPULimit(ctx context.Context, workspaceID int, limit *int) error {
+	res, err := db.Bun().NewUpdate().Table("workspaces").
+		Set("gpu_limit = ?", limit).
+		Where("id = ?", workspaceID).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("setting GPU limit of workspace %d: %w", workspaceID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("setting GPU limit of workspace %d: %w", workspaceID, db.ErrNotFound)
+	}
+	return nil
+}
@@ -31,6 +31,8 @@ type MasterSetAgentOptions struct {
 	MasterInfo           MasterInfo
 	LoggingOptions       model.LoggingConfig
 	ContainersToReattach []ContainerReattach
+	// TimeSlicesPerGPU is how many slots the agent advertises for each of its GPUs.
+	TimeSlicesPerGPU int
 }
 
 // StartContainer notifies the agent to start a container with the provided spec.
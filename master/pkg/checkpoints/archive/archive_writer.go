@@ -115,3 +115,26 @@ func (aw *zipArchiveWriter) Write(p []byte) (int, error) {
 	}
 	return aw.zwContent.Write(p)
 }
+
+type prefixArchiveWriter struct {
+	aw     ArchiveWriter
+	prefix string
+}
+
+// WithPrefix returns an ArchiveWriter that writes to aw with prefix prepended to every path.
+// Closing it leaves aw open, so that several downloads can share one archive.
+func WithPrefix(aw ArchiveWriter, prefix string) ArchiveWriter {
+	return &prefixArchiveWriter{aw: aw, prefix: prefix}
+}
+
+func (aw *prefixArchiveWriter) WriteHeader(path string, size int64) error {
+	return aw.aw.WriteHeader(aw.prefix+strings.TrimPrefix(path, "/"), size)
+}
+
+func (aw *prefixArchiveWriter) Write(p []byte) (int, error) {
+	return aw.aw.Write(p)
+}
+
+func (aw *prefixArchiveWriter) Close() error {
+	return nil
+}
@@ -35,7 +35,14 @@ func NewDownloader(
 	if err != nil {
 		return nil, err
 	}
+	return newDownloader(aw, id, storageConfig)
+}
 
+func newDownloader(
+	aw archive.ArchiveWriter,
+	id string,
+	storageConfig *expconf.CheckpointStorageConfig,
+) (CheckpointDownloader, error) {
 	idPrefix := func(prefix string) string {
 		return prefix + "/" + id
 	}
@@ -75,6 +82,68 @@ func NewDownloader(
 	}
 }
 
+// EnsembleMember is a checkpoint that is downloaded as part of an ensemble.
+type EnsembleMember struct {
+	// ID is the UUID string of the checkpoint.
+	ID string
+	// Dir is the directory of the archive the checkpoint is written to.
+	Dir           string
+	StorageConfig *expconf.CheckpointStorageConfig
+}
+
+type ensembleDownloader struct {
+	aw           archive.ArchiveWriter
+	manifestName string
+	manifest     []byte
+	members      []CheckpointDownloader
+}
+
+// NewEnsembleDownloader returns a CheckpointDownloader that writes several checkpoints to w in
+// one archive, each in its own directory, along with a manifest file describing them.
+func NewEnsembleDownloader(
+	w io.Writer,
+	members []EnsembleMember,
+	manifestName string,
+	manifest []byte,
+	archiveType archive.ArchiveType,
+) (CheckpointDownloader, error) {
+	aw, err := archive.NewArchiveWriter(w, archiveType)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &ensembleDownloader{aw: aw, manifestName: manifestName, manifest: manifest}
+	for _, m := range members {
+		md, err := newDownloader(archive.WithPrefix(aw, m.Dir+"/"), m.ID, m.StorageConfig)
+		if err != nil {
+			return nil, fmt.Errorf("checkpoint %s: %w", m.ID, err)
+		}
+		d.members = append(d.members, md)
+	}
+	return d, nil
+}
+
+// Download downloads the manifest and then every checkpoint, in order.
+func (d *ensembleDownloader) Download(ctx context.Context) error {
+	if err := d.aw.WriteHeader(d.manifestName, int64(len(d.manifest))); err != nil {
+		return err
+	}
+	if _, err := d.aw.Write(d.manifest); err != nil {
+		return err
+	}
+	for _, md := range d.members {
+		if err := md.Download(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the underlying ArchiveWriter.
+func (d *ensembleDownloader) Close() error {
+	return d.aw.Close()
+}
+
 func storageConfig2Str(config any) string {
 	switch config.(type) {
 	case expconf.AzureConfig:
@@ -0,0 +1,61 @@
+package checkpoints
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/pkg/checkpoints/archive"
+	"github.com/determined-ai/determined/master/pkg/ptrs"
+	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
+)
+
+func TestEnsembleDownloader(t *testing.T) {
+	root := t.TempDir()
+	storage := &expconf.CheckpointStorageConfig{
+		RawDirectoryConfig: &expconf.DirectoryConfig{RawContainerPath: ptrs.Ptr(root)},
+	}
+	for _, id := range []string{"a", "b"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, id, "sub"), 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(root, id, "sub", "weights"), []byte(id), 0o600))
+	}
+
+	var buf bytes.Buffer
+	d, err := NewEnsembleDownloader(&buf, []EnsembleMember{
+		{ID: "b", Dir: "0-b", StorageConfig: storage},
+		{ID: "a", Dir: "1-a", StorageConfig: storage},
+	}, "ensemble.json", []byte(`{}`), archive.ArchiveTgz)
+	require.NoError(t, err)
+	require.NoError(t, d.Download(context.Background()))
+	require.NoError(t, d.Close())
+
+	gz, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	files := map[string]string{}
+	var order []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[hdr.Name] = string(content)
+		order = append(order, hdr.Name)
+	}
+	require.Equal(t, []string{"ensemble.json", "0-b/sub/weights", "1-a/sub/weights"}, order)
+	require.Equal(t, map[string]string{
+		"ensemble.json":   "{}",
+		"0-b/sub/weights": "b",
+		"1-a/sub/weights": "a",
+	}, files)
+}
@@ -0,0 +1,49 @@
+package gcs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"cloud.google.com/go/storage"
+)
+
+// Probe writes data to the object name in bucket, reads it back and deletes it.
+func Probe(ctx context.Context, bucket, name string, data []byte) error {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+	obj := client.Bucket(bucket).Object(name)
+
+	w := obj.NewWriter(ctx)
+	if _, err := w.Write(data); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("writing gs://%s/%s: %w", bucket, name, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("writing gs://%s/%s: %w", bucket, name, err)
+	}
+
+	r, err := obj.NewReader(ctx)
+	if err != nil {
+		return fmt.Errorf("reading gs://%s/%s: %w", bucket, name, err)
+	}
+	read, err := io.ReadAll(r)
+	_ = r.Close()
+	if err != nil {
+		return fmt.Errorf("reading gs://%s/%s: %w", bucket, name, err)
+	}
+	if !bytes.Equal(read, data) {
+		return fmt.Errorf("gs://%s/%s read back different contents than were written", bucket, name)
+	}
+
+	if err := obj.Delete(ctx); err != nil {
+		return fmt.Errorf("deleting gs://%s/%s: %w", bucket, name, err)
+	}
+	return nil
+}
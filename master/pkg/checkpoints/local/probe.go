@@ -0,0 +1,27 @@
+package local
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Probe writes data to the file name in dir, reads it back and deletes it.
+func Probe(dir, name string, data []byte) error {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	read, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	if !bytes.Equal(read, data) {
+		return fmt.Errorf("%s read back different contents than were written", path)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("deleting %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package checkpoints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/determined-ai/determined/master/pkg/checkpoints/gcs"
+	"github.com/determined-ai/determined/master/pkg/checkpoints/local"
+	"github.com/determined-ai/determined/master/pkg/checkpoints/s3"
+	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
+)
+
+// ErrProbeUnsupported is returned by ProbeStorage for storage the master can't access.
+var ErrProbeUnsupported = errors.New("probing is not supported for this storage type")
+
+// ProbeStorage checks that the master can write, read back and delete an object in the
+// checkpoint storage described by storageConfig, under its prefix if it has one.
+func ProbeStorage(ctx context.Context, storageConfig *expconf.CheckpointStorageConfig) error {
+	name := ".determined-probe-" + uuid.New().String()
+	data := []byte(name)
+
+	objectKey := func(prefixRef *string) string {
+		prefix := ""
+		if prefixRef != nil {
+			prefix = *prefixRef
+		}
+		return strings.TrimLeft(path.Join(prefix, name), "/")
+	}
+
+	switch storage := storageConfig.GetUnionMember().(type) {
+	case expconf.S3Config:
+		return s3.Probe(ctx, storage.Bucket(), objectKey(storage.Prefix()), data,
+			storage.EndpointURL(), storage.AccessKey(), storage.SecretKey())
+
+	case expconf.GCSConfig:
+		return gcs.Probe(ctx, storage.Bucket(), objectKey(storage.Prefix()), data)
+
+	case expconf.SharedFSConfig:
+		dir, err := storage.PathInContainerOrHost()
+		if err != nil {
+			return err
+		}
+		return local.Probe(dir, name, data)
+
+	case expconf.DirectoryConfig:
+		return local.Probe(storage.ContainerPath(), name, data)
+
+	default:
+		return fmt.Errorf("%w: %s", ErrProbeUnsupported, storageConfig2Str(storage))
+	}
+}
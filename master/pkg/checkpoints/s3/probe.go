@@ -0,0 +1,73 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// defaultProbeRegion is used for buckets behind a custom endpoint, whose region can't be looked up.
+const defaultProbeRegion = "us-east-1"
+
+// Probe writes data to key in bucket, reads it back and deletes it. Like downloads, it relies on
+// the existing AWS credentials unless both accessKey and secretKey are set.
+func Probe(
+	ctx context.Context, bucket, key string, data []byte, endpointURL, accessKey, secretKey *string,
+) error {
+	awsConfig := &aws.Config{Endpoint: endpointURL}
+	if endpointURL != nil {
+		awsConfig.Region = aws.String(defaultProbeRegion)
+		awsConfig.S3ForcePathStyle = aws.Bool(true)
+	} else {
+		region, err := GetS3BucketRegion(ctx, bucket)
+		if err != nil {
+			return err
+		}
+		awsConfig.Region = &region
+	}
+	if accessKey != nil && secretKey != nil {
+		awsConfig.Credentials = credentials.NewStaticCredentials(*accessKey, *secretKey, "")
+	}
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return err
+	}
+	s3client := s3.New(sess)
+
+	if _, err := s3client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+		Body:   bytes.NewReader(data),
+	}); err != nil {
+		return fmt.Errorf("writing s3://%s/%s: %w", bucket, key, err)
+	}
+
+	out, err := s3client.GetObjectWithContext(ctx, &s3.GetObjectInput{Bucket: &bucket, Key: &key})
+	if err != nil {
+		return fmt.Errorf("reading s3://%s/%s: %w", bucket, key, err)
+	}
+	read, err := io.ReadAll(out.Body)
+	if cerr := out.Body.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("reading s3://%s/%s: %w", bucket, key, err)
+	}
+	if !bytes.Equal(read, data) {
+		return fmt.Errorf("s3://%s/%s read back different contents than were written", bucket, key)
+	}
+
+	if _, err := s3client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	}); err != nil {
+		return fmt.Errorf("deleting s3://%s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
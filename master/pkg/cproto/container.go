@@ -28,7 +28,8 @@ func (c Container) Transition(new State) Container {
 // DeviceUUIDsByType returns the UUIDs of the devices with the given device type.
 func (c Container) DeviceUUIDsByType(deviceType device.Type) (uuids []string) {
 	for _, d := range c.Devices {
-		if d.Type == deviceType {
+		// The time slices of a GPU share its UUID.
+		if d.Type == deviceType && !slices.Contains(uuids, d.UUID) {
 			uuids = append(uuids, d.UUID)
 		}
 	}
@@ -12,9 +12,26 @@ import (
 
 // Spec provides the necessary information for an agent to start a container.
 type Spec struct {
-	TaskType string
-	PullSpec PullSpec
-	RunSpec  RunSpec
+	TaskType   string
+	PullSpec   PullSpec
+	StagedData []StagedDataset
+	Egress     *EgressSpec
+	RunSpec    RunSpec
+}
+
+// EgressSpec restricts the network traffic of a container to the master, the other containers of
+// its task and the CIDRs, IP addresses and hostnames it allows. The agent enforces it by running
+// the container on its egress network.
+type EgressSpec struct {
+	Allow []string
+}
+
+// StagedDataset is a dataset the agent stages into its local cache after pulling the image and
+// bind mounts read-only into the container.
+type StagedDataset struct {
+	Source    string
+	CacheKey  string
+	MountPath string
 }
 
 // PullSpec contains configs for an ImagePull call.
@@ -24,6 +24,7 @@ func (s State) Before(other State) bool {
 	ordering := []State{
 		Assigned,
 		Pulling,
+		Staging,
 		Starting,
 		Running,
 		Terminated,
@@ -41,6 +42,9 @@ const (
 	Assigned State = "ASSIGNED"
 	// Pulling state means that the container's base image is being pulled from the Docker registry.
 	Pulling State = "PULLING"
+	// Staging state means that the image has been pulled and the agent is staging the container's
+	// datasets into its local cache.
+	Staging State = "STAGING"
 	// Starting state means that the image has been pulled and the container is being started, but
 	// the container is not ready yet.
 	Starting State = "STARTING"
@@ -54,7 +58,8 @@ const (
 
 var validTransitions = map[State]map[State]bool{
 	Assigned:   {Pulling: true, Terminated: true},
-	Pulling:    {Starting: true, Terminated: true},
+	Pulling:    {Staging: true, Starting: true, Terminated: true},
+	Staging:    {Starting: true, Terminated: true},
 	Starting:   {Running: true, Terminated: true},
 	Running:    {Terminated: true},
 	Terminated: {},
@@ -89,6 +94,8 @@ func (s State) Proto() containerv1.State {
 		return containerv1.State_STATE_ASSIGNED
 	case Pulling:
 		return containerv1.State_STATE_PULLING
+	case Staging:
+		return containerv1.State_STATE_STAGING
 	case Starting:
 		return containerv1.State_STATE_STARTING
 	case Running:
@@ -22,6 +22,8 @@ const (
 	ShellEntrypointResource = "shell-entrypoint.sh"
 	// GCCheckpointsEntrypointResource is the script to run checkpoint GC.
 	GCCheckpointsEntrypointResource = "gc-checkpoints-entrypoint.sh"
+	// ModelExportEntrypointResource is the script to export a model version.
+	ModelExportEntrypointResource = "model-export-entrypoint.sh"
 	// NotebookTemplateResource is the template notebook config file.
 	NotebookTemplateResource = "notebook-template.ipynb"
 	// NotebookEntrypointResource is the script to set up a notebook.
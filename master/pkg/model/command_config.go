@@ -56,8 +56,13 @@ type CommandConfig struct {
 
 // Validate implements the check.Validatable interface.
 func (c *CommandConfig) Validate() []error {
+	var scratchErr error
+	if c.Resources.Scratch != nil {
+		scratchErr = check.NotEmpty(c.Resources.Scratch.Size(), "resources.scratch.size must be set")
+	}
 	return []error{
-		check.GreaterThanOrEqualTo(c.Resources.Slots, 0, "resources.slots must be >= 0"),
+		check.GreaterThanOrEqualTo(c.Resources.Slots, float64(0), "resources.slots must be >= 0"),
+		scratchErr,
 		check.GreaterThan(len(c.Entrypoint), 0, "entrypoint must be non-empty"),
 		check.Contains(
 			c.NotebookIdleType,
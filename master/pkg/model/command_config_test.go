@@ -58,6 +58,29 @@ func TestConfigValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "fractional-slots",
+			fields: fields{
+				Resources:   ResourcesConfig{Slots: 0.25, Weight: 1},
+				Environment: environment,
+				Entrypoint: []string{
+					"test",
+				},
+				NotebookIdleType: NotebookIdleTypeActivity,
+			},
+		},
+		{
+			name: "invalid-fractional-slots",
+			fields: fields{
+				Resources:   ResourcesConfig{Slots: 1.5, Weight: 1},
+				Environment: environment,
+				Entrypoint: []string{
+					"test",
+				},
+				NotebookIdleType: NotebookIdleTypeActivity,
+			},
+			wantErr: true,
+		},
 	}
 	runTestCase := func(t *testing.T, tc testCase) {
 		t.Run(tc.name, func(t *testing.T) {
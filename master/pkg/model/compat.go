@@ -32,7 +32,7 @@ func (r ResourcesConfig) ToExpconf() expconf.ResourcesConfig {
 	}
 
 	return schemas.WithDefaults(expconf.ResourcesConfig{
-		RawSlots:          ptrs.Ptr(r.Slots),
+		RawSlots:          ptrs.Ptr(r.WholeSlots()),
 		RawMaxSlots:       r.MaxSlots,
 		RawSlotsPerTrial:  ptrs.Ptr(1),
 		RawWeight:         ptrs.Ptr(r.Weight),
@@ -41,6 +41,8 @@ func (r ResourcesConfig) ToExpconf() expconf.ResourcesConfig {
 		RawResourcePool:   ptrs.Ptr(r.ResourcePool),
 		RawPriority:       r.Priority,
 		RawDevices:        r.Devices.ToExpconf(),
+		RawScratch:        r.Scratch,
+		RawStagedData:     r.StagedData,
 	})
 }
 
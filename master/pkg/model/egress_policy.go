@@ -0,0 +1,108 @@
+package model
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// EgressMode is how much of the network the containers of a task can send traffic to.
+type EgressMode string
+
+const (
+	// EgressAllowAll doesn't restrict the traffic of containers.
+	EgressAllowAll EgressMode = "allow_all"
+	// EgressMasterOnly denies all traffic except to the master, which tasks need to run, and
+	// between the containers of a task.
+	EgressMasterOnly EgressMode = "master_only"
+	// EgressAllowList also allows traffic to a list of CIDRs and hostnames.
+	EgressAllowList EgressMode = "allow_list"
+)
+
+// EgressRule restricts the traffic that the containers of a task can send.
+type EgressRule struct {
+	// Mode is allow_all if empty.
+	Mode EgressMode `json:"mode"`
+	// Allow are the CIDRs, IP addresses and hostnames that allow_list rules allow. Hostnames are
+	// resolved when the containers of tasks are created.
+	Allow []string `json:"allow,omitempty"`
+}
+
+// Restricted returns whether the rule restricts traffic at all.
+func (r EgressRule) Restricted() bool {
+	return r.Mode != "" && r.Mode != EgressAllowAll
+}
+
+func (r EgressRule) validate(name string) []error {
+	var errs []error
+	switch r.Mode {
+	case "", EgressAllowAll, EgressMasterOnly:
+		if len(r.Allow) > 0 {
+			errs = append(errs, fmt.Errorf("%s: allow can only be set with mode %s", name, EgressAllowList))
+		}
+	case EgressAllowList:
+		if len(r.Allow) == 0 {
+			errs = append(errs, fmt.Errorf("%s: mode %s needs a list to allow", name, EgressAllowList))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%s: mode must be one of %s, %s or %s, not %q",
+			name, EgressAllowAll, EgressMasterOnly, EgressAllowList, r.Mode))
+	}
+	for _, dest := range r.Allow {
+		if _, _, err := ParseEgressDestination(dest); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errs
+}
+
+// EgressPolicy restricts the traffic that the containers of tasks can send, with a rule for each
+// task type that overrides the default rule.
+type EgressPolicy struct {
+	EgressRule
+	TaskTypes map[TaskType]EgressRule `json:"task_types,omitempty"`
+}
+
+// ForTaskType returns the rule of the tasks of a type.
+func (p EgressPolicy) ForTaskType(t TaskType) EgressRule {
+	if r, ok := p.TaskTypes[t]; ok {
+		return r
+	}
+	return p.EgressRule
+}
+
+// Validate implements the check.Validatable interface.
+func (p EgressPolicy) Validate() []error {
+	errs := p.EgressRule.validate("egress policy")
+	for t, r := range p.TaskTypes {
+		switch t {
+		case TaskTypeTrial, TaskTypeNotebook, TaskTypeShell, TaskTypeCommand,
+			TaskTypeTensorboard, TaskTypeCheckpointGC, TaskTypeModelExport:
+			errs = append(errs, r.validate(fmt.Sprintf("egress policy of %s tasks", t))...)
+		default:
+			errs = append(errs, fmt.Errorf("egress policy: unknown task type %q", t))
+		}
+	}
+	return errs
+}
+
+var hostnameRegex = regexp.MustCompile(
+	`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*\.?$`)
+
+// ParseEgressDestination parses an entry of the allow list of an egress rule, which is either a
+// CIDR, an IP address or a hostname.
+func ParseEgressDestination(dest string) (netip.Prefix, string, error) {
+	if prefix, err := netip.ParsePrefix(dest); err == nil {
+		return prefix.Masked(), "", nil
+	}
+	if addr, err := netip.ParseAddr(dest); err == nil {
+		return netip.PrefixFrom(addr, addr.BitLen()), "", nil
+	}
+	host := strings.ToLower(dest)
+	if len(host) > 253 || !hostnameRegex.MatchString(host) {
+		return netip.Prefix{}, "", fmt.Errorf(
+			"%q is neither a CIDR, an IP address nor a hostname", dest)
+	}
+	return netip.Prefix{}, strings.TrimSuffix(host, "."), nil
+}
@@ -357,7 +357,11 @@ type Experiment struct {
 	ProjectID            int        `db:"project_id"`
 	Unmanaged            bool       `db:"unmanaged"`
 	ExternalExperimentID *string    `db:"external_experiment_id"`
-	Progress             *float64
+	// TemplateName and TemplateVersion record the template version the experiment was created
+	// from, if any.
+	TemplateName    *string `db:"template_name"`
+	TemplateVersion *int    `db:"template_version"`
+	Progress        *float64
 }
 
 // ExperimentFromProto converts a experimentv1.Experiment to a model.Experiment.
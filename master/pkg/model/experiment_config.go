@@ -2,6 +2,7 @@ package model
 
 import (
 	"encoding/json"
+	"math"
 	"path/filepath"
 	"strings"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/ghodss/yaml"
 
 	"github.com/determined-ai/determined/master/pkg/check"
+	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
 )
 
 const (
@@ -82,7 +84,9 @@ func (d *DeviceConfig) UnmarshalJSON(data []byte) error {
 
 // ResourcesConfig configures resource usage for an experiment, command, notebook, or tensorboard.
 type ResourcesConfig struct {
-	Slots int `json:"slots"`
+	// Slots is the number of slots to use. Fractions of a slot, like 0.25, share a GPU with
+	// other tasks in resource pools that time-slice their GPUs.
+	Slots float64 `json:"slots"`
 
 	MaxSlots       *int         `json:"max_slots,omitempty"`
 	Weight         float64      `json:"weight"`
@@ -93,10 +97,28 @@ type ResourcesConfig struct {
 
 	Devices DevicesConfig `json:"devices"`
 
+	Scratch *expconf.ScratchVolumeConfig `json:"scratch,omitempty"`
+
+	StagedData expconf.StagedDataConfig `json:"staged_data,omitempty"`
+
 	// Deprecated: Use ResourcePool instead.
 	AgentLabel string `json:"agent_label,omitempty"`
 }
 
+// WholeSlots returns the number of slots to schedule, with a fraction of a slot rounded up to
+// a whole slot.
+func (r ResourcesConfig) WholeSlots() int {
+	return int(math.Ceil(r.Slots))
+}
+
+// SlotFraction returns the fraction of a slot that is requested, or 0 if whole slots are.
+func (r ResourcesConfig) SlotFraction() float64 {
+	if r.Slots == math.Trunc(r.Slots) {
+		return 0
+	}
+	return r.Slots
+}
+
 // StorageSize is a named type for custom marshaling behavior for shm_size.
 type StorageSize int64
 
@@ -162,7 +184,9 @@ func ValidatePrioritySetting(priority *int) []error {
 // Validate implements the check.Validatable interface.
 func (r ResourcesConfig) Validate() []error {
 	errs := []error{
-		check.GreaterThanOrEqualTo(r.Slots, 0, "slots must be >= 0"),
+		check.GreaterThanOrEqualTo(r.Slots, float64(0), "slots must be >= 0"),
+		check.True(r.Slots < 1 || r.SlotFraction() == 0,
+			"slots must be a whole number or a fraction less than 1"),
 		check.GreaterThan(r.Weight, float64(0), "weight must be > 0"),
 	}
 	errs = append(errs, ValidatePrioritySetting(r.Priority)...)
@@ -37,6 +37,8 @@ const (
 	JobTypeExperiment JobType = "EXPERIMENT"
 	// JobTypeCheckpointGC is the "CheckpointGC" job type for enum.job_type in Postgres.
 	JobTypeCheckpointGC JobType = "CHECKPOINT_GC"
+	// JobTypeModelExport is the "MODEL_EXPORT" job type for enum.job_type in Postgres.
+	JobTypeModelExport JobType = "MODEL_EXPORT"
 )
 
 // Proto returns the proto representation of the job type.
@@ -54,6 +56,8 @@ func (jt JobType) Proto() jobv1.Type {
 		return jobv1.Type_TYPE_TENSORBOARD
 	case JobTypeCheckpointGC:
 		return jobv1.Type_TYPE_CHECKPOINT_GC
+	case JobTypeModelExport:
+		return jobv1.Type_TYPE_MODEL_EXPORT
 	default:
 		panic("unknown job type")
 	}
@@ -74,6 +78,8 @@ func JobTypeFromProto(t jobv1.Type) JobType {
 		return JobTypeTensorboard
 	case jobv1.Type_TYPE_CHECKPOINT_GC:
 		return JobTypeCheckpointGC
+	case jobv1.Type_TYPE_MODEL_EXPORT:
+		return JobTypeModelExport
 	default:
 		panic("unknown job type")
 	}
@@ -0,0 +1,142 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// MetadataType is the type of a typed experiment or trial metadata value.
+type MetadataType string
+
+const (
+	// MetadataTypeString values are arbitrary strings.
+	MetadataTypeString MetadataType = "string"
+	// MetadataTypeNumber values are double precision floats.
+	MetadataTypeNumber MetadataType = "number"
+	// MetadataTypeBool values are booleans.
+	MetadataTypeBool MetadataType = "bool"
+	// MetadataTypeTimestamp values are points in time.
+	MetadataTypeTimestamp MetadataType = "timestamp"
+)
+
+// MaxMetadataKeyLength is the longest metadata key that is accepted.
+const MaxMetadataKeyLength = 256
+
+// MetadataValue is a single typed metadata value. Exactly the field matching Type is set.
+//
+// In JSON, string, number and bool values may be given as bare scalars; every type may also be
+// given as {"type": ..., "value": ...}, which is the only way to give a timestamp. Values are
+// always marshaled in the latter form.
+type MetadataValue struct {
+	Type           MetadataType `bun:"type,notnull"`
+	StringValue    *string      `bun:"string_value"`
+	NumberValue    *float64     `bun:"number_value"`
+	BoolValue      *bool        `bun:"bool_value"`
+	TimestampValue *time.Time   `bun:"timestamp_value"`
+}
+
+type metadataValueJSON struct {
+	Type  MetadataType    `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (v MetadataValue) MarshalJSON() ([]byte, error) {
+	var value any
+	switch v.Type {
+	case MetadataTypeString:
+		value = v.StringValue
+	case MetadataTypeNumber:
+		value = v.NumberValue
+	case MetadataTypeBool:
+		value = v.BoolValue
+	case MetadataTypeTimestamp:
+		value = v.TimestampValue
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(metadataValueJSON{Type: v.Type, Value: raw})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (v *MetadataValue) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	typed := metadataValueJSON{Value: data}
+	if len(data) > 0 && data[0] == '{' {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&typed); err != nil {
+			return fmt.Errorf("invalid metadata value: %w", err)
+		}
+	} else {
+		switch {
+		case len(data) > 0 && data[0] == '"':
+			typed.Type = MetadataTypeString
+		case bytes.Equal(data, []byte("true")) || bytes.Equal(data, []byte("false")):
+			typed.Type = MetadataTypeBool
+		default:
+			typed.Type = MetadataTypeNumber
+		}
+	}
+
+	*v = MetadataValue{Type: typed.Type}
+	var err error
+	switch typed.Type {
+	case MetadataTypeString:
+		err = json.Unmarshal(typed.Value, &v.StringValue)
+	case MetadataTypeNumber:
+		err = json.Unmarshal(typed.Value, &v.NumberValue)
+	case MetadataTypeBool:
+		err = json.Unmarshal(typed.Value, &v.BoolValue)
+	case MetadataTypeTimestamp:
+		err = json.Unmarshal(typed.Value, &v.TimestampValue)
+	default:
+		return fmt.Errorf("invalid metadata type %q", typed.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s metadata value: %w", typed.Type, err)
+	}
+	if v.StringValue == nil && v.NumberValue == nil && v.BoolValue == nil &&
+		v.TimestampValue == nil {
+		return fmt.Errorf("%s metadata value must not be null", typed.Type)
+	}
+	return nil
+}
+
+// ExperimentMetadata represents a row from the `experiment_metadata` table.
+type ExperimentMetadata struct {
+	bun.BaseModel `bun:"table:experiment_metadata"`
+
+	ExperimentID int    `bun:"experiment_id,pk"`
+	Key          string `bun:"key,pk"`
+	MetadataValue
+}
+
+// TrialMetadata represents a row from the `trial_metadata` table.
+type TrialMetadata struct {
+	bun.BaseModel `bun:"table:trial_metadata"`
+
+	TrialID int    `bun:"trial_id,pk"`
+	Key     string `bun:"key,pk"`
+	MetadataValue
+}
+
+// ValidateMetadataKey checks that a metadata key can be used in filters and sort strings.
+func ValidateMetadataKey(key string) error {
+	switch {
+	case key == "":
+		return fmt.Errorf("metadata key must not be empty")
+	case len(key) > MaxMetadataKeyLength:
+		return fmt.Errorf("metadata key %q is longer than %d characters", key, MaxMetadataKeyLength)
+	case strings.ContainsAny(key, ",="):
+		return fmt.Errorf("metadata key %q must not contain ',' or '='", key)
+	}
+	return nil
+}
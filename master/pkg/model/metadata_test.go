@@ -0,0 +1,56 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/pkg/ptrs"
+)
+
+func TestMetadataValueJSON(t *testing.T) {
+	ts := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	var values map[string]*MetadataValue
+	err := json.Unmarshal([]byte(`{
+		"dataset": "v3",
+		"rows": 1000,
+		"cleaned": true,
+		"ratio": {"type": "number", "value": 0.5},
+		"snapshot": {"type": "timestamp", "value": "2023-12-01T00:00:00Z"},
+		"removed": null
+	}`), &values)
+	require.NoError(t, err)
+	require.Equal(t, map[string]*MetadataValue{
+		"dataset":  {Type: MetadataTypeString, StringValue: ptrs.Ptr("v3")},
+		"rows":     {Type: MetadataTypeNumber, NumberValue: ptrs.Ptr(1000.0)},
+		"cleaned":  {Type: MetadataTypeBool, BoolValue: ptrs.Ptr(true)},
+		"ratio":    {Type: MetadataTypeNumber, NumberValue: ptrs.Ptr(0.5)},
+		"snapshot": {Type: MetadataTypeTimestamp, TimestampValue: &ts},
+		"removed":  nil,
+	}, values)
+
+	bs, err := json.Marshal(values["snapshot"])
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type": "timestamp", "value": "2023-12-01T00:00:00Z"}`, string(bs))
+
+	for _, invalid := range []string{
+		`{"type": "timestamp", "value": "yesterday"}`,
+		`{"type": "number", "value": "1"}`,
+		`{"type": "color", "value": "red"}`,
+		`{"type": "string", "value": null}`,
+		`{"type": "string", "value": "a", "extra": 1}`,
+		`[1, 2]`,
+	} {
+		var v MetadataValue
+		require.Error(t, json.Unmarshal([]byte(invalid), &v), invalid)
+	}
+}
+
+func TestValidateMetadataKey(t *testing.T) {
+	require.NoError(t, ValidateMetadataKey("dataset.version"))
+	require.Error(t, ValidateMetadataKey(""))
+	require.Error(t, ValidateMetadataKey("a=b"))
+	require.Error(t, ValidateMetadataKey("a,b"))
+}
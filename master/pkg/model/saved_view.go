@@ -0,0 +1,57 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// SavedViewVisibility describes who besides the owner can see a saved view.
+type SavedViewVisibility string
+
+const (
+	// SavedViewVisibilityPrivate views are only visible to their owner.
+	SavedViewVisibilityPrivate SavedViewVisibility = "PRIVATE"
+	// SavedViewVisibilityProject views are visible to anyone who can view the project.
+	SavedViewVisibilityProject SavedViewVisibility = "PROJECT"
+)
+
+// Validate checks that the visibility is one of the known values.
+func (v SavedViewVisibility) Validate() error {
+	switch v {
+	case SavedViewVisibilityPrivate, SavedViewVisibilityProject:
+		return nil
+	default:
+		return fmt.Errorf("invalid saved view visibility %q", v)
+	}
+}
+
+// SavedView is a named experiment search (filter tree, sort order and column set) that belongs
+// to a project.
+type SavedView struct {
+	bun.BaseModel `bun:"table:saved_views"`
+
+	ID         int                 `bun:"id,pk,autoincrement" json:"id"`
+	Name       string              `bun:"name,notnull" json:"name"`
+	ProjectID  int                 `bun:"project_id,notnull" json:"project_id"`
+	OwnerID    UserID              `bun:"owner_id,notnull" json:"owner_id"`
+	Visibility SavedViewVisibility `bun:"visibility,notnull" json:"visibility"`
+	// Filter is the filter tree accepted by SearchExperiments.
+	Filter    json.RawMessage `bun:"filter,type:jsonb,nullzero" json:"filter"`
+	Sort      *string         `bun:"sort" json:"sort"`
+	Columns   []string        `bun:"columns,type:jsonb" json:"columns"`
+	CreatedAt time.Time       `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"created_at"`
+	UpdatedAt time.Time       `bun:"updated_at,nullzero,notnull,default:current_timestamp" json:"updated_at"`
+}
+
+// VisibleTo returns whether the user may see the view, assuming they can view its project.
+func (v *SavedView) VisibleTo(user User) bool {
+	return v.Visibility == SavedViewVisibilityProject || v.OwnerID == user.ID || user.Admin
+}
+
+// EditableBy returns whether the user may modify or delete the view.
+func (v *SavedView) EditableBy(user User) bool {
+	return v.OwnerID == user.ID || user.Admin
+}
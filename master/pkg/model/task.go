@@ -48,6 +48,8 @@ const (
 	TaskTypeTensorboard TaskType = "TENSORBOARD"
 	// TaskTypeCheckpointGC is the "CHECKPOINT_GC" job type for the enum public.job_type in Postgres.
 	TaskTypeCheckpointGC TaskType = "CHECKPOINT_GC"
+	// TaskTypeModelExport is the "MODEL_EXPORT" task type for the enum.task_type in Postgres.
+	TaskTypeModelExport TaskType = "MODEL_EXPORT"
 )
 
 // TaskLogVersion is the version for our log-storing scheme. Useful because changing designs
@@ -170,6 +172,9 @@ const (
 	// AllocationStatePulling state denotes that the command's base image is being pulled from the
 	// Docker registry.
 	AllocationStatePulling AllocationState = "PULLING"
+	// AllocationStateStaging state denotes that the task's datasets are being staged into the
+	// local caches of its agents.
+	AllocationStateStaging AllocationState = "STAGING"
 	// AllocationStateStarting state denotes that the image has been pulled and the task is being
 	// started, but the task is not ready yet.
 	AllocationStateStarting AllocationState = "STARTING"
@@ -196,11 +201,12 @@ func MostProgressedAllocationState(states ...AllocationState) AllocationState {
 		AllocationStatePending:     0,
 		AllocationStateAssigned:    1,
 		AllocationStatePulling:     2,
-		AllocationStateStarting:    3,
-		AllocationStateRunning:     4,
-		AllocationStateWaiting:     5,
-		AllocationStateTerminating: 6,
-		AllocationStateTerminated:  7,
+		AllocationStateStaging:     3,
+		AllocationStateStarting:    4,
+		AllocationStateRunning:     5,
+		AllocationStateWaiting:     6,
+		AllocationStateTerminating: 7,
+		AllocationStateTerminated:  8,
 	}
 	maxOrder, state := statesToOrder[states[0]], states[0]
 	for _, s := range states {
@@ -233,6 +239,8 @@ func (s *AllocationState) Proto() taskv1.State {
 		return taskv1.State_STATE_WAITING
 	case AllocationStatePulling:
 		return taskv1.State_STATE_PULLING
+	case AllocationStateStaging:
+		return taskv1.State_STATE_STAGING
 	case AllocationStateStarting:
 		return taskv1.State_STATE_STARTING
 	case AllocationStateRunning:
@@ -45,3 +45,17 @@ func TestAllocationProto(t *testing.T) {
 	}
 	require.Equal(t, expected, a.Proto())
 }
+
+func TestAllocationStateProto(t *testing.T) {
+	for s, expected := range map[AllocationState]taskv1.State{
+		AllocationStateWaiting:     taskv1.State_STATE_WAITING,
+		AllocationStatePulling:     taskv1.State_STATE_PULLING,
+		AllocationStateStaging:     taskv1.State_STATE_STAGING,
+		AllocationStateStarting:    taskv1.State_STATE_STARTING,
+		AllocationStateRunning:     taskv1.State_STATE_RUNNING,
+		AllocationStateTerminating: taskv1.State_STATE_TERMINATING,
+		AllocationStateTerminated:  taskv1.State_STATE_TERMINATED,
+	} {
+		require.Equal(t, expected, s.Proto(), "state %s", s)
+	}
+}
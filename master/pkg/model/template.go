@@ -1,8 +1,28 @@
 package model
 
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
 // Template represents a row from the `templates` table.
 type Template struct {
 	Name        string `db:"name" json:"name"`
 	Config      []byte `db:"config" json:"config" bun:"config"`
 	WorkspaceID int    `db:"workspace_id" json:"workspace_id"`
 }
+
+// TemplateVersion represents a row from the `template_versions` table. Every update of a template
+// creates a new immutable version. Versions are deleted with the template, so a template
+// re-created under the same name does not inherit them.
+type TemplateVersion struct {
+	bun.BaseModel `bun:"table:template_versions"`
+
+	TemplateName string         `bun:"template_name,pk" json:"template_name"`
+	Version      int            `bun:"version,pk" json:"version"`
+	WorkspaceID  int            `bun:"workspace_id,notnull" json:"workspace_id"`
+	Config       map[string]any `bun:"config,type:jsonb,notnull" json:"config"`
+	CreatedBy    *UserID        `bun:"created_by" json:"created_by"`
+	CreatedAt    time.Time      `bun:"created_at,nullzero,notnull,default:current_timestamp" json:"created_at"`
+}
@@ -36,6 +36,24 @@ type Workspace struct {
 	CheckpointStorageConfig *expconf.CheckpointStorageConfig `bun:"checkpoint_storage_config"`
 	DefaultComputePool      string                           `bun:"default_compute_pool"`
 	DefaultAuxPool          string                           `bun:"default_aux_pool"`
+	// GPULimit is how many GPUs the tasks of the workspace can request at once, if limited.
+	GPULimit *int `bun:"gpu_limit"`
+	// ImagePolicy restricts the images the tasks of the workspace can run, if set.
+	ImagePolicy *ImagePolicy `bun:"image_policy"`
+	// EgressPolicy restricts the network traffic of the containers of the workspace's tasks, if
+	// set; otherwise the egress policy of the master applies.
+	EgressPolicy *EgressPolicy `bun:"egress_policy"`
+}
+
+// ImagePolicy restricts the images that the tasks of a workspace can run.
+type ImagePolicy struct {
+	// AllowedRepositories are the repositories tasks can run images from, or any repository if
+	// empty. Each is either a repository, like determinedai/pytorch-ngc, or a prefix ending in /*,
+	// like registry.example.com/* or determinedai/*, which allows every repository under it.
+	AllowedRepositories []string `json:"allowed_repositories"`
+	// RequireDigests pins images to the digest their tag points to when tasks are submitted, so
+	// that the config of each experiment records exactly which image its trials ran.
+	RequireDigests bool `json:"require_digests"`
 }
 
 // ToProto converts a bun model of a workspace to a proto object.
@@ -219,6 +219,69 @@ type ResourcesConfigV0 struct {
 	RawPriority       *int     `json:"priority"`
 
 	RawDevices DevicesConfigV0 `json:"devices"`
+
+	RawScratch *ScratchVolumeConfigV0 `json:"scratch,omitempty"`
+
+	RawStagedData StagedDataConfigV0 `json:"staged_data,omitempty"`
+}
+
+// ScratchVolumeConfigV0 configures a volume that is created for each task container and deleted
+// with it. Only the Kubernetes resource manager supports scratch volumes.
+//
+//go:generate ../gen.sh
+type ScratchVolumeConfigV0 struct {
+	RawSize         string  `json:"size"`
+	RawStorageClass *string `json:"storage_class"`
+	RawMountPath    *string `json:"mount_path"`
+}
+
+// StagedDataConfigV0 configures the datasets that are staged into the local cache of an agent
+// before the task's containers start there. Only the agent resource manager stages data.
+//
+//go:generate ../gen.sh
+type StagedDataConfigV0 []StagedDatasetV0
+
+// StagedDatasetV0 configures a dataset that is downloaded once per agent and cache key, and bind
+// mounted read-only into every container of the agent that stages it.
+//
+//go:generate ../gen.sh
+type StagedDatasetV0 struct {
+	RawSource    string `json:"source"`
+	RawCacheKey  string `json:"cache_key"`
+	RawMountPath string `json:"mount_path"`
+}
+
+// Validate implements the check.Validatable interface.
+func (s StagedDataConfigV0) Validate() []error {
+	var errs []error
+	keys, paths := map[string]bool{}, map[string]bool{}
+	for _, d := range s {
+		if keys[d.RawCacheKey] {
+			errs = append(errs, fmt.Errorf("cache_key %q is staged more than once", d.RawCacheKey))
+		}
+		if paths[d.RawMountPath] {
+			errs = append(errs, fmt.Errorf("mount_path %q is used more than once", d.RawMountPath))
+		}
+		keys[d.RawCacheKey], paths[d.RawMountPath] = true, true
+	}
+	return errs
+}
+
+// Validate implements the check.Validatable interface. Commands aren't validated against the
+// schema, so this repeats its checks.
+func (s StagedDatasetV0) Validate() []error {
+	var errs []error
+	if !strings.HasPrefix(s.RawSource, "s3://") && !strings.HasPrefix(s.RawSource, "gs://") &&
+		!strings.HasPrefix(s.RawSource, "file://") {
+		errs = append(errs, fmt.Errorf("source %q must be an s3://, gs:// or file:// URI", s.RawSource))
+	}
+	if s.RawCacheKey == "" || strings.ContainsAny(s.RawCacheKey, "/\\") || s.RawCacheKey[0] == '.' {
+		errs = append(errs, fmt.Errorf("cache_key %q must be a plain file name", s.RawCacheKey))
+	}
+	if !strings.HasPrefix(s.RawMountPath, "/") {
+		errs = append(errs, fmt.Errorf("mount_path %q must be an absolute path", s.RawMountPath))
+	}
+	return errs
 }
 
 // OptimizationsConfigV0 is a legacy config value.
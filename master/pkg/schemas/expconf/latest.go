@@ -43,9 +43,12 @@ type (
 	ReproducibilityConfig     = ReproducibilityConfigV0
 	ResourcesConfig           = ResourcesConfigV0
 	S3Config                  = S3ConfigV0
+	ScratchVolumeConfig       = ScratchVolumeConfigV0
 	SearcherConfig            = SearcherConfigV0
 	SharedFSConfig            = SharedFSConfigV0
 	SingleConfig              = SingleConfigV0
+	StagedDataConfig          = StagedDataConfigV0
+	StagedDataset             = StagedDatasetV0
 	SlurmConfig               = SlurmConfigV0
 )
 
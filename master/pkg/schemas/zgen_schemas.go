@@ -1843,6 +1843,14 @@ var (
             ],
             "default": ""
         },
+        "scratch": {
+            "type": [
+                "object",
+                "null"
+            ],
+            "default": null,
+            "optionalRef": "http://determined.ai/schemas/expconf/v0/scratch-volume.json"
+        },
         "shm_size": {
             "type": [
                 "integer",
@@ -1871,6 +1879,14 @@ var (
             "minimum": 0,
             "default": 1
         },
+        "staged_data": {
+            "type": [
+                "array",
+                "null"
+            ],
+            "default": null,
+            "optionalRef": "http://determined.ai/schemas/expconf/v0/staged-data.json"
+        },
         "weight": {
             "type": [
                 "number",
@@ -1982,6 +1998,41 @@ var (
         }
     }
 }
+`)
+	textScratchVolumeConfigV0 = []byte(`{
+    "$schema": "http://json-schema.org/draft-07/schema#",
+    "$id": "http://determined.ai/schemas/expconf/v0/scratch-volume.json",
+    "title": "ScratchVolumeConfig",
+    "type": "object",
+    "additionalProperties": false,
+    "required": [
+        "size"
+    ],
+    "properties": {
+        "size": {
+            "type": "string",
+            "checks": {
+                "must be a valid Kubernetes quantity, like 100Gi": {
+                    "pattern": "^[0-9]+([.][0-9]+)?([KMGTPE]i|[kMGTPE])?$"
+                }
+            }
+        },
+        "storage_class": {
+            "type": [
+                "string",
+                "null"
+            ],
+            "default": null
+        },
+        "mount_path": {
+            "type": [
+                "string",
+                "null"
+            ],
+            "default": "/scratch"
+        }
+    }
+}
 `)
 	textAdaptiveASHAConfigV0 = []byte(`{
     "$schema": "http://json-schema.org/draft-07/schema#",
@@ -2962,6 +3013,55 @@ var (
         }
     }
 }
+`)
+	textStagedDataConfigV0 = []byte(`{
+    "$schema": "http://json-schema.org/draft-07/schema#",
+    "$id": "http://determined.ai/schemas/expconf/v0/staged-data.json",
+    "title": "StagedDataConfig",
+    "type": "array",
+    "items": {
+        "$ref": "http://determined.ai/schemas/expconf/v0/staged-dataset.json"
+    }
+}
+`)
+	textStagedDatasetV0 = []byte(`{
+    "$schema": "http://json-schema.org/draft-07/schema#",
+    "$id": "http://determined.ai/schemas/expconf/v0/staged-dataset.json",
+    "title": "StagedDataset",
+    "type": "object",
+    "additionalProperties": false,
+    "required": [
+        "source",
+        "cache_key",
+        "mount_path"
+    ],
+    "properties": {
+        "source": {
+            "type": "string",
+            "checks": {
+                "must be an s3://, gs:// or file:// URI": {
+                    "pattern": "^(s3|gs|file)://.+$"
+                }
+            }
+        },
+        "cache_key": {
+            "type": "string",
+            "checks": {
+                "must be at most 128 letters, digits, dots, dashes and underscores": {
+                    "pattern": "^[a-zA-Z0-9][-_.a-zA-Z0-9]{0,127}$"
+                }
+            }
+        },
+        "mount_path": {
+            "type": "string",
+            "checks": {
+                "must be an absolute path": {
+                    "pattern": "^/"
+                }
+            }
+        }
+    }
+}
 `)
 	textTensorboardStorageConfigV0 = []byte(`{
     "$schema": "http://json-schema.org/draft-07/schema#",
@@ -3220,6 +3320,8 @@ var (
 
 	schemaS3ConfigV0 interface{}
 
+	schemaScratchVolumeConfigV0 interface{}
+
 	schemaAdaptiveASHAConfigV0 interface{}
 
 	schemaAdaptiveSimpleConfigV0 interface{}
@@ -3246,6 +3348,10 @@ var (
 
 	schemaSharedFSConfigV0 interface{}
 
+	schemaStagedDataConfigV0 interface{}
+
+	schemaStagedDatasetV0 interface{}
+
 	schemaTensorboardStorageConfigV0 interface{}
 
 	schemaTestRootV0 interface{}
@@ -4045,6 +4151,26 @@ func ParsedS3ConfigV0() interface{} {
 	return schemaS3ConfigV0
 }
 
+func ParsedScratchVolumeConfigV0() interface{} {
+	cacheLock.RLock()
+	if schemaScratchVolumeConfigV0 != nil {
+		cacheLock.RUnlock()
+		return schemaScratchVolumeConfigV0
+	}
+	cacheLock.RUnlock()
+
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	if schemaScratchVolumeConfigV0 != nil {
+		return schemaScratchVolumeConfigV0
+	}
+	err := json.Unmarshal(textScratchVolumeConfigV0, &schemaScratchVolumeConfigV0)
+	if err != nil {
+		panic("invalid embedded json for ScratchVolumeConfigV0")
+	}
+	return schemaScratchVolumeConfigV0
+}
+
 func ParsedAdaptiveASHAConfigV0() interface{} {
 	cacheLock.RLock()
 	if schemaAdaptiveASHAConfigV0 != nil {
@@ -4305,6 +4431,46 @@ func ParsedSharedFSConfigV0() interface{} {
 	return schemaSharedFSConfigV0
 }
 
+func ParsedStagedDataConfigV0() interface{} {
+	cacheLock.RLock()
+	if schemaStagedDataConfigV0 != nil {
+		cacheLock.RUnlock()
+		return schemaStagedDataConfigV0
+	}
+	cacheLock.RUnlock()
+
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	if schemaStagedDataConfigV0 != nil {
+		return schemaStagedDataConfigV0
+	}
+	err := json.Unmarshal(textStagedDataConfigV0, &schemaStagedDataConfigV0)
+	if err != nil {
+		panic("invalid embedded json for StagedDataConfigV0")
+	}
+	return schemaStagedDataConfigV0
+}
+
+func ParsedStagedDatasetV0() interface{} {
+	cacheLock.RLock()
+	if schemaStagedDatasetV0 != nil {
+		cacheLock.RUnlock()
+		return schemaStagedDatasetV0
+	}
+	cacheLock.RUnlock()
+
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	if schemaStagedDatasetV0 != nil {
+		return schemaStagedDatasetV0
+	}
+	err := json.Unmarshal(textStagedDatasetV0, &schemaStagedDatasetV0)
+	if err != nil {
+		panic("invalid embedded json for StagedDatasetV0")
+	}
+	return schemaStagedDatasetV0
+}
+
 func ParsedTensorboardStorageConfigV0() interface{} {
 	cacheLock.RLock()
 	if schemaTensorboardStorageConfigV0 != nil {
@@ -4518,6 +4684,8 @@ func schemaBytesMap() map[string][]byte {
 	cachedSchemaBytesMap[url] = textResourcesConfigV0
 	url = "http://determined.ai/schemas/expconf/v0/s3.json"
 	cachedSchemaBytesMap[url] = textS3ConfigV0
+	url = "http://determined.ai/schemas/expconf/v0/scratch-volume.json"
+	cachedSchemaBytesMap[url] = textScratchVolumeConfigV0
 	url = "http://determined.ai/schemas/expconf/v0/searcher-adaptive-asha.json"
 	cachedSchemaBytesMap[url] = textAdaptiveASHAConfigV0
 	url = "http://determined.ai/schemas/expconf/v0/searcher-adaptive-simple.json"
@@ -4544,6 +4712,10 @@ func schemaBytesMap() map[string][]byte {
 	cachedSchemaBytesMap[url] = textSecurityConfigV0
 	url = "http://determined.ai/schemas/expconf/v0/shared-fs.json"
 	cachedSchemaBytesMap[url] = textSharedFSConfigV0
+	url = "http://determined.ai/schemas/expconf/v0/staged-data.json"
+	cachedSchemaBytesMap[url] = textStagedDataConfigV0
+	url = "http://determined.ai/schemas/expconf/v0/staged-dataset.json"
+	cachedSchemaBytesMap[url] = textStagedDatasetV0
 	url = "http://determined.ai/schemas/expconf/v0/tensorboard-storage.json"
 	cachedSchemaBytesMap[url] = textTensorboardStorageConfigV0
 	url = "http://determined.ai/schemas/expconf/v0/test-root.json"
@@ -2,6 +2,7 @@
 package searcher
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -196,3 +197,134 @@ func TestCustomSearchWatcher(t *testing.T) {
 	// Unwatching should work.
 	queue.Unwatch(w2.ID)
 }
+
+func TestCustomSearchWatchAfter(t *testing.T) {
+	queue := newSearcherEventQueue()
+	for i := 0; i < 3; i++ {
+		queue.Enqueue(&experimentv1.SearcherEvent{
+			Event: &experimentv1.SearcherEvent_InitialOperations{
+				InitialOperations: &experimentv1.InitialOperations{},
+			},
+		})
+	}
+
+	// Only events after the given one are sent.
+	w, err := queue.WatchAfter(1)
+	require.NoError(t, err)
+	select {
+	case events := <-w.C:
+		require.Equal(t, []int32{2, 3}, eventIDs(events))
+	default:
+		t.Fatal("did not receive events")
+	}
+
+	// A watcher that has seen every event waits for the next one.
+	w2, err := queue.WatchAfter(3)
+	require.NoError(t, err)
+	select {
+	case <-w2.C:
+		t.Fatal("received events that were already seen")
+	default:
+	}
+	queue.Enqueue(&experimentv1.SearcherEvent{
+		Event: &experimentv1.SearcherEvent_InitialOperations{
+			InitialOperations: &experimentv1.InitialOperations{},
+		},
+	})
+	select {
+	case events := <-w2.C:
+		require.Equal(t, []int32{4}, eventIDs(events))
+	default:
+		t.Fatal("did not receive events")
+	}
+
+	// Events removed from the queue are not resent.
+	require.NoError(t, queue.RemoveUpTo(3))
+	w3, err := queue.WatchAfter(0)
+	require.NoError(t, err)
+	select {
+	case events := <-w3.C:
+		require.Equal(t, []int32{4}, eventIDs(events))
+	default:
+		t.Fatal("did not receive events")
+	}
+}
+
+func TestCustomSearchStream(t *testing.T) {
+	queue := newSearcherEventQueue()
+	enqueue := func() {
+		queue.Enqueue(&experimentv1.SearcherEvent{
+			Event: &experimentv1.SearcherEvent_InitialOperations{
+				InitialOperations: &experimentv1.InitialOperations{},
+			},
+		})
+	}
+	enqueue()
+	enqueue()
+
+	w, err := queue.Stream(1)
+	require.NoError(t, err)
+	select {
+	case events := <-w.C:
+		require.Equal(t, []int32{2}, eventIDs(events))
+	default:
+		t.Fatal("did not receive events")
+	}
+
+	// The watcher stays registered, and events it hasn't received yet are sent together.
+	enqueue()
+	enqueue()
+	select {
+	case events := <-w.C:
+		require.Equal(t, []int32{3, 4}, eventIDs(events))
+	default:
+		t.Fatal("did not receive events")
+	}
+	enqueue()
+	select {
+	case events := <-w.C:
+		require.Equal(t, []int32{5}, eventIDs(events))
+	default:
+		t.Fatal("did not receive events")
+	}
+
+	queue.Unwatch(w.ID)
+	enqueue()
+	select {
+	case <-w.C:
+		t.Fatal("received events after unwatching")
+	default:
+	}
+}
+
+func TestCustomSearchAck(t *testing.T) {
+	queue := newSearcherEventQueue()
+	for i := 0; i < 3; i++ {
+		queue.Enqueue(&experimentv1.SearcherEvent{
+			Event: &experimentv1.SearcherEvent_InitialOperations{
+				InitialOperations: &experimentv1.InitialOperations{},
+			},
+		})
+	}
+
+	require.True(t, queue.Ack(2))
+	// Acknowledgements only move forward, and only up to the last event.
+	require.False(t, queue.Ack(1))
+	require.False(t, queue.Ack(4))
+	require.Equal(t, int32(2), queue.LastAck())
+
+	// The last acknowledged event is kept across restarts.
+	bs, err := json.Marshal(queue)
+	require.NoError(t, err)
+	restored := newSearcherEventQueue()
+	require.NoError(t, json.Unmarshal(bs, restored))
+	require.Equal(t, int32(2), restored.LastAck())
+}
+
+func eventIDs(events []*experimentv1.SearcherEvent) []int32 {
+	var ids []int32
+	for _, e := range events {
+		ids = append(ids, e.Id)
+	}
+	return ids
+}
@@ -18,13 +18,25 @@ type (
 	SearcherEventQueue struct {
 		events     []*experimentv1.SearcherEvent
 		eventCount int32
-		watchers   map[uuid.UUID]chan<- []*experimentv1.SearcherEvent
+		// lastAck is the ID of the last event the client acknowledged having processed.
+		lastAck  int32
+		watchers map[uuid.UUID]eventsWatcher
+	}
+
+	// eventsWatcher is a registered watcher along with the ID of the last event it has seen.
+	eventsWatcher struct {
+		c     chan []*experimentv1.SearcherEvent
+		after int32
+		// stream is set for watchers that stay registered and receive every new event, instead of
+		// receiving a single list of events.
+		stream bool
 	}
 
 	// searcherEventQueueJSON is used internally for JSON marshaling purposes.
 	searcherEventQueueJSON struct {
 		Events     []json.RawMessage `json:"custom_searcher_events"`
 		EventCount int32             `json:"custom_searcher_event_count"`
+		LastAck    int32             `json:"custom_searcher_last_ack"`
 	}
 
 	// EventsWatcher has a channel which allows communication to the GET searcher events API.
@@ -38,34 +50,75 @@ func newSearcherEventQueue() *SearcherEventQueue {
 	return &SearcherEventQueue{
 		events:     nil,
 		eventCount: 0,
-		watchers:   map[uuid.UUID]chan<- []*experimentv1.SearcherEvent{},
+		watchers:   map[uuid.UUID]eventsWatcher{},
+	}
+}
+
+// eventsAfter returns a copy of the events in the queue with an ID greater than the given one.
+func (q *SearcherEventQueue) eventsAfter(after int32) []*experimentv1.SearcherEvent {
+	events := []*experimentv1.SearcherEvent{}
+	for _, event := range q.events {
+		if event.Id > after {
+			events = append(events, event)
+		}
 	}
+	return events
 }
 
-func (q *SearcherEventQueue) sendEventsToWatcher(
-	id uuid.UUID,
-	w chan<- []*experimentv1.SearcherEvent,
-) {
-	events := make([]*experimentv1.SearcherEvent, len(q.events))
-	copy(events, q.events)
-	w <- events
-	close(w)
+func (q *SearcherEventQueue) sendEventsToWatcher(id uuid.UUID, w eventsWatcher) {
+	w.c <- q.eventsAfter(w.after)
+	close(w.c)
 	delete(q.watchers, id)
 }
 
+// streamEventToWatcher adds an event to the events a streaming watcher has not received yet. The
+// queue is the only sender, so after taking any pending events out of the buffer the send never
+// blocks.
+func streamEventToWatcher(w eventsWatcher, event *experimentv1.SearcherEvent) {
+	events := []*experimentv1.SearcherEvent{event}
+	select {
+	case pending := <-w.c:
+		events = append(pending, event)
+	default:
+	}
+	w.c <- events
+}
+
 // Watch creates an eventsWatcher. If any events are currently in the queue, they are immediately
 // sent; otherwise, the channel in the result will block until an event comes in.
 func (q *SearcherEventQueue) Watch() (EventsWatcher, error) {
+	return q.WatchAfter(0)
+}
+
+// WatchAfter creates an eventsWatcher that only receives events with an ID greater than the given
+// one, which lets a client that has already seen some of the events in the queue resume after
+// them.
+func (q *SearcherEventQueue) WatchAfter(after int32) (EventsWatcher, error) {
 	// Buffer size is 1 because we don't want to block until another goroutine receives from this
 	// channel and only one event list can be sent to a channel.
-	w := make(chan []*experimentv1.SearcherEvent, 1)
+	c := make(chan []*experimentv1.SearcherEvent, 1)
 	id := uuid.New()
+	w := eventsWatcher{c: c, after: after}
 	q.watchers[id] = w
 
-	if len(q.events) > 0 {
+	if len(q.eventsAfter(after)) > 0 {
 		q.sendEventsToWatcher(id, w)
 	}
-	return EventsWatcher{ID: id, C: w}, nil
+	return EventsWatcher{ID: id, C: c}, nil
+}
+
+// Stream creates an eventsWatcher that stays registered until it is unwatched. Its channel
+// receives the events in the queue with an ID greater than the given one and then every event
+// that comes in; events that come in before the previous ones were received are sent together.
+func (q *SearcherEventQueue) Stream(after int32) (EventsWatcher, error) {
+	c := make(chan []*experimentv1.SearcherEvent, 1)
+	id := uuid.New()
+	q.watchers[id] = eventsWatcher{c: c, after: after, stream: true}
+
+	if events := q.eventsAfter(after); len(events) > 0 {
+		c <- events
+	}
+	return EventsWatcher{ID: id, C: c}, nil
 }
 
 // Unwatch unregisters an eventsWatcher.
@@ -84,7 +137,13 @@ func (q *SearcherEventQueue) Enqueue(event *experimentv1.SearcherEvent) {
 
 	// Add events to all watcher channels.
 	for id, w := range q.watchers {
-		q.sendEventsToWatcher(id, w)
+		switch {
+		case event.Id <= w.after:
+		case w.stream:
+			streamEventToWatcher(w, event)
+		default:
+			q.sendEventsToWatcher(id, w)
+		}
 	}
 }
 
@@ -104,6 +163,22 @@ func (q *SearcherEventQueue) RemoveUpTo(eventID int) error {
 	return nil
 }
 
+// Ack records that the client processed every event up to and including the one with the given
+// ID. It returns whether that moved the last acknowledged event forward.
+func (q *SearcherEventQueue) Ack(eventID int32) bool {
+	if eventID <= q.lastAck || eventID > q.eventCount {
+		return false
+	}
+	q.lastAck = eventID
+	return true
+}
+
+// LastAck returns the ID of the last event the client acknowledged, or 0 if it hasn't
+// acknowledged any.
+func (q *SearcherEventQueue) LastAck() int32 {
+	return q.lastAck
+}
+
 // MarshalJSON implements the json.Marshaler interface.
 func (q *SearcherEventQueue) MarshalJSON() ([]byte, error) {
 	events, err := marshalEvents(q.events)
@@ -114,6 +189,7 @@ func (q *SearcherEventQueue) MarshalJSON() ([]byte, error) {
 	return json.Marshal(searcherEventQueueJSON{
 		Events:     events,
 		EventCount: q.eventCount,
+		LastAck:    q.lastAck,
 	})
 }
 
@@ -129,7 +205,8 @@ func (q *SearcherEventQueue) UnmarshalJSON(data []byte) error {
 	}
 	q.events = events
 	q.eventCount = js.EventCount
-	q.watchers = map[uuid.UUID]chan<- []*experimentv1.SearcherEvent{}
+	q.lastAck = js.LastAck
+	q.watchers = map[uuid.UUID]eventsWatcher{}
 	return nil
 }
 
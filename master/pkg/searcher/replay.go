@@ -0,0 +1,294 @@
+package searcher
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// ReplayValidation is a recorded validation metric of a trial after it trained for Length
+// searcher units.
+type ReplayValidation struct {
+	Length float64
+	Metric float64
+}
+
+// ReplayTrial is the recorded history of a real trial that simulated trials are matched against.
+type ReplayTrial struct {
+	TrialID int
+	Hparams HParamSample
+	// Validations are sorted by length.
+	Validations []ReplayValidation
+	// SlotSecondsPerUnit is how many slot-seconds the trial took to train for one searcher unit.
+	SlotSecondsPerUnit float64
+}
+
+// ReplayResult summarizes what a searcher would have done against the recorded trials.
+type ReplayResult struct {
+	Simulation Simulation `json:"simulation"`
+	// Trials is the number of trials the searcher created.
+	Trials int `json:"trials"`
+	// Units is the total number of searcher units the trials trained for.
+	Units float64 `json:"units"`
+	// GPUHours is an estimate of the slot-hours the search would have used.
+	GPUHours float64 `json:"gpu_hours"`
+	// BestMetric is the best validation metric the search would have found.
+	BestMetric *float64 `json:"best_metric"`
+	// BestHparams are the hyperparameters of the simulated trial that found BestMetric.
+	BestHparams HParamSample `json:"best_hparams"`
+	// BestRecordedTrialID is the real trial nearest to BestHparams.
+	BestRecordedTrialID int `json:"best_recorded_trial_id"`
+}
+
+// replayNeighbor is a recorded trial and its weight in the estimate for a simulated trial.
+type replayNeighbor struct {
+	trial  *ReplayTrial
+	weight float64
+}
+
+// replayer estimates the behavior of simulated trials from the recorded trials nearest to them in
+// hyperparameter space.
+type replayer struct {
+	trials    []ReplayTrial
+	neighbors int
+	// ranges holds the spread of every numeric hyperparameter across recorded trials, used to
+	// normalize distances.
+	ranges map[string]float64
+	cache  map[string][]replayNeighbor
+}
+
+func newReplayer(trials []ReplayTrial, neighbors int) *replayer {
+	mins, maxs := map[string]float64{}, map[string]float64{}
+	for _, t := range trials {
+		for k, v := range flattenHparams(t.Hparams) {
+			f, ok := toFloat(v)
+			if !ok {
+				continue
+			}
+			if cur, ok := mins[k]; !ok || f < cur {
+				mins[k] = f
+			}
+			if cur, ok := maxs[k]; !ok || f > cur {
+				maxs[k] = f
+			}
+		}
+	}
+	ranges := make(map[string]float64, len(mins))
+	for k := range mins {
+		ranges[k] = maxs[k] - mins[k]
+	}
+	if neighbors > len(trials) {
+		neighbors = len(trials)
+	}
+	return &replayer{
+		trials:    trials,
+		neighbors: neighbors,
+		ranges:    ranges,
+		cache:     map[string][]replayNeighbor{},
+	}
+}
+
+// distance is the normalized euclidean distance between two hyperparameter samples. Numeric
+// values contribute their difference relative to the recorded range; any other value contributes
+// 0 if equal and 1 otherwise.
+func (r *replayer) distance(a, b map[string]any) float64 {
+	var sum float64
+	keys := map[string]bool{}
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	for k := range keys {
+		av, aok := a[k]
+		bv, bok := b[k]
+		if !aok || !bok {
+			sum++
+			continue
+		}
+		af, aNum := toFloat(av)
+		bf, bNum := toFloat(bv)
+		switch {
+		case aNum && bNum:
+			if span := r.ranges[k]; span > 0 {
+				d := (af - bf) / span
+				sum += d * d
+			} else if af != bf {
+				sum++
+			}
+		case !reflect.DeepEqual(av, bv):
+			sum++
+		}
+	}
+	return math.Sqrt(sum)
+}
+
+// nearest returns the recorded trials nearest to the hyperparameters, weighted by inverse
+// distance. An exact match gets all the weight.
+func (r *replayer) nearest(hparams HParamSample) []replayNeighbor {
+	key := fmt.Sprint(hparams)
+	if n, ok := r.cache[key]; ok {
+		return n
+	}
+
+	flat := flattenHparams(hparams)
+	type candidate struct {
+		idx  int
+		dist float64
+	}
+	candidates := make([]candidate, len(r.trials))
+	for i := range r.trials {
+		dist := r.distance(flat, flattenHparams(r.trials[i].Hparams))
+		candidates[i] = candidate{idx: i, dist: dist}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].dist < candidates[j].dist
+	})
+
+	var neighbors []replayNeighbor
+	if candidates[0].dist == 0 {
+		neighbors = []replayNeighbor{{trial: &r.trials[candidates[0].idx], weight: 1}}
+	} else {
+		var total float64
+		for _, c := range candidates[:r.neighbors] {
+			w := 1 / c.dist
+			total += w
+			neighbors = append(neighbors, replayNeighbor{trial: &r.trials[c.idx], weight: w})
+		}
+		for i := range neighbors {
+			neighbors[i].weight /= total
+		}
+	}
+	r.cache[key] = neighbors
+	return neighbors
+}
+
+// metric estimates the validation metric of a trial with the hyperparameters after it trained for
+// length units.
+func (r *replayer) metric(hparams HParamSample, length float64) float64 {
+	var m float64
+	for _, n := range r.nearest(hparams) {
+		m += n.weight * interpolateValidations(n.trial.Validations, length)
+	}
+	return m
+}
+
+// slotSeconds estimates the slot-seconds a trial with the hyperparameters took to train for
+// length units.
+func (r *replayer) slotSeconds(hparams HParamSample, length float64) float64 {
+	var s float64
+	for _, n := range r.nearest(hparams) {
+		s += n.weight * n.trial.SlotSecondsPerUnit * length
+	}
+	return s
+}
+
+// interpolateValidations linearly interpolates the metric at the length. Lengths outside the
+// recorded range take the nearest recorded metric.
+func interpolateValidations(vals []ReplayValidation, length float64) float64 {
+	i := sort.Search(len(vals), func(i int) bool { return vals[i].Length >= length })
+	switch {
+	case i == 0:
+		return vals[0].Metric
+	case i == len(vals):
+		return vals[len(vals)-1].Metric
+	}
+	lo, hi := vals[i-1], vals[i]
+	frac := (length - lo.Length) / (hi.Length - lo.Length)
+	return lo.Metric + frac*(hi.Metric-lo.Metric)
+}
+
+// Replay simulates the searcher, taking the validation metrics of each simulated trial from the
+// recorded trials nearest to it by hyperparameters. With more than one neighbor, metrics and
+// costs are interpolated between the neighbors, weighted by inverse distance.
+func Replay(
+	s *Searcher, seed *int64, trials []ReplayTrial, neighbors int, smallerIsBetter bool,
+) (ReplayResult, error) {
+	var result ReplayResult
+	if neighbors < 1 {
+		return result, errors.New("neighbors must be at least 1")
+	}
+	var recorded []ReplayTrial
+	for _, t := range trials {
+		if len(t.Validations) > 0 {
+			recorded = append(recorded, t)
+		}
+	}
+	if len(recorded) == 0 {
+		return result, errors.New("no recorded trials with validations to replay against")
+	}
+
+	r := newReplayer(recorded, neighbors)
+	lengths := map[int]float64{}
+	trialHparams := map[int]HParamSample{}
+	sim, err := simulate(s, seed, true,
+		func(_ *rand.Rand, trialID int, hparams HParamSample, op ValidateAfter, _ int) float64 {
+			length := float64(op.Length)
+			lengths[trialID] = math.Max(lengths[trialID], length)
+			trialHparams[trialID] = hparams
+
+			metric := r.metric(hparams, length)
+			if result.BestMetric == nil ||
+				(smallerIsBetter && metric < *result.BestMetric) ||
+				(!smallerIsBetter && metric > *result.BestMetric) {
+				result.BestMetric = &metric
+				result.BestHparams = hparams
+				result.BestRecordedTrialID = r.nearest(hparams)[0].trial.TrialID
+			}
+			return metric
+		})
+	if err != nil {
+		return result, err
+	}
+
+	result.Simulation = sim
+	result.Trials = len(sim.Results)
+	var slotSeconds float64
+	for trialID, length := range lengths {
+		result.Units += length
+		slotSeconds += r.slotSeconds(trialHparams[trialID], length)
+	}
+	result.GPUHours = slotSeconds / 3600
+	return result, nil
+}
+
+// flattenHparams flattens nested hyperparameters into dotted keys.
+func flattenHparams(h map[string]any) map[string]any {
+	out := map[string]any{}
+	var flatten func(prefix string, m map[string]any)
+	flatten = func(prefix string, m map[string]any) {
+		for k, v := range m {
+			switch v := v.(type) {
+			case map[string]any:
+				flatten(prefix+k+".", v)
+			case HParamSample:
+				flatten(prefix+k+".", v)
+			default:
+				out[prefix+k] = v
+			}
+		}
+	}
+	flatten("", h)
+	return out
+}
+
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
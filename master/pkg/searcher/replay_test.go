@@ -0,0 +1,92 @@
+//nolint:exhaustruct
+package searcher
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/determined-ai/determined/master/pkg/ptrs"
+	"github.com/determined-ai/determined/master/pkg/schemas"
+	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
+)
+
+func TestInterpolateValidations(t *testing.T) {
+	vals := []ReplayValidation{{Length: 10, Metric: 1}, {Length: 20, Metric: 3}}
+	assert.Equal(t, interpolateValidations(vals, 5), 1.0)
+	assert.Equal(t, interpolateValidations(vals, 10), 1.0)
+	assert.Equal(t, interpolateValidations(vals, 15), 2.0)
+	assert.Equal(t, interpolateValidations(vals, 20), 3.0)
+	assert.Equal(t, interpolateValidations(vals, 30), 3.0)
+}
+
+func TestReplayNearest(t *testing.T) {
+	r := newReplayer([]ReplayTrial{
+		{TrialID: 1, Hparams: HParamSample{"x": 0, "opt": "sgd"}},
+		{TrialID: 2, Hparams: HParamSample{"x": 10, "opt": "sgd"}},
+		{TrialID: 3, Hparams: HParamSample{"x": 10, "opt": "adam"}},
+	}, 2)
+
+	n := r.nearest(HParamSample{"x": 10, "opt": "adam"})
+	assert.Equal(t, len(n), 1)
+	assert.Equal(t, n[0].trial.TrialID, 3)
+
+	n = r.nearest(HParamSample{"x": 2.0, "opt": "sgd"})
+	assert.Equal(t, len(n), 2)
+	assert.Equal(t, n[0].trial.TrialID, 1)
+	assert.Equal(t, n[1].trial.TrialID, 2)
+	assert.Assert(t, n[0].weight > n[1].weight)
+	assert.Equal(t, n[0].weight+n[1].weight, 1.0)
+}
+
+func TestReplayGrid(t *testing.T) {
+	config := expconf.GridConfig{RawMaxLength: ptrs.Ptr(expconf.NewLengthInBatches(100))}
+	config = schemas.WithDefaults(config)
+	params := expconf.Hyperparameters{
+		"x": expconf.Hyperparameter{
+			RawIntHyperparameter: &expconf.IntHyperparameter{RawMaxval: 20, RawCount: ptrs.Ptr(3)},
+		},
+	}
+	recorded := []ReplayTrial{
+		{
+			TrialID:            1,
+			Hparams:            HParamSample{"x": 0},
+			Validations:        []ReplayValidation{{Length: 50, Metric: 0.5}, {Length: 100, Metric: 0.4}},
+			SlotSecondsPerUnit: 36,
+		},
+		{
+			TrialID:            2,
+			Hparams:            HParamSample{"x": 10},
+			Validations:        []ReplayValidation{{Length: 100, Metric: 0.2}},
+			SlotSecondsPerUnit: 72,
+		},
+		{
+			TrialID:            3,
+			Hparams:            HParamSample{"x": 20},
+			Validations:        []ReplayValidation{{Length: 100, Metric: 0.3}},
+			SlotSecondsPerUnit: 36,
+		},
+		// Trials without validations are ignored.
+		{TrialID: 4, Hparams: HParamSample{"x": 10}},
+	}
+
+	s := NewSearcher(0, newGridSearch(config), params)
+	result, err := Replay(s, new(int64), recorded, 1, true)
+	assert.NilError(t, err)
+	assert.Equal(t, result.Trials, 3)
+	assert.Equal(t, result.Units, 300.0)
+	assert.Equal(t, result.GPUHours, 4.0)
+	assert.Equal(t, *result.BestMetric, 0.2)
+	assert.DeepEqual(t, result.BestHparams, HParamSample{"x": 10})
+	assert.Equal(t, result.BestRecordedTrialID, 2)
+
+	s = NewSearcher(0, newGridSearch(config), params)
+	result, err = Replay(s, new(int64), recorded, 1, false)
+	assert.NilError(t, err)
+	assert.Equal(t, *result.BestMetric, 0.4)
+	assert.Equal(t, result.BestRecordedTrialID, 1)
+
+	s = NewSearcher(0, newGridSearch(config), params)
+	_, err = Replay(s, new(int64), recorded[3:], 1, true)
+	assert.ErrorContains(t, err, "no recorded trials")
+}
@@ -54,6 +54,21 @@ type Simulation struct {
 // Simulate simulates the searcher.
 func Simulate(
 	s *Searcher, seed *int64, valFunc ValidationFunction, randomOrder bool, metricName string,
+) (Simulation, error) {
+	return simulate(s, seed, randomOrder,
+		func(random *rand.Rand, trialID int, _ HParamSample, _ ValidateAfter, idx int) float64 {
+			return valFunc(random, trialID, idx)
+		})
+}
+
+// metricFunction calculates the validation metric for a validation step from the hyperparameters
+// of the trial and the length it has trained for.
+type metricFunction func(
+	random *rand.Rand, trialID int, hparams HParamSample, op ValidateAfter, idx int,
+) float64
+
+func simulate(
+	s *Searcher, seed *int64, randomOrder bool, metricFunc metricFunction,
 ) (Simulation, error) {
 	simulation := Simulation{
 		Results: make(SimulationResults),
@@ -70,6 +85,7 @@ func Simulate(
 	lengthCompleted := make(map[model.RequestID]PartialUnits)
 	pending := make(map[model.RequestID][]Operation)
 	trialIDs := make(map[model.RequestID]int)
+	hparams := make(map[model.RequestID]HParamSample)
 	var requestIDs []model.RequestID
 	ops, err := s.InitialOperations()
 	if err != nil {
@@ -100,6 +116,7 @@ func Simulate(
 		case Create:
 			simulation.Results[requestID] = []ValidateAfter{}
 			trialIDs[requestID] = nextTrialID
+			hparams[requestID] = operation.Hparams
 			ops, err := s.TrialCreated(operation.RequestID)
 			if err != nil {
 				return simulation, err
@@ -115,7 +132,8 @@ func Simulate(
 			simulation.Results[requestID] = append(simulation.Results[requestID], operation)
 			s.SetTrialProgress(requestID, PartialUnits(operation.Length))
 
-			metric := valFunc(random, trialIDs[requestID], trialOpIdxs[requestID])
+			metric := metricFunc(
+				random, trialIDs[requestID], hparams[requestID], operation, trialOpIdxs[requestID])
 			ops, err := s.ValidationCompleted(requestID, metric, operation)
 			if err != nil {
 				return simulation, err
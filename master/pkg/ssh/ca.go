@@ -0,0 +1,91 @@
+package ssh
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"time"
+
+	"github.com/pkg/errors"
+	sshlib "golang.org/x/crypto/ssh"
+)
+
+// certificateClockSkew is how far in the past certificates become valid, so that clients with
+// clocks slightly behind the master's can use them right away.
+const certificateClockSkew = 5 * time.Minute
+
+// CertAuthority signs short-lived certificates for users' SSH keys and checks them.
+type CertAuthority struct {
+	signer sshlib.Signer
+}
+
+// NewCertAuthority returns a CertAuthority that signs with the given PEM encoded private key, such
+// as the PrivateKey returned by GenerateKey.
+func NewCertAuthority(privateKey []byte) (*CertAuthority, error) {
+	signer, err := sshlib.ParsePrivateKey(privateKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse certificate authority key")
+	}
+	return &CertAuthority{signer: signer}, nil
+}
+
+// PublicKey returns the public key of the authority, which sshd's TrustedUserCAKeys accepts.
+func (ca *CertAuthority) PublicKey() sshlib.PublicKey {
+	return ca.signer.PublicKey()
+}
+
+// SignUserKey returns a user certificate for key that is valid for ttl and names principal as the
+// only user it may log in as.
+func (ca *CertAuthority) SignUserKey(
+	key sshlib.PublicKey, principal string, ttl time.Duration,
+) (*sshlib.Certificate, error) {
+	var serial [8]byte
+	if _, err := rand.Read(serial[:]); err != nil {
+		return nil, errors.Wrap(err, "unable to generate certificate serial")
+	}
+	now := time.Now()
+	cert := &sshlib.Certificate{
+		Key:             key,
+		Serial:          binary.BigEndian.Uint64(serial[:]),
+		CertType:        sshlib.UserCert,
+		KeyId:           principal,
+		ValidPrincipals: []string{principal},
+		ValidAfter:      uint64(now.Add(-certificateClockSkew).Unix()),
+		ValidBefore:     uint64(now.Add(ttl).Unix()),
+		Permissions: sshlib.Permissions{
+			Extensions: map[string]string{
+				"permit-agent-forwarding": "",
+				"permit-port-forwarding":  "",
+				"permit-pty":              "",
+			},
+		},
+	}
+	if err := cert.SignCert(rand.Reader, ca.signer); err != nil {
+		return nil, errors.Wrap(err, "unable to sign certificate")
+	}
+	return cert, nil
+}
+
+// CheckUserCert checks that cert is a user certificate signed by the authority that is currently
+// valid, and returns the principal it was issued to.
+func (ca *CertAuthority) CheckUserCert(cert *sshlib.Certificate) (string, error) {
+	if cert.CertType != sshlib.UserCert {
+		return "", errors.New("not a user certificate")
+	}
+	if len(cert.ValidPrincipals) != 1 {
+		return "", errors.Errorf("certificate must name exactly one principal, got %d",
+			len(cert.ValidPrincipals))
+	}
+	principal := cert.ValidPrincipals[0]
+	checker := sshlib.CertChecker{
+		IsUserAuthority: func(auth sshlib.PublicKey) bool {
+			return string(auth.Marshal()) == string(ca.signer.PublicKey().Marshal())
+		},
+	}
+	if !checker.IsUserAuthority(cert.SignatureKey) {
+		return "", errors.New("certificate signed by an unknown authority")
+	}
+	if err := checker.CheckCert(principal, cert); err != nil {
+		return "", err
+	}
+	return principal, nil
+}
@@ -0,0 +1,52 @@
+package ssh
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	sshlib "golang.org/x/crypto/ssh"
+)
+
+func TestCertAuthority(t *testing.T) {
+	newCA := func() *CertAuthority {
+		keys, err := GenerateKey(1024, nil)
+		require.NoError(t, err)
+		ca, err := NewCertAuthority(keys.PrivateKey)
+		require.NoError(t, err)
+		return ca
+	}
+	ca, other := newCA(), newCA()
+
+	userKeys, err := GenerateKey(1024, nil)
+	require.NoError(t, err)
+	userKey, _, _, _, err := sshlib.ParseAuthorizedKey(userKeys.PublicKey)
+	require.NoError(t, err)
+
+	cert, err := ca.SignUserKey(userKey, "alice", time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, userKey.Marshal(), cert.Key.Marshal())
+	principal, err := ca.CheckUserCert(cert)
+	require.NoError(t, err)
+	require.Equal(t, "alice", principal)
+
+	// Certificates round trip through the authorized keys format clients store them in.
+	parsed, _, _, _, err := sshlib.ParseAuthorizedKey(sshlib.MarshalAuthorizedKey(cert))
+	require.NoError(t, err)
+	principal, err = ca.CheckUserCert(parsed.(*sshlib.Certificate))
+	require.NoError(t, err)
+	require.Equal(t, "alice", principal)
+
+	_, err = other.CheckUserCert(cert)
+	require.ErrorContains(t, err, "unknown authority")
+
+	expired, err := ca.SignUserKey(userKey, "alice", -time.Hour)
+	require.NoError(t, err)
+	_, err = ca.CheckUserCert(expired)
+	require.Error(t, err)
+
+	// Tampering with the principal invalidates the signature.
+	cert.ValidPrincipals = []string{"admin"}
+	_, err = ca.CheckUserCert(cert)
+	require.Error(t, err)
+}
@@ -40,4 +40,8 @@ const (
 	pubKeyMode     = 0o600
 
 	shellAuthorizedKeysFile = "/run/determined/ssh/authorized_keys_unmodified"
+
+	// commandRendezvousPort is the container port that rendezvous reports the addresses of the
+	// containers of commands and shells from.
+	commandRendezvousPort = 1734
 )
@@ -82,8 +82,13 @@ type TaskSpec struct {
 	AgentUserGroup        *model.AgentUserGroup
 	ExtraArchives         []cproto.RunArchive
 	ExtraEnvVars          map[string]string
-	Entrypoint            []string
-	Mounts                []mount.Mount
+	// SecretEnvVars and SecretArchives hold the values of the secrets the task references. They
+	// are kept apart from ExtraEnvVars and ExtraArchives so that resource managers that can store
+	// them as secrets do.
+	SecretEnvVars  map[string]string
+	SecretArchives []cproto.RunArchive
+	Entrypoint     []string
+	Mounts         []mount.Mount
 	// UseHostMode is whether host mode networking would be desirable for this task.
 	// This is used by Docker only.
 	UseHostMode bool
@@ -109,6 +114,11 @@ type TaskSpec struct {
 	Workspace string
 	Project   string
 	Labels    []string
+	// WorkspaceID is the workspace whose secrets and shared volumes the task can use, if any.
+	WorkspaceID int
+	// Egress restricts the network traffic of the task's containers, if set. It is resolved from
+	// the egress policy of the workspace when the task is allocated.
+	Egress *model.EgressRule
 	// Ports required by trial or commands and their respective base port values.
 	UniqueExposedPortRequests map[string]int
 }
@@ -137,6 +147,7 @@ func (t *TaskSpec) Archives() ([]cproto.RunArchive, []cproto.RunArchive) {
 		masterCertArchive(t.MasterCert),
 	}
 	res = append(res, t.ExtraArchives...)
+	res = append(res, t.SecretArchives...)
 
 	// Split into root and non root required files. In the case the user
 	// is root we will still differentiate files that need to be root
@@ -221,6 +232,9 @@ func (t TaskSpec) EnvVars() map[string]string {
 	for k, v := range t.ExtraEnvVars {
 		e[k] = v
 	}
+	for k, v := range t.SecretEnvVars {
+		e[k] = v
+	}
 	return e
 }
 
@@ -266,6 +280,20 @@ func (t *TaskSpec) ToDockerSpec() cproto.Spec {
 		})
 	}
 
+	var stagedData []cproto.StagedDataset
+	for _, d := range resources.StagedData() {
+		stagedData = append(stagedData, cproto.StagedDataset{
+			Source:    d.Source(),
+			CacheKey:  d.CacheKey(),
+			MountPath: d.MountPath(),
+		})
+	}
+
+	var egress *cproto.EgressSpec
+	if t.Egress != nil {
+		egress = &cproto.EgressSpec{Allow: t.Egress.Allow}
+	}
+
 	runArchives, rootArchives := t.Archives()
 	spec := cproto.Spec{
 		TaskType: string(t.TaskType),
@@ -273,6 +301,8 @@ func (t *TaskSpec) ToDockerSpec() cproto.Spec {
 			Registry:  env.RegistryAuth(),
 			ForcePull: env.ForcePullImage(),
 		},
+		StagedData: stagedData,
+		Egress:     egress,
 		RunSpec: cproto.RunSpec{
 			ContainerConfig: docker.Config{
 				User:         getUser(t.AgentUserGroup),
@@ -80,11 +80,23 @@ func (s GenericCommandSpec) ToTaskSpec() TaskSpec {
 	}
 	res.ResolveWorkDir()
 
+	if s.CanSpanAgents() {
+		res.UniqueExposedPortRequests = map[string]int{
+			DTrainSSHPort: DtrainSSHPortBase,
+		}
+	}
+
 	if s.Keys != nil {
+		// Shells rewrite their authorized keys on startup to inject the task environment into
+		// ssh sessions; commands only use their keys to ssh between containers.
+		authorizedKeysFile, authorizedKeysMode := shellAuthorizedKeysFile, 0o644
+		if s.TaskType != model.TaskTypeShell {
+			authorizedKeysFile, authorizedKeysMode = trialAuthorizedKeysFile, trialAuthorizedKeysMode
+		}
 		s.AdditionalFiles = append(s.AdditionalFiles, archive.Archive{
 			res.AgentUserGroup.OwnedArchiveItem(sshDir, nil, sshDirMode, tar.TypeDir),
 			res.AgentUserGroup.OwnedArchiveItem(
-				shellAuthorizedKeysFile, s.Keys.PublicKey, 0o644, tar.TypeReg,
+				authorizedKeysFile, s.Keys.PublicKey, authorizedKeysMode, tar.TypeReg,
 			),
 			res.AgentUserGroup.OwnedArchiveItem(
 				privKeyFile, s.Keys.PrivateKey, privKeyMode, tar.TypeReg,
@@ -104,8 +116,20 @@ func (s GenericCommandSpec) ToTaskSpec() TaskSpec {
 	res.ExtraArchives = []cproto.RunArchive{
 		wrapArchive(s.AdditionalFiles, rootDir),
 	}
+	if s.CanSpanAgents() {
+		// Like horovodrun, users ssh between the containers of the task without host keys.
+		res.ExtraArchives = append(res.ExtraArchives, wrapArchive(archive.Archive{
+			archive.RootItem(
+				trialSSHConfigFile,
+				etc.MustStaticFile(etc.SSHConfigResource),
+				trialSSHConfigMode,
+				tar.TypeReg,
+			),
+		}, rootDir))
+	}
 
 	res.Description = fmt.Sprintf("cmd-%s", s.CommandID)
+	res.WorkspaceID = int(s.Metadata.WorkspaceID)
 
 	res.Entrypoint = s.Config.Entrypoint
 
@@ -140,6 +164,17 @@ func (s *GenericCommandSpec) MakeEnvPorts() {
 		port := pp.ProxyPort()
 		s.Config.Environment.Ports[strconv.Itoa(port)] = port
 	}
+	if s.CanSpanAgents() {
+		s.Config.Environment.Ports["rendezvous"] = commandRendezvousPort
+	}
+}
+
+// CanSpanAgents returns whether the task may run in containers on several agents, which commands
+// and shells with more than one slot may. They rendezvous when they do, while notebooks and
+// TensorBoards always run in one container.
+func (s *GenericCommandSpec) CanSpanAgents() bool {
+	return (s.TaskType == model.TaskTypeCommand || s.TaskType == model.TaskTypeShell) &&
+		s.Config.Resources.Slots > 1
 }
 
 // ProxyPorts combines user-defined and system proxy configs.
@@ -0,0 +1,84 @@
+package tasks
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/determined-ai/determined/master/pkg/archive"
+	"github.com/determined-ai/determined/master/pkg/cproto"
+	"github.com/determined-ai/determined/master/pkg/etc"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/ssh"
+)
+
+func TestMain(m *testing.M) {
+	if err := etc.SetRootPath("../../static/srv"); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func archiveItem(archives []cproto.RunArchive, path string) *archive.Item {
+	for _, a := range archives {
+		for i := range a.Archive {
+			if a.Archive[i].Path == path {
+				return &a.Archive[i]
+			}
+		}
+	}
+	return nil
+}
+
+func TestGenericCommandSpecToTaskSpec(t *testing.T) {
+	keys, err := ssh.GenerateKey(1024, nil)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name               string
+		taskType           model.TaskType
+		slots              float64
+		canSpanAgents      bool
+		authorizedKeysFile string
+		authorizedKeysMode int
+	}{
+		{"multi-node command", model.TaskTypeCommand, 8, true, trialAuthorizedKeysFile, 0o600},
+		{"multi-node shell", model.TaskTypeShell, 8, true, shellAuthorizedKeysFile, 0o644},
+		{"single slot command", model.TaskTypeCommand, 1, false, trialAuthorizedKeysFile, 0o600},
+		{"single slot shell", model.TaskTypeShell, 1, false, shellAuthorizedKeysFile, 0o644},
+		{"notebook", model.TaskTypeNotebook, 8, false, trialAuthorizedKeysFile, 0o600},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := GenericCommandSpec{
+				Config:   model.DefaultConfig(nil),
+				Keys:     &keys,
+				TaskType: tc.taskType,
+			}
+			spec.Config.Resources.Slots = tc.slots
+			require.Equal(t, tc.canSpanAgents, spec.CanSpanAgents())
+
+			res := spec.ToTaskSpec()
+			archives := res.ExtraArchives
+			authorizedKeys := archiveItem(archives, tc.authorizedKeysFile)
+			require.NotNil(t, authorizedKeys)
+			require.Equal(t, keys.PublicKey, []byte(authorizedKeys.Content))
+			require.Equal(t, tc.authorizedKeysMode, int(authorizedKeys.FileMode))
+			require.NotNil(t, archiveItem(archives, privKeyFile))
+
+			sshConfig := archiveItem(archives, trialSSHConfigFile)
+			_, rendezvous := res.Environment.Ports()["rendezvous"]
+			if tc.canSpanAgents {
+				require.NotNil(t, sshConfig)
+				require.Equal(t, etc.MustStaticFile(etc.SSHConfigResource), []byte(sshConfig.Content))
+				require.True(t, rendezvous)
+				require.Equal(t, DtrainSSHPortBase, res.UniqueExposedPortRequests[DTrainSSHPort])
+			} else {
+				require.Nil(t, sshConfig)
+				require.False(t, rendezvous)
+				require.Empty(t, res.UniqueExposedPortRequests)
+			}
+		})
+	}
+}
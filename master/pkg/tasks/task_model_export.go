@@ -0,0 +1,147 @@
+package tasks
+
+import (
+	"archive/tar"
+	"fmt"
+	"path/filepath"
+
+	"github.com/docker/docker/api/types/mount"
+
+	"github.com/determined-ai/determined/master/pkg/archive"
+	"github.com/determined-ai/determined/master/pkg/cproto"
+	"github.com/determined-ai/determined/master/pkg/etc"
+	"github.com/determined-ai/determined/master/pkg/model"
+	"github.com/determined-ai/determined/master/pkg/schemas"
+	"github.com/determined-ai/determined/master/pkg/schemas/expconf"
+)
+
+// ModelExportCheckpoint is a checkpoint to download before running an export command.
+type ModelExportCheckpoint struct {
+	UUID string `json:"uuid"`
+	// Dir is where the checkpoint is downloaded, relative to the input directory of the command.
+	Dir string `json:"dir"`
+}
+
+// ModelExportSpec is a description of a task for exporting a model version to a serving format.
+type ModelExportSpec struct {
+	Base TaskSpec
+
+	ExportUUID string
+	Format     string
+	// Image overrides the image of the environment if set.
+	Image                string
+	Command              []string
+	EnvironmentVariables []string
+	// LegacyConfig is the config of the experiment of the first checkpoint. All checkpoints are
+	// in its checkpoint storage, which also receives the output of the export.
+	LegacyConfig expconf.LegacyConfig
+	Checkpoints  []ModelExportCheckpoint
+	// Delete lists the UUIDs of exports whose files the task deletes instead of exporting.
+	Delete      []string
+	Description string
+}
+
+// ToTaskSpec generates a TaskSpec.
+func (e ModelExportSpec) ToTaskSpec() TaskSpec {
+	res := e.Base
+
+	// Set Environment.
+	// Keep the EnvironmentVariables provided by the experiment's config and add the format's.
+	envVars := e.LegacyConfig.Environment.EnvironmentVariables()
+	envVars.RawCPU = append(append([]string{}, envVars.RawCPU...), e.EnvironmentVariables...)
+	envVars.RawCUDA = append(append([]string{}, envVars.RawCUDA...), e.EnvironmentVariables...)
+	envVars.RawROCM = append(append([]string{}, envVars.RawROCM...), e.EnvironmentVariables...)
+	//nolint:exhaustruct // This has caused an issue before, but is valid as a partial struct.
+	env := expconf.EnvironmentConfig{
+		RawEnvironmentVariables: &envVars,
+		RawPodSpec:              e.LegacyConfig.Environment.PodSpec(),
+	}
+	if e.Image != "" {
+		env.RawImage = &expconf.EnvironmentImageMapV0{
+			RawCPU:  &e.Image,
+			RawCUDA: &e.Image,
+			RawROCM: &e.Image,
+		}
+	}
+	// Fill the rest of the environment with default values.
+	var defaultConfig expconf.ExperimentConfig
+	e.Base.TaskContainerDefaults.MergeIntoExpConfig(&defaultConfig)
+	if defaultConfig.RawEnvironment != nil {
+		env = schemas.Merge(env, *defaultConfig.RawEnvironment)
+	}
+	res.Environment = schemas.WithDefaults(env)
+	res.ExtraEnvVars = map[string]string{
+		"DET_TASK_TYPE":     string(model.TaskTypeModelExport),
+		"DET_EXPORT_FORMAT": e.Format,
+	}
+	res.ResourcesConfig = schemas.WithDefaults(res.ResourcesConfig)
+	res.SlurmConfig = defaultConfig.SlurmConfig()
+	res.PbsConfig = defaultConfig.PbsConfig()
+
+	res.WorkDir = DefaultWorkDir
+
+	storageConfigPath := "model_export/storage_config.json"
+	checkpointsPath := "model_export/checkpoints.json"
+	commandPath := "model_export/command.json"
+	deletePath := "model_export/delete.json"
+	items := archive.Archive{
+		e.Base.AgentUserGroup.OwnedArchiveItem("model_export", nil, 0o700, tar.TypeDir),
+		e.Base.AgentUserGroup.OwnedArchiveItem(
+			storageConfigPath,
+			[]byte(jsonify(e.LegacyConfig.CheckpointStorage)),
+			0o600,
+			tar.TypeReg,
+		),
+		e.Base.AgentUserGroup.OwnedArchiveItem(
+			filepath.Join("model_export", etc.ModelExportEntrypointResource),
+			etc.MustStaticFile(etc.ModelExportEntrypointResource),
+			0o700,
+			tar.TypeReg,
+		),
+	}
+	// Like checkpoint GC, pass the storage config, checkpoints and command through JSON files to
+	// avoid reaching any OS limitations on sizes of CLI arguments.
+	res.Entrypoint = []string{
+		filepath.Join("/run/determined/model_export", etc.ModelExportEntrypointResource),
+		"--storage-config", fmt.Sprintf("/run/determined/%s", storageConfigPath),
+	}
+	if len(e.Delete) > 0 {
+		items = append(items, e.Base.AgentUserGroup.OwnedArchiveItem(
+			deletePath, []byte(jsonify(e.Delete)), 0o600, tar.TypeReg,
+		))
+		res.Entrypoint = append(res.Entrypoint,
+			"--delete", fmt.Sprintf("/run/determined/%s", deletePath))
+	} else {
+		items = append(items,
+			e.Base.AgentUserGroup.OwnedArchiveItem(
+				checkpointsPath, []byte(jsonify(e.Checkpoints)), 0o600, tar.TypeReg,
+			),
+			e.Base.AgentUserGroup.OwnedArchiveItem(
+				commandPath, []byte(jsonify(e.Command)), 0o600, tar.TypeReg,
+			),
+		)
+		res.Entrypoint = append(res.Entrypoint,
+			"--export-uuid", e.ExportUUID,
+			"--checkpoints", fmt.Sprintf("/run/determined/%s", checkpointsPath),
+			"--command", fmt.Sprintf("/run/determined/%s", commandPath),
+		)
+	}
+	res.ExtraArchives = []cproto.RunArchive{wrapArchive(items, RunDir)}
+
+	res.Description = e.Description
+
+	res.Mounts = ToDockerMounts(e.LegacyConfig.BindMounts, res.WorkDir)
+	if fs := e.LegacyConfig.CheckpointStorage.RawSharedFSConfig; fs != nil {
+		res.Mounts = append(res.Mounts, mount.Mount{
+			Type:   mount.TypeBind,
+			Source: fs.HostPath(),
+			Target: expconf.DefaultSharedFSContainerPath,
+			BindOptions: &mount.BindOptions{
+				Propagation: expconf.DefaultSharedFSPropagation,
+			},
+		})
+	}
+	res.TaskType = model.TaskTypeModelExport
+
+	return res
+}
@@ -0,0 +1,3 @@
+DROP TABLE saved_views;
+
+DROP TYPE saved_view_visibility;
@@ -0,0 +1,16 @@
+CREATE TYPE saved_view_visibility AS ENUM ('PRIVATE', 'PROJECT');
+
+CREATE TABLE saved_views (
+  id          integer PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+  name        text NOT NULL,
+  project_id  integer NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  owner_id    integer NOT NULL REFERENCES users(id),
+  visibility  saved_view_visibility NOT NULL DEFAULT 'PRIVATE',
+  filter      jsonb,
+  sort        text,
+  columns     jsonb NOT NULL DEFAULT '[]'::jsonb,
+  created_at  timestamptz NOT NULL DEFAULT current_timestamp,
+  updated_at  timestamptz NOT NULL DEFAULT current_timestamp,
+  CONSTRAINT uq_saved_views_project_owner_name UNIQUE (project_id, owner_id, name)
+);
+CREATE INDEX ix_saved_views_project_id ON saved_views(project_id);
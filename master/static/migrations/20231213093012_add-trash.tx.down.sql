@@ -0,0 +1,3 @@
+ALTER TABLE experiments DROP COLUMN trashed_at;
+ALTER TABLE projects DROP COLUMN trashed_at;
+ALTER TABLE models DROP COLUMN trashed_at;
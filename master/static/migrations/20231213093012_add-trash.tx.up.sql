@@ -0,0 +1,7 @@
+ALTER TABLE experiments ADD COLUMN trashed_at timestamptz;
+ALTER TABLE projects ADD COLUMN trashed_at timestamptz;
+ALTER TABLE models ADD COLUMN trashed_at timestamptz;
+
+CREATE INDEX ix_experiments_trashed_at ON experiments(trashed_at) WHERE trashed_at IS NOT NULL;
+CREATE INDEX ix_projects_trashed_at ON projects(trashed_at) WHERE trashed_at IS NOT NULL;
+CREATE INDEX ix_models_trashed_at ON models(trashed_at) WHERE trashed_at IS NOT NULL;
@@ -0,0 +1,5 @@
+DROP INDEX ix_experiments_template_name;
+ALTER TABLE experiments DROP COLUMN template_version;
+ALTER TABLE experiments DROP COLUMN template_name;
+
+DROP TABLE template_versions;
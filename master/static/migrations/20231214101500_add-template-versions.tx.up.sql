@@ -0,0 +1,22 @@
+-- Template versions belong to a single incarnation of a template: they go away with it, so a
+-- template re-created under the same name, maybe in another workspace, starts a new history.
+CREATE TABLE template_versions (
+    template_name character varying NOT NULL REFERENCES templates(name) ON DELETE CASCADE,
+    version integer NOT NULL,
+    config jsonb NOT NULL,
+    -- The workspace a version was written in, to authorize reading it.
+    workspace_id integer NOT NULL,
+    created_by integer REFERENCES users(id) ON DELETE SET NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (template_name, version)
+);
+
+INSERT INTO template_versions (template_name, version, config, workspace_id)
+SELECT name, 1, config, workspace_id FROM templates;
+
+ALTER TABLE experiments ADD COLUMN template_name character varying;
+ALTER TABLE experiments ADD COLUMN template_version integer;
+
+-- Version numbers of deleted templates stay taken by the experiments that recorded them.
+CREATE INDEX ix_experiments_template_name ON experiments (template_name)
+WHERE template_name IS NOT NULL;
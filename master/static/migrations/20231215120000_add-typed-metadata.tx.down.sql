@@ -0,0 +1,3 @@
+DROP TABLE trial_metadata;
+DROP TABLE experiment_metadata;
+DROP TYPE metadata_type;
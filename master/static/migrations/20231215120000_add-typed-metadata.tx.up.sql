@@ -0,0 +1,27 @@
+CREATE TYPE metadata_type AS ENUM ('string', 'number', 'bool', 'timestamp');
+
+CREATE TABLE experiment_metadata (
+    experiment_id integer NOT NULL REFERENCES experiments(id) ON DELETE CASCADE,
+    key text NOT NULL,
+    type metadata_type NOT NULL,
+    string_value text,
+    number_value double precision,
+    bool_value boolean,
+    timestamp_value timestamptz,
+    PRIMARY KEY (experiment_id, key),
+    CHECK (num_nonnulls(string_value, number_value, bool_value, timestamp_value) = 1)
+);
+CREATE INDEX ix_experiment_metadata_key ON experiment_metadata(key);
+
+CREATE TABLE trial_metadata (
+    trial_id integer NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+    key text NOT NULL,
+    type metadata_type NOT NULL,
+    string_value text,
+    number_value double precision,
+    bool_value boolean,
+    timestamp_value timestamptz,
+    PRIMARY KEY (trial_id, key),
+    CHECK (num_nonnulls(string_value, number_value, bool_value, timestamp_value) = 1)
+);
+CREATE INDEX ix_trial_metadata_key ON trial_metadata(key);
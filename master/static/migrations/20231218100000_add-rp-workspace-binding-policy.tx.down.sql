@@ -0,0 +1,5 @@
+ALTER TABLE rp_workspace_bindings
+    DROP COLUMN priority,
+    DROP COLUMN weight,
+    DROP COLUMN max_slots,
+    DROP COLUMN exclusive;
@@ -0,0 +1,5 @@
+ALTER TABLE rp_workspace_bindings
+    ADD COLUMN priority INT DEFAULT NULL,
+    ADD COLUMN weight DOUBLE PRECISION DEFAULT NULL,
+    ADD COLUMN max_slots INT DEFAULT NULL,
+    ADD COLUMN exclusive BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+DROP TABLE metric_rollups_backfill;
+DROP TABLE metric_rollups;
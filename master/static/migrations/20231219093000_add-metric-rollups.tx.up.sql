@@ -0,0 +1,24 @@
+CREATE TABLE metric_rollups (
+    trial_id integer NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+    metric_group text NOT NULL,
+    -- The width of the bucket in batches.
+    resolution integer NOT NULL,
+    -- The bucket covers batches [bucket * resolution, (bucket + 1) * resolution).
+    bucket bigint NOT NULL,
+    metric_name text NOT NULL,
+    count integer NOT NULL,
+    sum double precision NOT NULL,
+    min double precision NOT NULL,
+    max double precision NOT NULL,
+    last double precision NOT NULL,
+    last_batches integer NOT NULL,
+    last_end_time timestamptz NOT NULL,
+    PRIMARY KEY (trial_id, metric_group, resolution, metric_name, bucket)
+);
+
+-- Trials whose metrics were reported before rollups existed. The master rolls them up in the
+-- background, so that upgrading doesn't have to scan every metric.
+CREATE TABLE metric_rollups_backfill (
+    trial_id integer PRIMARY KEY REFERENCES runs(id) ON DELETE CASCADE
+);
+INSERT INTO metric_rollups_backfill (trial_id) SELECT id FROM runs;
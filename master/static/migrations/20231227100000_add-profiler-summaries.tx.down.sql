@@ -0,0 +1,4 @@
+DROP INDEX trial_profiler_metrics_unsummarized;
+ALTER TABLE trial_profiler_metrics DROP COLUMN summarized;
+DROP TABLE experiment_profiler_summaries;
+DROP TABLE trial_profiler_summaries;
@@ -0,0 +1,34 @@
+-- Summaries hold mergeable stats of the profiler metrics of a trial or experiment, plus the
+-- figures derived from them so that experiments can be searched by them.
+CREATE TABLE trial_profiler_summaries (
+    trial_id integer PRIMARY KEY REFERENCES runs(id) ON DELETE CASCADE,
+    stats jsonb NOT NULL,
+    gpu_util_mean double precision,
+    gpu_util_p95 double precision,
+    cpu_util_mean double precision,
+    dataloader_fraction double precision,
+    min_free_host_memory_gb double precision,
+    samples_per_second_per_slot double precision,
+    bottlenecks text[] NOT NULL DEFAULT '{}',
+    updated_at timestamptz NOT NULL
+);
+
+CREATE TABLE experiment_profiler_summaries (
+    experiment_id integer PRIMARY KEY REFERENCES experiments(id) ON DELETE CASCADE,
+    stats jsonb NOT NULL,
+    gpu_util_mean double precision,
+    gpu_util_p95 double precision,
+    cpu_util_mean double precision,
+    dataloader_fraction double precision,
+    min_free_host_memory_gb double precision,
+    samples_per_second_per_slot double precision,
+    bottlenecks text[] NOT NULL DEFAULT '{}',
+    updated_at timestamptz NOT NULL
+);
+
+-- Summaries are updated in the background from the raw metrics, which are marked once they are
+-- folded in. Metrics reported before summaries existed start out unmarked, so they are folded in
+-- too.
+ALTER TABLE trial_profiler_metrics ADD COLUMN summarized boolean NOT NULL DEFAULT false;
+CREATE INDEX trial_profiler_metrics_unsummarized ON trial_profiler_metrics (id)
+    WHERE NOT summarized;
@@ -0,0 +1,2 @@
+DROP VIEW registered_checkpoints;
+DROP TABLE model_version_checkpoints;
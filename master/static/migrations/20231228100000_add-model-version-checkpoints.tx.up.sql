@@ -0,0 +1,23 @@
+-- Members of model versions made of several checkpoints, such as ensembles or sharded models,
+-- in order. The checkpoint_uuid of such versions is that of their first member.
+CREATE TABLE model_version_checkpoints (
+    model_version_id integer NOT NULL REFERENCES model_versions(id) ON DELETE CASCADE,
+    ordinal integer NOT NULL,
+    checkpoint_uuid uuid NOT NULL,
+    role text NOT NULL DEFAULT '',
+    weight double precision,
+    PRIMARY KEY (model_version_id, ordinal),
+    UNIQUE (model_version_id, checkpoint_uuid)
+);
+
+CREATE INDEX ix_model_version_checkpoints_checkpoint_uuid
+    ON model_version_checkpoints (checkpoint_uuid);
+
+-- Every checkpoint a model version depends on, which keeps them all out of garbage collection.
+CREATE VIEW registered_checkpoints AS
+    SELECT id AS model_version_id, model_id, checkpoint_uuid
+    FROM model_versions
+    UNION
+    SELECT mv.id, mv.model_id, mvc.checkpoint_uuid
+    FROM model_version_checkpoints mvc
+    JOIN model_versions mv ON mv.id = mvc.model_version_id;
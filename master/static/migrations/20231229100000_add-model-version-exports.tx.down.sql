@@ -0,0 +1,30 @@
+DROP TABLE public.model_version_exports;
+DROP TYPE public.model_version_export_state;
+
+DELETE FROM public.allocations
+    WHERE task_id IN (SELECT task_id FROM public.tasks WHERE task_type = 'MODEL_EXPORT');
+DELETE FROM public.tasks WHERE task_type = 'MODEL_EXPORT';
+ALTER TYPE public.task_type RENAME TO _task_type;
+CREATE TYPE public.task_type AS ENUM (
+    'TRIAL',
+    'NOTEBOOK',
+    'SHELL',
+    'COMMAND',
+    'TENSORBOARD',
+    'CHECKPOINT_GC'
+);
+ALTER TABLE public.tasks ALTER COLUMN task_type TYPE public.task_type USING (task_type::text::task_type);
+DROP TYPE _task_type;
+
+DELETE FROM public.jobs WHERE job_type = 'MODEL_EXPORT';
+ALTER TYPE public.job_type RENAME TO _job_type;
+CREATE TYPE public.job_type AS ENUM (
+    'EXPERIMENT',
+    'NOTEBOOK',
+    'SHELL',
+    'COMMAND',
+    'TENSORBOARD',
+    'CHECKPOINT_GC'
+);
+ALTER TABLE public.jobs ALTER COLUMN job_type TYPE public.job_type USING (job_type::text::job_type);
+DROP TYPE _job_type;
@@ -0,0 +1,51 @@
+ALTER TYPE public.task_type RENAME TO _task_type;
+CREATE TYPE public.task_type AS ENUM (
+    'TRIAL',
+    'NOTEBOOK',
+    'SHELL',
+    'COMMAND',
+    'TENSORBOARD',
+    'CHECKPOINT_GC',
+    'MODEL_EXPORT'
+);
+ALTER TABLE public.tasks ALTER COLUMN task_type TYPE public.task_type USING (task_type::text::task_type);
+DROP TYPE _task_type;
+
+ALTER TYPE public.job_type RENAME TO _job_type;
+CREATE TYPE public.job_type AS ENUM (
+    'EXPERIMENT',
+    'NOTEBOOK',
+    'SHELL',
+    'COMMAND',
+    'TENSORBOARD',
+    'CHECKPOINT_GC',
+    'MODEL_EXPORT'
+);
+ALTER TABLE public.jobs ALTER COLUMN job_type TYPE public.job_type USING (job_type::text::job_type);
+DROP TYPE _job_type;
+
+CREATE TYPE public.model_version_export_state AS ENUM (
+    'RUNNING',
+    'COMPLETED',
+    'ERROR'
+);
+
+-- Exports of model versions to serving formats. The exported files are stored in the checkpoint
+-- storage of the version's first checkpoint, under the export's UUID. Exports of deleted versions
+-- are kept until their files are deleted.
+CREATE TABLE public.model_version_exports (
+    id serial PRIMARY KEY,
+    uuid uuid NOT NULL UNIQUE,
+    model_version_id integer NULL REFERENCES public.model_versions(id) ON DELETE SET NULL,
+    checkpoint_uuid uuid NOT NULL,
+    format text NOT NULL,
+    state public.model_version_export_state NOT NULL,
+    task_id text NULL REFERENCES public.tasks(task_id) ON DELETE SET NULL,
+    user_id integer NULL REFERENCES public.users(id) ON DELETE SET NULL,
+    error text NULL,
+    start_time timestamptz NOT NULL,
+    end_time timestamptz NULL
+);
+
+CREATE INDEX ix_model_version_exports_model_version_id
+    ON public.model_version_exports (model_version_id);
@@ -0,0 +1,2 @@
+DROP TABLE public.secrets;
+DROP TYPE public.secret_scope;
@@ -0,0 +1,26 @@
+CREATE TYPE public.secret_scope AS ENUM (
+    'USER',
+    'WORKSPACE',
+    'CLUSTER'
+);
+
+-- Secrets that tasks reference by name. Values are encrypted with the master key and are never
+-- returned by the API.
+CREATE TABLE public.secrets (
+    id serial PRIMARY KEY,
+    name text NOT NULL,
+    scope public.secret_scope NOT NULL,
+    user_id integer NULL REFERENCES public.users(id) ON DELETE CASCADE,
+    workspace_id integer NULL REFERENCES public.workspaces(id) ON DELETE CASCADE,
+    ciphertext bytea NOT NULL,
+    created_by integer NULL REFERENCES public.users(id) ON DELETE SET NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    CHECK ((scope = 'USER') = (user_id IS NOT NULL)),
+    CHECK ((scope = 'WORKSPACE') = (workspace_id IS NOT NULL))
+);
+
+CREATE UNIQUE INDEX ix_secrets_user_name ON public.secrets (user_id, name) WHERE scope = 'USER';
+CREATE UNIQUE INDEX ix_secrets_workspace_name
+    ON public.secrets (workspace_id, name) WHERE scope = 'WORKSPACE';
+CREATE UNIQUE INDEX ix_secrets_cluster_name ON public.secrets (name) WHERE scope = 'CLUSTER';
@@ -0,0 +1,2 @@
+DROP TABLE public.ssh_gateway_keys;
+DROP TABLE public.user_ssh_keys;
@@ -0,0 +1,20 @@
+-- Public keys users log in to the SSH gateway with.
+CREATE TABLE public.user_ssh_keys (
+    id serial PRIMARY KEY,
+    user_id integer NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+    name text NOT NULL,
+    public_key text NOT NULL,
+    fingerprint text NOT NULL UNIQUE,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    last_used_at timestamptz NULL
+);
+
+CREATE INDEX ix_user_ssh_keys_user_id ON public.user_ssh_keys (user_id);
+
+-- The host key of the SSH gateway and the key of the authority that signs user certificates,
+-- generated on first use so that they survive master restarts.
+CREATE TABLE public.ssh_gateway_keys (
+    kind text PRIMARY KEY,
+    private_key bytea NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
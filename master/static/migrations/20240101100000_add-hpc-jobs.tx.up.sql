@@ -0,0 +1,11 @@
+-- The batch jobs that the Slurm and PBS resource managers submitted for allocations, so that they
+-- can reattach to them after the master restarts.
+CREATE TABLE public.hpc_jobs (
+    allocation_id text PRIMARY KEY,
+    resources_id text NOT NULL,
+    workload_manager text NOT NULL,
+    job_id text NOT NULL,
+    dir text NOT NULL,
+    num_nodes integer NOT NULL,
+    submitted_at timestamptz NOT NULL DEFAULT now()
+);
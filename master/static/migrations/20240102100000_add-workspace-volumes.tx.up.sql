@@ -0,0 +1,13 @@
+-- Shared volumes of workspaces, which the Kubernetes resource manager creates as
+-- PersistentVolumeClaims and mounts into every task of the workspace.
+CREATE TABLE public.workspace_volumes (
+    workspace_id integer NOT NULL REFERENCES public.workspaces(id) ON DELETE CASCADE,
+    name text NOT NULL,
+    size text NOT NULL,
+    storage_class text NULL,
+    mount_path text NOT NULL,
+    read_only boolean NOT NULL DEFAULT false,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (workspace_id, name),
+    UNIQUE (workspace_id, mount_path)
+);
@@ -0,0 +1,3 @@
+-- The number of GPUs the tasks of a workspace can request at once. The Kubernetes resource
+-- manager enforces it with a ResourceQuota in the namespace of the workspace.
+ALTER TABLE public.workspaces ADD COLUMN gpu_limit integer NULL CHECK (gpu_limit >= 0);
@@ -0,0 +1 @@
+ALTER TABLE public.workspaces DROP COLUMN image_policy;
@@ -0,0 +1,3 @@
+-- The policy that restricts the images the tasks of a workspace can run: the repositories they
+-- are allowed from and whether their tags are pinned to digests when tasks are submitted.
+ALTER TABLE public.workspaces ADD COLUMN image_policy jsonb NULL;
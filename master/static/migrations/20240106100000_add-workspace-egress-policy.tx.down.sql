@@ -0,0 +1 @@
+ALTER TABLE public.workspaces DROP COLUMN egress_policy;
@@ -0,0 +1,3 @@
+-- The policy that restricts the network traffic of the containers of a workspace's tasks, with a
+-- rule for all tasks and rules for each task type.
+ALTER TABLE public.workspaces ADD COLUMN egress_policy jsonb NULL;
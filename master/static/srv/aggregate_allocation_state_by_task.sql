@@ -1,6 +1,6 @@
 SELECT
     t.task_id AS task,
-    BOOL_OR(CASE WHEN a.state = 'PULLING' THEN true ELSE false END) AS pulling,
+    BOOL_OR(CASE WHEN a.state IN ('PULLING', 'STAGING') THEN true ELSE false END) AS pulling,
     BOOL_OR(CASE WHEN a.state = 'STARTING' THEN true ELSE false END) AS starting,
     BOOL_OR(CASE WHEN a.state = 'RUNNING' THEN true ELSE false END) AS running
 FROM tasks t
@@ -14,6 +14,17 @@ fi
 # to register the proxy with the Determined master.
 "$DET_PYTHON_EXECUTABLE" -m determined.exec.prep_container --proxy --download_context_directory
 
+# Commands that span several containers run on the chief container; the others only run sshd
+# so that the command can reach them, and exit with the chief.
+if [ "${DET_NUM_CONTAINERS:-1}" -gt 1 ]; then
+    "$DET_PYTHON_EXECUTABLE" -m determined.exec.prep_container --rendezvous
+    source /run/determined/info/rendezvous.env
+    if [ "$DET_CONTAINER_RANK" -gt 0 ]; then
+        "$DET_PYTHON_EXECUTABLE" -m determined.exec.prep_container --daemon
+        exec /usr/sbin/sshd -p "$DTRAIN_SSH_PORT" -f /run/determined/ssh/sshd_config -D
+    fi
+fi
+
 if [ "$#" -eq 1 ]; then
     exec /bin/sh -c "$@"
 else
@@ -16,5 +16,5 @@ FROM models AS m
 LEFT JOIN model_versions AS mv
     ON mv.model_id = m.id
 LEFT JOIN users AS u ON u.id = m.user_id
-WHERE m.name = $1
+WHERE m.name = $1 AND m.trashed_at IS NULL
 GROUP BY m.id, u.id;
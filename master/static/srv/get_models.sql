@@ -2,7 +2,8 @@ SELECT m.id, m.name, m.description, m.notes, m.metadata, m.creation_time, m.last
 LEFT JOIN model_versions as mv ON mv.model_id = m.id
 LEFT JOIN users as u ON u.id = m.user_id
 LEFT JOIN workspaces as w on w.id = m.workspace_id
-WHERE ($1 = 0 OR m.id = $1)
+WHERE m.trashed_at IS NULL
+AND ($1 = 0 OR m.id = $1)
 AND ($2 = '' OR m.archived = $2::BOOL)
 AND ($3 = '' OR (u.username IN (SELECT unnest(string_to_array($3, ',')))))
 AND ($4 = '' OR m.user_id IN (SELECT unnest(string_to_array($4, ',')::int [])))
@@ -4,7 +4,7 @@ WITH pe AS (
         SUM(CASE WHEN state = 'ACTIVE' THEN 1 ELSE 0 END) AS num_active_experiments,
         MAX(start_time) AS last_experiment_started_at
     FROM experiments
-    WHERE project_id = $1
+    WHERE project_id = $1 AND trashed_at IS NULL
 )
 
 SELECT
@@ -1,11 +1,11 @@
 WITH p AS (
     SELECT id FROM projects
-    WHERE workspace_id = $1
+    WHERE workspace_id = $1 AND trashed_at IS NULL
 ),
 
 exp_count AS (
     SELECT COUNT(*) AS count FROM experiments
-    WHERE project_id IN (SELECT id FROM p)
+    WHERE project_id IN (SELECT id FROM p) AND trashed_at IS NULL
 )
 
 SELECT
@@ -21,10 +21,11 @@ SELECT
 FROM
   projects AS p
   LEFT JOIN workspaces AS w ON p.workspace_id = w.id
-  LEFT JOIN experiments AS pe ON p.id = pe.project_id
+  LEFT JOIN experiments AS pe ON p.id = pe.project_id AND pe.trashed_at IS NULL
   LEFT JOIN users AS u ON u.id = p.user_id
 WHERE
-  ($1 = 0 OR p.workspace_id = $1)
+  p.trashed_at IS NULL
+  AND ($1 = 0 OR p.workspace_id = $1)
   AND ($2 = '' OR (u.username IN (SELECT unnest(string_to_array($2, ',')))))
   AND ($3 = '' OR p.user_id IN (SELECT unnest(string_to_array($3, ',')::int [])))
   AND ($4 = '' OR p.name ILIKE $4)
@@ -9,8 +9,8 @@ COUNT(DISTINCT e.id) AS num_experiments
 FROM workspaces AS w
 LEFT JOIN users AS u ON u.id = w.user_id
 LEFT JOIN workspace_pins AS pins ON pins.workspace_id = w.id AND pins.user_id = $6
-LEFT JOIN projects AS p ON p.workspace_id = w.id
-LEFT JOIN experiments AS e ON e.project_id = p.id
+LEFT JOIN projects AS p ON p.workspace_id = w.id AND p.trashed_at IS NULL
+LEFT JOIN experiments AS e ON e.project_id = p.id AND e.trashed_at IS NULL
 
 WHERE ($1 = '' OR (u.username IN (SELECT unnest(string_to_array($1, ',')))))
 AND ($2 = '' OR w.user_id IN (SELECT unnest(string_to_array($2, ',')::int [])))
@@ -20,6 +20,13 @@ set -x
 test -f "${STARTUP_HOOK}" && source "${STARTUP_HOOK}"
 set +x
 
+# Shells that span several containers are reached through the chief container. Rendezvous before
+# capturing the environment below so that ssh sessions see the addresses of every container.
+if [ "${DET_NUM_CONTAINERS:-1}" -gt 1 ]; then
+    "$DET_PYTHON_EXECUTABLE" -m determined.exec.prep_container --rendezvous
+    source /run/determined/info/rendezvous.env
+fi
+
 # Prepend each key in authorized_keys with a set of environment="KEY=VALUE"
 # options to inject the entire docker environment into the eventual ssh
 # session via an options in the authorized keys file.  See syntax described in
@@ -90,6 +97,12 @@ sed -e "s/^/$options /" "$unmodified" >"$modified"
 # Ensure permissions are restrictive enough for ssh
 chmod 600 "$modified"
 
+# The other containers only run sshd so that the chief can reach them, and exit with it.
+if [ "${DET_CONTAINER_RANK:-0}" -gt 0 ]; then
+    "$DET_PYTHON_EXECUTABLE" -m determined.exec.prep_container --daemon
+    exec /usr/sbin/sshd -p "$DTRAIN_SSH_PORT" -f /run/determined/ssh/sshd_config -D
+fi
+
 READINESS_REGEX="Server listening on"
 
 /usr/sbin/sshd "$@" \
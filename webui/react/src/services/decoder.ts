@@ -167,6 +167,7 @@ export const jsonToAgents = (agents: Array<Sdk.V1Agent>): types.Agent[] => {
 const mapV1TaskState = (containerState: Sdk.Taskv1State): types.CommandState => {
   switch (containerState) {
     case Sdk.Taskv1State.PULLING:
+    case Sdk.Taskv1State.STAGING:
       return types.CommandState.Pulling;
     case Sdk.Taskv1State.STARTING:
       return types.CommandState.Starting;